@@ -4,36 +4,80 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
 	"text/tabwriter"
 	"time"
 
+	"github.com/ali01/mnemosyne/internal/access"
 	"github.com/ali01/mnemosyne/internal/api"
 	"github.com/ali01/mnemosyne/internal/config"
+	"github.com/ali01/mnemosyne/internal/doctor"
+	"github.com/ali01/mnemosyne/internal/duplicates"
+	"github.com/ali01/mnemosyne/internal/git"
 	"github.com/ali01/mnemosyne/internal/indexer"
+	"github.com/ali01/mnemosyne/internal/layout"
+	"github.com/ali01/mnemosyne/internal/linkcheck"
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/notify"
 	"github.com/ali01/mnemosyne/internal/positionsync"
+	"github.com/ali01/mnemosyne/internal/scripting"
 	"github.com/ali01/mnemosyne/internal/store"
+	"github.com/ali01/mnemosyne/internal/synth"
+	"github.com/ali01/mnemosyne/internal/vault"
 	"github.com/ali01/mnemosyne/internal/watcher"
 )
 
+// noMigrate leaves the database schema alone; see openStore.
+var noMigrate bool
+
 func main() {
 	portFlag := flag.Int("port", 0, "HTTP port to listen on (overrides config file)")
 	flag.IntVar(portFlag, "p", 0, "HTTP port to listen on (shorthand)")
+	profileFlag := flag.String("profile", "", "Config profile to merge over the config file, e.g. prod (default $"+config.ProfileEnv+")")
+	flag.BoolVar(&noMigrate, "no-migrate", false, "Do not migrate the database schema; fail if it is out of date (run 'mnemosyne migrate' to migrate)")
 	flag.Parse()
 
 	// Subcommand dispatch
+	if flag.NArg() > 0 && flag.Arg(0) == "migrate" {
+		cmdMigrate()
+		return
+	}
 	if flag.NArg() > 0 && flag.Arg(0) == "graphs" {
 		cmdGraphs(flag.Args()[1:])
 		return
 	}
+	if flag.NArg() > 0 && flag.Arg(0) == "positions" {
+		cmdPositions(flag.Args()[1:])
+		return
+	}
+	if flag.NArg() > 0 && flag.Arg(0) == "seed-demo" {
+		cmdSeedDemo(flag.Args()[1:])
+		return
+	}
+	if flag.NArg() > 0 && flag.Arg(0) == "bench" {
+		cmdBench(flag.Args()[1:])
+		return
+	}
+	if flag.NArg() > 0 && flag.Arg(0) == "doctor" {
+		cmdDoctor(flag.Args()[1:], *profileFlag)
+		return
+	}
+	if flag.NArg() > 0 && flag.Arg(0) == "export" {
+		cmdExport(flag.Args()[1:], *profileFlag)
+		return
+	}
 
 	cfgPath := config.DefaultConfigPath()
 	if flag.NArg() > 0 {
@@ -47,18 +91,29 @@ func main() {
 		}
 	}
 
-	cfg, err := config.Load(cfgPath)
+	profile := *profileFlag
+	if profile == "" {
+		profile = os.Getenv(config.ProfileEnv)
+	}
+	cfg, err := config.LoadProfile(cfgPath, profile)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if cfg.Profile != "" {
+		log.Printf("Config profile: %s", cfg.Profile)
+	}
 
 	// Command-line port flag overrides config file
 	if *portFlag != 0 {
 		cfg.Port = *portFlag
 	}
 
+	// Canceled on SIGINT/SIGTERM; stops indexing and in-flight requests promptly
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	dbPath := config.DBPath()
-	s, err := store.New(dbPath)
+	s, err := openStore(dbPath)
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
@@ -66,17 +121,70 @@ func main() {
 	log.Printf("Database: %s", dbPath)
 
 	idx := indexer.NewIndexManager(s)
+	if err := configureIndexer(idx, cfg); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+	cacheKey, err := cfg.CacheKey()
+	if err != nil {
+		log.Fatalf("Failed to fingerprint parse settings: %v", err)
+	}
+	idx.SetCacheKey(cacheKey)
+	if len(cfg.Scripts) > 0 {
+		scripts, err := scripting.LoadLua(cfg.Scripts)
+		if err != nil {
+			log.Fatalf("Failed to load scripts: %v", err)
+		}
+		defer scripts.Close()
+		idx.AddHook(scripts)
+	}
 	ps := positionsync.New(s)
+	policy := &access.Policy{PublishFlag: cfg.PublishFlag, ACLField: cfg.ACLField, AdminRole: cfg.Auth.AdminRole}
+
+	// Renamed nodes keep their positions and ACLs; refresh what was loaded from the store
+	idx.SetOnRename(func(graphIDs []int) {
+		for _, id := range graphIDs {
+			ps.MarkDirty(id)
+		}
+		acls, err := s.GetNodeACLs()
+		if err != nil {
+			log.Printf("Warning: failed to reload access control lists: %v", err)
+			return
+		}
+		policy.LoadACLs(acls)
+	})
+
+	// Email vault owners about failed indexes and broken links
+	if n := cfg.Notifications; n != nil {
+		server := notify.SMTP{Host: n.SMTP.Host, Port: n.SMTP.Port, Username: n.SMTP.Username, Password: n.SMTP.Password, From: n.SMTP.From}
+		notifier := notify.New(server, n.To, n.Owners, n.BrokenLinkThreshold, n.Throttle)
+		idx.SetOnFinish(func(vaultPath string, h models.ParseHistory) {
+			go notifier.ParseFinished(vaultPath, h)
+		})
+	}
+
+	// Close out runs interrupted by a crash; the full index below resumes them
+	interrupted, err := idx.RecoverInterruptedRuns()
+	if err != nil {
+		log.Fatalf("Failed to recover interrupted index runs: %v", err)
+	}
+	if len(interrupted) > 0 {
+		log.Printf("Resuming %d interrupted index run(s)", len(interrupted))
+	}
 
 	// Register and index all vaults
 	var watchers []*watcher.Watcher
+	watcherByVault := make(map[int]*watcher.Watcher)
 	for _, vaultPath := range cfg.Vaults {
 		vaultID, _, err := idx.RegisterVault(vaultPath)
 		if err != nil {
 			log.Fatalf("Failed to register vault %s: %v", vaultPath, err)
 		}
 
-		if err := idx.FullIndexVault(vaultID); err != nil {
+		if err := idx.FullIndexVaultContext(ctx, vaultID); err != nil {
+			if ctx.Err() != nil {
+				log.Printf("Indexing of %s interrupted, exiting", vaultPath)
+				return
+			}
 			log.Fatalf("Failed to index vault %s: %v", vaultPath, err)
 		}
 
@@ -97,9 +205,46 @@ func main() {
 			log.Fatalf("Failed to create watcher for %s: %v", vaultPath, err)
 		}
 		watchers = append(watchers, w)
+		watcherByVault[vaultID] = w
 	}
 
 	srv := api.NewServer(s, idx, ps, api.EmbeddedFS(), cfg.Port, cfg.HomeGraph)
+	srv.SetMetadataSchema(cfg.MetadataSchema)
+	srv.SetViewTracking(cfg.TrackViews)
+	srv.SetMaxBodySize(int64(cfg.MaxBodyMB) << 20)
+	srv.SetMaxCoordinate(cfg.MaxCoordinate)
+	profiles := make(map[string]api.PruningProfile, len(cfg.PruningProfiles))
+	for name, p := range cfg.PruningProfiles {
+		profiles[name] = api.PruningProfile(p)
+	}
+	if err := srv.SetPruningProfiles(profiles); err != nil {
+		log.Fatalf("Invalid pruning profiles: %v", err)
+	}
+
+	layouts := layout.NewRunner(s)
+	if n, err := layouts.RecoverInterruptedJobs(); err != nil {
+		log.Printf("Warning: failed to recover interrupted layout jobs: %v", err)
+	} else if n > 0 {
+		log.Printf("Marked %d interrupted layout job(s) as failed", n)
+	}
+	layouts.SetOnComplete(func(graphID int) {
+		ps.MarkDirty(graphID)
+		srv.NotifyChange([]int{graphID})
+	})
+	srv.SetLayoutRunner(layouts)
+	acls, err := s.GetNodeACLs()
+	if err != nil {
+		log.Fatalf("Failed to load access control lists: %v", err)
+	}
+	policy.LoadACLs(acls)
+	srv.SetAccessPolicy(policy)
+	if len(cfg.Auth.Users) > 0 {
+		tokens := make(map[string]access.User, len(cfg.Auth.Users))
+		for _, u := range cfg.Auth.Users {
+			tokens[u.Token] = access.User{Name: u.Name, Roles: u.Roles}
+		}
+		srv.SetAuthenticator(access.NewAuthenticator(tokens))
+	}
 
 	// Start watchers with SSE notification
 	for _, w := range watchers {
@@ -113,7 +258,38 @@ func main() {
 			log.Fatalf("Failed to start watcher: %v", err)
 		}
 	}
+
+	// Pull vaults kept in git; the watchers index what the pulls changed
+	gitSync := git.NewManager(cfg.Git.PollInterval)
+	if cfg.Git.PollInterval > 0 {
+		for _, vaultPath := range cfg.Vaults {
+			if err := gitSync.AddVault(idx.GetVaultID(vaultPath), vaultPath); err != nil {
+				log.Printf("Not polling %s for git changes: %v", vaultPath, err)
+			}
+		}
+		gitSync.SetOnUpdate(func(vaultID int, changed []string) {
+			watcherByVault[vaultID].Notify(changed)
+		})
+		gitSync.Start()
+	}
+
+	// Find dead external links in the background
+	links := linkcheck.NewChecker(s, cfg.LinkCheck.Interval)
+	if cfg.LinkCheck.Interval > 0 {
+		links.Start()
+	}
+
+	// Find near-duplicate notes in the background
+	dups := duplicates.NewDetector(s, cfg.Duplicates.Interval, cfg.Duplicates.Threshold)
+	if cfg.Duplicates.Interval > 0 {
+		dups.Start()
+	}
+
 	defer func() {
+		dups.Stop()
+		links.Stop()
+		gitSync.Stop()
+		layouts.Shutdown()
 		ps.Shutdown()
 		for _, w := range watchers {
 			w.Stop()
@@ -121,15 +297,14 @@ func main() {
 	}()
 
 	httpServer := &http.Server{
-		Addr:    fmt.Sprintf(":%d", cfg.Port),
-		Handler: srv.Handler(),
+		Addr:        fmt.Sprintf(":%d", cfg.Port),
+		Handler:     srv.Handler(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
 	// Graceful shutdown on signal
 	go func() {
-		sig := make(chan os.Signal, 1)
-		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
-		<-sig
+		<-ctx.Done()
 		fmt.Println("\nShutting down...")
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -176,6 +351,119 @@ func bootstrapConfig(cfgPath string) error {
 	return nil
 }
 
+// configureIndexer gives idx the graph building and parsing settings of cfg.
+func configureIndexer(idx *indexer.IndexManager, cfg *config.Config) error {
+	computed, err := vault.CompileComputedFields(cfg.ComputedFields)
+	if err != nil {
+		return fmt.Errorf("computed fields: %w", err)
+	}
+	idx.SetComputedFields(computed)
+	idx.SetEdgeWeighting(cfg.EdgeWeights.DecayHalfLife, cfg.EdgeWeights.Normalize)
+	idx.SetHubTypes(cfg.Graph.HubTypes)
+	idx.SetUnlinkedMentions(cfg.Graph.UnlinkedMentions)
+	idx.SetPositionRetention(cfg.Graph.PositionRetention)
+	parseOpts, err := parseOptions(cfg)
+	if err != nil {
+		return err
+	}
+	idx.SetParseOptions(parseOpts)
+	if cfg.Slugs != nil {
+		for _, vaultPath := range cfg.Vaults {
+			sc := cfg.Slugs.For(vaultPath)
+			opts := parseOpts
+			opts.Slugger = &vault.Slugger{Unicode: sc.Unicode, Separator: sc.Separator, MaxLength: sc.MaxLength}
+			opts.SlugIDs = sc.IDs
+			idx.SetVaultParseOptions(vaultPath, opts)
+		}
+	}
+	idx.SetMaxWorkers(cfg.Parser.MaxWorkers)
+	idx.SetMemoryBudget(int64(cfg.Parser.MemoryBudgetMB) << 20)
+	return nil
+}
+
+// parseOptions builds the markdown parsing options cfg describes, apart
+// from the per-vault slug settings.
+func parseOptions(cfg *config.Config) (vault.ParseOptions, error) {
+	dialect, err := vault.LookupDialect(cfg.Parser.Dialect)
+	if err != nil {
+		return vault.ParseOptions{}, err
+	}
+	if cfg.Parser.Wikilinks != nil {
+		dialect.Wikilinks = *cfg.Parser.Wikilinks
+	}
+	if cfg.Parser.StripComments != nil {
+		dialect.StripComments = *cfg.Parser.StripComments
+	}
+	if cfg.Parser.FrontmatterDelimiter != "" {
+		dialect.FrontmatterDelimiter = cfg.Parser.FrontmatterDelimiter
+	}
+	opts := vault.ParseOptions{
+		Dialect:            &dialect,
+		ExcerptSentences:   cfg.Parser.ExcerptSentences,
+		LenientFrontmatter: cfg.Parser.LenientFrontmatter,
+		Symlinks:           vault.SymlinkPolicy(cfg.Parser.Symlinks),
+		MaxFileSize:        int64(cfg.Graph.MaxFileSizeMB) << 20,
+	}
+	for _, le := range cfg.LinkExtractors {
+		ex, err := vault.NewLinkExtractor(le.Pattern, le.EdgeType, le.Target)
+		if err != nil {
+			return opts, fmt.Errorf("link extractor: %w", err)
+		}
+		opts.LinkExtractors = append(opts.LinkExtractors, *ex)
+	}
+	if cfg.Mentions != nil {
+		opts.Mentions, err = vault.NewMentionExtractor(cfg.Mentions.Pattern, cfg.Mentions.PeopleDir)
+		if err != nil {
+			return opts, fmt.Errorf("mentions: %w", err)
+		}
+	}
+	for _, r := range cfg.IDRules {
+		rule, err := vault.NewIDRule(r.Pattern, r.Template)
+		if err != nil {
+			return opts, fmt.Errorf("id rule: %w", err)
+		}
+		opts.IDRules = append(opts.IDRules, *rule)
+	}
+	for _, r := range cfg.Redactions {
+		replacement := vault.DefaultRedaction
+		if r.Replacement != nil {
+			replacement = *r.Replacement
+		}
+		rule, err := vault.NewRedaction(r.Pattern, replacement)
+		if err != nil {
+			return opts, fmt.Errorf("redaction: %w", err)
+		}
+		opts.Redactions = append(opts.Redactions, *rule)
+	}
+	for _, r := range cfg.Classify {
+		opts.Rules.Classify = append(opts.Rules.Classify, vault.ClassifyRule{Path: r.Path, Tag: r.Tag, Type: r.Type})
+	}
+	opts.Rules.Ignore = cfg.Ignore
+	return opts, nil
+}
+
+// openStore opens the database, migrating its schema unless --no-migrate
+// was given.
+func openStore(dbPath string) (*store.Store, error) {
+	if noMigrate {
+		return store.NewNoMigrate(dbPath)
+	}
+	return store.New(dbPath)
+}
+
+func cmdMigrate() {
+	dbPath := config.DBPath()
+	from, to, err := store.Migrate(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	if from == to {
+		fmt.Printf("Database %s is up to date (schema version %d).\n", dbPath, to)
+		return
+	}
+	fmt.Printf("Migrated database %s from schema version %d to %d.\n", dbPath, from, to)
+}
+
 func cmdGraphs(args []string) {
 	if len(args) > 0 && args[0] == "delete" {
 		cmdGraphsDelete(args[1:])
@@ -183,7 +471,7 @@ func cmdGraphs(args []string) {
 	}
 
 	dbPath := config.DBPath()
-	s, err := store.New(dbPath)
+	s, err := openStore(dbPath)
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
@@ -225,7 +513,7 @@ func cmdGraphsDelete(args []string) {
 	}
 
 	dbPath := config.DBPath()
-	s, err := store.New(dbPath)
+	s, err := openStore(dbPath)
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
@@ -255,3 +543,301 @@ func cmdGraphsDelete(args []string) {
 
 	fmt.Printf("Permanently deleted graph %d (%s/%s).\n", info.ID, info.VaultName, info.Name)
 }
+
+func cmdPositions(args []string) {
+	if len(args) == 0 || args[0] != "remap" {
+		fmt.Fprintln(os.Stderr, "Usage: mnemosyne positions remap <old-id> <new-id>")
+		fmt.Fprintln(os.Stderr, "       mnemosyne positions remap --csv <file>")
+		os.Exit(1)
+	}
+	args = args[1:]
+
+	var remap map[string]string
+	var err error
+	switch {
+	case len(args) == 2 && args[0] == "--csv":
+		f, ferr := os.Open(args[1])
+		if ferr != nil {
+			log.Fatalf("Failed to open %s: %v", args[1], ferr)
+		}
+		remap, err = positionsync.ReadRemapCSV(f)
+		f.Close()
+	case len(args) == 2:
+		remap, err = positionsync.ValidateRemap([][2]string{{args[0], args[1]}})
+	default:
+		cmdPositions(nil)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid remapping: %v\n", err)
+		os.Exit(1)
+	}
+
+	dbPath := config.DBPath()
+	s, err := openStore(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer s.Close()
+
+	graphIDs, moved, err := s.RemapPositions(remap)
+	if err != nil {
+		log.Fatalf("Failed to remap positions: %v", err)
+	}
+
+	// Rewrite the positions files of affected graphs
+	ps := positionsync.New(s)
+	for _, id := range graphIDs {
+		ps.MarkDirty(id)
+	}
+	ps.Shutdown()
+
+	fmt.Printf("Remapped %d node ID(s): moved %d position(s) across %d graph(s).\n", len(remap), moved, len(graphIDs))
+}
+
+// cmdSeedDemo generates a synthetic vault with a config using it, and
+// indexes it, for demos and frontend development.
+func cmdSeedDemo(args []string) {
+	fs := flag.NewFlagSet("seed-demo", flag.ExitOnError)
+	dir := fs.String("dir", "mnemosyne-demo", "Directory to create the vault and its config in")
+	opts := synth.DefaultOptions(0)
+	fs.IntVar(&opts.Notes, "notes", 1000, "Number of notes")
+	fs.Float64Var(&opts.HubRatio, "hub-ratio", opts.HubRatio, "Fraction of notes that are hubs, which most links point to")
+	fs.IntVar(&opts.Tags, "tags", opts.Tags, "Number of distinct tags; a few are common and most are rare")
+	fs.IntVar(&opts.TagsPerNote, "tags-per-note", opts.TagsPerNote, "Maximum tags on a note")
+	fs.Uint64Var(&opts.Seed, "seed", opts.Seed, "Random seed; the same seed generates the same vault")
+	fs.Parse(args)
+
+	root, err := filepath.Abs(*dir)
+	if err != nil {
+		log.Fatalf("Invalid directory: %v", err)
+	}
+	vaultPath := filepath.Join(root, "demo")
+	cfgPath := filepath.Join(root, "config.yaml")
+	if _, err := os.Stat(cfgPath); err == nil {
+		log.Fatalf("%s already exists; choose another --dir", cfgPath)
+	}
+
+	stats, err := synth.Generate(vaultPath, opts)
+	if err != nil {
+		log.Fatalf("Failed to generate vault: %v", err)
+	}
+	if err := config.CreateDefault(cfgPath, vaultPath); err != nil {
+		log.Fatalf("Failed to write config: %v", err)
+	}
+	fmt.Printf("Generated %d notes (%d hubs, %d links, %d tags) in %s\n", stats.Notes, stats.Hubs, stats.Links, stats.Tags, vaultPath)
+
+	s, err := openStore(config.DBPath())
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer s.Close()
+	idx := indexer.NewIndexManager(s)
+	vaultID, _, err := idx.RegisterVault(vaultPath)
+	if err != nil {
+		log.Fatalf("Failed to register vault: %v", err)
+	}
+	start := time.Now()
+	if err := idx.FullIndexVault(vaultID); err != nil {
+		log.Fatalf("Failed to index vault: %v", err)
+	}
+	graphs, err := s.GetGraphsByVault(vaultID)
+	if err != nil {
+		log.Fatalf("Failed to list graphs: %v", err)
+	}
+	for _, g := range graphs {
+		fmt.Printf("Indexed graph %d (%s/%s): %d nodes in %s\n", g.ID, g.VaultName, g.Name, g.NodeCount, time.Since(start).Round(time.Millisecond))
+	}
+
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	fmt.Printf("\nStart the server with:\n\n  mnemosyne %s\n\nthen open http://localhost:%d\n", cfgPath, cfg.Port)
+}
+
+// cmdBench indexes generated vaults of increasing size and reports the time
+// and allocations of each phase.
+func cmdBench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	sizes := fs.String("sizes", "1000,10000,100000", "Comma-separated vault sizes, in notes")
+	seed := fs.Uint64("seed", 1, "Random seed for the generated vaults")
+	asJSON := fs.Bool("json", false, "Print results as JSON")
+	fs.Parse(args)
+
+	var notes []int
+	for _, f := range strings.Split(*sizes, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 1 {
+			fmt.Fprintf(os.Stderr, "Invalid size: %q\n", f)
+			os.Exit(1)
+		}
+		notes = append(notes, n)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// The indexer's progress logging would be measured too
+	log.SetOutput(io.Discard)
+	var results []*synth.BenchResult
+	for _, n := range notes {
+		opts := synth.DefaultOptions(n)
+		opts.Seed = *seed
+		fmt.Fprintf(os.Stderr, "Indexing %d notes...\n", n)
+		res, err := synth.Benchmark(ctx, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Benchmark of %d notes failed: %v\n", n, err)
+			os.Exit(1)
+		}
+		results = append(results, res)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(results)
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Notes\tEdges\tPhase\tTime (ms)\tAlloc (MB)\tAllocs\t")
+	for _, res := range results {
+		for _, p := range append(res.Phases, res.Total) {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%.1f\t%.1f\t%d\t\n", res.Nodes, res.Edges, p.Phase, p.DurationMS, float64(p.AllocBytes)/(1<<20), p.Allocs)
+		}
+	}
+	w.Flush()
+	fmt.Println()
+	for _, res := range results {
+		fmt.Printf("%d notes: %.0f files/s\n", res.Options.Notes, res.FilesPerSecond)
+	}
+}
+
+// cmdDoctor checks the config, database, vaults, git remotes and
+// classification rules the server would start with, printing a checklist,
+// and exits non-zero if any check fails.
+func cmdDoctor(args []string, profile string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	fs.StringVar(&profile, "profile", profile, "Config profile to merge over the config file (default $"+config.ProfileEnv+")")
+	fs.Parse(args)
+
+	cfgPath := config.DefaultConfigPath()
+	if fs.NArg() > 0 {
+		cfgPath = fs.Arg(0)
+	}
+	if profile == "" {
+		profile = os.Getenv(config.ProfileEnv)
+	}
+	checks := doctor.Run(context.Background(), doctor.Options{
+		ConfigPath: cfgPath,
+		Profile:    profile,
+		DBPath:     config.DBPath(),
+		NoMigrate:  noMigrate,
+	})
+	if doctor.Print(os.Stdout, checks) > 0 {
+		os.Exit(1)
+	}
+}
+
+// cmdExport parses a vault and builds its graph without a database, writing
+// it as JSON, for static sites, CI checks and debugging classification.
+func cmdExport(args []string, profile string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("o", "graph.json", "File to write the graph to; - for stdout")
+	cfgPath := fs.String("config", "", "Config whose parser, classification, computed field and edge weight settings to use (default none)")
+	alg := fs.String("layout", "", "Also compute node positions with this layout: force-directed, hierarchical or radial")
+	content := fs.Bool("content", false, "Include each note's markdown content")
+	report := fs.Bool("report", false, "Print the parse report to stderr")
+	strict := fs.Bool("strict", false, "Exit 1 if any file fails to parse or any link resolves to nothing")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: mnemosyne export [flags] <vault-path>")
+		os.Exit(2)
+	}
+	vaultPath, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Invalid vault path: %v", err)
+	}
+	var algorithm layout.Algorithm
+	if *alg != "" {
+		if algorithm, err = layout.ParseAlgorithm(*alg); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
+	idx := indexer.NewIndexManager(nil)
+	if *cfgPath != "" {
+		if profile == "" {
+			profile = os.Getenv(config.ProfileEnv)
+		}
+		cfg, err := config.LoadProfile(*cfgPath, profile)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		if err := configureIndexer(idx, cfg); err != nil {
+			log.Fatalf("Invalid config: %v", err)
+		}
+		if len(cfg.Scripts) > 0 {
+			scripts, err := scripting.LoadLua(cfg.Scripts)
+			if err != nil {
+				log.Fatalf("Failed to load scripts: %v", err)
+			}
+			defer scripts.Close()
+			idx.AddHook(scripts)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	exp, err := idx.Export(ctx, vaultPath)
+	if err != nil {
+		log.Fatalf("Failed to export %s: %v", vaultPath, err)
+	}
+	if algorithm != "" {
+		nodes := make([]layout.Node, len(exp.Nodes))
+		for i, n := range exp.Nodes {
+			nodes[i] = layout.Node{ID: n.ID, Path: n.FilePath}
+		}
+		edges := make([]layout.Edge, len(exp.Edges))
+		for i, e := range exp.Edges {
+			edges[i] = layout.Edge{Source: e.SourceID, Target: e.TargetID}
+		}
+		if exp.Positions, err = layout.Compute(ctx, algorithm, nodes, edges, nil); err != nil {
+			log.Fatalf("Failed to lay out graph: %v", err)
+		}
+	}
+	if !*content {
+		for i := range exp.Nodes {
+			exp.Nodes[i].Content = ""
+		}
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(exp); err != nil {
+		log.Fatalf("Failed to write graph: %v", err)
+	}
+
+	unresolved := 0
+	for _, n := range exp.Nodes {
+		unresolved += len(n.Unresolved)
+	}
+	if *report {
+		fmt.Fprintln(os.Stderr, exp.Report)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d nodes and %d edges; %d files failed to parse, %d links resolve to nothing\n",
+		len(exp.Nodes), len(exp.Edges), len(exp.Errors), unresolved)
+	if *strict && (len(exp.Errors) > 0 || unresolved > 0) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,242 @@
+// Package access implements request authentication and node visibility rules.
+// Visibility is decided here, in one place, and applied by the API to every
+// response that exposes nodes.
+package access
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// User is an authenticated API user.
+type User struct {
+	Name  string
+	Roles []string
+}
+
+// matches reports whether any ACL principal names u or one of u's roles.
+func (u *User) matches(principals []string) bool {
+	for _, p := range principals {
+		if p == u.Name {
+			return true
+		}
+		for _, r := range u.Roles {
+			if p == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Authenticator maps bearer tokens to users.
+type Authenticator struct {
+	tokens map[string]*User
+}
+
+// NewAuthenticator creates an authenticator from a token -> user map.
+func NewAuthenticator(tokens map[string]User) *Authenticator {
+	a := &Authenticator{tokens: make(map[string]*User, len(tokens))}
+	for token, u := range tokens {
+		u := u
+		a.tokens[token] = &u
+	}
+	return a
+}
+
+// BearerProtocol is the WebSocket subprotocol that carries a bearer token for
+// clients, such as browsers, that cannot set the Authorization header on an
+// upgrade request. They offer the protocols ["bearer", token] and the server
+// accepts "bearer".
+const BearerProtocol = "bearer"
+
+// Authenticate returns the user for the request's bearer token, taken from
+// the Authorization header or, failing that, the WebSocket subprotocols. It
+// returns (nil, true) for anonymous requests and (nil, false) for an invalid
+// token.
+func (a *Authenticator) Authenticate(r *http.Request) (*User, bool) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return a.authenticateProtocol(r)
+	}
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return nil, false
+	}
+	return a.lookup(token)
+}
+
+// authenticateProtocol reads the token that follows BearerProtocol in the
+// Sec-WebSocket-Protocol header.
+func (a *Authenticator) authenticateProtocol(r *http.Request) (*User, bool) {
+	var protocols []string
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			protocols = append(protocols, strings.TrimSpace(p))
+		}
+	}
+	i := slices.Index(protocols, BearerProtocol)
+	if i < 0 {
+		return nil, true
+	}
+	if i+1 == len(protocols) {
+		return nil, false
+	}
+	return a.lookup(protocols[i+1])
+}
+
+func (a *Authenticator) lookup(token string) (*User, bool) {
+	for known, u := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+type userKey struct{}
+
+// WithUser returns a context carrying u.
+func WithUser(ctx context.Context, u *User) context.Context {
+	return context.WithValue(ctx, userKey{}, u)
+}
+
+// UserFromContext returns the authenticated user, or nil for anonymous requests.
+func UserFromContext(ctx context.Context) *User {
+	u, _ := ctx.Value(userKey{}).(*User)
+	return u
+}
+
+// Policy decides which nodes a user may see. The zero value allows everything.
+//
+// A node with an access control list (ACL) is visible only to users named in
+// it, directly or through one of their roles; anonymous users never see it.
+// ACLs assigned through the API take precedence over the frontmatter field.
+type Policy struct {
+	// PublishFlag, when set, hides nodes without an ACL from anonymous users
+	// unless their frontmatter sets this field to true.
+	PublishFlag string
+
+	// ACLField, when set, names a frontmatter field listing the users and
+	// roles allowed to see the node.
+	ACLField string
+
+	// AdminRole names the role whose users may assign ACLs through the API.
+	// Empty means no user may.
+	AdminRole string
+
+	mu   sync.RWMutex
+	acls map[string][]string // node ID -> principals assigned through the API
+}
+
+// CanView reports whether u (nil for anonymous) may see n.
+func (p *Policy) CanView(u *User, n *models.VaultNode) bool {
+	if p == nil {
+		return true
+	}
+	if acl, _ := p.NodeACL(n); acl != nil {
+		return u != nil && u.matches(acl)
+	}
+	if u == nil && p.PublishFlag != "" {
+		return IsPublished(n.Metadata, p.PublishFlag)
+	}
+	return true
+}
+
+// Restricts reports whether p may hide any node from u (nil for anonymous).
+func (p *Policy) Restricts(u *User) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.acls) > 0 || p.ACLField != "" || (u == nil && p.PublishFlag != "")
+}
+
+// FilterNodes returns the nodes u may see, preserving order.
+func (p *Policy) FilterNodes(u *User, nodes []models.VaultNode) []models.VaultNode {
+	out := nodes[:0:0]
+	for i := range nodes {
+		if p.CanView(u, &nodes[i]) {
+			out = append(out, nodes[i])
+		}
+	}
+	return out
+}
+
+// ACL sources reported by NodeACL.
+const (
+	ACLSourceAPI         = "api"
+	ACLSourceFrontmatter = "frontmatter"
+)
+
+// NodeACL returns the principals allowed to see n and where they came from.
+// It returns a nil slice when the node has no ACL, and an empty one when the
+// frontmatter field is set but names nobody (e.g. "acl: {}"), which hides the
+// node from everyone rather than leaving it public.
+func (p *Policy) NodeACL(n *models.VaultNode) ([]string, string) {
+	if p == nil {
+		return nil, ""
+	}
+	p.mu.RLock()
+	acl, ok := p.acls[n.ID]
+	p.mu.RUnlock()
+	if ok {
+		return acl, ACLSourceAPI
+	}
+	if _, set := n.Metadata[p.ACLField]; set && p.ACLField != "" {
+		raw, _ := n.Metadata.GetStringSlice(p.ACLField)
+		acl := make([]string, 0, len(raw))
+		for _, principal := range raw {
+			if principal = strings.TrimSpace(principal); principal != "" {
+				acl = append(acl, principal)
+			}
+		}
+		return acl, ACLSourceFrontmatter
+	}
+	return nil, ""
+}
+
+// IsAdmin reports whether u (nil for anonymous) has the admin role.
+func (p *Policy) IsAdmin(u *User) bool {
+	return p != nil && p.AdminRole != "" && u != nil && slices.Contains(u.Roles, p.AdminRole)
+}
+
+// SetNodeACL assigns the principals allowed to see a node. An empty list
+// removes the assignment, falling back to the frontmatter field.
+func (p *Policy) SetNodeACL(nodeID string, principals []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(principals) == 0 {
+		delete(p.acls, nodeID)
+		return
+	}
+	if p.acls == nil {
+		p.acls = make(map[string][]string)
+	}
+	p.acls[nodeID] = principals
+}
+
+// LoadACLs replaces all API-assigned ACLs, e.g. with those persisted in the store.
+func (p *Policy) LoadACLs(acls map[string][]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.acls = acls
+}
+
+// IsPublished reports whether metadata sets flag to true (or "true").
+func IsPublished(metadata models.JSONMetadata, flag string) bool {
+	switch v := metadata[flag].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package access
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+func TestAuthenticate(t *testing.T) {
+	a := NewAuthenticator(map[string]User{"secret": {Name: "alice"}})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	u, ok := a.Authenticate(r)
+	assert.True(t, ok)
+	assert.Nil(t, u, "no header is anonymous")
+
+	r.Header.Set("Authorization", "Bearer secret")
+	u, ok = a.Authenticate(r)
+	require.True(t, ok)
+	assert.Equal(t, "alice", u.Name)
+
+	r.Header.Set("Authorization", "Bearer wrong")
+	_, ok = a.Authenticate(r)
+	assert.False(t, ok)
+
+	r.Header.Set("Authorization", "Basic secret")
+	_, ok = a.Authenticate(r)
+	assert.False(t, ok)
+	// WebSocket clients may pass the token as a subprotocol
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Sec-WebSocket-Protocol", "bearer, secret")
+	u, ok = a.Authenticate(r)
+	require.True(t, ok)
+	assert.Equal(t, "alice", u.Name)
+
+	r.Header.Set("Sec-WebSocket-Protocol", "bearer, wrong")
+	_, ok = a.Authenticate(r)
+	assert.False(t, ok)
+
+	r.Header.Set("Sec-WebSocket-Protocol", "bearer")
+	_, ok = a.Authenticate(r)
+	assert.False(t, ok, "missing token")
+
+	r.Header.Set("Sec-WebSocket-Protocol", "graph.v1")
+	u, ok = a.Authenticate(r)
+	assert.True(t, ok)
+	assert.Nil(t, u, "other subprotocols are anonymous")
+}
+
+func TestPolicy_PublishFlag(t *testing.T) {
+	p := &Policy{PublishFlag: "publish"}
+	alice := &User{Name: "alice"}
+
+	published := &models.VaultNode{ID: "a", Metadata: models.JSONMetadata{"publish": true}}
+	draft := &models.VaultNode{ID: "b", Metadata: models.JSONMetadata{"publish": false}}
+
+	assert.True(t, p.CanView(nil, published))
+	assert.True(t, p.CanView(nil, &models.VaultNode{Metadata: models.JSONMetadata{"publish": "true"}}))
+	assert.False(t, p.CanView(nil, draft))
+	assert.False(t, p.CanView(nil, &models.VaultNode{}))
+	assert.True(t, p.CanView(alice, draft), "authenticated users see everything")
+	assert.True(t, p.Restricts(nil))
+	assert.False(t, p.Restricts(alice))
+
+	var open *Policy
+	assert.True(t, open.CanView(nil, draft), "nil policy allows everything")
+	assert.False(t, open.Restricts(nil))
+
+	nodes := []models.VaultNode{*published, *draft}
+	visible := p.FilterNodes(nil, nodes)
+	require.Len(t, visible, 1)
+	assert.Equal(t, "a", visible[0].ID)
+	assert.Len(t, nodes, 2, "input is not modified")
+}
+
+func TestPolicy_ACL(t *testing.T) {
+	p := &Policy{PublishFlag: "publish", ACLField: "access"}
+	alice := &User{Name: "alice"}
+	bob := &User{Name: "bob", Roles: []string{"editors"}}
+	carol := &User{Name: "carol"}
+
+	n := &models.VaultNode{ID: "n", Metadata: models.JSONMetadata{
+		"publish": true,
+		"access":  []interface{}{"alice", "editors"},
+	}}
+
+	assert.False(t, p.CanView(nil, n), "ACL overrides the publish flag")
+	assert.True(t, p.CanView(alice, n))
+	assert.True(t, p.CanView(bob, n), "role match")
+	assert.False(t, p.CanView(carol, n))
+
+	acl, source := p.NodeACL(n)
+	assert.Equal(t, []string{"alice", "editors"}, acl)
+	assert.Equal(t, ACLSourceFrontmatter, source)
+
+	// API-assigned ACLs take precedence over frontmatter
+	p.SetNodeACL("n", []string{"carol"})
+	assert.False(t, p.CanView(alice, n))
+	assert.True(t, p.CanView(carol, n))
+	_, source = p.NodeACL(n)
+	assert.Equal(t, ACLSourceAPI, source)
+
+	p.SetNodeACL("n", nil)
+	assert.True(t, p.CanView(alice, n))
+	assert.True(t, p.Restricts(alice), "frontmatter ACLs may hide nodes from anyone")
+	assert.False(t, (&Policy{}).Restricts(nil), "the zero policy hides nothing")
+	assert.False(t, p.CanView(carol, n))
+}
+
+func TestPolicy_MalformedACL(t *testing.T) {
+	p := &Policy{PublishFlag: "publish", ACLField: "access"}
+	alice := &User{Name: "alice", Roles: []string{"editors"}}
+
+	for _, value := range []interface{}{map[string]interface{}{}, []interface{}{""}, nil, "  "} {
+		n := &models.VaultNode{ID: "n", Metadata: models.JSONMetadata{"publish": true, "access": value}}
+		assert.False(t, p.CanView(alice, n), "malformed ACL %#v denies", value)
+		assert.False(t, p.CanView(nil, n), "malformed ACL %#v denies", value)
+		acl, source := p.NodeACL(n)
+		assert.Empty(t, acl)
+		assert.Equal(t, ACLSourceFrontmatter, source)
+	}
+}
+
+func TestPolicy_IsAdmin(t *testing.T) {
+	admin := &User{Name: "alice", Roles: []string{"admins"}}
+	assert.True(t, (&Policy{AdminRole: "admins"}).IsAdmin(admin))
+	assert.False(t, (&Policy{AdminRole: "admins"}).IsAdmin(&User{Name: "bob"}))
+	assert.False(t, (&Policy{AdminRole: "admins"}).IsAdmin(nil))
+	assert.False(t, (&Policy{}).IsAdmin(admin), "no admin role configured")
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// activityBucket counts notes created and last modified in one period.
+type activityBucket struct {
+	Start    string `json:"start"` // First day of the period, YYYY-MM-DD (UTC)
+	Created  int    `json:"created"`
+	Modified int    `json:"modified"`
+}
+
+// bucketStart returns the first day of the period containing t: the day
+// itself, the Monday of its ISO week, or the first of its month.
+func bucketStart(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case "week":
+		offset := (int(day.Weekday()) + 6) % 7 // Days since Monday
+		return day.AddDate(0, 0, -offset)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// handleGraphActivity aggregates note creation and modification times into
+// periods for an activity heatmap. Only each note's latest modification is
+// known, so a note counts once as modified. Query: granularity (day, week
+// (default) or month), graph_id (default all notes).
+func (s *Server) handleGraphActivity(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	granularity := q.Get("granularity")
+	switch granularity {
+	case "":
+		granularity = "week"
+	case "day", "week", "month":
+	default:
+		writeError(w, r, CodeBadRequest, "Granularity must be day, week or month")
+		return
+	}
+
+	var nodes []models.VaultNode
+	if v := q.Get("graph_id"); v != "" {
+		graphID, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, CodeBadRequest, "Invalid graph ID")
+			return
+		}
+		raw, err := s.store.GetGraphDataRaw(graphID)
+		if err != nil {
+			writeError(w, r, CodeNotFound, "Graph not found")
+			return
+		}
+		nodes = raw.Nodes
+	} else {
+		var err error
+		if nodes, err = s.store.GetAllNodes(); err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch nodes")
+			return
+		}
+	}
+
+	buckets := make(map[time.Time]*activityBucket)
+	bucket := func(t time.Time) *activityBucket {
+		start := bucketStart(t, granularity)
+		b, ok := buckets[start]
+		if !ok {
+			b = &activityBucket{Start: start.Format(time.DateOnly)}
+			buckets[start] = b
+		}
+		return b
+	}
+	for _, n := range s.visibleNodes(r, nodes) {
+		if !n.CreatedAt.IsZero() {
+			bucket(n.CreatedAt).Created++
+		}
+		if !n.UpdatedAt.IsZero() {
+			bucket(n.UpdatedAt).Modified++
+		}
+	}
+
+	out := make([]activityBucket, 0, len(buckets))
+	for _, b := range buckets {
+		out = append(out, *b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start < out[j].Start })
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"granularity": granularity,
+		"buckets":     out,
+	})
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/indexer"
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/vault"
+)
+
+// handleFlushCaches drops the cached graphs of past commits and every vault's
+// cached parsed files, so the next history request and the next index start
+// from scratch.
+func (s *Server) handleFlushCaches(w http.ResponseWriter, r *http.Request) {
+	historical := 0
+	if s.indexer != nil {
+		historical = s.indexer.FlushHistoryCache()
+	}
+	files, err := s.store.ClearFileCaches()
+	if err != nil {
+		log.Printf("Failed to clear file caches: %v", err)
+		writeError(w, r, CodeInternal, "Failed to clear file caches")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{
+		"historical_graphs": historical,
+		"cached_files":      files,
+	})
+}
+
+// handleReleaseParseLock lets a new full index start while one appears stuck.
+// The stuck run is recorded as failed; it is not stopped.
+func (s *Server) handleReleaseParseLock(w http.ResponseWriter, r *http.Request) {
+	if s.indexer == nil {
+		writeError(w, r, CodeInternal, "Indexer not configured")
+		return
+	}
+	id, err := s.indexer.ReleaseParseLock()
+	if err != nil {
+		log.Printf("Failed to record released parse %s: %v", id, err)
+	}
+	if id != "" {
+		log.Printf("Released parse lock of run %s", id)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"released": id != "",
+		"parse_id": id,
+	})
+}
+
+// handleRecomputeMetrics recomputes every node's in and out degree and
+// centrality from the stored edges, reporting how many nodes were corrected.
+func (s *Server) handleRecomputeMetrics(w http.ResponseWriter, r *http.Request) {
+	updated, err := s.store.RecomputeMetrics(r.Context())
+	if err != nil {
+		log.Printf("Failed to recompute metrics: %v", err)
+		writeError(w, r, CodeInternal, "Failed to recompute metrics")
+		return
+	}
+	if updated > 0 {
+		graphs, err := s.store.GetAllGraphs()
+		if err != nil {
+			log.Printf("Failed to list graphs: %v", err)
+		}
+		ids := make([]int, len(graphs))
+		for i, g := range graphs {
+			ids[i] = g.ID
+		}
+		s.NotifyChange(ids)
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"updated": updated})
+}
+
+// handleReclassify runs the classification hooks again over the stored
+// nodes and updates their types, without a full parse, so changed rules take
+// effect at once.
+func (s *Server) handleReclassify(w http.ResponseWriter, r *http.Request) {
+	if s.indexer == nil {
+		writeError(w, r, CodeInternal, "Indexer not configured")
+		return
+	}
+	res, err := s.indexer.Reclassify(r.Context())
+	if err != nil {
+		if errors.Is(err, indexer.ErrIndexRunning) {
+			writeError(w, r, CodeIndexRunning, "Full index running; reclassify after it finishes")
+			return
+		}
+		log.Printf("Failed to reclassify nodes: %v", err)
+		writeError(w, r, CodeInternal, "Failed to reclassify nodes")
+		return
+	}
+	if len(res.GraphIDs) > 0 {
+		s.NotifyChange(res.GraphIDs)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// handleVacuum analyzes the graph tables and vacuums the database. Writes
+// wait while it runs.
+func (s *Server) handleVacuum(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if err := s.store.Optimize(r.Context()); err != nil {
+		log.Printf("Failed to optimize database: %v", err)
+		writeError(w, r, CodeInternal, "Failed to optimize database")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"duration_ms": time.Since(start).Milliseconds()})
+}
+
+// handlePurgePositions deletes the saved positions of nodes missing from at
+// least the last parses (default 1) full indexes of their vault. Positions
+// outlive their nodes so notes that come back keep their places.
+func (s *Server) handlePurgePositions(w http.ResponseWriter, r *http.Request) {
+	parses := 1
+	if v := r.URL.Query().Get("parses"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, r, CodeBadRequest, "parses must be a positive integer")
+			return
+		}
+		parses = n
+	}
+	purged, err := s.store.PurgeAbsentPositions(r.Context(), parses)
+	if err != nil {
+		log.Printf("Failed to purge positions: %v", err)
+		writeError(w, r, CodeInternal, "Failed to purge positions")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"purged": purged})
+}
+
+// vaultHealth is the health of one vault, with its name.
+type vaultHealth struct {
+	Vault string `json:"vault"`
+	models.VaultHealth
+}
+
+// handleVaultHealth reports each vault's health score, from its orphaned
+// notes, broken links, duplicate IDs and untagged notes as of its last full
+// index, with recommendations naming a few of the files involved, and the
+// vaults' overall score, weighted by their notes. Vaults not yet fully
+// indexed are left out.
+func (s *Server) handleVaultHealth(w http.ResponseWriter, r *http.Request) {
+	vaults, err := s.store.GetVaults()
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch vaults")
+		return
+	}
+	out := make([]vaultHealth, 0, len(vaults))
+	var weighted, notes int
+	for _, v := range vaults {
+		h, err := s.store.GetVaultHealth(v.ID)
+		if err != nil {
+			log.Printf("Failed to fetch health of vault %s: %v", v.Path, err)
+			writeError(w, r, CodeInternal, "Failed to fetch vault health")
+			return
+		}
+		if h == nil {
+			continue
+		}
+		out = append(out, vaultHealth{Vault: v.Name, VaultHealth: *h})
+		weighted += h.Score * h.Notes
+		notes += h.Notes
+	}
+
+	resp := map[string]interface{}{"vaults": out}
+	if notes > 0 {
+		resp["score"] = int(math.Round(float64(weighted) / float64(notes)))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// vaultStorage is the space one vault's files take.
+type vaultStorage struct {
+	models.Vault
+	vault.DiskUsage
+	Error string `json:"error,omitempty"` // Why the vault could not be measured
+}
+
+// handleVaultStorage reports the space the vaults' files and the database
+// take, for watching growth and planning capacity: files and bytes of
+// markdown and of attachments per vault, and the bytes of each database
+// table and index.
+func (s *Server) handleVaultStorage(w http.ResponseWriter, r *http.Request) {
+	vaults, err := s.store.GetVaults()
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch vaults")
+		return
+	}
+	out := make([]vaultStorage, 0, len(vaults))
+	var total vault.DiskUsage
+	for _, v := range vaults {
+		vs := vaultStorage{Vault: v}
+		if vs.DiskUsage, err = vault.MeasureDisk(r.Context(), v.Path); err != nil {
+			if r.Context().Err() != nil {
+				return
+			}
+			log.Printf("Failed to measure vault %s: %v", v.Path, err)
+			vs.Error = "Failed to read vault"
+		}
+		total.MarkdownFiles += vs.MarkdownFiles
+		total.MarkdownBytes += vs.MarkdownBytes
+		total.AttachmentFiles += vs.AttachmentFiles
+		total.AttachmentBytes += vs.AttachmentBytes
+		out = append(out, vs)
+	}
+
+	db, err := s.store.StorageUsage(r.Context())
+	if err != nil {
+		if r.Context().Err() == nil {
+			log.Printf("Failed to measure database: %v", err)
+			writeError(w, r, CodeInternal, "Failed to measure database")
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"vaults":   out,
+		"total":    total,
+		"database": db,
+	})
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// defaultAggregateThreshold is the most nodes a folder may have before
+// ?aggregate=true collapses it into a super-node.
+const defaultAggregateThreshold = 100
+
+// aggregateGraph collapses g's large folders into super-nodes when
+// ?aggregate=true, with ?aggregate_threshold= overriding the most nodes a
+// folder keeps. It writes an error and returns false if the parameters are
+// not valid.
+func (s *Server) aggregateGraph(w http.ResponseWriter, r *http.Request, graphID int, g *models.Graph) bool {
+	q := r.URL.Query()
+	v := q.Get("aggregate")
+	if v == "" {
+		return true
+	}
+	on, err := strconv.ParseBool(v)
+	if err != nil {
+		writeError(w, r, CodeBadRequest, "Invalid aggregate")
+		return false
+	}
+	if !on {
+		return true
+	}
+	threshold := defaultAggregateThreshold
+	if v := q.Get("aggregate_threshold"); v != "" {
+		if threshold, err = strconv.Atoi(v); err != nil || threshold < 1 {
+			writeError(w, r, CodeBadRequest, "Invalid aggregate_threshold")
+			return false
+		}
+	}
+	info, err := s.store.GetGraphInfo(graphID)
+	if err != nil {
+		writeError(w, r, CodeNotFound, "Graph not found")
+		return false
+	}
+	*g = aggregateFolders(g, info.RootPath, threshold)
+	return true
+}
+
+// aggregateFolders collapses each top-level folder under root with more
+// than threshold nodes into one super-node, with ID "folder:<folder>", at
+// the centroid of its nodes and at the highest level of theirs. Its
+// metadata counts the nodes and the edges between them, which are dropped;
+// edges to or from its nodes move to it, and edges that come to join the
+// same nodes with the same type are merged, adding up their weights. Notes
+// directly in root are never collapsed.
+func aggregateFolders(g *models.Graph, root string, threshold int) models.Graph {
+	members := make(map[string][]*models.Node)
+	for i := range g.Nodes {
+		if f := topFolder(g.Nodes[i].FilePath, root); f != "" {
+			members[f] = append(members[f], &g.Nodes[i])
+		}
+	}
+
+	superOf := make(map[string]string) // Node ID -> super-node ID
+	supers := make(map[string]*models.Node)
+	for folder, nodes := range members {
+		if len(nodes) <= threshold {
+			continue
+		}
+		super := &models.Node{
+			ID:       "folder:" + folder,
+			Title:    folder,
+			FilePath: path.Join(root, folder),
+			Level:    nodes[0].Level,
+			Color:    nodes[0].Color,
+			Metadata: map[string]interface{}{"type": "folder", "aggregate": true, "node_count": len(nodes), "internal_edges": 0},
+		}
+		for _, n := range nodes {
+			superOf[n.ID] = super.ID
+			super.Position.X += n.Position.X / float64(len(nodes))
+			super.Position.Y += n.Position.Y / float64(len(nodes))
+			super.Position.Z += n.Position.Z / float64(len(nodes))
+			super.WordCount += n.WordCount
+			super.ReadingTime += n.ReadingTime
+			super.Level = min(super.Level, n.Level)
+			if n.Color != super.Color {
+				super.Color = ""
+			}
+		}
+		supers[super.ID] = super
+	}
+
+	out := models.Graph{Nodes: make([]models.Node, 0, len(g.Nodes)), Edges: make([]models.Edge, 0, len(g.Edges))}
+	for _, n := range g.Nodes {
+		if _, ok := superOf[n.ID]; !ok {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	for _, super := range supers {
+		out.Nodes = append(out.Nodes, *super)
+	}
+	slices.SortStableFunc(out.Nodes[len(out.Nodes)-len(supers):], func(a, b models.Node) int { return cmp.Compare(a.ID, b.ID) })
+
+	merged := make(map[[3]string]int) // Source, target, type -> index into out.Edges
+	for _, e := range g.Edges {
+		src, tgt := cmp.Or(superOf[e.Source], e.Source), cmp.Or(superOf[e.Target], e.Target)
+		if src == e.Source && tgt == e.Target {
+			out.Edges = append(out.Edges, e)
+			continue
+		}
+		if src == tgt {
+			supers[src].Metadata["internal_edges"] = supers[src].Metadata["internal_edges"].(int) + 1
+			continue
+		}
+		key := [3]string{src, tgt, e.Type}
+		if i, ok := merged[key]; ok {
+			out.Edges[i].Weight += e.Weight
+			continue
+		}
+		merged[key] = len(out.Edges)
+		out.Edges = append(out.Edges, models.Edge{ID: fmt.Sprintf("aggregate:%s:%s:%s", src, tgt, e.Type), Source: src, Target: tgt, Weight: e.Weight, Type: e.Type})
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/access"
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// defaultViewWindowDays is how far back most-viewed counts views by default.
+const defaultViewWindowDays = 30
+
+// SetViewTracking enables recording note content views for the analytics
+// endpoints. It is off by default.
+func (s *Server) SetViewTracking(enabled bool) {
+	s.trackViews = enabled
+}
+
+// recordView notes that the requester viewed a node, when tracking is on.
+func (s *Server) recordView(r *http.Request, nodeID string) {
+	if !s.trackViews {
+		return
+	}
+	user := ""
+	if u := access.UserFromContext(r.Context()); u != nil {
+		user = u.Name
+	}
+	if err := s.store.RecordView(nodeID, user, time.Now()); err != nil {
+		log.Printf("Warning: failed to record view of %s: %v", nodeID, err)
+	}
+}
+
+// handleMostViewed returns the most viewed notes. Query: days (default 30),
+// limit (default 20, max 100).
+func (s *Server) handleMostViewed(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	days := defaultViewWindowDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, r, CodeBadRequest, "Invalid days")
+			return
+		}
+		days = n
+	}
+
+	ids, err := s.visibleIDList(r)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch views")
+		return
+	}
+	views, err := s.store.GetMostViewed(time.Now().AddDate(0, 0, -days), limit, ids)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch views")
+		return
+	}
+	s.writeNodeViews(w, views)
+}
+
+// handleRecentlyViewed returns the most recently viewed notes. Query: limit
+// (default 20, max 100).
+func (s *Server) handleRecentlyViewed(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	ids, err := s.visibleIDList(r)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch views")
+		return
+	}
+	views, err := s.store.GetRecentlyViewed(limit, ids)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch views")
+		return
+	}
+	s.writeNodeViews(w, views)
+}
+
+// writeNodeViews responds with views already limited to visible nodes.
+func (s *Server) writeNodeViews(w http.ResponseWriter, views []models.NodeViews) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"enabled": s.trackViews,
+		"nodes":   views,
+	})
+}
+
+// visibleIDList lists the IDs of the nodes the requester may see, so queries
+// can filter before applying their limit. It is nil when nothing is hidden.
+func (s *Server) visibleIDList(r *http.Request) ([]string, error) {
+	visible, err := s.visibleIDs(r)
+	if visible == nil || err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(visible))
+	for id := range visible {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseLimit reads the limit query parameter (default 20, max 100), replying
+// with an error if it is invalid.
+func parseLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, r, CodeBadRequest, "Invalid limit")
+			return 0, false
+		}
+		limit = min(n, 100)
+	}
+	return limit, true
+}
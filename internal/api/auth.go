@@ -0,0 +1,158 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/ali01/mnemosyne/internal/access"
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// SetAuthenticator enables bearer-token authentication. Without one, every
+// request is anonymous and write endpoints are open.
+func (s *Server) SetAuthenticator(a *access.Authenticator) {
+	s.auth = a
+}
+
+// SetAccessPolicy sets the node visibility policy applied to all responses.
+// A nil policy is replaced with one that allows everything.
+func (s *Server) SetAccessPolicy(p *access.Policy) {
+	if p == nil {
+		p = &access.Policy{}
+	}
+	s.policy = p
+}
+
+// authenticate attaches the request's user to its context. Requests with an
+// invalid token are rejected; requests without one proceed anonymously.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.auth == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		u, ok := s.auth.Authenticate(r)
+		if !ok {
+			writeError(w, r, CodeInvalidCredentials, "Invalid credentials")
+			return
+		}
+		if u != nil {
+			r = r.WithContext(access.WithUser(r.Context(), u))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireUser rejects anonymous requests when authentication is enabled.
+func (s *Server) requireUser(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.auth != nil && access.UserFromContext(r.Context()) == nil {
+			writeError(w, r, CodeAuthRequired, "Authentication required")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// requireAdmin wraps a handler that only users with the policy's admin role
+// may call. Without authentication, as with requireUser, anyone may.
+func (s *Server) requireAdmin(h http.HandlerFunc) http.HandlerFunc {
+	return s.requireUser(func(w http.ResponseWriter, r *http.Request) {
+		if s.auth != nil && !s.policy.IsAdmin(access.UserFromContext(r.Context())) {
+			writeError(w, r, CodeForbidden, "Admin role required")
+			return
+		}
+		h(w, r)
+	})
+}
+
+// visibleNodes filters nodes to those the requesting user may see.
+func (s *Server) visibleNodes(r *http.Request, nodes []models.VaultNode) []models.VaultNode {
+	return s.policy.FilterNodes(access.UserFromContext(r.Context()), nodes)
+}
+
+// visibleNode loads a node and reports whether it exists and the requesting
+// user may see it. Hidden nodes are indistinguishable from missing ones.
+func (s *Server) visibleNode(r *http.Request, id string) (*models.VaultNode, bool) {
+	node, err := s.store.GetNode(id)
+	if err != nil {
+		return nil, false
+	}
+	if !s.policy.CanView(access.UserFromContext(r.Context()), node) {
+		return nil, false
+	}
+	return node, true
+}
+
+// visibleIDs returns the IDs of the nodes the requesting user may see, or nil
+// when the policy hides none from them.
+func (s *Server) visibleIDs(r *http.Request) (map[string]bool, error) {
+	if !s.policy.Restricts(access.UserFromContext(r.Context())) {
+		return nil, nil
+	}
+	nodes, err := s.store.GetAllNodes()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(nodes))
+	for _, n := range s.visibleNodes(r, nodes) {
+		ids[n.ID] = true
+	}
+	return ids, nil
+}
+
+type aclResponse struct {
+	NodeID     string   `json:"node_id"`
+	Source     string   `json:"source"` // "api", "frontmatter", or "" when unrestricted
+	Principals []string `json:"principals"`
+}
+
+func (s *Server) nodeACL(node *models.VaultNode) aclResponse {
+	principals, source := s.policy.NodeACL(node)
+	if principals == nil {
+		principals = []string{}
+	}
+	return aclResponse{NodeID: node.ID, Source: source, Principals: principals}
+}
+
+func (s *Server) handleGetNodeACL(w http.ResponseWriter, r *http.Request) {
+	node, ok := s.visibleNode(r, r.PathValue("id"))
+	if !ok {
+		writeError(w, r, CodeNotFound, "Node not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.nodeACL(node))
+}
+
+// handleSetNodeACL assigns the users and roles allowed to see a node. Only
+// admins may. Body: {"principals": [...]}; an empty list removes the
+// assignment.
+func (s *Server) handleSetNodeACL(w http.ResponseWriter, r *http.Request) {
+	node, ok := s.visibleNode(r, r.PathValue("id"))
+	if !ok {
+		writeError(w, r, CodeNotFound, "Node not found")
+		return
+	}
+
+	var body struct {
+		Principals []string `json:"principals"`
+	}
+	if !s.readJSON(w, r, &body) {
+		return
+	}
+	for _, p := range body.Principals {
+		if p == "" {
+			writeError(w, r, CodeBadRequest, "Principals must be non-empty")
+			return
+		}
+	}
+
+	if err := s.store.SetNodeACL(node.ID, body.Principals); err != nil {
+		log.Printf("Failed to set ACL for %s: %v", node.ID, err)
+		writeError(w, r, CodeInternal, "Failed to set ACL")
+		return
+	}
+	s.policy.SetNodeACL(node.ID, body.Principals)
+
+	writeJSON(w, http.StatusOK, s.nodeACL(node))
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ali01/mnemosyne/internal/access"
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// bookmarkUser returns whose bookmarks a request reads and writes. Without
+// auth, everyone shares one set under "".
+func bookmarkUser(r *http.Request) string {
+	if u := access.UserFromContext(r.Context()); u != nil {
+		return u.Name
+	}
+	return ""
+}
+
+// handleListBookmarks returns the requesting user's starred nodes, most
+// recently starred first. Nodes the user may no longer see are left out.
+func (s *Server) handleListBookmarks(w http.ResponseWriter, r *http.Request) {
+	bookmarks, err := s.store.GetBookmarks(bookmarkUser(r))
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch bookmarks")
+		return
+	}
+
+	visible := make([]models.Bookmark, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		if _, ok := s.visibleNode(r, b.NodeID); ok {
+			visible = append(visible, b)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"bookmarks": visible})
+}
+
+func (s *Server) handleAddBookmark(w http.ResponseWriter, r *http.Request) {
+	node, ok := s.visibleNode(r, r.PathValue("nodeId"))
+	if !ok {
+		writeError(w, r, CodeNotFound, "Node not found")
+		return
+	}
+	if err := s.store.AddBookmark(bookmarkUser(r), node.ID); err != nil {
+		writeError(w, r, CodeInternal, "Failed to add bookmark")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDeleteBookmark(w http.ResponseWriter, r *http.Request) {
+	if err := s.store.DeleteBookmark(bookmarkUser(r), r.PathValue("nodeId")); err != nil {
+		writeError(w, r, CodeInternal, "Failed to delete bookmark")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
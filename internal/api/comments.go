@@ -0,0 +1,71 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ali01/mnemosyne/internal/access"
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// maxCommentLength bounds a comment body, in characters.
+const maxCommentLength = 10000
+
+// handleListComments returns a node's comments, oldest first.
+func (s *Server) handleListComments(w http.ResponseWriter, r *http.Request) {
+	node, ok := s.visibleNode(r, r.PathValue("id"))
+	if !ok {
+		writeError(w, r, CodeNotFound, "Node not found")
+		return
+	}
+	comments, err := s.store.GetComments(node.ID)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch comments")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"comments": comments})
+}
+
+// handleAddComment annotates a node without touching its markdown file.
+// Body: {"body": "..."}; the comment is attributed to the requesting user.
+func (s *Server) handleAddComment(w http.ResponseWriter, r *http.Request) {
+	node, ok := s.visibleNode(r, r.PathValue("id"))
+	if !ok {
+		writeError(w, r, CodeNotFound, "Node not found")
+		return
+	}
+
+	var req struct {
+		Body string `json:"body"`
+	}
+	if !s.readJSON(w, r, &req) {
+		return
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		writeError(w, r, CodeBadRequest, "Comment body is required")
+		return
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		writeError(w, r, CodeBadRequest, "Comment is too long")
+		return
+	}
+
+	comment := &models.Comment{
+		NodeID:    node.ID,
+		Body:      body,
+		CreatedAt: time.Now(),
+	}
+	if u := access.UserFromContext(r.Context()); u != nil {
+		comment.Author = u.Name
+	}
+	if err := s.store.AddComment(comment); err != nil {
+		log.Printf("Failed to store comment on %s: %v", node.ID, err)
+		writeError(w, r, CodeInternal, "Failed to add comment")
+		return
+	}
+	writeJSON(w, http.StatusCreated, comment)
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ali01/mnemosyne/internal/access"
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// graphDelta is what changed in a graph since a version. Clients apply it to
+// the graph they fetched at that version, then use Version as their next
+// since. When Reset is set, the delta is unavailable and the lists are empty:
+// clients must fetch the whole graph again.
+type graphDelta struct {
+	GraphID      int                      `json:"graph_id"`
+	Since        int64                    `json:"since"`
+	Version      int64                    `json:"version"`
+	Reset        bool                     `json:"reset"`
+	Nodes        []models.Node            `json:"nodes"`         // Added or changed, with positions
+	RemovedNodes []string                 `json:"removed_nodes"` // Their edges are removed too
+	Edges        []models.Edge            `json:"edges"`         // Added or changed, or touching added nodes
+	RemovedEdges []string                 `json:"removed_edges"`
+	Positions    map[string]deltaPosition `json:"positions"` // Moved nodes not in Nodes
+}
+
+type deltaPosition struct {
+	models.Position
+	Pinned bool `json:"pinned,omitempty"`
+}
+
+// withGraphVersion sets the X-Graph-Version header on reads of graphs, nodes
+// and edges to the current graph version, so clients can tell when their
+// data is stale and fetch a delta. The version is read before the response
+// is built, so it never claims changes the response may lack.
+func (s *Server) withGraphVersion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isGraphRead(r) {
+			if version, err := s.store.GetGraphVersion(); err == nil {
+				w.Header().Set("X-Graph-Version", strconv.FormatInt(version, 10))
+			} else {
+				log.Printf("Failed to fetch graph version: %v", err)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isGraphRead reports whether a request reads graphs, nodes or edges.
+func isGraphRead(r *http.Request) bool {
+	path := r.URL.Path
+	switch {
+	case r.Method == http.MethodPost:
+		return path == "/api/v1/edges/batch"
+	case r.Method != http.MethodGet && r.Method != http.MethodHead:
+		return false
+	}
+	return strings.HasPrefix(path, "/api/v1/graph") || strings.HasPrefix(path, "/api/v1/nodes") ||
+		strings.HasPrefix(path, "/api/v1/edges")
+}
+
+// handleGraphDelta returns the changes to a graph since a version, as
+// returned in X-Graph-Version headers and by earlier deltas. Query: graph_id,
+// since. Changes to the graph's filter, groups, or archived flag, changes to
+// ACLs, changes to nodes the requester cannot see, and versions too old to
+// diff from, reset the client.
+func (s *Server) handleGraphDelta(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	graphID, err := strconv.Atoi(q.Get("graph_id"))
+	if err != nil {
+		writeError(w, r, CodeBadRequest, "Query parameter 'graph_id' is required")
+		return
+	}
+	since, err := strconv.ParseInt(q.Get("since"), 10, 64)
+	if err != nil || since < 0 {
+		writeError(w, r, CodeBadRequest, "Query parameter 'since' must be a graph version")
+		return
+	}
+
+	// Read the version first: changes racing with this request are sent
+	// again in the next delta, rather than missed.
+	version, err := s.store.GetGraphVersion()
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch graph version")
+		return
+	}
+	raw, err := s.store.GetGraphDataRaw(graphID)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, r, CodeNotFound, "Graph not found")
+		return
+	}
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch graph")
+		return
+	}
+	delta := graphDelta{
+		GraphID:      graphID,
+		Since:        since,
+		Version:      version,
+		Nodes:        []models.Node{},
+		RemovedNodes: []string{},
+		Edges:        []models.Edge{},
+		RemovedEdges: []string{},
+		Positions:    map[string]deltaPosition{},
+	}
+	if since > version {
+		delta.Reset = true
+		writeJSON(w, http.StatusOK, delta)
+		return
+	}
+	changes, err := s.store.GetGraphChanges(graphID, since, version)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch changes")
+		return
+	}
+	if !changes.Complete || changes.GraphChanged || changes.ACLChanged {
+		delta.Reset = true
+		writeJSON(w, http.StatusOK, delta)
+		return
+	}
+
+	members := make(map[string]bool, len(raw.Nodes))
+	for _, n := range raw.Nodes {
+		members[n.ID] = true
+	}
+	raw.Nodes = s.visibleNodes(r, raw.Nodes)
+	visible := make(map[string]bool, len(raw.Nodes))
+	for _, n := range raw.Nodes {
+		visible[n.ID] = true
+	}
+	graph := applyFilterAndGroups(raw)
+	restricted := s.policy.Restricts(access.UserFromContext(r.Context()))
+
+	// Nodes changed in other graphs are not relevant; nodes that left this
+	// one are, through their membership change.
+	changed := make(map[string]bool)
+	for _, id := range changes.MemberIDs {
+		changed[id] = true
+	}
+	for _, id := range changes.NodeIDs {
+		if members[id] {
+			changed[id] = true
+		}
+	}
+
+	// A changed node the requester cannot see now may never have been
+	// visible to them, and naming it as removed would reveal it
+	if restricted {
+		for id := range changed {
+			if !visible[id] {
+				delta.Reset = true
+				writeJSON(w, http.StatusOK, delta)
+				return
+			}
+		}
+	}
+
+	inGraph := make(map[string]bool, len(graph.Nodes))
+	for _, n := range graph.Nodes {
+		inGraph[n.ID] = true
+		if changed[n.ID] {
+			delta.Nodes = append(delta.Nodes, n)
+		}
+	}
+	for id := range changed {
+		if !inGraph[id] {
+			delta.RemovedNodes = append(delta.RemovedNodes, id)
+		}
+	}
+	sort.Strings(delta.RemovedNodes)
+
+	changedEdges := make(map[string]bool, len(changes.Edges))
+	for _, e := range changes.Edges {
+		// Edges touching nodes the requester cannot see were never sent
+		if restricted && (!visible[e.SourceID] || !visible[e.TargetID]) {
+			continue
+		}
+		if members[e.SourceID] || members[e.TargetID] || changed[e.SourceID] || changed[e.TargetID] {
+			changedEdges[e.ID] = true
+		}
+	}
+	edgeInGraph := make(map[string]bool, len(graph.Edges))
+	for _, e := range graph.Edges {
+		edgeInGraph[e.ID] = true
+		if changedEdges[e.ID] || changed[e.Source] || changed[e.Target] {
+			delta.Edges = append(delta.Edges, e)
+		}
+	}
+	for id := range changedEdges {
+		if !edgeInGraph[id] {
+			delta.RemovedEdges = append(delta.RemovedEdges, id)
+		}
+	}
+	sort.Strings(delta.RemovedEdges)
+
+	for _, id := range changes.PositionIDs {
+		if inGraph[id] && !changed[id] {
+			pos := raw.Positions[id]
+			delta.Positions[id] = deltaPosition{Position: pos.ToPosition(), Pinned: pos.Pinned}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, delta)
+}
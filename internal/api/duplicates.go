@@ -0,0 +1,49 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// handleNearDuplicates lists the pairs of nearly identical notes the
+// duplicate detector last found, most similar first, so they can be
+// merged. With ?min=0.9 only pairs at least that similar are listed. Pairs
+// with a note the requester cannot see are left out.
+func (s *Server) handleNearDuplicates(w http.ResponseWriter, r *http.Request) {
+	minSimilarity := 0.0
+	if v := r.URL.Query().Get("min"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 1 {
+			writeError(w, r, CodeBadRequest, "Invalid min")
+			return
+		}
+		minSimilarity = f
+	}
+
+	pairs, err := s.store.GetNearDuplicates(minSimilarity)
+	if err != nil {
+		log.Printf("Failed to fetch near duplicates: %v", err)
+		writeError(w, r, CodeInternal, "Failed to fetch near duplicates")
+		return
+	}
+
+	var ids []string
+	for _, p := range pairs {
+		ids = append(ids, p.A, p.B)
+	}
+	visible, ok := s.visibleNodeIDs(w, r, ids)
+	if !ok {
+		return
+	}
+
+	out := make([]models.NearDuplicate, 0, len(pairs))
+	for _, p := range pairs {
+		if visible[p.A] && visible[p.B] {
+			out = append(out, p)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"pairs": out})
+}
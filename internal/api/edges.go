@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// maxEdgeBatchNodes bounds the node IDs of one batch edge lookup.
+const maxEdgeBatchNodes = 10000
+
+// handleBatchEdges returns every edge touching any of a set of nodes, so
+// clients expanding neighborhoods or viewports need one request rather than
+// one per node. Body: {"node_ids": [...], "graph_id": 1}; with graph_id, only
+// edges between members of that graph are returned. Edges to nodes the
+// requester may not see are left out.
+func (s *Server) handleBatchEdges(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		NodeIDs []string `json:"node_ids"`
+		GraphID int      `json:"graph_id"`
+	}
+	if !s.readJSON(w, r, &req) {
+		return
+	}
+	if len(req.NodeIDs) == 0 {
+		writeError(w, r, CodeBadRequest, "No node IDs provided")
+		return
+	}
+	if len(req.NodeIDs) > maxEdgeBatchNodes {
+		writeError(w, r, CodeBadRequest, "Too many node IDs")
+		return
+	}
+	if req.GraphID != 0 {
+		if _, err := s.store.GetGraphInfo(req.GraphID); err != nil {
+			writeError(w, r, CodeNotFound, "Graph not found")
+			return
+		}
+	}
+
+	edges, err := s.store.GetEdgesByNodes(req.NodeIDs)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch edges")
+		return
+	}
+
+	// Endpoints must be visible, and members of the graph if one was given
+	seen := make(map[string]bool)
+	var endpoints []string
+	for _, e := range edges {
+		for _, id := range []string{e.SourceID, e.TargetID} {
+			if !seen[id] {
+				seen[id] = true
+				endpoints = append(endpoints, id)
+			}
+		}
+	}
+	nodes, err := s.store.GetNodesByIDs(endpoints)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch nodes")
+		return
+	}
+	allowed := make(map[string]bool, len(nodes))
+	for _, n := range s.visibleNodes(r, nodes) {
+		allowed[n.ID] = true
+	}
+	if req.GraphID != 0 {
+		missing, err := s.store.MissingGraphMembers(req.GraphID, endpoints)
+		if err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch graph members")
+			return
+		}
+		for _, id := range missing {
+			delete(allowed, id)
+		}
+	}
+
+	apiEdges := make([]models.Edge, 0, len(edges))
+	for _, e := range edges {
+		if allowed[e.SourceID] && allowed[e.TargetID] {
+			apiEdges = append(apiEdges, models.Edge{
+				ID:     e.ID,
+				Source: e.SourceID,
+				Target: e.TargetID,
+				Weight: e.Weight,
+				Type:   e.EdgeType,
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"edges": apiEdges})
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"sort"
+)
+
+// ErrorCode identifies a kind of API error. Clients should branch on codes,
+// which are stable, rather than on messages, which are for people.
+type ErrorCode string
+
+const (
+	CodeBadRequest         ErrorCode = "bad_request"
+	CodeInvalidBody        ErrorCode = "invalid_body"
+	CodeBodyTooLarge       ErrorCode = "body_too_large"
+	CodeAuthRequired       ErrorCode = "auth_required"
+	CodeInvalidCredentials ErrorCode = "invalid_credentials"
+	CodeForbidden          ErrorCode = "forbidden"
+	CodeNotFound           ErrorCode = "not_found"
+	CodeValidationFailed   ErrorCode = "validation_failed"
+	CodeNotGitRepository   ErrorCode = "not_git_repository"
+	CodeIndexRunning       ErrorCode = "index_running"
+	CodeLayoutJobActive    ErrorCode = "layout_job_active"
+	CodeInternal           ErrorCode = "internal_error"
+	CodeUnavailable        ErrorCode = "unavailable"
+)
+
+// errorCodeInfo describes an error code in the registry.
+type errorCodeInfo struct {
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+}
+
+// errorCodes is the registry of error codes. Every error response uses one of
+// them, with its status.
+var errorCodes = map[ErrorCode]errorCodeInfo{
+	CodeBadRequest:         {http.StatusBadRequest, "A path or query parameter, or a field of the request body, is invalid"},
+	CodeInvalidBody:        {http.StatusBadRequest, "The request body is empty, malformed, or has unknown fields"},
+	CodeBodyTooLarge:       {http.StatusRequestEntityTooLarge, "The request body exceeds the configured max-body-mb"},
+	CodeAuthRequired:       {http.StatusUnauthorized, "The endpoint requires a bearer token"},
+	CodeInvalidCredentials: {http.StatusUnauthorized, "The bearer token is not recognized"},
+	CodeForbidden:          {http.StatusForbidden, "The requester is authenticated but not allowed to do this"},
+	CodeNotFound:           {http.StatusNotFound, "The resource does not exist or is not visible to the requester"},
+	CodeValidationFailed:   {http.StatusUnprocessableEntity, "The request is well-formed but a field's value is invalid; details name the field"},
+	CodeNotGitRepository:   {http.StatusBadRequest, "The vault is not in a git repository"},
+	CodeIndexRunning:       {http.StatusConflict, "A full index is already running"},
+	CodeLayoutJobActive:    {http.StatusConflict, "A layout job is already queued or running for the graph"},
+	CodeInternal:           {http.StatusInternalServerError, "The server failed to handle the request; see its log"},
+	CodeUnavailable:        {http.StatusServiceUnavailable, "The feature is not enabled on this server"},
+}
+
+// apiError is the envelope of every error response, under an "error" key.
+type apiError struct {
+	Code      ErrorCode   `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// writeError writes an error response with the code's status.
+func writeError(w http.ResponseWriter, r *http.Request, code ErrorCode, message string) {
+	writeErrorDetails(w, r, code, message, nil)
+}
+
+// writeErrorDetails writes an error response with structured details, e.g.
+// the accepted values of an invalid parameter.
+func writeErrorDetails(w http.ResponseWriter, r *http.Request, code ErrorCode, message string, details interface{}) {
+	info, ok := errorCodes[code]
+	if !ok {
+		info = errorCodes[CodeInternal]
+	}
+	writeJSON(w, info.Status, map[string]apiError{"error": {
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: requestIDFromContext(r.Context()),
+	}})
+}
+
+// handleListErrorCodes documents the error code registry.
+func (s *Server) handleListErrorCodes(w http.ResponseWriter, r *http.Request) {
+	type entry struct {
+		Code ErrorCode `json:"code"`
+		errorCodeInfo
+	}
+	codes := make([]entry, 0, len(errorCodes))
+	for code, info := range errorCodes {
+		codes = append(codes, entry{code, info})
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	writeJSON(w, http.StatusOK, map[string]interface{}{"codes": codes})
+}
+
+// --- Request IDs ---
+
+type requestIDKey struct{}
+
+// validRequestID matches client-supplied request IDs worth keeping.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// withRequestID tags each request with an ID, taken from its X-Request-ID
+// header if valid or generated otherwise, and echoes it in the response's
+// X-Request-ID header and error bodies so failures can be matched to logs.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID.MatchString(id) {
+			b := make([]byte, 8)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestIDFromContext returns the request's ID, or "" outside withRequestID.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
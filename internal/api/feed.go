@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+const (
+	// defaultFeedFlag opts a note into the feed when no publish flag is configured.
+	defaultFeedFlag = "public"
+
+	defaultFeedLimit = 20
+	maxFeedLimit     = 100
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title    string         `xml:"title"`
+	ID       string         `xml:"id"`
+	Updated  string         `xml:"updated"`
+	Link     atomLink       `xml:"link"`
+	Summary  string         `xml:"summary"`
+	Category []atomCategory `xml:"category,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// handleFeed serves an Atom feed of recently updated published notes. Notes
+// appear only when their frontmatter sets the configured publish flag
+// (default `public`) to true and does not set `private: true`. The feed is
+// always anonymous. Query: limit (default 20, max 100).
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	limit := defaultFeedLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, r, CodeBadRequest, "Invalid limit")
+			return
+		}
+		limit = min(n, maxFeedLimit)
+	}
+
+	flag := defaultFeedFlag
+	if s.policy.PublishFlag != "" {
+		flag = s.policy.PublishFlag
+	}
+
+	nodes, err := s.store.GetRecentPublicNodes(flag, limit)
+	if err != nil {
+		log.Printf("Feed query failed: %v", err)
+		writeError(w, r, CodeInternal, "Failed to build feed")
+		return
+	}
+	nodes = s.policy.FilterNodes(nil, nodes)
+
+	base := requestBaseURL(r)
+	feed := atomFeed{
+		Title:   "Mnemosyne",
+		ID:      base + "/feed.xml",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Link: []atomLink{
+			{Href: base + "/feed.xml", Rel: "self"},
+			{Href: base + "/"},
+		},
+	}
+	if len(nodes) > 0 {
+		feed.Updated = nodes[0].UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	for _, n := range nodes {
+		feed.Entries = append(feed.Entries, feedEntry(base, n))
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		log.Printf("Feed encode failed: %v", err)
+	}
+}
+
+func feedEntry(base string, n models.VaultNode) atomEntry {
+	link := base + "/api/v1/nodes/" + url.PathEscape(n.ID)
+	e := atomEntry{
+		Title:   n.Title,
+		ID:      link,
+		Updated: n.UpdatedAt.UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: link},
+		Summary: n.Excerpt,
+	}
+	for _, tag := range n.Tags {
+		e.Category = append(e.Category, atomCategory{Term: tag})
+	}
+	return e
+}
+
+// requestBaseURL reconstructs the scheme and host the client used.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// nodeGroup is the nodes of one top-level folder, for drawing the folder as
+// a single super-node.
+type nodeGroup struct {
+	ID            string    `json:"id"`     // "<vault_id>:<folder>"
+	Folder        string    `json:"folder"` // Top-level directory; "" for notes at the root
+	VaultID       int       `json:"vault_id"`
+	NodeCount     int       `json:"node_count"`
+	WordCount     int       `json:"word_count"`
+	InternalEdges int       `json:"internal_edges"` // Edges between the group's nodes
+	ExternalEdges int       `json:"external_edges"` // Edges to or from other groups
+	LastModified  time.Time `json:"last_modified"`
+	NodeIDs       []string  `json:"node_ids"`
+}
+
+// groupLink counts the edges from the nodes of one group to those of another.
+type groupLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Count  int    `json:"count"`
+}
+
+// handleGraphGroups groups the nodes the requester can see by top-level
+// folder, with aggregate stats and the links between groups, for a
+// "folders as super-nodes" view. Query: by (only folder, the default),
+// graph_id (default all notes; folders are then relative to the graph's
+// root).
+func (s *Server) handleGraphGroups(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if by := q.Get("by"); by != "" && by != "folder" {
+		writeError(w, r, CodeBadRequest, "Grouping must be by folder")
+		return
+	}
+
+	var nodes []models.VaultNode
+	var edges []models.VaultEdge
+	root := ""
+	if v := q.Get("graph_id"); v != "" {
+		graphID, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, CodeBadRequest, "Invalid graph ID")
+			return
+		}
+		info, err := s.store.GetGraphInfo(graphID)
+		if err != nil {
+			writeError(w, r, CodeNotFound, "Graph not found")
+			return
+		}
+		raw, err := s.store.GetGraphDataRaw(graphID)
+		if err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch graph")
+			return
+		}
+		nodes, edges, root = raw.Nodes, raw.Edges, info.RootPath
+	} else {
+		var err error
+		if nodes, err = s.store.GetAllNodes(); err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch nodes")
+			return
+		}
+		if edges, err = s.store.GetAllEdges(); err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch edges")
+			return
+		}
+	}
+
+	groups, links := groupByFolder(s.visibleNodes(r, nodes), edges, root)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"by":     "folder",
+		"groups": groups,
+		"links":  links,
+	})
+}
+
+// groupByFolder groups nodes by their top-level folder under root, largest
+// group first, and counts the edges within and between groups. Edges with
+// an end outside nodes are left out.
+func groupByFolder(nodes []models.VaultNode, edges []models.VaultEdge, root string) ([]nodeGroup, []groupLink) {
+	byID := make(map[string]*nodeGroup)
+	groupOf := make(map[string]*nodeGroup, len(nodes))
+	for _, n := range nodes {
+		folder := topFolder(n.FilePath, root)
+		id := fmt.Sprintf("%d:%s", n.VaultID, folder)
+		g, ok := byID[id]
+		if !ok {
+			g = &nodeGroup{ID: id, Folder: folder, VaultID: n.VaultID, NodeIDs: []string{}}
+			byID[id] = g
+		}
+		g.NodeCount++
+		g.WordCount += n.WordCount
+		if n.UpdatedAt.After(g.LastModified) {
+			g.LastModified = n.UpdatedAt
+		}
+		g.NodeIDs = append(g.NodeIDs, n.ID)
+		groupOf[n.ID] = g
+	}
+
+	counts := make(map[[2]string]int)
+	for _, e := range edges {
+		src, tgt := groupOf[e.SourceID], groupOf[e.TargetID]
+		if src == nil || tgt == nil || e.SourceID == e.TargetID {
+			continue
+		}
+		if src == tgt {
+			src.InternalEdges++
+			continue
+		}
+		src.ExternalEdges++
+		tgt.ExternalEdges++
+		counts[[2]string{src.ID, tgt.ID}]++
+	}
+
+	groups := make([]nodeGroup, 0, len(byID))
+	for _, g := range byID {
+		slices.Sort(g.NodeIDs)
+		groups = append(groups, *g)
+	}
+	slices.SortFunc(groups, func(a, b nodeGroup) int {
+		return cmp.Or(cmp.Compare(b.NodeCount, a.NodeCount), cmp.Compare(a.ID, b.ID))
+	})
+	links := make([]groupLink, 0, len(counts))
+	for k, n := range counts {
+		links = append(links, groupLink{Source: k[0], Target: k[1], Count: n})
+	}
+	slices.SortFunc(links, func(a, b groupLink) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Source, b.Source), cmp.Compare(a.Target, b.Target))
+	})
+	return groups, links
+}
+
+// topFolder returns the first directory of filePath below root, or "" for
+// a file directly in root.
+func topFolder(filePath, root string) string {
+	if root != "" {
+		filePath = strings.TrimPrefix(filePath, root+"/")
+	}
+	folder, _, found := strings.Cut(filePath, "/")
+	if !found {
+		return ""
+	}
+	return folder
+}
@@ -1,15 +1,24 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ali01/mnemosyne/internal/discovery"
+	"github.com/ali01/mnemosyne/internal/indexer"
 	"github.com/ali01/mnemosyne/internal/models"
 	"github.com/ali01/mnemosyne/internal/search"
 	"github.com/ali01/mnemosyne/internal/store"
+	"github.com/ali01/mnemosyne/internal/vault"
 	"gopkg.in/yaml.v3"
 )
 
@@ -22,7 +31,7 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleListGraphs(w http.ResponseWriter, r *http.Request) {
 	graphs, err := s.store.GetAllGraphs()
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch graphs"})
+		writeError(w, r, CodeInternal, "Failed to fetch graphs")
 		return
 	}
 	if graphs == nil {
@@ -38,46 +47,113 @@ func (s *Server) handleListGraphs(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetGraphData(w http.ResponseWriter, r *http.Request) {
 	graphID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid graph ID"})
+		writeError(w, r, CodeBadRequest, "Invalid graph ID")
 		return
 	}
 
 	raw, err := s.store.GetGraphDataRaw(graphID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch graph"})
+		writeError(w, r, CodeInternal, "Failed to fetch graph")
 		return
 	}
 
+	raw.Nodes = s.visibleNodes(r, raw.Nodes)
+	if !s.pruneGraph(w, r, raw) {
+		return
+	}
 	graph := applyFilterAndGroups(raw)
-	writeJSON(w, http.StatusOK, graph)
+	if !s.aggregateGraph(w, r, graphID, graph) {
+		return
+	}
+	if v := r.URL.Query().Get("bidirectional"); v != "" {
+		mark, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, r, CodeBadRequest, "Invalid bidirectional")
+			return
+		}
+		if mark {
+			markBidirectional(graph.Edges)
+		}
+	}
+	switch r.URL.Query().Get("format") {
+	case "", "edges":
+		writeJSON(w, http.StatusOK, graph)
+	case "adjacency":
+		writeJSON(w, http.StatusOK, toAdjacency(graph))
+	default:
+		writeErrorDetails(w, r, CodeBadRequest, "Unknown format", map[string][]string{"formats": {"edges", "adjacency"}})
+	}
+}
+
+// adjacencyGraph is a compact form of models.Graph for dense graphs. Edges
+// refer to nodes by index into Nodes rather than by ID: Adjacency[i] lists
+// the outgoing edges of Nodes[i] as [target index, index into EdgeTypes]
+// pairs. Edge IDs and weights are left out.
+type adjacencyGraph struct {
+	Format    string        `json:"format"`
+	Nodes     []models.Node `json:"nodes"`
+	EdgeTypes []string      `json:"edge_types"`
+	Adjacency [][][2]int    `json:"adjacency"`
+}
+
+func toAdjacency(g *models.Graph) adjacencyGraph {
+	index := make(map[string]int, len(g.Nodes))
+	for i, n := range g.Nodes {
+		index[n.ID] = i
+	}
+	adj := adjacencyGraph{
+		Format:    "adjacency",
+		Nodes:     g.Nodes,
+		EdgeTypes: []string{},
+		Adjacency: make([][][2]int, len(g.Nodes)),
+	}
+	typeIndex := make(map[string]int)
+	for i := range adj.Adjacency {
+		adj.Adjacency[i] = [][2]int{}
+	}
+	for _, e := range g.Edges {
+		t, ok := typeIndex[e.Type]
+		if !ok {
+			t = len(adj.EdgeTypes)
+			typeIndex[e.Type] = t
+			adj.EdgeTypes = append(adj.EdgeTypes, e.Type)
+		}
+		src := index[e.Source]
+		adj.Adjacency[src] = append(adj.Adjacency[src], [2]int{index[e.Target], t})
+	}
+	return adj
 }
 
 func (s *Server) handleSearchInGraph(w http.ResponseWriter, r *http.Request) {
 	graphID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid graph ID"})
+		writeError(w, r, CodeBadRequest, "Invalid graph ID")
 		return
 	}
 
 	query := r.URL.Query().Get("q")
 	if query == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Query parameter 'q' is required"})
+		writeError(w, r, CodeBadRequest, "Query parameter 'q' is required")
 		return
 	}
 
 	nodes, err := s.store.SearchInGraph(graphID, query)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Search failed"})
+		writeError(w, r, CodeInternal, "Search failed")
 		return
 	}
+	nodes = s.visibleNodes(r, nodes)
 
 	apiNodes := make([]models.Node, 0, len(nodes))
 	for _, n := range nodes {
 		apiNodes = append(apiNodes, models.Node{
-			ID:       n.ID,
-			Title:    n.Title,
-			FilePath: n.FilePath,
-			Metadata: map[string]interface{}{"type": n.NodeType},
+			ID:          n.ID,
+			Title:       n.Title,
+			FilePath:    n.FilePath,
+			WordCount:   n.WordCount,
+			ReadingTime: n.ReadingTime,
+			Excerpt:     n.Excerpt,
+			Metadata:    map[string]interface{}{"type": n.NodeType},
 		})
 	}
 
@@ -86,29 +162,109 @@ func (s *Server) handleSearchInGraph(w http.ResponseWriter, r *http.Request) {
 
 // --- Graph-scoped positions ---
 
+// defaultMaxCoordinate bounds position coordinates unless SetMaxCoordinate
+// overrides it.
+const defaultMaxCoordinate = 1e6
+
+// SetMaxCoordinate sets how far from the origin, on any axis, saved positions
+// may be. Zero or less restores the default of one million.
+func (s *Server) SetMaxCoordinate(max float64) {
+	if max <= 0 {
+		max = defaultMaxCoordinate
+	}
+	s.maxCoord = max
+}
+
+// positionError describes the first invalid position in a batch.
+type positionError struct {
+	Index   int
+	Field   string
+	Message string
+	Missing []string // Node IDs not in the graph, if Field is node_id
+}
+
+// checkPositions checks positions before they are saved to a graph: their
+// coordinates must be finite and in bounds, and their nodes members of the
+// graph. It returns nil if all are valid.
+func (s *Server) checkPositions(graphID int, positions []models.NodePosition) (*positionError, error) {
+	ids := make([]string, len(positions))
+	for i := range positions {
+		if err := positions[i].Validate(s.maxCoord); err != nil {
+			var fe *models.FieldError
+			if !errors.As(err, &fe) {
+				return nil, err
+			}
+			return &positionError{Index: i, Field: fe.Field, Message: "Invalid position: " + err.Error()}, nil
+		}
+		ids[i] = positions[i].NodeID
+	}
+
+	missing, err := s.store.MissingGraphMembers(graphID, ids)
+	if err != nil || len(missing) == 0 {
+		return nil, err
+	}
+	for i, id := range ids {
+		if id == missing[0] {
+			return &positionError{
+				Index:   i,
+				Field:   "node_id",
+				Message: fmt.Sprintf("Invalid position: node %q is not in the graph", id),
+				Missing: missing,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// validatePositions runs checkPositions, writing a 422 that names the invalid
+// field on failure, and the position's index when index is set. It returns
+// whether the positions are valid.
+func (s *Server) validatePositions(w http.ResponseWriter, r *http.Request, graphID int, positions []models.NodePosition, index bool) bool {
+	perr, err := s.checkPositions(graphID, positions)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to check positions")
+		return false
+	}
+	if perr == nil {
+		return true
+	}
+	details := map[string]interface{}{"field": perr.Field}
+	if index {
+		details["index"] = perr.Index
+	}
+	if perr.Missing != nil {
+		details["missing"] = perr.Missing
+	}
+	writeErrorDetails(w, r, CodeValidationFailed, perr.Message, details)
+	return false
+}
+
 func (s *Server) handleUpdateGraphPosition(w http.ResponseWriter, r *http.Request) {
 	graphID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid graph ID"})
+		writeError(w, r, CodeBadRequest, "Invalid graph ID")
 		return
 	}
 	nodeID := r.PathValue("nodeId")
 
 	var pos models.NodePosition
-	if err := readJSON(r, &pos); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	if !s.readJSON(w, r, &pos) {
 		return
 	}
 	pos.NodeID = nodeID
+	if !s.validatePositions(w, r, graphID, []models.NodePosition{pos}, false) {
+		return
+	}
 
 	if err := s.store.UpsertPosition(graphID, &pos); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update position"})
+		writeError(w, r, CodeInternal, "Failed to update position")
 		return
 	}
 
 	if s.positionSync != nil {
 		s.positionSync.MarkDirty(graphID)
 	}
+	s.publishPositions(r, "positions-updated", graphID, toLivePositions([]models.NodePosition{pos}), nil)
 
 	writeJSON(w, http.StatusOK, map[string]string{"message": "Position updated"})
 }
@@ -116,29 +272,32 @@ func (s *Server) handleUpdateGraphPosition(w http.ResponseWriter, r *http.Reques
 func (s *Server) handleUpdateGraphPositions(w http.ResponseWriter, r *http.Request) {
 	graphID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid graph ID"})
+		writeError(w, r, CodeBadRequest, "Invalid graph ID")
 		return
 	}
 
 	var positions []models.NodePosition
-	if err := readJSON(r, &positions); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body"})
+	if !s.readJSON(w, r, &positions) {
 		return
 	}
 
 	if len(positions) == 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "No positions provided"})
+		writeError(w, r, CodeBadRequest, "No positions provided")
+		return
+	}
+	if !s.validatePositions(w, r, graphID, positions, true) {
 		return
 	}
 
 	if err := s.store.UpsertPositions(graphID, positions); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update positions"})
+		writeError(w, r, CodeInternal, "Failed to update positions")
 		return
 	}
 
 	if s.positionSync != nil {
 		s.positionSync.MarkDirty(graphID)
 	}
+	s.publishPositions(r, "positions-updated", graphID, toLivePositions(positions), nil)
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"message": "Positions updated",
@@ -148,40 +307,391 @@ func (s *Server) handleUpdateGraphPositions(w http.ResponseWriter, r *http.Reque
 
 // --- Node content (global, not graph-scoped) ---
 
+// handleListNodes lists nodes (without content) by modification time, oldest
+// first, for tools that mirror the graph incrementally. Query:
+// modified_after (inclusive) and modified_before (exclusive), each RFC 3339
+// or YYYY-MM-DD; either may be omitted.
+func (s *Server) handleListNodes(w http.ResponseWriter, r *http.Request) {
+	var bounds [2]time.Time
+	for i, key := range []string{"modified_after", "modified_before"} {
+		v := r.URL.Query().Get(key)
+		if v == "" {
+			continue
+		}
+		t, err := parseTimeParam(v)
+		if err != nil {
+			writeError(w, r, CodeBadRequest, "Invalid "+key+": expected RFC 3339 time or YYYY-MM-DD")
+			return
+		}
+		bounds[i] = t
+	}
+
+	nodes, err := s.store.GetNodesModified(bounds[0], bounds[1])
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch nodes")
+		return
+	}
+	nodes = s.visibleNodes(r, nodes)
+	if nodes == nil {
+		nodes = []models.VaultNode{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"nodes": nodes})
+}
+
+// parseTimeParam parses an RFC 3339 time or a YYYY-MM-DD date (midnight UTC).
+func parseTimeParam(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
+
 func (s *Server) handleGetNode(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
-	node, err := s.store.GetNode(id)
-	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Node not found"})
+	node, ok := s.visibleNode(r, id)
+	if !ok {
+		writeError(w, r, CodeNotFound, "Node not found")
 		return
 	}
+	s.recordView(r, node.ID)
 
 	writeJSON(w, http.StatusOK, models.Node{
-		ID:       node.ID,
-		Title:    node.Title,
-		FilePath: node.FilePath,
-		Metadata: map[string]interface{}{"type": node.NodeType},
+		ID:          node.ID,
+		Title:       node.Title,
+		FilePath:    node.FilePath,
+		WordCount:   node.WordCount,
+		ReadingTime: node.ReadingTime,
+		Excerpt:     node.Excerpt,
+		Metadata:    map[string]interface{}{"type": node.NodeType},
+	})
+}
+
+func (s *Server) handleGetNodeOutline(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if _, ok := s.visibleNode(r, id); !ok {
+		writeError(w, r, CodeNotFound, "Node not found")
+		return
+	}
+
+	outline, err := s.store.GetNodeOutline(id)
+	if err != nil {
+		writeError(w, r, CodeNotFound, "Node not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id": id,
+		"outline": outline,
+	})
+}
+
+func (s *Server) handleGetNodeMetadata(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	node, ok := s.visibleNode(r, id)
+	if !ok {
+		writeError(w, r, CodeNotFound, "Node not found")
+		return
+	}
+
+	typed, errs := node.Metadata.Coerce(s.schema)
+	schema := s.schema
+	if schema == nil {
+		schema = map[string]models.MetadataType{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id":  id,
+		"schema":   schema,
+		"metadata": typed,
+		"errors":   errs,
+	})
+}
+
+// --- Frontmatter keys ---
+
+// handleListMetadataKeys lists the frontmatter keys of the notes the requester
+// can see.
+func (s *Server) handleListMetadataKeys(w http.ResponseWriter, r *http.Request) {
+	nodes, err := s.store.GetAllNodes()
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch metadata keys")
+		return
+	}
+	var ids []string // nil: every note counts
+	if visible := s.visibleNodes(r, nodes); len(visible) < len(nodes) {
+		ids = make([]string, len(visible))
+		for i, n := range visible {
+			ids[i] = n.ID
+		}
+	}
+	keys, err := s.store.GetMetadataKeys(ids)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch metadata keys")
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// --- Calendar ---
+
+type calendarDay struct {
+	Date    string   `json:"date"`
+	NodeIDs []string `json:"node_ids"`
+}
+
+// handleCalendar lists the days of a month that have daily notes.
+// Query: month=YYYY-MM (defaults to the current month).
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = time.Now().Format("2006-01")
+	}
+	if _, err := time.Parse("2006-01", month); err != nil {
+		writeError(w, r, CodeBadRequest, "month must be YYYY-MM")
+		return
+	}
+
+	nodes, err := s.store.GetNodesByPathGlob("*" + month + "-[0-9][0-9]*.md")
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch daily notes")
+		return
+	}
+
+	byDate := make(map[string][]string)
+	for _, n := range s.visibleNodes(r, nodes) {
+		d, ok := vault.DailyNoteDate(n.FilePath)
+		if !ok || d.Format("2006-01") != month {
+			continue
+		}
+		key := d.Format("2006-01-02")
+		byDate[key] = append(byDate[key], n.ID)
+	}
+
+	days := make([]calendarDay, 0, len(byDate))
+	for date, ids := range byDate {
+		days = append(days, calendarDay{Date: date, NodeIDs: ids})
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"month": month,
+		"days":  days,
 	})
 }
 
 // --- Reindex ---
 
+// handleReindex runs a full index of all vaults and responds when it is
+// done, or with ?queue=true queues one behind any running index and
+// responds at once with its place in the queue.
 func (s *Server) handleReindex(w http.ResponseWriter, r *http.Request) {
 	if s.indexer == nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Indexer not configured"})
+		writeError(w, r, CodeInternal, "Indexer not configured")
 		return
 	}
 
-	if err := s.indexer.FullIndexAll(); err != nil {
+	if v := r.URL.Query().Get("queue"); v != "" {
+		queue, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, r, CodeBadRequest, "Invalid queue")
+			return
+		}
+		if queue {
+			s.queueReindex(w, r)
+			return
+		}
+	}
+
+	if err := s.indexer.FullIndexAllContext(r.Context()); err != nil {
+		if errors.Is(err, indexer.ErrIndexRunning) {
+			writeError(w, r, CodeIndexRunning, "Reindex already running")
+			return
+		}
+		if r.Context().Err() != nil {
+			log.Printf("Reindex cancelled: %v", err)
+			return
+		}
 		log.Printf("Reindex failed: %v", err)
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Reindex failed"})
+		writeError(w, r, CodeInternal, "Reindex failed")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, map[string]string{"message": "Reindex completed"})
 }
 
+func (s *Server) queueReindex(w http.ResponseWriter, r *http.Request) {
+	queued, err := s.indexer.QueueFullIndexAll()
+	if errors.Is(err, indexer.ErrIndexQueueFull) {
+		writeError(w, r, CodeIndexRunning, "Reindex queue is full")
+		return
+	}
+	if err != nil {
+		log.Printf("Queue reindex failed: %v", err)
+		writeError(w, r, CodeInternal, "Failed to queue reindex")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, queued)
+}
+
+// handleParseStatus reports the running full index with per-phase progress and
+// an ETA, or the outcome of the most recent one.
+func (s *Server) handleParseStatus(w http.ResponseWriter, r *http.Request) {
+	if s.indexer == nil {
+		writeError(w, r, CodeInternal, "Indexer not configured")
+		return
+	}
+
+	status, err := s.indexer.ParseStatus()
+	if err != nil {
+		log.Printf("Failed to get parse status: %v", err)
+		writeError(w, r, CodeInternal, "Failed to get parse status")
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleListParses returns recent full index runs, newest first, and how
+// many there are. Query: limit (default 20, max 100), offset (default 0).
+func (s *Server) handleListParses(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	offset := 0
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, r, CodeBadRequest, "Invalid offset")
+			return
+		}
+		offset = n
+	}
+
+	history, err := s.store.GetParseHistoryPage(0, "", limit, offset)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch parse history")
+		return
+	}
+	total, err := s.store.CountParseHistory(0, "")
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch parse history")
+		return
+	}
+	if history == nil {
+		history = []models.ParseHistory{}
+	}
+	s.sanitizeParses(history)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"parses": history,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// handleGetParse returns one full index run.
+func (s *Server) handleGetParse(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	h, err := s.store.GetParse(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, r, CodeNotFound, "Parse not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch parse %s: %v", id, err)
+		writeError(w, r, CodeInternal, "Failed to fetch parse")
+		return
+	}
+	history := []models.ParseHistory{*h}
+	s.sanitizeParses(history)
+	writeJSON(w, http.StatusOK, history[0])
+}
+
+// sanitizeParses removes the server's vault paths from the runs' errors,
+// which may quote the files they failed on, leaving paths relative to their
+// vault. If the vaults cannot be read, errors are replaced by a generic one.
+func (s *Server) sanitizeParses(history []models.ParseHistory) {
+	vaults, err := s.store.GetVaults()
+	for i, h := range history {
+		if h.Error == nil {
+			continue
+		}
+		msg := "Parse failed"
+		if err == nil {
+			msg = *h.Error
+			for _, v := range vaults {
+				if v.Path != "" {
+					msg = strings.ReplaceAll(msg, strings.TrimSuffix(v.Path, "/")+"/", "")
+				}
+			}
+		}
+		history[i].Error = &msg
+	}
+}
+
+// handleGetParseReport downloads the markdown report of a full index. Runs
+// that failed before storing their data have none.
+func (s *Server) handleGetParseReport(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	report, err := s.store.GetParseReport(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, r, CodeNotFound, "Parse report not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch parse report %s: %v", id, err)
+		writeError(w, r, CodeInternal, "Failed to fetch parse report")
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="parse-%s.md"`, id))
+	w.Write([]byte(report))
+}
+
+// handleGetParseErrors lists the files a full index failed to parse, or
+// parsed with problems, by path. Query: kind (read, frontmatter, encoding or
+// hook) limits the list to one kind.
+func (s *Server) handleGetParseErrors(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	errs, err := s.store.GetParseErrors(id, r.URL.Query().Get("kind"))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, r, CodeNotFound, "Parse not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch parse errors %s: %v", id, err)
+		writeError(w, r, CodeInternal, "Failed to fetch parse errors")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"parse_id": id, "errors": errs})
+}
+
+// handleGetParseLogs lists what a full index logged, in order, so failures
+// can be looked into without access to the server's output. Query: level
+// (info or warning) limits the list to one level.
+func (s *Server) handleGetParseLogs(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	level := r.URL.Query().Get("level")
+	if level != "" && level != "info" && level != "warning" {
+		writeError(w, r, CodeBadRequest, "Invalid level")
+		return
+	}
+	logs, err := s.store.GetParseLogs(id, level)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, r, CodeNotFound, "Parse not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch parse logs %s: %v", id, err)
+		writeError(w, r, CodeInternal, "Failed to fetch parse logs")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"parse_id": id, "logs": logs})
+}
+
 // --- Filter and group evaluation ---
 
 // graphConfig is the parsed structure of a GRAPH.yaml file for filter/group evaluation.
@@ -250,11 +760,16 @@ func applyFilterAndGroups(raw *store.GraphDataRaw) *models.Graph {
 
 		pos := raw.Positions[n.ID]
 		apiNodes = append(apiNodes, models.Node{
-			ID:       n.ID,
-			Title:    n.Title,
-			FilePath: n.FilePath,
-			Position: models.Position{X: pos.X, Y: pos.Y, Z: pos.Z},
-			Color:    color,
+			ID:          n.ID,
+			Title:       n.Title,
+			FilePath:    n.FilePath,
+			Position:    models.Position{X: pos.X, Y: pos.Y, Z: pos.Z},
+			Pinned:      pos.Pinned,
+			Level:       n.Level,
+			Color:       color,
+			WordCount:   n.WordCount,
+			ReadingTime: n.ReadingTime,
+			Excerpt:     n.Excerpt,
 		})
 	}
 
@@ -283,8 +798,70 @@ func writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	json.NewEncoder(w).Encode(v)
 }
 
-func readJSON(r *http.Request, v interface{}) error {
+// defaultMaxBodySize limits request bodies unless SetMaxBodySize overrides it.
+const defaultMaxBodySize = 10 << 20
+
+// SetMaxBodySize limits request bodies to n bytes; larger ones are rejected
+// with 413. Zero or less restores the 10 MB default.
+func (s *Server) SetMaxBodySize(n int64) {
+	if n <= 0 {
+		n = defaultMaxBodySize
+	}
+	s.maxBodySize = n
+}
+
+// limitBody caps the request body at the configured size.
+func (s *Server) limitBody(w http.ResponseWriter, r *http.Request) io.Reader {
+	return http.MaxBytesReader(w, r.Body, s.maxBodySize)
+}
+
+// readJSON decodes a request body into v. Decoding is strict: unknown fields
+// and anything after the JSON value are rejected. On failure it writes a 413
+// or 400 error saying what was wrong and returns false.
+func (s *Server) readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
 	defer r.Body.Close()
-	r.Body = http.MaxBytesReader(nil, r.Body, 10<<20) // 10MB limit
-	return json.NewDecoder(r.Body).Decode(v)
+	dec := json.NewDecoder(s.limitBody(w, r))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil {
+		if _, extra := dec.Token(); extra != io.EOF {
+			err = errors.New("unexpected data after JSON value")
+		}
+	}
+	if err != nil {
+		writeBodyError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// writeBodyError reports why a request body could not be read, with the
+// offending field or the size limit in the details where known.
+func writeBodyError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	var syntax *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeErrorDetails(w, r, CodeBodyTooLarge, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit),
+			map[string]int64{"limit": tooLarge.Limit})
+	case errors.Is(err, io.EOF):
+		writeError(w, r, CodeInvalidBody, "Request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		writeError(w, r, CodeInvalidBody, "Invalid request body: unexpected end of JSON")
+	case errors.As(err, &syntax):
+		writeErrorDetails(w, r, CodeInvalidBody, fmt.Sprintf("Invalid request body: malformed JSON at byte %d", syntax.Offset),
+			map[string]int64{"offset": syntax.Offset})
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		writeErrorDetails(w, r, CodeInvalidBody, fmt.Sprintf("Invalid request body: field %q must be %s", typeErr.Field, typeErr.Type),
+			map[string]string{"field": typeErr.Field})
+	case errors.As(err, &typeErr):
+		writeError(w, r, CodeInvalidBody, fmt.Sprintf("Invalid request body: expected %s", typeErr.Type))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		writeErrorDetails(w, r, CodeInvalidBody, fmt.Sprintf("Invalid request body: unknown field %q", field),
+			map[string]string{"field": field})
+	default:
+		writeError(w, r, CodeInvalidBody, "Invalid request body: "+err.Error())
+	}
 }
@@ -2,15 +2,28 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/ali01/mnemosyne/internal/access"
+	"github.com/ali01/mnemosyne/internal/indexer"
+	"github.com/ali01/mnemosyne/internal/mnemosynetest"
+	"github.com/ali01/mnemosyne/internal/layout"
 	"github.com/ali01/mnemosyne/internal/models"
 	"github.com/ali01/mnemosyne/internal/store"
+	"github.com/ali01/mnemosyne/internal/vault"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -29,27 +42,7 @@ func newTestServer(t *testing.T) (*Server, *store.Store) {
 // Returns the graph ID.
 func seedGraph(t *testing.T, s *store.Store) int {
 	t.Helper()
-	vid, err := s.UpsertVault("test", "/test")
-	require.NoError(t, err)
-	gid, err := s.UpsertGraph(vid, "root", "", "")
-	require.NoError(t, err)
-
-	require.NoError(t, s.UpsertNode(&models.VaultNode{
-		ID: "a", VaultID: vid, Title: "Aviation", FilePath: "aviation.md", NodeType: "hub",
-		Content: "All about aviation.", CreatedAt: time.Now(), UpdatedAt: time.Now(),
-	}))
-	require.NoError(t, s.UpsertNode(&models.VaultNode{
-		ID: "b", VaultID: vid, Title: "Economics", FilePath: "econ.md", NodeType: "note",
-		Content: "Supply and demand.", CreatedAt: time.Now(), UpdatedAt: time.Now(),
-	}))
-	require.NoError(t, s.UpsertEdge(&models.VaultEdge{
-		ID: "e1", SourceID: "a", TargetID: "b", EdgeType: "wikilink", Weight: 1,
-	}))
-	require.NoError(t, s.ReplaceGraphMemberships("a", []int{gid}))
-	require.NoError(t, s.ReplaceGraphMemberships("b", []int{gid}))
-	require.NoError(t, s.UpsertPosition(gid, &models.NodePosition{NodeID: "a", X: 10, Y: 20}))
-
-	return gid
+	return mnemosynetest.SeedGraph(t, s).GraphID
 }
 
 func doRequest(handler http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
@@ -123,6 +116,33 @@ func TestGetGraphData(t *testing.T) {
 	}
 }
 
+func TestGetGraphDataAdjacency(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{ID: "e2", SourceID: "b", TargetID: "a", EdgeType: "embed", Weight: 1}))
+	path := "/api/v1/graphs/" + strconv.Itoa(gid)
+
+	w := doRequest(srv.Handler(), "GET", path+"?format=adjacency", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var adj adjacencyGraph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &adj))
+	assert.Equal(t, "adjacency", adj.Format)
+	require.Len(t, adj.Nodes, 2)
+	require.Len(t, adj.Adjacency, 2)
+
+	// Expand back to edges
+	var edges []string
+	for i, out := range adj.Adjacency {
+		for _, e := range out {
+			edges = append(edges, adj.Nodes[i].ID+"->"+adj.Nodes[e[0]].ID+":"+adj.EdgeTypes[e[1]])
+		}
+	}
+	assert.ElementsMatch(t, []string{"a->b:wikilink", "b->a:embed"}, edges)
+
+	w = doRequest(srv.Handler(), "GET", path+"?format=csv", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestGetGraphDataInvalidID(t *testing.T) {
 	srv, _ := newTestServer(t)
 	w := doRequest(srv.Handler(), "GET", "/api/v1/graphs/abc", nil)
@@ -168,162 +188,965 @@ func TestGetNode(t *testing.T) {
 	assert.Equal(t, "Aviation", node.Title)
 }
 
-func TestGetNodeNotFound(t *testing.T) {
+func TestGetNodeIncludesReadingMetrics(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "long", VaultID: vid, Title: "Long Read", FilePath: "long.md",
+		WordCount: 900, ReadingTime: 5, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+
+	w := doRequest(srv.Handler(), "GET", "/api/v1/nodes/long", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var node models.Node
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &node))
+	assert.Equal(t, 900, node.WordCount)
+	assert.Equal(t, 5, node.ReadingTime)
+}
+
+func TestGetNodeOutline(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "o", VaultID: vid, Title: "Outlined", FilePath: "o.md",
+		Outline:   []models.Heading{{Level: 1, Text: "Intro", Slug: "intro", Line: 1}},
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+
+	w := doRequest(srv.Handler(), "GET", "/api/v1/nodes/o/outline", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Outline []models.Heading `json:"outline"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Outline, 1)
+	assert.Equal(t, "intro", resp.Outline[0].Slug)
+}
+
+func TestGetNodeOutlineNotFound(t *testing.T) {
 	srv, _ := newTestServer(t)
-	w := doRequest(srv.Handler(), "GET", "/api/v1/nodes/nonexistent", nil)
+	w := doRequest(srv.Handler(), "GET", "/api/v1/nodes/missing/outline", nil)
 	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
-// --- Positions ---
+func TestSearchNode(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	long := strings.Repeat("x", 50)
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "n", VaultID: vid, Title: "Note", FilePath: "n.md",
+		Content:   "---\nid: n\n---\nCafé GO go\n" + long + " Go " + long + "\r\nlast go",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	h := srv.Handler()
+
+	type match struct {
+		Offset int    `json:"offset"`
+		Length int    `json:"length"`
+		Line   int    `json:"line"`
+		Column int    `json:"column"`
+		Before string `json:"before"`
+		Match  string `json:"match"`
+		After  string `json:"after"`
+	}
+	var resp struct {
+		NodeID  string  `json:"node_id"`
+		Total   int     `json:"total"`
+		Matches []match `json:"matches"`
+	}
+	w := doRequest(h, "GET", "/api/v1/nodes/n/search?q=go", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "n", resp.NodeID)
+	assert.Equal(t, 4, resp.Total)
+	require.Len(t, resp.Matches, 4)
+	assert.Equal(t, match{Offset: 20, Length: 2, Line: 4, Column: 6, Before: "Café ", Match: "GO", After: " go"}, resp.Matches[0])
+	assert.Equal(t, 9, resp.Matches[1].Column)
+	assert.Equal(t, match{Offset: 77, Length: 2, Line: 5, Column: 52, Before: "…" + long[11:] + " ", Match: "Go", After: " " + long[11:] + "…"}, resp.Matches[2])
+	assert.Equal(t, match{Offset: 137, Length: 2, Line: 6, Column: 6, Before: "last ", Match: "go", After: ""}, resp.Matches[3])
+
+	w = doRequest(h, "GET", "/api/v1/nodes/n/search?q=go&case_sensitive=true&limit=1", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Total)
+	require.Len(t, resp.Matches, 1)
+	assert.Equal(t, 23, resp.Matches[0].Offset)
 
-func TestUpdateGraphPosition(t *testing.T) {
+	// Queries are text, not patterns
+	w = doRequest(h, "GET", "/api/v1/nodes/n/search?q=.%2A", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 0, resp.Total)
+	assert.Empty(t, resp.Matches)
+
+	assert.Equal(t, http.StatusBadRequest, doRequest(h, "GET", "/api/v1/nodes/n/search", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, doRequest(h, "GET", "/api/v1/nodes/n/search?q=go&limit=0", nil).Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(h, "GET", "/api/v1/nodes/missing/search?q=go", nil).Code)
+}
+
+func TestGetNeighbors(t *testing.T) {
 	srv, s := newTestServer(t)
-	gid := seedGraph(t, s)
+	seed := mnemosynetest.SeedGraph(t, s)
+	// a -> b -> c -> d, and d -> a; c is outside the graph
+	for _, id := range []string{"c", "d"} {
+		require.NoError(t, s.UpsertNode(&models.VaultNode{ID: id, VaultID: seed.VaultID, Title: id, FilePath: id + ".md"}))
+	}
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{ID: "e2", SourceID: "b", TargetID: "c", EdgeType: "wikilink", Weight: 1}))
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{ID: "e3", SourceID: "c", TargetID: "d", EdgeType: "wikilink", Weight: 1}))
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{ID: "e4", SourceID: "d", TargetID: "a", EdgeType: "wikilink", Weight: 1}))
+	require.NoError(t, s.ReplaceGraphMemberships("d", []int{seed.GraphID}))
+	h := srv.Handler()
+
+	type neighborhood struct {
+		Nodes     []models.Node  `json:"nodes"`
+		Edges     []models.Edge  `json:"edges"`
+		Hops      map[string]int `json:"hops"`
+		Truncated bool           `json:"truncated"`
+	}
+	get := func(url string) neighborhood {
+		t.Helper()
+		w := doRequest(h, "GET", url, nil)
+		require.Equal(t, http.StatusOK, w.Code, url)
+		var resp neighborhood
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	edgeIDs := func(resp neighborhood) []string {
+		var ids []string
+		for _, e := range resp.Edges {
+			ids = append(ids, e.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
 
-	pos := models.NodePosition{X: 42, Y: 99}
-	w := doRequest(srv.Handler(), "PUT", "/api/v1/graphs/"+strconv.Itoa(gid)+"/positions/a", pos)
-	assert.Equal(t, http.StatusOK, w.Code)
+	resp := get("/api/v1/nodes/a/neighbors")
+	assert.Equal(t, map[string]int{"a": 0, "b": 1, "d": 1}, resp.Hops)
+	assert.Equal(t, []string{"e1", "e4"}, edgeIDs(resp))
+	assert.False(t, resp.Truncated)
 
-	graph, _ := s.GetGraphData(gid)
-	for _, n := range graph.Nodes {
+	resp = get("/api/v1/nodes/a/neighbors?depth=2&direction=out")
+	assert.Equal(t, map[string]int{"a": 0, "b": 1, "c": 2}, resp.Hops)
+	assert.Equal(t, []string{"e1", "e2"}, edgeIDs(resp))
+
+	resp = get("/api/v1/nodes/a/neighbors?depth=2&direction=in")
+	assert.Equal(t, map[string]int{"a": 0, "d": 1, "c": 2}, resp.Hops)
+	assert.Equal(t, []string{"e3", "e4"}, edgeIDs(resp))
+
+	// In the graph, c is neither returned nor followed through
+	resp = get(fmt.Sprintf("/api/v1/nodes/b/neighbors?depth=5&graph_id=%d", seed.GraphID))
+	assert.Equal(t, map[string]int{"b": 0, "a": 1, "d": 2}, resp.Hops)
+	assert.Equal(t, []string{"e1", "e4"}, edgeIDs(resp))
+	for _, n := range resp.Nodes {
 		if n.ID == "a" {
-			assert.InDelta(t, 42, n.Position.X, 0.01)
+			assert.InDelta(t, 10, n.Position.X, 0.01)
 		}
 	}
+
+	assert.Equal(t, http.StatusBadRequest, doRequest(h, "GET", "/api/v1/nodes/a/neighbors?depth=0", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, doRequest(h, "GET", "/api/v1/nodes/a/neighbors?depth=6", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, doRequest(h, "GET", "/api/v1/nodes/a/neighbors?direction=up", nil).Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(h, "GET", "/api/v1/nodes/missing/neighbors", nil).Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(h, "GET", fmt.Sprintf("/api/v1/nodes/c/neighbors?graph_id=%d", seed.GraphID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(h, "GET", "/api/v1/nodes/a/neighbors?graph_id=999", nil).Code)
 }
 
-func TestUpdateGraphPositionsBatch(t *testing.T) {
+func TestGetNodeMetadataTyped(t *testing.T) {
 	srv, s := newTestServer(t)
-	gid := seedGraph(t, s)
+	srv.SetMetadataSchema(map[string]models.MetadataType{
+		"reviews": models.MetadataInteger,
+		"draft":   models.MetadataBoolean,
+		"tags":    models.MetadataList,
+	})
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "m", VaultID: vid, Title: "Meta", FilePath: "m.md",
+		Metadata:  models.JSONMetadata{"reviews": "3", "draft": "perhaps", "tags": "a, b"},
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
 
-	batch := []models.NodePosition{
-		{NodeID: "a", X: 1, Y: 2},
-		{NodeID: "b", X: 3, Y: 4},
-	}
-	w := doRequest(srv.Handler(), "PUT", "/api/v1/graphs/"+strconv.Itoa(gid)+"/positions", batch)
+	w := doRequest(srv.Handler(), "GET", "/api/v1/nodes/m/metadata", nil)
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	graph, _ := s.GetGraphData(gid)
-	assert.Len(t, graph.Nodes, 2)
+	var resp struct {
+		Metadata map[string]interface{} `json:"metadata"`
+		Errors   map[string]string      `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(3), resp.Metadata["reviews"])
+	assert.Equal(t, []interface{}{"a", "b"}, resp.Metadata["tags"])
+	assert.Contains(t, resp.Errors, "draft")
 }
 
-func TestUpdateGraphPositionsEmpty(t *testing.T) {
+func TestListMetadataKeys(t *testing.T) {
 	srv, s := newTestServer(t)
-	gid := seedGraph(t, s)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "k", VaultID: vid, Title: "Keys", FilePath: "k.md",
+		Metadata:  models.JSONMetadata{"status": "draft"},
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
 
-	w := doRequest(srv.Handler(), "PUT", "/api/v1/graphs/"+strconv.Itoa(gid)+"/positions", []models.NodePosition{})
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w := doRequest(srv.Handler(), "GET", "/api/v1/metadata/keys", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var keys []models.MetadataKey
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &keys))
+	require.Len(t, keys, 1)
+	assert.Equal(t, "status", keys[0].Key)
+	assert.Equal(t, 1, keys[0].Count)
 }
 
-// --- Reindex ---
+func TestListMetadataKeysHidden(t *testing.T) {
+	srv, s := newTestServer(t)
+	seedPublishGraph(t, srv, s)
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{
+		"tok":     {Name: "alice"},
+		"bob-tok": {Name: "bob"},
+	}))
+	b, err := s.GetNode("b")
+	require.NoError(t, err)
+	b.Metadata = models.JSONMetadata{"secret": "x"}
+	require.NoError(t, s.UpsertNode(b))
+	srv.policy.SetNodeACL("b", []string{"bob"})
+	h := srv.Handler()
+
+	keys := func(token string) []string {
+		w := doAuthRequest(h, "GET", "/api/v1/metadata/keys", token)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp []models.MetadataKey
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		var out []string
+		for _, k := range resp {
+			out = append(out, k.Key)
+		}
+		return out
+	}
+	// Anonymous requests only count published notes, and users only the
+	// notes their ACLs allow
+	assert.Equal(t, []string{"publish"}, keys(""))
+	assert.Equal(t, []string{"publish"}, keys("tok"))
+	assert.ElementsMatch(t, []string{"publish", "secret"}, keys("bob-tok"))
+}
 
-func TestReindexNoIndexer(t *testing.T) {
+func TestGetNodeNotFound(t *testing.T) {
 	srv, _ := newTestServer(t)
-	w := doRequest(srv.Handler(), "POST", "/api/v1/reindex", nil)
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	w := doRequest(srv.Handler(), "GET", "/api/v1/nodes/nonexistent", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
-// --- Filter and Groups ---
+// --- Calendar ---
 
-// seedGraphWithConfig creates a vault, graph with config, nodes with tags, and edges.
-func seedGraphWithConfig(t *testing.T, s *store.Store, config string) int {
-	t.Helper()
+func TestCalendar(t *testing.T) {
+	srv, s := newTestServer(t)
 	vid, err := s.UpsertVault("test", "/test")
 	require.NoError(t, err)
-	gid, err := s.UpsertGraph(vid, "root", "", config)
-	require.NoError(t, err)
+	for id, path := range map[string]string{
+		"d1":    "journal/2024-06-01.md",
+		"d2":    "journal/2024-06-15 Saturday.md",
+		"d3":    "archive/2024-06-15.md",
+		"other": "journal/2024-07-01.md",
+		"plain": "notes/2024-06-01-plan.md",
+	} {
+		require.NoError(t, s.UpsertNode(&models.VaultNode{
+			ID: id, VaultID: vid, Title: id, FilePath: path, CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}))
+	}
+
+	w := doRequest(srv.Handler(), "GET", "/api/v1/calendar?month=2024-06", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Month string        `json:"month"`
+		Days  []calendarDay `json:"days"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "2024-06", resp.Month)
+	require.Len(t, resp.Days, 2)
+	assert.Equal(t, "2024-06-01", resp.Days[0].Date)
+	assert.Equal(t, []string{"d1"}, resp.Days[0].NodeIDs)
+	assert.Equal(t, "2024-06-15", resp.Days[1].Date)
+	assert.ElementsMatch(t, []string{"d2", "d3"}, resp.Days[1].NodeIDs)
+}
+
+func TestCalendarInvalidMonth(t *testing.T) {
+	srv, _ := newTestServer(t)
+	w := doRequest(srv.Handler(), "GET", "/api/v1/calendar?month=June", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// --- Feed ---
 
+func TestFeed(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
 	require.NoError(t, s.UpsertNode(&models.VaultNode{
-		ID: "a", VaultID: vid, Title: "Aviation", FilePath: "concepts/aviation.md",
-		Tags: models.StringArray{"index"}, Content: "Aviation note.",
+		ID: "pub", VaultID: vid, Title: "Published Post", FilePath: "pub.md",
+		Content:   "---\nid: pub\npublic: true\n---\nHello   **world**. %%private%%\n",
+		Excerpt:   "Hello world.",
+		Metadata:  models.JSONMetadata{"public": true},
+		Tags:      models.StringArray{"blog"},
 		CreatedAt: time.Now(), UpdatedAt: time.Now(),
 	}))
 	require.NoError(t, s.UpsertNode(&models.VaultNode{
-		ID: "b", VaultID: vid, Title: "Economics", FilePath: "concepts/econ.md",
-		Tags: models.StringArray{"open-question"}, Content: "Econ note.",
+		ID: "priv", VaultID: vid, Title: "Private Diary", FilePath: "priv.md",
 		CreatedAt: time.Now(), UpdatedAt: time.Now(),
 	}))
-	require.NoError(t, s.UpsertNode(&models.VaultNode{
-		ID: "c", VaultID: vid, Title: "Projects", FilePath: "projects/proj.md",
-		Content: "Projects note.", CreatedAt: time.Now(), UpdatedAt: time.Now(),
-	}))
-	require.NoError(t, s.UpsertEdge(&models.VaultEdge{
-		ID: "e1", SourceID: "a", TargetID: "b", EdgeType: "wikilink", Weight: 1,
-	}))
-	require.NoError(t, s.UpsertEdge(&models.VaultEdge{
-		ID: "e2", SourceID: "a", TargetID: "c", EdgeType: "wikilink", Weight: 1,
-	}))
-	require.NoError(t, s.ReplaceGraphMemberships("a", []int{gid}))
-	require.NoError(t, s.ReplaceGraphMemberships("b", []int{gid}))
-	require.NoError(t, s.ReplaceGraphMemberships("c", []int{gid}))
 
-	return gid
+	w := doRequest(srv.Handler(), "GET", "/feed.xml", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/atom+xml")
+
+	body := w.Body.String()
+	assert.Contains(t, body, "<title>Published Post</title>")
+	assert.Contains(t, body, "<summary>Hello world.</summary>", "the note's excerpt")
+	assert.NotContains(t, body, "private")
+	assert.Contains(t, body, `<category term="blog">`)
+	assert.Contains(t, body, "/api/v1/nodes/pub")
+	assert.NotContains(t, body, "Private Diary")
+
+	// The access policy's publish flag replaces the default "public" flag
+	srv.SetAccessPolicy(&access.Policy{PublishFlag: "publish"})
+	w = doRequest(srv.Handler(), "GET", "/feed.xml", nil)
+	assert.NotContains(t, w.Body.String(), "Published Post")
 }
 
-func TestGetGraphDataWithGroups(t *testing.T) {
+// --- External links ---
+
+func TestExternalLinks(t *testing.T) {
 	srv, s := newTestServer(t)
-	config := `groups:
-  - query: "tag:#index"
-    color: "#E05555"
-  - query: "tag:#open-question"
-    color: "#E5A84B"
-`
-	gid := seedGraphWithConfig(t, s, config)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "pub", VaultID: vid, Title: "Published", FilePath: "pub.md",
+		Metadata:  models.JSONMetadata{"publish": true},
+		URLs:      models.StringArray{"https://a.example.com", "https://b.example.com"},
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "priv", VaultID: vid, Title: "Private", FilePath: "priv.md",
+		URLs:      models.StringArray{"https://b.example.com", "https://secret.example.com"},
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, s.SaveLinkCheck(models.LinkCheck{URL: "https://b.example.com", Status: 404, Dead: true, CheckedAt: time.Now()}))
+	h := srv.Handler()
 
-	w := doRequest(srv.Handler(), "GET", "/api/v1/graphs/"+strconv.Itoa(gid), nil)
-	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Links []models.ExternalLink `json:"links"`
+	}
+	w := doRequest(h, "GET", "/api/v1/vault/external-links", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Links, 3)
+	assert.Equal(t, "https://a.example.com", resp.Links[0].URL)
+	assert.Nil(t, resp.Links[0].CheckedAt, "not checked yet")
+	assert.Equal(t, []string{"priv", "pub"}, resp.Links[1].NodeIDs)
+	assert.True(t, resp.Links[1].Dead)
+	assert.Equal(t, 404, resp.Links[1].Status)
+
+	w = doRequest(h, "GET", "/api/v1/vault/external-links?dead=true", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Links, 1)
+	assert.Equal(t, "https://b.example.com", resp.Links[0].URL)
 
-	var graph models.Graph
-	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
-	assert.Len(t, graph.Nodes, 3)
-	assert.Len(t, graph.Edges, 2)
+	// Anonymous requests only see published notes and the URLs they link to
+	srv.SetAccessPolicy(&access.Policy{PublishFlag: "publish"})
+	w = doRequest(h, "GET", "/api/v1/vault/external-links", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Links, 2)
+	assert.Equal(t, []string{"pub"}, resp.Links[1].NodeIDs)
 
-	// Check colors assigned by groups
-	colors := map[string]string{}
-	for _, n := range graph.Nodes {
-		colors[n.ID] = n.Color
-	}
-	assert.Equal(t, "#E05555", colors["a"]) // has tag:index
-	assert.Equal(t, "#E5A84B", colors["b"]) // has tag:open-question
-	assert.Equal(t, "", colors["c"])         // no matching group
+	w = doRequest(h, "GET", "/api/v1/vault/external-links?dead=maybe", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
-func TestGetGraphDataWithFilter(t *testing.T) {
+func TestBrokenLinks(t *testing.T) {
 	srv, s := newTestServer(t)
-	config := `filter: "path:concepts"
-`
-	gid := seedGraphWithConfig(t, s, config)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "pub", VaultID: vid, Title: "Published", FilePath: "pub.md",
+		Metadata:   models.JSONMetadata{"publish": true},
+		Unresolved: models.StringArray{"missing", "gone"},
+		CreatedAt:  time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "priv", VaultID: vid, Title: "Private", FilePath: "priv.md",
+		Unresolved: models.StringArray{"missing", "secret"},
+		CreatedAt:  time.Now(), UpdatedAt: time.Now(),
+	}))
+	h := srv.Handler()
 
-	w := doRequest(srv.Handler(), "GET", "/api/v1/graphs/"+strconv.Itoa(gid), nil)
-	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Links []models.BrokenLink `json:"links"`
+	}
+	w := doRequest(h, "GET", "/api/v1/vault/broken-links", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []models.BrokenLink{
+		{Target: "gone", NodeIDs: []string{"pub"}},
+		{Target: "missing", NodeIDs: []string{"priv", "pub"}},
+		{Target: "secret", NodeIDs: []string{"priv"}},
+	}, resp.Links)
+
+	// Anonymous requests only see published notes and the targets they link to
+	srv.SetAccessPolicy(&access.Policy{PublishFlag: "publish"})
+	w = doRequest(h, "GET", "/api/v1/vault/broken-links", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []models.BrokenLink{
+		{Target: "gone", NodeIDs: []string{"pub"}},
+		{Target: "missing", NodeIDs: []string{"pub"}},
+	}, resp.Links)
+}
 
-	var graph models.Graph
-	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+func TestUnlinkedMentions(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	mention := func(target, text string, count float64) models.VaultEdge {
+		return models.VaultEdge{TargetID: target, EdgeType: vault.UnlinkedMentionType, DisplayText: text, Weight: count}
+	}
+	for _, n := range []models.VaultNode{
+		// Targets may be stored after the notes naming them
+		{ID: "pub", Title: "Published", FilePath: "pub.md", Metadata: models.JSONMetadata{"publish": true},
+			Potential: []models.VaultEdge{mention("pub2", "published too", 2), mention("priv", "Private", 1), mention("gone", "Gone", 1)}},
+		{ID: "pub2", Title: "Published too", FilePath: "pub2.md", Metadata: models.JSONMetadata{"publish": true},
+			Potential: []models.VaultEdge{mention("pub", "published", 1)}},
+		{ID: "priv", Title: "Private", FilePath: "priv.md"},
+	} {
+		n.VaultID, n.CreatedAt, n.UpdatedAt = vid, time.Now(), time.Now()
+		require.NoError(t, s.UpsertNode(&n))
+	}
+	h := srv.Handler()
 
-	// Only concepts/ nodes should be included (a and b), not projects/ (c)
-	assert.Len(t, graph.Nodes, 2)
-	ids := map[string]bool{}
-	for _, n := range graph.Nodes {
-		ids[n.ID] = true
+	type mentionJSON struct {
+		SourceID string `json:"source_id"`
+		TargetID string `json:"target_id"`
+		Text     string `json:"text"`
+		Count    int    `json:"count"`
+	}
+	var resp struct {
+		Mentions []mentionJSON `json:"mentions"`
+	}
+	get := func(url string) []mentionJSON {
+		t.Helper()
+		w := doRequest(h, "GET", url, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		resp.Mentions = nil
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Mentions
 	}
-	assert.True(t, ids["a"])
-	assert.True(t, ids["b"])
-	assert.False(t, ids["c"])
 
-	// Edge a→c should be pruned (c filtered out), only a→b remains
-	assert.Len(t, graph.Edges, 1)
-	assert.Equal(t, "a", graph.Edges[0].Source)
-	assert.Equal(t, "b", graph.Edges[0].Target)
+	// Mentions of notes that are not stored are left out
+	assert.Equal(t, []mentionJSON{
+		{SourceID: "pub", TargetID: "priv", Text: "Private", Count: 1},
+		{SourceID: "pub", TargetID: "pub2", Text: "published too", Count: 2},
+		{SourceID: "pub2", TargetID: "pub", Text: "published", Count: 1},
+	}, get("/api/v1/vault/unlinked-mentions"))
+	assert.Equal(t, []mentionJSON{
+		{SourceID: "pub2", TargetID: "pub", Text: "published", Count: 1},
+	}, get("/api/v1/vault/unlinked-mentions?target=pub"))
+	assert.Len(t, get("/api/v1/vault/unlinked-mentions?source=pub"), 2)
+
+	// Anonymous requests only see mentions between published notes
+	srv.SetAccessPolicy(&access.Policy{PublishFlag: "publish"})
+	assert.Len(t, get("/api/v1/vault/unlinked-mentions"), 2)
+	assert.Empty(t, get("/api/v1/vault/unlinked-mentions?target=priv"))
 }
 
-func TestGetGraphDataNoConfig(t *testing.T) {
+func TestNearDuplicates(t *testing.T) {
 	srv, s := newTestServer(t)
-	gid := seedGraph(t, s)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	for _, n := range []models.VaultNode{
+		{ID: "pub", Title: "Published", FilePath: "pub.md", Metadata: models.JSONMetadata{"publish": true}},
+		{ID: "pub-copy", Title: "Published copy", FilePath: "pub-copy.md", Metadata: models.JSONMetadata{"publish": true}},
+		{ID: "priv", Title: "Private", FilePath: "priv.md"},
+	} {
+		n.VaultID, n.CreatedAt, n.UpdatedAt = vid, time.Now(), time.Now()
+		require.NoError(t, s.UpsertNode(&n))
+	}
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, s.ReplaceNearDuplicates(context.Background(), []models.NearDuplicate{
+		{A: "priv", B: "pub", Similarity: 0.85, DetectedAt: at},
+		{A: "pub", B: "pub-copy", Similarity: 0.97, DetectedAt: at},
+	}))
+	h := srv.Handler()
 
-	w := doRequest(srv.Handler(), "GET", "/api/v1/graphs/"+strconv.Itoa(gid), nil)
-	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Pairs []models.NearDuplicate `json:"pairs"`
+	}
+	w := doRequest(h, "GET", "/api/v1/vault/duplicates", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []models.NearDuplicate{
+		{A: "pub", B: "pub-copy", Similarity: 0.97, DetectedAt: at},
+		{A: "priv", B: "pub", Similarity: 0.85, DetectedAt: at},
+	}, resp.Pairs)
 
-	var graph models.Graph
+	w = doRequest(h, "GET", "/api/v1/vault/duplicates?min=0.9", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Pairs, 1)
+	w = doRequest(h, "GET", "/api/v1/vault/duplicates?min=2", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Anonymous requests only see pairs of published notes
+	srv.SetAccessPolicy(&access.Policy{PublishFlag: "publish"})
+	w = doRequest(h, "GET", "/api/v1/vault/duplicates", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Pairs, 1)
+	assert.Equal(t, "pub-copy", resp.Pairs[0].B)
+}
+
+// --- Positions ---
+
+func TestUpdateGraphPosition(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+
+	pos := models.NodePosition{X: 42, Y: 99}
+	w := doRequest(srv.Handler(), "PUT", "/api/v1/graphs/"+strconv.Itoa(gid)+"/positions/a", pos)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	graph, _ := s.GetGraphData(gid)
+	for _, n := range graph.Nodes {
+		if n.ID == "a" {
+			assert.InDelta(t, 42, n.Position.X, 0.01)
+		}
+	}
+}
+
+func TestUpdateGraphPositionsBatch(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+
+	batch := []models.NodePosition{
+		{NodeID: "a", X: 1, Y: 2},
+		{NodeID: "b", X: 3, Y: 4},
+	}
+	w := doRequest(srv.Handler(), "PUT", "/api/v1/graphs/"+strconv.Itoa(gid)+"/positions", batch)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	graph, _ := s.GetGraphData(gid)
+	assert.Len(t, graph.Nodes, 2)
+}
+
+func TestUpdateGraphPositionsEmpty(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+
+	w := doRequest(srv.Handler(), "PUT", "/api/v1/graphs/"+strconv.Itoa(gid)+"/positions", []models.NodePosition{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateGraphPositionsValidation(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	srv.SetMaxCoordinate(1000)
+	h := srv.Handler()
+	base := "/api/v1/graphs/" + strconv.Itoa(gid) + "/positions"
+
+	w := doRequest(h, "PUT", base+"/a", models.NodePosition{X: 1000.5, Y: 0})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	e := decodeError(t, w)
+	assert.Equal(t, CodeValidationFailed, e.Code)
+	assert.Equal(t, map[string]interface{}{"field": "x"}, e.Details)
+
+	w = doRequest(h, "PUT", base+"/unknown", models.NodePosition{X: 1, Y: 2})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "node_id", decodeError(t, w).Details.(map[string]interface{})["field"])
+
+	w = doRequest(h, "PUT", base, []models.NodePosition{
+		{NodeID: "a", X: 1, Y: 2},
+		{NodeID: "b", X: 3, Y: -2000},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, map[string]interface{}{"field": "y", "index": 1.0}, decodeError(t, w).Details)
+
+	w = doRequest(h, "PUT", base, []models.NodePosition{
+		{NodeID: "a", X: 1, Y: 2},
+		{NodeID: "gone", X: 3, Y: 4},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	details := decodeError(t, w).Details.(map[string]interface{})
+	assert.Equal(t, 1.0, details["index"])
+	assert.Equal(t, []interface{}{"gone"}, details["missing"])
+
+	// Nothing was saved
+	positions, err := s.GetPositionsByGraph(gid)
+	require.NoError(t, err)
+	assert.InDelta(t, 10, positions["a"].X, 0.01)
+	assert.NotContains(t, positions, "b")
+}
+
+// --- Edges ---
+
+func TestBatchEdges(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "c", VaultID: vid, Title: "Outside", FilePath: "c.md", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{ID: "e2", SourceID: "c", TargetID: "a", EdgeType: "wikilink", Weight: 1}))
+	h := srv.Handler()
+
+	edgeIDs := func(w *httptest.ResponseRecorder) []string {
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Edges []models.Edge `json:"edges"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		ids := []string{}
+		for _, e := range resp.Edges {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	w := doRequest(h, "POST", "/api/v1/edges/batch", map[string]interface{}{"node_ids": []string{"a"}})
+	assert.ElementsMatch(t, []string{"e1", "e2"}, edgeIDs(w))
+
+	w = doRequest(h, "POST", "/api/v1/edges/batch", map[string]interface{}{"node_ids": []string{"a"}, "graph_id": gid})
+	assert.Equal(t, []string{"e1"}, edgeIDs(w))
+
+	w = doRequest(h, "POST", "/api/v1/edges/batch", map[string]interface{}{"node_ids": []string{"b", "missing"}})
+	assert.Equal(t, []string{"e1"}, edgeIDs(w))
+
+	w = doRequest(h, "POST", "/api/v1/edges/batch", map[string]interface{}{"node_ids": []string{}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(h, "POST", "/api/v1/edges/batch", map[string]interface{}{"node_ids": []string{"a"}, "graph_id": 999})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// --- Graph deltas ---
+
+func TestGraphDelta(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	h := srv.Handler()
+
+	getDelta := func(since int64) graphDelta {
+		w := doRequest(h, "GET", fmt.Sprintf("/api/v1/graph/delta?graph_id=%d&since=%d", gid, since), nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var delta graphDelta
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &delta))
+		return delta
+	}
+
+	v0 := getDelta(0).Version
+	delta := getDelta(v0)
+	assert.False(t, delta.Reset)
+	assert.Empty(t, delta.Nodes)
+	assert.Empty(t, delta.Positions)
+
+	// Add c linked from b, move a, and remove the edge a->b
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "c", VaultID: vid, Title: "Climate", FilePath: "climate.md", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, s.ReplaceGraphMemberships("c", []int{gid}))
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{ID: "e2", SourceID: "b", TargetID: "c", EdgeType: "wikilink", Weight: 1}))
+	require.NoError(t, s.DeleteEdgesBySource("a"))
+	require.NoError(t, s.UpsertPosition(gid, &models.NodePosition{NodeID: "a", X: 7, Y: 8}))
+
+	delta = getDelta(v0)
+	assert.False(t, delta.Reset)
+	assert.Greater(t, delta.Version, v0)
+	require.Len(t, delta.Nodes, 1)
+	assert.Equal(t, "c", delta.Nodes[0].ID)
+	require.Len(t, delta.Edges, 1)
+	assert.Equal(t, "e2", delta.Edges[0].ID)
+	assert.Equal(t, []string{"e1"}, delta.RemovedEdges)
+	assert.Empty(t, delta.RemovedNodes)
+	assert.Equal(t, map[string]deltaPosition{"a": {Position: models.Position{X: 7, Y: 8}}}, delta.Positions)
+
+	v1 := delta.Version
+	require.NoError(t, s.DeleteNode("c"))
+	delta = getDelta(v1)
+	assert.Equal(t, []string{"c"}, delta.RemovedNodes)
+	assert.Equal(t, []string{"e2"}, delta.RemovedEdges)
+
+	// Config changes and unknown versions reset the client
+	_, err = s.UpsertGraph(vid, "root", "", `{"filter": "tag:x"}`)
+	require.NoError(t, err)
+	assert.True(t, getDelta(delta.Version).Reset)
+	assert.True(t, getDelta(1 << 40).Reset)
+
+	w := doRequest(h, "GET", "/api/v1/graph/delta?graph_id=999&since=0", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(h, "GET", fmt.Sprintf("/api/v1/graph/delta?graph_id=%d", gid), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGraphDeltaHiddenNodes(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedPublishGraph(t, srv, s)
+	h := srv.Handler()
+
+	getDelta := func(since int64, token string) graphDelta {
+		w := doAuthRequest(h, "GET", fmt.Sprintf("/api/v1/graph/delta?graph_id=%d&since=%d", gid, since), token)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var delta graphDelta
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &delta))
+		return delta
+	}
+
+	// b is unpublished: anonymous clients never saw it, so a change to it
+	// resets them rather than naming it as removed
+	v0 := getDelta(0, "").Version
+	b, err := s.GetNode("b")
+	require.NoError(t, err)
+	b.Title = "Renamed"
+	require.NoError(t, s.UpsertNode(b))
+
+	delta := getDelta(v0, "")
+	assert.True(t, delta.Reset)
+	assert.Empty(t, delta.RemovedNodes)
+	assert.Empty(t, delta.RemovedEdges)
+	delta = getDelta(v0, "tok")
+	assert.False(t, delta.Reset)
+	require.Len(t, delta.Nodes, 1)
+	assert.Equal(t, "b", delta.Nodes[0].ID)
+
+	// ACL changes reset everyone
+	srv.policy.AdminRole = "admins"
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{"tok": {Name: "alice", Roles: []string{"admins"}}}))
+	v1 := delta.Version
+	req := httptest.NewRequest("PUT", "/api/v1/nodes/a/acl", bytes.NewBufferString(`{"principals":["bob"]}`))
+	req.Header.Set("Authorization", "Bearer tok")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, getDelta(v1, "tok").Reset)
+	delta = getDelta(v1, "")
+	assert.True(t, delta.Reset)
+	assert.Empty(t, delta.RemovedNodes)
+}
+
+func TestGraphDeltaLevelAndExcerptChange(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("v", "/v")
+	require.NoError(t, err)
+	gid, err := s.UpsertGraph(vid, "root", "", "")
+	require.NoError(t, err)
+	h := srv.Handler()
+	nodes := []models.VaultNode{
+		{ID: "a", VaultID: vid, Title: "A", FilePath: "a.md", Level: 1},
+		{ID: "b", VaultID: vid, Title: "B", FilePath: "b.md", Level: 2},
+	}
+	require.NoError(t, s.ReplaceVaultData(vid, nodes, nil, map[int][]string{gid: {"a", "b"}}))
+
+	w := doRequest(h, "GET", fmt.Sprintf("/api/v1/graph/delta?graph_id=%d&since=0", gid), nil)
+	var delta graphDelta
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &delta))
+	v0 := delta.Version
+
+	// Only b's level changes, as when a hub moves
+	nodes[1].Level = 3
+	require.NoError(t, s.ReplaceVaultData(vid, nodes, nil, map[int][]string{gid: {"a", "b"}}))
+	w = doRequest(h, "GET", fmt.Sprintf("/api/v1/graph/delta?graph_id=%d&since=%d", gid, v0), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	delta = graphDelta{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &delta))
+	assert.False(t, delta.Reset)
+	require.Len(t, delta.Nodes, 1)
+	assert.Equal(t, "b", delta.Nodes[0].ID)
+	assert.Equal(t, 3, delta.Nodes[0].Level)
+
+	// Likewise an edit to the first sentence only
+	v1 := delta.Version
+	nodes[0].Excerpt = "A new start."
+	require.NoError(t, s.ReplaceVaultData(vid, nodes, nil, map[int][]string{gid: {"a", "b"}}))
+	w = doRequest(h, "GET", fmt.Sprintf("/api/v1/graph/delta?graph_id=%d&since=%d", gid, v1), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	delta = graphDelta{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &delta))
+	require.Len(t, delta.Nodes, 1)
+	assert.Equal(t, "a", delta.Nodes[0].ID)
+}
+
+func TestGraphVersionHeader(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	h := srv.Handler()
+	path := "/api/v1/graphs/" + strconv.Itoa(gid)
+
+	w := doRequest(h, "GET", path, nil)
+	v1, err := strconv.ParseInt(w.Header().Get("X-Graph-Version"), 10, 64)
+	require.NoError(t, err)
+	assert.Positive(t, v1)
+
+	w = doRequest(h, "GET", "/api/v1/nodes/a", nil)
+	assert.Equal(t, strconv.FormatInt(v1, 10), w.Header().Get("X-Graph-Version"))
+	w = doRequest(h, "POST", "/api/v1/edges/batch", map[string]interface{}{"node_ids": []string{"a"}})
+	assert.Equal(t, strconv.FormatInt(v1, 10), w.Header().Get("X-Graph-Version"))
+
+	// Writes change the version, but do not report it
+	w = doRequest(h, "PUT", path+"/positions/a", models.NodePosition{X: 1, Y: 1})
+	assert.Empty(t, w.Header().Get("X-Graph-Version"))
+	w = doRequest(h, "GET", path, nil)
+	v2, err := strconv.ParseInt(w.Header().Get("X-Graph-Version"), 10, 64)
+	require.NoError(t, err)
+	assert.Greater(t, v2, v1)
+
+	// The header's version works as a delta's since
+	w = doRequest(h, "GET", fmt.Sprintf("/api/v1/graph/delta?graph_id=%d&since=%d", gid, v1), nil)
+	var delta graphDelta
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &delta))
+	assert.Equal(t, v2, delta.Version)
+	assert.Contains(t, delta.Positions, "a")
+
+	w = doRequest(h, "GET", "/api/v1/health", nil)
+	assert.Empty(t, w.Header().Get("X-Graph-Version"))
+}
+
+// --- Reindex ---
+
+func TestReindexNoIndexer(t *testing.T) {
+	srv, _ := newTestServer(t)
+	w := doRequest(srv.Handler(), "POST", "/api/v1/reindex", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestReindexQueued(t *testing.T) {
+	srv, s := newTestServer(t)
+	srv.indexer = indexer.NewIndexManager(s)
+	dir := mnemosynetest.NewVault(t, mnemosynetest.GenerateVault(3, 1))
+	_, _, err := srv.indexer.RegisterVault(dir)
+	require.NoError(t, err)
+	h := srv.Handler()
+
+	w := doRequest(h, "POST", "/api/v1/reindex?queue=maybe", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(h, "POST", "/api/v1/reindex?queue=true", nil)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var queued models.QueuedParse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &queued))
+	assert.NotEmpty(t, queued.ID)
+	assert.Equal(t, 1, queued.Position)
+
+	require.Eventually(t, func() bool {
+		var status models.ParseStatusResponse
+		w := doRequest(h, "GET", "/api/v1/vault/parse-status", nil)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		return status.Status == "completed" && len(status.Queue) == 0
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+// --- Filter and Groups ---
+
+// seedGraphWithConfig creates a vault, graph with config, nodes with tags, and edges.
+func seedGraphWithConfig(t *testing.T, s *store.Store, config string) int {
+	t.Helper()
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	gid, err := s.UpsertGraph(vid, "root", "", config)
+	require.NoError(t, err)
+
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "a", VaultID: vid, Title: "Aviation", FilePath: "concepts/aviation.md",
+		Tags: models.StringArray{"index"}, Content: "Aviation note.",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "b", VaultID: vid, Title: "Economics", FilePath: "concepts/econ.md",
+		Tags: models.StringArray{"open-question"}, Content: "Econ note.",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "c", VaultID: vid, Title: "Projects", FilePath: "projects/proj.md",
+		Content: "Projects note.", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{
+		ID: "e1", SourceID: "a", TargetID: "b", EdgeType: "wikilink", Weight: 1,
+	}))
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{
+		ID: "e2", SourceID: "a", TargetID: "c", EdgeType: "wikilink", Weight: 1,
+	}))
+	require.NoError(t, s.ReplaceGraphMemberships("a", []int{gid}))
+	require.NoError(t, s.ReplaceGraphMemberships("b", []int{gid}))
+	require.NoError(t, s.ReplaceGraphMemberships("c", []int{gid}))
+
+	return gid
+}
+
+func TestGetGraphDataWithGroups(t *testing.T) {
+	srv, s := newTestServer(t)
+	config := `groups:
+  - query: "tag:#index"
+    color: "#E05555"
+  - query: "tag:#open-question"
+    color: "#E5A84B"
+`
+	gid := seedGraphWithConfig(t, s, config)
+
+	w := doRequest(srv.Handler(), "GET", "/api/v1/graphs/"+strconv.Itoa(gid), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var graph models.Graph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+	assert.Len(t, graph.Nodes, 3)
+	assert.Len(t, graph.Edges, 2)
+
+	// Check colors assigned by groups
+	colors := map[string]string{}
+	for _, n := range graph.Nodes {
+		colors[n.ID] = n.Color
+	}
+	assert.Equal(t, "#E05555", colors["a"]) // has tag:index
+	assert.Equal(t, "#E5A84B", colors["b"]) // has tag:open-question
+	assert.Equal(t, "", colors["c"])         // no matching group
+}
+
+func TestGetGraphDataWithFilter(t *testing.T) {
+	srv, s := newTestServer(t)
+	config := `filter: "path:concepts"
+`
+	gid := seedGraphWithConfig(t, s, config)
+
+	w := doRequest(srv.Handler(), "GET", "/api/v1/graphs/"+strconv.Itoa(gid), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var graph models.Graph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+
+	// Only concepts/ nodes should be included (a and b), not projects/ (c)
+	assert.Len(t, graph.Nodes, 2)
+	ids := map[string]bool{}
+	for _, n := range graph.Nodes {
+		ids[n.ID] = true
+	}
+	assert.True(t, ids["a"])
+	assert.True(t, ids["b"])
+	assert.False(t, ids["c"])
+
+	// Edge a→c should be pruned (c filtered out), only a→b remains
+	assert.Len(t, graph.Edges, 1)
+	assert.Equal(t, "a", graph.Edges[0].Source)
+	assert.Equal(t, "b", graph.Edges[0].Target)
+}
+
+func TestGetGraphDataNoConfig(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+
+	w := doRequest(srv.Handler(), "GET", "/api/v1/graphs/"+strconv.Itoa(gid), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var graph models.Graph
 	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
 
 	// No config = show all, no colors
@@ -363,6 +1186,40 @@ groups:
 	}
 }
 
+// Edges whose ends are not both in the response are never returned, whatever
+// drops nodes: graph membership, the graph filter, aggregation or sampling.
+func TestGetGraphDataNoDanglingEdges(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraphWithConfig(t, s, `filter: "path:concepts"`+"\n")
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	// A note outside the graph, linked from inside it
+	require.NoError(t, s.UpsertNode(&models.VaultNode{ID: "d", VaultID: vid, Title: "Elsewhere", FilePath: "other/d.md"}))
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{ID: "e3", SourceID: "a", TargetID: "d", EdgeType: "wikilink", Weight: 1}))
+	h := srv.Handler()
+	path := "/api/v1/graphs/" + strconv.Itoa(gid)
+
+	for _, url := range []string{
+		path,
+		path + "?bidirectional=true",
+		path + "?aggregate=true&aggregate_threshold=1",
+		fmt.Sprintf("/api/v1/graph/sample?graph_id=%d&n=1", gid),
+		"/api/v1/graph/sample?n=2&strategy=random",
+	} {
+		w := doRequest(h, "GET", url, nil)
+		require.Equal(t, http.StatusOK, w.Code, url)
+		var graph models.Graph
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+		ids := make(map[string]bool)
+		for _, n := range graph.Nodes {
+			ids[n.ID] = true
+		}
+		for _, e := range graph.Edges {
+			assert.True(t, ids[e.Source] && ids[e.Target], "%s: edge %s %s->%s has an end not in the response", url, e.ID, e.Source, e.Target)
+		}
+	}
+}
+
 // --- CORS ---
 
 func TestCORSPreflight(t *testing.T) {
@@ -377,3 +1234,1882 @@ func TestCORSHeaders(t *testing.T) {
 	w := doRequest(srv.Handler(), "GET", "/api/v1/health", nil)
 	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
 }
+
+// --- Publish filtering ---
+
+func seedPublishGraph(t *testing.T, srv *Server, s *store.Store) int {
+	t.Helper()
+	gid := seedGraph(t, s)
+	// "a" is published, "b" is not
+	node, err := s.GetNode("a")
+	require.NoError(t, err)
+	node.Metadata = models.JSONMetadata{"publish": true}
+	require.NoError(t, s.UpsertNode(node))
+
+	srv.SetAccessPolicy(&access.Policy{PublishFlag: "publish"})
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{"tok": {Name: "alice"}}))
+	return gid
+}
+
+func doAuthRequest(handler http.Handler, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestPublishFilterAnonymous(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedPublishGraph(t, srv, s)
+	h := srv.Handler()
+
+	w := doAuthRequest(h, "GET", "/api/v1/graphs/"+strconv.Itoa(gid), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var graph models.Graph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+	require.Len(t, graph.Nodes, 1)
+	assert.Equal(t, "a", graph.Nodes[0].ID)
+	assert.Empty(t, graph.Edges)
+
+	assert.Equal(t, http.StatusOK, doAuthRequest(h, "GET", "/api/v1/nodes/a", "").Code)
+	assert.Equal(t, http.StatusNotFound, doAuthRequest(h, "GET", "/api/v1/nodes/b", "").Code)
+	assert.Equal(t, http.StatusNotFound, doAuthRequest(h, "GET", "/api/v1/nodes/b/outline", "").Code)
+	assert.Equal(t, http.StatusNotFound, doAuthRequest(h, "GET", "/api/v1/nodes/b/metadata", "").Code)
+
+	w = doAuthRequest(h, "GET", "/api/v1/graphs/"+strconv.Itoa(gid)+"/search?q=Economics", "")
+	assert.NotContains(t, w.Body.String(), `"b"`)
+
+	// Writes require authentication
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(h, "POST", "/api/v1/reindex", "").Code)
+}
+
+func TestPublishFilterAuthenticated(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedPublishGraph(t, srv, s)
+	h := srv.Handler()
+
+	w := doAuthRequest(h, "GET", "/api/v1/graphs/"+strconv.Itoa(gid), "tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	var graph models.Graph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+	assert.Len(t, graph.Nodes, 2)
+
+	assert.Equal(t, http.StatusOK, doAuthRequest(h, "GET", "/api/v1/nodes/b", "tok").Code)
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(h, "GET", "/api/v1/nodes/b", "bad").Code)
+}
+
+func TestNodeACL(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{
+		"alice-tok": {Name: "alice", Roles: []string{"admins"}},
+		"bob-tok":   {Name: "bob", Roles: []string{"editors"}},
+	}))
+	srv.SetAccessPolicy(&access.Policy{AdminRole: "admins"})
+	h := srv.Handler()
+
+	w := doAuthRequest(h, "GET", "/api/v1/nodes/b/acl", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"node_id":"b","source":"","principals":[]}`, w.Body.String())
+
+	// Assigning requires authentication, as an admin
+	w = doRequest(h, "PUT", "/api/v1/nodes/b/acl", map[string]interface{}{"principals": []string{"editors"}})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	req := httptest.NewRequest("PUT", "/api/v1/nodes/b/acl", bytes.NewBufferString(`{"principals":["bob"]}`))
+	req.Header.Set("Authorization", "Bearer bob-tok")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req = httptest.NewRequest("PUT", "/api/v1/nodes/b/acl", bytes.NewBufferString(`{"principals":["editors"]}`))
+	req.Header.Set("Authorization", "Bearer alice-tok")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"node_id":"b","source":"api","principals":["editors"]}`, w.Body.String())
+
+	acls, err := s.GetNodeACLs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editors"}, acls["b"])
+
+	// Only bob (via the editors role) can now see node b
+	assert.Equal(t, http.StatusNotFound, doAuthRequest(h, "GET", "/api/v1/nodes/b", "").Code)
+	assert.Equal(t, http.StatusNotFound, doAuthRequest(h, "GET", "/api/v1/nodes/b", "alice-tok").Code)
+	assert.Equal(t, http.StatusOK, doAuthRequest(h, "GET", "/api/v1/nodes/b", "bob-tok").Code)
+
+	w = doAuthRequest(h, "GET", "/api/v1/graphs/"+strconv.Itoa(gid), "alice-tok")
+	var graph models.Graph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+	require.Len(t, graph.Nodes, 1)
+	assert.Equal(t, "a", graph.Nodes[0].ID)
+
+	// Exports leave out hidden nodes and their edges
+	w = doAuthRequest(h, "GET", "/api/v1/graph/export?format=dot", "alice-tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"a" [label=`)
+	assert.NotContains(t, w.Body.String(), `"b"`)
+	w = doAuthRequest(h, "GET", "/api/v1/graph/export?format=dot", "bob-tok")
+	assert.Contains(t, w.Body.String(), `"a" -> "b"`)
+}
+
+// --- Parse history ---
+
+func TestListParses(t *testing.T) {
+	srv, s := newTestServer(t)
+	require.NoError(t, s.SaveParseHistory(&models.ParseHistory{
+		ID: "run-1", VaultID: 1, StartedAt: time.Now(), Status: models.ParseStatusCompleted,
+	}))
+
+	w := doRequest(srv.Handler(), "GET", "/api/v1/vault/parses", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Parses []models.ParseHistory `json:"parses"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Parses, 1)
+	assert.Equal(t, "run-1", resp.Parses[0].ID)
+
+	w = doRequest(srv.Handler(), "GET", "/api/v1/vault/parses?limit=0", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListParsesPaged(t *testing.T) {
+	srv, s := newTestServer(t)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		require.NoError(t, s.SaveParseHistory(&models.ParseHistory{
+			ID: fmt.Sprintf("run-%d", i), VaultID: 1, StartedAt: start.Add(time.Duration(i) * time.Hour), Status: models.ParseStatusCompleted,
+		}))
+	}
+	type response struct {
+		Parses []models.ParseHistory `json:"parses"`
+		Total  int                   `json:"total"`
+		Offset int                   `json:"offset"`
+	}
+	list := func(query string) response {
+		w := doRequest(srv.Handler(), "GET", "/api/v1/vault/parses"+query, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	ids := func(resp response) []string {
+		var ids []string
+		for _, p := range resp.Parses {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	resp := list("?limit=2")
+	assert.Equal(t, 5, resp.Total)
+	assert.Equal(t, []string{"run-4", "run-3"}, ids(resp))
+	resp = list("?limit=2&offset=2")
+	assert.Equal(t, 2, resp.Offset)
+	assert.Equal(t, []string{"run-2", "run-1"}, ids(resp))
+	assert.Equal(t, []string{"run-0"}, ids(list("?limit=2&offset=4")))
+	assert.Empty(t, list("?offset=10").Parses)
+
+	w := doRequest(srv.Handler(), "GET", "/api/v1/vault/parses?offset=-1", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetParse(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("test", "/srv/vaults/test")
+	require.NoError(t, err)
+	msg := "parse vault: open /srv/vaults/test/private/a.md: permission denied"
+	require.NoError(t, s.SaveParseHistory(&models.ParseHistory{
+		ID: "run-1", VaultID: vid, StartedAt: time.Now(), Status: models.ParseStatusFailed, Error: &msg,
+		Stats: models.JSONStats{TotalFiles: 3},
+	}))
+	h := srv.Handler()
+
+	w := doRequest(h, "GET", "/api/v1/vault/parses/run-1", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var run models.ParseHistory
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &run))
+	assert.Equal(t, "run-1", run.ID)
+	assert.Equal(t, models.ParseStatusFailed, run.Status)
+	assert.Equal(t, 3, run.Stats.TotalFiles)
+	// The server's vault path is not given away
+	require.NotNil(t, run.Error)
+	assert.Equal(t, "parse vault: open private/a.md: permission denied", *run.Error)
+
+	w = doRequest(h, "GET", "/api/v1/vault/parses", nil)
+	assert.NotContains(t, w.Body.String(), "/srv/vaults")
+
+	w = doRequest(h, "GET", "/api/v1/vault/parses/nope", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetParseReport(t *testing.T) {
+	srv, s := newTestServer(t)
+	require.NoError(t, s.SaveParseHistory(&models.ParseHistory{
+		ID: "run-1", VaultID: 1, StartedAt: time.Now(), Status: models.ParseStatusCompleted,
+	}))
+	h := srv.Handler()
+
+	w := doRequest(h, "GET", "/api/v1/vault/parses/run-1/report", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code, "no report yet")
+
+	require.NoError(t, s.SaveParseReport("run-1", "# Parse report\n"))
+	w = doRequest(h, "GET", "/api/v1/vault/parses/run-1/report", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "# Parse report\n", w.Body.String())
+	assert.Equal(t, "text/markdown; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="parse-run-1.md"`, w.Header().Get("Content-Disposition"))
+
+	w = doRequest(h, "GET", "/api/v1/vault/parses/run-2/report", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetParseErrors(t *testing.T) {
+	srv, s := newTestServer(t)
+	require.NoError(t, s.SaveParseHistory(&models.ParseHistory{
+		ID: "run-1", VaultID: 1, StartedAt: time.Now(), Status: models.ParseStatusCompleted,
+	}))
+	require.NoError(t, s.SaveParseErrors("run-1", []models.ParseFileError{
+		{FilePath: "bad.md", Kind: "frontmatter", Line: 3, Message: "failed to parse frontmatter YAML"},
+		{FilePath: "latin1.md", Kind: "encoding", Line: 4, Message: "file latin1.md is not valid UTF-8"},
+	}))
+	h := srv.Handler()
+
+	var resp struct {
+		ParseID string                  `json:"parse_id"`
+		Errors  []models.ParseFileError `json:"errors"`
+	}
+	w := doRequest(h, "GET", "/api/v1/vault/parses/run-1/errors", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "run-1", resp.ParseID)
+	require.Len(t, resp.Errors, 2)
+	assert.Equal(t, models.ParseFileError{FilePath: "bad.md", Kind: "frontmatter", Line: 3, Message: "failed to parse frontmatter YAML"}, resp.Errors[0])
+
+	w = doRequest(h, "GET", "/api/v1/vault/parses/run-1/errors?kind=encoding", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "latin1.md", resp.Errors[0].FilePath)
+
+	w = doRequest(h, "GET", "/api/v1/vault/parses/run-2/errors", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetParseLogs(t *testing.T) {
+	srv, s := newTestServer(t)
+	require.NoError(t, s.SaveParseHistory(&models.ParseHistory{
+		ID: "run-1", VaultID: 1, StartedAt: time.Now(), Status: models.ParseStatusFailed,
+	}))
+	require.NoError(t, s.SaveParseLogs("run-1", []models.ParseLogRecord{
+		{Time: time.Now(), Level: "info", Message: "Starting full index of /v"},
+		{Time: time.Now(), Level: "warning", Message: "Warning: failed to compute node metrics"},
+	}))
+	h := srv.Handler()
+
+	var resp struct {
+		ParseID string                  `json:"parse_id"`
+		Logs    []models.ParseLogRecord `json:"logs"`
+	}
+	w := doRequest(h, "GET", "/api/v1/vault/parses/run-1/logs", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "run-1", resp.ParseID)
+	require.Len(t, resp.Logs, 2)
+	assert.Equal(t, "info", resp.Logs[0].Level)
+
+	w = doRequest(h, "GET", "/api/v1/vault/parses/run-1/logs?level=warning", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Logs, 1)
+	assert.Equal(t, "Warning: failed to compute node metrics", resp.Logs[0].Message)
+
+	w = doRequest(h, "GET", "/api/v1/vault/parses/run-1/logs?level=debug", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(h, "GET", "/api/v1/vault/parses/run-2/logs", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestParseMetrics(t *testing.T) {
+	srv, s := newTestServer(t)
+	base := time.Now().Add(-time.Hour)
+	for i, ms := range []int64{1000, 2000, 4000} {
+		require.NoError(t, s.SaveParseHistory(&models.ParseHistory{
+			ID: "run-" + strconv.Itoa(i), VaultID: 1, StartedAt: base.Add(time.Duration(i) * time.Minute),
+			Status: models.ParseStatusCompleted,
+			Stats:  models.JSONStats{ParsedFiles: 100, TotalNodes: 100 + i, DurationMS: ms},
+		}))
+	}
+	require.NoError(t, s.SaveParseHistory(&models.ParseHistory{
+		ID: "failed", VaultID: 1, StartedAt: time.Now(), Status: models.ParseStatusFailed,
+	}))
+
+	w := doRequest(srv.Handler(), "GET", "/api/v1/vault/parses/metrics", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var m parseMetrics
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &m))
+
+	assert.Equal(t, 3, m.Runs, "only completed runs count")
+	require.Len(t, m.Series, 3)
+	assert.Equal(t, "run-0", m.Series[0].ID, "oldest first")
+	assert.Equal(t, 1000.0, m.DurationMS.Min)
+	assert.Equal(t, 4000.0, m.DurationMS.Max)
+	assert.Equal(t, 2000.0, m.DurationMS.P50)
+	assert.InDelta(t, 2333.33, m.DurationMS.Mean, 0.01)
+	assert.Equal(t, 100.0, m.FilesPerS.Max)
+	assert.Equal(t, 25.0, m.FilesPerS.Min)
+	require.NotNil(t, m.Latest)
+	assert.Equal(t, "run-2", m.Latest.ID)
+
+	w = doRequest(srv.Handler(), "GET", "/api/v1/vault/parses/metrics?vault_id=2", nil)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &m))
+	assert.Equal(t, 0, m.Runs)
+	assert.Empty(t, m.Series)
+}
+
+// --- Widget ---
+
+func TestGraphWidget(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraphWithConfig(t, s, `{"groups":[{"query":"path:aviation","color":"#FF0000"}]}`)
+
+	w := doRequest(srv.Handler(), "GET", "/api/v1/graphs/"+strconv.Itoa(gid)+"/widget", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+
+	var resp widgetGraph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, gid, resp.GraphID)
+	assert.Equal(t, "root", resp.Name)
+	require.NotEmpty(t, resp.Nodes)
+
+	for _, n := range resp.Nodes {
+		assert.NotEmpty(t, n.Color)
+		assert.GreaterOrEqual(t, n.Size, widgetBaseSize)
+		assert.False(t, n.X == 0 && n.Y == 0, "node %s has no position", n.ID)
+		assert.True(t, n.X >= resp.Bounds.MinX && n.X <= resp.Bounds.MaxX)
+		if n.ID == "a" {
+			assert.Equal(t, "#FF0000", n.Color)
+		}
+	}
+	for _, e := range resp.Edges {
+		assert.Less(t, e[0], len(resp.Nodes))
+		assert.Less(t, e[1], len(resp.Nodes))
+	}
+	assert.Len(t, resp.Edges, 2)
+
+	w = doRequest(srv.Handler(), "GET", "/api/v1/graphs/999/widget", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// --- Shares ---
+
+func TestShares(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	vaults, err := s.GetVaults()
+	require.NoError(t, err)
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "c", VaultID: vaults[0].ID, Title: "Cooking", FilePath: "cooking.md", NodeType: "note",
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, s.ReplaceGraphMemberships("c", []int{gid}))
+	h := srv.Handler()
+
+	createShare := func(body map[string]interface{}) string {
+		t.Helper()
+		w := doRequest(h, "POST", "/api/v1/shares", body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var resp struct {
+			Token string `json:"token"`
+			URL   string `json:"url"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Len(t, resp.Token, 32)
+		assert.Equal(t, "/api/v1/shares/"+resp.Token, resp.URL)
+		return resp.Token
+	}
+	sharedIDs := func(token string) []string {
+		t.Helper()
+		w := doRequest(h, "GET", "/api/v1/shares/"+token, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var share models.Share
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &share))
+		var ids []string
+		for _, n := range share.Graph.Nodes {
+			ids = append(ids, n.ID)
+		}
+		return ids
+	}
+
+	// Neighborhood of "a" includes its linked node "b" but not "c"
+	token := createShare(map[string]interface{}{"graph_id": gid, "node_id": "a", "title": "Aviation"})
+	assert.ElementsMatch(t, []string{"a", "b"}, sharedIDs(token))
+
+	// Type filter
+	token = createShare(map[string]interface{}{"graph_id": gid, "type": "note"})
+	assert.ElementsMatch(t, []string{"b", "c"}, sharedIDs(token))
+
+	// The snapshot does not change when the vault does
+	require.NoError(t, s.DeleteNode("c"))
+	assert.ElementsMatch(t, []string{"b", "c"}, sharedIDs(token))
+
+	// Revocation
+	assert.Equal(t, http.StatusNoContent, doRequest(h, "DELETE", "/api/v1/shares/"+token, nil).Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(h, "GET", "/api/v1/shares/"+token, nil).Code)
+
+	assert.Equal(t, http.StatusNotFound, doRequest(h, "POST", "/api/v1/shares", map[string]interface{}{"graph_id": gid, "node_id": "missing"}).Code)
+	assert.Equal(t, http.StatusBadRequest, doRequest(h, "POST", "/api/v1/shares", map[string]interface{}{"graph_id": gid, "depth": 9}).Code)
+}
+
+func TestDeleteShareOwnership(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{
+		"alice-tok": {Name: "alice"},
+		"bob-tok":   {Name: "bob"},
+		"root-tok":  {Name: "root", Roles: []string{"admins"}},
+	}))
+	srv.SetAccessPolicy(&access.Policy{AdminRole: "admins"})
+	h := srv.Handler()
+
+	createShare := func() string {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/v1/shares", bytes.NewBufferString(fmt.Sprintf(`{"graph_id":%d}`, gid)))
+		req.Header.Set("Authorization", "Bearer alice-tok")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var resp struct {
+			Token string `json:"token"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Token
+	}
+
+	// Another user cannot revoke alice's share
+	token := createShare()
+	assert.Equal(t, http.StatusForbidden, doAuthRequest(h, "DELETE", "/api/v1/shares/"+token, "bob-tok").Code)
+	assert.Equal(t, http.StatusOK, doRequest(h, "GET", "/api/v1/shares/"+token, nil).Code)
+
+	// The creator can
+	assert.Equal(t, http.StatusNoContent, doAuthRequest(h, "DELETE", "/api/v1/shares/"+token, "alice-tok").Code)
+	assert.Equal(t, http.StatusNotFound, doAuthRequest(h, "DELETE", "/api/v1/shares/"+token, "alice-tok").Code)
+
+	// So can an admin
+	token = createShare()
+	assert.Equal(t, http.StatusNoContent, doAuthRequest(h, "DELETE", "/api/v1/shares/"+token, "root-tok").Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(h, "GET", "/api/v1/shares/"+token, nil).Code)
+}
+
+// --- Extension points ---
+
+func headerMiddleware(name, value string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add(name, value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestUseMiddlewareWrapsBuiltinRoutes(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.Use(headerMiddleware("X-Order", "first"), headerMiddleware("X-Order", "second"))
+
+	w := doRequest(srv.Handler(), "GET", "/api/v1/health", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"first", "second"}, w.Header().Values("X-Order"))
+}
+
+func TestRouteGroup(t *testing.T) {
+	srv, _ := newTestServer(t)
+	g := srv.Group("/api/v1/plugins/", headerMiddleware("X-Group", "yes"))
+	g.HandleFunc("GET /echo/{word}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"word": r.PathValue("word")})
+	})
+	srv.HandleFunc("GET /api/v1/custom", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	w := doRequest(srv.Handler(), "GET", "/api/v1/plugins/echo/hi", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "yes", w.Header().Get("X-Group"))
+	assert.Contains(t, w.Body.String(), `"hi"`)
+
+	w = doRequest(srv.Handler(), "GET", "/api/v1/custom", nil)
+	assert.Equal(t, http.StatusTeapot, w.Code)
+	assert.Empty(t, w.Header().Get("X-Group"))
+}
+
+// --- Position remapping ---
+
+func TestRemapPositions(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	h := srv.Handler()
+
+	w := doRequest(h, "POST", "/api/v1/positions/remap", map[string]string{"old_id": "a", "new_id": "a2"})
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Remapped  int   `json:"remapped"`
+		Positions int   `json:"positions"`
+		GraphIDs  []int `json:"graph_ids"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Remapped)
+	assert.Equal(t, 1, resp.Positions)
+	assert.Equal(t, []int{gid}, resp.GraphIDs)
+
+	positions, err := s.GetPositionsByGraph(gid)
+	require.NoError(t, err)
+	assert.Equal(t, 10.0, positions["a2"].X)
+	assert.NotContains(t, positions, "a")
+
+	// Bulk CSV with a header row
+	req := httptest.NewRequest("POST", "/api/v1/positions/remap", strings.NewReader("old_id,new_id\na2,a3\nmissing,x\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Remapped)
+	assert.Equal(t, 1, resp.Positions)
+
+	// Invalid mappings are rejected
+	w = doRequest(h, "POST", "/api/v1/positions/remap", map[string]interface{}{
+		"mappings": []map[string]string{{"old_id": "a3", "new_id": "z"}, {"old_id": "b", "new_id": "z"}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(h, "POST", "/api/v1/positions/remap", map[string]string{"old_id": "a3"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExportImportPositions(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	h := srv.Handler()
+
+	w := doRequest(h, "GET", "/api/v1/positions/export", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var doc positionsExport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, 1, doc.Version)
+	require.Len(t, doc.Graphs, 1)
+	assert.Equal(t, "test", doc.Graphs[0].Vault)
+	assert.Equal(t, "", doc.Graphs[0].RootPath)
+	assert.Equal(t, exportedPosition{X: 10, Y: 20}, doc.Graphs[0].Positions["a"])
+
+	// Importing replaces the graph's positions; unknown graphs are skipped
+	doc.Graphs[0].Positions = map[string]exportedPosition{"b": {X: 1, Y: 2, Locked: true}}
+	doc.Graphs = append(doc.Graphs, graphPositions{Vault: "elsewhere", RootPath: "x", Positions: map[string]exportedPosition{"c": {}}})
+	w = doRequest(h, "PUT", "/api/v1/positions/export", doc)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Graphs    int            `json:"graphs"`
+		Positions int            `json:"positions"`
+		Skipped   []graphLocator `json:"skipped"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Graphs)
+	assert.Equal(t, 1, resp.Positions)
+	assert.Equal(t, []graphLocator{{Vault: "elsewhere", RootPath: "x"}}, resp.Skipped)
+
+	positions, err := s.GetPositionsByGraph(gid)
+	require.NoError(t, err)
+	assert.NotContains(t, positions, "a")
+	assert.True(t, positions["b"].Locked)
+
+	doc.Version = 99
+	w = doRequest(h, "PUT", "/api/v1/positions/export", doc)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExportImportPositionsHidden(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	require.NoError(t, s.UpsertPosition(gid, &models.NodePosition{NodeID: "b", X: 5, Y: 6}))
+	srv.SetAccessPolicy(&access.Policy{})
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{"alice-tok": {Name: "alice"}}))
+	srv.policy.SetNodeACL("b", []string{"bob"})
+	h := srv.Handler()
+
+	// Alice cannot see b, so its position is not exported to her
+	w := doAuthRequest(h, "GET", "/api/v1/positions/export", "alice-tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	var doc positionsExport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	require.Len(t, doc.Graphs, 1)
+	assert.Equal(t, map[string]exportedPosition{"a": {X: 10, Y: 20}}, doc.Graphs[0].Positions)
+
+	// nor replaced by her imports
+	doc.Graphs[0].Positions = map[string]exportedPosition{"a": {X: 1, Y: 1}, "b": {X: 99, Y: 99}}
+	body, err := json.Marshal(doc)
+	require.NoError(t, err)
+	req := httptest.NewRequest("PUT", "/api/v1/positions/export", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer alice-tok")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	positions, err := s.GetPositionsByGraph(gid)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, positions["a"].X)
+	assert.Equal(t, 5.0, positions["b"].X)
+
+	doc.Graphs[0].Positions = map[string]exportedPosition{}
+	body, err = json.Marshal(doc)
+	require.NoError(t, err)
+	req = httptest.NewRequest("PUT", "/api/v1/positions/export", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer alice-tok")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	positions, err = s.GetPositionsByGraph(gid)
+	require.NoError(t, err)
+	assert.NotContains(t, positions, "a")
+	assert.Contains(t, positions, "b")
+}
+
+func TestComputeLayout(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	h := srv.Handler()
+
+	w := doRequest(h, "POST", "/api/v1/layouts/compute", map[string]interface{}{"graph_id": gid, "algorithm": "radial"})
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	runner := layout.NewRunner(s)
+	t.Cleanup(runner.Shutdown)
+	srv.SetLayoutRunner(runner)
+
+	w = doRequest(h, "POST", "/api/v1/layouts/compute", map[string]interface{}{"graph_id": gid, "algorithm": "spiral"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(h, "POST", "/api/v1/layouts/compute", map[string]interface{}{"graph_id": 999, "algorithm": "radial"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(h, "POST", "/api/v1/layouts/compute", map[string]interface{}{"graph_id": gid, "algorithm": "radial"})
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var job models.LayoutJob
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "radial", job.Algorithm)
+
+	require.Eventually(t, func() bool {
+		w := doRequest(h, "GET", "/api/v1/layouts/jobs/"+job.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		return job.Status == models.LayoutJobCompleted
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, job.Positions)
+
+	// "a" and "b" tie for most links; the lower ID is the hub at the center
+	positions, err := s.GetPositionsByGraph(gid)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, positions["a"].X)
+	assert.NotEqual(t, 0.0, positions["b"].X)
+
+	w = doRequest(h, "GET", "/api/v1/layouts/jobs/unknown", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPinNodes(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	require.NoError(t, s.UpsertPosition(gid, &models.NodePosition{NodeID: "b", X: 30, Y: 40}))
+	h := srv.Handler()
+	path := "/api/v1/graphs/" + strconv.Itoa(gid)
+
+	w := doRequest(h, "PUT", path+"/positions/a/pin", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = doRequest(h, "GET", path, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var graph models.Graph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+	for _, n := range graph.Nodes {
+		assert.Equal(t, n.ID == "a", n.Pinned, n.ID)
+	}
+
+	w = doRequest(h, "DELETE", path+"/positions/a/pin", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// Bulk pin; nodes without saved positions are reported
+	w = doRequest(h, "PUT", path+"/pins", map[string]interface{}{"node_ids": []string{"a", "b", "c"}})
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Pinned  bool     `json:"pinned"`
+		Updated int      `json:"updated"`
+		Missing []string `json:"missing"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Pinned)
+	assert.Equal(t, 2, resp.Updated)
+	assert.Equal(t, []string{"c"}, resp.Missing)
+
+	w = doRequest(h, "PUT", path+"/pins", map[string]interface{}{"node_ids": []string{"b"}, "pinned": false})
+	require.Equal(t, http.StatusOK, w.Code)
+	positions, err := s.GetPositionsByGraph(gid)
+	require.NoError(t, err)
+	assert.True(t, positions["a"].Pinned)
+	assert.False(t, positions["b"].Pinned)
+
+	w = doRequest(h, "PUT", path+"/positions/c/pin", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(h, "PUT", path+"/pins", map[string]interface{}{"node_ids": []string{}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func dialLive(t *testing.T, url, token string) *websocket.Conn {
+	t.Helper()
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestLivePositionsSubprotocolAuth(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{"tok": {Name: "alice"}}))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/v1/graphs/" + strconv.Itoa(gid) + "/live"
+
+	// Browsers cannot set headers on the upgrade, so they send the token as a subprotocol
+	dialer := websocket.Dialer{Subprotocols: []string{access.BearerProtocol, "tok"}}
+	alice, _, err := dialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer alice.Close()
+	assert.Equal(t, access.BearerProtocol, alice.Subprotocol())
+	watcher := dialLive(t, url, "")
+	require.Eventually(t, func() bool {
+		srv.sseClientsMu.Lock()
+		defer srv.sseClientsMu.Unlock()
+		return len(srv.sseClients) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, alice.WriteJSON(map[string]interface{}{
+		"type":      "moving",
+		"positions": []map[string]interface{}{{"node_id": "a", "x": 1, "y": 2}},
+	}))
+	evt := readLiveEvent(t, watcher)
+	assert.Equal(t, "positions-moving", evt["type"])
+	assert.Equal(t, "alice", evt["user"])
+
+	dialer.Subprotocols = []string{access.BearerProtocol, "wrong"}
+	_, resp, err := dialer.Dial(url, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func readLiveEvent(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var evt map[string]interface{}
+	require.NoError(t, conn.ReadJSON(&evt))
+	return evt
+}
+
+func TestLivePositions(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{"tok": {Name: "alice"}, "tok2": {Name: "bob"}}))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/v1/graphs/" + strconv.Itoa(gid) + "/live"
+
+	alice := dialLive(t, url, "tok")
+	bob := dialLive(t, url, "tok2")
+	watcher := dialLive(t, url, "")
+	require.Eventually(t, func() bool {
+		srv.sseClientsMu.Lock()
+		defer srv.sseClientsMu.Unlock()
+		return len(srv.sseClients) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Saved positions reach the other clients, attributed to the sender
+	require.NoError(t, alice.WriteJSON(map[string]interface{}{
+		"type":      "positions",
+		"positions": []map[string]interface{}{{"node_id": "b", "x": 7, "y": 8}},
+	}))
+	for _, conn := range []*websocket.Conn{bob, watcher} {
+		evt := readLiveEvent(t, conn)
+		assert.Equal(t, "positions-updated", evt["type"])
+		assert.Equal(t, "alice", evt["user"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"node_id": "b", "x": 7.0, "y": 8.0}}, evt["positions"])
+	}
+	positions, err := s.GetPositionsByGraph(gid)
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, positions["b"].X)
+
+	// Anonymous clients may watch but not send
+	require.NoError(t, watcher.WriteJSON(map[string]interface{}{
+		"type":      "moving",
+		"positions": []map[string]interface{}{{"node_id": "a", "x": 1, "y": 1}},
+	}))
+	evt := readLiveEvent(t, watcher)
+	assert.Equal(t, "error", evt["type"])
+
+	// Updates through the REST API are broadcast too; the sender got none of the above
+	req := httptest.NewRequest("PUT", "/api/v1/graphs/"+strconv.Itoa(gid)+"/positions/a", strings.NewReader(`{"x": 3, "y": 4}`))
+	req.Header.Set("Authorization", "Bearer tok2")
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	evt = readLiveEvent(t, alice)
+	assert.Equal(t, "positions-updated", evt["type"])
+	assert.Equal(t, "bob", evt["user"])
+}
+
+func TestNodeComments(t *testing.T) {
+	srv, s := newTestServer(t)
+	seedGraph(t, s)
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{"tok": {Name: "alice"}}))
+	h := srv.Handler()
+
+	w := doRequest(h, "GET", "/api/v1/nodes/a/comments", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"comments": []}`, w.Body.String())
+
+	// Writing requires a user when auth is enabled
+	w = doRequest(h, "POST", "/api/v1/nodes/a/comments", map[string]string{"body": "hi"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer tok")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	w = post("/api/v1/nodes/a/comments", `{"body": "  Check the sources.  "}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created models.Comment
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "alice", created.Author)
+	assert.Equal(t, "Check the sources.", created.Body)
+
+	assert.Equal(t, http.StatusBadRequest, post("/api/v1/nodes/a/comments", `{"body": "   "}`).Code)
+	assert.Equal(t, http.StatusNotFound, post("/api/v1/nodes/missing/comments", `{"body": "x"}`).Code)
+
+	w = doRequest(h, "GET", "/api/v1/nodes/a/comments", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Comments []models.Comment `json:"comments"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Comments, 1)
+	assert.Equal(t, created.ID, resp.Comments[0].ID)
+	assert.Equal(t, "a", resp.Comments[0].NodeID)
+}
+
+func TestBookmarks(t *testing.T) {
+	srv, s := newTestServer(t)
+	seedGraph(t, s)
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{"tok": {Name: "alice"}, "tok2": {Name: "bob"}}))
+	h := srv.Handler()
+
+	list := func(token string) []models.Bookmark {
+		w := doAuthRequest(h, "GET", "/api/v1/bookmarks", token)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Bookmarks []models.Bookmark `json:"bookmarks"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Bookmarks
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(h, "GET", "/api/v1/bookmarks", "").Code)
+	assert.Empty(t, list("tok"))
+
+	assert.Equal(t, http.StatusNoContent, doAuthRequest(h, "PUT", "/api/v1/bookmarks/a", "tok").Code)
+	assert.Equal(t, http.StatusNoContent, doAuthRequest(h, "PUT", "/api/v1/bookmarks/a", "tok").Code)
+	assert.Equal(t, http.StatusNotFound, doAuthRequest(h, "PUT", "/api/v1/bookmarks/missing", "tok").Code)
+
+	bookmarks := list("tok")
+	require.Len(t, bookmarks, 1)
+	assert.Equal(t, "a", bookmarks[0].NodeID)
+	assert.Equal(t, "Aviation", bookmarks[0].Title)
+	assert.Empty(t, list("tok2"), "bookmarks are per user")
+
+	assert.Equal(t, http.StatusNoContent, doAuthRequest(h, "DELETE", "/api/v1/bookmarks/a", "tok").Code)
+	assert.Empty(t, list("tok"))
+}
+
+func TestViewAnalytics(t *testing.T) {
+	srv, s := newTestServer(t)
+	seedGraph(t, s)
+	h := srv.Handler()
+
+	viewed := func(path string) (bool, []models.NodeViews) {
+		w := doRequest(h, "GET", path, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Enabled bool               `json:"enabled"`
+			Nodes   []models.NodeViews `json:"nodes"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Enabled, resp.Nodes
+	}
+
+	// Off by default
+	doRequest(h, "GET", "/api/v1/nodes/a", nil)
+	enabled, nodes := viewed("/api/v1/analytics/most-viewed")
+	assert.False(t, enabled)
+	assert.Empty(t, nodes)
+
+	srv.SetViewTracking(true)
+	for _, id := range []string{"a", "a", "b", "missing"} {
+		doRequest(h, "GET", "/api/v1/nodes/"+id, nil)
+	}
+
+	enabled, nodes = viewed("/api/v1/analytics/most-viewed")
+	assert.True(t, enabled)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "a", nodes[0].NodeID)
+	assert.Equal(t, 2, nodes[0].Views)
+	assert.Equal(t, "Aviation", nodes[0].Title)
+
+	_, nodes = viewed("/api/v1/analytics/recently-viewed?limit=1")
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "b", nodes[0].NodeID)
+
+	// Hidden nodes are filtered before the limit, so they do not crowd out visible ones
+	policy := &access.Policy{}
+	policy.SetNodeACL("a", []string{"nobody"})
+	srv.SetAccessPolicy(policy)
+	require.NoError(t, s.RecordView("a", "", time.Now().Add(time.Minute)))
+	_, nodes = viewed("/api/v1/analytics/most-viewed?limit=1")
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "b", nodes[0].NodeID)
+	_, nodes = viewed("/api/v1/analytics/recently-viewed?limit=1")
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "b", nodes[0].NodeID)
+
+	w := doRequest(h, "GET", "/api/v1/analytics/most-viewed?days=0", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGraphActivity(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	day := func(d string) time.Time {
+		t, _ := time.Parse(time.DateOnly, d)
+		return t.Add(12 * time.Hour)
+	}
+	// 2026-03-04 is a Wednesday; its week starts Monday 2026-03-02
+	for _, n := range []models.VaultNode{
+		{ID: "a", FilePath: "a.md", CreatedAt: day("2026-03-04"), UpdatedAt: day("2026-03-05")},
+		{ID: "b", FilePath: "b.md", CreatedAt: day("2026-03-02"), UpdatedAt: day("2026-04-10")},
+	} {
+		n.VaultID, n.Title = vid, n.ID
+		require.NoError(t, s.UpsertNode(&n))
+	}
+	h := srv.Handler()
+
+	activity := func(query string) []activityBucket {
+		w := doRequest(h, "GET", "/api/v1/graph/activity"+query, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Buckets []activityBucket `json:"buckets"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Buckets
+	}
+
+	assert.Equal(t, []activityBucket{
+		{Start: "2026-03-02", Created: 2, Modified: 1},
+		{Start: "2026-04-06", Modified: 1},
+	}, activity(""))
+	assert.Equal(t, []activityBucket{
+		{Start: "2026-03-01", Created: 2, Modified: 1},
+		{Start: "2026-04-01", Modified: 1},
+	}, activity("?granularity=month"))
+	assert.Len(t, activity("?granularity=day"), 4)
+
+	w := doRequest(h, "GET", "/api/v1/graph/activity?granularity=year", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGraphGroups(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	gid, err := s.UpsertGraph(vid, "projects", "projects", "")
+	require.NoError(t, err)
+	day := func(d string) time.Time {
+		t, _ := time.Parse(time.DateOnly, d)
+		return t.UTC()
+	}
+	for _, n := range []models.VaultNode{
+		{ID: "idx", FilePath: "index.md", WordCount: 5, UpdatedAt: day("2026-01-01")},
+		{ID: "a", FilePath: "projects/apollo/a.md", WordCount: 10, UpdatedAt: day("2026-02-01")},
+		{ID: "b", FilePath: "projects/apollo/b.md", WordCount: 20, UpdatedAt: day("2026-03-01")},
+		{ID: "c", FilePath: "projects/gemini.md", WordCount: 1, UpdatedAt: day("2026-01-15")},
+	} {
+		n.VaultID, n.Title, n.CreatedAt = vid, n.ID, n.UpdatedAt
+		require.NoError(t, s.UpsertNode(&n))
+	}
+	for _, e := range [][2]string{{"a", "b"}, {"b", "a"}, {"idx", "a"}, {"idx", "b"}, {"c", "a"}} {
+		require.NoError(t, s.UpsertEdge(&models.VaultEdge{SourceID: e[0], TargetID: e[1], EdgeType: "wikilink", Weight: 1}))
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, s.ReplaceGraphMemberships(id, []int{gid}))
+	}
+	h := srv.Handler()
+
+	type response struct {
+		By     string      `json:"by"`
+		Groups []nodeGroup `json:"groups"`
+		Links  []groupLink `json:"links"`
+	}
+	groups := func(query string) response {
+		w := doRequest(h, "GET", "/api/v1/graph/groups"+query, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	resp := groups("?by=folder")
+	assert.Equal(t, "folder", resp.By)
+	root, projects := fmt.Sprintf("%d:", vid), fmt.Sprintf("%d:projects", vid)
+	assert.Equal(t, []nodeGroup{
+		{ID: projects, Folder: "projects", VaultID: vid, NodeCount: 3, WordCount: 31, InternalEdges: 3, ExternalEdges: 2,
+			LastModified: day("2026-03-01"), NodeIDs: []string{"a", "b", "c"}},
+		{ID: root, Folder: "", VaultID: vid, NodeCount: 1, WordCount: 5, ExternalEdges: 2,
+			LastModified: day("2026-01-01"), NodeIDs: []string{"idx"}},
+	}, resp.Groups)
+	assert.Equal(t, []groupLink{{Source: root, Target: projects, Count: 2}}, resp.Links)
+
+	// Within a graph, folders are relative to its root
+	resp = groups(fmt.Sprintf("?graph_id=%d", gid))
+	require.Len(t, resp.Groups, 2)
+	assert.Equal(t, "apollo", resp.Groups[0].Folder)
+	assert.Equal(t, 2, resp.Groups[0].InternalEdges)
+	assert.Equal(t, "", resp.Groups[1].Folder)
+	assert.Equal(t, []groupLink{{Source: root, Target: fmt.Sprintf("%d:apollo", vid), Count: 1}}, resp.Links)
+
+	w := doRequest(h, "GET", "/api/v1/graph/groups?by=tag", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(h, "GET", "/api/v1/graph/groups?graph_id=999", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetGraphDataAggregate(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	gid, err := s.UpsertGraph(vid, "root", "", "")
+	require.NoError(t, err)
+	for _, n := range []models.VaultNode{
+		{ID: "idx", FilePath: "index.md", WordCount: 5},
+		{ID: "a", FilePath: "big/a.md", WordCount: 10},
+		{ID: "b", FilePath: "big/b.md", WordCount: 20},
+		{ID: "c", FilePath: "big/sub/c.md", WordCount: 30},
+		{ID: "s", FilePath: "small/s.md", WordCount: 1},
+	} {
+		n.VaultID, n.Title = vid, n.ID
+		require.NoError(t, s.UpsertNode(&n))
+		require.NoError(t, s.ReplaceGraphMemberships(n.ID, []int{gid}))
+	}
+	for _, e := range []struct {
+		src, tgt, typ string
+		weight        float64
+	}{
+		{"a", "b", "wikilink", 1}, {"b", "c", "wikilink", 1}, // Internal to big
+		{"idx", "a", "wikilink", 1}, {"idx", "b", "wikilink", 2}, {"idx", "c", "embed", 1},
+		{"s", "a", "wikilink", 1}, {"idx", "s", "wikilink", 1},
+	} {
+		require.NoError(t, s.UpsertEdge(&models.VaultEdge{
+			ID: e.src + "-" + e.tgt, SourceID: e.src, TargetID: e.tgt, EdgeType: e.typ, Weight: e.weight,
+		}))
+	}
+	for id, x := range map[string]float64{"a": 0, "b": 30, "c": 60} {
+		require.NoError(t, s.UpsertPosition(gid, &models.NodePosition{NodeID: id, X: x, Y: 3}))
+	}
+	path := "/api/v1/graphs/" + strconv.Itoa(gid)
+	get := func(query string) models.Graph {
+		w := doRequest(srv.Handler(), "GET", path+query, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var graph models.Graph
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+		return graph
+	}
+
+	graph := get("?aggregate=true&aggregate_threshold=2")
+	ids := make(map[string]models.Node)
+	for _, n := range graph.Nodes {
+		ids[n.ID] = n
+	}
+	require.Len(t, ids, 3)
+	require.Contains(t, ids, "folder:big")
+	assert.Contains(t, ids, "idx")
+	assert.Contains(t, ids, "s") // Folders at or under the threshold stay as they are
+	big := ids["folder:big"]
+	assert.Equal(t, "big", big.Title)
+	assert.Equal(t, 60, big.WordCount)
+	assert.InDelta(t, 30, big.Position.X, 0.01)
+	assert.InDelta(t, 3, big.Position.Y, 0.01)
+	assert.Equal(t, true, big.Metadata["aggregate"])
+	assert.EqualValues(t, 3, big.Metadata["node_count"])
+	assert.EqualValues(t, 2, big.Metadata["internal_edges"])
+
+	edges := make(map[string]float64)
+	for _, e := range graph.Edges {
+		edges[e.Source+"->"+e.Target+":"+e.Type] = e.Weight
+	}
+	assert.Equal(t, map[string]float64{
+		"idx->folder:big:wikilink": 3,
+		"idx->folder:big:embed":    1,
+		"s->folder:big:wikilink":   1,
+		"idx->s:wikilink":          1,
+	}, edges)
+
+	// Under the default threshold nothing is collapsed
+	assert.Len(t, get("?aggregate=true").Nodes, 5)
+	assert.Len(t, get("?aggregate=false&aggregate_threshold=2").Nodes, 5)
+
+	for _, query := range []string{"?aggregate=maybe", "?aggregate=true&aggregate_threshold=0"} {
+		w := doRequest(srv.Handler(), "GET", path+query, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestGraphSample(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	// A hub linked to nine notes, and a separate pair
+	ids := []string{"a", "b", "hub"}
+	for i := 1; i <= 9; i++ {
+		ids = append(ids, fmt.Sprintf("x%d", i))
+	}
+	for _, id := range ids {
+		require.NoError(t, s.UpsertNode(&models.VaultNode{ID: id, VaultID: vid, Title: id, FilePath: id + ".md"}))
+		if id[0] == 'x' {
+			require.NoError(t, s.UpsertEdge(&models.VaultEdge{ID: "hub-" + id, SourceID: "hub", TargetID: id, EdgeType: "wikilink", Weight: 1}))
+		}
+	}
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{ID: "a-b", SourceID: "a", TargetID: "b", EdgeType: "wikilink", Weight: 1}))
+	h := srv.Handler()
+
+	sample := func(query string) graphSample {
+		w := doRequest(h, "GET", "/api/v1/graph/sample"+query, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp graphSample
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	nodeIDs := func(g graphSample) []string {
+		var ids []string
+		for _, n := range g.Nodes {
+			ids = append(ids, n.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	resp := sample("?n=3")
+	assert.Equal(t, "degree", resp.Strategy)
+	assert.Equal(t, 12, resp.TotalNodes)
+	assert.Equal(t, 10, resp.TotalEdges)
+	// The hub, then the ties in ID order
+	assert.Equal(t, []string{"a", "b", "hub"}, nodeIDs(resp))
+	require.Len(t, resp.Edges, 1)
+	assert.Equal(t, "a-b", resp.Edges[0].ID)
+
+	for _, strategy := range []string{"random", "forest-fire"} {
+		query := "?n=5&strategy=" + strategy + "&seed=42"
+		first := sample(query)
+		assert.Len(t, first.Nodes, 5, strategy)
+		assert.Equal(t, nodeIDs(first), nodeIDs(sample(query)), "%s: same seed, same sample", strategy)
+		in := make(map[string]bool)
+		for _, n := range first.Nodes {
+			in[n.ID] = true
+		}
+		for _, e := range first.Edges {
+			assert.True(t, in[e.Source] && in[e.Target], "%s: edge %s leaves the sample", strategy, e.ID)
+		}
+	}
+
+	// Asking for more than there is returns everything
+	resp = sample("?n=100&strategy=forest-fire")
+	assert.Len(t, resp.Nodes, 12)
+	assert.Len(t, resp.Edges, 10)
+
+	for _, query := range []string{"?n=0", "?n=x", "?strategy=pagerank", "?seed=-1"} {
+		w := doRequest(h, "GET", "/api/v1/graph/sample"+query, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+	w := doRequest(h, "GET", "/api/v1/graph/sample?graph_id=999", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGraphExport(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	h := srv.Handler()
+
+	w := doRequest(h, "GET", "/api/v1/graph/export?format=dot", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/vnd.graphviz", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="mnemosyne.dot"`, w.Header().Get("Content-Disposition"))
+	assert.Contains(t, w.Body.String(), `"a" -> "b" [type="wikilink", weight=1];`)
+
+	w = doRequest(h, "GET", fmt.Sprintf("/api/v1/graph/export?format=graphml&graph_id=%d", gid), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, fmt.Sprintf(`attachment; filename="mnemosyne-graph-%d.graphml"`, gid), w.Header().Get("Content-Disposition"))
+	assert.Contains(t, w.Body.String(), `<data key="x">10</data>`)
+	assert.Contains(t, w.Body.String(), `<data key="type">hub</data>`)
+
+	w = doRequest(h, "GET", "/api/v1/graph/export?format=gexf", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<edge id="e1" source="a" target="b" label="wikilink" weight="1">`)
+
+	assert.Equal(t, http.StatusBadRequest, doRequest(h, "GET", "/api/v1/graph/export", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, doRequest(h, "GET", "/api/v1/graph/export?format=svg", nil).Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(h, "GET", "/api/v1/graph/export?format=dot&graph_id=999", nil).Code)
+}
+
+// stalledWriter is a response writer whose client stops reading: its first
+// Write blocks until release is closed.
+type stalledWriter struct {
+	*httptest.ResponseRecorder
+	once    sync.Once
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (w *stalledWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.writing) })
+	<-w.release
+	return w.ResponseRecorder.Write(p)
+}
+
+func TestGraphExportStalledClient(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("v", "/v")
+	require.NoError(t, err)
+	nodes := make([]models.VaultNode, 200)
+	for i := range nodes {
+		nodes[i] = models.VaultNode{ID: fmt.Sprintf("n%03d", i), VaultID: vid, Title: fmt.Sprintf("Note %d", i), FilePath: fmt.Sprintf("n%03d.md", i)}
+	}
+	require.NoError(t, s.ReplaceVaultData(vid, nodes, nil, nil))
+	h := srv.Handler()
+
+	w := &stalledWriter{ResponseRecorder: httptest.NewRecorder(), writing: make(chan struct{}), release: make(chan struct{})}
+	exported := make(chan struct{})
+	go func() {
+		defer close(exported)
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/graph/export?format=dot", nil))
+	}()
+	<-w.writing
+
+	// Other requests are served while the export waits on its client
+	served := make(chan int, 1)
+	go func() { served <- doRequest(h, "GET", "/api/v1/nodes/n001", nil).Code }()
+	select {
+	case code := <-served:
+		assert.Equal(t, http.StatusOK, code)
+	case <-time.After(5 * time.Second):
+		t.Error("request blocked by a stalled export")
+	}
+
+	close(w.release)
+	<-exported
+	assert.Contains(t, w.Body.String(), `"n199"`)
+}
+
+func TestListNodesModifiedInRange(t *testing.T) {
+	srv, s := newTestServer(t)
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	// Stored in UTC regardless of the zone they were recorded in
+	est := time.FixedZone("EST", -5*3600)
+	for _, n := range []models.VaultNode{
+		{ID: "old", UpdatedAt: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{ID: "mid", UpdatedAt: time.Date(2026, 2, 1, 20, 0, 0, 0, est)}, // 2026-02-02T01:00Z
+		{ID: "new", UpdatedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)},
+	} {
+		n.VaultID, n.Title, n.FilePath, n.CreatedAt = vid, n.ID, n.ID+".md", n.UpdatedAt
+		require.NoError(t, s.UpsertNode(&n))
+	}
+	h := srv.Handler()
+
+	ids := func(query string) []string {
+		w := doRequest(h, "GET", "/api/v1/nodes"+query, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp struct {
+			Nodes []models.VaultNode `json:"nodes"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		var out []string
+		for _, n := range resp.Nodes {
+			out = append(out, n.ID)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"old", "mid", "new"}, ids(""))
+	assert.Equal(t, []string{"mid", "new"}, ids("?modified_after=2026-02-02"))
+	assert.Equal(t, []string{"old"}, ids("?modified_before=2026-02-02T01:00:00Z"))
+	assert.Equal(t, []string{"mid"}, ids("?modified_after=2026-02-01T20:00:00-05:00&modified_before=2026-03-01"))
+
+	w := doRequest(h, "GET", "/api/v1/nodes?modified_after=yesterday", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// --- Time travel ---
+
+// newGitVault returns a server indexing a new git repository, the
+// repository's directory, and a function writing files and committing them.
+func newGitVault(t *testing.T) (*Server, *indexer.IndexManager, *store.Store, string, func(files map[string]string)) {
+	t.Helper()
+	repo := mnemosynetest.NewRepo(t, nil)
+	s := mnemosynetest.NewStore(t)
+	idx := indexer.NewIndexManager(s)
+	srv := NewServer(s, idx, nil, nil, 0, "")
+	commit := func(files map[string]string) {
+		t.Helper()
+		repo.Commit(files)
+	}
+	return srv, idx, s, repo.Dir, commit
+}
+
+func TestGraphAtCommit(t *testing.T) {
+	srv, idx, s, dir, commit := newGitVault(t)
+	h := srv.Handler()
+	commit(map[string]string{
+		"GRAPH.yaml": `groups: [{query: "tag:#old", color: "#ff0000"}]`,
+		"a.md":       "---\nid: a\ntags: [old]\n---\n# A\n",
+	})
+	commit(map[string]string{"b.md": "---\nid: b\n---\n# B\n[[a]]\n"})
+
+	vaultID, graphIDs, err := idx.RegisterVault(dir)
+	require.NoError(t, err)
+	require.NoError(t, idx.FullIndexVault(vaultID))
+	require.NoError(t, s.UpsertPosition(graphIDs[0], &models.NodePosition{NodeID: "a", X: 5, Y: 6}))
+
+	w := doRequest(h, "GET", "/api/v1/graph?at_commit=HEAD~1&graph_id="+strconv.Itoa(graphIDs[0]), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Commit string        `json:"commit"`
+		Nodes  []models.Node `json:"nodes"`
+		Edges  []models.Edge `json:"edges"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Commit, 40)
+	require.Len(t, resp.Nodes, 1)
+	assert.Equal(t, "a", resp.Nodes[0].ID)
+	assert.Equal(t, "#ff0000", resp.Nodes[0].Color)
+	assert.Equal(t, 5.0, resp.Nodes[0].Position.X)
+	assert.Empty(t, resp.Edges)
+
+	// The only vault is the default
+	w = doRequest(h, "GET", "/api/v1/graph?at_commit=HEAD", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Nodes, 2)
+	assert.Len(t, resp.Edges, 1)
+
+	w = doRequest(h, "GET", "/api/v1/graph?at_commit=deadbeef", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(h, "GET", "/api/v1/graph", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGraphDiff(t *testing.T) {
+	srv, idx, _, dir, commit := newGitVault(t)
+	h := srv.Handler()
+	commit(map[string]string{
+		"a.md": "---\nid: a\n---\n# A\n[[b]]\n",
+		"b.md": "---\nid: b\n---\n# B\n",
+		"c.md": "---\nid: c\n---\n# C\n[[a]]\n",
+	})
+	// Reorganize: move b into a folder, drop c, add d linking to b
+	commit(map[string]string{
+		"b.md":        "",
+		"c.md":        "",
+		"topics/b.md": "---\nid: b\n---\n# B\n",
+		"d.md":        "---\nid: d\n---\n# D\n[[b]]\n",
+	})
+	_, _, err := idx.RegisterVault(dir)
+	require.NoError(t, err)
+
+	w := doRequest(h, "GET", "/api/v1/graph/diff?from=HEAD~1&to=HEAD", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var diff graphDiff
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &diff))
+	assert.Len(t, diff.From, 40)
+	assert.Len(t, diff.To, 40)
+	assert.Equal(t, []diffNode{{ID: "d", Title: "d", FilePath: "d.md"}}, diff.NodesAdded)
+	assert.Equal(t, []diffNode{{ID: "c", Title: "c", FilePath: "c.md"}}, diff.NodesRemoved)
+	assert.Equal(t, []diffNode{{ID: "b", Title: "b", FilePath: "topics/b.md", PreviousPath: "b.md"}}, diff.NodesMoved)
+	assert.Equal(t, []diffEdge{{Source: "d", Target: "b", Type: "wikilink"}}, diff.EdgesAdded)
+	assert.Equal(t, []diffEdge{{Source: "c", Target: "a", Type: "wikilink"}}, diff.EdgesRemoved)
+
+	w = doRequest(h, "GET", "/api/v1/graph/diff?from=HEAD", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(h, "GET", "/api/v1/graph/diff?from=HEAD&to=no-such-branch", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestNodeBlame(t *testing.T) {
+	srv, idx, _, dir, commit := newGitVault(t)
+	h := srv.Handler()
+	commit(map[string]string{"a.md": "---\nid: a\n---\nfirst\n"})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.md"), []byte("---\nid: a\n---\nfirst\nsecond\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.md"), []byte("---\nid: new\n---\n"), 0o644))
+	vaultID, _, err := idx.RegisterVault(dir)
+	require.NoError(t, err)
+	require.NoError(t, idx.FullIndexVault(vaultID))
+
+	w := doRequest(h, "GET", "/api/v1/nodes/a/blame", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Lines []indexer.BlameLine `json:"lines"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Lines, 5)
+	assert.Equal(t, 4, resp.Lines[3].Line)
+	assert.Equal(t, "first", resp.Lines[3].Content)
+	assert.Len(t, resp.Lines[3].Commit, 40)
+	assert.Equal(t, "test", resp.Lines[3].Author)
+	assert.Equal(t, "test@example.com", resp.Lines[3].AuthorEmail)
+	assert.Equal(t, "update", resp.Lines[3].Summary)
+	assert.NotNil(t, resp.Lines[3].AuthoredAt)
+	// Not committed yet
+	assert.Equal(t, indexer.BlameLine{Line: 5, Content: "second"}, resp.Lines[4])
+
+	w = doRequest(h, "GET", "/api/v1/nodes/new/blame", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = doRequest(h, "GET", "/api/v1/nodes/missing/blame", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestNodeBlameRedacted(t *testing.T) {
+	srv, idx, _, dir, commit := newGitVault(t)
+	h := srv.Handler()
+	commit(map[string]string{"a.md": "---\nid: a\n---\napi_key: s3cret\nseen %%hidden%% too\n"})
+	r, err := vault.NewRedaction(`(api_key:\s*)\S+`, "${1}"+vault.DefaultRedaction)
+	require.NoError(t, err)
+	idx.SetParseOptions(vault.ParseOptions{Redactions: []vault.Redaction{*r}})
+	vaultID, _, err := idx.RegisterVault(dir)
+	require.NoError(t, err)
+	require.NoError(t, idx.FullIndexVault(vaultID))
+
+	w := doRequest(h, "GET", "/api/v1/nodes/a/blame", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.NotContains(t, w.Body.String(), "s3cret")
+	assert.NotContains(t, w.Body.String(), "hidden")
+	var resp struct {
+		Lines []indexer.BlameLine `json:"lines"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Lines, 5)
+	assert.Equal(t, "api_key: [REDACTED]", resp.Lines[3].Content)
+	assert.Equal(t, "seen "+strings.Repeat(" ", len("%%hidden%%"))+" too", resp.Lines[4].Content)
+	assert.Len(t, resp.Lines[3].Commit, 40)
+}
+
+func TestVaultContributors(t *testing.T) {
+	srv, idx, _, dir, commit := newGitVault(t)
+	h := srv.Handler()
+	commit(map[string]string{"a.md": "---\nid: a\n---\n", "b.md": "---\nid: b\n---\n"})
+	commit(map[string]string{"a.md": "---\nid: a\n---\nMore.\n"})
+	t.Setenv("GIT_AUTHOR_NAME", "Other")
+	t.Setenv("GIT_AUTHOR_EMAIL", "Other@Example.com")
+	commit(map[string]string{"b.md": "---\nid: b\n---\nEdited.\n", "gone.md": "---\nid: gone\n---\n"})
+	commit(map[string]string{"gone.md": ""})
+
+	vaultID, _, err := idx.RegisterVault(dir)
+	require.NoError(t, err)
+	require.NoError(t, idx.FullIndexVault(vaultID))
+
+	w := doRequest(h, "GET", "/api/v1/vault/contributors", nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Contributors []struct {
+			Name    string `json:"name"`
+			Email   string `json:"email"`
+			Commits int    `json:"commits"`
+			Notes   int    `json:"notes"`
+		} `json:"contributors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Contributors, 2)
+	// Ties on commits go by email; deleted notes don't count
+	assert.Equal(t, "Other", resp.Contributors[0].Name)
+	assert.Equal(t, "other@example.com", resp.Contributors[0].Email)
+	assert.Equal(t, 2, resp.Contributors[0].Commits)
+	assert.Equal(t, 1, resp.Contributors[0].Notes)
+	assert.Equal(t, "test", resp.Contributors[1].Name)
+	assert.Equal(t, 2, resp.Contributors[1].Commits)
+	assert.Equal(t, 2, resp.Contributors[1].Notes)
+}
+
+// --- Request bodies ---
+
+func TestStrictRequestBodies(t *testing.T) {
+	srv, s := newTestServer(t)
+	gid := seedGraph(t, s)
+	h := srv.Handler()
+	path := "/api/v1/graphs/" + strconv.Itoa(gid) + "/positions/a"
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	errorOf := func(w *httptest.ResponseRecorder) string {
+		return decodeError(t, w).Message
+	}
+
+	w := put(`{"x": 1, "y": 2}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = put(`{"x": 1, "why": 2}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, errorOf(w), `unknown field "why"`)
+
+	w = put(`{"x": "left"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, errorOf(w), `field "x" must be float64`)
+
+	w = put(`{"x": 1} {"x": 2}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, errorOf(w), "after JSON value")
+
+	w = put(``)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Request body is empty", errorOf(w))
+
+	srv.SetMaxBodySize(16)
+	w = put(`{"x": 1, "y": 2, "z": 3}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, "Request body exceeds 16 bytes", errorOf(w))
+	assert.Equal(t, CodeBodyTooLarge, decodeError(t, w).Code)
+
+	req := httptest.NewRequest("POST", "/api/v1/positions/remap", strings.NewReader("old_id,new_id\na,renamed-a\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+// --- Errors ---
+
+// decodeError decodes an error response's envelope.
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) apiError {
+	t.Helper()
+	var resp struct {
+		Error apiError `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Error
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	srv, s := newTestServer(t)
+	seedGraph(t, s)
+	h := srv.Handler()
+
+	req := httptest.NewRequest("GET", "/api/v1/nodes/missing", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	e := decodeError(t, w)
+	assert.Equal(t, CodeNotFound, e.Code)
+	assert.Equal(t, "Node not found", e.Message)
+	assert.NotEmpty(t, e.RequestID)
+	assert.Equal(t, w.Header().Get("X-Request-ID"), e.RequestID)
+
+	// Client-supplied IDs are kept if valid
+	req = httptest.NewRequest("PUT", "/api/v1/graphs/1/positions/a", strings.NewReader(`{"x": 1, "why": 2}`))
+	req.Header.Set("X-Request-ID", "client-42")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	e = decodeError(t, w)
+	assert.Equal(t, CodeInvalidBody, e.Code)
+	assert.Equal(t, "client-42", e.RequestID)
+	assert.Equal(t, map[string]interface{}{"field": "why"}, e.Details)
+
+	req = httptest.NewRequest("GET", "/api/v1/nodes/missing", nil)
+	req.Header.Set("X-Request-ID", "not valid!")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.NotEqual(t, "not valid!", decodeError(t, w).RequestID)
+
+	// Successful responses carry the ID too
+	w = doRequest(h, "GET", "/api/v1/errors", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get("X-Request-ID"))
+	var codes struct {
+		Codes []struct {
+			Code   ErrorCode `json:"code"`
+			Status int       `json:"status"`
+		} `json:"codes"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &codes))
+	assert.Len(t, codes.Codes, len(errorCodes))
+	for _, c := range codes.Codes {
+		assert.Equal(t, errorCodes[c.Code].Status, c.Status)
+	}
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	srv, s := newTestServer(t)
+	seed := mnemosynetest.SeedGraph(t, s)
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{"tok": {Name: "alice"}}))
+	h := srv.Handler()
+
+	for _, path := range []string{"/api/v1/admin/cache/flush", "/api/v1/admin/recompute-metrics", "/api/v1/admin/metrics/recompute", "/api/v1/admin/reclassify", "/api/v1/admin/vacuum", "/api/v1/admin/positions/purge"} {
+		w := doAuthRequest(h, "POST", path, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+	}
+
+	require.NoError(t, s.ReplaceFileCache(1, []models.CachedFile{{Path: "aviation.md", Hash: "h", Data: "{}"}}))
+	w := doAuthRequest(h, "POST", "/api/v1/admin/cache/flush", "tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"historical_graphs": 0, "cached_files": 1}`, w.Body.String())
+
+	// seedGraph leaves degrees at zero despite edge a -> b
+	w = doAuthRequest(h, "POST", "/api/v1/admin/recompute-metrics", "tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"updated": 2}`, w.Body.String())
+	a, err := s.GetNode("a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, a.OutDegree)
+	b, err := s.GetNode("b")
+	require.NoError(t, err)
+	assert.InDelta(t, 1, b.Centrality, 1e-9)
+	assert.Less(t, a.Centrality, b.Centrality)
+	w = doAuthRequest(h, "POST", "/api/v1/admin/metrics/recompute", "tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"updated": 0}`, w.Body.String())
+
+	w = doAuthRequest(h, "POST", "/api/v1/admin/vacuum", "tok")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Positions of nodes missing from the last full indexes are purged
+	require.NoError(t, s.UpsertPositions(seed.GraphID, []models.NodePosition{{NodeID: "gone"}}))
+	require.NoError(t, s.AgeAbsentPositions(context.Background(), seed.VaultID))
+	w = doAuthRequest(h, "POST", "/api/v1/admin/positions/purge?parses=2", "tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"purged": 0}`, w.Body.String())
+	w = doAuthRequest(h, "POST", "/api/v1/admin/positions/purge", "tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"purged": 1}`, w.Body.String())
+	count, err := s.GetPositionCount(seed.GraphID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "a's position is kept")
+	w = doAuthRequest(h, "POST", "/api/v1/admin/positions/purge?parses=0", "tok")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Releasing the parse lock needs an indexer
+	w = doAuthRequest(h, "POST", "/api/v1/admin/parse-lock/release", "tok")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	srv.indexer = indexer.NewIndexManager(s)
+	w = doAuthRequest(h, "POST", "/api/v1/admin/parse-lock/release", "tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"released": false, "parse_id": ""}`, w.Body.String())
+
+	// Without registered vaults there is nothing to reclassify
+	w = doAuthRequest(h, "POST", "/api/v1/admin/reclassify", "tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"nodes": 0, "changed": []}`, w.Body.String())
+}
+
+func TestVaultStorage(t *testing.T) {
+	srv, s := newTestServer(t)
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{"tok": {Name: "alice"}}))
+	h := srv.Handler()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.md":              "# A\n",
+		"sub/b.md":          "# Bee\n",
+		"sub/img.png":       "12345678",
+		".obsidian/app.json": "{}",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	_, err := s.UpsertVault("notes", dir)
+	require.NoError(t, err)
+	_, err = s.UpsertVault("gone", filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+
+	w := doAuthRequest(h, "GET", "/api/v1/vault/storage", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = doAuthRequest(h, "GET", "/api/v1/vault/storage", "tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Vaults []struct {
+			Name string `json:"name"`
+			vault.DiskUsage
+			Error string `json:"error"`
+		} `json:"vaults"`
+		Total    vault.DiskUsage    `json:"total"`
+		Database store.StorageUsage `json:"database"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Vaults, 2)
+	assert.Equal(t, "gone", resp.Vaults[0].Name)
+	assert.NotEmpty(t, resp.Vaults[0].Error)
+	assert.Equal(t, "notes", resp.Vaults[1].Name)
+	assert.Empty(t, resp.Vaults[1].Error)
+	want := vault.DiskUsage{MarkdownFiles: 2, MarkdownBytes: 10, AttachmentFiles: 1, AttachmentBytes: 8}
+	assert.Equal(t, want, resp.Vaults[1].DiskUsage)
+	assert.Equal(t, want, resp.Total)
+	assert.Positive(t, resp.Database.FileBytes)
+	assert.NotEmpty(t, resp.Database.Tables)
+	assert.NotEmpty(t, resp.Database.Indexes)
+}
+
+func TestVaultHealth(t *testing.T) {
+	srv, s := newTestServer(t)
+	srv.SetAuthenticator(access.NewAuthenticator(map[string]access.User{"tok": {Name: "alice"}}))
+	h := srv.Handler()
+
+	w := doAuthRequest(h, "GET", "/api/v1/vault/health", "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	var resp struct {
+		Vaults []struct {
+			Vault string `json:"vault"`
+			models.VaultHealth
+		} `json:"vaults"`
+		Score *int `json:"score"`
+	}
+	w = doAuthRequest(h, "GET", "/api/v1/vault/health", "tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Empty(t, resp.Vaults)
+	assert.Nil(t, resp.Score, "no score before any index")
+
+	notes, err := s.UpsertVault("notes", t.TempDir())
+	require.NoError(t, err)
+	work, err := s.UpsertVault("work", t.TempDir())
+	require.NoError(t, err)
+	_, err = s.UpsertVault("new", t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, s.SaveVaultHealth(models.VaultHealth{VaultID: notes, Score: 100, Notes: 30, Recommendations: []models.HealthRecommendation{}}))
+	require.NoError(t, s.SaveVaultHealth(models.VaultHealth{
+		VaultID: work, Score: 60, Notes: 10, Orphans: 4, OrphanRatio: 0.4,
+		Recommendations: []models.HealthRecommendation{{Metric: "orphans", Penalty: 12, Examples: []string{"x.md"}}},
+	}))
+
+	w = doAuthRequest(h, "GET", "/api/v1/vault/health", "tok")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Vaults, 2, "vaults never indexed are left out")
+	assert.Equal(t, "notes", resp.Vaults[0].Vault)
+	assert.Equal(t, "work", resp.Vaults[1].Vault)
+	assert.Equal(t, 4, resp.Vaults[1].Orphans)
+	assert.Equal(t, []string{"x.md"}, resp.Vaults[1].Recommendations[0].Examples)
+	require.NotNil(t, resp.Score)
+	assert.Equal(t, 90, *resp.Score) // (100*30 + 60*10) / 40
+}
+
+func TestGraphPruningProfiles(t *testing.T) {
+	srv, s := newTestServer(t)
+	seed := mnemosynetest.SeedGraph(t, s)
+	for _, n := range []models.VaultNode{
+		{ID: "day", Title: "2024-06-01", FilePath: "daily/2024-06-01.md"},
+		{ID: "img", Title: "Diagram", FilePath: "diagram.md"},
+		{ID: "ref", Title: "A Paper", FilePath: "refs.bib#paper", NodeType: "reference"},
+		{ID: "lonely", Title: "Lonely", FilePath: "lonely.md"},
+	} {
+		n.VaultID, n.CreatedAt, n.UpdatedAt = seed.VaultID, time.Now(), time.Now()
+		require.NoError(t, s.UpsertNode(&n))
+		require.NoError(t, s.ReplaceGraphMemberships(n.ID, []int{seed.GraphID}))
+	}
+	for _, e := range []models.VaultEdge{
+		{SourceID: "day", TargetID: "a", EdgeType: "wikilink"},
+		{SourceID: "a", TargetID: "img", EdgeType: "embed"},
+		{SourceID: "b", TargetID: "ref", EdgeType: "citation"},
+	} {
+		require.NoError(t, s.UpsertEdge(&e))
+	}
+	require.NoError(t, srv.SetPruningProfiles(map[string]PruningProfile{
+		"overview": {ExcludeDaily: true, ExcludeTypes: []string{"reference"}, ExcludeAttachments: true, ExcludeOrphans: true},
+		"full":     {},
+	}))
+	h := srv.Handler()
+	path := fmt.Sprintf("/api/v1/graphs/%d", seed.GraphID)
+
+	nodeIDs := func(w *httptest.ResponseRecorder) []string {
+		require.Equal(t, http.StatusOK, w.Code)
+		var g models.Graph
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &g))
+		var ids []string
+		for _, n := range g.Nodes {
+			ids = append(ids, n.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	all := []string{"a", "b", "day", "img", "lonely", "ref"}
+	assert.Equal(t, all, nodeIDs(doRequest(h, "GET", path, nil)))
+	assert.Equal(t, all, nodeIDs(doRequest(h, "GET", path+"?profile=full", nil)))
+	assert.Equal(t, []string{"a", "b"}, nodeIDs(doRequest(h, "GET", path+"?profile=overview", nil)))
+
+	w := doRequest(h, "GET", path+"?profile=nope", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"profiles":["full","overview"]`)
+
+	assert.Error(t, srv.SetPruningProfiles(map[string]PruningProfile{"bad": {Exclude: "("}}))
+}
+
+func TestGraphReciprocity(t *testing.T) {
+	srv, s := newTestServer(t)
+	seed := mnemosynetest.SeedGraph(t, s)
+	c := models.VaultNode{ID: "c", VaultID: seed.VaultID, Title: "C", FilePath: "c.md", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, s.UpsertNode(&c))
+	require.NoError(t, s.ReplaceGraphMemberships("c", []int{seed.GraphID}))
+	for _, e := range []models.VaultEdge{
+		{ID: "e2", SourceID: "b", TargetID: "a", EdgeType: "wikilink"},
+		{ID: "e3", SourceID: "a", TargetID: "c", EdgeType: "embed"},
+	} {
+		require.NoError(t, s.UpsertEdge(&e))
+	}
+	h := srv.Handler()
+
+	w := doRequest(h, "GET", fmt.Sprintf("/api/v1/graphs/%d/reciprocity", seed.GraphID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"edges": 3, "linked_pairs": 2, "reciprocal_pairs": 1, "one_way_pairs": 1, "reciprocity": 0.5,
+		"edge_types": {"wikilink": {"reciprocal": 2, "one_way": 0}, "embed": {"reciprocal": 0, "one_way": 1}},
+		"reciprocal": [{"a": "a", "b": "b"}],
+		"one_way": [{"source": "a", "target": "c"}]
+	}`, w.Body.String())
+
+	w = doRequest(h, "GET", fmt.Sprintf("/api/v1/graphs/%d/reciprocity?limit=x", seed.GraphID), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(h, "GET", "/api/v1/graphs/999/reciprocity", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The graph marks bidirectional edges on request
+	w = doRequest(h, "GET", fmt.Sprintf("/api/v1/graphs/%d?bidirectional=true", seed.GraphID), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var graph models.Graph
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &graph))
+	marked := make(map[string]bool)
+	for _, e := range graph.Edges {
+		marked[e.ID] = e.Bidirectional
+	}
+	assert.Equal(t, map[string]bool{"e1": true, "e2": true, "e3": false}, marked)
+	w = doRequest(h, "GET", fmt.Sprintf("/api/v1/graphs/%d", seed.GraphID), nil)
+	assert.NotContains(t, w.Body.String(), "bidirectional")
+}
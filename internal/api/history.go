@@ -0,0 +1,371 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/discovery"
+	"github.com/ali01/mnemosyne/internal/indexer"
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/store"
+)
+
+// historicalGraph is a graph as it was at a past commit.
+type historicalGraph struct {
+	Commit      string    `json:"commit"`
+	CommittedAt time.Time `json:"committed_at"`
+	models.Graph
+}
+
+// handleGraphAtCommit returns a graph as it was at a git commit, parsed on
+// demand from a temporary checkout. With graph_id, only that graph's notes
+// are included, filtered and colored by its current GRAPH.yaml and placed at
+// its current positions; with vault_id (optional if there is a single vault),
+// the whole vault is. Query: at_commit (required).
+func (s *Server) handleGraphAtCommit(w http.ResponseWriter, r *http.Request) {
+	rev := r.URL.Query().Get("at_commit")
+	if rev == "" {
+		writeError(w, r, CodeBadRequest, "Query parameter 'at_commit' is required")
+		return
+	}
+	graph, vaultID, ok := s.historyScope(w, r)
+	if !ok {
+		return
+	}
+	hist, ok := s.graphAtCommit(w, r, vaultID, rev)
+	if !ok {
+		return
+	}
+
+	raw := &store.GraphDataRaw{Nodes: s.scopeNodes(r, graph, hist.Nodes), Edges: hist.Edges}
+	if graph != nil {
+		raw.Config = graph.Config
+		var err error
+		if raw.Positions, err = s.store.GetPositionsByGraph(graph.ID); err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch positions")
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, historicalGraph{
+		Commit:      hist.Commit,
+		CommittedAt: hist.CommittedAt,
+		Graph:       *applyFilterAndGroups(raw),
+	})
+}
+
+// diffNode is a note added, removed or moved between two commits.
+type diffNode struct {
+	ID           string `json:"id"`
+	Title        string `json:"title"`
+	FilePath     string `json:"file_path"`
+	PreviousPath string `json:"previous_path,omitempty"` // For moved notes
+}
+
+// diffEdge is a link added or removed between two commits. Edges are
+// identified by their endpoints and type.
+type diffEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Type   string `json:"type"`
+}
+
+// graphDiff is the structural difference between a graph at two commits.
+type graphDiff struct {
+	From         string     `json:"from"`
+	To           string     `json:"to"`
+	NodesAdded   []diffNode `json:"nodes_added"`
+	NodesRemoved []diffNode `json:"nodes_removed"`
+	NodesMoved   []diffNode `json:"nodes_moved"`
+	EdgesAdded   []diffEdge `json:"edges_added"`
+	EdgesRemoved []diffEdge `json:"edges_removed"`
+}
+
+// handleGraphDiff compares a graph at two branches or commits: notes and
+// links added and removed, and notes whose files moved. Query: from and to
+// (required), graph_id or vault_id as for handleGraphAtCommit.
+func (s *Server) handleGraphDiff(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to := q.Get("from"), q.Get("to")
+	if from == "" || to == "" {
+		writeError(w, r, CodeBadRequest, "Query parameters 'from' and 'to' are required")
+		return
+	}
+	graph, vaultID, ok := s.historyScope(w, r)
+	if !ok {
+		return
+	}
+	before, ok := s.graphAtCommit(w, r, vaultID, from)
+	if !ok {
+		return
+	}
+	after, ok := s.graphAtCommit(w, r, vaultID, to)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, diffGraphs(
+		before.Commit, s.scopeNodes(r, graph, before.Nodes), before.Edges,
+		after.Commit, s.scopeNodes(r, graph, after.Nodes), after.Edges,
+	))
+}
+
+// diffGraphs compares two versions of a graph. Edges with an endpoint missing
+// from their version's nodes are ignored. Results are sorted for stable output.
+func diffGraphs(from string, fromNodes []models.VaultNode, fromEdges []models.VaultEdge,
+	to string, toNodes []models.VaultNode, toEdges []models.VaultEdge) graphDiff {
+	diff := graphDiff{
+		From:         from,
+		To:           to,
+		NodesAdded:   []diffNode{},
+		NodesRemoved: []diffNode{},
+		NodesMoved:   []diffNode{},
+		EdgesAdded:   []diffEdge{},
+		EdgesRemoved: []diffEdge{},
+	}
+
+	before := make(map[string]models.VaultNode, len(fromNodes))
+	for _, n := range fromNodes {
+		before[n.ID] = n
+	}
+	after := make(map[string]models.VaultNode, len(toNodes))
+	for _, n := range toNodes {
+		after[n.ID] = n
+		prev, ok := before[n.ID]
+		switch {
+		case !ok:
+			diff.NodesAdded = append(diff.NodesAdded, diffNode{ID: n.ID, Title: n.Title, FilePath: n.FilePath})
+		case prev.FilePath != n.FilePath:
+			diff.NodesMoved = append(diff.NodesMoved, diffNode{ID: n.ID, Title: n.Title, FilePath: n.FilePath, PreviousPath: prev.FilePath})
+		}
+	}
+	for _, n := range fromNodes {
+		if _, ok := after[n.ID]; !ok {
+			diff.NodesRemoved = append(diff.NodesRemoved, diffNode{ID: n.ID, Title: n.Title, FilePath: n.FilePath})
+		}
+	}
+
+	edgeSet := func(nodes map[string]models.VaultNode, edges []models.VaultEdge) map[diffEdge]bool {
+		set := make(map[diffEdge]bool, len(edges))
+		for _, e := range edges {
+			_, src := nodes[e.SourceID]
+			_, dst := nodes[e.TargetID]
+			if src && dst {
+				set[diffEdge{Source: e.SourceID, Target: e.TargetID, Type: e.EdgeType}] = true
+			}
+		}
+		return set
+	}
+	beforeEdges, afterEdges := edgeSet(before, fromEdges), edgeSet(after, toEdges)
+	for e := range afterEdges {
+		if !beforeEdges[e] {
+			diff.EdgesAdded = append(diff.EdgesAdded, e)
+		}
+	}
+	for e := range beforeEdges {
+		if !afterEdges[e] {
+			diff.EdgesRemoved = append(diff.EdgesRemoved, e)
+		}
+	}
+
+	for _, nodes := range [][]diffNode{diff.NodesAdded, diff.NodesRemoved, diff.NodesMoved} {
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	}
+	for _, edges := range [][]diffEdge{diff.EdgesAdded, diff.EdgesRemoved} {
+		sort.Slice(edges, func(i, j int) bool {
+			a, b := edges[i], edges[j]
+			if a.Source != b.Source {
+				return a.Source < b.Source
+			}
+			if a.Target != b.Target {
+				return a.Target < b.Target
+			}
+			return a.Type < b.Type
+		})
+	}
+	return diff
+}
+
+// historyScope resolves which vault a historical query reads and, with
+// graph_id, which graph it is limited to. vault_id may be omitted when there
+// is a single vault. It writes an error response and returns false on failure.
+func (s *Server) historyScope(w http.ResponseWriter, r *http.Request) (*models.GraphInfo, int, bool) {
+	if s.indexer == nil {
+		writeError(w, r, CodeInternal, "Indexer not configured")
+		return nil, 0, false
+	}
+	q := r.URL.Query()
+	if v := q.Get("graph_id"); v != "" {
+		graphID, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, CodeBadRequest, "Invalid graph ID")
+			return nil, 0, false
+		}
+		graph, err := s.store.GetGraphInfo(graphID)
+		if err != nil {
+			writeError(w, r, CodeNotFound, "Graph not found")
+			return nil, 0, false
+		}
+		return graph, graph.VaultID, true
+	}
+	vaults, err := s.store.GetVaults()
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch vaults")
+		return nil, 0, false
+	}
+	v := q.Get("vault_id")
+	if v == "" {
+		if len(vaults) != 1 {
+			writeError(w, r, CodeBadRequest, "Query parameter 'graph_id' or 'vault_id' is required")
+			return nil, 0, false
+		}
+		return nil, vaults[0].ID, true
+	}
+	vaultID, err := strconv.Atoi(v)
+	if err != nil {
+		writeError(w, r, CodeBadRequest, "Invalid vault ID")
+		return nil, 0, false
+	}
+	for _, vault := range vaults {
+		if vault.ID == vaultID {
+			return nil, vaultID, true
+		}
+	}
+	writeError(w, r, CodeNotFound, "Vault not found")
+	return nil, 0, false
+}
+
+// graphAtCommit loads a vault's graph at a revision. It writes an error
+// response and returns false on failure.
+func (s *Server) graphAtCommit(w http.ResponseWriter, r *http.Request, vaultID int, rev string) (*indexer.HistoricalGraph, bool) {
+	hist, err := s.indexer.GraphAtCommit(r.Context(), vaultID, rev)
+	switch {
+	case errors.Is(err, indexer.ErrUnknownCommit):
+		writeError(w, r, CodeNotFound, "Commit not found: "+rev)
+		return nil, false
+	case errors.Is(err, indexer.ErrNotGitRepository):
+		writeError(w, r, CodeNotGitRepository, "Vault is not a git repository")
+		return nil, false
+	case err != nil:
+		if r.Context().Err() == nil {
+			log.Printf("Failed to parse vault %d at %s: %v", vaultID, rev, err)
+			writeError(w, r, CodeInternal, "Failed to load graph at commit")
+		}
+		return nil, false
+	}
+	return hist, true
+}
+
+// scopeNodes returns the historical nodes in graph (all of them if graph is
+// nil) that the requesting user may see.
+func (s *Server) scopeNodes(r *http.Request, graph *models.GraphInfo, nodes []models.VaultNode) []models.VaultNode {
+	if graph != nil {
+		var scoped []models.VaultNode
+		for _, n := range nodes {
+			if discovery.IsUnderPath(n.FilePath, graph.RootPath) {
+				scoped = append(scoped, n)
+			}
+		}
+		nodes = scoped
+	}
+	return s.visibleNodes(r, nodes)
+}
+
+// handleNodeBlame attributes each line of a note's file to the commit and
+// author that last changed it, for an authorship overlay. Line numbers count
+// the whole file, frontmatter included.
+func (s *Server) handleNodeBlame(w http.ResponseWriter, r *http.Request) {
+	if s.indexer == nil {
+		writeError(w, r, CodeInternal, "Indexer not configured")
+		return
+	}
+	node, ok := s.visibleNode(r, r.PathValue("id"))
+	if !ok {
+		writeError(w, r, CodeNotFound, "Node not found")
+		return
+	}
+
+	lines, err := s.indexer.Blame(r.Context(), node.VaultID, node.FilePath)
+	switch {
+	case errors.Is(err, indexer.ErrNotGitRepository):
+		writeError(w, r, CodeNotGitRepository, "Vault is not a git repository")
+		return
+	case errors.Is(err, indexer.ErrFileNotTracked):
+		writeError(w, r, CodeNotFound, "File is not tracked by git")
+		return
+	case err != nil:
+		if r.Context().Err() == nil {
+			log.Printf("Failed to blame %s: %v", node.FilePath, err)
+			writeError(w, r, CodeInternal, "Failed to blame file")
+		}
+		return
+	}
+	if lines == nil {
+		lines = []indexer.BlameLine{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id":   node.ID,
+		"file_path": node.FilePath,
+		"lines":     lines,
+	})
+}
+
+// contributor is a vault author with the number of current notes they changed.
+type contributor struct {
+	indexer.Contributor
+	Notes int `json:"notes"`
+}
+
+// handleVaultContributors aggregates git authorship across a vault: each
+// author's commits, first and last commit times, and how many of the current
+// notes they changed. Query: graph_id (limits to that graph's folder) or
+// vault_id (optional if there is a single vault).
+func (s *Server) handleVaultContributors(w http.ResponseWriter, r *http.Request) {
+	graph, vaultID, ok := s.historyScope(w, r)
+	if !ok {
+		return
+	}
+	dir := ""
+	if graph != nil {
+		dir = graph.RootPath
+	}
+
+	authors, err := s.indexer.Contributors(r.Context(), vaultID, dir)
+	switch {
+	case errors.Is(err, indexer.ErrNotGitRepository):
+		writeError(w, r, CodeNotGitRepository, "Vault is not a git repository")
+		return
+	case err != nil:
+		if r.Context().Err() == nil {
+			log.Printf("Failed to read contributors of vault %d: %v", vaultID, err)
+			writeError(w, r, CodeInternal, "Failed to read git history")
+		}
+		return
+	}
+
+	nodes, err := s.store.GetNodesByVault(vaultID)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch nodes")
+		return
+	}
+	notes := make(map[string]bool, len(nodes))
+	for _, n := range s.scopeNodes(r, graph, nodes) {
+		notes[n.FilePath] = true
+	}
+
+	contributors := make([]contributor, len(authors))
+	for i, a := range authors {
+		contributors[i].Contributor = a
+		for _, p := range a.Paths {
+			if notes[p] {
+				contributors[i].Notes++
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"contributors": contributors})
+}
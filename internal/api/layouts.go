@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/ali01/mnemosyne/internal/layout"
+)
+
+// SetLayoutRunner enables server-side layout jobs.
+func (s *Server) SetLayoutRunner(r *layout.Runner) {
+	s.layouts = r
+}
+
+type layoutRequest struct {
+	GraphID   int    `json:"graph_id"`
+	Algorithm string `json:"algorithm"`
+}
+
+// handleComputeLayout starts a layout job for a graph. The job runs in the
+// background; its status is served at /api/v1/layouts/jobs/{id}.
+func (s *Server) handleComputeLayout(w http.ResponseWriter, r *http.Request) {
+	if s.layouts == nil {
+		writeError(w, r, CodeUnavailable, "Layout jobs are not available")
+		return
+	}
+	var req layoutRequest
+	if !s.readJSON(w, r, &req) {
+		return
+	}
+	alg, err := layout.ParseAlgorithm(req.Algorithm)
+	if err != nil {
+		writeErrorDetails(w, r, CodeBadRequest, "Unknown layout algorithm", map[string]interface{}{
+			"algorithms": layout.Algorithms,
+		})
+		return
+	}
+	if _, err := s.store.GetGraphInfo(req.GraphID); err != nil {
+		writeError(w, r, CodeNotFound, "Graph not found")
+		return
+	}
+
+	job, err := s.layouts.Start(req.GraphID, alg)
+	if errors.Is(err, layout.ErrJobActive) {
+		writeError(w, r, CodeLayoutJobActive, "A layout job is already running for this graph")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to start layout job: %v", err)
+		writeError(w, r, CodeInternal, "Failed to start layout job")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleGetLayoutJob(w http.ResponseWriter, r *http.Request) {
+	job, err := s.store.GetLayoutJob(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, CodeNotFound, "Layout job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/vault"
+)
+
+// handleExternalLinks lists the external URLs notes link to, with the notes
+// linking to each and the link checker's last result. With ?dead=true only
+// URLs found dead are listed. Notes the requester cannot see are left out,
+// and so are URLs only such notes link to.
+func (s *Server) handleExternalLinks(w http.ResponseWriter, r *http.Request) {
+	deadOnly := false
+	if v := r.URL.Query().Get("dead"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, r, CodeBadRequest, "Invalid dead")
+			return
+		}
+		deadOnly = b
+	}
+
+	links, err := s.store.GetExternalLinks(deadOnly)
+	if err != nil {
+		log.Printf("Failed to fetch external links: %v", err)
+		writeError(w, r, CodeInternal, "Failed to fetch external links")
+		return
+	}
+
+	var ids []string
+	for _, l := range links {
+		ids = append(ids, l.NodeIDs...)
+	}
+	visible, ok := s.visibleNodeIDs(w, r, ids)
+	if !ok {
+		return
+	}
+
+	out := make([]models.ExternalLink, 0, len(links))
+	for _, l := range links {
+		if l.NodeIDs = keepVisible(l.NodeIDs, visible); len(l.NodeIDs) > 0 {
+			out = append(out, l)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"links": out})
+}
+
+// handleBrokenLinks lists the link targets that resolve to no node, with
+// the notes linking to each. Notes the requester cannot see are left out,
+// and so are targets only such notes link to.
+func (s *Server) handleBrokenLinks(w http.ResponseWriter, r *http.Request) {
+	links, err := s.store.GetBrokenLinks()
+	if err != nil {
+		log.Printf("Failed to fetch broken links: %v", err)
+		writeError(w, r, CodeInternal, "Failed to fetch broken links")
+		return
+	}
+
+	var ids []string
+	for _, l := range links {
+		ids = append(ids, l.NodeIDs...)
+	}
+	visible, ok := s.visibleNodeIDs(w, r, ids)
+	if !ok {
+		return
+	}
+
+	out := make([]models.BrokenLink, 0, len(links))
+	for _, l := range links {
+		if l.NodeIDs = keepVisible(l.NodeIDs, visible); len(l.NodeIDs) > 0 {
+			out = append(out, l)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"links": out})
+}
+
+// unlinkedMention is a note naming another in plain text without linking
+// to it.
+type unlinkedMention struct {
+	SourceID string `json:"source_id"`
+	TargetID string `json:"target_id"`
+	Text     string `json:"text"`  // The target's name as the source first writes it
+	Count    int    `json:"count"` // Times the source names it
+}
+
+// handleUnlinkedMentions lists the notes named in other notes' text without
+// a link, found at index time when graph.unlinked-mentions is set, for
+// review before linking them. Query: source and target (optional node IDs)
+// keep only the mentions in or of a note. Mentions are listed only when the
+// requester can see both notes.
+func (s *Server) handleUnlinkedMentions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	edges, err := s.store.GetPotentialEdges(vault.UnlinkedMentionType, q.Get("source"), q.Get("target"))
+	if err != nil {
+		log.Printf("Failed to fetch unlinked mentions: %v", err)
+		writeError(w, r, CodeInternal, "Failed to fetch unlinked mentions")
+		return
+	}
+
+	var ids []string
+	for _, e := range edges {
+		ids = append(ids, e.SourceID, e.TargetID)
+	}
+	visible, ok := s.visibleNodeIDs(w, r, ids)
+	if !ok {
+		return
+	}
+
+	out := make([]unlinkedMention, 0, len(edges))
+	for _, e := range edges {
+		if visible[e.SourceID] && visible[e.TargetID] {
+			out = append(out, unlinkedMention{SourceID: e.SourceID, TargetID: e.TargetID, Text: e.DisplayText, Count: int(e.Weight)})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"mentions": out})
+}
+
+// visibleNodeIDs returns which of the nodes ids the requester can see. It
+// writes an error and returns false if the nodes cannot be loaded.
+func (s *Server) visibleNodeIDs(w http.ResponseWriter, r *http.Request, ids []string) (map[string]bool, bool) {
+	nodes, err := s.store.GetNodesByIDs(ids)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch nodes")
+		return nil, false
+	}
+	visible := make(map[string]bool, len(nodes))
+	for _, n := range s.visibleNodes(r, nodes) {
+		visible[n.ID] = true
+	}
+	return visible, true
+}
+
+// keepVisible returns the IDs in ids that are visible.
+func keepVisible(ids []string, visible map[string]bool) []string {
+	var out []string
+	for _, id := range ids {
+		if visible[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/access"
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	liveBufferSize   = 64               // Events queued per live client before dropping
+	livePingInterval = 30 * time.Second // Keeps idle connections open through proxies
+	liveWriteTimeout = 10 * time.Second
+	liveMaxMessage   = 1 << 20
+)
+
+// liveUpgrader accepts connections from any origin, matching the API's CORS
+// policy, and selects the bearer subprotocol when a client authenticates with it.
+var liveUpgrader = websocket.Upgrader{
+	CheckOrigin:  func(*http.Request) bool { return true },
+	Subprotocols: []string{access.BearerProtocol},
+}
+
+// livePosition is a node position exchanged in a live layout session.
+type livePosition struct {
+	NodeID string  `json:"node_id"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Z      float64 `json:"z,omitempty"`
+}
+
+// liveMessage is sent by live clients. "positions" saves the positions and
+// broadcasts them as a positions-updated event; "moving" only broadcasts them,
+// as positions-moving, for nodes still being dragged.
+type liveMessage struct {
+	Type      string         `json:"type"`
+	Positions []livePosition `json:"positions"`
+}
+
+// handleLivePositions joins a WebSocket room for a graph. Position changes
+// sent by one client, or saved through the REST endpoints, are broadcast to
+// the others with the name of the user who made them. Anyone who may read the
+// graph can watch; sending changes requires a user when auth is enabled.
+func (s *Server) handleLivePositions(w http.ResponseWriter, r *http.Request) {
+	graphID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, CodeBadRequest, "Invalid graph ID")
+		return
+	}
+	if _, err := s.store.GetGraphInfo(graphID); err != nil {
+		writeError(w, r, CodeNotFound, "Graph not found")
+		return
+	}
+
+	conn, err := liveUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade has already replied
+	}
+	defer conn.Close()
+	conn.SetReadLimit(liveMaxMessage)
+
+	user := access.UserFromContext(r.Context())
+	ch := s.subscribe(liveBufferSize)
+	defer s.unsubscribe(ch)
+
+	// Replies to the client's own messages, e.g. errors, go through the writer
+	replies := make(chan map[string]string, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var msg liveMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if errMsg := s.applyLiveMessage(r, graphID, user, ch, msg); errMsg != "" {
+				select {
+				case replies <- map[string]string{"type": "error", "error": errMsg}:
+				default:
+				}
+			}
+		}
+	}()
+
+	ping := time.NewTicker(livePingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case <-r.Context().Done():
+			return
+		case reply := <-replies:
+			conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			err = conn.WriteJSON(reply)
+		case evt := <-ch:
+			if !isLiveEvent(evt, graphID) {
+				continue
+			}
+			evt, ok := s.visibleEvent(user, evt)
+			if !ok {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			err = conn.WriteJSON(evt)
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveWriteTimeout))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// isLiveEvent reports whether a live client of graphID wants evt.
+func isLiveEvent(evt sseEvent, graphID int) bool {
+	if evt.Type != "positions-updated" && evt.Type != "positions-moving" {
+		return false
+	}
+	return len(evt.GraphIDs) == 1 && evt.GraphIDs[0] == graphID
+}
+
+// applyLiveMessage saves and broadcasts a live client's positions. It returns
+// an error message for the client, or "" on success.
+func (s *Server) applyLiveMessage(r *http.Request, graphID int, user *access.User, origin chan sseEvent, msg liveMessage) string {
+	if s.auth != nil && user == nil {
+		return "Authentication required"
+	}
+	if len(msg.Positions) == 0 {
+		return "No positions provided"
+	}
+
+	positions := make([]models.NodePosition, len(msg.Positions))
+	for i, p := range msg.Positions {
+		positions[i] = models.NodePosition{NodeID: p.NodeID, X: p.X, Y: p.Y, Z: p.Z}
+	}
+	perr, err := s.checkPositions(graphID, positions)
+	if err != nil {
+		log.Printf("Failed to check live positions for graph %d: %v", graphID, err)
+		return "Failed to check positions"
+	}
+	if perr != nil {
+		return perr.Message
+	}
+
+	switch msg.Type {
+	case "positions":
+		if err := s.store.UpsertPositions(graphID, positions); err != nil {
+			log.Printf("Failed to save live positions for graph %d: %v", graphID, err)
+			return "Failed to update positions"
+		}
+		if s.positionSync != nil {
+			s.positionSync.MarkDirty(graphID)
+		}
+		s.publishPositions(r, "positions-updated", graphID, msg.Positions, origin)
+	case "moving":
+		s.publishPositions(r, "positions-moving", graphID, msg.Positions, origin)
+	default:
+		return "Unknown message type"
+	}
+	return ""
+}
+
+// publishPositions broadcasts position changes to SSE and live clients,
+// attributed to the requesting user. origin, if set, is not sent the event.
+func (s *Server) publishPositions(r *http.Request, eventType string, graphID int, positions []livePosition, origin chan sseEvent) {
+	evt := sseEvent{
+		Type:      eventType,
+		GraphIDs:  []int{graphID},
+		Positions: positions,
+		origin:    origin,
+		nodes:     make(map[string]*models.VaultNode, len(positions)),
+	}
+	if u := access.UserFromContext(r.Context()); u != nil {
+		evt.User = u.Name
+	}
+	for _, p := range positions {
+		if node, err := s.store.GetNode(p.NodeID); err == nil {
+			evt.nodes[p.NodeID] = node
+		}
+	}
+	s.broadcast(evt)
+}
+
+// visibleEvent returns evt with only the positions u may see, and false if
+// none are left. Other events pass through unchanged.
+func (s *Server) visibleEvent(u *access.User, evt sseEvent) (sseEvent, bool) {
+	if evt.Positions == nil {
+		return evt, true
+	}
+	visible := make([]livePosition, 0, len(evt.Positions))
+	for _, p := range evt.Positions {
+		if node := evt.nodes[p.NodeID]; node != nil && s.policy.CanView(u, node) {
+			visible = append(visible, p)
+		}
+	}
+	evt.Positions = visible
+	return evt, len(visible) > 0
+}
+
+// toLivePositions converts positions saved through the REST endpoints for
+// broadcasting.
+func toLivePositions(positions []models.NodePosition) []livePosition {
+	live := make([]livePosition, len(positions))
+	for i, p := range positions {
+		live[i] = livePosition{NodeID: p.NodeID, X: p.X, Y: p.Y, Z: p.Z}
+	}
+	return live
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// parseMetrics aggregates completed full index runs so performance regressions
+// stand out.
+type parseMetrics struct {
+	Runs       int           `json:"runs"`
+	DurationMS summaryStats  `json:"duration_ms"`
+	Nodes      summaryStats  `json:"nodes"`
+	Edges      summaryStats  `json:"edges"`
+	FilesPerS  summaryStats  `json:"files_per_second"`
+	Latest     *parseSample  `json:"latest,omitempty"`
+	Series     []parseSample `json:"series"` // Oldest first
+}
+
+type summaryStats struct {
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+}
+
+type parseSample struct {
+	ID         string    `json:"id"`
+	VaultID    int       `json:"vault_id"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Files      int       `json:"files"`
+	Nodes      int       `json:"nodes"`
+	Edges      int       `json:"edges"`
+	FilesPerS  float64   `json:"files_per_second"`
+}
+
+// handleParseMetrics aggregates duration, graph size, and throughput across
+// completed full index runs. Query: vault_id (default all), limit (default 50, max 500).
+func (s *Server) handleParseMetrics(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := 50
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, r, CodeBadRequest, "Invalid limit")
+			return
+		}
+		limit = min(n, 500)
+	}
+	vaultID := 0
+	if v := q.Get("vault_id"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, CodeBadRequest, "Invalid vault_id")
+			return
+		}
+		vaultID = n
+	}
+
+	history, err := s.store.GetParseHistory(vaultID, models.ParseStatusCompleted, limit)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch parse history")
+		return
+	}
+	writeJSON(w, http.StatusOK, computeParseMetrics(history))
+}
+
+// computeParseMetrics summarizes history, which is ordered newest first.
+func computeParseMetrics(history []models.ParseHistory) parseMetrics {
+	m := parseMetrics{Runs: len(history), Series: make([]parseSample, 0, len(history))}
+	if len(history) == 0 {
+		return m
+	}
+
+	var durations, nodes, edges, rates []float64
+	for i := len(history) - 1; i >= 0; i-- {
+		h := history[i]
+		st := h.Stats.ToParseStats()
+		sample := parseSample{
+			ID:         h.ID,
+			VaultID:    h.VaultID,
+			StartedAt:  h.StartedAt,
+			DurationMS: st.DurationMS,
+			Files:      st.ParsedFiles,
+			Nodes:      st.TotalNodes,
+			Edges:      st.TotalEdges,
+		}
+		if st.DurationMS > 0 {
+			sample.FilesPerS = round2(float64(st.ParsedFiles) / (float64(st.DurationMS) / 1000))
+		}
+		m.Series = append(m.Series, sample)
+
+		durations = append(durations, float64(sample.DurationMS))
+		nodes = append(nodes, float64(sample.Nodes))
+		edges = append(edges, float64(sample.Edges))
+		rates = append(rates, sample.FilesPerS)
+	}
+
+	m.DurationMS = summarize(durations)
+	m.Nodes = summarize(nodes)
+	m.Edges = summarize(edges)
+	m.FilesPerS = summarize(rates)
+	m.Latest = &m.Series[len(m.Series)-1]
+	return m
+}
+
+func summarize(values []float64) summaryStats {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	return summaryStats{
+		Min:  sorted[0],
+		Max:  sorted[len(sorted)-1],
+		Mean: round2(sum / float64(len(sorted))),
+		P50:  percentile(sorted, 0.50),
+		P95:  percentile(sorted, 0.95),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
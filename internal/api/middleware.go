@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps an http.Handler, e.g. to add authentication or logging.
+type Middleware func(http.Handler) http.Handler
+
+// Use appends middlewares that wrap every route, including the built-in API
+// and static routes. The first middleware added is the outermost. CORS
+// handling and authentication always run before any registered middleware.
+func (s *Server) Use(mw ...Middleware) {
+	s.middlewares = append(s.middlewares, mw...)
+}
+
+// Handle registers a custom route using net/http pattern syntax,
+// e.g. "GET /api/v1/custom/{id}".
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+// HandleFunc registers a custom route handler function.
+func (s *Server) HandleFunc(pattern string, h http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, h)
+}
+
+// RouteGroup registers routes under a common path prefix with their own middlewares.
+type RouteGroup struct {
+	server      *Server
+	prefix      string
+	middlewares []Middleware
+}
+
+// Group returns a route group rooted at prefix (e.g. "/api/v1/plugins").
+// Middlewares passed here apply only to routes in the group, inside any
+// server-wide middlewares.
+func (s *Server) Group(prefix string, mw ...Middleware) *RouteGroup {
+	return &RouteGroup{server: s, prefix: strings.TrimSuffix(prefix, "/"), middlewares: mw}
+}
+
+// Handle registers a route in the group. The pattern may include a method,
+// e.g. "GET /items/{id}"; its path is joined to the group prefix.
+func (g *RouteGroup) Handle(pattern string, h http.Handler) {
+	method, path := "", pattern
+	if i := strings.Index(pattern, " "); i >= 0 {
+		method, path = pattern[:i+1], strings.TrimSpace(pattern[i+1:])
+	}
+	g.server.mux.Handle(method+g.prefix+path, chain(h, g.middlewares))
+}
+
+// HandleFunc registers a route handler function in the group.
+func (g *RouteGroup) HandleFunc(pattern string, h http.HandlerFunc) {
+	g.Handle(pattern, h)
+}
+
+// chain wraps h so that mws[0] is the outermost middleware.
+func chain(h http.Handler, mws []Middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+const (
+	maxNeighborDepth = 5
+
+	// maxNeighborNodes bounds a neighborhood; the nearest nodes are kept.
+	maxNeighborNodes = 5000
+)
+
+// Directions of the links a neighborhood follows.
+const (
+	directionBoth = "both"
+	directionIn   = "in"  // Links to the node
+	directionOut  = "out" // Links from the node
+)
+
+// handleGetNeighbors returns the subgraph of the nodes within depth links of
+// a node, for expanding the graph around it without loading all of it.
+// Query: depth (1, the default, to 5), direction (both, the default; in, to
+// follow links to the nodes reached; out, links from them), graph_id
+// (optional; only members of the graph are reached, and they carry its
+// positions). Nodes the requester cannot see are neither returned nor
+// followed. Edges are those between the nodes returned in the direction
+// followed; hops gives each node's distance from the first.
+func (s *Server) handleGetNeighbors(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	depth := 1
+	if v := q.Get("depth"); v != "" {
+		var err error
+		if depth, err = strconv.Atoi(v); err != nil || depth < 1 || depth > maxNeighborDepth {
+			writeError(w, r, CodeBadRequest, "Depth must be from 1 to 5")
+			return
+		}
+	}
+	direction := cmp.Or(q.Get("direction"), directionBoth)
+	if direction != directionBoth && direction != directionIn && direction != directionOut {
+		writeError(w, r, CodeBadRequest, "Direction must be in, out or both")
+		return
+	}
+	graphID := 0
+	if v := q.Get("graph_id"); v != "" {
+		var err error
+		if graphID, err = strconv.Atoi(v); err != nil {
+			writeError(w, r, CodeBadRequest, "Invalid graph ID")
+			return
+		}
+		if _, err := s.store.GetGraphInfo(graphID); err != nil {
+			writeError(w, r, CodeNotFound, "Graph not found")
+			return
+		}
+	}
+
+	start, ok := s.visibleNode(r, r.PathValue("id"))
+	if ok && graphID != 0 {
+		missing, err := s.store.MissingGraphMembers(graphID, []string{start.ID})
+		if err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch graph members")
+			return
+		}
+		ok = len(missing) == 0
+	}
+	if !ok {
+		writeError(w, r, CodeNotFound, "Node not found")
+		return
+	}
+
+	hops := map[string]int{start.ID: 0}
+	reached := []models.VaultNode{*start}
+	var edges []models.VaultEdge
+	truncated := false
+	for frontier, hop := []string{start.ID}, 1; len(frontier) > 0 && hop <= depth && !truncated; hop++ {
+		found, err := s.store.GetEdgesByNodes(frontier)
+		if err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch edges")
+			return
+		}
+		inFrontier := make(map[string]bool, len(frontier))
+		for _, id := range frontier {
+			inFrontier[id] = true
+		}
+		var candidates []string
+		for _, e := range found {
+			if (direction != directionIn && inFrontier[e.SourceID]) || (direction != directionOut && inFrontier[e.TargetID]) {
+				edges = append(edges, e)
+				for _, id := range []string{e.SourceID, e.TargetID} {
+					if _, ok := hops[id]; !ok && !slices.Contains(candidates, id) {
+						candidates = append(candidates, id)
+					}
+				}
+			}
+		}
+
+		nodes, err := s.store.GetNodesByIDs(candidates)
+		if err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch nodes")
+			return
+		}
+		nodes = s.visibleNodes(r, nodes)
+		if graphID != 0 && len(nodes) > 0 {
+			ids := make([]string, len(nodes))
+			for i, n := range nodes {
+				ids[i] = n.ID
+			}
+			missing, err := s.store.MissingGraphMembers(graphID, ids)
+			if err != nil {
+				writeError(w, r, CodeInternal, "Failed to fetch graph members")
+				return
+			}
+			nodes = slices.DeleteFunc(nodes, func(n models.VaultNode) bool { return slices.Contains(missing, n.ID) })
+		}
+		slices.SortFunc(nodes, func(a, b models.VaultNode) int { return cmp.Compare(a.ID, b.ID) })
+		if room := maxNeighborNodes - len(reached); len(nodes) > room {
+			nodes, truncated = nodes[:room], true
+		}
+		frontier = frontier[:0:0]
+		for _, n := range nodes {
+			hops[n.ID] = hop
+			reached = append(reached, n)
+			frontier = append(frontier, n.ID)
+		}
+	}
+
+	var positions map[string]models.NodePosition
+	if graphID != 0 {
+		var err error
+		if positions, err = s.store.GetPositionsByGraph(graphID); err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch positions")
+			return
+		}
+	}
+	apiNodes := make([]models.Node, 0, len(reached))
+	for _, n := range reached {
+		pos := positions[n.ID]
+		apiNodes = append(apiNodes, models.Node{
+			ID:          n.ID,
+			Title:       n.Title,
+			FilePath:    n.FilePath,
+			Position:    models.Position{X: pos.X, Y: pos.Y, Z: pos.Z},
+			Pinned:      pos.Pinned,
+			Level:       n.Level,
+			WordCount:   n.WordCount,
+			ReadingTime: n.ReadingTime,
+			Excerpt:     n.Excerpt,
+			Metadata:    map[string]interface{}{"type": n.NodeType},
+		})
+	}
+	// An edge is found from each end in the frontier, so may come twice
+	seen := make(map[string]bool, len(edges))
+	apiEdges := make([]models.Edge, 0, len(edges))
+	for _, e := range edges {
+		_, src := hops[e.SourceID]
+		_, tgt := hops[e.TargetID]
+		if src && tgt && !seen[e.ID] {
+			seen[e.ID] = true
+			apiEdges = append(apiEdges, models.Edge{ID: e.ID, Source: e.SourceID, Target: e.TargetID, Weight: e.Weight, Type: e.EdgeType})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id":   start.ID,
+		"depth":     depth,
+		"direction": direction,
+		"nodes":     apiNodes,
+		"edges":     apiEdges,
+		"hops":      hops,
+		"truncated": truncated,
+	})
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultNodeMatches = 100
+	maxNodeMatches     = 1000
+
+	// snippetRadius is how many characters of the match's line a snippet
+	// keeps on each side of it.
+	snippetRadius = 40
+)
+
+// contentMatch is one occurrence of a query in a note's content.
+type contentMatch struct {
+	Offset int    `json:"offset"` // Byte offset into the content
+	Length int    `json:"length"` // In bytes
+	Line   int    `json:"line"`   // From 1, counting frontmatter lines, as outline lines do
+	Column int    `json:"column"` // Character of the line the match starts at, from 1
+	Before string `json:"before"` // Text of the line before the match, cut to snippetRadius characters and "…"
+	Match  string `json:"match"`
+	After  string `json:"after"`
+}
+
+// handleSearchNode finds the occurrences of a query in one note's content,
+// for find-in-note without loading the content. Query: q (required; plain
+// text, not a pattern), case_sensitive (default false), limit (default 100,
+// at most 1000 matches; total counts them all).
+func (s *Server) handleSearchNode(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := q.Get("q")
+	if query == "" {
+		writeError(w, r, CodeBadRequest, "Query parameter 'q' is required")
+		return
+	}
+	caseSensitive := false
+	if v := q.Get("case_sensitive"); v != "" {
+		var err error
+		if caseSensitive, err = strconv.ParseBool(v); err != nil {
+			writeError(w, r, CodeBadRequest, "Invalid case_sensitive")
+			return
+		}
+	}
+	limit := defaultNodeMatches
+	if v := q.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			writeError(w, r, CodeBadRequest, "Invalid limit")
+			return
+		}
+		limit = min(limit, maxNodeMatches)
+	}
+
+	node, ok := s.visibleNode(r, r.PathValue("id"))
+	if !ok {
+		writeError(w, r, CodeNotFound, "Node not found")
+		return
+	}
+
+	matches, total := findInContent(node.Content, query, caseSensitive, limit)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"node_id": node.ID,
+		"query":   query,
+		"total":   total,
+		"matches": matches,
+	})
+}
+
+// findInContent returns the first limit non-overlapping occurrences of query
+// in content, and how many there are in all. Case is folded as Unicode
+// defines it unless caseSensitive.
+func findInContent(content, query string, caseSensitive bool, limit int) ([]contentMatch, int) {
+	pattern := regexp.QuoteMeta(query)
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	locs := regexp.MustCompile(pattern).FindAllStringIndex(content, -1)
+
+	matches := make([]contentMatch, 0, min(len(locs), limit))
+	line, lineStart := 1, 0
+	for _, loc := range locs[:min(len(locs), limit)] {
+		// Matches come in order, so lines are counted once
+		for {
+			i := strings.IndexByte(content[lineStart:loc[0]], '\n')
+			if i < 0 {
+				break
+			}
+			line++
+			lineStart += i + 1
+		}
+		lineEnd := len(content)
+		if i := strings.IndexByte(content[loc[1]:], '\n'); i >= 0 {
+			lineEnd = loc[1] + i
+		}
+		matches = append(matches, contentMatch{
+			Offset: loc[0],
+			Length: loc[1] - loc[0],
+			Line:   line,
+			Column: utf8.RuneCountInString(content[lineStart:loc[0]]) + 1,
+			Before: snippetBefore(content[lineStart:loc[0]]),
+			Match:  content[loc[0]:loc[1]],
+			After:  snippetAfter(strings.TrimSuffix(content[loc[1]:lineEnd], "\r")),
+		})
+	}
+	return matches, len(locs)
+}
+
+// snippetBefore returns the last snippetRadius characters of text, marked
+// with "…" if cut.
+func snippetBefore(text string) string {
+	if runes := []rune(text); len(runes) > snippetRadius {
+		return "…" + string(runes[len(runes)-snippetRadius:])
+	}
+	return text
+}
+
+// snippetAfter returns the first snippetRadius characters of text, marked
+// with "…" if cut.
+func snippetAfter(text string) string {
+	if runes := []rune(text); len(runes) > snippetRadius {
+		return string(runes[:snippetRadius]) + "…"
+	}
+	return text
+}
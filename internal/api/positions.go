@@ -0,0 +1,320 @@
+package api
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/positionsync"
+)
+
+// positionsExportVersion is the format version of positionsExport documents.
+const positionsExportVersion = 1
+
+// positionsExport is a portable document of every saved position. Graphs are
+// identified by vault name and root path rather than database ID, so layouts
+// can be copied between environments.
+type positionsExport struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Graphs     []graphPositions `json:"graphs"`
+}
+
+type graphPositions struct {
+	Vault     string                      `json:"vault"`
+	RootPath  string                      `json:"root_path"`
+	Name      string                      `json:"name,omitempty"`
+	Positions map[string]exportedPosition `json:"positions"`
+}
+
+type exportedPosition struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Z      float64 `json:"z,omitempty"`
+	Locked bool    `json:"locked,omitempty"`
+	Pinned bool    `json:"pinned,omitempty"`
+}
+
+// graphLocator identifies a graph across environments.
+type graphLocator struct {
+	Vault    string `json:"vault"`
+	RootPath string `json:"root_path"`
+}
+
+// handleExportPositions returns the positions of every graph, including
+// archived ones. Requesters who cannot see every note get only the positions
+// of the notes they can.
+func (s *Server) handleExportPositions(w http.ResponseWriter, r *http.Request) {
+	graphs, err := s.store.GetAllGraphsIncludeArchived()
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to list graphs")
+		return
+	}
+	visible, err := s.visibleIDs(r)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch positions")
+		return
+	}
+	sort.Slice(graphs, func(i, j int) bool {
+		if graphs[i].VaultName != graphs[j].VaultName {
+			return graphs[i].VaultName < graphs[j].VaultName
+		}
+		return graphs[i].RootPath < graphs[j].RootPath
+	})
+
+	doc := positionsExport{
+		Version:    positionsExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Graphs:     make([]graphPositions, 0, len(graphs)),
+	}
+	for _, g := range graphs {
+		positions, err := s.store.GetPositionsByGraph(g.ID)
+		if err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch positions")
+			return
+		}
+		gp := graphPositions{
+			Vault:     g.VaultName,
+			RootPath:  g.RootPath,
+			Name:      g.Name,
+			Positions: make(map[string]exportedPosition, len(positions)),
+		}
+		for id, p := range positions {
+			if visible != nil && !visible[id] {
+				continue
+			}
+			gp.Positions[id] = exportedPosition{X: p.X, Y: p.Y, Z: p.Z, Locked: p.Locked, Pinned: p.Pinned}
+		}
+		doc.Graphs = append(doc.Graphs, gp)
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="mnemosyne-positions.json"`)
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// handleImportPositions accepts a document from handleExportPositions. Each
+// graph in it that exists here, matched by vault name and root path, has its
+// positions replaced; other graphs are left alone and reported as skipped.
+// For requesters who cannot see every note, only the positions of the notes
+// they can see are replaced, and the rest of the document is ignored.
+func (s *Server) handleImportPositions(w http.ResponseWriter, r *http.Request) {
+	var doc positionsExport
+	if !s.readJSON(w, r, &doc) {
+		return
+	}
+	if doc.Version != positionsExportVersion {
+		writeError(w, r, CodeBadRequest, "Unsupported positions export version")
+		return
+	}
+	// Validate everything up front so a bad position imports nothing. Node
+	// IDs are not checked: layouts may be imported before their notes are.
+	for _, gp := range doc.Graphs {
+		for id, p := range gp.Positions {
+			pos := models.NodePosition{NodeID: id, X: p.X, Y: p.Y, Z: p.Z}
+			var fe *models.FieldError
+			if err := pos.Validate(s.maxCoord); errors.As(err, &fe) {
+				writeErrorDetails(w, r, CodeValidationFailed, "Invalid position: "+err.Error(), map[string]string{
+					"field": fe.Field, "node_id": id, "vault": gp.Vault, "root_path": gp.RootPath,
+				})
+				return
+			}
+		}
+	}
+
+	graphs, err := s.store.GetAllGraphsIncludeArchived()
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to list graphs")
+		return
+	}
+	visible, err := s.visibleIDs(r)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to import positions")
+		return
+	}
+	var visibleList []string
+	for id := range visible {
+		visibleList = append(visibleList, id)
+	}
+	byLocator := make(map[graphLocator]int, len(graphs))
+	for _, g := range graphs {
+		byLocator[graphLocator{g.VaultName, g.RootPath}] = g.ID
+	}
+
+	imported, count := 0, 0
+	skipped := []graphLocator{}
+	for _, gp := range doc.Graphs {
+		loc := graphLocator{gp.Vault, gp.RootPath}
+		graphID, ok := byLocator[loc]
+		if !ok {
+			skipped = append(skipped, loc)
+			continue
+		}
+		positions := make([]models.NodePosition, 0, len(gp.Positions))
+		for id, p := range gp.Positions {
+			if visible != nil && !visible[id] {
+				continue
+			}
+			positions = append(positions, models.NodePosition{NodeID: id, X: p.X, Y: p.Y, Z: p.Z, Locked: p.Locked, Pinned: p.Pinned})
+		}
+		if visible != nil {
+			err = s.store.ReplacePositionsOf(graphID, visibleList, positions)
+		} else {
+			err = s.store.ReplacePositions(graphID, positions)
+		}
+		if err != nil {
+			writeError(w, r, CodeInternal, "Failed to import positions")
+			return
+		}
+		if s.positionSync != nil {
+			s.positionSync.MarkDirty(graphID)
+		}
+		imported++
+		count += len(positions)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"graphs":    imported,
+		"positions": count,
+		"skipped":   skipped,
+	})
+}
+
+// remapRequest remaps one node ID (OldID/NewID) or many (Mappings).
+type remapRequest struct {
+	OldID    string       `json:"old_id"`
+	NewID    string       `json:"new_id"`
+	Mappings []remapEntry `json:"mappings"`
+}
+
+type remapEntry struct {
+	OldID string `json:"old_id"`
+	NewID string `json:"new_id"`
+}
+
+// handleRemapPositions moves saved positions from old node IDs to new ones in
+// every graph, so layouts survive frontmatter ID changes. The body is JSON,
+// or CSV rows of old_id,new_id when sent as text/csv.
+func (s *Server) handleRemapPositions(w http.ResponseWriter, r *http.Request) {
+	var remap map[string]string
+	var err error
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		remap, err = positionsync.ReadRemapCSV(s.limitBody(w, r))
+		if errors.As(err, new(*http.MaxBytesError)) {
+			writeBodyError(w, r, err)
+			return
+		}
+	} else {
+		var req remapRequest
+		if !s.readJSON(w, r, &req) {
+			return
+		}
+		pairs := make([][2]string, 0, len(req.Mappings)+1)
+		if req.OldID != "" || req.NewID != "" {
+			pairs = append(pairs, [2]string{req.OldID, req.NewID})
+		}
+		for _, m := range req.Mappings {
+			pairs = append(pairs, [2]string{m.OldID, m.NewID})
+		}
+		remap, err = positionsync.ValidateRemap(pairs)
+	}
+	if err != nil {
+		writeError(w, r, CodeBadRequest, "Invalid remapping: "+err.Error())
+		return
+	}
+
+	graphIDs, moved, err := s.store.RemapPositions(remap)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to remap positions")
+		return
+	}
+	if s.positionSync != nil {
+		for _, id := range graphIDs {
+			s.positionSync.MarkDirty(id)
+		}
+	}
+
+	if graphIDs == nil {
+		graphIDs = []int{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"remapped":  len(remap),
+		"positions": moved,
+		"graph_ids": graphIDs,
+	})
+}
+
+// pinRequest pins or unpins a selection of nodes. Pinned defaults to true.
+type pinRequest struct {
+	NodeIDs []string `json:"node_ids"`
+	Pinned  *bool    `json:"pinned"`
+}
+
+// handlePinNode pins (PUT) or unpins (DELETE) a single node.
+func (s *Server) handlePinNode(w http.ResponseWriter, r *http.Request) {
+	graphID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, CodeBadRequest, "Invalid graph ID")
+		return
+	}
+	s.setPinned(w, r, graphID, []string{r.PathValue("nodeId")}, r.Method == http.MethodPut)
+}
+
+// handlePinNodes pins or unpins a selection of nodes.
+func (s *Server) handlePinNodes(w http.ResponseWriter, r *http.Request) {
+	graphID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, CodeBadRequest, "Invalid graph ID")
+		return
+	}
+	var req pinRequest
+	if !s.readJSON(w, r, &req) {
+		return
+	}
+	if len(req.NodeIDs) == 0 {
+		writeError(w, r, CodeBadRequest, "No node IDs provided")
+		return
+	}
+	s.setPinned(w, r, graphID, req.NodeIDs, req.Pinned == nil || *req.Pinned)
+}
+
+// setPinned updates the pinned flag of nodes with saved positions. Nodes
+// without one are reported as missing: they must be positioned first.
+func (s *Server) setPinned(w http.ResponseWriter, r *http.Request, graphID int, nodeIDs []string, pinned bool) {
+	positions, err := s.store.GetPositionsByGraph(graphID)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch positions")
+		return
+	}
+	var found []string
+	missing := []string{}
+	for _, id := range nodeIDs {
+		if _, ok := positions[id]; ok {
+			found = append(found, id)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(found) == 0 {
+		writeErrorDetails(w, r, CodeNotFound, "No saved positions for these nodes", map[string][]string{"missing": missing})
+		return
+	}
+
+	updated, err := s.store.SetPinned(graphID, found, pinned)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to update pinned nodes")
+		return
+	}
+	if s.positionSync != nil {
+		s.positionSync.MarkDirty(graphID)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pinned":  pinned,
+		"updated": updated,
+		"missing": missing,
+	})
+}
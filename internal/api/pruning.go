@@ -0,0 +1,151 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/search"
+	"github.com/ali01/mnemosyne/internal/store"
+	"github.com/ali01/mnemosyne/internal/vault"
+)
+
+// PruningProfile removes classes of nodes from a graph, e.g. an "overview"
+// without daily notes and orphans. Clients select one by name with
+// ?profile= on the graph endpoint. A profile that excludes nothing shows
+// the whole graph.
+type PruningProfile struct {
+	ExcludeDaily       bool     // Daily notes, named YYYY-MM-DD
+	ExcludeTypes       []string // Node types, e.g. "reference" or "person"
+	Exclude            string   // Search query, e.g. "path:archive OR tag:#draft"
+	ExcludeAttachments bool     // Embed edges, and notes that are only ever embedded
+	ExcludeOrphans     bool     // Nodes left without edges by the other steps
+}
+
+// pruneStep removes nodes or edges from graph data. Edges to removed nodes
+// are dropped between steps.
+type pruneStep func(g *store.GraphDataRaw)
+
+// SetPruningProfiles sets the profiles selectable with ?profile= on the
+// graph endpoint. It fails if a profile's Exclude query does not parse.
+func (s *Server) SetPruningProfiles(profiles map[string]PruningProfile) error {
+	chains := make(map[string][]pruneStep, len(profiles))
+	for name, p := range profiles {
+		chain, err := p.chain()
+		if err != nil {
+			return fmt.Errorf("pruning profile %q: %w", name, err)
+		}
+		chains[name] = chain
+	}
+	s.profiles = chains
+	return nil
+}
+
+// chain returns the profile's steps in the order they run. Orphans are
+// removed last, so nodes orphaned by earlier steps go too.
+func (p PruningProfile) chain() ([]pruneStep, error) {
+	var steps []pruneStep
+	if p.ExcludeDaily {
+		steps = append(steps, keepNodes(func(n *models.VaultNode) bool {
+			_, daily := vault.DailyNoteDate(n.FilePath)
+			return !daily
+		}))
+	}
+	if len(p.ExcludeTypes) > 0 {
+		steps = append(steps, keepNodes(func(n *models.VaultNode) bool {
+			return !slices.Contains(p.ExcludeTypes, n.NodeType)
+		}))
+	}
+	if p.Exclude != "" {
+		q, err := search.Parse(p.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, keepNodes(func(n *models.VaultNode) bool {
+			return !q.Match(&search.NodeData{
+				FilePath:    n.FilePath,
+				Title:       n.Title,
+				Tags:        []string(n.Tags),
+				Frontmatter: map[string]interface{}(n.Metadata),
+			})
+		}))
+	}
+	if p.ExcludeAttachments {
+		steps = append(steps, pruneAttachments)
+	}
+	if p.ExcludeOrphans {
+		steps = append(steps, pruneOrphans)
+	}
+	return steps, nil
+}
+
+// pruneGraph runs the profile named by ?profile= on g. It writes an error
+// and returns false if there is no such profile; no profile is no pruning.
+func (s *Server) pruneGraph(w http.ResponseWriter, r *http.Request, g *store.GraphDataRaw) bool {
+	name := r.URL.Query().Get("profile")
+	if name == "" {
+		return true
+	}
+	chain, ok := s.profiles[name]
+	if !ok {
+		names := make([]string, 0, len(s.profiles))
+		for n := range s.profiles {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		writeErrorDetails(w, r, CodeBadRequest, "Unknown profile", map[string][]string{"profiles": names})
+		return false
+	}
+	// Edges to nodes outside the graph or hidden from the user do not count
+	dropDanglingEdges(g)
+	for _, step := range chain {
+		step(g)
+		dropDanglingEdges(g)
+	}
+	return true
+}
+
+// keepNodes returns a step removing the nodes keep rejects.
+func keepNodes(keep func(n *models.VaultNode) bool) pruneStep {
+	return func(g *store.GraphDataRaw) {
+		g.Nodes = slices.DeleteFunc(g.Nodes, func(n models.VaultNode) bool { return !keep(&n) })
+	}
+}
+
+// pruneAttachments removes embed edges and the nodes all of whose incoming
+// edges are embeds: notes that exist to be embedded in others.
+func pruneAttachments(g *store.GraphDataRaw) {
+	embedded := make(map[string]bool)
+	linked := make(map[string]bool)
+	for _, e := range g.Edges {
+		if e.EdgeType == "embed" {
+			embedded[e.TargetID] = true
+		} else {
+			linked[e.TargetID] = true
+		}
+	}
+	g.Edges = slices.DeleteFunc(g.Edges, func(e models.VaultEdge) bool { return e.EdgeType == "embed" })
+	g.Nodes = slices.DeleteFunc(g.Nodes, func(n models.VaultNode) bool { return embedded[n.ID] && !linked[n.ID] })
+}
+
+// pruneOrphans removes nodes without edges.
+func pruneOrphans(g *store.GraphDataRaw) {
+	connected := make(map[string]bool)
+	for _, e := range g.Edges {
+		connected[e.SourceID] = true
+		connected[e.TargetID] = true
+	}
+	g.Nodes = slices.DeleteFunc(g.Nodes, func(n models.VaultNode) bool { return !connected[n.ID] })
+}
+
+// dropDanglingEdges removes edges whose source or target is not in g.
+func dropDanglingEdges(g *store.GraphDataRaw) {
+	present := make(map[string]bool, len(g.Nodes))
+	for _, n := range g.Nodes {
+		present[n.ID] = true
+	}
+	g.Edges = slices.DeleteFunc(g.Edges, func(e models.VaultEdge) bool {
+		return !present[e.SourceID] || !present[e.TargetID]
+	})
+}
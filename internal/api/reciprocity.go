@@ -0,0 +1,129 @@
+package api
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// nodePair is two nodes, A before B.
+type nodePair struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+// directedLink is a link from Source to Target that is not linked back.
+type directedLink struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// linkCounts counts edges by whether their target links back.
+type linkCounts struct {
+	Reciprocal int `json:"reciprocal"`
+	OneWay     int `json:"one_way"`
+}
+
+// reciprocityReport describes how many of a graph's links are reciprocated.
+// Pairs count two nodes once however many edges join them; an edge is
+// reciprocal if any edge, of any type, runs the other way.
+type reciprocityReport struct {
+	Edges           int                   `json:"edges"`
+	LinkedPairs     int                   `json:"linked_pairs"`
+	ReciprocalPairs int                   `json:"reciprocal_pairs"` // Linked both ways, A↔B
+	OneWayPairs     int                   `json:"one_way_pairs"`
+	Reciprocity     float64               `json:"reciprocity"` // Share of linked pairs that are reciprocal
+	EdgeTypes       map[string]linkCounts `json:"edge_types"`
+	Reciprocal      []nodePair            `json:"reciprocal"` // First pairs by ID, up to the limit
+	OneWay          []directedLink        `json:"one_way"`    // First links by source and target, up to the limit
+}
+
+// handleGraphReciprocity reports the graph's reciprocal (A↔B) and one-way
+// links, over the nodes the requester can see and the graph's filter.
+// Query: limit on the listed pairs and links (default 20, max 100).
+func (s *Server) handleGraphReciprocity(w http.ResponseWriter, r *http.Request) {
+	graphID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, CodeBadRequest, "Invalid graph ID")
+		return
+	}
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	if _, err := s.store.GetGraphInfo(graphID); err != nil {
+		writeError(w, r, CodeNotFound, "Graph not found")
+		return
+	}
+	raw, err := s.store.GetGraphDataRaw(graphID)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch graph")
+		return
+	}
+	raw.Nodes = s.visibleNodes(r, raw.Nodes)
+	writeJSON(w, http.StatusOK, reciprocity(applyFilterAndGroups(raw).Edges, limit))
+}
+
+// reciprocity builds the report on edges, listing up to limit pairs and
+// links.
+func reciprocity(edges []models.Edge, limit int) reciprocityReport {
+	linked := make(map[directedLink]bool)
+	for _, e := range edges {
+		if e.Source != e.Target {
+			linked[directedLink{e.Source, e.Target}] = true
+		}
+	}
+
+	rep := reciprocityReport{EdgeTypes: make(map[string]linkCounts), Reciprocal: []nodePair{}, OneWay: []directedLink{}}
+	for _, e := range edges {
+		if e.Source == e.Target {
+			continue
+		}
+		rep.Edges++
+		c := rep.EdgeTypes[e.Type]
+		if linked[directedLink{e.Target, e.Source}] {
+			c.Reciprocal++
+		} else {
+			c.OneWay++
+		}
+		rep.EdgeTypes[e.Type] = c
+	}
+
+	for l := range linked {
+		switch {
+		case !linked[directedLink{l.Target, l.Source}]:
+			rep.OneWay = append(rep.OneWay, l)
+		case l.Source < l.Target:
+			rep.Reciprocal = append(rep.Reciprocal, nodePair{l.Source, l.Target})
+		}
+	}
+	rep.ReciprocalPairs = len(rep.Reciprocal)
+	rep.OneWayPairs = len(rep.OneWay)
+	rep.LinkedPairs = rep.ReciprocalPairs + rep.OneWayPairs
+	if rep.LinkedPairs > 0 {
+		rep.Reciprocity = float64(rep.ReciprocalPairs) / float64(rep.LinkedPairs)
+	}
+
+	slices.SortFunc(rep.Reciprocal, func(x, y nodePair) int { return cmp.Or(cmp.Compare(x.A, y.A), cmp.Compare(x.B, y.B)) })
+	slices.SortFunc(rep.OneWay, func(x, y directedLink) int {
+		return cmp.Or(cmp.Compare(x.Source, y.Source), cmp.Compare(x.Target, y.Target))
+	})
+	rep.Reciprocal = rep.Reciprocal[:min(len(rep.Reciprocal), limit)]
+	rep.OneWay = rep.OneWay[:min(len(rep.OneWay), limit)]
+	return rep
+}
+
+// markBidirectional sets Bidirectional on the edges whose target links back
+// to their source.
+func markBidirectional(edges []models.Edge) {
+	linked := make(map[directedLink]bool, len(edges))
+	for _, e := range edges {
+		linked[directedLink{e.Source, e.Target}] = true
+	}
+	for i, e := range edges {
+		edges[i].Bidirectional = e.Source != e.Target && linked[directedLink{e.Target, e.Source}]
+	}
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/store"
+)
+
+// Sampling strategies for handleGraphSample.
+const (
+	sampleDegree     = "degree"      // The best-connected nodes
+	sampleRandom     = "random"      // Nodes drawn uniformly
+	sampleForestFire = "forest-fire" // Nodes reached by random burns, which keep local structure
+)
+
+const (
+	defaultSampleSize = 2000
+
+	// forestFireForward is the chance of a burn spreading on, which makes
+	// each burning node spread to forestFireForward/(1-forestFireForward)
+	// neighbors on average.
+	forestFireForward = 0.7
+)
+
+// graphSample is a subgraph chosen to preview a larger one.
+type graphSample struct {
+	Strategy   string `json:"strategy"`
+	Seed       uint64 `json:"seed"`
+	TotalNodes int    `json:"total_nodes"` // Nodes of the graph sampled
+	TotalEdges int    `json:"total_edges"`
+	models.Graph
+}
+
+// handleGraphSample returns a representative subgraph of the nodes the
+// requester can see, for quick previews of large vaults, with the edges
+// between the nodes chosen. Query: n (default 2000), strategy (degree, the
+// default, random or forest-fire), seed (default 1; the same seed over the
+// same graph gives the same sample), graph_id (default all notes; the
+// graph's filter, colors and positions then apply).
+func (s *Server) handleGraphSample(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	n := defaultSampleSize
+	if v := q.Get("n"); v != "" {
+		var err error
+		if n, err = strconv.Atoi(v); err != nil || n < 1 {
+			writeError(w, r, CodeBadRequest, "Invalid n")
+			return
+		}
+	}
+	strategy := cmp.Or(q.Get("strategy"), sampleDegree)
+	if strategy != sampleDegree && strategy != sampleRandom && strategy != sampleForestFire {
+		writeError(w, r, CodeBadRequest, "Strategy must be degree, random or forest-fire")
+		return
+	}
+	seed := uint64(1)
+	if v := q.Get("seed"); v != "" {
+		var err error
+		if seed, err = strconv.ParseUint(v, 10, 64); err != nil {
+			writeError(w, r, CodeBadRequest, "Invalid seed")
+			return
+		}
+	}
+
+	raw := &store.GraphDataRaw{}
+	if v := q.Get("graph_id"); v != "" {
+		graphID, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, r, CodeBadRequest, "Invalid graph ID")
+			return
+		}
+		if _, err := s.store.GetGraphInfo(graphID); err != nil {
+			writeError(w, r, CodeNotFound, "Graph not found")
+			return
+		}
+		if raw, err = s.store.GetGraphDataRaw(graphID); err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch graph")
+			return
+		}
+	} else {
+		var err error
+		if raw.Nodes, err = s.store.GetAllNodes(); err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch nodes")
+			return
+		}
+		if raw.Edges, err = s.store.GetAllEdges(); err != nil {
+			writeError(w, r, CodeInternal, "Failed to fetch edges")
+			return
+		}
+	}
+	raw.Nodes = s.visibleNodes(r, raw.Nodes)
+
+	graph := applyFilterAndGroups(raw)
+	writeJSON(w, http.StatusOK, graphSample{
+		Strategy:   strategy,
+		Seed:       seed,
+		TotalNodes: len(graph.Nodes),
+		TotalEdges: len(graph.Edges),
+		Graph:      sampleGraph(graph, n, strategy, seed),
+	})
+}
+
+// sampleGraph returns n of g's nodes, chosen by strategy, and the edges
+// between them. Nodes keep their order in g. Random choices come from seed
+// alone, with nodes and neighbors taken in ID order, so they do not depend
+// on the order g lists them in.
+func sampleGraph(g *models.Graph, n int, strategy string, seed uint64) models.Graph {
+	if n >= len(g.Nodes) {
+		return *g
+	}
+	ids := make([]string, len(g.Nodes))
+	for i, nd := range g.Nodes {
+		ids[i] = nd.ID
+	}
+	slices.Sort(ids)
+	neighbors := make(map[string][]string, len(ids))
+	for _, e := range g.Edges {
+		if e.Source != e.Target {
+			neighbors[e.Source] = append(neighbors[e.Source], e.Target)
+			neighbors[e.Target] = append(neighbors[e.Target], e.Source)
+		}
+	}
+	for id, ns := range neighbors {
+		slices.Sort(ns)
+		neighbors[id] = slices.Compact(ns)
+	}
+
+	rng := rand.New(rand.NewPCG(seed, 0))
+	var chosen []string
+	switch strategy {
+	case sampleDegree:
+		chosen = slices.Clone(ids)
+		slices.SortStableFunc(chosen, func(a, b string) int {
+			return cmp.Compare(len(neighbors[b]), len(neighbors[a]))
+		})
+		chosen = chosen[:n]
+	case sampleRandom:
+		for _, i := range rng.Perm(len(ids))[:n] {
+			chosen = append(chosen, ids[i])
+		}
+	case sampleForestFire:
+		chosen = forestFire(ids, neighbors, n, rng)
+	}
+
+	keep := make(map[string]bool, n)
+	for _, id := range chosen {
+		keep[id] = true
+	}
+	out := models.Graph{Nodes: make([]models.Node, 0, n), Edges: []models.Edge{}}
+	for _, nd := range g.Nodes {
+		if keep[nd.ID] {
+			out.Nodes = append(out.Nodes, nd)
+		}
+	}
+	for _, e := range g.Edges {
+		if keep[e.Source] && keep[e.Target] {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	return out
+}
+
+// forestFire samples n of ids by forest fire (Leskovec and Faloutsos, 2006):
+// a fire starts at a random node and spreads to a geometrically distributed
+// number of its unburned neighbors, and on from each of those, until it dies
+// out; fires start again at random unburned nodes until n have burned. n must
+// be less than len(ids).
+func forestFire(ids []string, neighbors map[string][]string, n int, rng *rand.Rand) []string {
+	burned := make(map[string]bool, n)
+	var order []string
+	burn := func(id string) {
+		burned[id] = true
+		order = append(order, id)
+	}
+	starts := rng.Perm(len(ids))
+	for _, start := range starts {
+		if len(order) == n {
+			break
+		}
+		if burned[ids[start]] {
+			continue
+		}
+		burn(ids[start])
+		for queue := []string{ids[start]}; len(queue) > 0 && len(order) < n; {
+			id := queue[0]
+			queue = queue[1:]
+			var unburned []string
+			for _, nb := range neighbors[id] {
+				if !burned[nb] {
+					unburned = append(unburned, nb)
+				}
+			}
+			spread := 0
+			for rng.Float64() < forestFireForward {
+				spread++
+			}
+			rng.Shuffle(len(unburned), func(i, j int) { unburned[i], unburned[j] = unburned[j], unburned[i] })
+			for _, nb := range unburned[:min(spread, len(unburned), n-len(order))] {
+				burn(nb)
+				queue = append(queue, nb)
+			}
+		}
+	}
+	return order
+}
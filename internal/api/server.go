@@ -9,15 +9,23 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/ali01/mnemosyne/internal/access"
 	"github.com/ali01/mnemosyne/internal/indexer"
+	"github.com/ali01/mnemosyne/internal/layout"
+	"github.com/ali01/mnemosyne/internal/models"
 	"github.com/ali01/mnemosyne/internal/positionsync"
 	"github.com/ali01/mnemosyne/internal/store"
 )
 
-// sseEvent carries typed event data to SSE clients.
+// sseEvent carries typed event data to SSE and live position clients.
 type sseEvent struct {
-	Type     string `json:"type"`               // "graph-updated" or "graphs-changed"
-	GraphIDs []int  `json:"graphIds,omitempty"`
+	Type      string         `json:"type"` // "graph-updated", "graphs-changed", "positions-updated" or "positions-moving"
+	GraphIDs  []int          `json:"graphIds,omitempty"`
+	User      string         `json:"user,omitempty"` // Who moved the positions; empty when anonymous
+	Positions []livePosition `json:"positions,omitempty"`
+
+	origin chan sseEvent                // Subscriber that caused the event; it is not sent back
+	nodes  map[string]*models.VaultNode // Nodes of Positions, for per-subscriber visibility
 }
 
 // Server is the HTTP server for Mnemosyne.
@@ -25,9 +33,18 @@ type Server struct {
 	store        *store.Store
 	indexer      *indexer.IndexManager
 	positionSync *positionsync.Syncer
+	layouts      *layout.Runner
 	homeGraph    string
+	trackViews   bool
+	schema       map[string]models.MetadataType
+	auth         *access.Authenticator
+	policy       *access.Policy
 	mux          *http.ServeMux
+	middlewares  []Middleware
 	port         int
+	maxBodySize  int64
+	maxCoord     float64
+	profiles     map[string][]pruneStep
 
 	sseClients   map[chan sseEvent]struct{}
 	sseClientsMu sync.Mutex
@@ -40,29 +57,124 @@ func NewServer(s *store.Store, idx *indexer.IndexManager, ps *positionsync.Synce
 		indexer:      idx,
 		positionSync: ps,
 		homeGraph:    homeGraph,
+		policy:       &access.Policy{},
 		mux:          http.NewServeMux(),
 		port:         port,
+		maxBodySize:  defaultMaxBodySize,
+		maxCoord:     defaultMaxCoordinate,
 		sseClients:   make(map[chan sseEvent]struct{}),
 	}
 
 	// API routes
 	srv.mux.HandleFunc("GET /api/v1/events", srv.handleSSE)
 	srv.mux.HandleFunc("GET /api/v1/health", srv.handleHealth)
+	srv.mux.HandleFunc("GET /api/v1/errors", srv.handleListErrorCodes)
 
 	// Graph listing and data
 	srv.mux.HandleFunc("GET /api/v1/graphs", srv.handleListGraphs)
 	srv.mux.HandleFunc("GET /api/v1/graphs/{id}", srv.handleGetGraphData)
 	srv.mux.HandleFunc("GET /api/v1/graphs/{id}/search", srv.handleSearchInGraph)
+	srv.mux.HandleFunc("GET /api/v1/graphs/{id}/widget", srv.handleGetGraphWidget)
+	srv.mux.HandleFunc("GET /api/v1/graphs/{id}/reciprocity", srv.handleGraphReciprocity)
 
 	// Graph-scoped positions
-	srv.mux.HandleFunc("PUT /api/v1/graphs/{id}/positions", srv.handleUpdateGraphPositions)
-	srv.mux.HandleFunc("PUT /api/v1/graphs/{id}/positions/{nodeId}", srv.handleUpdateGraphPosition)
+	srv.mux.HandleFunc("PUT /api/v1/graphs/{id}/positions", srv.requireUser(srv.handleUpdateGraphPositions))
+	srv.mux.HandleFunc("PUT /api/v1/graphs/{id}/positions/{nodeId}", srv.requireUser(srv.handleUpdateGraphPosition))
+	srv.mux.HandleFunc("PUT /api/v1/graphs/{id}/positions/{nodeId}/pin", srv.requireUser(srv.handlePinNode))
+	srv.mux.HandleFunc("DELETE /api/v1/graphs/{id}/positions/{nodeId}/pin", srv.requireUser(srv.handlePinNode))
+	srv.mux.HandleFunc("PUT /api/v1/graphs/{id}/pins", srv.requireUser(srv.handlePinNodes))
+	srv.mux.HandleFunc("GET /api/v1/graphs/{id}/live", srv.handleLivePositions)
+	srv.mux.HandleFunc("POST /api/v1/positions/remap", srv.requireUser(srv.handleRemapPositions))
+	srv.mux.HandleFunc("GET /api/v1/positions/export", srv.requireUser(srv.handleExportPositions))
+	srv.mux.HandleFunc("PUT /api/v1/positions/export", srv.requireUser(srv.handleImportPositions))
+
+	// Server-side layouts
+	srv.mux.HandleFunc("POST /api/v1/layouts/compute", srv.requireUser(srv.handleComputeLayout))
+	srv.mux.HandleFunc("GET /api/v1/layouts/jobs/{id}", srv.handleGetLayoutJob)
 
 	// Node metadata (not graph-scoped)
+	srv.mux.HandleFunc("GET /api/v1/nodes", srv.handleListNodes)
 	srv.mux.HandleFunc("GET /api/v1/nodes/{id}", srv.handleGetNode)
+	srv.mux.HandleFunc("GET /api/v1/nodes/{id}/outline", srv.handleGetNodeOutline)
+	srv.mux.HandleFunc("GET /api/v1/nodes/{id}/search", srv.handleSearchNode)
+	srv.mux.HandleFunc("GET /api/v1/nodes/{id}/neighbors", srv.handleGetNeighbors)
+	srv.mux.HandleFunc("GET /api/v1/nodes/{id}/metadata", srv.handleGetNodeMetadata)
+	srv.mux.HandleFunc("GET /api/v1/nodes/{id}/acl", srv.handleGetNodeACL)
+	srv.mux.HandleFunc("PUT /api/v1/nodes/{id}/acl", srv.requireAdmin(srv.handleSetNodeACL))
+	srv.mux.HandleFunc("GET /api/v1/nodes/{id}/comments", srv.handleListComments)
+	srv.mux.HandleFunc("POST /api/v1/nodes/{id}/comments", srv.requireUser(srv.handleAddComment))
+	srv.mux.HandleFunc("GET /api/v1/nodes/{id}/blame", srv.handleNodeBlame)
+
+	// Edge lookup
+	srv.mux.HandleFunc("POST /api/v1/edges/batch", srv.handleBatchEdges)
+
+	// Per-user starred nodes
+	srv.mux.HandleFunc("GET /api/v1/bookmarks", srv.requireUser(srv.handleListBookmarks))
+	srv.mux.HandleFunc("PUT /api/v1/bookmarks/{nodeId}", srv.requireUser(srv.handleAddBookmark))
+	srv.mux.HandleFunc("DELETE /api/v1/bookmarks/{nodeId}", srv.requireUser(srv.handleDeleteBookmark))
+
+	// Note view analytics
+	srv.mux.HandleFunc("GET /api/v1/analytics/most-viewed", srv.requireUser(srv.handleMostViewed))
+	srv.mux.HandleFunc("GET /api/v1/analytics/recently-viewed", srv.requireUser(srv.handleRecentlyViewed))
+
+	// Frontmatter key discovery
+	srv.mux.HandleFunc("GET /api/v1/metadata/keys", srv.handleListMetadataKeys)
+
+	// Activity heatmap
+	srv.mux.HandleFunc("GET /api/v1/graph/activity", srv.handleGraphActivity)
+
+	// Folders as super-nodes
+	srv.mux.HandleFunc("GET /api/v1/graph/groups", srv.handleGraphGroups)
+
+	// Representative subgraphs for previews
+	srv.mux.HandleFunc("GET /api/v1/graph/sample", srv.handleGraphSample)
+
+	// Graph files for external tools
+	srv.mux.HandleFunc("GET /api/v1/graph/export", srv.handleGraphExport)
+
+	// Time travel: a vault's graph at past git commits, and diffs between them
+	srv.mux.HandleFunc("GET /api/v1/graph", srv.handleGraphAtCommit)
+	srv.mux.HandleFunc("GET /api/v1/graph/diff", srv.handleGraphDiff)
+
+	// Incremental updates since a graph version
+	srv.mux.HandleFunc("GET /api/v1/graph/delta", srv.handleGraphDelta)
+
+	// Daily notes calendar
+	srv.mux.HandleFunc("GET /api/v1/calendar", srv.handleCalendar)
+
+	// Shared subgraph snapshots
+	srv.mux.HandleFunc("POST /api/v1/shares", srv.requireUser(srv.handleCreateShare))
+	srv.mux.HandleFunc("GET /api/v1/shares/{token}", srv.handleGetShare)
+	srv.mux.HandleFunc("DELETE /api/v1/shares/{token}", srv.requireUser(srv.handleDeleteShare))
+
+	// Atom feed of public notes
+	srv.mux.HandleFunc("GET /feed.xml", srv.handleFeed)
 
 	// Reindex
-	srv.mux.HandleFunc("POST /api/v1/reindex", srv.handleReindex)
+	srv.mux.HandleFunc("POST /api/v1/reindex", srv.requireUser(srv.handleReindex))
+	srv.mux.HandleFunc("GET /api/v1/vault/parse-status", srv.handleParseStatus)
+	srv.mux.HandleFunc("GET /api/v1/vault/parses", srv.handleListParses)
+	srv.mux.HandleFunc("GET /api/v1/vault/parses/metrics", srv.handleParseMetrics)
+	srv.mux.HandleFunc("GET /api/v1/vault/parses/{id}", srv.handleGetParse)
+	srv.mux.HandleFunc("GET /api/v1/vault/parses/{id}/report", srv.requireUser(srv.handleGetParseReport))
+	srv.mux.HandleFunc("GET /api/v1/vault/parses/{id}/errors", srv.requireUser(srv.handleGetParseErrors))
+	srv.mux.HandleFunc("GET /api/v1/vault/parses/{id}/logs", srv.requireUser(srv.handleGetParseLogs))
+	srv.mux.HandleFunc("GET /api/v1/vault/contributors", srv.handleVaultContributors)
+	srv.mux.HandleFunc("GET /api/v1/vault/external-links", srv.handleExternalLinks)
+	srv.mux.HandleFunc("GET /api/v1/vault/broken-links", srv.handleBrokenLinks)
+	srv.mux.HandleFunc("GET /api/v1/vault/unlinked-mentions", srv.handleUnlinkedMentions)
+	srv.mux.HandleFunc("GET /api/v1/vault/duplicates", srv.handleNearDuplicates)
+	srv.mux.HandleFunc("GET /api/v1/vault/storage", srv.requireUser(srv.handleVaultStorage))
+	srv.mux.HandleFunc("GET /api/v1/vault/health", srv.requireUser(srv.handleVaultHealth))
+
+	// Admin
+	srv.mux.HandleFunc("POST /api/v1/admin/cache/flush", srv.requireUser(srv.handleFlushCaches))
+	srv.mux.HandleFunc("POST /api/v1/admin/parse-lock/release", srv.requireUser(srv.handleReleaseParseLock))
+	srv.mux.HandleFunc("POST /api/v1/admin/recompute-metrics", srv.requireUser(srv.handleRecomputeMetrics))
+	srv.mux.HandleFunc("POST /api/v1/admin/metrics/recompute", srv.requireUser(srv.handleRecomputeMetrics)) // Former path
+	srv.mux.HandleFunc("POST /api/v1/admin/reclassify", srv.requireUser(srv.handleReclassify))
+	srv.mux.HandleFunc("POST /api/v1/admin/vacuum", srv.requireUser(srv.handleVacuum))
+	srv.mux.HandleFunc("POST /api/v1/admin/positions/purge", srv.requireUser(srv.handlePurgePositions))
 
 	// Static files with SPA fallback
 	if staticFS != nil {
@@ -74,7 +186,13 @@ func NewServer(s *store.Store, idx *indexer.IndexManager, ps *positionsync.Synce
 
 // Handler returns the http.Handler.
 func (s *Server) Handler() http.Handler {
-	return corsMiddleware(s.mux)
+	return withRequestID(corsMiddleware(s.withGraphVersion(s.authenticate(chain(s.mux, s.middlewares)))))
+}
+
+// SetMetadataSchema sets the declared frontmatter field types used by the
+// typed metadata endpoint.
+func (s *Server) SetMetadataSchema(schema map[string]models.MetadataType) {
+	s.schema = schema
 }
 
 // NotifyChange broadcasts a graph-updated event to all SSE clients.
@@ -91,6 +209,9 @@ func (s *Server) broadcast(evt sseEvent) {
 	s.sseClientsMu.Lock()
 	defer s.sseClientsMu.Unlock()
 	for ch := range s.sseClients {
+		if ch == evt.origin {
+			continue
+		}
 		select {
 		case ch <- evt:
 		default:
@@ -98,11 +219,27 @@ func (s *Server) broadcast(evt sseEvent) {
 	}
 }
 
+// subscribe registers a channel for broadcast events. Events that arrive while
+// its buffer is full are dropped.
+func (s *Server) subscribe(buffer int) chan sseEvent {
+	ch := make(chan sseEvent, buffer)
+	s.sseClientsMu.Lock()
+	s.sseClients[ch] = struct{}{}
+	s.sseClientsMu.Unlock()
+	return ch
+}
+
+func (s *Server) unsubscribe(ch chan sseEvent) {
+	s.sseClientsMu.Lock()
+	delete(s.sseClients, ch)
+	s.sseClientsMu.Unlock()
+}
+
 // handleSSE streams server-sent events to the client.
 func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
-		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		writeError(w, r, CodeInternal, "Streaming not supported")
 		return
 	}
 
@@ -110,16 +247,9 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
-	ch := make(chan sseEvent, 1)
-	s.sseClientsMu.Lock()
-	s.sseClients[ch] = struct{}{}
-	s.sseClientsMu.Unlock()
-
-	defer func() {
-		s.sseClientsMu.Lock()
-		delete(s.sseClients, ch)
-		s.sseClientsMu.Unlock()
-	}()
+	ch := s.subscribe(1)
+	defer s.unsubscribe(ch)
+	user := access.UserFromContext(r.Context())
 
 	fmt.Fprintf(w, "event: connected\ndata: ok\n\n")
 	flusher.Flush()
@@ -129,6 +259,10 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 		case <-r.Context().Done():
 			return
 		case evt := <-ch:
+			evt, ok := s.visibleEvent(user, evt)
+			if !ok {
+				continue
+			}
 			data, _ := json.Marshal(evt)
 			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Type, data)
 			flusher.Flush()
@@ -162,7 +296,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-Graph-Version")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/access"
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/store"
+)
+
+// maxShareDepth bounds neighborhood expansion around a share's center node.
+const maxShareDepth = 5
+
+// shareRequest selects the subgraph to snapshot. All given criteria must match.
+type shareRequest struct {
+	GraphID int    `json:"graph_id"`
+	Title   string `json:"title,omitempty"`
+	Tag     string `json:"tag,omitempty"`     // Only nodes with this tag
+	Type    string `json:"type,omitempty"`    // Only nodes of this node type
+	NodeID  string `json:"node_id,omitempty"` // Only nodes within Depth links of this node
+	Depth   int    `json:"depth,omitempty"`   // Defaults to 1
+}
+
+// handleCreateShare snapshots a subgraph, as seen by the requesting user, into
+// a read-only view served at /api/v1/shares/{token}.
+func (s *Server) handleCreateShare(w http.ResponseWriter, r *http.Request) {
+	var req shareRequest
+	if !s.readJSON(w, r, &req) {
+		return
+	}
+	if req.Depth == 0 {
+		req.Depth = 1
+	}
+	if req.Depth < 0 || req.Depth > maxShareDepth {
+		writeError(w, r, CodeBadRequest, "Depth must be between 1 and 5")
+		return
+	}
+
+	raw, err := s.store.GetGraphDataRaw(req.GraphID)
+	if err != nil {
+		writeError(w, r, CodeNotFound, "Graph not found")
+		return
+	}
+	raw.Nodes = s.visibleNodes(r, raw.Nodes)
+	if req.NodeID != "" && !slices.ContainsFunc(raw.Nodes, func(n models.VaultNode) bool { return n.ID == req.NodeID }) {
+		writeError(w, r, CodeNotFound, "Node not found")
+		return
+	}
+	raw.Nodes = selectShareNodes(raw, req)
+
+	token, err := newShareToken()
+	if err != nil {
+		log.Printf("Failed to generate share token: %v", err)
+		writeError(w, r, CodeInternal, "Failed to create share")
+		return
+	}
+
+	share := &models.Share{
+		Token:     token,
+		Title:     req.Title,
+		GraphID:   req.GraphID,
+		Graph:     *applyFilterAndGroups(raw),
+		CreatedAt: time.Now(),
+	}
+	if u := access.UserFromContext(r.Context()); u != nil {
+		share.CreatedBy = u.Name
+	}
+	if err := s.store.CreateShare(share); err != nil {
+		log.Printf("Failed to store share: %v", err)
+		writeError(w, r, CodeInternal, "Failed to create share")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"token":      token,
+		"url":        "/api/v1/shares/" + token,
+		"node_count": len(share.Graph.Nodes),
+		"edge_count": len(share.Graph.Edges),
+	})
+}
+
+// handleGetShare serves a share's snapshot. Shares are readable by anyone with the token.
+func (s *Server) handleGetShare(w http.ResponseWriter, r *http.Request) {
+	share, err := s.store.GetShare(r.PathValue("token"))
+	if err != nil {
+		writeError(w, r, CodeNotFound, "Share not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, share)
+}
+
+// handleDeleteShare revokes a share. When auth is configured only the share's
+// creator or an admin may revoke it.
+func (s *Server) handleDeleteShare(w http.ResponseWriter, r *http.Request) {
+	share, err := s.store.GetShare(r.PathValue("token"))
+	if err != nil {
+		writeError(w, r, CodeNotFound, "Share not found")
+		return
+	}
+	if s.auth != nil {
+		u := access.UserFromContext(r.Context())
+		if u.Name != share.CreatedBy && !s.policy.IsAdmin(u) {
+			writeError(w, r, CodeForbidden, "Only the share's creator or an admin may revoke it")
+			return
+		}
+	}
+	if err := s.store.DeleteShare(share.Token); err != nil {
+		writeError(w, r, CodeInternal, "Failed to delete share")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// selectShareNodes returns the nodes of raw matching every criterion in req.
+func selectShareNodes(raw *store.GraphDataRaw, req shareRequest) []models.VaultNode {
+	var near map[string]bool
+	if req.NodeID != "" {
+		near = neighborhood(raw, req.NodeID, req.Depth)
+	}
+
+	out := raw.Nodes[:0:0]
+	for _, n := range raw.Nodes {
+		if req.Tag != "" && !slices.Contains(n.Tags, req.Tag) {
+			continue
+		}
+		if req.Type != "" && n.NodeType != req.Type {
+			continue
+		}
+		if near != nil && !near[n.ID] {
+			continue
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// neighborhood returns the IDs of nodes within depth links of center,
+// following edges in either direction between nodes present in raw.
+func neighborhood(raw *store.GraphDataRaw, center string, depth int) map[string]bool {
+	present := make(map[string]bool, len(raw.Nodes))
+	for _, n := range raw.Nodes {
+		present[n.ID] = true
+	}
+	adj := make(map[string][]string)
+	for _, e := range raw.Edges {
+		if present[e.SourceID] && present[e.TargetID] {
+			adj[e.SourceID] = append(adj[e.SourceID], e.TargetID)
+			adj[e.TargetID] = append(adj[e.TargetID], e.SourceID)
+		}
+	}
+
+	seen := map[string]bool{center: true}
+	frontier := []string{center}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []string
+		for _, id := range frontier {
+			for _, nb := range adj[id] {
+				if !seen[nb] {
+					seen[nb] = true
+					next = append(next, nb)
+				}
+			}
+		}
+		frontier = next
+	}
+	return seen
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// Defaults matching the frontend's graph rendering.
+const (
+	widgetDefaultColor = "#7b8cff"
+	widgetBaseSize     = 3.0
+)
+
+// widgetGraph is a self-contained graph payload for third-party embeds.
+// Everything needed to draw the graph is precomputed: clients plot each node
+// at (x, y) with its size and color and draw a line for each edge.
+type widgetGraph struct {
+	GraphID int          `json:"graph_id"`
+	Name    string       `json:"name"`
+	Bounds  widgetBounds `json:"bounds"`
+	Nodes   []widgetNode `json:"nodes"`
+	Edges   [][2]int     `json:"edges"` // Pairs of indexes into Nodes
+}
+
+type widgetBounds struct {
+	MinX float64 `json:"min_x"`
+	MinY float64 `json:"min_y"`
+	MaxX float64 `json:"max_x"`
+	MaxY float64 `json:"max_y"`
+}
+
+type widgetNode struct {
+	ID    string  `json:"id"`
+	Label string  `json:"label"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Size  float64 `json:"size"`
+	Color string  `json:"color"`
+}
+
+// handleGetGraphWidget serves a graph in the compact widget format. Filters,
+// group colors, and visibility rules apply as for the regular graph endpoint.
+func (s *Server) handleGetGraphWidget(w http.ResponseWriter, r *http.Request) {
+	graphID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, CodeBadRequest, "Invalid graph ID")
+		return
+	}
+
+	info, err := s.store.GetGraphInfo(graphID)
+	if err != nil {
+		writeError(w, r, CodeNotFound, "Graph not found")
+		return
+	}
+	raw, err := s.store.GetGraphDataRaw(graphID)
+	if err != nil {
+		writeError(w, r, CodeInternal, "Failed to fetch graph")
+		return
+	}
+	raw.Nodes = s.visibleNodes(r, raw.Nodes)
+	graph := applyFilterAndGroups(raw)
+
+	index := make(map[string]int, len(graph.Nodes))
+	for i, n := range graph.Nodes {
+		index[n.ID] = i
+	}
+	degree := make([]int, len(graph.Nodes))
+	edges := make([][2]int, 0, len(graph.Edges))
+	for _, e := range graph.Edges {
+		src, dst := index[e.Source], index[e.Target]
+		degree[src]++
+		degree[dst]++
+		edges = append(edges, [2]int{src, dst})
+	}
+
+	out := widgetGraph{
+		GraphID: graphID,
+		Name:    info.Name,
+		Nodes:   make([]widgetNode, 0, len(graph.Nodes)),
+		Edges:   edges,
+	}
+	for i, n := range graph.Nodes {
+		x, y := n.Position.X, n.Position.Y
+		if x == 0 && y == 0 {
+			x, y = spiralPosition(i)
+		}
+		color := n.Color
+		if color == "" {
+			color = widgetDefaultColor
+		}
+		out.Nodes = append(out.Nodes, widgetNode{
+			ID:    n.ID,
+			Label: n.Title,
+			X:     round2(x),
+			Y:     round2(y),
+			Size:  round2(widgetBaseSize + math.Sqrt(float64(degree[i]))),
+			Color: color,
+		})
+	}
+	out.Bounds = nodeBounds(out.Nodes)
+
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	writeJSON(w, http.StatusOK, out)
+}
+
+// spiralPosition places unpositioned nodes on a golden-angle spiral so the
+// widget never needs to run a layout.
+func spiralPosition(i int) (float64, float64) {
+	const goldenAngle = 2.399963229728653 // pi * (3 - sqrt(5))
+	r := 10 * math.Sqrt(float64(i+1))
+	theta := float64(i) * goldenAngle
+	return r * math.Cos(theta), r * math.Sin(theta)
+}
+
+func nodeBounds(nodes []widgetNode) widgetBounds {
+	if len(nodes) == 0 {
+		return widgetBounds{}
+	}
+	b := widgetBounds{MinX: nodes[0].X, MinY: nodes[0].Y, MaxX: nodes[0].X, MaxY: nodes[0].Y}
+	for _, n := range nodes[1:] {
+		b.MinX = min(b.MinX, n.X)
+		b.MinY = min(b.MinY, n.Y)
+		b.MaxX = max(b.MaxX, n.X)
+		b.MaxY = max(b.MaxY, n.Y)
+	}
+	return b
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -2,18 +2,312 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/ali01/mnemosyne/internal/expr"
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/search"
 )
 
 // Config holds all application configuration.
 type Config struct {
+	// Profile is the profile merged over the file, if any; see LoadProfile.
+	Profile string `yaml:"-"`
+
 	Port      int      `yaml:"port"`
 	Vaults    []string `yaml:"vaults"`
 	HomeGraph string   `yaml:"home-graph,omitempty"` // e.g. "walros/memex"
+
+	// MetadataSchema declares types for frontmatter fields, e.g. {"due": "date"}.
+	MetadataSchema map[string]models.MetadataType `yaml:"metadata-schema,omitempty"`
+
+	// ComputedFields maps metadata field names to expressions evaluated at
+	// parse time, e.g. {"maturity": `metadata.reviews >= 3 ? "evergreen" : "seedling"`}.
+	ComputedFields map[string]string `yaml:"computed-fields,omitempty"`
+
+	// Scripts are Lua files run against every note at index time for
+	// classification and metadata enrichment.
+	Scripts []string `yaml:"scripts,omitempty"`
+
+	// LinkExtractors define regex-based link syntaxes that produce typed edges.
+	LinkExtractors []LinkExtractorConfig `yaml:"link-extractors,omitempty"`
+
+	// Mentions, when present, turns mentions of people into person nodes
+	// and "mention" edges.
+	Mentions *MentionsConfig `yaml:"mentions,omitempty"`
+
+	// Parser selects the markdown dialect and overrides its behaviors.
+	Parser ParserConfig `yaml:"parser,omitempty"`
+
+	// IDRules derive node IDs from filenames when frontmatter has no 'id'.
+	IDRules []IDRuleConfig `yaml:"id-rules,omitempty"`
+
+	// Slugs, when present, configures the slugs generated from titles, used
+	// to resolve links and optionally as node IDs.
+	Slugs *SlugsConfig `yaml:"slugs,omitempty"`
+
+	// Classify gives notes node types by path and tag. A directory's
+	// .mnemosyne.yaml can add rules of its own, tried first for the notes
+	// under it.
+	Classify []ClassifyRuleConfig `yaml:"classify,omitempty"`
+
+	// Ignore lists patterns of vault files and directories not to index,
+	// e.g. "*.excalidraw.md" or "/archive", along with those of the
+	// directories' .mnemosyne.yaml files.
+	Ignore []string `yaml:"ignore,omitempty"`
+
+	// Redactions rewrite note content at parse time, before it is stored or
+	// served, e.g. to hide secrets or private blocks.
+	Redactions []RedactionConfig `yaml:"redactions,omitempty"`
+
+	// Auth lists API users. When empty, all requests are anonymous.
+	Auth AuthConfig `yaml:"auth,omitempty"`
+
+	// PublishFlag names a frontmatter field (e.g. "publish"). When set,
+	// anonymous requests only see notes that set it to true.
+	PublishFlag string `yaml:"publish-flag,omitempty"`
+
+	// ACLField names a frontmatter field (e.g. "access") listing the users and
+	// roles allowed to see a note. Notes that set it are hidden from everyone else.
+	ACLField string `yaml:"acl-field,omitempty"`
+
+	// TrackViews records each note content view for the view analytics
+	// endpoints. Off by default.
+	TrackViews bool `yaml:"track-views,omitempty"`
+
+	// MaxBodyMB limits the size of API request bodies; larger requests are
+	// rejected with 413. Zero uses the 10 MB default.
+	MaxBodyMB int `yaml:"max-body-mb,omitempty"`
+
+	// MaxCoordinate bounds saved node positions on each axis; positions
+	// further from the origin are rejected with 422. Zero uses the default
+	// of one million.
+	MaxCoordinate float64 `yaml:"max-coordinate,omitempty"`
+
+	// Graph configures which vault files make it into the graph, and how.
+	Graph GraphConfig `yaml:"graph,omitempty"`
+
+	// EdgeWeights configures post-processing of edge weights, which the
+	// frontend draws as edge thickness.
+	EdgeWeights EdgeWeightsConfig `yaml:"edge-weights,omitempty"`
+
+	// PruningProfiles are named sets of node classes to leave out of a graph,
+	// selected with ?profile= on the graph endpoint.
+	PruningProfiles map[string]PruningProfileConfig `yaml:"pruning-profiles,omitempty"`
+
+	// Git configures syncing of vaults kept in git repositories.
+	Git GitConfig `yaml:"git,omitempty"`
+
+	// LinkCheck configures checking of the external URLs in notes.
+	LinkCheck LinkCheckConfig `yaml:"link-check,omitempty"`
+
+	// Duplicates configures detection of near-duplicate notes.
+	Duplicates DuplicatesConfig `yaml:"duplicates,omitempty"`
+
+	// Notifications, when present, emails vault owners when a full index
+	// fails or leaves too many broken links.
+	Notifications *NotificationsConfig `yaml:"notifications,omitempty"`
+}
+
+// NotificationsConfig configures email notifications about full indexes.
+type NotificationsConfig struct {
+	SMTP SMTPConfig `yaml:"smtp"`
+
+	// To receives notifications about vaults without an owner.
+	To []string `yaml:"to,omitempty"`
+
+	// Owners maps vault paths to the addresses notified about them instead
+	// of To.
+	Owners map[string][]string `yaml:"owners,omitempty"`
+
+	// BrokenLinkThreshold, when positive, notifies owners of completed
+	// indexes that leave more unresolved links than this.
+	BrokenLinkThreshold int `yaml:"broken-link-threshold,omitempty"`
+
+	// Throttle, e.g. "6h", is the least time between two notifications of
+	// the same kind about a vault. Zero uses the default of one hour.
+	Throttle time.Duration `yaml:"throttle,omitempty"`
+}
+
+// SMTPConfig is the mail server notifications are sent through.
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port,omitempty"` // Defaults to 587
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	From     string `yaml:"from"`
+
+	// PasswordFile names a file to read the password from instead.
+	PasswordFile string `yaml:"password-file,omitempty"`
+}
+
+// GraphConfig configures how vault files become nodes.
+type GraphConfig struct {
+	// MaxFileSizeMB, when positive, limits the notes parsed in full. Larger
+	// files, e.g. pasted logs, become nodes from their frontmatter only,
+	// without content, and are flagged in the parse report.
+	MaxFileSizeMB int `yaml:"max-file-size-mb,omitempty"`
+
+	// HubTypes are the node types of the notes at the top of the graph, at
+	// level 1; other notes' levels count the links from the nearest. Unset
+	// means index and hub.
+	HubTypes []string `yaml:"hub-types,omitempty"`
+
+	// UnlinkedMentions finds, at index time, notes named in other notes'
+	// text without a link, for review at /api/v1/vault/unlinked-mentions.
+	UnlinkedMentions bool `yaml:"unlinked-mentions,omitempty"`
+
+	// PositionRetention, when positive, deletes a node's saved positions once
+	// that many full indexes of its vault in a row have not found it. Positions
+	// outlive their nodes so notes moved back keep their places; zero keeps
+	// them until purged at /api/v1/admin/positions/purge.
+	PositionRetention int `yaml:"position-retention,omitempty"`
+}
+
+// EdgeWeightsConfig configures post-processing of edge weights at index time.
+type EdgeWeightsConfig struct {
+	// DecayHalfLife, e.g. "4380h", halves a link's weight for each half-life
+	// its note has gone unmodified. Zero disables decay.
+	DecayHalfLife time.Duration `yaml:"decay-half-life,omitempty"`
+
+	// Normalize scales weights so the heaviest edge weighs 1.
+	Normalize bool `yaml:"normalize,omitempty"`
+}
+
+// PruningProfileConfig lists the node classes a pruning profile leaves out.
+// A profile excluding nothing, e.g. "full: {}", shows the whole graph.
+type PruningProfileConfig struct {
+	ExcludeDaily       bool     `yaml:"exclude-daily,omitempty"`       // Notes named YYYY-MM-DD
+	ExcludeTypes       []string `yaml:"exclude-types,omitempty"`       // Node types, e.g. reference
+	Exclude            string   `yaml:"exclude,omitempty"`             // Search query, e.g. "path:archive"
+	ExcludeAttachments bool     `yaml:"exclude-attachments,omitempty"` // Embeds and notes only ever embedded
+	ExcludeOrphans     bool     `yaml:"exclude-orphans,omitempty"`     // Nodes left without edges
+}
+
+// GitConfig configures syncing of vaults kept in git repositories.
+type GitConfig struct {
+	// PollInterval, e.g. "5m", sets how often vaults are pulled from their
+	// upstream branches and changed notes re-indexed. Zero disables polling.
+	PollInterval time.Duration `yaml:"poll-interval,omitempty"`
+}
+
+// LinkCheckConfig configures checking of the external URLs in notes.
+type LinkCheckConfig struct {
+	// Interval, e.g. "24h", sets how often every URL is requested to find
+	// dead ones, starting at startup. Zero disables link checking.
+	Interval time.Duration `yaml:"interval,omitempty"`
+}
+
+// DuplicatesConfig configures detection of near-duplicate notes.
+type DuplicatesConfig struct {
+	// Interval, e.g. "24h", sets how often all notes are compared, starting
+	// at startup. Zero disables detection.
+	Interval time.Duration `yaml:"interval,omitempty"`
+
+	// Threshold is the least similarity, from 0 to 1, of the word sequences
+	// of two notes reported as near duplicates. Zero means 0.8.
+	Threshold float64 `yaml:"threshold,omitempty"`
+}
+
+// AuthConfig configures bearer-token authentication.
+type AuthConfig struct {
+	Users []UserConfig `yaml:"users,omitempty"`
+
+	// AdminRole names the role whose users may assign node ACLs through the
+	// API. Empty means no user may.
+	AdminRole string `yaml:"admin-role,omitempty"`
+}
+
+// UserConfig is an API user and their bearer token.
+type UserConfig struct {
+	Name      string   `yaml:"name"`
+	Token     string   `yaml:"token"`
+	TokenFile string   `yaml:"token-file,omitempty"` // Read the token from this file instead
+	Roles     []string `yaml:"roles,omitempty"`
+}
+
+// IDRuleConfig maps a filename pattern to an ID, e.g. `^(\d{12})\b` for
+// Zettelkasten names like "202301151230 Title.md".
+type IDRuleConfig struct {
+	Pattern  string `yaml:"pattern"`
+	Template string `yaml:"template,omitempty"` // Expansion template; defaults to the first capture group
+}
+
+// ClassifyRuleConfig gives the notes matching Path and having Tag, each
+// optional, the node type Type.
+type ClassifyRuleConfig struct {
+	Path string `yaml:"path,omitempty"` // Pattern like Ignore's, e.g. "meetings/*.md"
+	Tag  string `yaml:"tag,omitempty"`
+	Type string `yaml:"type"`
+}
+
+// SlugConfig configures slugs generated from titles (see vault.Slugger).
+type SlugConfig struct {
+	Unicode   bool   `yaml:"unicode,omitempty"`    // Keep letters of every script instead of transliterating to ASCII
+	Separator string `yaml:"separator,omitempty"`  // Between words: -, _ or .; defaults to -
+	MaxLength int    `yaml:"max-length,omitempty"` // In characters; defaults to 80
+	IDs       bool   `yaml:"ids,omitempty"`        // Notes without frontmatter id take their filename's slug as ID
+}
+
+// SlugsConfig configures slugs for all vaults, with per-vault overrides.
+type SlugsConfig struct {
+	SlugConfig `yaml:",inline"`
+
+	// Vaults maps vault paths to settings used for them in place of the
+	// defaults above.
+	Vaults map[string]SlugConfig `yaml:"vaults,omitempty"`
+}
+
+// For returns the slug settings of the vault at vaultPath.
+func (c *SlugsConfig) For(vaultPath string) SlugConfig {
+	if sc, ok := c.Vaults[vaultPath]; ok {
+		return sc
+	}
+	return c.SlugConfig
+}
+
+// RedactionConfig replaces every match of a regex in note content.
+type RedactionConfig struct {
+	Pattern     string  `yaml:"pattern"`
+	Replacement *string `yaml:"replacement,omitempty"` // Expansion template; defaults to "[REDACTED]"
+}
+
+// ParserConfig selects markdown parsing behaviors. Unset overrides keep the
+// dialect's defaults.
+type ParserConfig struct {
+	Dialect              string `yaml:"dialect,omitempty"` // obsidian (default), commonmark, gfm
+	Wikilinks            *bool  `yaml:"wikilinks,omitempty"`
+	StripComments        *bool  `yaml:"strip-comments,omitempty"`
+	FrontmatterDelimiter string `yaml:"frontmatter-delimiter,omitempty"`
+	MaxWorkers           int    `yaml:"max-workers,omitempty"`         // Cap on adaptive parse workers; 0 = 4x CPUs
+	MemoryBudgetMB       int    `yaml:"memory-budget-mb,omitempty"`    // Heap limit while parsing; 0 = unlimited
+	ExcerptSentences     int    `yaml:"excerpt-sentences,omitempty"`   // Length of node excerpts; 0 = 2
+	LenientFrontmatter   bool   `yaml:"lenient-frontmatter,omitempty"` // Keep files whose frontmatter is not valid YAML
+	Symlinks             string `yaml:"symlinks,omitempty"`            // follow (default) or skip; never outside the vault
+}
+
+// LinkExtractorConfig defines a custom link syntax, e.g. `@([a-z-]+)` -> "people/$1".
+type LinkExtractorConfig struct {
+	Pattern  string `yaml:"pattern"`
+	EdgeType string `yaml:"edge-type"`
+	Target   string `yaml:"target,omitempty"` // Expansion template; defaults to the first capture group
+}
+
+// MentionsConfig configures mentions of people, e.g. "@jane-doe". A mention
+// links to the person's note in the people directory, or to a person node
+// created for it.
+type MentionsConfig struct {
+	Pattern   string `yaml:"pattern,omitempty"`    // First capture group is the name; defaults to @name
+	PeopleDir string `yaml:"people-dir,omitempty"` // Notes here are people; defaults to "people"
 }
 
 // DefaultConfigPath returns the default config file location.
@@ -30,17 +324,37 @@ func DBPath() string {
 
 // Load reads and parses a config file.
 func Load(path string) (*Config, error) {
+	return LoadProfile(path, "")
+}
+
+// LoadProfile reads and parses a config file with the named profile merged
+// over it, from the file's profiles section and its overlay file (see
+// OverlayPath). An empty profile merges none.
+func LoadProfile(path, profile string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("read config: %w", err)
 	}
 
 	cfg := &Config{
-		Port: 5555,
+		Port:    5555,
+		Profile: profile,
 	}
-	if err := yaml.Unmarshal(data, cfg); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("parse config: %w", err)
 	}
+	if err := applyProfile(&doc, path, profile); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if err := expandEnv(&doc); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if doc.Kind != 0 {
+		if err := doc.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("parse config: %w", err)
+		}
+	}
 
 	if len(cfg.Vaults) == 0 {
 		return nil, fmt.Errorf("at least one vault path is required in 'vaults'")
@@ -49,10 +363,236 @@ func Load(path string) (*Config, error) {
 	for i, v := range cfg.Vaults {
 		cfg.Vaults[i] = expandHome(v)
 	}
+	for i, p := range cfg.Scripts {
+		cfg.Scripts[i] = expandHome(p)
+	}
+
+	for field, typ := range cfg.MetadataSchema {
+		if !typ.IsValid() {
+			return nil, fmt.Errorf("metadata-schema: field %q has unknown type %q", field, typ)
+		}
+	}
+
+	switch cfg.Parser.Dialect {
+	case "", "obsidian", "commonmark", "gfm":
+	default:
+		return nil, fmt.Errorf("parser: unknown dialect %q (want obsidian, commonmark, or gfm)", cfg.Parser.Dialect)
+	}
+
+	switch cfg.Parser.Symlinks {
+	case "", "follow", "skip":
+	default:
+		return nil, fmt.Errorf("parser: unknown symlinks policy %q (want follow or skip)", cfg.Parser.Symlinks)
+	}
+
+	if cfg.Parser.MaxWorkers < 0 {
+		return nil, fmt.Errorf("parser: max-workers must not be negative")
+	}
+	if cfg.Parser.MemoryBudgetMB < 0 {
+		return nil, fmt.Errorf("parser: memory-budget-mb must not be negative")
+	}
+	if cfg.Parser.ExcerptSentences < 0 {
+		return nil, fmt.Errorf("parser: excerpt-sentences must not be negative")
+	}
+	if cfg.Graph.MaxFileSizeMB < 0 {
+		return nil, fmt.Errorf("graph: max-file-size-mb must not be negative")
+	}
+	if cfg.Graph.PositionRetention < 0 {
+		return nil, fmt.Errorf("graph: position-retention must not be negative")
+	}
+	for _, t := range cfg.Graph.HubTypes {
+		if t == "" {
+			return nil, fmt.Errorf("graph: hub-types must not contain empty types")
+		}
+	}
+	if cfg.MaxBodyMB < 0 {
+		return nil, fmt.Errorf("max-body-mb must not be negative")
+	}
+	if cfg.MaxCoordinate < 0 {
+		return nil, fmt.Errorf("max-coordinate must not be negative")
+	}
+	if cfg.EdgeWeights.DecayHalfLife < 0 {
+		return nil, fmt.Errorf("edge-weights: decay-half-life must not be negative")
+	}
+	if cfg.Git.PollInterval < 0 {
+		return nil, fmt.Errorf("git: poll-interval must not be negative")
+	}
+	if cfg.LinkCheck.Interval < 0 {
+		return nil, fmt.Errorf("link-check: interval must not be negative")
+	}
+	if cfg.Duplicates.Interval < 0 {
+		return nil, fmt.Errorf("duplicates: interval must not be negative")
+	}
+	if cfg.Duplicates.Threshold < 0 || cfg.Duplicates.Threshold > 1 {
+		return nil, fmt.Errorf("duplicates: threshold must be between 0 and 1")
+	}
+
+	if n := cfg.Notifications; n != nil {
+		if n.SMTP.Host == "" || n.SMTP.From == "" {
+			return nil, fmt.Errorf("notifications: smtp host and from are required")
+		}
+		if n.SMTP.Port < 0 || n.SMTP.Port > 65535 {
+			return nil, fmt.Errorf("notifications: smtp port %d is out of range", n.SMTP.Port)
+		}
+		if n.BrokenLinkThreshold < 0 {
+			return nil, fmt.Errorf("notifications: broken-link-threshold must not be negative")
+		}
+		if n.Throttle < 0 {
+			return nil, fmt.Errorf("notifications: throttle must not be negative")
+		}
+		if len(n.To) == 0 && len(n.Owners) == 0 {
+			return nil, fmt.Errorf("notifications: to or owners is required")
+		}
+		owners := make(map[string][]string, len(n.Owners))
+		for path, addrs := range n.Owners {
+			owners[expandHome(path)] = addrs
+		}
+		n.Owners = owners
+	}
+
+	for i, le := range cfg.LinkExtractors {
+		if le.EdgeType == "" {
+			return nil, fmt.Errorf("link-extractors[%d]: edge-type is required", i)
+		}
+		if _, err := regexp.Compile(le.Pattern); err != nil {
+			return nil, fmt.Errorf("link-extractors[%d]: %w", i, err)
+		}
+	}
+
+	if cfg.Mentions != nil && cfg.Mentions.Pattern != "" {
+		if _, err := regexp.Compile(cfg.Mentions.Pattern); err != nil {
+			return nil, fmt.Errorf("mentions: %w", err)
+		}
+	}
+
+	if err := cfg.resolveSecrets(); err != nil {
+		return nil, err
+	}
+
+	tokens := make(map[string]bool)
+	for i, u := range cfg.Auth.Users {
+		if u.Name == "" || u.Token == "" {
+			return nil, fmt.Errorf("auth.users[%d]: name and token are required", i)
+		}
+		if tokens[u.Token] {
+			return nil, fmt.Errorf("auth.users[%d]: duplicate token", i)
+		}
+		tokens[u.Token] = true
+	}
+
+	for name, p := range cfg.PruningProfiles {
+		if p.Exclude == "" {
+			continue
+		}
+		if _, err := search.Parse(p.Exclude); err != nil {
+			return nil, fmt.Errorf("pruning-profiles: profile %q: %w", name, err)
+		}
+	}
+
+	for i, r := range cfg.IDRules {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return nil, fmt.Errorf("id-rules[%d]: %w", i, err)
+		}
+	}
+
+	for i, r := range cfg.Classify {
+		if r.Type == "" {
+			return nil, fmt.Errorf("classify[%d]: type is required", i)
+		}
+		if r.Path != "" && !validPattern(r.Path) {
+			return nil, fmt.Errorf("classify[%d]: path pattern %q is not valid", i, r.Path)
+		}
+	}
+	for i, p := range cfg.Ignore {
+		if !validPattern(p) {
+			return nil, fmt.Errorf("ignore[%d]: pattern %q is not valid", i, p)
+		}
+	}
+
+	if sl := cfg.Slugs; sl != nil {
+		if err := sl.SlugConfig.validate("slugs"); err != nil {
+			return nil, err
+		}
+		vaults := make(map[string]SlugConfig, len(sl.Vaults))
+		for path, sc := range sl.Vaults {
+			if err := sc.validate(fmt.Sprintf("slugs: vault %q", path)); err != nil {
+				return nil, err
+			}
+			vaults[expandHome(path)] = sc
+		}
+		sl.Vaults = vaults
+	}
+
+	for i, r := range cfg.Redactions {
+		if r.Pattern == "" {
+			return nil, fmt.Errorf("redactions[%d]: pattern is required", i)
+		}
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return nil, fmt.Errorf("redactions[%d]: %w", i, err)
+		}
+	}
+
+	for field, src := range cfg.ComputedFields {
+		if _, err := expr.Parse(src); err != nil {
+			return nil, fmt.Errorf("computed-fields: field %q: %w", field, err)
+		}
+	}
 
 	return cfg, nil
 }
 
+// validate checks the settings, prefixing errors with section.
+func (c SlugConfig) validate(section string) error {
+	switch c.Separator {
+	case "", "-", "_", ".":
+	default:
+		return fmt.Errorf("%s: separator must be -, _ or .", section)
+	}
+	if c.MaxLength < 0 {
+		return fmt.Errorf("%s: max-length must not be negative", section)
+	}
+	return nil
+}
+
+// CacheKey fingerprints the settings that shape how notes are parsed and
+// classified: the parser dialect, link extractors, mentions, ID rules,
+// slugs, redactions, computed fields, classify rules, ignore patterns, graph
+// settings, and the contents of scripts. Worker and memory limits and
+// position retention are excluded since they do not change results.
+func (c *Config) CacheKey() (string, error) {
+	parser := c.Parser
+	parser.MaxWorkers, parser.MemoryBudgetMB = 0, 0
+	graph := c.Graph
+	graph.PositionRetention = 0
+	data, err := yaml.Marshal(struct {
+		ComputedFields map[string]string     `yaml:"computed-fields"`
+		LinkExtractors []LinkExtractorConfig `yaml:"link-extractors"`
+		Mentions       *MentionsConfig       `yaml:"mentions"`
+		Parser         ParserConfig          `yaml:"parser"`
+		IDRules        []IDRuleConfig        `yaml:"id-rules"`
+		Slugs          *SlugsConfig          `yaml:"slugs"`
+		Redactions     []RedactionConfig     `yaml:"redactions"`
+		Classify       []ClassifyRuleConfig  `yaml:"classify"`
+		Ignore         []string              `yaml:"ignore"`
+		Graph          GraphConfig           `yaml:"graph"`
+	}{c.ComputedFields, c.LinkExtractors, c.Mentions, parser, c.IDRules, c.Slugs, c.Redactions, c.Classify, c.Ignore, graph})
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write(data)
+	for _, path := range c.Scripts {
+		script, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read script: %w", err)
+		}
+		fmt.Fprintf(h, "\x00%s\x00", path)
+		h.Write(script)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // CreateDefault writes a new config file with the given vault path.
 func CreateDefault(cfgPath, vaultPath string) error {
 	if err := os.MkdirAll(filepath.Dir(cfgPath), 0o755); err != nil {
@@ -66,6 +606,12 @@ func CreateDefault(cfgPath, vaultPath string) error {
 	return os.WriteFile(cfgPath, data, 0o644)
 }
 
+// validPattern reports whether p is a valid path.Match pattern.
+func validPattern(p string) bool {
+	_, err := path.Match(p, "")
+	return p != "" && err == nil
+}
+
 // ExpandHome expands a leading ~/ to the user's home directory.
 func ExpandHome(path string) string {
 	return expandHome(path)
@@ -4,9 +4,12 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/models"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -93,3 +96,515 @@ func TestExpandHome(t *testing.T) {
 	// Non-tilde path unchanged
 	assert.Equal(t, "/abs/path", ExpandHome("/abs/path"))
 }
+
+func TestLoadConfigMetadataSchema(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nmetadata-schema:\n  due: date\n  reviews: integer\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, models.MetadataDate, cfg.MetadataSchema["due"])
+	assert.Equal(t, models.MetadataInteger, cfg.MetadataSchema["reviews"])
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nmetadata-schema:\n  due: timestamp\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigComputedFields(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\ncomputed-fields:\n  maturity: 'metadata.reviews >= 3 ? \"evergreen\" : \"seedling\"'\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Contains(t, cfg.ComputedFields["maturity"], "evergreen")
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\ncomputed-fields:\n  bad: '1 +'\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigLinkExtractors(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte(`
+vaults:
+  - /my/vault
+link-extractors:
+  - pattern: '@([a-z-]+)'
+    edge-type: mention
+    target: people/$1
+`), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	require.Len(t, cfg.LinkExtractors, 1)
+	assert.Equal(t, "mention", cfg.LinkExtractors[0].EdgeType)
+	assert.Equal(t, "people/$1", cfg.LinkExtractors[0].Target)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nlink-extractors:\n  - pattern: '('\n    edge-type: x\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigParser(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte(`
+vaults:
+  - /my/vault
+parser:
+  dialect: gfm
+  wikilinks: true
+  max-workers: 16
+  memory-budget-mb: 512
+  excerpt-sentences: 3
+  symlinks: skip
+`), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, "gfm", cfg.Parser.Dialect)
+	require.NotNil(t, cfg.Parser.Wikilinks)
+	assert.True(t, *cfg.Parser.Wikilinks)
+	assert.Nil(t, cfg.Parser.StripComments)
+	assert.Equal(t, 16, cfg.Parser.MaxWorkers)
+	assert.Equal(t, 512, cfg.Parser.MemoryBudgetMB)
+	assert.Equal(t, 3, cfg.Parser.ExcerptSentences)
+	assert.Equal(t, "skip", cfg.Parser.Symlinks)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nparser:\n  dialect: rst\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nparser:\n  symlinks: always\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nparser:\n  max-workers: -1\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigMaxBody(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nmax-body-mb: 2\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.MaxBodyMB)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nmax-body-mb: -1\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigMaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\ngraph:\n  max-file-size-mb: 5\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, 5, cfg.Graph.MaxFileSizeMB)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\ngraph:\n  max-file-size-mb: -1\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigPositionRetention(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\ngraph:\n  position-retention: 3\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, 3, cfg.Graph.PositionRetention)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\ngraph:\n  position-retention: -1\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigHubTypes(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\ngraph:\n  hub-types: [moc, index]\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"moc", "index"}, cfg.Graph.HubTypes)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\ngraph:\n  hub-types: [\"\"]\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigMaxCoordinate(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nmax-coordinate: 5000\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, 5000.0, cfg.MaxCoordinate)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nmax-coordinate: -1\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigGit(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\ngit:\n  poll-interval: 5m\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, cfg.Git.PollInterval)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\ngit:\n  poll-interval: -1m\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigLinkCheck(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nlink-check:\n  interval: 24h\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, cfg.LinkCheck.Interval)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nlink-check:\n  interval: -1h\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nduplicates:\n  interval: 24h\n  threshold: 0.9\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, cfg.Duplicates.Interval)
+	assert.Equal(t, 0.9, cfg.Duplicates.Threshold)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nduplicates:\n  threshold: 1.5\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigNotifications(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte(`vaults:
+  - /my/vault
+notifications:
+  smtp:
+    host: smtp.example.com
+    from: mnemosyne@example.com
+  to: [me@example.com]
+  owners:
+    /my/vault: [owner@example.com]
+  broken-link-threshold: 50
+  throttle: 6h
+`), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	require.NotNil(t, cfg.Notifications)
+	assert.Equal(t, "smtp.example.com", cfg.Notifications.SMTP.Host)
+	assert.Equal(t, []string{"owner@example.com"}, cfg.Notifications.Owners["/my/vault"])
+	assert.Equal(t, 50, cfg.Notifications.BrokenLinkThreshold)
+	assert.Equal(t, 6*time.Hour, cfg.Notifications.Throttle)
+
+	for _, bad := range []string{
+		"notifications:\n  smtp: {host: smtp.example.com}\n  to: [me@example.com]\n",
+		"notifications:\n  smtp: {host: smtp.example.com, from: a@example.com}\n",
+		"notifications:\n  smtp: {host: smtp.example.com, from: a@example.com}\n  to: [me@example.com]\n  throttle: -1h\n",
+	} {
+		os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\n"+bad), 0o644)
+		_, err = Load(cfgPath)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfigSecrets(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	tokenPath := filepath.Join(dir, "token")
+	os.WriteFile(tokenPath, []byte("from-file\n"), 0o600)
+	t.Setenv("MNEMOSYNE_TEST_PASSWORD", "from-env")
+	os.WriteFile(cfgPath, []byte(`# Comments may mention ${UNSET_VARIABLE}
+vaults:
+  - /my/vault
+link-extractors:
+  - pattern: '$${literal}'
+    edge-type: x
+notifications:
+  smtp:
+    host: smtp.example.com
+    from: mnemosyne@example.com
+    password: ${MNEMOSYNE_TEST_PASSWORD}
+  to: [me@example.com]
+auth:
+  users:
+    - name: ali
+      token-file: `+tokenPath+`
+`), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", cfg.Notifications.SMTP.Password)
+	assert.Equal(t, "from-file", cfg.Auth.Users[0].Token)
+	assert.Equal(t, "${literal}", cfg.LinkExtractors[0].Pattern)
+
+	// Logged configs show neither secret, and redacting leaves cfg alone
+	out := cfg.String()
+	assert.NotContains(t, out, "from-env")
+	assert.NotContains(t, out, "from-file")
+	assert.Contains(t, out, "password: REDACTED")
+	assert.Equal(t, "from-file", cfg.Auth.Users[0].Token)
+	assert.Equal(t, "from-env", cfg.Notifications.SMTP.Password)
+
+	for _, bad := range []string{
+		"port: ${UNSET_VARIABLE}\n",
+		"auth:\n  users:\n    - {name: ali, token: x, token-file: " + tokenPath + "}\n",
+		"auth:\n  users:\n    - {name: ali, token-file: " + filepath.Join(dir, "missing") + "}\n",
+	} {
+		os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\n"+bad), 0o644)
+		_, err = Load(cfgPath)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfigProfiles(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte(`port: 5555
+vaults:
+  - /my/vault
+git:
+  poll-interval: 5m
+link-check:
+  interval: 24h
+profiles:
+  dev:
+    port: 5556
+    link-check:
+      interval: 0s
+  prod:
+    vaults: [/srv/vault]
+`), 0o644)
+	os.WriteFile(OverlayPath(cfgPath, "prod"), []byte("port: 80\ngit:\n  poll-interval: 1m\n"), 0o644)
+	os.WriteFile(OverlayPath(cfgPath, "staging"), []byte("port: 8080\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, 5555, cfg.Port)
+	assert.Empty(t, cfg.Profile)
+
+	// In-file profiles merge key by key
+	cfg, err = LoadProfile(cfgPath, "dev")
+	require.NoError(t, err)
+	assert.Equal(t, "dev", cfg.Profile)
+	assert.Equal(t, 5556, cfg.Port)
+	assert.Zero(t, cfg.LinkCheck.Interval)
+	assert.Equal(t, 5*time.Minute, cfg.Git.PollInterval)
+	assert.Equal(t, []string{"/my/vault"}, cfg.Vaults)
+
+	// Overlay files merge over the in-file profile; lists are replaced
+	cfg, err = LoadProfile(cfgPath, "prod")
+	require.NoError(t, err)
+	assert.Equal(t, 80, cfg.Port)
+	assert.Equal(t, time.Minute, cfg.Git.PollInterval)
+	assert.Equal(t, 24*time.Hour, cfg.LinkCheck.Interval)
+	assert.Equal(t, []string{"/srv/vault"}, cfg.Vaults)
+
+	cfg, err = LoadProfile(cfgPath, "staging")
+	require.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Port)
+
+	for _, bad := range []string{"missing", "../config"} {
+		_, err = LoadProfile(cfgPath, bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfigMentions(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Nil(t, cfg.Mentions)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nmentions:\n  people-dir: contacts\n"), 0o644)
+	cfg, err = Load(cfgPath)
+	require.NoError(t, err)
+	require.NotNil(t, cfg.Mentions)
+	assert.Equal(t, "contacts", cfg.Mentions.PeopleDir)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nmentions:\n  pattern: '('\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigPruningProfiles(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\npruning-profiles:\n  overview:\n    exclude-daily: true\n    exclude-orphans: true\n    exclude: 'path:archive'\n  full: {}\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	require.Len(t, cfg.PruningProfiles, 2)
+	assert.True(t, cfg.PruningProfiles["overview"].ExcludeDaily)
+	assert.True(t, cfg.PruningProfiles["overview"].ExcludeOrphans)
+	assert.Equal(t, "path:archive", cfg.PruningProfiles["overview"].Exclude)
+	assert.Equal(t, PruningProfileConfig{}, cfg.PruningProfiles["full"])
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\npruning-profiles:\n  bad:\n    exclude: '('\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigEdgeWeights(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nedge-weights:\n  decay-half-life: 720h\n  normalize: true\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, 720*time.Hour, cfg.EdgeWeights.DecayHalfLife)
+	assert.True(t, cfg.EdgeWeights.Normalize)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nedge-weights:\n  decay-half-life: -1h\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigIDRules(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nid-rules:\n  - pattern: '^(\\d{12})'\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	require.Len(t, cfg.IDRules, 1)
+	assert.Equal(t, `^(\d{12})`, cfg.IDRules[0].Pattern)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nid-rules:\n  - pattern: '('\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigSlugs(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\n  - /ja/vault\nslugs:\n  ids: true\n  max-length: 40\n  vaults:\n    /ja/vault:\n      unicode: true\n      separator: _\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	require.NotNil(t, cfg.Slugs)
+	assert.Equal(t, SlugConfig{IDs: true, MaxLength: 40}, cfg.Slugs.For("/my/vault"))
+	assert.Equal(t, SlugConfig{Unicode: true, Separator: "_"}, cfg.Slugs.For("/ja/vault"))
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nslugs:\n  separator: '/'\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nslugs:\n  vaults:\n    /my/vault:\n      max-length: -1\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigRedactions(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nredactions:\n  - pattern: '(?s)%%private.*?%%'\n  - pattern: 'sk-\\w+'\n    replacement: ''\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	require.Len(t, cfg.Redactions, 2)
+	assert.Nil(t, cfg.Redactions[0].Replacement)
+	require.NotNil(t, cfg.Redactions[1].Replacement)
+	assert.Empty(t, *cfg.Redactions[1].Replacement)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nredactions:\n  - pattern: '('\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestLoadConfigClassifyAndIgnore(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nclassify:\n  - path: 'meetings/*.md'\n    type: meeting\n  - tag: rfc\n    type: spec\nignore:\n  - '*.excalidraw.md'\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, []ClassifyRuleConfig{{Path: "meetings/*.md", Type: "meeting"}, {Tag: "rfc", Type: "spec"}}, cfg.Classify)
+	assert.Equal(t, []string{"*.excalidraw.md"}, cfg.Ignore)
+
+	for _, bad := range []string{"classify:\n  - tag: rfc\n", "classify:\n  - path: '['\n    type: x\n", "ignore:\n  - '['\n"} {
+		os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\n"+bad), 0o644)
+		_, err = Load(cfgPath)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestLoadConfigAuth(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\npublish-flag: publish\nauth:\n  users:\n    - name: alice\n      token: s3cret\n      roles: [editors]\nacl-field: access\n"), 0o644)
+
+	cfg, err := Load(cfgPath)
+	require.NoError(t, err)
+	assert.Equal(t, "publish", cfg.PublishFlag)
+	require.Len(t, cfg.Auth.Users, 1)
+	assert.Equal(t, "alice", cfg.Auth.Users[0].Name)
+	assert.Equal(t, []string{"editors"}, cfg.Auth.Users[0].Roles)
+	assert.Equal(t, "access", cfg.ACLField)
+
+	os.WriteFile(cfgPath, []byte("vaults:\n  - /my/vault\nauth:\n  users:\n    - name: alice\n"), 0o644)
+	_, err = Load(cfgPath)
+	assert.Error(t, err)
+}
+
+func TestCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "classify.lua")
+	require.NoError(t, os.WriteFile(script, []byte(`function classify(note) return "a" end`), 0o644))
+
+	cfg := &Config{Vaults: []string{"/my/vault"}, Scripts: []string{script}}
+	key, err := cfg.CacheKey()
+	require.NoError(t, err)
+
+	// Resource limits do not affect parse results
+	cfg.Parser.MaxWorkers = 8
+	same, err := cfg.CacheKey()
+	require.NoError(t, err)
+	assert.Equal(t, key, same)
+
+	cfg.Parser.Dialect = "gfm"
+	changed, err := cfg.CacheKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, key, changed)
+
+	require.NoError(t, os.WriteFile(script, []byte(`function classify(note) return "b" end`), 0o644))
+	edited, err := cfg.CacheKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, changed, edited)
+
+	cfg.Classify = []ClassifyRuleConfig{{Tag: "index", Type: "index"}}
+	classified, err := cfg.CacheKey()
+	require.NoError(t, err)
+	assert.NotEqual(t, edited, classified)
+}
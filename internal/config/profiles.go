@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileEnv names the environment variable selecting a config profile when
+// none is given on the command line.
+const ProfileEnv = "MNEMOSYNE_PROFILE"
+
+var profileNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// OverlayPath returns the overlay file of profile for the config at path,
+// e.g. config.prod.yaml for config.yaml.
+func OverlayPath(path, profile string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// applyProfile removes the profiles section from the config document doc,
+// read from path, and merges the named profile over the rest: first the
+// section's entry for it, then its overlay file, if either exists. Mappings
+// merge key by key; any other value replaces the one it overrides. An empty
+// profile merges nothing, and a profile with neither is an error.
+func applyProfile(doc *yaml.Node, path, profile string) error {
+	root := rootMapping(doc)
+	var section *yaml.Node
+	if root != nil {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == "profiles" {
+				section = root.Content[i+1]
+				root.Content = append(root.Content[:i], root.Content[i+2:]...)
+				break
+			}
+		}
+	}
+	if section != nil && section.Kind != yaml.MappingNode {
+		return fmt.Errorf("profiles: line %d: must be a mapping of profile names to settings", section.Line)
+	}
+	if profile == "" {
+		return nil
+	}
+	if !profileNameRegex.MatchString(profile) {
+		return fmt.Errorf("profile %q: names are letters, digits, '-' and '_'", profile)
+	}
+
+	found := false
+	if section != nil {
+		for i := 0; i+1 < len(section.Content); i += 2 {
+			if section.Content[i].Value != profile {
+				continue
+			}
+			overrides := section.Content[i+1]
+			if overrides.Kind != yaml.MappingNode {
+				return fmt.Errorf("profiles: %s: line %d: must be a mapping", profile, overrides.Line)
+			}
+			root = mergeInto(doc, root, overrides)
+			found = true
+		}
+	}
+
+	overlay := OverlayPath(path, profile)
+	data, err := os.ReadFile(overlay)
+	switch {
+	case err == nil:
+		var odoc yaml.Node
+		if err := yaml.Unmarshal(data, &odoc); err != nil {
+			return fmt.Errorf("parse %s: %w", overlay, err)
+		}
+		if overrides := rootMapping(&odoc); overrides != nil {
+			root = mergeInto(doc, root, overrides)
+		}
+		found = true
+	case !os.IsNotExist(err):
+		return fmt.Errorf("read %s: %w", overlay, err)
+	}
+
+	if !found {
+		return fmt.Errorf("profile %q is neither in profiles nor in %s", profile, overlay)
+	}
+	return nil
+}
+
+// rootMapping returns the top-level mapping of a YAML document, or nil if
+// the document is empty.
+func rootMapping(doc *yaml.Node) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// mergeInto merges overrides into root, the top-level mapping of doc,
+// creating it if doc is empty, and returns root.
+func mergeInto(doc, root, overrides *yaml.Node) *yaml.Node {
+	if root == nil {
+		root = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{root}
+	}
+	mergeMappings(root, overrides)
+	return root
+}
+
+// mergeMappings sets each key of overrides in base, merging mappings
+// recursively and replacing any other value.
+func mergeMappings(base, overrides *yaml.Node) {
+	for i := 0; i+1 < len(overrides.Content); i += 2 {
+		key, value := overrides.Content[i], overrides.Content[i+1]
+		replaced := false
+		for j := 0; j+1 < len(base.Content); j += 2 {
+			if base.Content[j].Value != key.Value {
+				continue
+			}
+			if base.Content[j+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+				mergeMappings(base.Content[j+1], value)
+			} else {
+				base.Content[j+1] = value
+			}
+			replaced = true
+			break
+		}
+		if !replaced {
+			base.Content = append(base.Content, key, value)
+		}
+	}
+}
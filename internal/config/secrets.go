@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secrets in configs meant for display.
+const redacted = "REDACTED"
+
+// envRefRegex matches ${NAME} in config values, or $${NAME}, which escapes it.
+var envRefRegex = regexp.MustCompile(`\$(\$?)\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${NAME} in every scalar value of the document under
+// node with the environment variable NAME, failing if it is not set.
+// Comments and keys are left alone; $${NAME} stands for a literal ${NAME}.
+func expandEnv(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "${") {
+		var missing string
+		node.Value = envRefRegex.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			m := envRefRegex.FindStringSubmatch(ref)
+			if m[1] != "" {
+				return ref[1:]
+			}
+			v, ok := os.LookupEnv(m[2])
+			if !ok && missing == "" {
+				missing = m[2]
+			}
+			return v
+		})
+		if missing != "" {
+			return fmt.Errorf("line %d: environment variable %s is not set", node.Line, missing)
+		}
+		return nil
+	}
+	for i, child := range node.Content {
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			continue // Key
+		}
+		if err := expandEnv(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSecret returns value, or else the contents of file without trailing
+// line breaks, so secrets can live outside the config. Setting both is an
+// error.
+func readSecret(value, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+	if value != "" {
+		return "", fmt.Errorf("set either the value or the file, not both")
+	}
+	data, err := os.ReadFile(expandHome(file))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveSecrets reads the secrets the config references by file.
+func (c *Config) resolveSecrets() error {
+	for i := range c.Auth.Users {
+		u := &c.Auth.Users[i]
+		token, err := readSecret(u.Token, u.TokenFile)
+		if err != nil {
+			return fmt.Errorf("auth.users[%d]: token-file: %w", i, err)
+		}
+		u.Token = token
+	}
+	if n := c.Notifications; n != nil {
+		password, err := readSecret(n.SMTP.Password, n.SMTP.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("notifications: smtp password-file: %w", err)
+		}
+		n.SMTP.Password = password
+	}
+	return nil
+}
+
+// Redacted returns a copy of the config with its secrets, such as tokens
+// and passwords, replaced, for logging and display.
+func (c *Config) Redacted() *Config {
+	r := *c
+	r.Auth.Users = make([]UserConfig, len(c.Auth.Users))
+	for i, u := range c.Auth.Users {
+		if u.Token != "" {
+			u.Token = redacted
+		}
+		r.Auth.Users[i] = u
+	}
+	if c.Notifications != nil {
+		n := *c.Notifications
+		if n.SMTP.Password != "" {
+			n.SMTP.Password = redacted
+		}
+		r.Notifications = &n
+	}
+	return &r
+}
+
+// String returns the config as YAML with its secrets redacted, so configs
+// can be logged safely.
+func (c *Config) String() string {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return fmt.Sprintf("config: %v", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,231 @@
+// Package doctor checks that a deployment is ready to serve before the
+// server starts: its config, database, vaults, git remotes, and the rules
+// that classify notes.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/config"
+	"github.com/ali01/mnemosyne/internal/git"
+	"github.com/ali01/mnemosyne/internal/scripting"
+	"github.com/ali01/mnemosyne/internal/store"
+	"github.com/ali01/mnemosyne/internal/vault"
+)
+
+// remoteTimeout bounds reaching a vault's git remote.
+const remoteTimeout = 30 * time.Second
+
+// Check is the outcome of one check.
+type Check struct {
+	Name   string
+	Err    error  // Why the check failed; nil if it passed
+	Detail string // What was found, when it passed
+}
+
+// OK reports whether the check passed.
+func (c Check) OK() bool {
+	return c.Err == nil
+}
+
+// Options says what to check.
+type Options struct {
+	ConfigPath string
+	Profile    string
+	DBPath     string
+	NoMigrate  bool // The server will not migrate, so an outdated schema fails
+}
+
+// Run runs every check and returns their outcomes in order. Checks that
+// need the config are skipped if it does not load.
+func Run(ctx context.Context, opts Options) []Check {
+	var checks []Check
+	cfg, err := config.LoadProfile(opts.ConfigPath, opts.Profile)
+	if err != nil {
+		checks = append(checks, Check{Name: "Config", Err: err})
+	} else {
+		detail := fmt.Sprintf("%s, %d vault(s)", opts.ConfigPath, len(cfg.Vaults))
+		if cfg.Profile != "" {
+			detail += ", profile " + cfg.Profile
+		}
+		checks = append(checks, Check{Name: "Config", Detail: detail})
+	}
+
+	checks = append(checks, checkDatabase(opts.DBPath, opts.NoMigrate))
+	if cfg == nil {
+		return checks
+	}
+	for _, path := range cfg.Vaults {
+		checks = append(checks, checkVault(path))
+	}
+	for _, path := range cfg.Vaults {
+		checks = append(checks, checkGit(ctx, path, cfg.Git.PollInterval))
+	}
+	return append(checks, checkRules(cfg)...)
+}
+
+// checkDatabase checks that the database can be opened and that its schema
+// suits this build.
+func checkDatabase(dbPath string, noMigrate bool) Check {
+	c := Check{Name: "Database"}
+	latest := store.LatestSchemaVersion()
+	version, err := store.SchemaVersion(dbPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if noMigrate {
+			c.Err = fmt.Errorf("%s does not exist; run 'mnemosyne migrate' to create it", dbPath)
+		} else {
+			c.Detail = fmt.Sprintf("%s does not exist yet; the server creates it", dbPath)
+		}
+	case err != nil:
+		c.Err = fmt.Errorf("%s: %w", dbPath, err)
+	case version > latest:
+		c.Err = fmt.Errorf("%s: schema version %d is newer than this build supports (%d)", dbPath, version, latest)
+	case version < latest && noMigrate:
+		c.Err = fmt.Errorf("%s: schema version %d, want %d; run 'mnemosyne migrate'", dbPath, version, latest)
+	case version < latest:
+		c.Detail = fmt.Sprintf("%s, schema version %d; the server migrates it to %d", dbPath, version, latest)
+	default:
+		c.Detail = fmt.Sprintf("%s, schema version %d", dbPath, version)
+	}
+	return c
+}
+
+// checkVault checks that the vault at path is a directory the server can
+// read, and write positions to.
+func checkVault(path string) Check {
+	c := Check{Name: "Vault " + path}
+	info, err := os.Stat(path)
+	if err != nil {
+		c.Err = err
+		return c
+	}
+	if !info.IsDir() {
+		c.Err = fmt.Errorf("not a directory")
+		return c
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		c.Err = err
+		return c
+	}
+	f, err := os.CreateTemp(path, ".mnemosyne-doctor-*")
+	if err != nil {
+		c.Err = fmt.Errorf("not writable, so positions cannot be saved: %w", err)
+		return c
+	}
+	f.Close()
+	os.Remove(f.Name())
+	c.Detail = fmt.Sprintf("readable and writable, %d entries", len(entries))
+	return c
+}
+
+// checkGit checks that a vault kept in git can reach its remote, if the
+// server will poll it.
+func checkGit(ctx context.Context, path string, pollInterval time.Duration) Check {
+	c := Check{Name: "Git " + path}
+	if _, err := git.Run(ctx, path, "rev-parse", "--show-prefix"); err != nil {
+		c.Detail = "not in a git repository"
+		return c
+	}
+	if pollInterval == 0 {
+		c.Detail = "not polled (git: poll-interval is 0)"
+		return c
+	}
+	ctx, cancel := context.WithTimeout(ctx, remoteTimeout)
+	defer cancel()
+	remote, err := git.CheckRemote(ctx, path)
+	switch {
+	case errors.Is(err, git.ErrNoUpstream):
+		c.Detail = "branch has no upstream, so it is not polled"
+	case err != nil:
+		c.Err = err
+	default:
+		c.Detail = fmt.Sprintf("remote %s reachable", remote)
+	}
+	return c
+}
+
+// checkRules compiles the configured rules that shape how notes are parsed
+// and classified, one check for each kind configured.
+func checkRules(cfg *config.Config) []Check {
+	var checks []Check
+	if len(cfg.ComputedFields) > 0 {
+		c := Check{Name: "Computed fields", Detail: fmt.Sprintf("%d compiled", len(cfg.ComputedFields))}
+		if _, err := vault.CompileComputedFields(cfg.ComputedFields); err != nil {
+			c.Err = err
+		}
+		checks = append(checks, c)
+	}
+	if len(cfg.LinkExtractors) > 0 {
+		c := Check{Name: "Link extractors", Detail: fmt.Sprintf("%d compiled", len(cfg.LinkExtractors))}
+		for _, le := range cfg.LinkExtractors {
+			if _, err := vault.NewLinkExtractor(le.Pattern, le.EdgeType, le.Target); err != nil {
+				c.Err = err
+				break
+			}
+		}
+		checks = append(checks, c)
+	}
+	if cfg.Mentions != nil {
+		c := Check{Name: "Mentions", Detail: "compiled"}
+		if _, err := vault.NewMentionExtractor(cfg.Mentions.Pattern, cfg.Mentions.PeopleDir); err != nil {
+			c.Err = err
+		}
+		checks = append(checks, c)
+	}
+	if len(cfg.IDRules) > 0 {
+		c := Check{Name: "ID rules", Detail: fmt.Sprintf("%d compiled", len(cfg.IDRules))}
+		for _, r := range cfg.IDRules {
+			if _, err := vault.NewIDRule(r.Pattern, r.Template); err != nil {
+				c.Err = err
+				break
+			}
+		}
+		checks = append(checks, c)
+	}
+	if len(cfg.Redactions) > 0 {
+		c := Check{Name: "Redactions", Detail: fmt.Sprintf("%d compiled", len(cfg.Redactions))}
+		for _, r := range cfg.Redactions {
+			if _, err := vault.NewRedaction(r.Pattern, ""); err != nil {
+				c.Err = err
+				break
+			}
+		}
+		checks = append(checks, c)
+	}
+	if len(cfg.Scripts) > 0 {
+		c := Check{Name: "Scripts", Detail: fmt.Sprintf("%d loaded", len(cfg.Scripts))}
+		if scripts, err := scripting.LoadLua(cfg.Scripts); err != nil {
+			c.Err = err
+		} else {
+			scripts.Close()
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// Print writes the checks as a checklist and returns how many failed.
+func Print(w io.Writer, checks []Check) int {
+	failed := 0
+	for _, c := range checks {
+		if c.OK() {
+			fmt.Fprintf(w, "[PASS] %s: %s\n", c.Name, c.Detail)
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "[FAIL] %s: %v\n", c.Name, c.Err)
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "\n%d of %d checks failed.\n", failed, len(checks))
+	} else {
+		fmt.Fprintf(w, "\nAll %d checks passed.\n", len(checks))
+	}
+	return failed
+}
@@ -0,0 +1,93 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/store"
+)
+
+func writeConfig(t *testing.T, dir, yaml string) string {
+	t.Helper()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(yaml), 0o644))
+	return path
+}
+
+func byName(checks []Check) map[string]Check {
+	m := make(map[string]Check, len(checks))
+	for _, c := range checks {
+		m[c.Name] = c
+	}
+	return m
+}
+
+func TestRunPasses(t *testing.T) {
+	dir := t.TempDir()
+	vaultPath := filepath.Join(dir, "vault")
+	require.NoError(t, os.Mkdir(vaultPath, 0o755))
+	cfgPath := writeConfig(t, dir, fmt.Sprintf(`
+vaults: [%s]
+computed-fields:
+  maturity: 'metadata.reviews >= 3 ? "evergreen" : "seedling"'
+id-rules:
+  - pattern: '^(\d+)-'
+`, vaultPath))
+	dbPath := filepath.Join(dir, "mnemosyne.db")
+
+	checks := Run(context.Background(), Options{ConfigPath: cfgPath, DBPath: dbPath})
+	var out bytes.Buffer
+	assert.Equal(t, 0, Print(&out, checks), out.String())
+	got := byName(checks)
+	assert.Contains(t, got["Database"].Detail, "does not exist yet")
+	assert.Equal(t, "not in a git repository", got["Git "+vaultPath].Detail)
+	assert.Contains(t, got, "Computed fields")
+	assert.Contains(t, got, "ID rules")
+	entries, err := os.ReadDir(vaultPath)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "write probe removed")
+
+	// A missing database fails when the server will not create it
+	checks = Run(context.Background(), Options{ConfigPath: cfgPath, DBPath: dbPath, NoMigrate: true})
+	assert.False(t, byName(checks)["Database"].OK())
+
+	s, err := store.New(dbPath)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+	checks = Run(context.Background(), Options{ConfigPath: cfgPath, DBPath: dbPath, NoMigrate: true})
+	assert.Equal(t, fmt.Sprintf("%s, schema version %d", dbPath, store.LatestSchemaVersion()), byName(checks)["Database"].Detail)
+}
+
+func TestRunFails(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "classify.lua")
+	require.NoError(t, os.WriteFile(script, []byte("function classify(note"), 0o644))
+	cfgPath := writeConfig(t, dir, fmt.Sprintf(`
+vaults: [%s]
+scripts: [%s]
+`, filepath.Join(dir, "missing"), script))
+
+	checks := Run(context.Background(), Options{ConfigPath: cfgPath, DBPath: filepath.Join(dir, "db")})
+	got := byName(checks)
+	assert.True(t, got["Config"].OK())
+	assert.False(t, got["Vault "+filepath.Join(dir, "missing")].OK())
+	assert.False(t, got["Scripts"].OK())
+
+	var out bytes.Buffer
+	assert.Equal(t, 2, Print(&out, checks))
+	assert.Contains(t, out.String(), "[FAIL] Scripts: ")
+	assert.Contains(t, out.String(), "2 of 5 checks failed.")
+
+	// A config that does not load stops the checks that need it
+	checks = Run(context.Background(), Options{ConfigPath: filepath.Join(dir, "none.yaml"), DBPath: filepath.Join(dir, "db")})
+	require.Len(t, checks, 2)
+	assert.False(t, checks[0].OK())
+	assert.True(t, checks[1].OK())
+}
@@ -0,0 +1,237 @@
+// Package duplicates finds notes whose contents are nearly identical, once
+// at start and then periodically. Each note is reduced to a MinHash
+// signature of its word shingles; locality-sensitive hashing of the
+// signatures picks the pairs worth comparing, so the cost grows with the
+// number of notes rather than the number of pairs.
+package duplicates
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/store"
+)
+
+const (
+	// DefaultThreshold is the least similarity reported by default.
+	DefaultThreshold = 0.8
+
+	// shingleSize is how many consecutive words make a shingle.
+	shingleSize = 5
+
+	// minWords skips notes too short to compare meaningfully, such as
+	// stubs, which would all look alike.
+	minWords = 20
+
+	// numHashes is the signature length. The similarity estimate's
+	// standard error is at most 1/(2*sqrt(numHashes)), about 0.044.
+	numHashes = 128
+
+	// bands of rowsPerBand signature values each are hashed to find
+	// candidate pairs: pairs about (1/bands)^(1/rowsPerBand) = 0.42
+	// similar or more are likely to share a band.
+	bands       = 32
+	rowsPerBand = numHashes / bands
+
+	// maxPairs caps the pairs stored, keeping the most similar.
+	maxPairs = 10000
+)
+
+// Detector finds the near-duplicate notes in a store.
+type Detector struct {
+	store     *store.Store
+	interval  time.Duration
+	threshold float64
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDetector creates a detector that reports pairs of notes at least
+// threshold similar every interval once started. A threshold of zero means
+// DefaultThreshold.
+func NewDetector(s *store.Store, interval time.Duration, threshold float64) *Detector {
+	if threshold == 0 {
+		threshold = DefaultThreshold
+	}
+	return &Detector{store: s, interval: interval, threshold: threshold}
+}
+
+// Start detects near duplicates in the background, right away and then
+// every interval.
+func (d *Detector) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			pairs, err := d.DetectAll(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				log.Printf("Warning: duplicate detection failed: %v", err)
+			} else {
+				log.Printf("Found %d pair(s) of near-duplicate notes", pairs)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops detecting, abandoning a run in progress, and waits for the
+// background loop to finish.
+func (d *Detector) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.wg.Wait()
+}
+
+// DetectAll compares every stored note with content and replaces the stored
+// near-duplicate pairs with those found. It returns how many were found.
+func (d *Detector) DetectAll(ctx context.Context) (int, error) {
+	var ids []string
+	var sigs [][]uint64
+	err := d.store.ForEachNodeContent(ctx, func(id, content string) error {
+		if sig := signature(content); sig != nil {
+			ids = append(ids, id)
+			sigs = append(sigs, sig)
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	var pairs []models.NearDuplicate
+	for _, c := range candidates(sigs) {
+		sim := similarity(sigs[c[0]], sigs[c[1]])
+		if sim < d.threshold {
+			continue
+		}
+		a, b := ids[c[0]], ids[c[1]]
+		if b < a {
+			a, b = b, a
+		}
+		pairs = append(pairs, models.NearDuplicate{A: a, B: b, Similarity: sim, DetectedAt: now})
+	}
+	slices.SortFunc(pairs, func(x, y models.NearDuplicate) int {
+		return cmp.Or(cmp.Compare(y.Similarity, x.Similarity), cmp.Compare(x.A, y.A), cmp.Compare(x.B, y.B))
+	})
+	pairs = pairs[:min(len(pairs), maxPairs)]
+	if err := d.store.ReplaceNearDuplicates(ctx, pairs); err != nil {
+		return 0, err
+	}
+	return len(pairs), nil
+}
+
+// signature returns the MinHash signature of the word shingles of text, or
+// nil if it has fewer than minWords words. Words are compared
+// case-insensitively, ignoring punctuation and markup.
+func signature(text string) []uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) < minWords {
+		return nil
+	}
+	sig := make([]uint64, numHashes)
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		h := fnv.New64a()
+		for _, w := range words[i : i+shingleSize] {
+			h.Write([]byte(w))
+			h.Write([]byte{0})
+		}
+		x := h.Sum64()
+		for j := range sig {
+			if v := mix(x ^ seeds[j]); v < sig[j] {
+				sig[j] = v
+			}
+		}
+	}
+	return sig
+}
+
+// seeds derive the signature's hash functions from one shingle hash.
+var seeds = func() [numHashes]uint64 {
+	var s [numHashes]uint64
+	for i := range s {
+		s[i] = mix(uint64(i) + 1)
+	}
+	return s
+}()
+
+// mix is the splitmix64 finalizer, a fast hash of 64-bit values.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// candidates returns the index pairs, lower first, of signatures that agree
+// on every value of at least one band, each pair once.
+func candidates(sigs [][]uint64) [][2]int {
+	seen := make(map[[2]int]bool)
+	var out [][2]int
+	buf := make([]byte, 8*rowsPerBand)
+	for band := 0; band < bands; band++ {
+		buckets := make(map[uint64][]int)
+		for i, sig := range sigs {
+			for r, v := range sig[band*rowsPerBand : (band+1)*rowsPerBand] {
+				binary.LittleEndian.PutUint64(buf[8*r:], v)
+			}
+			h := fnv.New64a()
+			h.Write(buf)
+			key := h.Sum64()
+			buckets[key] = append(buckets[key], i)
+		}
+		for _, members := range buckets {
+			for x := 0; x < len(members); x++ {
+				for y := x + 1; y < len(members); y++ {
+					p := [2]int{members[x], members[y]}
+					if !seen[p] {
+						seen[p] = true
+						out = append(out, p)
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// similarity estimates the Jaccard similarity of the shingle sets behind two
+// signatures: the share of values they agree on.
+func similarity(a, b []uint64) float64 {
+	same := 0
+	for i := range a {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(a))
+}
@@ -0,0 +1,76 @@
+package duplicates
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/mnemosynetest"
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+const (
+	lift = "Lift is generated when air flows faster over the curved upper surface of a wing than " +
+		"under its flat lower surface, lowering the pressure above the wing. The angle of attack " +
+		"and the airspeed together determine how much lift a wing produces before it stalls."
+	supply = "Prices rise when demand for a good outgrows its supply and fall when producers make " +
+		"more than buyers want. Markets settle near the price where the quantity supplied matches " +
+		"the quantity demanded, until tastes, costs or incomes shift one of the curves."
+)
+
+func TestSignature(t *testing.T) {
+	assert.Nil(t, signature("Too short to compare."))
+
+	a := signature(lift)
+	require.Len(t, a, numHashes)
+	assert.Equal(t, 1.0, similarity(a, signature(strings.ToUpper(lift)+"!")), "case and punctuation are ignored")
+	edited := signature(strings.Replace(lift, "before it stalls", "until it stalls", 1))
+	assert.Greater(t, similarity(a, edited), 0.75)
+	assert.Less(t, similarity(a, signature(supply)), 0.1)
+}
+
+func TestCandidates(t *testing.T) {
+	sigs := [][]uint64{signature(lift), signature(supply), signature(lift + " Flaps add lift at low speed.")}
+	assert.Equal(t, [][2]int{{0, 2}}, candidates(sigs))
+}
+
+func TestDetectAll(t *testing.T) {
+	s := mnemosynetest.NewStore(t)
+	seed := mnemosynetest.SeedGraph(t, s)
+	for id, content := range map[string]string{
+		"a":      lift, // Replaces the seeded content
+		"b":      supply,
+		"lift-2": lift + " Flaps add lift at low speed.",
+		"stub":   "TODO",
+	} {
+		require.NoError(t, s.UpsertNode(&models.VaultNode{
+			ID: id, VaultID: seed.VaultID, Title: id, FilePath: id + ".md", Content: content,
+			CreatedAt: time.Now(), UpdatedAt: time.Now(),
+		}))
+	}
+
+	d := NewDetector(s, time.Hour, 0)
+	n, err := d.DetectAll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	pairs, err := s.GetNearDuplicates(0)
+	require.NoError(t, err)
+	require.Len(t, pairs, 1)
+	assert.Equal(t, "a", pairs[0].A)
+	assert.Equal(t, "lift-2", pairs[0].B)
+	assert.GreaterOrEqual(t, pairs[0].Similarity, DefaultThreshold)
+	assert.False(t, pairs[0].DetectedAt.IsZero())
+
+	// Pairs go with their notes, and each run replaces the last
+	require.NoError(t, s.DeleteNode("lift-2"))
+	pairs, err = s.GetNearDuplicates(0)
+	require.NoError(t, err)
+	assert.Empty(t, pairs)
+	n, err = NewDetector(s, time.Hour, 0.99).DetectAll(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
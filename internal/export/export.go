@@ -0,0 +1,438 @@
+// Package export writes graphs in standard interchange formats, so a vault's
+// graph can be opened in tools such as Gephi, yEd or Graphviz.
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// Formats written by Write.
+const (
+	GraphML = "graphml"
+	DOT     = "dot"
+	GEXF    = "gexf"
+)
+
+// Formats lists the supported formats.
+var Formats = []string{GraphML, DOT, GEXF}
+
+// ContentType returns the media type of format, or "" when it is unknown.
+func ContentType(format string) string {
+	switch format {
+	case GraphML:
+		return "application/graphml+xml"
+	case DOT:
+		return "text/vnd.graphviz"
+	case GEXF:
+		return "application/gexf+xml"
+	}
+	return ""
+}
+
+// Write writes g to w in format. Nodes carry their title, file path, type,
+// level, color and position; edges their type and weight. All edges are
+// directed, from the linking note to the linked one.
+func Write(w io.Writer, g *models.Graph, format string) error {
+	enc, err := NewEncoder(w, format)
+	if err != nil {
+		return err
+	}
+	for i := range g.Nodes {
+		if err := enc.Node(&g.Nodes[i]); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		if err := enc.Edge(e); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// Encoder writes a graph as Write does, a node or edge at a time, so large
+// graphs need not be held in memory. Every node must be written before the
+// first edge, and Close must be called to finish the file.
+type Encoder struct {
+	w       *bufio.Writer
+	format  string
+	xml     *xml.Encoder // GraphML and GEXF
+	open    []xml.Token  // Elements Close ends, innermost last
+	edges   int          // Edges written, for GEXF IDs
+	inEdges bool         // Whether an edge has been written
+}
+
+// NewEncoder starts a graph in format on w.
+func NewEncoder(w io.Writer, format string) (*Encoder, error) {
+	e := &Encoder{w: bufio.NewWriter(w), format: format}
+	var err error
+	switch format {
+	case GraphML:
+		err = e.startGraphML()
+	case DOT:
+		_, err = e.w.WriteString("digraph mnemosyne {\n")
+	case GEXF:
+		err = e.startGEXF()
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Node writes n.
+func (e *Encoder) Node(n *models.Node) error {
+	if e.inEdges {
+		return errors.New("export: node written after edges")
+	}
+	switch e.format {
+	case GraphML:
+		return e.graphMLNode(n)
+	case DOT:
+		return e.dotNode(n)
+	default:
+		return e.gexfNode(n)
+	}
+}
+
+// Edge writes ed.
+func (e *Encoder) Edge(ed models.Edge) error {
+	if !e.inEdges {
+		e.inEdges = true
+		if e.format == GEXF {
+			if err := e.gexfEdges(); err != nil {
+				return err
+			}
+		}
+	}
+	switch e.format {
+	case GraphML:
+		return e.graphMLEdge(ed)
+	case DOT:
+		return e.dotEdge(ed)
+	default:
+		return e.gexfEdge(ed)
+	}
+}
+
+// Close finishes the file and flushes it to the underlying writer.
+func (e *Encoder) Close() error {
+	if e.format == DOT {
+		if _, err := e.w.WriteString("}\n"); err != nil {
+			return err
+		}
+		return e.w.Flush()
+	}
+	if !e.inEdges && e.format == GEXF {
+		if err := e.gexfEdges(); err != nil {
+			return err
+		}
+	}
+	for i := len(e.open) - 1; i >= 0; i-- {
+		if err := e.xml.EncodeToken(e.open[i]); err != nil {
+			return err
+		}
+	}
+	if err := e.xml.Flush(); err != nil {
+		return err
+	}
+	if _, err := e.w.WriteString("\n"); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// startXML writes the XML header and opens the elements in tokens, which
+// Close ends.
+func (e *Encoder) startXML(tokens ...xml.StartElement) error {
+	if _, err := e.w.WriteString(xml.Header); err != nil {
+		return err
+	}
+	e.xml = xml.NewEncoder(e.w)
+	e.xml.Indent("", "  ")
+	return e.openXML(tokens...)
+}
+
+func (e *Encoder) openXML(tokens ...xml.StartElement) error {
+	for _, t := range tokens {
+		if err := e.xml.EncodeToken(t); err != nil {
+			return err
+		}
+		e.open = append(e.open, t.End())
+	}
+	return nil
+}
+
+// closeXML ends the innermost open element.
+func (e *Encoder) closeXML() error {
+	end := e.open[len(e.open)-1]
+	e.open = e.open[:len(e.open)-1]
+	return e.xml.EncodeToken(end)
+}
+
+// nodeType returns the classified type of n, set by the API in its metadata.
+func nodeType(n *models.Node) string {
+	t, _ := n.Metadata["type"].(string)
+	return t
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// --- GraphML ---
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr,omitempty"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+var graphMLKeys = []graphMLKey{
+	{"title", "node", "title", "string"},
+	{"file_path", "node", "file_path", "string"},
+	{"type", "node", "type", "string"},
+	{"level", "node", "level", "int"},
+	{"color", "node", "color", "string"},
+	{"x", "node", "x", "double"},
+	{"y", "node", "y", "double"},
+	{"z", "node", "z", "double"},
+	{"edge_type", "edge", "type", "string"},
+	{"weight", "edge", "weight", "double"},
+}
+
+func (e *Encoder) startGraphML() error {
+	root := xml.StartElement{
+		Name: xml.Name{Local: "graphml"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: "http://graphml.graphdrawing.org/xmlns"}},
+	}
+	graph := xml.StartElement{
+		Name: xml.Name{Local: "graph"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "id"}, Value: "G"}, {Name: xml.Name{Local: "edgedefault"}, Value: "directed"}},
+	}
+	if err := e.startXML(root); err != nil {
+		return err
+	}
+	for _, k := range graphMLKeys {
+		if err := e.xml.EncodeElement(k, xml.StartElement{Name: xml.Name{Local: "key"}}); err != nil {
+			return err
+		}
+	}
+	return e.openXML(graph)
+}
+
+func (e *Encoder) graphMLNode(n *models.Node) error {
+	data := []graphMLData{
+		{"title", n.Title},
+		{"file_path", n.FilePath},
+		{"type", nodeType(n)},
+		{"level", strconv.Itoa(n.Level)},
+		{"color", n.Color},
+		{"x", formatFloat(n.Position.X)},
+		{"y", formatFloat(n.Position.Y)},
+		{"z", formatFloat(n.Position.Z)},
+	}
+	// Empty values are left for the key's default
+	data = deleteEmpty(data)
+	return e.xml.EncodeElement(graphMLNode{ID: n.ID, Data: data}, xml.StartElement{Name: xml.Name{Local: "node"}})
+}
+
+func (e *Encoder) graphMLEdge(ed models.Edge) error {
+	data := deleteEmpty([]graphMLData{{"edge_type", ed.Type}, {"weight", formatFloat(ed.Weight)}})
+	return e.xml.EncodeElement(graphMLEdge{ID: ed.ID, Source: ed.Source, Target: ed.Target, Data: data}, xml.StartElement{Name: xml.Name{Local: "edge"}})
+}
+
+func deleteEmpty(data []graphMLData) []graphMLData {
+	kept := data[:0]
+	for _, d := range data {
+		if d.Value != "" {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// --- DOT ---
+
+func (e *Encoder) dotNode(n *models.Node) error {
+	w := e.w
+	fmt.Fprintf(w, "  %s [label=%s", dotID(n.ID), dotID(n.Title))
+	if p := n.FilePath; p != "" {
+		fmt.Fprintf(w, ", file_path=%s", dotID(p))
+	}
+	if t := nodeType(n); t != "" {
+		fmt.Fprintf(w, ", type=%s", dotID(t))
+	}
+	if n.Color != "" {
+		fmt.Fprintf(w, ", color=%s", dotID(n.Color))
+	}
+	// Graphviz reads pos in points; neato -n keeps it
+	_, err := fmt.Fprintf(w, ", pos=\"%s,%s\"];\n", formatFloat(n.Position.X), formatFloat(n.Position.Y))
+	return err
+}
+
+func (e *Encoder) dotEdge(ed models.Edge) error {
+	_, err := fmt.Fprintf(e.w, "  %s -> %s [type=%s, weight=%s];\n", dotID(ed.Source), dotID(ed.Target), dotID(ed.Type), formatFloat(ed.Weight))
+	return err
+}
+
+// dotID quotes s as a DOT string ID. Backslashes are escaped too, since
+// Graphviz would otherwise read them as label escapes such as \n.
+func dotID(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r': // Dropped, as in \r\n
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// --- GEXF ---
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfPosition struct {
+	X float64 `xml:"x,attr"`
+	Y float64 `xml:"y,attr"`
+	Z float64 `xml:"z,attr"`
+}
+
+type gexfNode struct {
+	ID        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue,omitempty"`
+	Position  gexfPosition   `xml:"viz:position"`
+}
+
+type gexfEdge struct {
+	ID     string  `xml:"id,attr"`
+	Source string  `xml:"source,attr"`
+	Target string  `xml:"target,attr"`
+	Label  string  `xml:"label,attr,omitempty"`
+	Weight float64 `xml:"weight,attr"`
+}
+
+var gexfNodeAttributes = []gexfAttribute{
+	{"file_path", "file_path", "string"},
+	{"type", "type", "string"},
+	{"level", "level", "integer"},
+	{"color", "color", "string"},
+}
+
+func (e *Encoder) startGEXF() error {
+	root := xml.StartElement{
+		Name: xml.Name{Local: "gexf"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns"}, Value: "http://gexf.net/1.3"},
+			{Name: xml.Name{Local: "xmlns:viz"}, Value: "http://gexf.net/1.3/viz"},
+			{Name: xml.Name{Local: "version"}, Value: "1.3"},
+		},
+	}
+	graph := xml.StartElement{
+		Name: xml.Name{Local: "graph"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "defaultedgetype"}, Value: "directed"}},
+	}
+	attributes := xml.StartElement{
+		Name: xml.Name{Local: "attributes"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "class"}, Value: "node"}},
+	}
+	if err := e.startXML(root, graph, attributes); err != nil {
+		return err
+	}
+	for _, a := range gexfNodeAttributes {
+		if err := e.xml.EncodeElement(a, xml.StartElement{Name: xml.Name{Local: "attribute"}}); err != nil {
+			return err
+		}
+	}
+	if err := e.closeXML(); err != nil {
+		return err
+	}
+	return e.openXML(xml.StartElement{Name: xml.Name{Local: "nodes"}})
+}
+
+// gexfEdges ends the nodes and starts the edges.
+func (e *Encoder) gexfEdges() error {
+	if err := e.closeXML(); err != nil {
+		return err
+	}
+	return e.openXML(xml.StartElement{Name: xml.Name{Local: "edges"}})
+}
+
+func (e *Encoder) gexfNode(n *models.Node) error {
+	var values []gexfAttValue
+	for _, v := range []gexfAttValue{
+		{"file_path", n.FilePath},
+		{"type", nodeType(n)},
+		{"level", strconv.Itoa(n.Level)},
+		{"color", n.Color},
+	} {
+		if v.Value != "" {
+			values = append(values, v)
+		}
+	}
+	gn := gexfNode{
+		ID:        n.ID,
+		Label:     n.Title,
+		AttValues: values,
+		Position:  gexfPosition{X: n.Position.X, Y: n.Position.Y, Z: n.Position.Z},
+	}
+	return e.xml.EncodeElement(gn, xml.StartElement{Name: xml.Name{Local: "node"}})
+}
+
+func (e *Encoder) gexfEdge(ed models.Edge) error {
+	// GEXF requires edge IDs; edges built on the fly may lack them
+	id := ed.ID
+	if id == "" {
+		id = ed.Source + "->" + ed.Target + "#" + strconv.Itoa(e.edges)
+	}
+	e.edges++
+	ge := gexfEdge{ID: id, Source: ed.Source, Target: ed.Target, Label: ed.Type, Weight: ed.Weight}
+	return e.xml.EncodeElement(ge, xml.StartElement{Name: xml.Name{Local: "edge"}})
+}
@@ -0,0 +1,104 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+func testGraph() *models.Graph {
+	return &models.Graph{
+		Nodes: []models.Node{
+			{
+				ID: "a", Title: `Say "hi" & <go>`, FilePath: `dir\a.md`, Level: 1, Color: "#ff0000",
+				Position: models.Position{X: 1.5, Y: -2}, Metadata: map[string]interface{}{"type": "hub"},
+			},
+			{ID: "b", Title: "Line\nbreak"},
+		},
+		Edges: []models.Edge{
+			{ID: "e1", Source: "a", Target: "b", Type: "wikilink", Weight: 1},
+			{Source: "b", Target: "a", Type: "embed", Weight: 0.5},
+		},
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, testGraph(), GraphML))
+
+	var doc struct {
+		Keys  []graphMLKey `xml:"key"`
+		Graph struct {
+			EdgeDefault string        `xml:"edgedefault,attr"`
+			Nodes       []graphMLNode `xml:"node"`
+			Edges       []graphMLEdge `xml:"edge"`
+		} `xml:"graph"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Len(t, doc.Keys, len(graphMLKeys))
+	assert.Equal(t, "directed", doc.Graph.EdgeDefault)
+	require.Len(t, doc.Graph.Nodes, 2)
+	assert.Equal(t, []graphMLData{
+		{"title", `Say "hi" & <go>`}, {"file_path", `dir\a.md`}, {"type", "hub"}, {"level", "1"},
+		{"color", "#ff0000"}, {"x", "1.5"}, {"y", "-2"}, {"z", "0"},
+	}, doc.Graph.Nodes[0].Data)
+	require.Len(t, doc.Graph.Edges, 2)
+	assert.Equal(t, graphMLEdge{ID: "e1", Source: "a", Target: "b", Data: []graphMLData{{"edge_type", "wikilink"}, {"weight", "1"}}}, doc.Graph.Edges[0])
+	assert.Equal(t, "0.5", doc.Graph.Edges[1].Data[1].Value)
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, testGraph(), DOT))
+	assert.Equal(t, `digraph mnemosyne {
+  "a" [label="Say \"hi\" & <go>", file_path="dir\\a.md", type="hub", color="#ff0000", pos="1.5,-2"];
+  "b" [label="Line\nbreak", pos="0,0"];
+  "a" -> "b" [type="wikilink", weight=1];
+  "b" -> "a" [type="embed", weight=0.5];
+}
+`, buf.String())
+}
+
+func TestWriteGEXF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, testGraph(), GEXF))
+
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Graph   struct {
+			Attributes []gexfAttribute `xml:"attributes>attribute"`
+			Nodes      []struct {
+				ID        string         `xml:"id,attr"`
+				Label     string         `xml:"label,attr"`
+				AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+				Position  gexfPosition   `xml:"http://gexf.net/1.3/viz position"`
+			} `xml:"nodes>node"`
+			Edges []gexfEdge `xml:"edges>edge"`
+		} `xml:"graph"`
+	}
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "1.3", doc.Version)
+	assert.Len(t, doc.Graph.Attributes, len(gexfNodeAttributes))
+	require.Len(t, doc.Graph.Nodes, 2)
+	assert.Equal(t, `Say "hi" & <go>`, doc.Graph.Nodes[0].Label)
+	assert.Equal(t, gexfPosition{X: 1.5, Y: -2}, doc.Graph.Nodes[0].Position)
+	assert.Contains(t, doc.Graph.Nodes[0].AttValues, gexfAttValue{"type", "hub"})
+	// Edges without IDs are given one
+	assert.Equal(t, []gexfEdge{
+		{ID: "e1", Source: "a", Target: "b", Label: "wikilink", Weight: 1},
+		{ID: "b->a#1", Source: "b", Target: "a", Label: "embed", Weight: 0.5},
+	}, doc.Graph.Edges)
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	assert.Error(t, Write(&bytes.Buffer{}, testGraph(), "svg"))
+	assert.Empty(t, ContentType("svg"))
+	for _, f := range Formats {
+		assert.NotEmpty(t, ContentType(f), f)
+	}
+}
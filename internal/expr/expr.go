@@ -0,0 +1,624 @@
+// Package expr implements a small expression language for computed node fields.
+// Supported: number, string, true/false/null literals; dotted identifiers
+// (metadata.reviews); arithmetic (+ - * / %); comparison (== != < <= > >=);
+// boolean logic (&& || !); the ternary operator (cond ? a : b); parentheses;
+// and the functions len, lower, upper, contains, and default.
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Env maps top-level identifiers to values. Nested maps are reached with dots.
+type Env map[string]interface{}
+
+// Expr is a compiled expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse compiles an expression.
+func Parse(input string) (*Expr, error) {
+	p := &parser{input: input}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	n, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tok.text, p.tok.pos)
+	}
+	return &Expr{src: input, root: n}, nil
+}
+
+// String returns the source text of the expression.
+func (e *Expr) String() string { return e.src }
+
+// Eval evaluates the expression against env. Missing identifiers evaluate to nil.
+func (e *Expr) Eval(env Env) (interface{}, error) {
+	return e.root.eval(env)
+}
+
+// --- AST ---
+
+type node interface {
+	eval(env Env) (interface{}, error)
+}
+
+type literal struct{ value interface{} }
+
+func (l literal) eval(Env) (interface{}, error) { return l.value, nil }
+
+type ident struct{ path []string }
+
+func (id ident) eval(env Env) (interface{}, error) {
+	var cur interface{} = map[string]interface{}(env)
+	for _, part := range id.path {
+		switch m := cur.(type) {
+		case map[string]interface{}:
+			cur = m[part]
+		case Env:
+			cur = m[part]
+		default:
+			return nil, nil
+		}
+	}
+	return cur, nil
+}
+
+type unary struct {
+	op      string
+	operand node
+}
+
+func (u unary) eval(env Env) (interface{}, error) {
+	v, err := u.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch u.op {
+	case "!":
+		return !truthy(v), nil
+	case "-":
+		f, ok := toNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate %v", v)
+		}
+		return -f, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", u.op)
+}
+
+type binary struct {
+	op          string
+	left, right node
+}
+
+func (b binary) eval(env Env) (interface{}, error) {
+	l, err := b.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit boolean operators.
+	switch b.op {
+	case "&&":
+		if !truthy(l) {
+			return false, nil
+		}
+		r, err := b.right.eval(env)
+		return truthy(r), err
+	case "||":
+		if truthy(l) {
+			return true, nil
+		}
+		r, err := b.right.eval(env)
+		return truthy(r), err
+	}
+
+	r, err := b.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch b.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "<", "<=", ">", ">=":
+		c, ok := compare(l, r)
+		if !ok {
+			return false, nil
+		}
+		switch b.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case "+":
+		lf, lok := toNumber(l)
+		rf, rok := toNumber(r)
+		if lok && rok {
+			return lf + rf, nil
+		}
+		return toString(l) + toString(r), nil
+	case "-", "*", "/", "%":
+		lf, lok := toNumber(l)
+		rf, rok := toNumber(r)
+		if !lok || !rok {
+			return nil, fmt.Errorf("operator %s needs numbers, got %v and %v", b.op, l, r)
+		}
+		switch b.op {
+		case "-":
+			return lf - rf, nil
+		case "*":
+			return lf * rf, nil
+		case "/":
+			if rf == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return lf / rf, nil
+		default:
+			if rf == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return math.Mod(lf, rf), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operator %q", b.op)
+}
+
+type ternary struct {
+	cond, then, els node
+}
+
+func (t ternary) eval(env Env) (interface{}, error) {
+	c, err := t.cond.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(c) {
+		return t.then.eval(env)
+	}
+	return t.els.eval(env)
+}
+
+type call struct {
+	name string
+	args []node
+}
+
+func (c call) eval(env Env) (interface{}, error) {
+	args := make([]interface{}, len(c.args))
+	for i, a := range c.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	switch c.name {
+	case "len":
+		switch v := args[0].(type) {
+		case nil:
+			return float64(0), nil
+		case string:
+			return float64(len([]rune(v))), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		case []string:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("len: unsupported value %v", args[0])
+	case "lower":
+		return strings.ToLower(toString(args[0])), nil
+	case "upper":
+		return strings.ToUpper(toString(args[0])), nil
+	case "contains":
+		switch v := args[0].(type) {
+		case []interface{}:
+			for _, item := range v {
+				if equal(item, args[1]) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case []string:
+			for _, item := range v {
+				if equal(item, args[1]) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case nil:
+			return false, nil
+		}
+		return strings.Contains(toString(args[0]), toString(args[1])), nil
+	case "default":
+		if args[0] == nil || args[0] == "" {
+			return args[1], nil
+		}
+		return args[0], nil
+	}
+	return nil, fmt.Errorf("unknown function %q", c.name)
+}
+
+// functionArity lists the supported functions and their argument counts.
+var functionArity = map[string]int{
+	"len":      1,
+	"lower":    1,
+	"upper":    1,
+	"contains": 2,
+	"default":  2,
+}
+
+// --- Value helpers ---
+
+func truthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case string:
+		return val != ""
+	case []interface{}:
+		return len(val) > 0
+	case []string:
+		return len(val) > 0
+	}
+	if f, ok := toNumber(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if af, ok := toNumber(a); ok {
+		if bf, ok := toNumber(b); ok {
+			return af == bf
+		}
+	}
+	if ab, ok := a.(bool); ok {
+		bb, ok := b.(bool)
+		return ok && ab == bb
+	}
+	return toString(a) == toString(b)
+}
+
+// compare orders two values numerically when both are numbers, otherwise as strings.
+func compare(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if af, ok := toNumber(a); ok {
+		if bf, ok := toNumber(b); ok {
+			switch {
+			case af < bf:
+				return -1, true
+			case af > bf:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return strings.Compare(toString(a), toString(b)), true
+}
+
+// --- Lexer ---
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+// operators is ordered so that two-character operators match first.
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "+", "-", "*", "/", "%", "!", "?", ":", "(", ")", ",", "."}
+
+type parser struct {
+	input string
+	pos   int
+	tok   token
+}
+
+func (p *parser) next() error {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t' || p.input[p.pos] == '\n') {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.input) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return nil
+	}
+
+	ch := p.input[p.pos]
+	switch {
+	case ch >= '0' && ch <= '9':
+		for p.pos < len(p.input) && (isDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		p.tok = token{kind: tokNumber, text: p.input[start:p.pos], pos: start}
+		return nil
+	case ch == '"' || ch == '\'':
+		p.pos++
+		var sb strings.Builder
+		for p.pos < len(p.input) && p.input[p.pos] != ch {
+			if p.input[p.pos] == '\\' && p.pos+1 < len(p.input) {
+				p.pos++
+			}
+			sb.WriteByte(p.input[p.pos])
+			p.pos++
+		}
+		if p.pos >= len(p.input) {
+			return fmt.Errorf("unterminated string at position %d", start)
+		}
+		p.pos++ // closing quote
+		p.tok = token{kind: tokString, text: sb.String(), pos: start}
+		return nil
+	case isIdentStart(ch):
+		for p.pos < len(p.input) && isIdentPart(p.input[p.pos]) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: p.input[start:p.pos], pos: start}
+		return nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(p.input[p.pos:], op) {
+			p.pos += len(op)
+			p.tok = token{kind: tokOp, text: op, pos: start}
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected character %q at position %d", ch, start)
+}
+
+func (p *parser) isOp(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+func (p *parser) expect(op string) error {
+	if !p.isOp(op) {
+		return fmt.Errorf("expected %q at position %d", op, p.tok.pos)
+	}
+	return p.next()
+}
+
+func isDigit(ch byte) bool { return ch >= '0' && ch <= '9' }
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isIdentPart(ch byte) bool { return isIdentStart(ch) || isDigit(ch) }
+
+// --- Parser (lowest to highest precedence) ---
+
+func (p *parser) parseTernary() (node, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("?") {
+		return cond, nil
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	then, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return ternary{cond: cond, then: then, els: els}, nil
+}
+
+// precedence lists binary operator levels from loosest to tightest.
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) parseBinary(level int) (node, error) {
+	if level == len(precedence) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && contains(precedence[level], p.tok.text) {
+		op := p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("!") || p.isOp("-") {
+		op := p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unary{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return literal{f}, p.next()
+	case tokString:
+		return literal{tok.text}, p.next()
+	case tokIdent:
+		return p.parseIdent()
+	case tokOp:
+		if tok.text == "(" {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			n, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+func (p *parser) parseIdent() (node, error) {
+	name := p.tok.text
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "true":
+		return literal{true}, nil
+	case "false":
+		return literal{false}, nil
+	case "null":
+		return literal{nil}, nil
+	}
+
+	if p.isOp("(") {
+		arity, ok := functionArity[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown function %q", name)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		var args []node
+		for !p.isOp(")") {
+			if len(args) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if len(args) != arity {
+			return nil, fmt.Errorf("%s expects %d argument(s), got %d", name, arity, len(args))
+		}
+		return call{name: name, args: args}, nil
+	}
+
+	path := []string{name}
+	for p.isOp(".") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokIdent {
+			return nil, fmt.Errorf("expected field name at position %d", p.tok.pos)
+		}
+		path = append(path, p.tok.text)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	return ident{path: path}, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func eval(t *testing.T, src string, env Env) interface{} {
+	t.Helper()
+	e, err := Parse(src)
+	require.NoError(t, err, src)
+	v, err := e.Eval(env)
+	require.NoError(t, err, src)
+	return v
+}
+
+func TestEval(t *testing.T) {
+	env := Env{
+		"title": "Rust Notes",
+		"tags":  []string{"lang", "systems"},
+		"metadata": map[string]interface{}{
+			"reviews": float64(4),
+			"status":  "draft",
+			"score":   "2.5",
+		},
+	}
+
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{`metadata.reviews >= 3 ? "evergreen" : "seedling"`, "evergreen"},
+		{`metadata.reviews < 3 ? "evergreen" : "seedling"`, "seedling"},
+		{`metadata.score * 2`, float64(5)},
+		{`1 + 2 * 3`, float64(7)},
+		{`(1 + 2) * 3`, float64(9)},
+		{`-metadata.reviews`, float64(-4)},
+		{`title + "!"`, "Rust Notes!"},
+		{`metadata.status == "draft" && !metadata.missing`, true},
+		{`metadata.missing == null`, true},
+		{`metadata.missing || false`, false},
+		{`contains(tags, "lang")`, true},
+		{`len(tags)`, float64(2)},
+		{`lower(title)`, "rust notes"},
+		{`default(metadata.owner, "nobody")`, "nobody"},
+		{`7 % 4`, float64(3)},
+		{`'single'`, "single"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, eval(t, tt.src, env), tt.src)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		``,
+		`1 +`,
+		`a ? b`,
+		`"unterminated`,
+		`nosuch(1)`,
+		`len(1, 2)`,
+		`(1`,
+		`a # b`,
+	} {
+		_, err := Parse(src)
+		assert.Error(t, err, src)
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	e, err := Parse(`1 / 0`)
+	require.NoError(t, err)
+	_, err = e.Eval(nil)
+	assert.Error(t, err)
+
+	e, err = Parse(`"a" - 1`)
+	require.NoError(t, err)
+	_, err = e.Eval(nil)
+	assert.Error(t, err)
+}
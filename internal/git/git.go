@@ -0,0 +1,190 @@
+// Package git runs the git command line against vaults kept in git
+// repositories, and keeps such vaults up to date with their remotes.
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoUpstream is returned for repositories whose branch tracks no remote.
+var ErrNoUpstream = errors.New("branch has no upstream")
+
+// Run runs a git command in dir and returns its trimmed standard output.
+func Run(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := Output(ctx, dir, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// Output runs a git command in dir and returns its standard output. Git
+// never prompts for credentials: without them, the command fails instead of
+// waiting on a terminal no one is watching.
+func Output(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// Manager periodically fetches the remotes of vaults kept in git and
+// fast-forwards them, reporting the files each pull changed.
+type Manager struct {
+	interval time.Duration
+	vaults   map[int]string // Vault ID -> path
+	onUpdate func(vaultID int, changed []string)
+
+	mu     sync.Mutex // Serializes pulls
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a manager polling every interval.
+func NewManager(interval time.Duration) *Manager {
+	return &Manager{
+		interval: interval,
+		vaults:   make(map[int]string),
+	}
+}
+
+// SetOnUpdate sets a callback invoked after a pull changed a vault, with the
+// changed files' paths relative to the vault, including deleted ones.
+func (m *Manager) SetOnUpdate(fn func(vaultID int, changed []string)) {
+	m.onUpdate = fn
+}
+
+// AddVault registers a vault for polling. It fails with ErrNoUpstream if the
+// vault's branch does not track a remote branch, and with another error if
+// the vault is not in a git repository.
+func (m *Manager) AddVault(vaultID int, path string) error {
+	if _, err := Run(context.Background(), path, "rev-parse", "--show-prefix"); err != nil {
+		return err
+	}
+	if _, err := Run(context.Background(), path, "rev-parse", "--verify", "--quiet", "@{upstream}"); err != nil {
+		return ErrNoUpstream
+	}
+	m.vaults[vaultID] = path
+	return nil
+}
+
+// CheckRemote verifies that the remote tracked by the branch checked out at
+// dir answers with the credentials git has, and returns its name. It fails
+// with ErrNoUpstream if the branch tracks no remote.
+func CheckRemote(ctx context.Context, dir string) (string, error) {
+	branch, err := Run(ctx, dir, "symbolic-ref", "--quiet", "HEAD")
+	if err != nil {
+		return "", ErrNoUpstream // Detached HEAD
+	}
+	remote, err := Run(ctx, dir, "for-each-ref", "--format=%(upstream:remotename)", branch)
+	if err != nil {
+		return "", err
+	}
+	if remote == "" {
+		return "", ErrNoUpstream
+	}
+	if _, err := Run(ctx, dir, "ls-remote", "--quiet", "--heads", remote); err != nil {
+		return remote, err
+	}
+	return remote, nil
+}
+
+// Start begins polling in the background.
+func (m *Manager) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.pollAll(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops polling, interrupting a pull in progress, and waits for the
+// background loop to finish.
+func (m *Manager) Stop() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	m.wg.Wait()
+}
+
+func (m *Manager) pollAll(ctx context.Context) {
+	for vaultID, path := range m.vaults {
+		changed, err := m.Pull(ctx, vaultID)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Warning: failed to pull %s: %v", path, err)
+			}
+			continue
+		}
+		if len(changed) > 0 {
+			log.Printf("Pulled %d changed file(s) into %s", len(changed), path)
+			if m.onUpdate != nil {
+				m.onUpdate(vaultID, changed)
+			}
+		}
+	}
+}
+
+// Pull fetches a vault's upstream and fast-forwards to it, returning the
+// vault-relative paths of the files that changed. Local commits are kept: a
+// branch that is ahead is left alone, and one that has diverged fails.
+func (m *Manager) Pull(ctx context.Context, vaultID int) ([]string, error) {
+	path, ok := m.vaults[vaultID]
+	if !ok {
+		return nil, fmt.Errorf("vault %d not registered", vaultID)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := Run(ctx, path, "fetch", "--quiet"); err != nil {
+		return nil, err
+	}
+	head, err := Run(ctx, path, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := Run(ctx, path, "rev-parse", "@{upstream}")
+	if err != nil {
+		return nil, err
+	}
+	if head == upstream {
+		return nil, nil
+	}
+	if _, err := Run(ctx, path, "merge-base", "--is-ancestor", upstream, head); err == nil {
+		return nil, nil // Only local commits are new
+	}
+	if _, err := Run(ctx, path, "merge", "--ff-only", "--quiet", upstream); err != nil {
+		return nil, err
+	}
+
+	out, err := Run(ctx, path, "-c", "core.quotePath=false", "diff", "--name-only", "--relative", head, upstream, "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
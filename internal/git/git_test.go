@@ -0,0 +1,118 @@
+package git
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/mnemosynetest"
+)
+
+// newClones creates a bare remote and two clones of it, the second with its
+// vault in a subdirectory. Returns the first clone and the vault's path.
+func newClones(t *testing.T) (upstream *mnemosynetest.Repo, vault string) {
+	t.Helper()
+	// Pulls may merge, which needs an identity
+	t.Setenv("GIT_AUTHOR_NAME", "test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	upstream, clone := mnemosynetest.NewClones(t, map[string]string{"notes/a.md": "# A\n"})
+	return upstream, filepath.Join(clone.Dir, "notes")
+}
+
+func run(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	_, err := Run(context.Background(), dir, args...)
+	require.NoError(t, err)
+}
+
+func TestPull(t *testing.T) {
+	upstream, vault := newClones(t)
+	m := NewManager(time.Minute)
+	require.NoError(t, m.AddVault(1, vault))
+
+	changed, err := m.Pull(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	upstream.Commit(map[string]string{
+		"notes/new/b.md": "# B\n",
+		"outside.md":     "Not in the vault\n",
+		"notes/a.md":     "",
+	})
+	upstream.Push()
+
+	changed, err = m.Pull(context.Background(), 1)
+	require.NoError(t, err)
+	sort.Strings(changed)
+	assert.Equal(t, []string{"a.md", "new/b.md"}, changed)
+	assert.FileExists(t, filepath.Join(vault, "new", "b.md"))
+
+	// Local commits are kept and not reported
+	mnemosynetest.WriteFiles(t, vault, map[string]string{"local.md": "# Local\n"})
+	run(t, vault, "add", "-A")
+	run(t, vault, "commit", "-q", "-m", "local")
+	changed, err = m.Pull(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+}
+
+func TestPollNotifiesChanges(t *testing.T) {
+	upstream, vault := newClones(t)
+	m := NewManager(20 * time.Millisecond)
+	require.NoError(t, m.AddVault(7, vault))
+	updates := make(chan []string, 1)
+	m.SetOnUpdate(func(vaultID int, changed []string) {
+		assert.Equal(t, 7, vaultID)
+		updates <- changed
+	})
+	m.Start()
+	defer m.Stop()
+
+	upstream.Commit(map[string]string{"notes/a.md": "# A, edited\n"})
+	upstream.Push()
+
+	select {
+	case changed := <-updates:
+		assert.Equal(t, []string{"a.md"}, changed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update after pushing a change")
+	}
+}
+
+func TestAddVaultRequiresUpstream(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	m := NewManager(time.Minute)
+	assert.Error(t, m.AddVault(1, t.TempDir()))
+
+	dir := t.TempDir()
+	run(t, dir, "init", "-q")
+	assert.ErrorIs(t, m.AddVault(1, dir), ErrNoUpstream)
+}
+
+func TestCheckRemote(t *testing.T) {
+	upstream, vault := newClones(t)
+	remote, err := CheckRemote(context.Background(), vault)
+	require.NoError(t, err)
+	assert.Equal(t, "origin", remote)
+
+	// An unreachable remote fails rather than prompting
+	upstream.Git("remote", "set-url", "origin", filepath.Join(t.TempDir(), "missing.git"))
+	_, err = CheckRemote(context.Background(), upstream.Dir)
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	run(t, dir, "init", "-q")
+	_, err = CheckRemote(context.Background(), dir)
+	assert.ErrorIs(t, err, ErrNoUpstream)
+}
@@ -0,0 +1,43 @@
+package indexer
+
+import (
+	"context"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// Export is the graph of a vault as indexing would store it, for writing to
+// a file.
+type Export struct {
+	Vault     string                     `json:"vault"`
+	Nodes     []models.VaultNode         `json:"nodes"`
+	Edges     []models.VaultEdge         `json:"edges"`
+	Positions map[string]models.Position `json:"positions,omitempty"` // By node ID; left to the caller to lay out
+	Errors    []models.ParseFileError    `json:"errors"`              // Files that failed to parse
+	Report    string                     `json:"-"`                   // Markdown summary, as in a parse's report
+}
+
+// Export parses the vault at vaultPath and builds its graph with the
+// manager's settings and hooks, as a full index would, but reads and writes
+// nothing in the store, so m may have been made with a nil one. The vault
+// need not be registered; nodes have no vault ID.
+func (m *IndexManager) Export(ctx context.Context, vaultPath string) (*Export, error) {
+	start := time.Now()
+	graph, parsed, err := m.parseAndBuild(ctx, vaultPath, m.parseOptionsFor(vaultPath), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	// With nothing cached, every node is classified afresh
+	if err := m.classifyChanged(0, graph.Nodes, graph.Edges, parsed); err != nil {
+		return nil, err
+	}
+	m.levelAfterHooks(graph)
+	return &Export{
+		Vault:  vaultPath,
+		Nodes:  graph.Nodes,
+		Edges:  graph.Edges,
+		Errors: parseFileErrors(parsed),
+		Report: parseReport(vaultPath, start, graph, parsed),
+	}, nil
+}
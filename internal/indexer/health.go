@@ -0,0 +1,103 @@
+package indexer
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/vault"
+)
+
+// Points of the health score each problem can cost, at its worst: when every
+// note is orphaned, every link broken, and so on. They add up to 100.
+const (
+	orphanWeight    = 30
+	brokenWeight    = 30
+	duplicateWeight = 20
+	untaggedWeight  = 20
+)
+
+// maxHealthExamples caps the files named in each recommendation.
+const maxHealthExamples = 3
+
+// vaultHealth scores the graph a full index built. Notes are the nodes of
+// markdown files; references and people without notes are not counted.
+func vaultHealth(graph *vault.Graph, parsed *vault.ParseResult) models.VaultHealth {
+	h := models.VaultHealth{ComputedAt: time.Now().UTC(), Links: parsed.Stats.TotalLinks, DuplicateIDs: len(graph.DuplicateIDs)}
+
+	var orphans, untagged []string
+	for _, n := range graph.Nodes {
+		if !strings.HasSuffix(n.FilePath, ".md") {
+			continue
+		}
+		h.Notes++
+		if n.InDegree == 0 && n.OutDegree == 0 {
+			orphans = append(orphans, n.FilePath)
+		}
+		if len(n.Tags) == 0 {
+			untagged = append(untagged, n.FilePath)
+		}
+	}
+	h.Orphans, h.Untagged = len(orphans), len(untagged)
+	h.OrphanRatio = ratio(h.Orphans, h.Notes)
+	h.UntaggedRatio = ratio(h.Untagged, h.Notes)
+
+	var broken []string
+	for _, l := range parsed.UnresolvedLinks {
+		broken = append(broken, fmt.Sprintf("%s → %s", l.SourcePath, l.Link.Target))
+	}
+	h.BrokenLinks = len(broken)
+	h.BrokenLinkRatio = ratio(h.BrokenLinks, h.Links)
+
+	var duplicates []string
+	for _, d := range graph.DuplicateIDs {
+		h.DuplicateFiles += len(d.SkippedPaths)
+		duplicates = append(duplicates, d.SkippedPaths...)
+	}
+
+	recommend := func(metric string, count int, r float64, weight int, examples []string, format string, args ...any) {
+		if count == 0 {
+			return
+		}
+		slices.Sort(examples)
+		h.Recommendations = append(h.Recommendations, models.HealthRecommendation{
+			Metric:   metric,
+			Penalty:  int(math.Round(r * float64(weight))),
+			Message:  fmt.Sprintf(format, args...),
+			Examples: examples[:min(len(examples), maxHealthExamples)],
+		})
+	}
+	recommend("orphans", h.Orphans, h.OrphanRatio, orphanWeight, orphans,
+		"Link the %d orphaned notes (%s of notes) to related notes", h.Orphans, percent(h.OrphanRatio))
+	recommend("broken_links", h.BrokenLinks, h.BrokenLinkRatio, brokenWeight, broken,
+		"Fix or create the targets of the %d broken links (%s of links)", h.BrokenLinks, percent(h.BrokenLinkRatio))
+	recommend("duplicate_ids", h.DuplicateFiles, ratio(h.DuplicateFiles, h.Notes+h.DuplicateFiles), duplicateWeight, duplicates,
+		"Give the %d files that reuse another note's ID (%d IDs in all) IDs of their own; they are left out of the graph", h.DuplicateFiles, h.DuplicateIDs)
+	recommend("untagged", h.Untagged, h.UntaggedRatio, untaggedWeight, untagged,
+		"Tag the %d untagged notes (%s of notes)", h.Untagged, percent(h.UntaggedRatio))
+	slices.SortStableFunc(h.Recommendations, func(a, b models.HealthRecommendation) int { return cmp.Compare(b.Penalty, a.Penalty) })
+
+	h.Score = 100
+	for _, r := range h.Recommendations {
+		h.Score -= r.Penalty
+	}
+	if h.Recommendations == nil {
+		h.Recommendations = []models.HealthRecommendation{}
+	}
+	return h
+}
+
+func ratio(n, of int) float64 {
+	if of == 0 {
+		return 0
+	}
+	return float64(n) / float64(of)
+}
+
+func percent(r float64) string {
+	return fmt.Sprintf("%.0f%%", r*100)
+}
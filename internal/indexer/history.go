@@ -0,0 +1,370 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/git"
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/vault"
+)
+
+var (
+	// ErrNotGitRepository is returned for historical graphs of vaults that
+	// are not inside a git working tree.
+	ErrNotGitRepository = errors.New("vault is not in a git repository")
+	// ErrUnknownCommit is returned when a revision does not name a commit.
+	ErrUnknownCommit = errors.New("unknown commit")
+	// ErrFileNotTracked is returned when blaming a file git does not track.
+	ErrFileNotTracked = errors.New("file is not tracked by git")
+)
+
+// historyCacheSize bounds the historical graphs kept in memory. Each is a
+// full parse of the vault, so only a few are worth keeping.
+const historyCacheSize = 8
+
+// HistoricalGraph is a vault's graph as it was at a past commit. Nodes have
+// the vault's ID but are never stored.
+type HistoricalGraph struct {
+	VaultID     int
+	Commit      string // Full SHA the requested revision resolved to
+	CommittedAt time.Time
+	Nodes       []models.VaultNode
+	Edges       []models.VaultEdge
+}
+
+// historyCache keeps recently computed historical graphs, keyed by vault and
+// commit. Its lock is held while a graph is computed, so concurrent requests
+// for the same commit parse it once and historical parses never overlap.
+type historyCache struct {
+	mu      sync.Mutex
+	entries map[string]*HistoricalGraph
+	order   []string // Keys, least recently used first
+}
+
+func (c *historyCache) get(key string) *HistoricalGraph {
+	g, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.touch(key)
+	return g
+}
+
+func (c *historyCache) put(key string, g *HistoricalGraph) {
+	if c.entries == nil {
+		c.entries = make(map[string]*HistoricalGraph)
+	}
+	c.entries[key] = g
+	c.touch(key)
+	for len(c.order) > historyCacheSize {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+func (c *historyCache) touch(key string) {
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+	c.order = append(c.order, key)
+}
+
+// FlushHistoryCache drops every cached historical graph, returning how many
+// there were. It waits for a historical parse in progress to finish.
+func (m *IndexManager) FlushHistoryCache() int {
+	m.history.mu.Lock()
+	defer m.history.mu.Unlock()
+	n := len(m.history.entries)
+	m.history.entries = nil
+	m.history.order = nil
+	return n
+}
+
+// GraphAtCommit parses a vault as it was at a git revision (a commit SHA or
+// any other name git resolves to a commit). The commit is checked out into a
+// temporary worktree, so the vault's own working tree is left alone. Results
+// are cached by commit. Node timestamps come from the checked-out files and
+// so reflect the checkout, not the commit.
+func (m *IndexManager) GraphAtCommit(ctx context.Context, vaultID int, rev string) (*HistoricalGraph, error) {
+	vs, ok := m.vaults[vaultID]
+	if !ok {
+		return nil, fmt.Errorf("vault %d not registered", vaultID)
+	}
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return nil, ErrUnknownCommit
+	}
+
+	// The vault may be a subdirectory of the repository
+	prefix, err := git.Run(ctx, vs.path, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, ErrNotGitRepository
+	}
+	commit, err := git.Run(ctx, vs.path, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return nil, ErrUnknownCommit
+	}
+
+	m.history.mu.Lock()
+	defer m.history.mu.Unlock()
+
+	key := fmt.Sprintf("%d:%s", vaultID, commit)
+	if g := m.history.get(key); g != nil {
+		return g, nil
+	}
+
+	committed, err := git.Run(ctx, vs.path, "show", "-s", "--format=%cI", commit)
+	if err != nil {
+		return nil, err
+	}
+	committedAt, err := time.Parse(time.RFC3339, committed)
+	if err != nil {
+		return nil, fmt.Errorf("parse commit time %q: %w", committed, err)
+	}
+
+	dir, err := os.MkdirTemp("", "mnemosyne-history-")
+	if err != nil {
+		return nil, fmt.Errorf("create worktree directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := git.Run(ctx, vs.path, "worktree", "add", "--detach", dir, commit); err != nil {
+		return nil, err
+	}
+	defer func() {
+		// Not ctx: the worktree must be removed even if the request was cancelled
+		if _, err := git.Run(context.Background(), vs.path, "worktree", "remove", "--force", dir); err != nil {
+			log.Printf("Warning: failed to remove worktree %s: %v", dir, err)
+		}
+	}()
+
+	graph, _, err := m.parseAndBuild(ctx, filepath.Join(dir, filepath.FromSlash(prefix)), m.parseOptionsFor(vs.path), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range graph.Nodes {
+		graph.Nodes[i].VaultID = vaultID
+	}
+	if len(m.hooks) > 0 {
+		err := vault.RunBeforeStore(m.hooks, graph.Nodes, graph.Edges)
+		var failed vault.FileErrors
+		if err != nil && !errors.As(err, &failed) {
+			return nil, err
+		}
+	}
+	m.levelAfterHooks(graph)
+
+	g := &HistoricalGraph{
+		VaultID:     vaultID,
+		Commit:      commit,
+		CommittedAt: committedAt,
+		Nodes:       graph.Nodes,
+		Edges:       graph.Edges,
+	}
+	m.history.put(key, g)
+	return g, nil
+}
+
+// BlameLine attributes one line of a file to the commit that last changed it.
+// Lines changed since the last commit have no commit or author.
+type BlameLine struct {
+	Line        int        `json:"line"` // 1-based, counting frontmatter
+	Commit      string     `json:"commit,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	AuthorEmail string     `json:"author_email,omitempty"`
+	AuthoredAt  *time.Time `json:"authored_at,omitempty"`
+	Summary     string     `json:"summary,omitempty"` // First line of the commit message
+	Content     string     `json:"content"`
+}
+
+// uncommitted is the commit git blame reports for lines not yet committed.
+const uncommitted = "0000000000000000000000000000000000000000"
+
+// Blame attributes each line of a vault file, given by its vault-relative
+// path, to the commit and author that last changed it. Line content has the
+// vault's redactions applied, as stored note content does.
+func (m *IndexManager) Blame(ctx context.Context, vaultID int, relPath string) ([]BlameLine, error) {
+	vs, ok := m.vaults[vaultID]
+	if !ok {
+		return nil, fmt.Errorf("vault %d not registered", vaultID)
+	}
+	if _, err := git.Run(ctx, vs.path, "rev-parse", "--show-prefix"); err != nil {
+		return nil, ErrNotGitRepository
+	}
+	if _, err := git.Run(ctx, vs.path, "ls-files", "--error-unmatch", "--", relPath); err != nil {
+		return nil, ErrFileNotTracked
+	}
+	out, err := git.Output(ctx, vs.path, "blame", "--porcelain", "--", relPath)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := parseBlame(string(out))
+	if err != nil {
+		return nil, err
+	}
+	// Served like note content, so redacted and without comments
+	content := make([]string, len(lines))
+	for i := range lines {
+		content[i] = lines[i].Content
+	}
+	for i, c := range vault.RedactLines(content, m.parseOptionsFor(vs.path)) {
+		lines[i].Content = c
+	}
+	return lines, nil
+}
+
+// parseBlame parses git blame --porcelain output. Commit details are only
+// given the first time a commit appears, so they are remembered by SHA.
+func parseBlame(out string) ([]BlameLine, error) {
+	commits := make(map[string]*BlameLine)
+	var lines []BlameLine
+	var cur *BlameLine // Details of the commit of the line being read
+	line := 0
+	for _, text := range strings.Split(out, "\n") {
+		if content, ok := strings.CutPrefix(text, "\t"); ok {
+			if cur == nil {
+				return nil, fmt.Errorf("malformed blame output: content before header")
+			}
+			bl := *cur
+			bl.Line = line
+			bl.Content = content
+			if bl.Commit == uncommitted {
+				bl = BlameLine{Line: line, Content: content}
+			}
+			lines = append(lines, bl)
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			// Header: <sha> <original line> <final line> [<group size>]
+			fields := strings.Fields(text)
+			if len(fields) < 3 {
+				continue
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("malformed blame header %q", text)
+			}
+			line = n
+			if cur = commits[fields[0]]; cur == nil {
+				cur = &BlameLine{Commit: fields[0]}
+				commits[fields[0]] = cur
+			}
+			continue
+		}
+		key, value, _ := strings.Cut(text, " ")
+		switch key {
+		case "author":
+			cur.Author = value
+		case "author-mail":
+			cur.AuthorEmail = strings.Trim(value, "<>")
+		case "author-time":
+			if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				t := time.Unix(sec, 0).UTC()
+				cur.AuthoredAt = &t
+			}
+		case "summary":
+			cur.Summary = value
+		}
+	}
+	return lines, nil
+}
+
+// Contributor summarizes one author's commits to a vault. Authors are told
+// apart by email.
+type Contributor struct {
+	Name        string    `json:"name"` // As of the author's latest commit
+	Email       string    `json:"email"`
+	Commits     int       `json:"commits"`
+	FirstCommit time.Time `json:"first_commit"`
+	LastCommit  time.Time `json:"last_commit"`
+	Paths       []string  `json:"-"` // Vault-relative files the author changed, sorted
+}
+
+// Contributors aggregates the authorship of commits touching a vault, or only
+// its subdirectory dir if dir is not empty. Contributors are sorted by commit
+// count, most first.
+func (m *IndexManager) Contributors(ctx context.Context, vaultID int, dir string) ([]Contributor, error) {
+	vs, ok := m.vaults[vaultID]
+	if !ok {
+		return nil, fmt.Errorf("vault %d not registered", vaultID)
+	}
+	if _, err := git.Run(ctx, vs.path, "rev-parse", "--show-prefix"); err != nil {
+		return nil, ErrNotGitRepository
+	}
+	if dir == "" {
+		dir = "."
+	}
+	// Commits are headed by a NUL so they cannot be mistaken for file names
+	out, err := git.Output(ctx, vs.path, "-c", "core.quotePath=false", "log",
+		"--relative", "--name-only", "--format=%x00%aN%x00%aE%x00%at", "--", dir)
+	if err != nil {
+		// A repository without commits has no history to report
+		if _, headErr := git.Run(ctx, vs.path, "rev-parse", "--verify", "--quiet", "HEAD"); headErr != nil {
+			return []Contributor{}, nil
+		}
+		return nil, err
+	}
+	return parseContributors(string(out))
+}
+
+// parseContributors parses git log output in the format Contributors asks for.
+// Commits are listed newest first.
+func parseContributors(out string) ([]Contributor, error) {
+	byEmail := make(map[string]*Contributor)
+	paths := make(map[string]map[string]bool)
+	var cur *Contributor
+	for _, line := range strings.Split(out, "\n") {
+		if header, ok := strings.CutPrefix(line, "\x00"); ok {
+			fields := strings.Split(header, "\x00")
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("malformed log header %q", line)
+			}
+			sec, err := strconv.ParseInt(fields[2], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed commit time %q", fields[2])
+			}
+			at := time.Unix(sec, 0).UTC()
+			email := strings.ToLower(fields[1])
+			if cur = byEmail[email]; cur == nil {
+				cur = &Contributor{Name: fields[0], Email: email, LastCommit: at}
+				byEmail[email] = cur
+				paths[email] = make(map[string]bool)
+			}
+			cur.Commits++
+			cur.FirstCommit = at
+			continue
+		}
+		if line != "" && cur != nil {
+			paths[cur.Email][line] = true
+		}
+	}
+
+	contributors := make([]Contributor, 0, len(byEmail))
+	for email, c := range byEmail {
+		for p := range paths[email] {
+			c.Paths = append(c.Paths, p)
+		}
+		sort.Strings(c.Paths)
+		contributors = append(contributors, *c)
+	}
+	sort.Slice(contributors, func(i, j int) bool {
+		a, b := contributors[i], contributors[j]
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		return a.Email < b.Email
+	})
+	return contributors, nil
+}
@@ -2,9 +2,14 @@
 package indexer
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/ali01/mnemosyne/internal/discovery"
@@ -15,8 +20,35 @@ import (
 
 // IndexManager coordinates indexing across multiple vaults.
 type IndexManager struct {
-	store  *store.Store
-	vaults map[int]*vaultState
+	store          *store.Store
+	vaults         map[int]*vaultState
+	computedFields []vault.ComputedField
+	decayHalfLife  time.Duration
+	normalize      bool
+	hubTypes       []string
+	unlinked       bool
+	retention      int // Full indexes a node may be missing before its positions go; 0 keeps them
+	hooks          []vault.ParserHook
+	parseOptions   vault.ParseOptions
+	vaultOptions   map[string]vault.ParseOptions // By vault path, overriding parseOptions
+	maxWorkers     int
+	memoryBudget   int64
+	cacheKey       string
+	onRename       func(graphIDs []int)
+	onPhase        func(phase models.ParsePhase)
+	onFinish       func(vaultPath string, h models.ParseHistory)
+
+	runMu    sync.Mutex
+	run      *parseRun     // Full index in progress, if any
+	runIdle  *sync.Cond    // Broadcast on runMu when run is cleared
+	queue    []queuedIndex // Full indexes waiting for run, oldest first
+	draining bool          // Whether drainQueue is running
+
+	metricsMu      sync.Mutex
+	refreshing     bool // Whether refreshMetrics is running
+	refreshPending bool // Whether another refresh was requested while it ran
+
+	history historyCache // Graphs parsed at past commits
 }
 
 type vaultState struct {
@@ -32,10 +64,113 @@ type registeredGraph struct {
 
 // NewIndexManager creates a new index manager.
 func NewIndexManager(s *store.Store) *IndexManager {
-	return &IndexManager{
+	m := &IndexManager{
 		store:  s,
 		vaults: make(map[int]*vaultState),
 	}
+	m.runIdle = sync.NewCond(&m.runMu)
+	return m
+}
+
+// SetComputedFields sets the computed metadata fields evaluated for every node
+// on subsequent indexing.
+func (m *IndexManager) SetComputedFields(fields []vault.ComputedField) {
+	m.computedFields = fields
+}
+
+// SetEdgeWeighting sets how edge weights are post-processed on subsequent
+// indexing: decayed by their source note's age with halfLife (zero disables
+// decay), then, if normalize is set, scaled so the heaviest edge weighs 1.
+func (m *IndexManager) SetEdgeWeighting(halfLife time.Duration, normalize bool) {
+	m.decayHalfLife = halfLife
+	m.normalize = normalize
+}
+
+// SetHubTypes sets the node types at the top of the graph, whose notes are at
+// level 1, on subsequent indexing. Nil means vault.DefaultHubTypes.
+func (m *IndexManager) SetHubTypes(types []string) {
+	m.hubTypes = types
+}
+
+// SetUnlinkedMentions sets whether subsequent indexing records, for each
+// note, the notes it names without linking to them.
+func (m *IndexManager) SetUnlinkedMentions(enabled bool) {
+	m.unlinked = enabled
+}
+
+// SetPositionRetention sets how many full indexes of a vault in a row may
+// miss a node before its positions in the vault's graphs are deleted, at the
+// end of the last one. Zero keeps them until purged by hand.
+func (m *IndexManager) SetPositionRetention(parses int) {
+	m.retention = parses
+}
+
+// SetParseOptions sets the markdown parsing options used on subsequent indexing.
+func (m *IndexManager) SetParseOptions(opts vault.ParseOptions) {
+	m.parseOptions = opts
+}
+
+// SetVaultParseOptions sets the markdown parsing options used on subsequent
+// indexing of the vault at vaultPath, in place of those set by
+// SetParseOptions.
+func (m *IndexManager) SetVaultParseOptions(vaultPath string, opts vault.ParseOptions) {
+	if m.vaultOptions == nil {
+		m.vaultOptions = make(map[string]vault.ParseOptions)
+	}
+	m.vaultOptions[vaultPath] = opts
+}
+
+// parseOptionsFor returns the parsing options of the vault at vaultPath.
+func (m *IndexManager) parseOptionsFor(vaultPath string) vault.ParseOptions {
+	if opts, ok := m.vaultOptions[vaultPath]; ok {
+		return opts
+	}
+	return m.parseOptions
+}
+
+// SetMaxWorkers caps the parser's adaptive worker pool. Zero uses the parser default.
+func (m *IndexManager) SetMaxWorkers(n int) {
+	m.maxWorkers = n
+}
+
+// SetMemoryBudget limits the heap, in bytes, while parsing a vault. A full
+// index that would exceed it fails with vault.ErrMemoryBudgetExceeded. Zero
+// disables the limit.
+func (m *IndexManager) SetMemoryBudget(bytes int64) {
+	m.memoryBudget = bytes
+}
+
+// SetCacheKey identifies the settings that shape parsing and classification
+// (parse options, computed fields, scripts). Files cached under a different
+// key are parsed and classified again, so the key must change whenever those
+// settings do.
+func (m *IndexManager) SetCacheKey(key string) {
+	m.cacheKey = key
+}
+
+// SetOnRename sets a callback invoked after renamed nodes' positions and ACLs
+// move to their new IDs, with the graphs whose positions changed.
+func (m *IndexManager) SetOnRename(fn func(graphIDs []int)) {
+	m.onRename = fn
+}
+
+// SetOnPhase sets a callback invoked as each phase of a full index starts,
+// and with "" when the index ends, e.g. to measure phases. It runs on the
+// indexing goroutine, so it should return quickly.
+func (m *IndexManager) SetOnPhase(fn func(phase models.ParsePhase)) {
+	m.onPhase = fn
+}
+
+// SetOnFinish registers a callback invoked with the final record of each
+// full index once it is saved, whether it completed, failed or was
+// cancelled. It runs on the indexing goroutine, so it should not block.
+func (m *IndexManager) SetOnFinish(fn func(vaultPath string, h models.ParseHistory)) {
+	m.onFinish = fn
+}
+
+// AddHook registers a parser hook that runs on every subsequent index.
+func (m *IndexManager) AddHook(h vault.ParserHook) {
+	m.hooks = append(m.hooks, h)
 }
 
 // RegisterVault discovers graphs and registers a vault for indexing.
@@ -87,19 +222,51 @@ func (m *IndexManager) RegisterVault(vaultPath string) (int, []int, error) {
 }
 
 // FullIndexVault parses an entire vault and replaces its data in the database.
+// Progress is reported by ParseStatus and each run is recorded in the parse
+// history. Only one full index runs at a time; others fail with ErrIndexRunning.
 func (m *IndexManager) FullIndexVault(vaultID int) error {
+	return m.FullIndexVaultContext(context.Background(), vaultID)
+}
+
+// FullIndexVaultContext is FullIndexVault with cancellation. When ctx is done,
+// parsing, building, and storing stop promptly, the vault's previous data is
+// kept, and the run is recorded as cancelled.
+func (m *IndexManager) FullIndexVaultContext(ctx context.Context, vaultID int) (err error) {
 	vs, ok := m.vaults[vaultID]
 	if !ok {
 		return fmt.Errorf("vault %d not registered", vaultID)
 	}
 
+	run, err := m.startRun(vaultID)
+	if err != nil {
+		return err
+	}
+	defer func() { m.finishRun(run, err) }()
+	// The run logs to its own logger, so its parse logs hold only its lines
+	logs := &parseLog{}
+	logger := slog.New(logs)
+	defer func() {
+		if err := m.store.SaveParseLogs(run.history.ID, logs.finish()); err != nil {
+			log.Printf("Warning: failed to save parse logs for %s: %v", vs.path, err)
+		}
+	}()
+
 	start := time.Now()
-	log.Printf("Starting full index of %s", vs.path)
+	logger.Info("Starting full index of " + vs.path)
 
-	graph, err := parseAndBuild(vs.path)
+	// Vaults are read in place; the pull phase is a no-op until a vault
+	// source needs fetching.
+	run.begin(models.ParsePhasePull)
+
+	cache, err := m.store.GetFileCache(vaultID)
+	if err != nil {
+		return fmt.Errorf("load file cache: %w", err)
+	}
+	graph, parsed, err := m.parseAndBuild(ctx, vs.path, m.parseOptionsFor(vs.path), cache, run, logger)
 	if err != nil {
 		return err
 	}
+	run.counts(len(graph.Nodes), len(graph.Edges))
 
 	// Set vault_id on all nodes
 	for i := range graph.Nodes {
@@ -109,23 +276,70 @@ func (m *IndexManager) FullIndexVault(vaultID int) error {
 	// Compute graph memberships
 	memberships := computeMemberships(vs.graphs, graph.Nodes)
 
-	if err := m.store.ReplaceVaultData(vaultID, graph.Nodes, graph.Edges, memberships); err != nil {
+	run.begin(models.ParsePhaseClassify)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := m.classifyChanged(vaultID, graph.Nodes, graph.Edges, parsed); err != nil {
+		return err
+	}
+	m.levelAfterHooks(graph)
+	if err := m.store.SaveParseErrors(run.history.ID, parseFileErrors(parsed)); err != nil {
+		logger.Warn(fmt.Sprintf("failed to save parse errors for %s: %v", vs.path, err))
+	}
+
+	run.begin(models.ParsePhaseStore)
+	if err := m.store.ReplaceVaultDataContext(ctx, vaultID, graph.Nodes, graph.Edges, memberships); err != nil {
 		return fmt.Errorf("store vault data: %w", err)
 	}
+	// Degrees come from the parse; centrality needs the stored edges of
+	// every vault
+	if _, err := m.store.RecomputeMetrics(ctx); err != nil {
+		logger.Warn(fmt.Sprintf("failed to compute node metrics for %s: %v", vs.path, err))
+	}
+	if err := m.store.SaveParseReport(run.history.ID, parseReport(vs.path, start, graph, parsed)); err != nil {
+		logger.Warn(fmt.Sprintf("failed to save parse report for %s: %v", vs.path, err))
+	}
+	health := vaultHealth(graph, parsed)
+	health.VaultID, health.ParseID = vaultID, run.history.ID
+	if err := m.store.SaveVaultHealth(health); err != nil {
+		logger.Warn(fmt.Sprintf("failed to save health of %s: %v", vs.path, err))
+	}
+	// Written after the data so the cache never describes files newer than
+	// their stored nodes
+	if err := m.store.ReplaceFileCache(vaultID, parsed.Cache); err != nil {
+		logger.Warn(fmt.Sprintf("failed to save file cache for %s: %v", vs.path, err))
+	}
+	m.migrateRenames(detectRenames(cache, parsed.Cache))
+	// After renames, so moved positions are not counted absent
+	if err := m.store.AgeAbsentPositions(ctx, vaultID); err != nil {
+		logger.Warn(fmt.Sprintf("failed to age positions of missing nodes in %s: %v", vs.path, err))
+	} else if m.retention > 0 {
+		if n, err := m.store.PurgeAbsentPositions(ctx, m.retention); err != nil {
+			logger.Warn(fmt.Sprintf("failed to purge positions of missing nodes: %v", err))
+		} else if n > 0 {
+			logger.Info(fmt.Sprintf("Purged %d positions of nodes missing from %d full indexes", n, m.retention))
+		}
+	}
 
 	if err := m.store.SetMetadata(fmt.Sprintf("last_index_vault_%d", vaultID), time.Now().Format(time.RFC3339)); err != nil {
 		return fmt.Errorf("set metadata: %w", err)
 	}
 
-	log.Printf("Full index of %s completed in %v: %d nodes, %d edges, %d graphs",
-		vs.path, time.Since(start), len(graph.Nodes), len(graph.Edges), len(vs.graphs))
+	logger.Info(fmt.Sprintf("Full index of %s completed in %v: %d nodes, %d edges, %d graphs",
+		vs.path, time.Since(start), len(graph.Nodes), len(graph.Edges), len(vs.graphs)))
 	return nil
 }
 
 // FullIndexAll indexes all registered vaults.
 func (m *IndexManager) FullIndexAll() error {
+	return m.FullIndexAllContext(context.Background())
+}
+
+// FullIndexAllContext is FullIndexAll with cancellation.
+func (m *IndexManager) FullIndexAllContext(ctx context.Context) error {
 	for vaultID := range m.vaults {
-		if err := m.FullIndexVault(vaultID); err != nil {
+		if err := m.FullIndexVaultContext(ctx, vaultID); err != nil {
 			return err
 		}
 	}
@@ -141,7 +355,11 @@ func (m *IndexManager) IndexFile(vaultID int, relPath string) ([]int, error) {
 
 	log.Printf("Incremental index: %s (vault %d)", relPath, vaultID)
 
-	graph, err := parseAndBuild(vs.path)
+	cache, err := m.store.GetFileCache(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("load file cache: %w", err)
+	}
+	graph, parsed, err := m.parseAndBuild(context.Background(), vs.path, m.parseOptionsFor(vs.path), cache, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -158,18 +376,49 @@ func (m *IndexManager) IndexFile(vaultID int, relPath string) ([]int, error) {
 	}
 
 	node.VaultID = vaultID
+	var edges []models.VaultEdge
+	for _, e := range graph.Edges {
+		if e.SourceID == node.ID {
+			edges = append(edges, e)
+		}
+	}
+
+	nodes := []models.VaultNode{*node}
+	if err := m.classifyChanged(vaultID, nodes, edges, parsed); err != nil {
+		return nil, err
+	}
+	node = &nodes[0]
+
 	if err := m.store.UpsertNode(node); err != nil {
 		return nil, fmt.Errorf("upsert node: %w", err)
 	}
+	// Only this file's node was stored, so only its cache entry may advance
+	for _, f := range parsed.Cache {
+		if f.Path == relPath {
+			if err := m.store.UpsertCachedFile(vaultID, f); err != nil {
+				log.Printf("Warning: failed to cache %s: %v", relPath, err)
+			}
+			break
+		}
+	}
+	// A new file may be a renamed one; renames of other files wait for their own events
+	renames := detectRenames(cache, parsed.Cache)
+	for oldID, newID := range renames {
+		if newID != node.ID {
+			delete(renames, oldID)
+		}
+	}
+	m.migrateRenames(renames)
 
 	if err := m.store.DeleteEdgesBySource(node.ID); err != nil {
 		return nil, fmt.Errorf("delete old edges: %w", err)
 	}
-	for _, e := range graph.Edges {
-		if e.SourceID == node.ID {
-			if err := m.store.UpsertEdge(&e); err != nil {
-				return nil, fmt.Errorf("upsert edge: %w", err)
-			}
+	if err := m.storeNewTargets(vs, graph.Nodes, edges); err != nil {
+		return nil, err
+	}
+	for _, e := range edges {
+		if err := m.store.UpsertEdge(&e); err != nil {
+			return nil, fmt.Errorf("upsert edge: %w", err)
 		}
 	}
 
@@ -183,10 +432,46 @@ func (m *IndexManager) IndexFile(vaultID int, relPath string) ([]int, error) {
 	if err := m.store.ReplaceGraphMemberships(node.ID, affectedGraphIDs); err != nil {
 		return nil, fmt.Errorf("update memberships: %w", err)
 	}
+	m.scheduleMetricsRefresh()
 
 	return affectedGraphIDs, nil
 }
 
+// storeNewTargets stores the targets of edges that are not stored yet. Only
+// nodes no file backs can be missing, e.g. a person first mentioned in the
+// file being indexed; they would otherwise wait for the next full index.
+func (m *IndexManager) storeNewTargets(vs *vaultState, nodes []models.VaultNode, edges []models.VaultEdge) error {
+	byID := make(map[string]*models.VaultNode, len(nodes))
+	for i := range nodes {
+		byID[nodes[i].ID] = &nodes[i]
+	}
+	for _, e := range edges {
+		target, ok := byID[e.TargetID]
+		if !ok {
+			continue
+		}
+		if _, err := m.store.GetNode(target.ID); err == nil {
+			continue
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("load edge target: %w", err)
+		}
+		target.VaultID = vs.id
+		if err := m.store.UpsertNode(target); err != nil {
+			return fmt.Errorf("upsert edge target: %w", err)
+		}
+		var graphIDs []int
+		for _, g := range vs.graphs {
+			if discovery.IsUnderPath(target.FilePath, g.rootPath) {
+				graphIDs = append(graphIDs, g.id)
+			}
+		}
+		if err := m.store.ReplaceGraphMemberships(target.ID, graphIDs); err != nil {
+			return fmt.Errorf("update memberships: %w", err)
+		}
+	}
+	return nil
+}
+
 // RemoveFile removes a node by file path. Returns affected graph IDs.
 func (m *IndexManager) RemoveFile(vaultID int, relPath string) ([]int, error) {
 	vs, ok := m.vaults[vaultID]
@@ -215,6 +500,7 @@ func (m *IndexManager) RemoveFile(vaultID int, relPath string) ([]int, error) {
 	if err := m.store.DeleteNode(node.ID); err != nil {
 		return nil, fmt.Errorf("delete node: %w", err)
 	}
+	m.scheduleMetricsRefresh()
 
 	return affectedGraphIDs, nil
 }
@@ -317,22 +603,103 @@ func computeMemberships(graphs []registeredGraph, nodes []models.VaultNode) map[
 	return memberships
 }
 
-// parseAndBuild runs the vault parser and graph builder.
-func parseAndBuild(vaultPath string) (*vault.Graph, error) {
-	parser := vault.NewParser(vaultPath, 4, 100)
-	parseResult, err := parser.ParseVault()
+// parseAndBuild runs the vault parser with opts and the graph builder,
+// reporting progress to run if it is non-nil and logging to logger, or the
+// standard logger if it is nil. Files unchanged since they were cached are not
+// parsed again.
+func (m *IndexManager) parseAndBuild(ctx context.Context, vaultPath string, opts vault.ParseOptions, cache map[string]models.CachedFile, run *parseRun, logger *slog.Logger) (*vault.Graph, *vault.ParseResult, error) {
+	run.begin(models.ParsePhaseParse)
+	parser := vault.NewParser(vaultPath, 0, 100)
+	parser.SetFileCache(m.cacheKey, cache)
+	parser.SetMaxConcurrency(m.maxWorkers)
+	parser.SetMemoryBudget(m.memoryBudget)
+	parser.SetHooks(m.hooks)
+	parser.SetOptions(opts)
+	parser.SetLogger(logger)
+	if run != nil {
+		parser.SetProgressFunc(run.files)
+	}
+	parseResult, err := parser.ParseVaultContext(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("parse vault: %w", err)
+		return nil, nil, fmt.Errorf("parse vault: %w", err)
 	}
+	run.cached(parseResult.Stats.CachedFiles)
 
+	run.begin(models.ParsePhaseBuild)
 	builder := vault.NewGraphBuilder(vault.GraphBuilderConfig{
-		DefaultWeight: 1.0,
-		SkipOrphans:   false,
+		DefaultWeight:    1.0,
+		SkipOrphans:      false,
+		DecayHalfLife:    m.decayHalfLife,
+		NormalizeWeights: m.normalize,
+		ComputedFields:   m.computedFields,
+		HubTypes:         m.hubTypes,
+		UnlinkedMentions: m.unlinked,
+		Hooks:            m.hooks,
+		Logger:           logger,
 	})
-	graph, err := builder.BuildGraph(parseResult)
+	graph, err := builder.BuildGraphContext(ctx, parseResult)
 	if err != nil {
-		return nil, fmt.Errorf("build graph: %w", err)
+		return nil, nil, fmt.Errorf("build graph: %w", err)
+	}
+
+	return graph, parseResult, nil
+}
+
+// levelAfterHooks assigns node levels again once before-store hooks have run,
+// since they may have changed which notes are hubs. Incremental indexes and
+// Reclassify leave levels as they are until the next full index.
+func (m *IndexManager) levelAfterHooks(graph *vault.Graph) {
+	if len(m.hooks) > 0 {
+		vault.AssignLevels(graph.Nodes, graph.Edges, m.hubTypes)
+	}
+}
+
+// classifyChanged runs before-store hooks on nodes whose files changed since
+// they were cached. Nodes of unchanged files keep the type and metadata the
+// hooks gave them when they were stored. Files the hooks failed for are
+// added to parsed's errors.
+func (m *IndexManager) classifyChanged(vaultID int, nodes []models.VaultNode, edges []models.VaultEdge, parsed *vault.ParseResult) error {
+	unchanged := parsed.Unchanged
+	if len(m.hooks) == 0 {
+		return nil
 	}
 
-	return graph, nil
+	stored := make(map[string]models.VaultNode)
+	if len(unchanged) > 0 {
+		existing, err := m.store.GetNodesByVault(vaultID)
+		if err != nil {
+			return fmt.Errorf("load stored nodes: %w", err)
+		}
+		for _, n := range existing {
+			if unchanged[n.FilePath] {
+				stored[n.FilePath] = n
+			}
+		}
+	}
+
+	var pending []models.VaultNode
+	var pendingIdx []int
+	for i := range nodes {
+		if prev, ok := stored[nodes[i].FilePath]; ok && prev.ID == nodes[i].ID {
+			nodes[i].NodeType = prev.NodeType
+			nodes[i].Metadata = prev.Metadata
+			continue
+		}
+		pending = append(pending, nodes[i])
+		pendingIdx = append(pendingIdx, i)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	err := vault.RunBeforeStore(m.hooks, pending, edges)
+	var failed vault.FileErrors
+	if errors.As(err, &failed) {
+		parsed.ParseErrors = append(parsed.ParseErrors, failed...)
+	} else if err != nil {
+		return err
+	}
+	for j, i := range pendingIdx {
+		nodes[i] = pending[j]
+	}
+	return nil
 }
@@ -1,12 +1,26 @@
 package indexer
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/ali01/mnemosyne/internal/git"
 	"github.com/ali01/mnemosyne/internal/models"
 	"github.com/ali01/mnemosyne/internal/store"
+	"github.com/ali01/mnemosyne/internal/vault"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -53,6 +67,22 @@ func TestFullIndexVault(t *testing.T) {
 	t.Logf("Indexed %d nodes, %d edges", len(graph.Nodes), len(graph.Edges))
 }
 
+func TestFullIndexVaultStoresExcerpt(t *testing.T) {
+	m, s := newTestManager(t)
+
+	dir := t.TempDir()
+	copyVault(t, sampleVault, dir)
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+
+	vaultID, _, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	index, err := s.GetNode("index-main")
+	require.NoError(t, err)
+	assert.Equal(t, "Network ~AI", index.Excerpt)
+}
+
 func TestFullIndexVaultIdempotent(t *testing.T) {
 	m, s := newTestManager(t)
 
@@ -160,6 +190,278 @@ func TestIndexFile(t *testing.T) {
 	assert.Len(t, g.Nodes, 2)
 }
 
+func TestIndexFileNewMention(t *testing.T) {
+	m, s := newTestManager(t)
+	mentions, err := vault.NewMentionExtractor("", "")
+	require.NoError(t, err)
+	m.SetParseOptions(vault.ParseOptions{Mentions: mentions})
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\n# A\n")
+
+	vaultID, graphIDs, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	// The first mention of a person creates their node with the edge
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\n# A\nMet @ana.\n")
+	_, err = m.IndexFile(vaultID, "a.md")
+	require.NoError(t, err)
+
+	g, err := s.GetGraphData(graphIDs[0])
+	require.NoError(t, err)
+	assert.Len(t, g.Nodes, 2)
+	require.Len(t, g.Edges, 1)
+	assert.Equal(t, "person:ana", g.Edges[0].Target)
+	assert.Equal(t, vault.MentionLinkType, g.Edges[0].Type)
+}
+
+func TestIndexFileRefreshesMetrics(t *testing.T) {
+	m, s := newTestManager(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\n# A\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\n---\n# B\n")
+
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+	a, err := s.GetNode("a")
+	require.NoError(t, err)
+	assert.InDelta(t, 1, a.Centrality, 1e-9) // Unlinked nodes rank equally
+
+	// Linking a to b refreshes b's degree and both centralities in the background
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\n# A\n[[b]]\n")
+	_, err = m.IndexFile(vaultID, "a.md")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		b, err := s.GetNode("b")
+		return err == nil && b.InDegree == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		a, err := s.GetNode("a")
+		return err == nil && a.Centrality < 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestIndexStoresBrokenLinks(t *testing.T) {
+	m, s := newTestManager(t)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\n[[missing]] and [[missing]]\n")
+
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+	links, err := s.GetBrokenLinks()
+	require.NoError(t, err)
+	assert.Equal(t, []models.BrokenLink{{Target: "missing", NodeIDs: []string{"a"}}}, links)
+
+	// Fixing the link clears it
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\nNo links.\n")
+	_, err = m.IndexFile(vaultID, "a.md")
+	require.NoError(t, err)
+	links, err = s.GetBrokenLinks()
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
+
+func TestComputedFieldsStored(t *testing.T) {
+	m, s := newTestManager(t)
+	fields, err := vault.CompileComputedFields(map[string]string{"long": "word_count > 2"})
+	require.NoError(t, err)
+	m.SetComputedFields(fields)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\none two three four\n")
+
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	n, err := s.GetNode("a")
+	require.NoError(t, err)
+	assert.Equal(t, true, n.Metadata["long"])
+}
+
+type recordingHook struct {
+	vault.NopHook
+	mu     sync.Mutex
+	parsed int
+	built  int
+	stored int // Nodes passed to OnBeforeStore
+}
+
+func (h *recordingHook) OnFileParsed(*vault.MarkdownFile) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.parsed++
+	return nil
+}
+
+func (h *recordingHook) OnGraphBuilt(*vault.Graph) error {
+	h.built++
+	return nil
+}
+
+func (h *recordingHook) OnBeforeStore(nodes []models.VaultNode, _ []models.VaultEdge) error {
+	h.stored += len(nodes)
+	for i := range nodes {
+		if nodes[i].Metadata == nil {
+			nodes[i].Metadata = models.JSONMetadata{}
+		}
+		nodes[i].Metadata["hooked"] = true
+	}
+	return nil
+}
+
+func TestParserHooks(t *testing.T) {
+	m, s := newTestManager(t)
+	hook := &recordingHook{}
+	m.AddHook(hook)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\n# A\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\n---\n# B\n")
+
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	assert.Equal(t, 2, hook.parsed)
+	assert.Equal(t, 1, hook.built)
+	n, err := s.GetNode("a")
+	require.NoError(t, err)
+	assert.Equal(t, true, n.Metadata["hooked"])
+
+	// Incremental indexing runs the hooks too
+	writeFile(t, filepath.Join(dir, "c.md"), "---\nid: c\n---\n# C\n")
+	_, err = m.IndexFile(vaultID, "c.md")
+	require.NoError(t, err)
+	n, err = s.GetNode("c")
+	require.NoError(t, err)
+	assert.Equal(t, true, n.Metadata["hooked"])
+}
+
+// failingHook fails for the files in paths, and stores the rest.
+type failingHook struct {
+	vault.NopHook
+	paths map[string]bool
+}
+
+func (h *failingHook) OnBeforeStore(nodes []models.VaultNode, _ []models.VaultEdge) error {
+	var failed vault.FileErrors
+	for _, n := range nodes {
+		if h.paths[n.FilePath] {
+			failed = append(failed, vault.ParseError{FilePath: n.FilePath, Kind: vault.ParseErrorHook, Error: errors.New("bad value")})
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+func TestBeforeStoreFileErrors(t *testing.T) {
+	m, s := newTestManager(t)
+	m.AddHook(&failingHook{paths: map[string]bool{"b.md": true}})
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\n# A\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\n---\n# B\n")
+
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID), "a hook failing for one file does not abort the index")
+
+	_, err := s.GetNode("b")
+	require.NoError(t, err)
+	history, err := s.GetParseHistory(vaultID, "", 1)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	errs, err := s.GetParseErrors(history[0].ID, vault.ParseErrorHook)
+	require.NoError(t, err)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "b.md", errs[0].FilePath)
+}
+
+func TestCustomLinkExtractors(t *testing.T) {
+	m, s := newTestManager(t)
+	ex, err := vault.NewLinkExtractor(`@(\w+)`, "mention", "people/$1")
+	require.NoError(t, err)
+	m.SetParseOptions(vault.ParseOptions{LinkExtractors: []vault.LinkExtractor{*ex}})
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\nLunch with @alice.\n")
+	writeFile(t, filepath.Join(dir, "people", "alice.md"), "---\nid: alice\n---\n# Alice\n")
+
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	edges, err := s.GetAllEdges()
+	require.NoError(t, err)
+	require.Len(t, edges, 1)
+	assert.Equal(t, "a", edges[0].SourceID)
+	assert.Equal(t, "alice", edges[0].TargetID)
+	assert.Equal(t, "mention", edges[0].EdgeType)
+}
+
+func TestIDRulesReduceSkippedFiles(t *testing.T) {
+	m, s := newTestManager(t)
+	rule, err := vault.NewIDRule(`^(\d{12}) `, "")
+	require.NoError(t, err)
+	m.SetParseOptions(vault.ParseOptions{IDRules: []vault.IDRule{*rule}})
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "202301151230 First.md"), "Links to [[202301151231 Second]].\n")
+	writeFile(t, filepath.Join(dir, "202301151231 Second.md"), "# Second\n")
+
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	n, err := s.GetNode("202301151230")
+	require.NoError(t, err)
+	assert.Equal(t, "202301151230 First", n.Title)
+
+	edges, err := s.GetAllEdges()
+	require.NoError(t, err)
+	require.Len(t, edges, 1)
+	assert.Equal(t, "202301151231", edges[0].TargetID)
+}
+
+func TestVaultParseOptions(t *testing.T) {
+	m, s := newTestManager(t)
+
+	slugged := t.TempDir()
+	writeFile(t, filepath.Join(slugged, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(slugged, "Café Ørsted.md"), "Links to [[Moskva]].\n")
+	writeFile(t, filepath.Join(slugged, "Москва.md"), "# Moscow\n")
+	m.SetVaultParseOptions(slugged, vault.ParseOptions{Slugger: &vault.Slugger{}, SlugIDs: true})
+
+	// Other vaults keep the default options, under which these files have no ID
+	plain := t.TempDir()
+	writeFile(t, filepath.Join(plain, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(plain, "Plain Note.md"), "# Plain\n")
+
+	for _, dir := range []string{slugged, plain} {
+		vaultID, _, err := m.RegisterVault(dir)
+		require.NoError(t, err)
+		require.NoError(t, m.FullIndexVault(vaultID))
+	}
+
+	n, err := s.GetNode("cafe-orsted")
+	require.NoError(t, err)
+	assert.Equal(t, "Café Ørsted", n.Title)
+	_, err = s.GetNode("plain-note")
+	assert.Error(t, err)
+
+	edges, err := s.GetAllEdges()
+	require.NoError(t, err)
+	require.Len(t, edges, 1)
+	assert.Equal(t, "cafe-orsted", edges[0].SourceID)
+	assert.Equal(t, "moskva", edges[0].TargetID)
+}
+
 func TestRemoveFile(t *testing.T) {
 	m, s := newTestManager(t)
 
@@ -283,3 +585,764 @@ func copyVault(t *testing.T, src, dst string) {
 	})
 	require.NoError(t, err)
 }
+
+func TestFullIndexRecordsParseHistory(t *testing.T) {
+	m, s := newTestManager(t)
+
+	dir := t.TempDir()
+	copyVault(t, sampleVault, dir)
+	vaultID, _, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+	var finished []models.ParseHistory
+	m.SetOnFinish(func(vaultPath string, h models.ParseHistory) {
+		assert.Equal(t, dir, vaultPath)
+		finished = append(finished, h)
+	})
+
+	status, err := m.ParseStatus()
+	require.NoError(t, err)
+	assert.Equal(t, "idle", status.Status)
+
+	require.NoError(t, m.FullIndexVault(vaultID))
+	require.Len(t, finished, 1)
+	assert.Equal(t, models.ParseStatusCompleted, finished[0].Status)
+
+	status, err = m.ParseStatus()
+	require.NoError(t, err)
+	assert.Equal(t, "completed", status.Status)
+	assert.Equal(t, vaultID, status.VaultID)
+
+	history, err := s.GetParseHistory(vaultID, models.ParseStatusCompleted, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	stats := history[0].Stats
+	assert.Greater(t, stats.TotalFiles, 0)
+	assert.Equal(t, stats.TotalFiles, stats.ParsedFiles)
+	assert.Greater(t, stats.TotalNodes, 0)
+	for _, phase := range models.ParsePhases {
+		assert.Contains(t, stats.PhaseDurations, phase)
+	}
+
+	report, err := s.GetParseReport(history[0].ID)
+	require.NoError(t, err)
+	assert.Contains(t, report, "# Parse report: "+dir)
+	assert.Contains(t, report, fmt.Sprintf("| Nodes | %d |", stats.TotalNodes))
+	assert.Contains(t, report, "## Unresolved links")
+
+	// A second full index is refused while one is running
+	m.run = newParseRun(vaultID, nil)
+	assert.ErrorIs(t, m.FullIndexVault(vaultID), ErrIndexRunning)
+}
+
+func TestFullIndexRecordsParseErrors(t *testing.T) {
+	m, s := newTestManager(t)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\n# A\n")
+	writeFile(t, filepath.Join(dir, "bad.md"), "---\nid: bad\ntags: x: y\n---\n")
+	writeFile(t, filepath.Join(dir, "latin1.md"), "---\nid: latin1\n---\nCaf\xe9\n")
+
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	history, err := s.GetParseHistory(vaultID, "", 1)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	errs, err := s.GetParseErrors(history[0].ID, "")
+	require.NoError(t, err)
+	require.Len(t, errs, 2)
+	assert.Equal(t, "bad.md", errs[0].FilePath)
+	assert.Equal(t, vault.ParseErrorFrontmatter, errs[0].Kind)
+	assert.Equal(t, 3, errs[0].Line)
+	assert.Contains(t, errs[0].Message, "frontmatter")
+	assert.Equal(t, "latin1.md", errs[1].FilePath)
+	assert.Equal(t, vault.ParseErrorEncoding, errs[1].Kind)
+	assert.Equal(t, 4, errs[1].Line)
+
+	// Files with encoding errors are still indexed
+	n, err := s.GetNode("latin1")
+	require.NoError(t, err)
+	assert.Contains(t, n.Content, "Caf\uFFFD")
+}
+
+func TestFullIndexPurgesAbsentPositions(t *testing.T) {
+	m, s := newTestManager(t)
+	m.SetPositionRetention(2)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\nA\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\n---\nB\n")
+	vaultID, graphIDs, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+	require.NoError(t, m.FullIndexVault(vaultID))
+	require.NoError(t, s.UpsertPositions(graphIDs[0], []models.NodePosition{{NodeID: "a", X: 1}, {NodeID: "b", X: 2}}))
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "b.md")))
+	require.NoError(t, m.FullIndexVault(vaultID))
+	count, err := s.GetPositionCount(graphIDs[0])
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "kept while b has missed one index")
+
+	require.NoError(t, m.FullIndexVault(vaultID))
+	positions, err := s.GetPositionsByGraph(graphIDs[0])
+	require.NoError(t, err)
+	assert.Len(t, positions, 1)
+	assert.Contains(t, positions, "a")
+}
+
+func TestFullIndexRecordsHealth(t *testing.T) {
+	m, s := newTestManager(t)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\ntags: [x]\n---\n[[b]] and [[missing]]\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\n---\nUntagged.\n")
+	writeFile(t, filepath.Join(dir, "c.md"), "---\nid: c\ntags: [y]\n---\nAlone.\n")
+
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	h, err := s.GetVaultHealth(vaultID)
+	require.NoError(t, err)
+	require.NotNil(t, h)
+	history, err := s.GetParseHistory(vaultID, "", 1)
+	require.NoError(t, err)
+	assert.Equal(t, history[0].ID, h.ParseID)
+	assert.Equal(t, 3, h.Notes)
+	assert.Equal(t, 1, h.Orphans)
+	assert.Equal(t, 2, h.Links)
+	assert.Equal(t, 1, h.BrokenLinks)
+	assert.InDelta(t, 0.5, h.BrokenLinkRatio, 1e-9)
+	assert.Equal(t, 1, h.Untagged)
+
+	// Penalties: 30 * 1/2, 30 * 1/3 and 20 * 1/3
+	var metrics []string
+	var penalties []int
+	for _, r := range h.Recommendations {
+		metrics = append(metrics, r.Metric)
+		penalties = append(penalties, r.Penalty)
+	}
+	assert.Equal(t, []string{"broken_links", "orphans", "untagged"}, metrics)
+	assert.Equal(t, []int{15, 10, 7}, penalties)
+	assert.Equal(t, 68, h.Score)
+	assert.Equal(t, []string{"a.md → missing"}, h.Recommendations[0].Examples)
+	assert.Equal(t, []string{"b.md"}, h.Recommendations[2].Examples)
+
+	// Fixing everything restores a perfect score
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\ntags: [x]\n---\n[[b]] and [[c]]\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\ntags: [z]\n---\nTagged.\n")
+	require.NoError(t, m.FullIndexVault(vaultID))
+	h, err = s.GetVaultHealth(vaultID)
+	require.NoError(t, err)
+	assert.Equal(t, 100, h.Score)
+	assert.Empty(t, h.Recommendations)
+}
+
+func TestVaultHealthDuplicateIDs(t *testing.T) {
+	graph := &vault.Graph{
+		Nodes: []models.VaultNode{
+			{ID: "a", FilePath: "a.md", Tags: []string{"x"}, OutDegree: 1},
+			{ID: "b", FilePath: "b.md", Tags: []string{"x"}, InDegree: 1},
+			{ID: "c", FilePath: "c.md", Tags: []string{"x"}, InDegree: 1},
+		},
+		DuplicateIDs: []vault.DuplicateID{{ID: "a", KeptPath: "a.md", SkippedPaths: []string{"z.md"}}},
+	}
+	h := vaultHealth(graph, &vault.ParseResult{})
+	assert.Equal(t, 1, h.DuplicateIDs)
+	assert.Equal(t, 1, h.DuplicateFiles)
+	require.Len(t, h.Recommendations, 1)
+	assert.Equal(t, "duplicate_ids", h.Recommendations[0].Metric)
+	assert.Equal(t, []string{"z.md"}, h.Recommendations[0].Examples)
+	assert.Equal(t, 95, h.Score) // 20 * 1/4
+}
+
+// elsewhereHook logs through the standard logger in the middle of a full
+// index, as other work running at the same time might.
+type elsewhereHook struct{ vault.NopHook }
+
+func (elsewhereHook) OnGraphBuilt(*vault.Graph) error {
+	log.Print("logged elsewhere")
+	return nil
+}
+
+func TestFullIndexRecordsLogs(t *testing.T) {
+	m, s := newTestManager(t)
+	m.AddHook(elsewhereHook{})
+	var out bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&out)
+	defer log.SetOutput(prev)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\nA\n")
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	history, err := s.GetParseHistory(vaultID, "", 1)
+	require.NoError(t, err)
+	logs, err := s.GetParseLogs(history[0].ID, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, logs)
+	assert.Equal(t, "Starting full index of "+dir, logs[0].Message)
+	assert.Equal(t, "info", logs[0].Level)
+	assert.Contains(t, logs[len(logs)-1].Message, "Full index of "+dir+" completed")
+
+	// The lines still reach the standard logger, whose other lines are
+	// not recorded
+	assert.Same(t, &out, log.Writer())
+	assert.Contains(t, out.String(), "Starting full index of "+dir)
+	assert.Contains(t, out.String(), "logged elsewhere")
+	for _, l := range logs {
+		assert.NotContains(t, l.Message, "logged elsewhere")
+	}
+}
+
+func TestParseLogCap(t *testing.T) {
+	prev := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(prev)
+
+	c := &parseLog{}
+	logger := slog.New(c)
+	logger.Warn("one")
+	logger.Info("two")
+	records := c.finish()
+	require.Len(t, records, 2)
+	assert.Equal(t, models.ParseLogRecord{Time: records[0].Time, Level: "warning", Message: "one"}, records[0])
+	assert.Equal(t, models.ParseLogRecord{Time: records[1].Time, Level: "info", Message: "two"}, records[1])
+
+	for i := 0; i < maxParseLogRecords; i++ {
+		logger.Info("line")
+	}
+	records = c.finish()
+	require.Len(t, records, maxParseLogRecords+1)
+	assert.Equal(t, "2 more log lines were not kept", records[maxParseLogRecords].Message)
+}
+
+func TestParseRunProgress(t *testing.T) {
+	past := []models.ParseHistory{{Stats: models.JSONStats{PhaseDurations: map[models.ParsePhase]int64{
+		models.ParsePhasePull: 0, models.ParsePhaseParse: 800, models.ParsePhaseBuild: 100,
+		models.ParsePhaseClassify: 0, models.ParsePhaseStore: 100,
+	}}}}
+	run := newParseRun(1, past)
+	run.begin(models.ParsePhasePull)
+	run.begin(models.ParsePhaseParse)
+	run.files(1, 2)
+
+	status := run.status()
+	assert.Equal(t, "running", status.Status)
+	p := status.Progress
+	assert.Equal(t, models.ParsePhaseParse, p.Phase)
+	require.Len(t, p.Phases, len(models.ParsePhases))
+	assert.Equal(t, 100.0, p.Phases[0].Percent)
+	assert.Equal(t, 50.0, p.Phases[1].Percent)
+	assert.Equal(t, 0.0, p.Phases[2].Percent)
+
+	// Phases are weighted by historical duration: half of parse's 800ms of 1000ms
+	assert.Equal(t, 40.0, p.Percent)
+	require.NotNil(t, p.ETASeconds)
+	assert.Equal(t, 0.6, *p.ETASeconds)
+
+	// Without history there is no estimate until parsing has made progress
+	run = newParseRun(1, nil)
+	run.begin(models.ParsePhaseParse)
+	assert.Nil(t, run.status().Progress.ETASeconds)
+	run.files(1, 4)
+	assert.NotNil(t, run.status().Progress.ETASeconds)
+}
+
+func TestFullIndexVaultCancelled(t *testing.T) {
+	m, s := newTestManager(t)
+
+	dir := t.TempDir()
+	copyVault(t, sampleVault, dir)
+	vaultID, _, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+	require.NoError(t, m.FullIndexVault(vaultID))
+	before, err := s.GetAllNodes()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = m.FullIndexVaultContext(ctx, vaultID)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// Previously indexed data is untouched
+	after, err := s.GetAllNodes()
+	require.NoError(t, err)
+	assert.Len(t, after, len(before))
+
+	history, err := s.GetParseHistory(vaultID, models.ParseStatusCancelled, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.NotNil(t, history[0].Error)
+
+	status, err := m.ParseStatus()
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", status.Status)
+}
+
+func TestRecoverInterruptedRuns(t *testing.T) {
+	m, s := newTestManager(t)
+
+	dir := t.TempDir()
+	copyVault(t, sampleVault, dir)
+	vaultID, _, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+
+	// Simulate a crash: a run starts and checkpoints mid-parse, then the
+	// process dies before finishRun.
+	run, err := m.startRun(vaultID)
+	require.NoError(t, err)
+	run.begin(models.ParsePhasePull)
+	run.begin(models.ParsePhaseParse)
+	run.files(checkpointInterval, 250)
+	m.run = nil
+
+	history, err := s.GetParseHistory(vaultID, models.ParseStatusRunning, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.ParsePhaseParse, history[0].Stats.Phase)
+	assert.Equal(t, checkpointInterval, history[0].Stats.ParsedFiles)
+
+	vaults, err := m.RecoverInterruptedRuns()
+	require.NoError(t, err)
+	assert.Equal(t, []int{vaultID}, vaults)
+
+	history, err = s.GetParseHistory(vaultID, "", 0)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.ParseStatusFailed, history[0].Status)
+	require.NotNil(t, history[0].CompletedAt)
+	require.NotNil(t, history[0].Error)
+	assert.Contains(t, *history[0].Error, "during parse phase (100 of 250 files parsed)")
+
+	// Nothing left to recover; a fresh index resumes the vault
+	vaults, err = m.RecoverInterruptedRuns()
+	require.NoError(t, err)
+	assert.Empty(t, vaults)
+	require.NoError(t, m.FullIndexVault(vaultID))
+}
+
+func TestReleaseParseLock(t *testing.T) {
+	m, s := newTestManager(t)
+
+	dir := t.TempDir()
+	copyVault(t, sampleVault, dir)
+	vaultID, _, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+
+	id, err := m.ReleaseParseLock()
+	require.NoError(t, err)
+	assert.Empty(t, id)
+
+	// A hung run blocks full indexes until its lock is released
+	run, err := m.startRun(vaultID)
+	require.NoError(t, err)
+	run.begin(models.ParsePhaseParse)
+	assert.ErrorIs(t, m.FullIndexVault(vaultID), ErrIndexRunning)
+
+	id, err = m.ReleaseParseLock()
+	require.NoError(t, err)
+	assert.Equal(t, run.history.ID, id)
+
+	history, err := s.GetParseHistory(vaultID, models.ParseStatusFailed, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.NotNil(t, history[0].Error)
+	assert.Contains(t, *history[0].Error, "released")
+
+	// Late checkpoints do not mark it running again
+	run.files(checkpointInterval, 250)
+	history, err = s.GetParseHistory(vaultID, models.ParseStatusRunning, 0)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	// The released run finishing does not clear a later run's lock
+	next, err := m.startRun(vaultID)
+	require.NoError(t, err)
+	m.finishRun(run, nil)
+	assert.Same(t, next, m.run)
+	m.finishRun(next, nil)
+	assert.Nil(t, m.run)
+}
+
+func TestSetOnPhase(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	dir := t.TempDir()
+	copyVault(t, sampleVault, dir)
+	vaultID, _, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+
+	var phases []models.ParsePhase
+	m.SetOnPhase(func(p models.ParsePhase) { phases = append(phases, p) })
+	require.NoError(t, m.FullIndexVault(vaultID))
+	assert.Equal(t, append(slices.Clone(models.ParsePhases), ""), phases)
+}
+
+func TestQueueFullIndexAll(t *testing.T) {
+	m, s := newTestManager(t)
+
+	dir := t.TempDir()
+	copyVault(t, sampleVault, dir)
+	vaultID, _, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+
+	run, err := m.startRun(vaultID)
+	require.NoError(t, err)
+
+	first, err := m.QueueFullIndexAll()
+	require.NoError(t, err)
+	second, err := m.QueueFullIndexAll()
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.Position)
+	assert.Equal(t, 2, second.Position)
+
+	status, err := m.ParseStatus()
+	require.NoError(t, err)
+	assert.Equal(t, "running", status.Status)
+	require.Len(t, status.Queue, 2)
+	assert.Equal(t, first.ID, status.Queue[0].ID)
+	assert.Equal(t, second.ID, status.Queue[1].ID)
+
+	// Both queued indexes run once the running one finishes
+	m.finishRun(run, nil)
+	require.Eventually(t, func() bool {
+		history, err := s.GetParseHistory(vaultID, models.ParseStatusCompleted, 0)
+		require.NoError(t, err)
+		return len(history) == 3
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		status, err := m.ParseStatus()
+		require.NoError(t, err)
+		return len(status.Queue) == 0 && status.Status == "completed"
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestQueueFullIndexAllFull(t *testing.T) {
+	m, _ := newTestManager(t)
+	dir := t.TempDir()
+	copyVault(t, sampleVault, dir)
+	vaultID, _, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+
+	// The run never finishes, so the queue never drains
+	_, err = m.startRun(vaultID)
+	require.NoError(t, err)
+	for i := 0; i < maxQueuedIndexes; i++ {
+		_, err := m.QueueFullIndexAll()
+		require.NoError(t, err)
+	}
+	_, err = m.QueueFullIndexAll()
+	assert.ErrorIs(t, err, ErrIndexQueueFull)
+}
+
+func TestFullIndexSkipsUnchangedFiles(t *testing.T) {
+	m, s := newTestManager(t)
+	hook := &recordingHook{}
+	m.AddHook(hook)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\ncount: 3\n---\nSee [[b]].\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\n---\n# B\n")
+
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+	assert.Equal(t, 2, hook.parsed)
+	assert.Equal(t, 2, hook.stored)
+
+	// Nothing changed: no file is parsed or classified again, and the graph is intact
+	require.NoError(t, m.FullIndexVault(vaultID))
+	assert.Equal(t, 2, hook.parsed)
+	assert.Equal(t, 2, hook.stored)
+	n, err := s.GetNode("a")
+	require.NoError(t, err)
+	assert.Equal(t, true, n.Metadata["hooked"])
+	assert.EqualValues(t, 3, n.Metadata["count"])
+	assert.Equal(t, 1, n.OutDegree)
+	history, err := s.GetParseHistory(vaultID, models.ParseStatusCompleted, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, history[0].Stats.CachedFiles)
+
+	// Only the edited file is parsed and classified
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\n---\n# B\nBack to [[a]].\n")
+	require.NoError(t, m.FullIndexVault(vaultID))
+	assert.Equal(t, 3, hook.parsed)
+	assert.Equal(t, 3, hook.stored)
+	edges, err := s.GetAllEdges()
+	require.NoError(t, err)
+	assert.Len(t, edges, 2)
+
+	// A new cache key invalidates every entry
+	m.SetCacheKey("changed")
+	require.NoError(t, m.FullIndexVault(vaultID))
+	assert.Equal(t, 5, hook.parsed)
+}
+
+func TestFileCacheClassifyRules(t *testing.T) {
+	m, s := newTestManager(t)
+	m.AddHook(&recordingHook{})
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "specs", vault.DirConfigName), "classify:\n  - type: spec\n")
+	writeFile(t, filepath.Join(dir, "specs", "a.md"), "---\nid: a\n---\n# A\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\ntags: [x]\n---\n# B\n")
+
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+	nodeType := func(id string) string {
+		n, err := s.GetNode(id)
+		require.NoError(t, err)
+		return n.NodeType
+	}
+	assert.Equal(t, "spec", nodeType("a"))
+	assert.Equal(t, "", nodeType("b"))
+
+	// Edited rules apply to the files they cover at the next index, though
+	// the files did not change
+	writeFile(t, filepath.Join(dir, "specs", vault.DirConfigName), "classify:\n  - type: design\n")
+	m.SetParseOptions(vault.ParseOptions{Rules: vault.DirConfig{Classify: []vault.ClassifyRule{{Tag: "x", Type: "topic"}}}})
+	require.NoError(t, m.FullIndexVault(vaultID))
+	assert.Equal(t, "design", nodeType("a"))
+	assert.Equal(t, "topic", nodeType("b"))
+}
+
+func TestRenameMigratesPositions(t *testing.T) {
+	m, s := newTestManager(t)
+	rule, err := vault.NewIDRule(`^(\w+)$`, "")
+	require.NoError(t, err)
+	m.SetParseOptions(vault.ParseOptions{IDRules: []vault.IDRule{*rule}})
+	var renamedGraphs []int
+	m.SetOnRename(func(graphIDs []int) { renamedGraphs = append(renamedGraphs, graphIDs...) })
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "alpha.md"), "# Alpha\nSee [[beta]].\n")
+	writeFile(t, filepath.Join(dir, "beta.md"), "# Beta\n")
+
+	vaultID, graphIDs, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+	require.NoError(t, m.FullIndexVault(vaultID))
+	graphID := graphIDs[0]
+	require.NoError(t, s.UpsertPosition(graphID, &models.NodePosition{NodeID: "alpha", X: 10, Y: 20}))
+	require.NoError(t, s.SetNodeACL("alpha", []string{"admin"}))
+
+	// Renamed between full indexes
+	require.NoError(t, os.Rename(filepath.Join(dir, "alpha.md"), filepath.Join(dir, "gamma.md")))
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	positions, err := s.GetPositionsByGraph(graphID)
+	require.NoError(t, err)
+	assert.NotContains(t, positions, "alpha")
+	require.Contains(t, positions, "gamma")
+	assert.Equal(t, 10.0, positions["gamma"].X)
+	acls, err := s.GetNodeACLs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, acls["gamma"])
+	assert.Equal(t, []int{graphID}, renamedGraphs)
+
+	// Renamed while watching: the new file is indexed before the old one is removed
+	require.NoError(t, os.Rename(filepath.Join(dir, "gamma.md"), filepath.Join(dir, "delta.md")))
+	_, err = m.IndexFile(vaultID, "delta.md")
+	require.NoError(t, err)
+	_, err = m.RemoveFile(vaultID, "gamma.md")
+	require.NoError(t, err)
+
+	positions, err = s.GetPositionsByGraph(graphID)
+	require.NoError(t, err)
+	assert.NotContains(t, positions, "gamma")
+	assert.Equal(t, 20.0, positions["delta"].Y)
+
+	// Unrelated new files with other content are left alone
+	writeFile(t, filepath.Join(dir, "epsilon.md"), "# Epsilon\n")
+	_, err = m.IndexFile(vaultID, "epsilon.md")
+	require.NoError(t, err)
+	assert.Len(t, renamedGraphs, 2)
+}
+
+// gitCommit commits everything in dir and returns the new commit's SHA.
+func gitCommit(t *testing.T, dir, message string) string {
+	t.Helper()
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", message},
+	} {
+		_, err := git.Run(context.Background(), dir, args...)
+		require.NoError(t, err)
+	}
+	sha, err := git.Run(context.Background(), dir, "rev-parse", "HEAD")
+	require.NoError(t, err)
+	return sha
+}
+
+func TestGraphAtCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	m, s := newTestManager(t)
+
+	repo := t.TempDir()
+	_, err := git.Run(context.Background(), repo, "init", "-q")
+	require.NoError(t, err)
+	dir := filepath.Join(repo, "notes") // Vault in a subdirectory of the repository
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\n# A\n")
+	first := gitCommit(t, repo, "first")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\n---\n# B\nSee [[a]].\n")
+	gitCommit(t, repo, "second")
+
+	vaultID, _, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	hist, err := m.GraphAtCommit(context.Background(), vaultID, first[:10])
+	require.NoError(t, err)
+	assert.Equal(t, first, hist.Commit)
+	require.Len(t, hist.Nodes, 1)
+	assert.Equal(t, "a", hist.Nodes[0].ID)
+	assert.Equal(t, vaultID, hist.Nodes[0].VaultID)
+	assert.Empty(t, hist.Edges)
+
+	head, err := m.GraphAtCommit(context.Background(), vaultID, "HEAD")
+	require.NoError(t, err)
+	assert.Len(t, head.Nodes, 2)
+	assert.Len(t, head.Edges, 1)
+
+	// Cached, and the temporary worktrees are gone
+	again, err := m.GraphAtCommit(context.Background(), vaultID, first)
+	require.NoError(t, err)
+	assert.Same(t, hist, again)
+	worktrees, err := git.Run(context.Background(), repo, "worktree", "list")
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(worktrees, "\n"), 1)
+
+	// The stored graph is untouched
+	nodes, err := s.GetNodesByVault(vaultID)
+	require.NoError(t, err)
+	assert.Len(t, nodes, 2)
+
+	_, err = m.GraphAtCommit(context.Background(), vaultID, "0000000")
+	assert.ErrorIs(t, err, ErrUnknownCommit)
+	_, err = m.GraphAtCommit(context.Background(), vaultID, "--help")
+	assert.ErrorIs(t, err, ErrUnknownCommit)
+
+	plain, _, err := m.RegisterVault(t.TempDir())
+	require.NoError(t, err)
+	_, err = m.GraphAtCommit(context.Background(), plain, "HEAD")
+	assert.ErrorIs(t, err, ErrNotGitRepository)
+}
+
+// typeHook classifies nodes by file path.
+type typeHook struct {
+	vault.NopHook
+	types map[string]string
+}
+
+func (h *typeHook) OnBeforeStore(nodes []models.VaultNode, _ []models.VaultEdge) error {
+	for i := range nodes {
+		if t, ok := h.types[nodes[i].FilePath]; ok {
+			nodes[i].NodeType = t
+		}
+	}
+	return nil
+}
+
+func TestFullIndexLevelsAfterHooks(t *testing.T) {
+	m, s := newTestManager(t)
+	m.AddHook(&typeHook{types: map[string]string{"c.md": "hub"}})
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\n[[b]]\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\n---\n[[c]]\n")
+	writeFile(t, filepath.Join(dir, "c.md"), "---\nid: c\n---\n# C\n")
+
+	vaultID, _, _ := m.RegisterVault(dir)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	// The hook made c a hub, so the levels count from it
+	for id, level := range map[string]int{"a": 3, "b": 2, "c": 1} {
+		n, err := s.GetNode(id)
+		require.NoError(t, err)
+		assert.Equal(t, level, n.Level, id)
+	}
+}
+
+func TestReclassify(t *testing.T) {
+	m, s := newTestManager(t)
+	hook := &typeHook{types: map[string]string{"a.md": "hub"}}
+	m.AddHook(hook)
+	mentions, err := vault.NewMentionExtractor("", "")
+	require.NoError(t, err)
+	m.SetParseOptions(vault.ParseOptions{Mentions: mentions})
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "GRAPH.yaml"), "")
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\n# A\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\n---\nAsk @jane.\n")
+	writeFile(t, filepath.Join(dir, "people", "joe.md"), "---\nid: joe\n---\n# Joe\n")
+	writeFile(t, filepath.Join(dir, "specs", "c.md"), "---\nid: c\n---\n# C\n")
+	vaultID, graphIDs, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	// The rules changed: a loses its type, b gains one, c gains one from its
+	// directory's rules, and people stay people
+	hook.types = map[string]string{"b.md": "topic"}
+	writeFile(t, filepath.Join(dir, "specs", vault.DirConfigName), "classify:\n  - type: spec\n")
+	res, err := m.Reclassify(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5, res.Nodes)
+	assert.Equal(t, []string{"a", "b", "c"}, res.Changed)
+	assert.Equal(t, graphIDs, res.GraphIDs)
+	for id, want := range map[string]string{"a": "", "b": "topic", "c": "spec", "joe": "person", "person:jane": "person"} {
+		n, err := s.GetNode(id)
+		require.NoError(t, err)
+		assert.Equal(t, want, n.NodeType, id)
+	}
+
+	res, err = m.Reclassify(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, res.Changed)
+	assert.Empty(t, res.GraphIDs)
+
+	_, err = m.startRun(vaultID)
+	require.NoError(t, err)
+	_, err = m.Reclassify(context.Background())
+	assert.ErrorIs(t, err, ErrIndexRunning)
+}
+
+func TestExport(t *testing.T) {
+	// No store: exporting must not touch one
+	m := NewIndexManager(nil)
+	hook := &recordingHook{}
+	m.AddHook(hook)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.md"), "---\nid: a\n---\nSee [[b]] and [[missing]].\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\nid: b\n---\n# B\n")
+	writeFile(t, filepath.Join(dir, "bad.md"), "---\nid: bad\ntags: x: y\n---\n")
+
+	exp, err := m.Export(context.Background(), dir)
+	require.NoError(t, err)
+	assert.Equal(t, dir, exp.Vault)
+	ids := make([]string, 0, len(exp.Nodes))
+	for _, n := range exp.Nodes {
+		ids = append(ids, n.ID)
+		assert.Equal(t, true, n.Metadata["hooked"], n.ID)
+	}
+	assert.ElementsMatch(t, []string{"a", "b"}, ids)
+	require.Len(t, exp.Edges, 1)
+	assert.Equal(t, "a", exp.Edges[0].SourceID)
+	assert.Equal(t, "b", exp.Edges[0].TargetID)
+	require.Len(t, exp.Errors, 1)
+	assert.Equal(t, "bad.md", exp.Errors[0].FilePath)
+	assert.Contains(t, exp.Report, "missing")
+	assert.Equal(t, 2, hook.stored)
+}
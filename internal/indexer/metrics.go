@@ -0,0 +1,40 @@
+package indexer
+
+import (
+	"context"
+	"log"
+)
+
+// scheduleMetricsRefresh recomputes node degrees and centrality from the
+// stored edges in the background, after an incremental update has changed
+// them. Requests made while a refresh runs coalesce into one more refresh.
+func (m *IndexManager) scheduleMetricsRefresh() {
+	m.metricsMu.Lock()
+	defer m.metricsMu.Unlock()
+	if m.refreshing {
+		m.refreshPending = true
+		return
+	}
+	m.refreshing = true
+	go m.refreshMetrics()
+}
+
+// refreshMetrics runs metrics refreshes until none is pending.
+func (m *IndexManager) refreshMetrics() {
+	for {
+		if n, err := m.store.RecomputeMetrics(context.Background()); err != nil {
+			log.Printf("Warning: failed to refresh node metrics: %v", err)
+		} else if n > 0 {
+			log.Printf("Refreshed metrics of %d nodes", n)
+		}
+
+		m.metricsMu.Lock()
+		if !m.refreshPending {
+			m.refreshing = false
+			m.metricsMu.Unlock()
+			return
+		}
+		m.refreshPending = false
+		m.metricsMu.Unlock()
+	}
+}
@@ -0,0 +1,68 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/vault"
+)
+
+// maxParseLogRecords caps the log records kept for one full index, so a
+// vault full of bad files cannot fill the database with warnings.
+const maxParseLogRecords = 5000
+
+// parseLog is a slog.Handler that records what one full index logs and
+// passes each message on to the standard logger. Every full index has its
+// own, handed to the parser and graph builder, so work running at the same
+// time never lands in another run's records.
+type parseLog struct {
+	mu      sync.Mutex
+	records []models.ParseLogRecord
+	dropped int
+}
+
+// Enabled implements slog.Handler.
+func (c *parseLog) Enabled(context.Context, slog.Level) bool { return true }
+
+// Handle implements slog.Handler. Messages at LevelWarn and above are
+// recorded as warnings.
+func (c *parseLog) Handle(ctx context.Context, r slog.Record) error {
+	c.mu.Lock()
+	if len(c.records) < maxParseLogRecords {
+		level := "info"
+		if r.Level >= slog.LevelWarn {
+			level = "warning"
+		}
+		c.records = append(c.records, models.ParseLogRecord{Time: r.Time.UTC(), Level: level, Message: r.Message})
+	} else {
+		c.dropped++
+	}
+	c.mu.Unlock()
+	return vault.StdLogHandler{}.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler. Attributes are not recorded.
+func (c *parseLog) WithAttrs([]slog.Attr) slog.Handler { return c }
+
+// WithGroup implements slog.Handler.
+func (c *parseLog) WithGroup(string) slog.Handler { return c }
+
+// finish returns the records. A final warning says how many were dropped
+// over the cap.
+func (c *parseLog) finish() []models.ParseLogRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	records := c.records
+	if c.dropped > 0 {
+		records = append(records, models.ParseLogRecord{
+			Time:    time.Now().UTC(),
+			Level:   "warning",
+			Message: fmt.Sprintf("%d more log lines were not kept", c.dropped),
+		})
+	}
+	return records
+}
@@ -0,0 +1,425 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// ErrIndexRunning is returned when a full index is requested while one is in progress.
+var ErrIndexRunning = errors.New("full index already running")
+
+// ErrParseLockReleased is recorded as the error of a full index whose lock was
+// released by ReleaseParseLock.
+var ErrParseLockReleased = errors.New("parse lock released while the index was running")
+
+const (
+	// etaHistorySize is how many past completed runs are averaged for ETA estimates.
+	etaHistorySize = 5
+
+	// checkpointInterval is how many parsed files pass between checkpoints,
+	// matching the parser's batch size.
+	checkpointInterval = 100
+)
+
+// parseRun tracks the progress of one full vault index. All methods are safe
+// for concurrent use and no-ops on a nil receiver, so incremental indexing can
+// share code paths with full indexing without reporting progress.
+type parseRun struct {
+	mu         sync.Mutex
+	history    models.ParseHistory
+	phase      int // index into models.ParsePhases; -1 before the first phase
+	phaseStart time.Time
+	phasePct   float64 // completion of the current phase, 0-1
+	durations  map[models.ParsePhase]int64
+	estimates  map[models.ParsePhase]int64 // mean historical ms per phase; nil without history
+
+	// checkpoint persists a snapshot of the run so it can be recovered after
+	// a crash. seq orders snapshots taken concurrently by parse workers.
+	checkpoint func(models.ParseHistory)
+	seq        int
+	saveMu     sync.Mutex
+	savedSeq   int
+
+	onPhase func(models.ParsePhase) // See IndexManager.SetOnPhase
+}
+
+func newParseRun(vaultID int, past []models.ParseHistory) *parseRun {
+	return &parseRun{
+		history: models.ParseHistory{
+			ID:        uuid.NewString(),
+			VaultID:   vaultID,
+			StartedAt: time.Now(),
+			Status:    models.ParseStatusRunning,
+			Stats:     models.JSONStats{PhaseDurations: make(map[models.ParsePhase]int64)},
+		},
+		phase:     -1,
+		durations: make(map[models.ParsePhase]int64),
+		estimates: phaseEstimates(past),
+	}
+}
+
+// phaseEstimates averages each phase's duration over past runs.
+func phaseEstimates(past []models.ParseHistory) map[models.ParsePhase]int64 {
+	totals := make(map[models.ParsePhase]int64)
+	counts := make(map[models.ParsePhase]int64)
+	for _, h := range past {
+		for phase, ms := range h.Stats.PhaseDurations {
+			totals[phase] += ms
+			counts[phase]++
+		}
+	}
+	if len(totals) == 0 {
+		return nil
+	}
+	est := make(map[models.ParsePhase]int64, len(totals))
+	for phase, total := range totals {
+		est[phase] = total / counts[phase]
+	}
+	return est
+}
+
+// begin ends the current phase (if any) and starts phase.
+func (r *parseRun) begin(phase models.ParsePhase) {
+	if r == nil {
+		return
+	}
+	if r.onPhase != nil {
+		r.onPhase(phase)
+	}
+	r.mu.Lock()
+	r.endLocked()
+	r.phase = slices.Index(models.ParsePhases, phase)
+	r.phaseStart = time.Now()
+	r.phasePct = 0
+	r.history.Stats.Phase = phase
+	snap, seq := r.snapshotLocked()
+	r.mu.Unlock()
+	r.save(snap, seq)
+}
+
+func (r *parseRun) endLocked() {
+	if r.phase < 0 || r.phase >= len(models.ParsePhases) {
+		return
+	}
+	name := models.ParsePhases[r.phase]
+	if _, done := r.durations[name]; !done {
+		r.durations[name] = time.Since(r.phaseStart).Milliseconds()
+		r.history.Stats.PhaseDurations[name] = r.durations[name]
+	}
+	r.phasePct = 1
+}
+
+// files records parse-phase file progress.
+func (r *parseRun) files(done, total int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.history.Stats.ParsedFiles = done
+	r.history.Stats.TotalFiles = total
+	if total > 0 {
+		r.phasePct = float64(done) / float64(total)
+	}
+	if done%checkpointInterval != 0 {
+		r.mu.Unlock()
+		return
+	}
+	snap, seq := r.snapshotLocked()
+	r.mu.Unlock()
+	r.save(snap, seq)
+}
+
+// snapshotLocked copies the history record for a checkpoint.
+func (r *parseRun) snapshotLocked() (models.ParseHistory, int) {
+	r.seq++
+	snap := r.history
+	snap.Stats.PhaseDurations = maps.Clone(r.history.Stats.PhaseDurations)
+	return snap, r.seq
+}
+
+// save writes a checkpoint unless a newer one has already been written.
+func (r *parseRun) save(snap models.ParseHistory, seq int) {
+	if r.checkpoint == nil {
+		return
+	}
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+	if seq <= r.savedSeq {
+		return
+	}
+	r.savedSeq = seq
+	r.checkpoint(snap)
+}
+
+// cached records how many files were reused from the file cache.
+func (r *parseRun) cached(n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.history.Stats.CachedFiles = n
+}
+
+// counts records the graph size once it is known.
+func (r *parseRun) counts(nodes, edges int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.history.Stats.TotalNodes = nodes
+	r.history.Stats.TotalEdges = edges
+}
+
+// finish ends the run and returns its final history record.
+func (r *parseRun) finish(err error) models.ParseHistory {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endLocked()
+	now := time.Now()
+	r.history.CompletedAt = &now
+	r.history.Stats.DurationMS = now.Sub(r.history.StartedAt).Milliseconds()
+	r.history.Status = models.ParseStatusCompleted
+	r.history.Error = nil
+	if err != nil {
+		msg := err.Error()
+		r.history.Status = models.ParseStatusFailed
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			r.history.Status = models.ParseStatusCancelled
+		}
+		r.history.Error = &msg
+	}
+	return r.history
+}
+
+// status reports the run's current progress.
+func (r *parseRun) status() *models.ParseStatusResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	startedAt := r.history.StartedAt
+	progress := &models.ParseProgress{
+		TotalFiles:     r.history.Stats.TotalFiles,
+		ProcessedFiles: r.history.Stats.ParsedFiles,
+		NodesCreated:   r.history.Stats.TotalNodes,
+		EdgesCreated:   r.history.Stats.TotalEdges,
+	}
+
+	// Weight phases by their historical duration so the overall percentage
+	// tracks time; without history every phase weighs the same.
+	var totalWeight, doneWeight float64
+	var remainingMS float64
+	for i, name := range models.ParsePhases {
+		pct := 0.0
+		switch {
+		case i < r.phase:
+			pct = 1
+		case i == r.phase:
+			pct = r.phasePct
+			progress.Phase = name
+		}
+		progress.Phases = append(progress.Phases, models.PhaseProgress{
+			Name:       name,
+			Percent:    roundPct(pct),
+			DurationMS: r.durations[name],
+		})
+
+		weight := 1.0
+		if r.estimates != nil {
+			weight = float64(r.estimates[name])
+		}
+		totalWeight += weight
+		doneWeight += weight * pct
+		remainingMS += float64(r.estimates[name]) * (1 - pct)
+	}
+	if totalWeight > 0 {
+		progress.Percent = roundPct(doneWeight / totalWeight)
+	}
+
+	if eta, ok := r.etaLocked(remainingMS); ok {
+		progress.ETASeconds = &eta
+	}
+
+	return &models.ParseStatusResponse{
+		VaultID:   r.history.VaultID,
+		Status:    string(models.ParseStatusRunning),
+		StartedAt: &startedAt,
+		Progress:  progress,
+	}
+}
+
+// etaLocked estimates the seconds remaining. With history it uses the mean
+// phase durations; without, it extrapolates the parse phase from its own rate.
+func (r *parseRun) etaLocked(remainingMS float64) (float64, bool) {
+	if r.estimates != nil {
+		return math.Round(remainingMS/100) / 10, true
+	}
+	if r.phase >= 0 && models.ParsePhases[r.phase] == models.ParsePhaseParse && r.phasePct > 0 {
+		elapsed := time.Since(r.phaseStart).Seconds()
+		return math.Round(elapsed*(1-r.phasePct)/r.phasePct*10) / 10, true
+	}
+	return 0, false
+}
+
+// roundPct converts a 0-1 fraction to a percentage with one decimal place.
+func roundPct(f float64) float64 {
+	return math.Round(f*1000) / 10
+}
+
+// ParseStatus reports the running full index, or the most recent one if none
+// is running, and the full indexes queued behind it.
+func (m *IndexManager) ParseStatus() (*models.ParseStatusResponse, error) {
+	m.runMu.Lock()
+	run := m.run
+	queue := m.queuedParses()
+	m.runMu.Unlock()
+	if run != nil {
+		status := run.status()
+		status.Queue = queue
+		return status, nil
+	}
+
+	latest, err := m.store.GetParseHistory(0, "", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(latest) == 0 {
+		status := models.NewParseStatusFromHistory(nil)
+		status.Queue = queue
+		return status, nil
+	}
+	status := models.NewParseStatusFromHistory(&latest[0])
+	status.VaultID = latest[0].VaultID
+	status.Queue = queue
+	return status, nil
+}
+
+// startRun registers a new full index of vaultID, failing if one is running.
+func (m *IndexManager) startRun(vaultID int) (*parseRun, error) {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+	if m.run != nil {
+		return nil, ErrIndexRunning
+	}
+	past, err := m.store.GetParseHistory(vaultID, models.ParseStatusCompleted, etaHistorySize)
+	if err != nil {
+		return nil, fmt.Errorf("load parse history: %w", err)
+	}
+	m.run = newParseRun(vaultID, past)
+	if err := m.store.SaveParseHistory(&m.run.history); err != nil {
+		m.run = nil
+		return nil, fmt.Errorf("save parse history: %w", err)
+	}
+	m.run.onPhase = m.onPhase
+	m.run.checkpoint = func(h models.ParseHistory) {
+		if err := m.store.SaveParseHistory(&h); err != nil {
+			log.Printf("Warning: failed to checkpoint parse history: %v", err)
+		}
+	}
+	return m.run, nil
+}
+
+// finishRun records the outcome of run and clears it.
+func (m *IndexManager) finishRun(run *parseRun, runErr error) {
+	if run.onPhase != nil {
+		run.onPhase("")
+	}
+	h := run.finish(runErr)
+	// Hold saveMu so a late checkpoint cannot overwrite the final record
+	run.saveMu.Lock()
+	run.savedSeq = math.MaxInt
+	if err := m.store.SaveParseHistory(&h); err != nil {
+		log.Printf("Warning: failed to save parse history: %v", err)
+	}
+	run.saveMu.Unlock()
+	if m.onFinish != nil {
+		if vs, ok := m.vaults[h.VaultID]; ok {
+			m.onFinish(vs.path, h)
+		}
+	}
+	m.runMu.Lock()
+	if m.run == run { // Not released and replaced by a later run
+		m.run = nil
+		m.runIdle.Broadcast()
+	}
+	m.runMu.Unlock()
+}
+
+// ReleaseParseLock forgets the running full index so another can start, for
+// runs that hang. The released run is recorded as failed; if it ever
+// finishes, its real outcome replaces that record. It returns the released
+// run's ID, or "" if no full index was running.
+func (m *IndexManager) ReleaseParseLock() (string, error) {
+	m.runMu.Lock()
+	run := m.run
+	m.run = nil
+	m.runIdle.Broadcast()
+	m.runMu.Unlock()
+	if run == nil {
+		return "", nil
+	}
+
+	h := run.finish(ErrParseLockReleased)
+	// Stop checkpoints from marking the run as running again
+	run.saveMu.Lock()
+	defer run.saveMu.Unlock()
+	run.savedSeq = math.MaxInt
+	if err := m.store.SaveParseHistory(&h); err != nil {
+		return h.ID, fmt.Errorf("save parse history: %w", err)
+	}
+	return h.ID, nil
+}
+
+// RecoverInterruptedRuns marks runs left "running" by a previous process as
+// failed and returns the IDs of their vaults, oldest run first, so callers can
+// resume them with a fresh full index. Vault data needs no cleanup: the store
+// phase writes in a single transaction, which SQLite rolls back on restart if
+// the process died before committing. Call it before starting any full index.
+func (m *IndexManager) RecoverInterruptedRuns() ([]int, error) {
+	stale, err := m.store.GetParseHistory(0, models.ParseStatusRunning, 0)
+	if err != nil {
+		return nil, fmt.Errorf("load interrupted runs: %w", err)
+	}
+
+	var vaultIDs []int
+	now := time.Now()
+	for i := len(stale) - 1; i >= 0; i-- {
+		h := stale[i]
+		msg := interruptedMessage(h.Stats)
+		h.Status = models.ParseStatusFailed
+		h.CompletedAt = &now
+		h.Error = &msg
+		if err := m.store.SaveParseHistory(&h); err != nil {
+			return nil, fmt.Errorf("mark run %s failed: %w", h.ID, err)
+		}
+		log.Printf("Recovered interrupted index of vault %d: %s", h.VaultID, msg)
+		if !slices.Contains(vaultIDs, h.VaultID) {
+			vaultIDs = append(vaultIDs, h.VaultID)
+		}
+	}
+	return vaultIDs, nil
+}
+
+// interruptedMessage describes how far an interrupted run got by its last checkpoint.
+func interruptedMessage(stats models.JSONStats) string {
+	msg := "interrupted by server shutdown"
+	if stats.Phase != "" {
+		msg += fmt.Sprintf(" during %s phase", stats.Phase)
+	}
+	if stats.TotalFiles > 0 {
+		msg += fmt.Sprintf(" (%d of %d files parsed)", stats.ParsedFiles, stats.TotalFiles)
+	}
+	return msg + "; previous vault data kept"
+}
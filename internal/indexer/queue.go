@@ -0,0 +1,88 @@
+package indexer
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// ErrIndexQueueFull is returned by QueueFullIndexAll when maxQueuedIndexes
+// full indexes are already waiting.
+var ErrIndexQueueFull = errors.New("full index queue is full")
+
+// maxQueuedIndexes bounds the queue so repeated requests cannot pile up
+// indexes that would run for hours.
+const maxQueuedIndexes = 10
+
+// queuedIndex is a full index of all vaults waiting for the running one.
+type queuedIndex struct {
+	id       string
+	queuedAt time.Time
+}
+
+// QueueFullIndexAll requests a full index of all vaults that runs in the
+// background once no full index is running, after any queued before it.
+// It returns the request's place in the queue, where position 1 runs next.
+// Queued indexes are not cancelled on shutdown; they are lost with the
+// process.
+func (m *IndexManager) QueueFullIndexAll() (models.QueuedParse, error) {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+	if len(m.queue) >= maxQueuedIndexes {
+		return models.QueuedParse{}, ErrIndexQueueFull
+	}
+	q := queuedIndex{id: uuid.NewString(), queuedAt: time.Now().UTC()}
+	m.queue = append(m.queue, q)
+	if !m.draining {
+		m.draining = true
+		go m.drainQueue()
+	}
+	return models.QueuedParse{ID: q.id, Position: len(m.queue), QueuedAt: q.queuedAt}, nil
+}
+
+// drainQueue runs queued full indexes one at a time, each once no full index
+// is running, and exits when the queue is empty.
+func (m *IndexManager) drainQueue() {
+	for {
+		m.runMu.Lock()
+		for m.run != nil {
+			m.runIdle.Wait()
+		}
+		if len(m.queue) == 0 {
+			m.draining = false
+			m.runMu.Unlock()
+			return
+		}
+		q := m.queue[0]
+		m.queue = m.queue[1:]
+		m.runMu.Unlock()
+
+		err := m.FullIndexAll()
+		if errors.Is(err, ErrIndexRunning) {
+			// Another request started a full index first; wait for it again
+			m.runMu.Lock()
+			m.queue = append([]queuedIndex{q}, m.queue...)
+			m.runMu.Unlock()
+			continue
+		}
+		if err != nil {
+			log.Printf("Queued full index %s failed: %v", q.id, err)
+		}
+	}
+}
+
+// queuedParses returns the queue in order. The caller holds runMu.
+func (m *IndexManager) queuedParses() []models.QueuedParse {
+	if len(m.queue) == 0 {
+		return nil
+	}
+	out := make([]models.QueuedParse, len(m.queue))
+	for i, q := range m.queue {
+		out[i] = models.QueuedParse{ID: q.id, Position: i + 1, QueuedAt: q.queuedAt}
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/ali01/mnemosyne/internal/discovery"
+	"github.com/ali01/mnemosyne/internal/vault"
+)
+
+// ReclassifyResult is the outcome of Reclassify.
+type ReclassifyResult struct {
+	Nodes    int      `json:"nodes"`   // Nodes classified
+	Changed  []string `json:"changed"` // IDs of the nodes whose type changed
+	GraphIDs []int    `json:"-"`       // Graphs containing a changed node
+}
+
+// Reclassify runs the before-store hooks, such as Lua classify scripts,
+// again over every stored node of the registered vaults and stores the node
+// types that changed, without parsing the vaults. Each node starts from the
+// type the graph builder gives it (see vault.BaseNodeType) with its stored
+// path, title, tags and metadata; content is not loaded. The vault is
+// walked again for its directories' classification rules. Metadata the hooks
+// change is not stored. It fails with ErrIndexRunning while a full index
+// runs, since that stores types of its own.
+func (m *IndexManager) Reclassify(ctx context.Context) (*ReclassifyResult, error) {
+	m.runMu.Lock()
+	running := m.run != nil
+	m.runMu.Unlock()
+	if running {
+		return nil, ErrIndexRunning
+	}
+
+	res := &ReclassifyResult{Changed: []string{}}
+	for _, vs := range m.vaults {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		nodes, err := m.store.GetNodesByVault(vs.id)
+		if err != nil {
+			return nil, fmt.Errorf("load nodes of vault %d: %w", vs.id, err)
+		}
+		opts := m.parseOptionsFor(vs.path)
+		classifier, err := vault.LoadClassifier(ctx, vs.path, opts)
+		if err != nil {
+			return nil, fmt.Errorf("load classification rules of vault %d: %w", vs.id, err)
+		}
+		for i := range nodes {
+			nodes[i].NodeType = vault.BaseNodeType(&nodes[i], opts.Mentions, classifier)
+		}
+		err = vault.RunBeforeStore(m.hooks, nodes, nil)
+		var failed vault.FileErrors
+		if errors.As(err, &failed) {
+			for _, e := range failed {
+				log.Printf("Warning: hook failed for '%s': %v", e.FilePath, e.Error)
+			}
+		} else if err != nil {
+			return nil, err
+		}
+
+		types := make(map[string]string, len(nodes))
+		for _, n := range nodes {
+			types[n.ID] = n.NodeType
+		}
+		changed, err := m.store.SetNodeTypes(ctx, types)
+		if err != nil {
+			return nil, fmt.Errorf("store node types of vault %d: %w", vs.id, err)
+		}
+		res.Nodes += len(nodes)
+		res.Changed = append(res.Changed, changed...)
+
+		paths := make(map[string]string, len(nodes))
+		for _, n := range nodes {
+			paths[n.ID] = n.FilePath
+		}
+		for _, g := range vs.graphs {
+			for _, id := range changed {
+				if discovery.IsUnderPath(paths[id], g.rootPath) {
+					res.GraphIDs = append(res.GraphIDs, g.id)
+					break
+				}
+			}
+		}
+	}
+	slices.Sort(res.Changed)
+	if len(res.Changed) > 0 {
+		log.Printf("Reclassified %d nodes, %d changed type", res.Nodes, len(res.Changed))
+	}
+	return res, nil
+}
@@ -0,0 +1,69 @@
+package indexer
+
+import (
+	"log"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// detectRenames finds files that moved and changed node ID on the way, as
+// happens when IDs are derived from filenames. A file whose content hash
+// matches exactly one file that no longer exists is taken to be that file
+// renamed. Returns old node ID -> new node ID.
+//
+// Renames that also edit the file are not detected, nor are moves of files
+// with identical content, which cannot be told apart.
+func detectRenames(previous map[string]models.CachedFile, current []models.CachedFile) map[string]string {
+	currentPaths := make(map[string]bool, len(current))
+	currentIDs := make(map[string]bool, len(current))
+	for _, f := range current {
+		currentPaths[f.Path] = true
+		currentIDs[f.NodeID] = true
+	}
+
+	gone := make(map[string][]models.CachedFile)
+	for path, f := range previous {
+		if !currentPaths[path] && f.NodeID != "" && !currentIDs[f.NodeID] {
+			gone[f.Hash] = append(gone[f.Hash], f)
+		}
+	}
+	if len(gone) == 0 {
+		return nil
+	}
+
+	added := make(map[string][]models.CachedFile)
+	for _, f := range current {
+		if _, ok := previous[f.Path]; !ok && f.NodeID != "" {
+			added[f.Hash] = append(added[f.Hash], f)
+		}
+	}
+
+	renames := make(map[string]string)
+	for hash, from := range gone {
+		to := added[hash]
+		if len(from) != 1 || len(to) != 1 || from[0].NodeID == to[0].NodeID {
+			continue
+		}
+		renames[from[0].NodeID] = to[0].NodeID
+	}
+	return renames
+}
+
+// migrateRenames moves positions and ACLs to the new IDs of renamed nodes.
+// Failures are logged rather than returned: the index itself succeeded.
+func (m *IndexManager) migrateRenames(renames map[string]string) {
+	if len(renames) == 0 {
+		return
+	}
+	graphIDs, err := m.store.RenameNodes(renames)
+	if err != nil {
+		log.Printf("Warning: failed to migrate renamed nodes: %v", err)
+		return
+	}
+	for oldID, newID := range renames {
+		log.Printf("Detected rename: node '%s' is now '%s'", oldID, newID)
+	}
+	if m.onRename != nil {
+		m.onRename(graphIDs)
+	}
+}
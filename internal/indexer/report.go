@@ -0,0 +1,135 @@
+package indexer
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/vault"
+)
+
+// maxReportItems caps each list in a parse report, so a vault with
+// thousands of broken links still gets a readable report.
+const maxReportItems = 200
+
+// parseReport renders a markdown summary of a full index of the vault at
+// vaultPath: what was parsed, IDs claimed by several files, links that
+// resolve to nothing, files that failed, and how nodes and edges were
+// classified.
+func parseReport(vaultPath string, started time.Time, graph *vault.Graph, parsed *vault.ParseResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Parse report: %s\n\n", vaultPath)
+	fmt.Fprintf(&b, "Started %s.\n\n", started.UTC().Format(time.RFC3339))
+
+	st := parsed.Stats
+	b.WriteString("## Summary\n\n| | |\n|---|---:|\n")
+	for _, row := range []struct {
+		name  string
+		value int
+	}{
+		{"Files", st.TotalFiles},
+		{"Parsed", st.ParsedFiles},
+		{"Failed", st.FailedFiles},
+		{"Skipped (not text)", st.SkippedFiles},
+		{"From cache", st.CachedFiles},
+		{"Too large (metadata only)", st.LargeFiles},
+		{"BibTeX references", st.References},
+		{"Nodes", len(graph.Nodes)},
+		{"Edges", len(graph.Edges)},
+		{"Orphaned nodes", graph.Stats.OrphanedNodes},
+		{"Links", st.TotalLinks},
+		{"Resolved links", st.ResolvedLinks},
+		{"Unresolved links", st.UnresolvedLinks},
+		{"Duplicate IDs", len(graph.DuplicateIDs)},
+	} {
+		fmt.Fprintf(&b, "| %s | %d |\n", row.name, row.value)
+	}
+
+	nodeTypes := make(map[string]int)
+	for _, n := range graph.Nodes {
+		t := n.NodeType
+		if t == "" {
+			t = "(none)"
+		}
+		nodeTypes[t]++
+	}
+	writeReportCounts(&b, "Node types", nodeTypes)
+	edgeTypes := make(map[string]int)
+	for _, e := range graph.Edges {
+		edgeTypes[e.EdgeType]++
+	}
+	writeReportCounts(&b, "Edge types", edgeTypes)
+
+	var lines []string
+	for _, d := range graph.DuplicateIDs {
+		lines = append(lines, fmt.Sprintf("`%s`: kept `%s`, skipped `%s`", d.ID, d.KeptPath, strings.Join(d.SkippedPaths, "`, `")))
+	}
+	writeReportList(&b, "Duplicate IDs", lines)
+
+	links := slices.Clone(parsed.UnresolvedLinks)
+	slices.SortStableFunc(links, func(a, b vault.UnresolvedLink) int {
+		return cmp.Or(cmp.Compare(a.SourcePath, b.SourcePath), cmp.Compare(a.Link.Position, b.Link.Position))
+	})
+	lines = lines[:0]
+	for _, l := range links {
+		lines = append(lines, fmt.Sprintf("`%s` → `%s` (%s)", l.SourcePath, l.Link.Target, l.Link.LinkType))
+	}
+	writeReportList(&b, "Unresolved links", lines)
+
+	lines = lines[:0]
+	for _, e := range parseFileErrors(parsed) {
+		where := e.Kind
+		if e.Line > 0 {
+			where += fmt.Sprintf(", line %d", e.Line)
+		}
+		lines = append(lines, fmt.Sprintf("`%s` (%s): %s", e.FilePath, where, e.Message))
+	}
+	writeReportList(&b, "Parse errors", lines)
+	return b.String()
+}
+
+// parseFileErrors lists the files that failed to parse, or parsed with
+// problems, by path.
+func parseFileErrors(parsed *vault.ParseResult) []models.ParseFileError {
+	errs := make([]models.ParseFileError, 0, len(parsed.ParseErrors))
+	for _, e := range parsed.ParseErrors {
+		errs = append(errs, models.ParseFileError{FilePath: e.FilePath, Kind: e.Kind, Line: e.Line, Message: e.Error.Error()})
+	}
+	slices.SortStableFunc(errs, func(a, b models.ParseFileError) int { return cmp.Compare(a.FilePath, b.FilePath) })
+	return errs
+}
+
+// writeReportCounts writes a section tabulating counts, most common first.
+func writeReportCounts(b *strings.Builder, title string, counts map[string]int) {
+	fmt.Fprintf(b, "\n## %s\n\n", title)
+	if len(counts) == 0 {
+		b.WriteString("None.\n")
+		return
+	}
+	keys := slices.SortedFunc(maps.Keys(counts), func(x, y string) int {
+		return cmp.Or(cmp.Compare(counts[y], counts[x]), cmp.Compare(x, y))
+	})
+	b.WriteString("| Type | Count |\n|---|---:|\n")
+	for _, k := range keys {
+		fmt.Fprintf(b, "| %s | %d |\n", k, counts[k])
+	}
+}
+
+// writeReportList writes a section listing items, up to maxReportItems.
+func writeReportList(b *strings.Builder, title string, items []string) {
+	fmt.Fprintf(b, "\n## %s\n\n", title)
+	if len(items) == 0 {
+		b.WriteString("None.\n")
+		return
+	}
+	for _, item := range items[:min(len(items), maxReportItems)] {
+		fmt.Fprintf(b, "- %s\n", item)
+	}
+	if len(items) > maxReportItems {
+		fmt.Fprintf(b, "- …and %d more\n", len(items)-maxReportItems)
+	}
+}
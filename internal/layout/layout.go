@@ -0,0 +1,361 @@
+// Package layout computes node positions server-side with selectable
+// algorithms, and runs those computations as asynchronous jobs whose results
+// are stored as graph positions.
+package layout
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// Algorithm names a layout algorithm.
+type Algorithm string
+
+const (
+	ForceDirected Algorithm = "force-directed" // Linked notes attract, all notes repel
+	Hierarchical  Algorithm = "hierarchical"   // Tree of folders, notes grouped under their folder
+	Radial        Algorithm = "radial"         // Rings by link distance from the best-connected note
+)
+
+// Algorithms lists the supported layout algorithms.
+var Algorithms = []Algorithm{ForceDirected, Hierarchical, Radial}
+
+// spacing is the distance kept between neighbouring nodes.
+const spacing = 100.0
+
+// ParseAlgorithm validates an algorithm name.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	for _, a := range Algorithms {
+		if string(a) == name {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("unknown layout algorithm %q", name)
+}
+
+// Node is a node to position. Path is its vault-relative file path.
+type Node struct {
+	ID   string
+	Path string
+}
+
+// Edge links two nodes. Edges to nodes not being laid out are ignored.
+type Edge struct {
+	Source string
+	Target string
+}
+
+// Compute positions nodes with the given algorithm. Nodes in pinned stay at
+// the given positions and are left out of the result; the force-directed
+// layout arranges the other nodes around them. Results are deterministic for
+// the same input and lie in the z = 0 plane.
+func Compute(ctx context.Context, alg Algorithm, nodes []Node, edges []Edge, pinned map[string]models.Position) (map[string]models.Position, error) {
+	nodes = append([]Node(nil), nodes...)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	var positions map[string]models.Position
+	switch alg {
+	case ForceDirected:
+		var err error
+		if positions, err = forceDirected(ctx, nodes, edges, pinned); err != nil {
+			return nil, err
+		}
+	case Hierarchical:
+		positions = hierarchical(nodes)
+	case Radial:
+		positions = radial(nodes, edges)
+	default:
+		return nil, fmt.Errorf("unknown layout algorithm %q", alg)
+	}
+	for id := range pinned {
+		delete(positions, id)
+	}
+	return positions, nil
+}
+
+// adjacency returns each node's distinct neighbours, sorted, ignoring
+// self-links and links leaving the node set.
+func adjacency(nodes []Node, edges []Edge) map[string][]string {
+	adj := make(map[string][]string, len(nodes))
+	for _, n := range nodes {
+		adj[n.ID] = nil
+	}
+	seen := make(map[[2]string]bool, len(edges))
+	for _, e := range edges {
+		a, b := min(e.Source, e.Target), max(e.Source, e.Target)
+		if a == b || seen[[2]string{a, b}] {
+			continue
+		}
+		if _, ok := adj[a]; !ok {
+			continue
+		}
+		if _, ok := adj[b]; !ok {
+			continue
+		}
+		seen[[2]string{a, b}] = true
+		adj[a] = append(adj[a], b)
+		adj[b] = append(adj[b], a)
+	}
+	for _, neighbours := range adj {
+		sort.Strings(neighbours)
+	}
+	return adj
+}
+
+// --- Force-directed ---
+
+// forceWork bounds the pairwise repulsion computations of a force-directed
+// layout, trading quality for time on large graphs.
+const (
+	forceIterations    = 300
+	minForceIterations = 20
+	forceWork          = 5e8
+)
+
+// forceDirected runs Fruchterman-Reingold from a deterministic spiral. Pinned
+// nodes start at their positions and never move.
+func forceDirected(ctx context.Context, nodes []Node, edges []Edge, pinned map[string]models.Position) (map[string]models.Position, error) {
+	n := len(nodes)
+	index := make(map[string]int, n)
+	xs, ys := make([]float64, n), make([]float64, n)
+	fixed := make([]bool, n)
+	for i, node := range nodes {
+		index[node.ID] = i
+		if p, ok := pinned[node.ID]; ok {
+			xs[i], ys[i], fixed[i] = p.X, p.Y, true
+		} else {
+			xs[i], ys[i] = spiral(i)
+		}
+	}
+
+	adj := adjacency(nodes, edges)
+	var links [][2]int
+	for _, node := range nodes {
+		for _, other := range adj[node.ID] {
+			if node.ID < other {
+				links = append(links, [2]int{index[node.ID], index[other]})
+			}
+		}
+	}
+
+	iterations := forceIterations
+	if n > 1 {
+		iterations = max(minForceIterations, min(forceIterations, int(forceWork/float64(n*n))))
+	}
+	k := spacing
+	temp := spacing * math.Sqrt(float64(n)) / 10
+	dx, dy := make([]float64, n), make([]float64, n)
+	for it := 0; it < iterations; it++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		clear(dx)
+		clear(dy)
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				ddx, ddy := xs[i]-xs[j], ys[i]-ys[j]
+				d2 := max(ddx*ddx+ddy*ddy, 0.01)
+				f := k * k / d2
+				dx[i] += ddx * f
+				dy[i] += ddy * f
+				dx[j] -= ddx * f
+				dy[j] -= ddy * f
+			}
+		}
+		for _, l := range links {
+			i, j := l[0], l[1]
+			ddx, ddy := xs[i]-xs[j], ys[i]-ys[j]
+			d := math.Sqrt(ddx*ddx + ddy*ddy)
+			f := d / k
+			dx[i] -= ddx * f
+			dy[i] -= ddy * f
+			dx[j] += ddx * f
+			dy[j] += ddy * f
+		}
+		for i := 0; i < n; i++ {
+			d := math.Sqrt(dx[i]*dx[i] + dy[i]*dy[i])
+			if d == 0 || fixed[i] {
+				continue
+			}
+			step := min(d, temp)
+			xs[i] += dx[i] / d * step
+			ys[i] += dy[i] / d * step
+		}
+		temp *= 1 - 1/float64(iterations-it+1)
+	}
+
+	positions := make(map[string]models.Position, n)
+	for i, node := range nodes {
+		positions[node.ID] = models.Position{X: xs[i], Y: ys[i]}
+	}
+	return positions, nil
+}
+
+// spiral places the i-th node on a golden-angle spiral, spreading nodes evenly
+// around the origin.
+func spiral(i int) (x, y float64) {
+	r := spacing * math.Sqrt(float64(i))
+	theta := float64(i) * math.Pi * (3 - math.Sqrt(5))
+	return r * math.Cos(theta), r * math.Sin(theta)
+}
+
+// --- Hierarchical ---
+
+// folder is a directory in the hierarchical layout's tree.
+type folder struct {
+	notes    []string
+	children map[string]*folder
+	cols     int     // Columns in the grid of this folder's own notes
+	width    float64 // Width of this folder and everything under it
+}
+
+func (f *folder) child(name string) *folder {
+	if f.children == nil {
+		f.children = make(map[string]*folder)
+	}
+	c, ok := f.children[name]
+	if !ok {
+		c = &folder{}
+		f.children[name] = c
+	}
+	return c
+}
+
+func (f *folder) sortedChildren() []*folder {
+	names := make([]string, 0, len(f.children))
+	for name := range f.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	children := make([]*folder, len(names))
+	for i, name := range names {
+		children[i] = f.children[name]
+	}
+	return children
+}
+
+// measure computes the width of f's subtree: its own notes form a roughly
+// square grid, and subfolders sit side by side below it.
+func (f *folder) measure() float64 {
+	f.cols = int(math.Ceil(math.Sqrt(float64(len(f.notes)))))
+	childWidth := 0.0
+	for _, c := range f.sortedChildren() {
+		childWidth += c.measure()
+	}
+	f.width = max(float64(f.cols)*spacing, childWidth)
+	return f.width
+}
+
+// place positions f's notes in a grid centered in [x, x+width] starting at
+// row y, then places subfolders below.
+func (f *folder) place(positions map[string]models.Position, x, y float64) {
+	rows := 0
+	if f.cols > 0 {
+		rows = (len(f.notes) + f.cols - 1) / f.cols
+		left := x + (f.width-float64(f.cols)*spacing)/2 + spacing/2
+		for i, id := range f.notes {
+			positions[id] = models.Position{
+				X: left + float64(i%f.cols)*spacing,
+				Y: y + float64(i/f.cols)*spacing,
+			}
+		}
+	}
+
+	children := f.sortedChildren()
+	childWidth := 0.0
+	for _, c := range children {
+		childWidth += c.width
+	}
+	cx := x + (f.width-childWidth)/2
+	cy := y + float64(rows+1)*spacing
+	for _, c := range children {
+		c.place(positions, cx, cy)
+		cx += c.width
+	}
+}
+
+// hierarchical lays out the vault's folder tree top-down, grouping each
+// folder's notes in a grid beneath its parent folder.
+func hierarchical(nodes []Node) map[string]models.Position {
+	root := &folder{}
+	for _, n := range nodes {
+		f := root
+		if dir := path.Dir(n.Path); dir != "." && dir != "/" {
+			for _, part := range strings.Split(strings.Trim(dir, "/"), "/") {
+				f = f.child(part)
+			}
+		}
+		f.notes = append(f.notes, n.ID)
+	}
+
+	width := root.measure()
+	positions := make(map[string]models.Position, len(nodes))
+	root.place(positions, -width/2, 0)
+	return positions
+}
+
+// --- Radial ---
+
+// radial centers the node with the most links and places the rest on rings
+// by link distance from it. Nodes it cannot reach go on an outer ring.
+func radial(nodes []Node, edges []Edge) map[string]models.Position {
+	positions := make(map[string]models.Position, len(nodes))
+	if len(nodes) == 0 {
+		return positions
+	}
+	adj := adjacency(nodes, edges)
+
+	hub := nodes[0].ID
+	for _, n := range nodes[1:] {
+		if len(adj[n.ID]) > len(adj[hub]) {
+			hub = n.ID
+		}
+	}
+
+	// Breadth-first rings; each ring keeps its parents' order, so subtrees
+	// stay together and links mostly run outwards
+	visited := map[string]bool{hub: true}
+	rings := [][]string{{hub}}
+	for {
+		var next []string
+		for _, id := range rings[len(rings)-1] {
+			for _, other := range adj[id] {
+				if !visited[other] {
+					visited[other] = true
+					next = append(next, other)
+				}
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+		rings = append(rings, next)
+	}
+	var unreached []string
+	for _, n := range nodes {
+		if !visited[n.ID] {
+			unreached = append(unreached, n.ID)
+		}
+	}
+	if len(unreached) > 0 {
+		rings = append(rings, unreached)
+	}
+
+	positions[hub] = models.Position{}
+	radius := 0.0
+	for _, ring := range rings[1:] {
+		// Far enough out to keep both the ring spacing and the node spacing
+		radius = max(radius+spacing, float64(len(ring))*spacing/(2*math.Pi))
+		for i, id := range ring {
+			theta := 2 * math.Pi * float64(i) / float64(len(ring))
+			positions[id] = models.Position{X: radius * math.Cos(theta), Y: radius * math.Sin(theta)}
+		}
+	}
+	return positions
+}
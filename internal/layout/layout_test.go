@@ -0,0 +1,194 @@
+package layout
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testNodes = []Node{
+		{ID: "hub", Path: "hub.md"},
+		{ID: "a", Path: "projects/a.md"},
+		{ID: "b", Path: "projects/b.md"},
+		{ID: "c", Path: "projects/archive/c.md"},
+		{ID: "d", Path: "projects/d.md"},
+		{ID: "lonely", Path: "notes/lonely.md"},
+	}
+	testEdges = []Edge{
+		{Source: "hub", Target: "a"},
+		{Source: "hub", Target: "b"},
+		{Source: "hub", Target: "d"},
+		{Source: "b", Target: "c"},
+		{Source: "a", Target: "missing"},
+	}
+)
+
+func distance(p models.Position) float64 {
+	return math.Hypot(p.X, p.Y)
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	alg, err := ParseAlgorithm("radial")
+	require.NoError(t, err)
+	assert.Equal(t, Radial, alg)
+
+	_, err = ParseAlgorithm("spiral")
+	assert.Error(t, err)
+}
+
+func TestCompute(t *testing.T) {
+	for _, alg := range Algorithms {
+		t.Run(string(alg), func(t *testing.T) {
+			positions, err := Compute(context.Background(), alg, testNodes, testEdges, nil)
+			require.NoError(t, err)
+			require.Len(t, positions, len(testNodes))
+			for _, n := range testNodes {
+				p := positions[n.ID]
+				assert.False(t, math.IsNaN(p.X) || math.IsNaN(p.Y), "node %s", n.ID)
+			}
+
+			// Same input, same layout
+			again, err := Compute(context.Background(), alg, testNodes, testEdges, nil)
+			require.NoError(t, err)
+			assert.Equal(t, positions, again)
+		})
+	}
+}
+
+func TestCompute_Empty(t *testing.T) {
+	for _, alg := range Algorithms {
+		positions, err := Compute(context.Background(), alg, nil, nil, nil)
+		require.NoError(t, err)
+		assert.Empty(t, positions)
+	}
+}
+
+func TestForceDirected_LinkedNodesCloser(t *testing.T) {
+	positions, err := Compute(context.Background(), ForceDirected, testNodes, testEdges, nil)
+	require.NoError(t, err)
+
+	linked := distance(models.Position{X: positions["b"].X - positions["c"].X, Y: positions["b"].Y - positions["c"].Y})
+	unlinked := distance(models.Position{X: positions["c"].X - positions["lonely"].X, Y: positions["c"].Y - positions["lonely"].Y})
+	assert.Less(t, linked, unlinked)
+}
+
+func TestForceDirected_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Compute(ctx, ForceDirected, testNodes, testEdges, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCompute_Pinned(t *testing.T) {
+	pinned := map[string]models.Position{"hub": {X: 500, Y: 500}}
+	for _, alg := range Algorithms {
+		positions, err := Compute(context.Background(), alg, testNodes, testEdges, pinned)
+		require.NoError(t, err)
+		assert.NotContains(t, positions, "hub", alg)
+		assert.Len(t, positions, len(testNodes)-1, alg)
+	}
+
+	// Force-directed pulls linked nodes towards the pinned node
+	free, err := Compute(context.Background(), ForceDirected, testNodes, testEdges, nil)
+	require.NoError(t, err)
+	anchored, err := Compute(context.Background(), ForceDirected, testNodes, testEdges, pinned)
+	require.NoError(t, err)
+	assert.Greater(t, anchored["a"].X, free["a"].X)
+}
+
+func TestHierarchical(t *testing.T) {
+	positions, err := Compute(context.Background(), Hierarchical, testNodes, testEdges, nil)
+	require.NoError(t, err)
+
+	// Deeper folders sit lower; notes in one folder share a row
+	assert.Less(t, positions["hub"].Y, positions["a"].Y)
+	assert.Less(t, positions["a"].Y, positions["c"].Y)
+	assert.Equal(t, positions["a"].Y, positions["b"].Y)
+	assert.Equal(t, positions["a"].Y, positions["lonely"].Y)
+	assert.NotEqual(t, positions["a"].X, positions["b"].X)
+}
+
+func TestRadial(t *testing.T) {
+	positions, err := Compute(context.Background(), Radial, testNodes, testEdges, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.Position{}, positions["hub"])
+	assert.InDelta(t, distance(positions["a"]), distance(positions["b"]), 1e-9)
+	assert.Greater(t, distance(positions["c"]), distance(positions["a"]))
+	assert.Greater(t, distance(positions["lonely"]), distance(positions["c"]))
+}
+
+func newTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	s, err := store.NewMemory()
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func waitForJob(t *testing.T, s *store.Store, id string) *models.LayoutJob {
+	t.Helper()
+	var job *models.LayoutJob
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = s.GetLayoutJob(id)
+		require.NoError(t, err)
+		return job.Status == models.LayoutJobCompleted || job.Status == models.LayoutJobFailed
+	}, 5*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestRunner(t *testing.T) {
+	s := newTestStore(t)
+	vaultID, err := s.UpsertVault("test", "/tmp/test-vault")
+	require.NoError(t, err)
+	graphID, err := s.UpsertGraph(vaultID, "root", "", "")
+	require.NoError(t, err)
+	nodes := []models.VaultNode{
+		{ID: "a", VaultID: vaultID, FilePath: "a.md", Title: "A"},
+		{ID: "b", VaultID: vaultID, FilePath: "sub/b.md", Title: "B"},
+	}
+	require.NoError(t, s.ReplaceVaultData(vaultID, nodes, nil, map[int][]string{graphID: {"a", "b"}}))
+
+	r := NewRunner(s)
+	defer r.Shutdown()
+	completed := make(chan int, 1)
+	r.SetOnComplete(func(id int) { completed <- id })
+
+	job, err := r.Start(graphID, Hierarchical)
+	require.NoError(t, err)
+	assert.Equal(t, models.LayoutJobQueued, job.Status)
+
+	job = waitForJob(t, s, job.ID)
+	assert.Equal(t, models.LayoutJobCompleted, job.Status, job.Error)
+	assert.Equal(t, 2, job.Positions)
+	assert.NotNil(t, job.CompletedAt)
+	assert.Equal(t, graphID, <-completed)
+
+	positions, err := s.GetPositionsByGraph(graphID)
+	require.NoError(t, err)
+	assert.Len(t, positions, 2)
+	assert.Less(t, positions["a"].Y, positions["b"].Y)
+}
+
+func TestRecoverInterruptedJobs(t *testing.T) {
+	s := newTestStore(t)
+	job := &models.LayoutJob{ID: "j1", GraphID: 1, Algorithm: "radial", Status: models.LayoutJobRunning, CreatedAt: time.Now()}
+	require.NoError(t, s.SaveLayoutJob(job))
+
+	n, err := NewRunner(s).RecoverInterruptedJobs()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	got, err := s.GetLayoutJob("j1")
+	require.NoError(t, err)
+	assert.Equal(t, models.LayoutJobFailed, got.Status)
+	assert.NotEmpty(t, got.Error)
+}
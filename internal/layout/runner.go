@@ -0,0 +1,189 @@
+package layout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/store"
+	"github.com/google/uuid"
+)
+
+// maxConcurrentJobs bounds how many layouts are computed at once; further
+// jobs wait in the queued state.
+const maxConcurrentJobs = 2
+
+// ErrJobActive is returned by Start when the graph already has a layout job
+// queued or running.
+var ErrJobActive = errors.New("a layout job is already active for this graph")
+
+// Runner computes layouts in the background, recording each job's status in
+// the store and saving the results as graph positions.
+type Runner struct {
+	store      *store.Store
+	onComplete func(graphID int)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	slots  chan struct{}
+
+	mu     sync.Mutex
+	active map[int]string // graphID → ID of its queued or running job
+}
+
+// NewRunner creates a layout job runner.
+func NewRunner(s *store.Store) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		store:  s,
+		ctx:    ctx,
+		cancel: cancel,
+		slots:  make(chan struct{}, maxConcurrentJobs),
+		active: make(map[int]string),
+	}
+}
+
+// SetOnComplete registers a callback invoked after a job saves new positions
+// for a graph.
+func (r *Runner) SetOnComplete(fn func(graphID int)) {
+	r.onComplete = fn
+}
+
+// RecoverInterruptedJobs marks jobs left queued or running by a previous
+// process as failed. Call it once at startup, before starting jobs.
+func (r *Runner) RecoverInterruptedJobs() (int, error) {
+	return r.store.FailUnfinishedLayoutJobs("interrupted by server shutdown")
+}
+
+// Start queues a layout job for a graph and returns it immediately. Poll the
+// store's GetLayoutJob for its status.
+func (r *Runner) Start(graphID int, alg Algorithm) (*models.LayoutJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.active[graphID]; ok {
+		return nil, ErrJobActive
+	}
+	if r.ctx.Err() != nil {
+		return nil, errors.New("layout runner is shut down")
+	}
+
+	job := &models.LayoutJob{
+		ID:        uuid.NewString(),
+		GraphID:   graphID,
+		Algorithm: string(alg),
+		Status:    models.LayoutJobQueued,
+		CreatedAt: time.Now(),
+	}
+	if err := r.store.SaveLayoutJob(job); err != nil {
+		return nil, fmt.Errorf("save layout job: %w", err)
+	}
+	r.active[graphID] = job.ID
+
+	queued := *job
+	r.wg.Add(1)
+	go r.run(&queued, alg)
+	return job, nil
+}
+
+// Shutdown cancels queued and running jobs and waits for them to stop.
+func (r *Runner) Shutdown() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *Runner) run(job *models.LayoutJob, alg Algorithm) {
+	defer r.wg.Done()
+
+	select {
+	case r.slots <- struct{}{}:
+		defer func() { <-r.slots }()
+	case <-r.ctx.Done():
+		r.finish(job, r.ctx.Err())
+		return
+	}
+
+	job.Status = models.LayoutJobRunning
+	if err := r.store.SaveLayoutJob(job); err != nil {
+		log.Printf("Warning: failed to update layout job %s: %v", job.ID, err)
+	}
+	r.finish(job, r.compute(job, alg))
+}
+
+// compute lays out the job's graph and saves the positions of its unpinned
+// nodes.
+func (r *Runner) compute(job *models.LayoutJob, alg Algorithm) error {
+	graph, err := r.store.GetGraphData(job.GraphID)
+	if err != nil {
+		return fmt.Errorf("load graph: %w", err)
+	}
+	nodes := make([]Node, len(graph.Nodes))
+	for i, n := range graph.Nodes {
+		nodes[i] = Node{ID: n.ID, Path: n.FilePath}
+	}
+	edges := make([]Edge, len(graph.Edges))
+	for i, e := range graph.Edges {
+		edges[i] = Edge{Source: e.Source, Target: e.Target}
+	}
+
+	saved, err := r.store.GetPositionsByGraph(job.GraphID)
+	if err != nil {
+		return fmt.Errorf("load positions: %w", err)
+	}
+	pinned := make(map[string]models.Position)
+	for id, p := range saved {
+		if p.Pinned {
+			pinned[id] = p.ToPosition()
+		}
+	}
+
+	computed, err := Compute(r.ctx, alg, nodes, edges, pinned)
+	if err != nil {
+		return err
+	}
+	positions := make([]models.NodePosition, 0, len(computed))
+	for id, p := range computed {
+		positions = append(positions, models.NodePosition{NodeID: id, X: p.X, Y: p.Y, Z: p.Z})
+	}
+	// Nodes pinned since the positions were read are kept in place there
+	if err := r.store.SaveLayoutPositions(job.GraphID, positions); err != nil {
+		return fmt.Errorf("save positions: %w", err)
+	}
+	job.Positions = len(positions)
+	return nil
+}
+
+// finish records the job's outcome and notifies listeners of new positions.
+func (r *Runner) finish(job *models.LayoutJob, err error) {
+	now := time.Now()
+	job.CompletedAt = &now
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.Status = models.LayoutJobFailed
+		job.Error = "cancelled by server shutdown"
+	case err != nil:
+		job.Status = models.LayoutJobFailed
+		job.Error = err.Error()
+	default:
+		job.Status = models.LayoutJobCompleted
+	}
+
+	r.mu.Lock()
+	delete(r.active, job.GraphID)
+	r.mu.Unlock()
+	if err := r.store.SaveLayoutJob(job); err != nil {
+		log.Printf("Warning: failed to update layout job %s: %v", job.ID, err)
+	}
+	if job.Status == models.LayoutJobCompleted {
+		log.Printf("Computed %s layout for graph %d (%d nodes)", job.Algorithm, job.GraphID, job.Positions)
+		if r.onComplete != nil {
+			r.onComplete(job.GraphID)
+		}
+	} else {
+		log.Printf("Layout job %s for graph %d failed: %s", job.ID, job.GraphID, job.Error)
+	}
+}
@@ -0,0 +1,189 @@
+// Package linkcheck requests the external URLs notes link to and records
+// which are dead, once at start and then periodically.
+package linkcheck
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/store"
+)
+
+const (
+	// requestTimeout bounds each request, including redirects.
+	requestTimeout = 15 * time.Second
+
+	// workers is how many URLs are requested at once.
+	workers = 8
+
+	userAgent = "mnemosyne-linkcheck/1"
+)
+
+// Checker checks the external URLs in a store.
+type Checker struct {
+	store    *store.Store
+	client   *http.Client
+	interval time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewChecker creates a checker that checks every URL every interval once
+// started.
+func NewChecker(s *store.Store, interval time.Duration) *Checker {
+	return &Checker{
+		store:    s,
+		client:   &http.Client{Timeout: requestTimeout},
+		interval: interval,
+	}
+}
+
+// SetClient sets the HTTP client URLs are requested with.
+func (c *Checker) SetClient(client *http.Client) {
+	c.client = client
+}
+
+// Start checks every URL in the background, right away and then every
+// interval.
+func (c *Checker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			checked, dead, err := c.CheckAll(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				log.Printf("Warning: link check failed: %v", err)
+			} else {
+				log.Printf("Checked %d external link(s): %d dead", checked, dead)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops checking, abandoning a check in progress, and waits for the
+// background loop to finish.
+func (c *Checker) Stop() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Wait()
+}
+
+// CheckAll checks every URL stored nodes link to, least recently checked
+// first, and records the outcomes. Checks of URLs no longer linked are
+// deleted. It returns how many URLs were checked and how many were dead.
+func (c *Checker) CheckAll(ctx context.Context) (checked, dead int, err error) {
+	if _, err := c.store.PruneLinkChecks(); err != nil {
+		return 0, 0, err
+	}
+	urls, err := c.store.GetExternalURLs()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	jobs := make(chan string)
+	results := make(chan models.LinkCheck)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range jobs {
+				results <- c.Check(ctx, u)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, u := range urls {
+			select {
+			case jobs <- u:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for res := range results {
+		if ctx.Err() != nil {
+			continue // Interrupted requests say nothing about the URL
+		}
+		if err := c.store.SaveLinkCheck(res); err != nil {
+			log.Printf("Warning: failed to save check of %s: %v", res.URL, err)
+			continue
+		}
+		checked++
+		if res.Dead {
+			dead++
+		}
+	}
+	return checked, dead, ctx.Err()
+}
+
+// Check requests a URL, with HEAD and then with GET if the server does not
+// support HEAD, and reports whether it is dead.
+func (c *Checker) Check(ctx context.Context, url string) models.LinkCheck {
+	res := models.LinkCheck{URL: url}
+	status, err := c.request(ctx, http.MethodHead, url)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.request(ctx, http.MethodGet, url)
+	}
+	res.CheckedAt = time.Now().UTC()
+	if err != nil {
+		res.Dead = true
+		res.Error = err.Error()
+		return res
+	}
+	res.Status = status
+	res.Dead = isDead(status)
+	return res
+}
+
+func (c *Checker) request(ctx context.Context, method, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// Drain a little so the connection can be reused, but not whole pages
+	io.CopyN(io.Discard, resp.Body, 4096)
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// isDead reports whether a response status means the link is broken.
+// Statuses servers use to turn away automated clients (401, 403, 429) do not
+// count.
+func isDead(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return false
+	}
+	return status >= 400
+}
@@ -0,0 +1,93 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/mnemosynetest"
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/blocked", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCheck(t *testing.T) {
+	srv := newTestServer(t)
+	c := NewChecker(nil, time.Hour)
+	ctx := context.Background()
+
+	res := c.Check(ctx, srv.URL+"/ok")
+	assert.Equal(t, http.StatusOK, res.Status)
+	assert.False(t, res.Dead)
+	assert.False(t, res.CheckedAt.IsZero())
+
+	res = c.Check(ctx, srv.URL+"/gone")
+	assert.Equal(t, http.StatusNotFound, res.Status)
+	assert.True(t, res.Dead)
+
+	res = c.Check(ctx, srv.URL+"/get-only")
+	assert.Equal(t, http.StatusOK, res.Status, "falls back to GET")
+	assert.False(t, res.Dead)
+
+	res = c.Check(ctx, srv.URL+"/blocked")
+	assert.False(t, res.Dead)
+
+	res = c.Check(ctx, "http://127.0.0.1:1/unreachable")
+	assert.True(t, res.Dead)
+	assert.Zero(t, res.Status)
+	assert.NotEmpty(t, res.Error)
+}
+
+func TestCheckAll(t *testing.T) {
+	srv := newTestServer(t)
+	s := mnemosynetest.NewStore(t)
+	seed := mnemosynetest.SeedGraph(t, s)
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "c", VaultID: seed.VaultID, Title: "Links", FilePath: "links.md",
+		URLs:      models.StringArray{srv.URL + "/ok", srv.URL + "/gone"},
+		CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, s.SaveLinkCheck(models.LinkCheck{URL: "https://unlinked.example.com", CheckedAt: time.Now()}))
+
+	c := NewChecker(s, time.Hour)
+	checked, dead, err := c.CheckAll(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, checked)
+	assert.Equal(t, 1, dead)
+
+	links, err := s.GetExternalLinks(true)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, srv.URL+"/gone", links[0].URL)
+	assert.Equal(t, []string{"c"}, links[0].NodeIDs)
+
+	all, err := s.GetExternalLinks(false)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+	pruned, err := s.PruneLinkChecks()
+	require.NoError(t, err)
+	assert.Zero(t, pruned, "CheckAll already pruned the unlinked URL's check")
+}
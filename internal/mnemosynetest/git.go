@@ -0,0 +1,87 @@
+package mnemosynetest
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Repo is a git working tree for tests. Commits are made as a fixed test
+// author, so they need no git configuration.
+type Repo struct {
+	Dir string
+	t   testing.TB
+}
+
+// requireGit skips the test when git is not installed.
+func requireGit(t testing.TB) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+}
+
+// NewRepo initializes a repository in a new temporary directory and, if
+// files are given, commits them. It skips the test when git is not installed.
+func NewRepo(t testing.TB, files map[string]string) *Repo {
+	t.Helper()
+	requireGit(t)
+	r := &Repo{Dir: t.TempDir(), t: t}
+	r.Git("init", "-q")
+	if len(files) > 0 {
+		r.Commit(files)
+	}
+	return r
+}
+
+// NewClones creates a bare remote repository with files committed and pushed
+// from upstream, and a second clone of it, as a vault that pulls from the
+// remote. Commit to upstream and Push to give the clone something to pull.
+func NewClones(t testing.TB, files map[string]string) (upstream, clone *Repo) {
+	t.Helper()
+	requireGit(t)
+	root := t.TempDir()
+	remote := filepath.Join(root, "remote.git")
+	upstream = &Repo{Dir: filepath.Join(root, "upstream"), t: t}
+	clone = &Repo{Dir: filepath.Join(root, "clone"), t: t}
+
+	upstream.run(root, "init", "-q", "--bare", remote)
+	upstream.run(root, "clone", "-q", remote, upstream.Dir)
+	upstream.Commit(files)
+	upstream.Push()
+	clone.run(root, "clone", "-q", remote, clone.Dir)
+	return upstream, clone
+}
+
+// Git runs a git command in the repository and returns its trimmed output.
+func (r *Repo) Git(args ...string) string {
+	r.t.Helper()
+	return r.run(r.Dir, args...)
+}
+
+func (r *Repo) run(dir string, args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	out, err := cmd.CombinedOutput()
+	require.NoError(r.t, err, "git %s: %s", strings.Join(args, " "), out)
+	return strings.TrimSpace(string(out))
+}
+
+// Commit writes files as WriteFiles does, commits every change in the
+// working tree, and returns the commit's SHA.
+func (r *Repo) Commit(files map[string]string) string {
+	r.t.Helper()
+	WriteFiles(r.t, r.Dir, files)
+	r.Git("add", "-A")
+	r.Git("commit", "-q", "--allow-empty", "-m", "update")
+	return r.Git("rev-parse", "HEAD")
+}
+
+// Push pushes the current branch to origin.
+func (r *Repo) Push() {
+	r.t.Helper()
+	r.Git("push", "-q", "origin", "HEAD")
+}
@@ -0,0 +1,54 @@
+package mnemosynetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/store"
+)
+
+// NewStore returns an empty in-memory store, closed when the test ends.
+func NewStore(t testing.TB) *store.Store {
+	t.Helper()
+	s, err := store.NewMemory()
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// Seed identifies the data SeedGraph stored.
+type Seed struct {
+	VaultID int
+	GraphID int
+}
+
+// SeedGraph stores a small graph without parsing a vault: vault "test" at
+// /test with a root graph holding node "a" (Aviation, a hub, positioned at
+// 10,20) and node "b" (Economics, a note), and edge "e1" from a to b.
+func SeedGraph(t testing.TB, s *store.Store) Seed {
+	t.Helper()
+	vid, err := s.UpsertVault("test", "/test")
+	require.NoError(t, err)
+	gid, err := s.UpsertGraph(vid, "root", "", "")
+	require.NoError(t, err)
+
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "a", VaultID: vid, Title: "Aviation", FilePath: "aviation.md", NodeType: "hub",
+		Content: "All about aviation.", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, s.UpsertNode(&models.VaultNode{
+		ID: "b", VaultID: vid, Title: "Economics", FilePath: "econ.md", NodeType: "note",
+		Content: "Supply and demand.", CreatedAt: time.Now(), UpdatedAt: time.Now(),
+	}))
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{
+		ID: "e1", SourceID: "a", TargetID: "b", EdgeType: "wikilink", Weight: 1,
+	}))
+	require.NoError(t, s.ReplaceGraphMemberships("a", []int{gid}))
+	require.NoError(t, s.ReplaceGraphMemberships("b", []int{gid}))
+	require.NoError(t, s.UpsertPosition(gid, &models.NodePosition{NodeID: "a", X: 10, Y: 20}))
+
+	return Seed{VaultID: vid, GraphID: gid}
+}
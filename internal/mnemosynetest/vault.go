@@ -0,0 +1,77 @@
+// Package mnemosynetest provides fixtures for tests of the indexer, API and
+// other packages that need vaults on disk, git repositories, or a store with
+// data in it. Helpers fail the test on error rather than returning one.
+package mnemosynetest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// WriteFiles writes files, keyed by path relative to dir, creating
+// directories as needed. Files with empty content are removed instead.
+func WriteFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if content == "" {
+			err := os.Remove(path)
+			if !os.IsNotExist(err) {
+				require.NoError(t, err)
+			}
+			continue
+		}
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+// NewVault writes files to a new temporary directory and returns its path.
+// It is removed when the test ends.
+func NewVault(t testing.TB, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	WriteFiles(t, dir, files)
+	return dir
+}
+
+// Note returns a note with id in its frontmatter, a title heading, and a
+// wikilink to each of links.
+func Note(id, title string, links ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "---\nid: %q\n---\n# %s\n", id, title)
+	for _, l := range links {
+		fmt.Fprintf(&b, "\n[[%s]]", l)
+	}
+	if len(links) > 0 {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// GeneratedNoteID returns the ID of the i-th note of GenerateVault.
+func GeneratedNoteID(i int) string {
+	return fmt.Sprintf("note-%04d", i)
+}
+
+// GenerateVault returns the files of a vault with a GRAPH.yaml and n notes,
+// notes/note-NNNN.md, each linking to the next linksPerNote notes (wrapping
+// around). It is deterministic, so graph sizes can be asserted: n nodes and
+// n*min(linksPerNote, n-1) edges.
+func GenerateVault(n, linksPerNote int) map[string]string {
+	files := map[string]string{"GRAPH.yaml": "name: generated\n"}
+	for i := 0; i < n; i++ {
+		var links []string
+		for j := 1; j <= linksPerNote && j < n; j++ {
+			links = append(links, GeneratedNoteID((i+j)%n))
+		}
+		id := GeneratedNoteID(i)
+		files["notes/"+id+".md"] = Note(id, "Note "+id, links...)
+	}
+	return files
+}
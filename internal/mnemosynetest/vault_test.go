@@ -0,0 +1,33 @@
+package mnemosynetest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/indexer"
+	"github.com/ali01/mnemosyne/internal/mnemosynetest"
+)
+
+func TestGenerateVault(t *testing.T) {
+	dir := mnemosynetest.NewVault(t, mnemosynetest.GenerateVault(20, 3))
+	s := mnemosynetest.NewStore(t)
+	m := indexer.NewIndexManager(s)
+	vaultID, graphIDs, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	g, err := s.GetGraphData(graphIDs[0])
+	require.NoError(t, err)
+	assert.Len(t, g.Nodes, 20)
+	assert.Len(t, g.Edges, 60)
+}
+
+func TestRepoCommit(t *testing.T) {
+	repo := mnemosynetest.NewRepo(t, map[string]string{"a.md": mnemosynetest.Note("a", "A")})
+	first := repo.Git("rev-parse", "HEAD")
+	second := repo.Commit(map[string]string{"a.md": "", "b.md": mnemosynetest.Note("b", "B", "a")})
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, "b.md", repo.Git("ls-files"))
+}
@@ -1,7 +1,105 @@
 package models
 
+import "time"
+
 // Graph represents the complete graph structure
 type Graph struct {
 	Nodes []Node `json:"nodes"`
 	Edges []Edge `json:"edges"`
-}
\ No newline at end of file
+}
+
+// Share is a read-only snapshot of a subgraph, addressable by an unguessable token.
+type Share struct {
+	Token     string    `json:"token"`
+	Title     string    `json:"title,omitempty"`
+	GraphID   int       `json:"graph_id"`
+	Graph     Graph     `json:"graph"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Comment is a note a user attached to a graph node, stored outside the vault.
+type Comment struct {
+	ID        int       `json:"id"`
+	NodeID    string    `json:"node_id"`
+	Author    string    `json:"author,omitempty"` // Empty for anonymous comments
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Bookmark is a node a user starred for quick access.
+type Bookmark struct {
+	NodeID    string    `json:"node_id"`
+	Title     string    `json:"title"`
+	FilePath  string    `json:"file_path"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NodeViews summarizes how often a node's content has been viewed.
+type NodeViews struct {
+	NodeID       string    `json:"node_id"`
+	Title        string    `json:"title"`
+	FilePath     string    `json:"file_path"`
+	Views        int       `json:"views"`
+	LastViewedAt time.Time `json:"last_viewed_at"`
+}
+
+// LayoutJobStatus is the state of a server-side layout job.
+type LayoutJobStatus string
+
+const (
+	LayoutJobQueued    LayoutJobStatus = "queued"
+	LayoutJobRunning   LayoutJobStatus = "running"
+	LayoutJobCompleted LayoutJobStatus = "completed"
+	LayoutJobFailed    LayoutJobStatus = "failed"
+)
+
+// LayoutJob computes positions for a graph with a layout algorithm. When it
+// completes, the results are saved as the graph's positions.
+type LayoutJob struct {
+	ID          string          `json:"id"`
+	GraphID     int             `json:"graph_id"`
+	Algorithm   string          `json:"algorithm"`
+	Status      LayoutJobStatus `json:"status"`
+	Positions   int             `json:"positions,omitempty"` // Nodes positioned, once completed
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
+
+// ExternalLink is an http(s) URL notes link to, with the outcome of the last
+// link check, if any.
+type ExternalLink struct {
+	URL       string     `json:"url"`
+	NodeIDs   []string   `json:"node_ids"`
+	Status    int        `json:"status,omitempty"` // HTTP status of the last check; 0 if the request failed
+	Dead      bool       `json:"dead"`
+	Error     string     `json:"error,omitempty"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"` // Nil until checked
+}
+
+// BrokenLink is a link target that resolves to no node, with the notes
+// linking to it.
+type BrokenLink struct {
+	Target  string   `json:"target"`
+	NodeIDs []string `json:"node_ids"`
+}
+
+// NearDuplicate is a pair of notes whose contents are nearly identical, A
+// sorting before B. Similarity estimates the Jaccard similarity of their
+// word shingles, from 0 to 1.
+type NearDuplicate struct {
+	A          string    `json:"a"`
+	B          string    `json:"b"`
+	Similarity float64   `json:"similarity"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// LinkCheck is the outcome of requesting an external URL.
+type LinkCheck struct {
+	URL       string
+	Status    int // 0 if the request failed
+	Dead      bool
+	Error     string
+	CheckedAt time.Time
+}
@@ -0,0 +1,247 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetadataType is a declared type for a frontmatter field.
+type MetadataType string
+
+const (
+	MetadataString  MetadataType = "string"
+	MetadataNumber  MetadataType = "number"
+	MetadataInteger MetadataType = "integer"
+	MetadataBoolean MetadataType = "boolean"
+	MetadataDate    MetadataType = "date"
+	MetadataList    MetadataType = "list"
+)
+
+// MetadataKey summarizes how a frontmatter key is used across the vault.
+type MetadataKey struct {
+	Key   string         `json:"key"`
+	Count int            `json:"count"` // number of notes with the key
+	Type  MetadataType   `json:"type"`  // most common inferred type
+	Types map[string]int `json:"types"` // inferred type -> number of notes
+}
+
+// dateLayouts are the formats accepted when coercing a value to a date.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+}
+
+// IsValid reports whether t is a known metadata type.
+func (t MetadataType) IsValid() bool {
+	switch t {
+	case MetadataString, MetadataNumber, MetadataInteger, MetadataBoolean, MetadataDate, MetadataList:
+		return true
+	}
+	return false
+}
+
+// GetString returns a field as a string. Scalars are formatted; lists are not strings.
+func (m JSONMetadata) GetString(key string) (string, bool) {
+	v, ok := m[key]
+	if !ok || v == nil {
+		return "", false
+	}
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case []interface{}, map[string]interface{}:
+		return "", false
+	case time.Time:
+		return val.Format(time.RFC3339), true
+	default:
+		return fmt.Sprintf("%v", val), true
+	}
+}
+
+// GetFloat returns a field as a float64, parsing numeric strings. NaN and
+// infinities are rejected, since JSON cannot hold them.
+func (m JSONMetadata) GetFloat(key string) (float64, bool) {
+	v, ok := m[key]
+	if !ok {
+		return 0, false
+	}
+	return toFloat(v)
+}
+
+// GetInt returns a field as an int. Fractional numbers are rejected.
+func (m JSONMetadata) GetInt(key string) (int, bool) {
+	f, ok := m.GetFloat(key)
+	if !ok || f != math.Trunc(f) {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// GetBool returns a field as a bool, accepting "true"/"false"/"yes"/"no" strings.
+func (m JSONMetadata) GetBool(key string) (bool, bool) {
+	v, ok := m[key]
+	if !ok {
+		return false, false
+	}
+	return toBool(v)
+}
+
+// GetTime returns a field as a time, parsing common date formats.
+func (m JSONMetadata) GetTime(key string) (time.Time, bool) {
+	v, ok := m[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	return toTime(v)
+}
+
+// GetStringSlice returns a field as a string list. A scalar becomes a one-element
+// list and a comma-separated string is split.
+func (m JSONMetadata) GetStringSlice(key string) ([]string, bool) {
+	v, ok := m[key]
+	if !ok || v == nil {
+		return nil, false
+	}
+	return toStringSlice(v)
+}
+
+// Coerce projects metadata into typed values according to schema. Fields not in
+// the schema are passed through unchanged. Fields that fail coercion are omitted
+// from the result and reported in the returned error map (field -> message).
+func (m JSONMetadata) Coerce(schema map[string]MetadataType) (map[string]interface{}, map[string]string) {
+	typed := make(map[string]interface{}, len(m))
+	errs := make(map[string]string)
+
+	for key, raw := range m {
+		typ, declared := schema[key]
+		if !declared {
+			typed[key] = raw
+			continue
+		}
+
+		var (
+			val interface{}
+			ok  bool
+		)
+		switch typ {
+		case MetadataString:
+			val, ok = m.GetString(key)
+		case MetadataNumber:
+			val, ok = m.GetFloat(key)
+		case MetadataInteger:
+			val, ok = m.GetInt(key)
+		case MetadataBoolean:
+			val, ok = m.GetBool(key)
+		case MetadataDate:
+			val, ok = m.GetTime(key)
+		case MetadataList:
+			val, ok = m.GetStringSlice(key)
+		}
+		if !ok {
+			errs[key] = fmt.Sprintf("cannot convert %v to %s", raw, typ)
+			continue
+		}
+		typed[key] = val
+	}
+
+	return typed, errs
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	var f float64
+	switch val := v.(type) {
+	case float64:
+		f = val
+	case float32:
+		f = float64(val)
+	case int:
+		f = float64(val)
+	case int64:
+		f = float64(val)
+	case string:
+		var err error
+		if f, err = strconv.ParseFloat(strings.TrimSpace(val), 64); err != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+func toBool(v interface{}) (bool, bool) {
+	switch val := v.(type) {
+	case bool:
+		return val, true
+	case string:
+		switch strings.ToLower(strings.TrimSpace(val)) {
+		case "true", "yes", "on", "1":
+			return true, true
+		case "false", "no", "off", "0":
+			return false, true
+		}
+	case float64:
+		if val == 0 || val == 1 {
+			return val == 1, true
+		}
+	case int:
+		if val == 0 || val == 1 {
+			return val == 1, true
+		}
+	}
+	return false, false
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, true
+	case string:
+		s := strings.TrimSpace(val)
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func toStringSlice(v interface{}) ([]string, bool) {
+	switch val := v.(type) {
+	case []string:
+		return val, true
+	case []interface{}:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			if item == nil {
+				continue
+			}
+			out = append(out, fmt.Sprintf("%v", item))
+		}
+		return out, true
+	case string:
+		parts := strings.Split(val, ",")
+		out := make([]string, 0, len(parts))
+		for _, p := range parts {
+			if p = strings.TrimSpace(p); p != "" {
+				out = append(out, p)
+			}
+		}
+		return out, true
+	case map[string]interface{}:
+		return nil, false
+	default:
+		return []string{fmt.Sprintf("%v", val)}, true
+	}
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONMetadata_TypedAccessors(t *testing.T) {
+	m := JSONMetadata{
+		"title":   "Note",
+		"reviews": "3",
+		"score":   4.5,
+		"draft":   "yes",
+		"due":     "2024-06-01",
+		"aliases": []interface{}{"a", "b"},
+		"topics":  "x, y ,z",
+	}
+
+	s, ok := m.GetString("title")
+	assert.True(t, ok)
+	assert.Equal(t, "Note", s)
+
+	n, ok := m.GetInt("reviews")
+	assert.True(t, ok)
+	assert.Equal(t, 3, n)
+
+	_, ok = m.GetInt("score")
+	assert.False(t, ok, "fractional numbers are not integers")
+
+	f, ok := m.GetFloat("score")
+	assert.True(t, ok)
+	assert.InDelta(t, 4.5, f, 0.001)
+
+	b, ok := m.GetBool("draft")
+	assert.True(t, ok)
+	assert.True(t, b)
+
+	d, ok := m.GetTime("due")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), d)
+
+	l, ok := m.GetStringSlice("aliases")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a", "b"}, l)
+
+	l, ok = m.GetStringSlice("topics")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"x", "y", "z"}, l)
+
+	_, ok = m.GetString("missing")
+	assert.False(t, ok)
+}
+
+func TestJSONMetadata_Coerce(t *testing.T) {
+	m := JSONMetadata{
+		"reviews": "3",
+		"draft":   "maybe",
+		"tags":    []interface{}{"t"},
+		"other":   "unchanged",
+	}
+	schema := map[string]MetadataType{
+		"reviews": MetadataInteger,
+		"draft":   MetadataBoolean,
+		"tags":    MetadataList,
+	}
+
+	typed, errs := m.Coerce(schema)
+	assert.Equal(t, 3, typed["reviews"])
+	assert.Equal(t, []string{"t"}, typed["tags"])
+	assert.Equal(t, "unchanged", typed["other"])
+	assert.NotContains(t, typed, "draft")
+	assert.Contains(t, errs, "draft")
+
+	// Numbers JSON cannot hold are coercion errors
+	m = JSONMetadata{"a": "NaN", "b": "Inf", "c": "-Infinity", "d": math.Inf(1), "e": "1e400"}
+	typed, errs = m.Coerce(map[string]MetadataType{"a": MetadataNumber, "b": MetadataNumber, "c": MetadataNumber, "d": MetadataInteger, "e": MetadataNumber})
+	assert.Empty(t, typed)
+	assert.Len(t, errs, 5)
+}
+
+func TestMetadataType_IsValid(t *testing.T) {
+	assert.True(t, MetadataDate.IsValid())
+	assert.False(t, MetadataType("uuid").IsValid())
+}
@@ -3,14 +3,26 @@ package models
 
 // Node represents a single node in the knowledge graph
 type Node struct {
-	ID       string                 `json:"id"`
-	Title    string                 `json:"title"`
-	FilePath string                 `json:"file_path,omitempty"`
-	Content  string                 `json:"content,omitempty"`
-	Position Position               `json:"position"`
-	Level    int                    `json:"level"`
-	Color    string                 `json:"color,omitempty"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	ID          string                 `json:"id"`
+	Title       string                 `json:"title"`
+	FilePath    string                 `json:"file_path,omitempty"`
+	Content     string                 `json:"content,omitempty"`
+	Position    Position               `json:"position"`
+	Pinned      bool                   `json:"pinned,omitempty"`
+	Level       int                    `json:"level"`
+	Color       string                 `json:"color,omitempty"`
+	WordCount   int                    `json:"word_count,omitempty"`
+	ReadingTime int                    `json:"reading_time,omitempty"` // minutes
+	Excerpt     string                 `json:"excerpt,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Heading is a single entry in a note's heading outline
+type Heading struct {
+	Level int    `json:"level"` // 1-6, number of leading '#'
+	Text  string `json:"text"`
+	Slug  string `json:"slug"` // anchor for section deep-linking
+	Line  int    `json:"line"` // 1-based line number in the file
 }
 
 // Position represents the 3D coordinates of a node in the graph visualization
@@ -27,4 +39,8 @@ type Edge struct {
 	Target string  `json:"target"`
 	Weight float64 `json:"weight"`
 	Type   string  `json:"type"`
+
+	// Bidirectional is set, when requested, on edges whose target also links
+	// back to their source.
+	Bidirectional bool `json:"bidirectional,omitempty"`
 }
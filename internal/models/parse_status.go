@@ -8,11 +8,20 @@ import "time"
 // ParseStatusResponse represents the current status of a parse operation
 // This is used for API responses to check parsing progress
 type ParseStatusResponse struct {
-	Status      string         `json:"status"`                    // "idle", "pending", "running", "completed", "failed"
+	VaultID     int            `json:"vault_id,omitempty"`
+	Status      string         `json:"status"`                    // "idle", "pending", "running", "completed", "failed", "cancelled"
 	StartedAt   *time.Time     `json:"started_at,omitempty"`
 	CompletedAt *time.Time     `json:"completed_at,omitempty"`
 	Progress    *ParseProgress `json:"progress,omitempty"`
 	Error       string         `json:"error,omitempty"`
+	Queue       []QueuedParse  `json:"queue,omitempty"` // Full indexes waiting to run, next first
+}
+
+// QueuedParse is a full index waiting for the running one to finish.
+type QueuedParse struct {
+	ID       string    `json:"id"`
+	Position int       `json:"position"` // 1 runs next
+	QueuedAt time.Time `json:"queued_at"`
 }
 
 // ParseProgress represents real-time progress of a parse operation
@@ -22,6 +31,34 @@ type ParseProgress struct {
 	NodesCreated   int `json:"nodes_created,omitempty"`
 	EdgesCreated   int `json:"edges_created,omitempty"`
 	ErrorCount     int `json:"error_count,omitempty"`
+
+	Phase      ParsePhase      `json:"phase,omitempty"`       // Phase currently running
+	Phases     []PhaseProgress `json:"phases,omitempty"`      // All phases in execution order
+	Percent    float64         `json:"percent"`               // Overall completion, 0-100
+	ETASeconds *float64        `json:"eta_seconds,omitempty"` // Omitted when there is nothing to estimate from
+}
+
+// ParsePhase is a stage of a full vault index.
+type ParsePhase string
+
+const (
+	ParsePhasePull     ParsePhase = "pull"     // Fetch the vault from its source
+	ParsePhaseParse    ParsePhase = "parse"    // Read and parse markdown files
+	ParsePhaseBuild    ParsePhase = "build"    // Build nodes and edges
+	ParsePhaseClassify ParsePhase = "classify" // Run before-store hooks (e.g. Lua classify/enrich)
+	ParsePhaseStore    ParsePhase = "store"    // Write to the database
+)
+
+// ParsePhases lists the phases of a full index in execution order.
+var ParsePhases = []ParsePhase{
+	ParsePhasePull, ParsePhaseParse, ParsePhaseBuild, ParsePhaseClassify, ParsePhaseStore,
+}
+
+// PhaseProgress reports the progress of one phase.
+type PhaseProgress struct {
+	Name       ParsePhase `json:"name"`
+	Percent    float64    `json:"percent"`               // 0-100
+	DurationMS int64      `json:"duration_ms,omitempty"` // Set once the phase completes
 }
 
 // mapParseStatus safely converts internal ParseStatus constants to API response values
@@ -38,6 +75,8 @@ func mapParseStatus(status ParseStatus) string {
 		return "completed"
 	case ParseStatusFailed:
 		return "failed"
+	case ParseStatusCancelled:
+		return "cancelled"
 	default:
 		// Fallback to idle for unknown status to ensure type safety
 		return "idle"
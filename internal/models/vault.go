@@ -3,25 +3,34 @@ package models
 
 import (
 	"fmt"
+	"math"
 	"time"
 )
 
 // VaultNode represents a node in the knowledge graph with vault-specific metadata
 // Bridge between file-centric parser output and node-centric graph visualization
 type VaultNode struct {
-	ID         string       `json:"id" db:"id" validate:"required,min=1"`                     // Required: from frontmatter
-	VaultID    int          `json:"vault_id" db:"vault_id"`                                   // Vault this node belongs to
-	Title      string       `json:"title" db:"title" validate:"required,min=1"`               // From frontmatter or filename fallback
-	NodeType   string       `json:"node_type" db:"node_type" validate:"omitempty,min=1"`      // Calculated node type from configuration
-	Tags       StringArray  `json:"tags,omitempty" db:"tags" validate:"omitempty,dive,min=1"` // From frontmatter tags field
-	Content    string       `json:"content,omitempty" db:"content"`                           // Full markdown content
-	Metadata   JSONMetadata `json:"metadata,omitempty" db:"metadata"`                         // All frontmatter fields
-	FilePath   string       `json:"file_path" db:"file_path" validate:"required,min=1"`       // Original file location
-	InDegree   int          `json:"in_degree" db:"in_degree" validate:"min=0"`                // Number of incoming links
-	OutDegree  int          `json:"out_degree" db:"out_degree" validate:"min=0"`              // Number of outgoing links
-	Centrality float64      `json:"centrality" db:"centrality" validate:"min=0,max=1"`        // PageRank or similar metric
-	CreatedAt  time.Time    `json:"created_at" db:"created_at" validate:"required"`
-	UpdatedAt  time.Time    `json:"updated_at" db:"updated_at" validate:"required"`
+	ID          string       `json:"id" db:"id" validate:"required,min=1"`                     // Required: from frontmatter
+	VaultID     int          `json:"vault_id" db:"vault_id"`                                   // Vault this node belongs to
+	Title       string       `json:"title" db:"title" validate:"required,min=1"`               // From frontmatter or filename fallback
+	NodeType    string       `json:"node_type" db:"node_type" validate:"omitempty,min=1"`      // Calculated node type from configuration
+	Tags        StringArray  `json:"tags,omitempty" db:"tags" validate:"omitempty,dive,min=1"` // From frontmatter tags field
+	Content     string       `json:"content,omitempty" db:"content"`                           // Full markdown content
+	Metadata    JSONMetadata `json:"metadata,omitempty" db:"metadata"`                         // All frontmatter fields
+	Outline     []Heading    `json:"outline,omitempty" db:"outline"`                           // Heading structure of the content
+	FilePath    string       `json:"file_path" db:"file_path" validate:"required,min=1"`       // Original file location
+	InDegree    int          `json:"in_degree" db:"in_degree" validate:"min=0"`                // Number of incoming links
+	OutDegree   int          `json:"out_degree" db:"out_degree" validate:"min=0"`              // Number of outgoing links
+	WordCount   int          `json:"word_count" db:"word_count" validate:"min=0"`              // Words in the note body
+	ReadingTime int          `json:"reading_time" db:"reading_time" validate:"min=0"`          // Estimated reading time in minutes
+	Excerpt     string       `json:"excerpt,omitempty" db:"excerpt"`                           // First sentences of the body as plain text, for previews
+	URLs        StringArray  `json:"urls,omitempty" db:"-"`                                    // http(s) URLs in the body, stored in node_links
+	Unresolved  StringArray  `json:"unresolved,omitempty" db:"-"`                              // Link targets no node resolves, stored in unresolved_links
+	Potential   []VaultEdge  `json:"potential_edges,omitempty" db:"-"`                         // Links the note could have, e.g. unlinked mentions, stored in potential_edges
+	Centrality  float64      `json:"centrality" db:"centrality" validate:"min=0,max=1"`        // PageRank or similar metric
+	Level       int          `json:"level" db:"level" validate:"min=0"`                        // 1 for hub notes, one more per link away from the nearest
+	CreatedAt   time.Time    `json:"created_at" db:"created_at" validate:"required"`
+	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at" validate:"required"`
 }
 
 // VaultEdge represents a connection between ideas in the knowledge graph
@@ -30,7 +39,7 @@ type VaultEdge struct {
 	ID          string    `json:"id" db:"id" validate:"required,uuid4"`                                // Auto-generated UUID
 	SourceID    string    `json:"source_id" db:"source_id" validate:"required,min=1"`                  // Node ID of link source
 	TargetID    string    `json:"target_id" db:"target_id" validate:"required,min=1,nefield=SourceID"` // Node ID of link target
-	EdgeType    string    `json:"edge_type" db:"edge_type" validate:"required,oneof=wikilink embed"`   // "wikilink" or "embed"
+	EdgeType    string    `json:"edge_type" db:"edge_type" validate:"required,max=50"`                 // "wikilink", "embed", or a custom link extractor type
 	DisplayText string    `json:"display_text,omitempty" db:"display_text"`                            // Link alias or section reference
 	Weight      float64   `json:"weight" db:"weight" validate:"min=0"`                                 // Default 1.0, for future use
 	CreatedAt   time.Time `json:"created_at" db:"created_at" validate:"required"`
@@ -45,6 +54,7 @@ type NodePosition struct {
 	Y         float64   `db:"y" json:"y"`
 	Z         float64   `db:"z" json:"z"`
 	Locked    bool      `db:"locked" json:"locked"` // Whether user has manually positioned this node
+	Pinned    bool      `db:"pinned" json:"pinned"` // Whether server-side layouts must leave this node in place
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
 
@@ -57,6 +67,37 @@ func (np *NodePosition) ToPosition() Position {
 	}
 }
 
+// FieldError reports an invalid field of a model, named by its JSON key.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// Validate checks that the position's coordinates are finite and, when
+// maxCoordinate is positive, no further than it from the origin on any axis.
+// It returns a *FieldError naming the first invalid field.
+func (np *NodePosition) Validate(maxCoordinate float64) error {
+	if np.NodeID == "" {
+		return &FieldError{"node_id", "is required"}
+	}
+	for _, c := range []struct {
+		field string
+		value float64
+	}{{"x", np.X}, {"y", np.Y}, {"z", np.Z}} {
+		if math.IsNaN(c.value) || math.IsInf(c.value, 0) {
+			return &FieldError{c.field, "must be a finite number"}
+		}
+		if maxCoordinate > 0 && math.Abs(c.value) > maxCoordinate {
+			return &FieldError{c.field, fmt.Sprintf("must be between %g and %g", -maxCoordinate, maxCoordinate)}
+		}
+	}
+	return nil
+}
+
 // VaultMetadata stores key-value metadata about the vault
 type VaultMetadata struct {
 	Key       string    `db:"key" json:"key" validate:"required,min=1"`
@@ -67,6 +108,7 @@ type VaultMetadata struct {
 // ParseHistory tracks vault parsing operations
 type ParseHistory struct {
 	ID          string      `db:"id" json:"id" validate:"required"`
+	VaultID     int         `db:"vault_id" json:"vault_id"`
 	StartedAt   time.Time   `db:"started_at" json:"started_at"`
 	CompletedAt *time.Time  `db:"completed_at" json:"completed_at"`
 	Status      ParseStatus `db:"status" json:"status" validate:"required"`
@@ -74,15 +116,60 @@ type ParseHistory struct {
 	Error       *string     `db:"error" json:"error,omitempty"`
 }
 
+// VaultHealth scores how well kept a vault's graph is, as of its last full
+// index: 100 for a vault whose notes are all linked and tagged, with no
+// broken links or duplicate IDs, less the more of each problem it has.
+type VaultHealth struct {
+	VaultID         int                    `json:"vault_id"`
+	ParseID         string                 `json:"parse_id"` // Full index it was computed after
+	ComputedAt      time.Time              `json:"computed_at"`
+	Score           int                    `json:"score"` // 0 to 100
+	Notes           int                    `json:"notes"`
+	Orphans         int                    `json:"orphans"` // Notes with no links in or out
+	OrphanRatio     float64                `json:"orphan_ratio"`
+	Links           int                    `json:"links"`
+	BrokenLinks     int                    `json:"broken_links"`
+	BrokenLinkRatio float64                `json:"broken_link_ratio"`
+	DuplicateIDs    int                    `json:"duplicate_ids"`   // IDs claimed by more than one file
+	DuplicateFiles  int                    `json:"duplicate_files"` // Files left out for reusing an ID
+	Untagged        int                    `json:"untagged"`
+	UntaggedRatio   float64                `json:"untagged_ratio"`
+	Recommendations []HealthRecommendation `json:"recommendations"` // Largest penalty first
+}
+
+// HealthRecommendation is a change that would raise a vault's health score.
+type HealthRecommendation struct {
+	Metric   string   `json:"metric"`  // orphans, broken_links, duplicate_ids or untagged
+	Penalty  int      `json:"penalty"` // Points the problem costs the score
+	Message  string   `json:"message"`
+	Examples []string `json:"examples,omitempty"` // A few of the files involved
+}
+
+// ParseFileError is an error parsing one file during a full index.
+type ParseFileError struct {
+	FilePath string `json:"file_path"`
+	Kind     string `json:"kind"`           // read, frontmatter, encoding, symlink, size, binary, config or hook
+	Line     int    `json:"line,omitempty"` // Line of the file the error is on, from 1
+	Message  string `json:"message"`
+}
+
+// ParseLogRecord is a line a full index logged.
+type ParseLogRecord struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"` // info or warning
+	Message string    `json:"message"`
+}
+
 // ParseStatus represents the status of a parse operation
 type ParseStatus string
 
 const (
-	ParseStatusIdle      ParseStatus = "idle"      // No parse has been performed
+	ParseStatusIdle      ParseStatus = "idle" // No parse has been performed
 	ParseStatusPending   ParseStatus = "pending"
 	ParseStatusRunning   ParseStatus = "running"
 	ParseStatusCompleted ParseStatus = "completed"
 	ParseStatusFailed    ParseStatus = "failed"
+	ParseStatusCancelled ParseStatus = "cancelled"
 )
 
 // ParseStats contains statistics about a parse operation
@@ -93,6 +180,19 @@ type ParseStats struct {
 	TotalEdges      int   `json:"total_edges"`
 	DurationMS      int64 `json:"duration_ms"` // Duration in milliseconds
 	UnresolvedLinks int   `json:"unresolved_links"`
+	CachedFiles     int   `json:"cached_files,omitempty"` // Unchanged files reused from the file cache
+
+	PhaseDurations map[ParsePhase]int64 `json:"phase_durations_ms,omitempty"` // Milliseconds per phase
+	Phase          ParsePhase           `json:"phase,omitempty"`              // Last phase reached, as of the latest checkpoint
+}
+
+// CachedFile is a parsed markdown file stored under the hash of its content.
+// Data is opaque to everything but the parser.
+type CachedFile struct {
+	Path   string
+	Hash   string
+	Data   string
+	NodeID string // ID of the node the file produced, if any
 }
 
 // Validate performs validation on VaultNode fields
@@ -149,8 +249,8 @@ func (e *VaultEdge) Validate() error {
 	if e.SourceID == e.TargetID {
 		return fmt.Errorf("self-referential edges are not allowed")
 	}
-	if e.EdgeType != "wikilink" && e.EdgeType != "embed" {
-		return fmt.Errorf("edge type must be 'wikilink' or 'embed', got: %s", e.EdgeType)
+	if e.EdgeType == "" {
+		return fmt.Errorf("edge type is required")
 	}
 	if e.Weight < 0 {
 		return fmt.Errorf("edge weight cannot be negative")
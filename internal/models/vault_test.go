@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	"testing"
 	"time"
@@ -1000,7 +1001,7 @@ func TestVaultEdge_Validation(t *testing.T) {
 				ID:        "550e8400-e29b-41d4-a716-446655440000",
 				SourceID:  "source",
 				TargetID:  "target",
-				EdgeType:  "invalid",
+				EdgeType:  strings.Repeat("x", 51),
 				CreatedAt: time.Now(),
 			},
 			shouldError: true,
@@ -1194,4 +1195,33 @@ func TestVaultNode_MemoryUsage(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestNodePosition_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		pos       NodePosition
+		max       float64
+		wantField string
+	}{
+		{"valid", NodePosition{NodeID: "a", X: 10, Y: -20, Z: 5}, 100, ""},
+		{"on the bound", NodePosition{NodeID: "a", X: 100, Y: -100}, 100, ""},
+		{"missing node ID", NodePosition{X: 1}, 100, "node_id"},
+		{"NaN", NodePosition{NodeID: "a", X: math.NaN()}, 100, "x"},
+		{"infinite", NodePosition{NodeID: "a", Y: math.Inf(-1)}, 0, "y"},
+		{"out of bounds", NodePosition{NodeID: "a", Z: 100.5}, 100, "z"},
+		{"unbounded", NodePosition{NodeID: "a", X: 1e300}, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.pos.Validate(tt.max)
+			if tt.wantField == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var fe *FieldError
+			require.ErrorAs(t, err, &fe)
+			assert.Equal(t, tt.wantField, fe.Field)
+		})
+	}
+}
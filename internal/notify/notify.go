@@ -0,0 +1,195 @@
+// Package notify emails vault owners about full indexes that fail or leave
+// too many broken links, at most once per throttle interval for each vault
+// and kind of problem.
+package notify
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+const (
+	// DefaultPort is the SMTP submission port.
+	DefaultPort = 587
+
+	// DefaultThrottle is the least time between two notifications of the
+	// same kind about a vault.
+	DefaultThrottle = time.Hour
+)
+
+// SMTP is the mail server notifications are sent through. Without a
+// Username, mail is sent unauthenticated.
+type SMTP struct {
+	Host     string
+	Port     int // 0 means DefaultPort
+	Username string
+	Password string
+	From     string
+}
+
+// SendFunc sends a message, as smtp.SendMail does.
+type SendFunc func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+
+// kind is a problem notified about.
+type kind string
+
+const (
+	kindFailure     kind = "failure"
+	kindBrokenLinks kind = "broken-links"
+)
+
+// Notifier decides which full indexes to notify about and sends the email.
+// It is safe for concurrent use.
+type Notifier struct {
+	smtp      SMTP
+	to        []string
+	owners    map[string][]string
+	threshold int
+	throttle  time.Duration
+	send      SendFunc
+	now       func() time.Time
+
+	mu   sync.Mutex
+	last map[string]time.Time // vault path + kind -> last notification
+}
+
+// New creates a notifier sending to the owners of each vault, by path, or
+// to to for vaults without owners. A threshold of zero disables broken-link
+// notifications; a throttle of zero means DefaultThrottle.
+func New(server SMTP, to []string, owners map[string][]string, threshold int, throttle time.Duration) *Notifier {
+	if server.Port == 0 {
+		server.Port = DefaultPort
+	}
+	if throttle == 0 {
+		throttle = DefaultThrottle
+	}
+	return &Notifier{
+		smtp:      server,
+		to:        to,
+		owners:    owners,
+		threshold: threshold,
+		throttle:  throttle,
+		send:      smtp.SendMail,
+		now:       time.Now,
+		last:      make(map[string]time.Time),
+	}
+}
+
+// SetSend replaces how messages are sent, e.g. to record them in tests.
+func (n *Notifier) SetSend(send SendFunc) {
+	n.send = send
+}
+
+// ParseFinished notifies the owners of the vault at vaultPath about the
+// finished full index h if it failed or left more unresolved links than
+// the threshold. A vault that indexes cleanly again is notified about its
+// next problem without waiting out the throttle.
+func (n *Notifier) ParseFinished(vaultPath string, h models.ParseHistory) {
+	var k kind
+	switch {
+	case h.Status == models.ParseStatusFailed:
+		k = kindFailure
+	case h.Status == models.ParseStatusCompleted && n.threshold > 0 && h.Stats.UnresolvedLinks > n.threshold:
+		k = kindBrokenLinks
+	}
+	if !n.claim(vaultPath, h.Status, k) {
+		return
+	}
+
+	to := n.recipients(vaultPath)
+	if len(to) == 0 {
+		return
+	}
+	subject, body := message(vaultPath, h, k, n.threshold)
+	if err := n.sendMail(to, subject, body); err != nil {
+		log.Printf("Warning: failed to send %s notification for %s: %v", k, vaultPath, err)
+	}
+}
+
+// claim records a notification of k about vaultPath and reports whether it
+// is due. A completed index clears the problems it shows resolved.
+func (n *Notifier) claim(vaultPath string, status models.ParseStatus, k kind) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if status == models.ParseStatusCompleted {
+		delete(n.last, throttleKey(vaultPath, kindFailure))
+		if k == "" {
+			delete(n.last, throttleKey(vaultPath, kindBrokenLinks))
+		}
+	}
+	if k == "" {
+		return false
+	}
+	key := throttleKey(vaultPath, k)
+	now := n.now()
+	if last, ok := n.last[key]; ok && now.Sub(last) < n.throttle {
+		return false
+	}
+	n.last[key] = now
+	return true
+}
+
+func throttleKey(vaultPath string, k kind) string {
+	return vaultPath + "\x00" + string(k)
+}
+
+// recipients returns the addresses notified about the vault at vaultPath.
+func (n *Notifier) recipients(vaultPath string) []string {
+	if owners, ok := n.owners[vaultPath]; ok {
+		return owners
+	}
+	return n.to
+}
+
+// message returns the subject and body of a notification.
+func message(vaultPath string, h models.ParseHistory, k kind, threshold int) (string, string) {
+	var subject string
+	var b strings.Builder
+	switch k {
+	case kindFailure:
+		subject = fmt.Sprintf("Indexing %s failed", vaultPath)
+		fmt.Fprintf(&b, "The full index of %s failed.\n\n", vaultPath)
+		if h.Error != nil {
+			fmt.Fprintf(&b, "Error: %s\n\n", *h.Error)
+		}
+	case kindBrokenLinks:
+		subject = fmt.Sprintf("%d broken links in %s", h.Stats.UnresolvedLinks, vaultPath)
+		fmt.Fprintf(&b, "The full index of %s found %d links that resolve to no note, more than the threshold of %d.\n\n",
+			vaultPath, h.Stats.UnresolvedLinks, threshold)
+	}
+	fmt.Fprintf(&b, "Run:     %s\n", h.ID)
+	fmt.Fprintf(&b, "Started: %s\n", h.StartedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Files:   %d parsed of %d\n", h.Stats.ParsedFiles, h.Stats.TotalFiles)
+	if k == kindBrokenLinks {
+		fmt.Fprintf(&b, "\nThe run's report lists them: /api/v1/vault/parses/%s/report\n", h.ID)
+	}
+	return subject, b.String()
+}
+
+// sendMail sends a plain text message to to.
+func (n *Notifier) sendMail(to []string, subject, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.smtp.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", n.now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if n.smtp.Username != "" {
+		auth = smtp.PlainAuth("", n.smtp.Username, n.smtp.Password, n.smtp.Host)
+	}
+	addr := net.JoinHostPort(n.smtp.Host, strconv.Itoa(n.smtp.Port))
+	return n.send(addr, auth, n.smtp.From, to, []byte(msg.String()))
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"net/smtp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+type sentMail struct {
+	addr string
+	auth smtp.Auth
+	to   []string
+	msg  string
+}
+
+func newTestNotifier(threshold int) (*Notifier, *[]sentMail, *time.Time) {
+	n := New(SMTP{Host: "mail.example.com", From: "mnemosyne@example.com"},
+		[]string{"admin@example.com"}, map[string][]string{"/vaults/work": {"owner@example.com"}}, threshold, time.Hour)
+	var sent []sentMail
+	n.SetSend(func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		sent = append(sent, sentMail{addr, auth, to, string(msg)})
+		return nil
+	})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return now }
+	return n, &sent, &now
+}
+
+func failed(msg string) models.ParseHistory {
+	return models.ParseHistory{ID: "run-1", Status: models.ParseStatusFailed, Error: &msg}
+}
+
+func TestParseFinishedFailure(t *testing.T) {
+	n, sent, now := newTestNotifier(0)
+
+	n.ParseFinished("/vaults/work", failed("store vault data: disk full"))
+	require.Len(t, *sent, 1)
+	mail := (*sent)[0]
+	assert.Equal(t, "mail.example.com:587", mail.addr)
+	assert.Nil(t, mail.auth, "no username, no auth")
+	assert.Equal(t, []string{"owner@example.com"}, mail.to)
+	assert.Contains(t, mail.msg, "Subject: Indexing /vaults/work failed\r\n")
+	assert.Contains(t, mail.msg, "Error: store vault data: disk full\r\n")
+
+	// Repeated failures within the throttle are not sent; other vaults are
+	n.ParseFinished("/vaults/work", failed("again"))
+	assert.Len(t, *sent, 1)
+	n.ParseFinished("/vaults/home", failed("also"))
+	require.Len(t, *sent, 2)
+	assert.Equal(t, []string{"admin@example.com"}, (*sent)[1].to)
+
+	*now = now.Add(time.Hour)
+	n.ParseFinished("/vaults/work", failed("still"))
+	assert.Len(t, *sent, 3)
+
+	// Cancelled runs are not failures, and a completed run resets the throttle
+	n.ParseFinished("/vaults/work", models.ParseHistory{Status: models.ParseStatusCancelled})
+	n.ParseFinished("/vaults/work", failed("throttled"))
+	assert.Len(t, *sent, 3)
+	n.ParseFinished("/vaults/work", models.ParseHistory{Status: models.ParseStatusCompleted})
+	n.ParseFinished("/vaults/work", failed("new problem"))
+	assert.Len(t, *sent, 4)
+}
+
+func TestParseFinishedBrokenLinks(t *testing.T) {
+	n, sent, _ := newTestNotifier(10)
+	completed := func(unresolved int) models.ParseHistory {
+		return models.ParseHistory{ID: "run-2", Status: models.ParseStatusCompleted, Stats: models.JSONStats{UnresolvedLinks: unresolved}}
+	}
+
+	n.ParseFinished("/vaults/work", completed(10))
+	assert.Empty(t, *sent, "at the threshold")
+	n.ParseFinished("/vaults/work", completed(11))
+	require.Len(t, *sent, 1)
+	assert.Contains(t, (*sent)[0].msg, "Subject: 11 broken links in /vaults/work\r\n")
+	assert.Contains(t, (*sent)[0].msg, "/api/v1/vault/parses/run-2/report")
+	n.ParseFinished("/vaults/work", completed(12))
+	assert.Len(t, *sent, 1)
+
+	// Broken links and failures are throttled separately
+	n.ParseFinished("/vaults/work", failed("boom"))
+	assert.Len(t, *sent, 2)
+
+	disabled, sent, _ := newTestNotifier(0)
+	disabled.ParseFinished("/vaults/work", completed(1000))
+	assert.Empty(t, *sent)
+}
@@ -35,8 +35,9 @@ type positionFile struct {
 }
 
 type posXY struct {
-	X float64 `json:"x"`
-	Y float64 `json:"y"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Pinned bool    `json:"pinned,omitempty"`
 }
 
 // graphRef identifies a graph for export/import.
@@ -123,10 +124,12 @@ func (s *Syncer) ImportIfEmpty(graphID int) error {
 			NodeID: nodeID,
 			X:      pos.X,
 			Y:      pos.Y,
+			Pinned: pos.Pinned,
 		})
 	}
 
-	if err := s.store.UpsertPositions(graphID, positions); err != nil {
+	// The graph has no positions, so replacing them also restores pinned flags
+	if err := s.store.ReplacePositions(graphID, positions); err != nil {
 		return fmt.Errorf("import positions: %w", err)
 	}
 
@@ -281,7 +284,7 @@ func (s *Syncer) exportGraph(graphID int, ref graphRef) error {
 
 	posMap := make(map[string]posXY, len(positions))
 	for nodeID, p := range positions {
-		posMap[nodeID] = posXY{X: p.X, Y: p.Y}
+		posMap[nodeID] = posXY{X: p.X, Y: p.Y, Pinned: p.Pinned}
 	}
 
 	pf := positionFile{
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -434,3 +435,21 @@ func TestMultipleGraphsExport(t *testing.T) {
 	_, err = os.Stat(FilePath(vaultPath, "invest"))
 	require.NoError(t, err)
 }
+
+func TestReadRemapCSV(t *testing.T) {
+	remap, err := ReadRemapCSV(strings.NewReader("old_id,new_id\nalpha, beta\n\ngamma,delta\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"alpha": "beta", "gamma": "delta"}, remap)
+
+	for _, bad := range []string{
+		"",
+		"a,b,c\n",
+		"a,\n",
+		"a,a\n",
+		"a,b\na,c\n",
+		"a,c\nb,c\n",
+	} {
+		_, err := ReadRemapCSV(strings.NewReader(bad))
+		assert.Error(t, err, "input %q", bad)
+	}
+}
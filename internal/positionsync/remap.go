@@ -0,0 +1,86 @@
+package positionsync
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadRemapCSV reads node ID remappings as "old_id,new_id" rows. A header row
+// naming those columns is optional, and blank lines are skipped.
+func ReadRemapCSV(r io.Reader) (map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+
+	remap := make(map[string]string)
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		oldID, newID := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		if line == 1 && oldID == "old_id" && newID == "new_id" {
+			continue
+		}
+		if err := addRemap(remap, oldID, newID); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if len(remap) == 0 {
+		return nil, errors.New("no remappings found")
+	}
+	if err := checkTargets(remap); err != nil {
+		return nil, err
+	}
+	return remap, nil
+}
+
+// ValidateRemap checks a list of old -> new pairs and returns them as a map.
+func ValidateRemap(pairs [][2]string) (map[string]string, error) {
+	remap := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		if err := addRemap(remap, p[0], p[1]); err != nil {
+			return nil, err
+		}
+	}
+	if len(remap) == 0 {
+		return nil, errors.New("no remappings provided")
+	}
+	if err := checkTargets(remap); err != nil {
+		return nil, err
+	}
+	return remap, nil
+}
+
+func addRemap(remap map[string]string, oldID, newID string) error {
+	switch {
+	case oldID == "" || newID == "":
+		return errors.New("old and new IDs are required")
+	case oldID == newID:
+		return fmt.Errorf("%q is mapped to itself", oldID)
+	}
+	if prev, dup := remap[oldID]; dup {
+		return fmt.Errorf("%q is mapped to both %q and %q", oldID, prev, newID)
+	}
+	remap[oldID] = newID
+	return nil
+}
+
+// checkTargets rejects mappings of several old IDs onto one new ID, whose
+// positions would overwrite each other.
+func checkTargets(remap map[string]string) error {
+	targets := make(map[string]string, len(remap))
+	for oldID, newID := range remap {
+		if other, dup := targets[newID]; dup {
+			return fmt.Errorf("%q and %q are both mapped to %q", min(oldID, other), max(oldID, other), newID)
+		}
+		targets[newID] = oldID
+	}
+	return nil
+}
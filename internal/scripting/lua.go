@@ -0,0 +1,296 @@
+// Package scripting runs user-provided Lua scripts against notes during
+// indexing, for classification and metadata enrichment without rebuilding the
+// server.
+//
+// A script may define either or both of these global functions, each called
+// once per note with a table of note fields (path, title, tags, metadata,
+// word_count, type):
+//
+//	function classify(note) return "hub" end     -- sets the node type
+//	function enrich(note) return {k = "v"} end   -- merged into metadata
+//
+// Scripts run with only the base, table, string, and math libraries loaded.
+package scripting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/vault"
+)
+
+// callTimeout bounds a single classify or enrich call.
+const callTimeout = time.Second
+
+// LuaHook is a vault.ParserHook that applies Lua scripts to nodes before they are stored.
+type LuaHook struct {
+	vault.NopHook
+
+	mu      sync.Mutex
+	scripts []*luaScript
+}
+
+type luaScript struct {
+	path string
+	L    *lua.LState
+}
+
+// LoadLua loads and runs each script file, ready to be called per note.
+func LoadLua(paths []string) (*LuaHook, error) {
+	h := &LuaHook{}
+	for _, path := range paths {
+		L := newSandboxedState()
+		if err := L.DoFile(path); err != nil {
+			L.Close()
+			h.Close()
+			return nil, fmt.Errorf("load script %s: %w", path, err)
+		}
+		h.scripts = append(h.scripts, &luaScript{path: path, L: L})
+	}
+	return h, nil
+}
+
+// Close releases all Lua states.
+func (h *LuaHook) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.scripts {
+		s.L.Close()
+	}
+	h.scripts = nil
+}
+
+// OnBeforeStore runs every script against each node. Script errors are logged
+// and skipped so a faulty script cannot block indexing. Values an enrich
+// function returns that cannot be stored, such as a table containing
+// itself, are returned as FileErrors.
+func (h *LuaHook) OnBeforeStore(nodes []models.VaultNode, _ []models.VaultEdge) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var failed vault.FileErrors
+	for i := range nodes {
+		for _, s := range h.scripts {
+			err := s.apply(&nodes[i])
+			var conv *conversionError
+			if errors.As(err, &conv) {
+				failed = append(failed, vault.ParseError{
+					FilePath: nodes[i].FilePath,
+					Kind:     vault.ParseErrorHook,
+					Error:    fmt.Errorf("script %s: %w", s.path, err),
+				})
+			} else if err != nil {
+				log.Printf("Warning: script %s failed for '%s': %v", s.path, nodes[i].FilePath, err)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// conversionError is a value returned by a script that has no JSON form.
+type conversionError struct{ msg string }
+
+func (e *conversionError) Error() string { return e.msg }
+
+func (s *luaScript) apply(n *models.VaultNode) error {
+	if fn, ok := s.L.GetGlobal("classify").(*lua.LFunction); ok {
+		ret, err := s.call(fn, n)
+		if err != nil {
+			return fmt.Errorf("classify: %w", err)
+		}
+		if str, ok := ret.(lua.LString); ok && str != "" {
+			n.NodeType = string(str)
+		}
+	}
+
+	if fn, ok := s.L.GetGlobal("enrich").(*lua.LFunction); ok {
+		ret, err := s.call(fn, n)
+		if err != nil {
+			return fmt.Errorf("enrich: %w", err)
+		}
+		if tbl, ok := ret.(*lua.LTable); ok {
+			fields := make(map[string]interface{})
+			var convErr error
+			tbl.ForEach(func(k, v lua.LValue) {
+				key, ok := k.(lua.LString)
+				if !ok || convErr != nil {
+					return
+				}
+				value, err := fromLua(v)
+				if err != nil {
+					convErr = fmt.Errorf("enrich: field %q: %w", string(key), err)
+					return
+				}
+				fields[string(key)] = value
+			})
+			if convErr != nil {
+				return convErr
+			}
+			if n.Metadata == nil {
+				n.Metadata = make(models.JSONMetadata)
+			}
+			for k, v := range fields {
+				n.Metadata[k] = v
+			}
+		}
+	}
+	return nil
+}
+
+func (s *luaScript) call(fn *lua.LFunction, n *models.VaultNode) (lua.LValue, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	s.L.SetContext(ctx)
+	defer s.L.RemoveContext()
+
+	if err := s.L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, noteTable(s.L, n)); err != nil {
+		return lua.LNil, err
+	}
+	ret := s.L.Get(-1)
+	s.L.Pop(1)
+	return ret, nil
+}
+
+func newSandboxedState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.fn))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	// The base library can load code from disk; scripts should not.
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "require"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	return L
+}
+
+func noteTable(L *lua.LState, n *models.VaultNode) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString("path", lua.LString(n.FilePath))
+	t.RawSetString("title", lua.LString(n.Title))
+	t.RawSetString("type", lua.LString(n.NodeType))
+	t.RawSetString("word_count", lua.LNumber(n.WordCount))
+	tags := L.NewTable()
+	for _, tag := range n.Tags {
+		tags.Append(lua.LString(tag))
+	}
+	t.RawSetString("tags", tags)
+	t.RawSetString("metadata", toLua(L, map[string]interface{}(n.Metadata)))
+	return t
+}
+
+func toLua(L *lua.LState, v interface{}) lua.LValue {
+	switch val := v.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(val)
+	case string:
+		return lua.LString(val)
+	case int:
+		return lua.LNumber(val)
+	case int64:
+		return lua.LNumber(val)
+	case float64:
+		return lua.LNumber(val)
+	case time.Time:
+		return lua.LString(val.Format(time.RFC3339))
+	case []interface{}:
+		t := L.NewTable()
+		for _, item := range val {
+			t.Append(toLua(L, item))
+		}
+		return t
+	case []string:
+		t := L.NewTable()
+		for _, item := range val {
+			t.Append(lua.LString(item))
+		}
+		return t
+	case map[string]interface{}:
+		t := L.NewTable()
+		for k, item := range val {
+			t.RawSetString(k, toLua(L, item))
+		}
+		return t
+	}
+	return lua.LString(fmt.Sprintf("%v", v))
+}
+
+// maxLuaDepth caps the nesting of tables converted by fromLua.
+const maxLuaDepth = 100
+
+// fromLua converts a Lua value to a JSON-compatible Go value. Tables with
+// only consecutive integer keys starting at 1 become lists; others become
+// maps. Tables that contain themselves, or nest deeper than maxLuaDepth,
+// are an error, as are NaN and infinite numbers, which JSON cannot hold.
+func fromLua(v lua.LValue) (interface{}, error) {
+	return fromLuaTable(v, make(map[*lua.LTable]bool), 0)
+}
+
+// fromLuaTable is fromLua for a value inside the tables in open.
+func fromLuaTable(v lua.LValue, open map[*lua.LTable]bool, depth int) (interface{}, error) {
+	switch val := v.(type) {
+	case lua.LBool:
+		return bool(val), nil
+	case lua.LString:
+		return string(val), nil
+	case lua.LNumber:
+		if f := float64(val); math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, &conversionError{fmt.Sprintf("number %v is not finite", f)}
+		}
+		return float64(val), nil
+	case *lua.LTable:
+		if open[val] {
+			return nil, &conversionError{"table contains itself"}
+		}
+		if depth >= maxLuaDepth {
+			return nil, &conversionError{fmt.Sprintf("tables nested more than %d deep", maxLuaDepth)}
+		}
+		open[val] = true
+		defer delete(open, val)
+		if n := val.Len(); n > 0 {
+			list := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				item, err := fromLuaTable(val.RawGetInt(i), open, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, item)
+			}
+			return list, nil
+		}
+		m := make(map[string]interface{})
+		var err error
+		val.ForEach(func(k, item lua.LValue) {
+			if err != nil {
+				return
+			}
+			m[k.String()], err = fromLuaTable(item, open, depth+1)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,143 @@
+package scripting
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/vault"
+)
+
+func writeScript(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.lua")
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	return path
+}
+
+func TestLuaHook_ClassifyAndEnrich(t *testing.T) {
+	path := writeScript(t, `
+function classify(note)
+  for _, tag in ipairs(note.tags) do
+    if tag == "moc" then return "hub" end
+  end
+end
+
+function enrich(note)
+  local reviews = note.metadata.reviews or 0
+  return {
+    maturity = reviews >= 3 and "evergreen" or "seedling",
+    upper_title = string.upper(note.title),
+    related = {"a", "b"},
+  }
+end
+`)
+	h, err := LoadLua([]string{path})
+	require.NoError(t, err)
+	defer h.Close()
+
+	nodes := []models.VaultNode{
+		{ID: "1", Title: "Index", NodeType: "note", Tags: models.StringArray{"moc"}, Metadata: models.JSONMetadata{"reviews": 5}},
+		{ID: "2", Title: "Draft", NodeType: "note"},
+	}
+	require.NoError(t, h.OnBeforeStore(nodes, nil))
+
+	assert.Equal(t, "hub", nodes[0].NodeType)
+	assert.Equal(t, "evergreen", nodes[0].Metadata["maturity"])
+	assert.Equal(t, "INDEX", nodes[0].Metadata["upper_title"])
+	assert.Equal(t, []interface{}{"a", "b"}, nodes[0].Metadata["related"])
+
+	assert.Equal(t, "note", nodes[1].NodeType)
+	assert.Equal(t, "seedling", nodes[1].Metadata["maturity"])
+}
+
+func TestLuaHook_RuntimeErrorDoesNotAbort(t *testing.T) {
+	path := writeScript(t, `function enrich(note) error("boom") end`)
+	h, err := LoadLua([]string{path})
+	require.NoError(t, err)
+	defer h.Close()
+
+	nodes := []models.VaultNode{{ID: "1", Title: "A"}}
+	assert.NoError(t, h.OnBeforeStore(nodes, nil))
+	assert.Nil(t, nodes[0].Metadata)
+}
+
+func TestLuaHook_CyclicTable(t *testing.T) {
+	path := writeScript(t, `
+function enrich(note)
+	local t = {}
+	t.self = t
+	return {bad = t}
+end`)
+	h, err := LoadLua([]string{path})
+	require.NoError(t, err)
+	defer h.Close()
+
+	nodes := []models.VaultNode{{ID: "1", FilePath: "a.md"}}
+	err = h.OnBeforeStore(nodes, nil)
+	var failed vault.FileErrors
+	require.ErrorAs(t, err, &failed)
+	require.Len(t, failed, 1)
+	assert.Equal(t, "a.md", failed[0].FilePath)
+	assert.Equal(t, vault.ParseErrorHook, failed[0].Kind)
+	assert.Contains(t, failed[0].Error.Error(), "contains itself")
+	assert.Nil(t, nodes[0].Metadata)
+}
+
+func TestLuaHook_DeepTable(t *testing.T) {
+	path := writeScript(t, `
+function enrich(note)
+	local t = {}
+	for i = 1, 1000 do t = {t} end
+	return {deep = t}
+end`)
+	h, err := LoadLua([]string{path})
+	require.NoError(t, err)
+	defer h.Close()
+
+	nodes := []models.VaultNode{{ID: "1", FilePath: "a.md"}}
+	var failed vault.FileErrors
+	require.ErrorAs(t, h.OnBeforeStore(nodes, nil), &failed)
+	assert.Contains(t, failed[0].Error.Error(), "nested")
+}
+
+func TestLuaHook_NaN(t *testing.T) {
+	path := writeScript(t, `
+function enrich(note)
+	return {ratio = 0/0, big = {math.huge}}
+end`)
+	h, err := LoadLua([]string{path})
+	require.NoError(t, err)
+	defer h.Close()
+
+	nodes := []models.VaultNode{{ID: "1", FilePath: "a.md"}}
+	var failed vault.FileErrors
+	require.ErrorAs(t, h.OnBeforeStore(nodes, nil), &failed)
+	assert.Equal(t, "a.md", failed[0].FilePath)
+	assert.Contains(t, failed[0].Error.Error(), "not finite")
+	_, err = json.Marshal(nodes[0].Metadata)
+	assert.NoError(t, err)
+}
+
+func TestLuaHook_Timeout(t *testing.T) {
+	path := writeScript(t, `function enrich(note) while true do end end`)
+	h, err := LoadLua([]string{path})
+	require.NoError(t, err)
+	defer h.Close()
+
+	nodes := []models.VaultNode{{ID: "1"}}
+	assert.NoError(t, h.OnBeforeStore(nodes, nil))
+}
+
+func TestLoadLua_Errors(t *testing.T) {
+	_, err := LoadLua([]string{writeScript(t, `function (`)})
+	assert.Error(t, err)
+
+	_, err = LoadLua([]string{writeScript(t, `dofile("/etc/passwd")`)})
+	assert.Error(t, err, "dofile is not available in the sandbox")
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migrations upgrade databases created by earlier versions. Each file in
+// migrations/ is named NNNN_description.sql and runs once, in order, in its
+// own transaction. The database's PRAGMA user_version records the last one
+// applied. schema.sql always describes the latest schema: new databases are
+// created from it and start at the latest version, so a change to an existing
+// table goes in both places.
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// ErrSchemaOutdated is returned when opening a database without migrating
+// and migrations are pending.
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// migrations returns the embedded migrations in version order.
+func migrations() ([]migration, error) {
+	entries, err := migrationFS.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	var ms []migration
+	for _, e := range entries {
+		prefix, _, ok := strings.Cut(e.Name(), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", e.Name())
+		}
+		data, err := migrationFS.ReadFile(path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, migration{version: version, name: e.Name(), sql: string(data)})
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].version < ms[j].version })
+	for i, m := range ms {
+		if m.version != i+1 {
+			return nil, fmt.Errorf("migration %s: expected version %d", m.name, i+1)
+		}
+	}
+	return ms, nil
+}
+
+// LatestSchemaVersion returns the schema version this build migrates to.
+func LatestSchemaVersion() int {
+	ms, err := migrations()
+	if err != nil {
+		return 0
+	}
+	return len(ms)
+}
+
+// schemaVersion returns the database's schema version, and whether it has
+// no tables yet.
+func schemaVersion(db *sql.DB) (int, bool, error) {
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return 0, false, err
+	}
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables); err != nil {
+		return 0, false, err
+	}
+	return version, tables == 0, nil
+}
+
+// migrate brings the database up to the latest schema. It returns the
+// versions before and after.
+func migrate(db *sql.DB) (int, int, error) {
+	ms, err := migrations()
+	if err != nil {
+		return 0, 0, err
+	}
+	from, empty, err := schemaVersion(db)
+	if err != nil {
+		return 0, 0, fmt.Errorf("read schema version: %w", err)
+	}
+	if from > len(ms) {
+		return from, from, fmt.Errorf("database schema version %d is newer than this build supports (%d)", from, len(ms))
+	}
+
+	// Create missing tables, indexes and triggers. A new database is then
+	// up to date.
+	if _, err := db.Exec(schemaSQL); err != nil {
+		return from, from, fmt.Errorf("initialize schema: %w", err)
+	}
+	if empty {
+		if _, err := db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, len(ms))); err != nil {
+			return from, from, fmt.Errorf("set schema version: %w", err)
+		}
+		return from, len(ms), nil
+	}
+
+	// Change-log triggers read columns that pending migrations may add, so
+	// they are dropped while migrations run and created again from
+	// schema.sql once the columns exist.
+	if from < len(ms) {
+		if err := dropChangelogTriggers(db); err != nil {
+			return from, from, fmt.Errorf("drop change-log triggers: %w", err)
+		}
+	}
+	for _, m := range ms[from:] {
+		if err := applyMigration(db, m, from == 0); err != nil {
+			return from, m.version - 1, fmt.Errorf("migration %s: %w", m.name, err)
+		}
+	}
+	if _, err := db.Exec(schemaSQL); err != nil {
+		return from, len(ms), fmt.Errorf("initialize schema: %w", err)
+	}
+	return from, len(ms), nil
+}
+
+// dropChangelogTriggers drops the triggers that fill change_log.
+func dropChangelogTriggers(db *sql.DB) error {
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'trigger' AND name LIKE '%\_changelog\_%' ESCAPE '\'`)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err := db.Exec(`DROP TRIGGER IF EXISTS "` + name + `"`); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigration runs one migration and records its version. Databases from
+// before migrations were versioned (version 0) may already have the columns
+// early migrations add, so with legacy set, a migration failing because its
+// column exists counts as applied.
+func applyMigration(db *sql.DB, m migration, legacy bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.sql); err != nil {
+		if !legacy || !strings.Contains(err.Error(), "duplicate column name") {
+			return err
+		}
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, m.version)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkSchema fails with ErrSchemaOutdated unless the database is at the
+// latest schema version.
+func checkSchema(db *sql.DB) error {
+	version, _, err := schemaVersion(db)
+	if err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if latest := LatestSchemaVersion(); version != latest {
+		return fmt.Errorf("%w: version %d, want %d", ErrSchemaOutdated, version, latest)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func schemaVersionOf(t *testing.T, s *Store) int {
+	t.Helper()
+	var version int
+	require.NoError(t, s.db.QueryRow(`PRAGMA user_version`).Scan(&version))
+	return version
+}
+
+func TestMigrations(t *testing.T) {
+	ms, err := migrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, ms)
+	assert.Equal(t, len(ms), LatestSchemaVersion())
+}
+
+func TestNewDatabaseStartsAtLatestVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(path)
+	require.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, LatestSchemaVersion(), schemaVersionOf(t, s))
+
+	from, to, err := Migrate(path)
+	require.NoError(t, err)
+	assert.Equal(t, LatestSchemaVersion(), from)
+	assert.Equal(t, LatestSchemaVersion(), to)
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	// A database from before versioning, with some of the later columns
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE vaults (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, path TEXT UNIQUE NOT NULL, created_at TEXT);
+		CREATE TABLE graphs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT, vault_id INTEGER NOT NULL, name TEXT NOT NULL,
+			root_path TEXT NOT NULL DEFAULT '', config TEXT, created_at TEXT, updated_at TEXT,
+			UNIQUE(vault_id, root_path)
+		);
+		CREATE TABLE nodes (
+			id TEXT PRIMARY KEY, vault_id INTEGER NOT NULL, file_path TEXT NOT NULL, title TEXT NOT NULL,
+			content TEXT, frontmatter TEXT, node_type TEXT, tags TEXT, in_degree INTEGER DEFAULT 0,
+			out_degree INTEGER DEFAULT 0, word_count INTEGER DEFAULT 0, created_at TEXT, updated_at TEXT,
+			parsed_at TEXT, UNIQUE(vault_id, file_path)
+		);
+		INSERT INTO vaults (name, path) VALUES ('v', '/v');
+		INSERT INTO graphs (vault_id, name) VALUES (1, 'root');
+	`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	from, to, err := Migrate(path)
+	require.NoError(t, err)
+	assert.Equal(t, 0, from)
+	assert.Equal(t, LatestSchemaVersion(), to)
+
+	s, err := NewNoMigrate(path)
+	require.NoError(t, err)
+	defer s.Close()
+	graphs, err := s.GetAllGraphs()
+	require.NoError(t, err)
+	require.Len(t, graphs, 1)
+	assert.False(t, graphs[0].Archived)
+	vid := createTestVault(t, s, "v", "/v")
+	node := testNode(vid, "a", "A", "a.md")
+	require.NoError(t, s.UpsertNode(&node))
+}
+
+func TestMigrateNodeChangelogTrigger(t *testing.T) {
+	// A database whose trigger predates levels and excerpts being recorded
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(path)
+	require.NoError(t, err)
+	_, err = s.db.Exec(`
+		DROP TRIGGER nodes_changelog_update;
+		CREATE TRIGGER nodes_changelog_update AFTER UPDATE ON nodes WHEN old.title IS NOT new.title BEGIN
+			INSERT INTO change_log(kind, entity_id) VALUES ('node', old.id);
+		END;
+		PRAGMA user_version = 19;
+	`)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = New(path)
+	require.NoError(t, err)
+	defer s.Close()
+	var trigger string
+	require.NoError(t, s.db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'trigger' AND name = 'nodes_changelog_update'`).Scan(&trigger))
+	assert.Contains(t, trigger, "old.level IS NOT new.level")
+	assert.Contains(t, trigger, "old.excerpt IS NOT new.excerpt")
+}
+
+func TestNewNoMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	_, err := NewNoMigrate(path)
+	assert.ErrorIs(t, err, ErrSchemaOutdated)
+
+	s, err := New(path)
+	require.NoError(t, err)
+	_, err = s.db.Exec(`PRAGMA user_version = 0`)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	_, err = NewNoMigrate(path)
+	assert.ErrorIs(t, err, ErrSchemaOutdated)
+
+	from, to, err := Migrate(path)
+	require.NoError(t, err)
+	assert.Equal(t, 0, from)
+	assert.Equal(t, LatestSchemaVersion(), to)
+	s, err = NewNoMigrate(path)
+	require.NoError(t, err)
+	s.Close()
+}
+
+func TestMigrateRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(path)
+	require.NoError(t, err)
+	_, err = s.db.Exec(`PRAGMA user_version = 1000`)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	_, err = New(path)
+	assert.ErrorContains(t, err, "newer")
+}
+
+func TestSchemaVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	_, err := SchemaVersion(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.NoFileExists(t, path, "not created")
+
+	s, err := New(path)
+	require.NoError(t, err)
+	_, err = s.db.Exec(`PRAGMA user_version = 3`)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	version, err := SchemaVersion(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, version)
+}
@@ -0,0 +1,2 @@
+-- Archived graphs are hidden but keep their positions
+ALTER TABLE graphs ADD COLUMN archived INTEGER NOT NULL DEFAULT 0;
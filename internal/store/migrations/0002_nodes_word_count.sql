@@ -0,0 +1 @@
+ALTER TABLE nodes ADD COLUMN word_count INTEGER DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE nodes ADD COLUMN reading_time INTEGER DEFAULT 0;
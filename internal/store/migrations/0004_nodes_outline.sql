@@ -0,0 +1,2 @@
+-- Heading outline, as JSON
+ALTER TABLE nodes ADD COLUMN outline TEXT;
@@ -0,0 +1,2 @@
+-- The node each cached file produced
+ALTER TABLE file_cache ADD COLUMN node_id TEXT;
@@ -0,0 +1 @@
+ALTER TABLE node_positions ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0;
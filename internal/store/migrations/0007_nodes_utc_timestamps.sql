@@ -0,0 +1,5 @@
+-- Store node timestamps in UTC so they compare correctly as text
+UPDATE nodes SET
+    created_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', created_at), created_at),
+    updated_at = COALESCE(strftime('%Y-%m-%dT%H:%M:%SZ', updated_at), updated_at)
+WHERE created_at NOT LIKE '%Z' OR updated_at NOT LIKE '%Z';
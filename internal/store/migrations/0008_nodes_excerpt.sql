@@ -0,0 +1,3 @@
+-- Plain-text excerpt for previews. Parser output changed with it, so the
+-- file cache misses and the next full index fills it in.
+ALTER TABLE nodes ADD COLUMN excerpt TEXT;
@@ -0,0 +1,18 @@
+-- External URLs in note bodies and the link checker's results. Parser output
+-- changed with them, so the file cache misses and the next full index fills
+-- node_links in.
+CREATE TABLE IF NOT EXISTS node_links (
+    node_id TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    PRIMARY KEY (node_id, url)
+);
+
+CREATE TABLE IF NOT EXISTS link_checks (
+    url TEXT PRIMARY KEY,
+    status INTEGER NOT NULL DEFAULT 0,
+    dead INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    checked_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_node_links_url ON node_links(url);
@@ -0,0 +1,3 @@
+-- PageRank centrality, scaled so the most central node scores 1. The next
+-- full index or metrics recompute fills it in.
+ALTER TABLE nodes ADD COLUMN centrality REAL DEFAULT 0;
@@ -0,0 +1,9 @@
+-- Link targets that resolve to no node. Unresolved links are found on every
+-- parse, cached files included, so the next full index fills the table in.
+CREATE TABLE IF NOT EXISTS unresolved_links (
+    node_id TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    target TEXT NOT NULL,
+    PRIMARY KEY (node_id, target)
+);
+
+CREATE INDEX IF NOT EXISTS idx_unresolved_links_target ON unresolved_links(target);
@@ -0,0 +1,2 @@
+-- Markdown report of each completed full index
+ALTER TABLE parse_history ADD COLUMN report TEXT;
@@ -0,0 +1,8 @@
+-- Pairs of nearly identical notes, found by the duplicate detector.
+CREATE TABLE IF NOT EXISTS near_duplicates (
+    node_a TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    node_b TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    similarity REAL NOT NULL,
+    detected_at TEXT NOT NULL,
+    PRIMARY KEY (node_a, node_b)
+);
@@ -0,0 +1,10 @@
+-- Files that failed to parse, or parsed with problems, in each full index.
+CREATE TABLE IF NOT EXISTS parse_errors (
+    parse_id TEXT NOT NULL REFERENCES parse_history(id) ON DELETE CASCADE,
+    file_path TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    line INTEGER,
+    message TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_parse_errors_parse ON parse_errors(parse_id, file_path);
@@ -0,0 +1,3 @@
+-- Depth below the vault's hub notes, for level-based rendering. The next
+-- full index fills it in.
+ALTER TABLE nodes ADD COLUMN level INTEGER DEFAULT 0;
@@ -0,0 +1,12 @@
+-- Links notes could have, e.g. unlinked mentions of other notes. The next
+-- full index with unlinked-mentions enabled fills them in.
+CREATE TABLE IF NOT EXISTS potential_edges (
+    source_id TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    target_id TEXT NOT NULL,   -- Not a foreign key: targets may be stored after their sources
+    edge_type TEXT NOT NULL,   -- e.g. unlinked-mention
+    display_text TEXT,         -- The target as the source names it
+    weight REAL DEFAULT 1.0,   -- Times the source names it
+    PRIMARY KEY (source_id, target_id, edge_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_potential_edges_target ON potential_edges(target_id);
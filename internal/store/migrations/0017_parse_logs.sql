@@ -0,0 +1,9 @@
+-- What each full index logged, in order.
+CREATE TABLE IF NOT EXISTS parse_logs (
+    parse_id TEXT NOT NULL REFERENCES parse_history(id) ON DELETE CASCADE,
+    logged_at TIMESTAMP NOT NULL,
+    level TEXT NOT NULL,
+    message TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_parse_logs_parse ON parse_logs(parse_id);
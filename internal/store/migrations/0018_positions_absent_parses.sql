@@ -0,0 +1,3 @@
+-- Positions outlive their nodes, e.g. across renames; counting the full
+-- indexes a node has been missing from lets them be purged in the end.
+ALTER TABLE node_positions ADD COLUMN absent_parses INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,20 @@
+-- ACL changes are recorded as their own kind, so graph deltas can tell that
+-- what each user may see changed.
+DROP TRIGGER IF EXISTS node_acls_changelog_insert;
+DROP TRIGGER IF EXISTS node_acls_changelog_delete;
+DROP TRIGGER IF EXISTS node_acls_changelog_update;
+
+CREATE TRIGGER node_acls_changelog_insert AFTER INSERT ON node_acls BEGIN
+    INSERT INTO change_log(kind, entity_id) VALUES ('acl', new.node_id);
+END;
+
+CREATE TRIGGER node_acls_changelog_delete AFTER DELETE ON node_acls BEGIN
+    INSERT INTO change_log(kind, entity_id) VALUES ('acl', old.node_id);
+END;
+
+CREATE TRIGGER node_acls_changelog_update AFTER UPDATE ON node_acls
+WHEN old.node_id IS NOT new.node_id OR old.principals IS NOT new.principals
+BEGIN
+    INSERT INTO change_log(kind, entity_id) VALUES ('acl', old.node_id);
+    INSERT INTO change_log(kind, entity_id) SELECT 'acl', new.node_id WHERE new.node_id IS NOT old.node_id;
+END;
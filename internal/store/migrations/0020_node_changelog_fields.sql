@@ -0,0 +1,13 @@
+-- Node levels and excerpts are served with graph nodes, so a change to
+-- either alone is recorded for graph deltas too.
+DROP TRIGGER IF EXISTS nodes_changelog_update;
+
+CREATE TRIGGER nodes_changelog_update AFTER UPDATE ON nodes
+WHEN old.id IS NOT new.id OR old.file_path IS NOT new.file_path OR old.title IS NOT new.title
+    OR old.frontmatter IS NOT new.frontmatter OR old.node_type IS NOT new.node_type OR old.tags IS NOT new.tags
+    OR old.word_count IS NOT new.word_count OR old.reading_time IS NOT new.reading_time
+    OR old.level IS NOT new.level OR old.excerpt IS NOT new.excerpt
+BEGIN
+    INSERT INTO change_log(kind, entity_id) VALUES ('node', old.id);
+    INSERT INTO change_log(kind, entity_id) SELECT 'node', new.id WHERE new.id IS NOT old.id;
+END;
@@ -0,0 +1,77 @@
+package store
+
+import "math"
+
+const (
+	pageRankDamping    = 0.85
+	pageRankTolerance  = 1e-9
+	pageRankIterations = 100
+)
+
+// pageRank returns the PageRank of each node in ids over the directed edges
+// (source, target), scaled so the highest ranked node scores 1. Edges to
+// nodes not in ids are ignored, and nodes without outgoing edges spread their
+// rank evenly over all nodes.
+func pageRank(ids []string, edges [][2]string) map[string]float64 {
+	ranks := make(map[string]float64, len(ids))
+	n := len(ids)
+	if n == 0 {
+		return ranks
+	}
+	index := make(map[string]int, n)
+	for i, id := range ids {
+		index[id] = i
+	}
+	out := make([][]int, n)
+	for _, e := range edges {
+		s, ok1 := index[e[0]]
+		t, ok2 := index[e[1]]
+		if ok1 && ok2 {
+			out[s] = append(out[s], t)
+		}
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+	for iter := 0; iter < pageRankIterations; iter++ {
+		dangling := 0.0
+		for i, targets := range out {
+			if len(targets) == 0 {
+				dangling += rank[i]
+			}
+		}
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i, targets := range out {
+			if len(targets) == 0 {
+				continue
+			}
+			share := pageRankDamping * rank[i] / float64(len(targets))
+			for _, t := range targets {
+				next[t] += share
+			}
+		}
+		delta := 0.0
+		for i := range rank {
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if delta < pageRankTolerance {
+			break
+		}
+	}
+
+	highest := 0.0
+	for _, r := range rank {
+		highest = max(highest, r)
+	}
+	for i, id := range ids {
+		ranks[id] = rank[i] / highest
+	}
+	return ranks
+}
@@ -18,6 +18,12 @@ CREATE TABLE IF NOT EXISTS nodes (
     tags TEXT,                 -- JSON array stored as text
     in_degree INTEGER DEFAULT 0,
     out_degree INTEGER DEFAULT 0,
+    word_count INTEGER DEFAULT 0,
+    reading_time INTEGER DEFAULT 0,   -- estimated minutes
+    excerpt TEXT,              -- first sentences of the body as plain text
+    centrality REAL DEFAULT 0, -- PageRank, scaled so the most central node scores 1
+    level INTEGER DEFAULT 0,   -- 1 for hub notes, one more per link away; 0 until indexed
+    outline TEXT,              -- JSON array of headings
     created_at TEXT,
     updated_at TEXT,
     parsed_at TEXT DEFAULT (datetime('now')),
@@ -60,6 +66,8 @@ CREATE TABLE IF NOT EXISTS node_positions (
     y REAL NOT NULL DEFAULT 0,
     z REAL DEFAULT 0,
     locked INTEGER DEFAULT 0,
+    pinned INTEGER NOT NULL DEFAULT 0, -- 1 = layout recomputation leaves the node in place
+    absent_parses INTEGER NOT NULL DEFAULT 0, -- Full indexes of the vault since the node was last there
     updated_at TEXT DEFAULT (datetime('now')),
     PRIMARY KEY (graph_id, node_id)
 );
@@ -70,6 +78,158 @@ CREATE TABLE IF NOT EXISTS vault_metadata (
     updated_at TEXT DEFAULT (datetime('now'))
 );
 
+-- Full index runs, for status reporting and ETA estimates
+CREATE TABLE IF NOT EXISTS parse_history (
+    id TEXT PRIMARY KEY,
+    vault_id INTEGER NOT NULL,
+    started_at TEXT NOT NULL,
+    completed_at TEXT,
+    status TEXT NOT NULL,
+    stats TEXT,                 -- JSON models.ParseStats
+    error TEXT,
+    report TEXT                 -- Markdown summary, once the run stores its data
+);
+
+-- Files that failed to parse, or parsed with problems, in each full index
+CREATE TABLE IF NOT EXISTS parse_errors (
+    parse_id TEXT NOT NULL REFERENCES parse_history(id) ON DELETE CASCADE,
+    file_path TEXT NOT NULL,
+    kind TEXT NOT NULL,         -- read, frontmatter, encoding or hook
+    line INTEGER,
+    message TEXT NOT NULL
+);
+
+-- What each full index logged, in order
+CREATE TABLE IF NOT EXISTS parse_logs (
+    parse_id TEXT NOT NULL REFERENCES parse_history(id) ON DELETE CASCADE,
+    logged_at TIMESTAMP NOT NULL,
+    level TEXT NOT NULL,        -- info or warning
+    message TEXT NOT NULL
+);
+
+-- Access control lists assigned through the API (node_id has no FK so ACLs
+-- survive full reindexes, like node_positions)
+CREATE TABLE IF NOT EXISTS node_acls (
+    node_id TEXT PRIMARY KEY,
+    principals TEXT NOT NULL,   -- JSON array of user names and roles
+    updated_at TEXT DEFAULT (datetime('now'))
+);
+
+-- Read-only subgraph snapshots shared by token
+CREATE TABLE IF NOT EXISTS shares (
+    token TEXT PRIMARY KEY,
+    title TEXT,
+    graph_id INTEGER NOT NULL,
+    snapshot TEXT NOT NULL,     -- JSON models.Graph
+    created_by TEXT,
+    created_at TEXT
+);
+
+-- User annotations on nodes (node_id has no FK so comments survive full
+-- reindexes, like node_acls)
+CREATE TABLE IF NOT EXISTS comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    node_id TEXT NOT NULL,
+    author TEXT,
+    body TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
+
+-- Starred nodes per user ('' when auth is disabled); no FK so bookmarks
+-- survive full reindexes
+CREATE TABLE IF NOT EXISTS bookmarks (
+    user TEXT NOT NULL,
+    node_id TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (user, node_id)
+);
+
+-- Note content views, recorded when track-views is enabled
+CREATE TABLE IF NOT EXISTS node_views (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    node_id TEXT NOT NULL,
+    user TEXT,                  -- Empty for anonymous views
+    viewed_at TEXT NOT NULL
+);
+
+-- Parsed files keyed by content hash, so unchanged files skip re-parsing
+CREATE TABLE IF NOT EXISTS file_cache (
+    vault_id INTEGER NOT NULL,
+    path TEXT NOT NULL,
+    hash TEXT NOT NULL,         -- SHA-256 of the file content and parse settings
+    data TEXT NOT NULL,         -- Parser-defined encoding of the parsed file
+    node_id TEXT,               -- Node the file produced, for rename detection
+    PRIMARY KEY (vault_id, path)
+);
+
+-- Server-side layout computations and their status
+CREATE TABLE IF NOT EXISTS layout_jobs (
+    id TEXT PRIMARY KEY,
+    graph_id INTEGER NOT NULL,
+    algorithm TEXT NOT NULL,
+    status TEXT NOT NULL,       -- queued, running, completed or failed
+    positions INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TEXT NOT NULL,
+    completed_at TEXT
+);
+
+-- External http(s) URLs in note bodies
+CREATE TABLE IF NOT EXISTS node_links (
+    node_id TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    PRIMARY KEY (node_id, url)
+);
+
+-- Link targets in note bodies that resolve to no node
+CREATE TABLE IF NOT EXISTS unresolved_links (
+    node_id TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    target TEXT NOT NULL,
+    PRIMARY KEY (node_id, target)
+);
+
+-- Links notes could have but do not, e.g. unlinked mentions of other notes
+CREATE TABLE IF NOT EXISTS potential_edges (
+    source_id TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    target_id TEXT NOT NULL,   -- Not a foreign key: targets may be stored after their sources
+    edge_type TEXT NOT NULL,   -- e.g. unlinked-mention
+    display_text TEXT,         -- The target as the source names it
+    weight REAL DEFAULT 1.0,   -- Times the source names it
+    PRIMARY KEY (source_id, target_id, edge_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_potential_edges_target ON potential_edges(target_id);
+
+-- Pairs of nearly identical notes, found by the duplicate detector; node_a
+-- sorts before node_b
+CREATE TABLE IF NOT EXISTS near_duplicates (
+    node_a TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    node_b TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    similarity REAL NOT NULL,
+    detected_at TEXT NOT NULL,
+    PRIMARY KEY (node_a, node_b)
+);
+
+-- Outcome of the last request to each external URL, by the link checker
+CREATE TABLE IF NOT EXISTS link_checks (
+    url TEXT PRIMARY KEY,
+    status INTEGER NOT NULL DEFAULT 0,  -- HTTP status; 0 when the request failed
+    dead INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    checked_at TEXT NOT NULL
+);
+
+-- Changes to graph data, recorded by the triggers below, so clients can fetch
+-- what changed since a revision instead of whole graphs
+CREATE TABLE IF NOT EXISTS change_log (
+    revision INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,         -- node, acl, edge, member, position or graph
+    graph_id INTEGER,           -- For member, position and graph changes
+    entity_id TEXT NOT NULL,    -- Node ID, or edge ID for edge changes
+    source_id TEXT,             -- Edge endpoints, for edge changes
+    target_id TEXT
+);
+
 -- FTS5 virtual table for full-text search
 CREATE VIRTUAL TABLE IF NOT EXISTS nodes_fts USING fts5(
     title,
@@ -96,11 +256,91 @@ CREATE TRIGGER IF NOT EXISTS nodes_fts_update AFTER UPDATE ON nodes BEGIN
     VALUES (new.rowid, new.title, new.content);
 END;
 
+-- Triggers to record graph changes in change_log. Updates are only recorded
+-- when a field clients see changes.
+CREATE TRIGGER IF NOT EXISTS nodes_changelog_insert AFTER INSERT ON nodes BEGIN
+    INSERT INTO change_log(kind, entity_id) VALUES ('node', new.id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS nodes_changelog_delete AFTER DELETE ON nodes BEGIN
+    INSERT INTO change_log(kind, entity_id) VALUES ('node', old.id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS nodes_changelog_update AFTER UPDATE ON nodes
+WHEN old.id IS NOT new.id OR old.file_path IS NOT new.file_path OR old.title IS NOT new.title
+    OR old.frontmatter IS NOT new.frontmatter OR old.node_type IS NOT new.node_type OR old.tags IS NOT new.tags
+    OR old.word_count IS NOT new.word_count OR old.reading_time IS NOT new.reading_time
+    OR old.level IS NOT new.level OR old.excerpt IS NOT new.excerpt
+BEGIN
+    INSERT INTO change_log(kind, entity_id) VALUES ('node', old.id);
+    INSERT INTO change_log(kind, entity_id) SELECT 'node', new.id WHERE new.id IS NOT old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS node_acls_changelog_insert AFTER INSERT ON node_acls BEGIN
+    INSERT INTO change_log(kind, entity_id) VALUES ('acl', new.node_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS node_acls_changelog_delete AFTER DELETE ON node_acls BEGIN
+    INSERT INTO change_log(kind, entity_id) VALUES ('acl', old.node_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS node_acls_changelog_update AFTER UPDATE ON node_acls
+WHEN old.node_id IS NOT new.node_id OR old.principals IS NOT new.principals
+BEGIN
+    INSERT INTO change_log(kind, entity_id) VALUES ('acl', old.node_id);
+    INSERT INTO change_log(kind, entity_id) SELECT 'acl', new.node_id WHERE new.node_id IS NOT old.node_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS edges_changelog_insert AFTER INSERT ON edges BEGIN
+    INSERT INTO change_log(kind, entity_id, source_id, target_id) VALUES ('edge', new.id, new.source_id, new.target_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS edges_changelog_delete AFTER DELETE ON edges BEGIN
+    INSERT INTO change_log(kind, entity_id, source_id, target_id) VALUES ('edge', old.id, old.source_id, old.target_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS edges_changelog_update AFTER UPDATE ON edges
+WHEN old.edge_type IS NOT new.edge_type OR old.weight IS NOT new.weight
+BEGIN
+    INSERT INTO change_log(kind, entity_id, source_id, target_id) VALUES ('edge', new.id, new.source_id, new.target_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS graph_nodes_changelog_insert AFTER INSERT ON graph_nodes BEGIN
+    INSERT INTO change_log(kind, graph_id, entity_id) VALUES ('member', new.graph_id, new.node_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS graph_nodes_changelog_delete AFTER DELETE ON graph_nodes BEGIN
+    INSERT INTO change_log(kind, graph_id, entity_id) VALUES ('member', old.graph_id, old.node_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS node_positions_changelog_insert AFTER INSERT ON node_positions BEGIN
+    INSERT INTO change_log(kind, graph_id, entity_id) VALUES ('position', new.graph_id, new.node_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS node_positions_changelog_delete AFTER DELETE ON node_positions BEGIN
+    INSERT INTO change_log(kind, graph_id, entity_id) VALUES ('position', old.graph_id, old.node_id);
+END;
+
+CREATE TRIGGER IF NOT EXISTS node_positions_changelog_update AFTER UPDATE ON node_positions
+WHEN old.node_id IS NOT new.node_id OR old.x IS NOT new.x OR old.y IS NOT new.y OR old.z IS NOT new.z
+    OR old.pinned IS NOT new.pinned
+BEGIN
+    INSERT INTO change_log(kind, graph_id, entity_id) VALUES ('position', old.graph_id, old.node_id);
+    INSERT INTO change_log(kind, graph_id, entity_id) SELECT 'position', new.graph_id, new.node_id WHERE new.node_id IS NOT old.node_id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS graphs_changelog_update AFTER UPDATE ON graphs
+WHEN old.config IS NOT new.config OR old.archived IS NOT new.archived
+BEGIN
+    INSERT INTO change_log(kind, graph_id, entity_id) VALUES ('graph', new.id, new.id);
+END;
+
 -- Indexes
 CREATE INDEX IF NOT EXISTS idx_nodes_vault ON nodes(vault_id);
 CREATE INDEX IF NOT EXISTS idx_nodes_file_path ON nodes(file_path);
 CREATE INDEX IF NOT EXISTS idx_nodes_type ON nodes(node_type);
 CREATE INDEX IF NOT EXISTS idx_nodes_created_at ON nodes(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_nodes_updated_at ON nodes(updated_at);
 
 CREATE INDEX IF NOT EXISTS idx_edges_source ON edges(source_id);
 CREATE INDEX IF NOT EXISTS idx_edges_target ON edges(target_id);
@@ -108,3 +348,15 @@ CREATE INDEX IF NOT EXISTS idx_edges_source_target ON edges(source_id, target_id
 CREATE INDEX IF NOT EXISTS idx_edges_type ON edges(edge_type);
 
 CREATE INDEX IF NOT EXISTS idx_graph_nodes_node ON graph_nodes(node_id);
+
+CREATE INDEX IF NOT EXISTS idx_parse_history_vault ON parse_history(vault_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_parse_errors_parse ON parse_errors(parse_id, file_path);
+CREATE INDEX IF NOT EXISTS idx_parse_logs_parse ON parse_logs(parse_id);
+
+CREATE INDEX IF NOT EXISTS idx_comments_node ON comments(node_id, id);
+
+CREATE INDEX IF NOT EXISTS idx_node_views_node ON node_views(node_id, viewed_at);
+CREATE INDEX IF NOT EXISTS idx_node_views_viewed_at ON node_views(viewed_at);
+
+CREATE INDEX IF NOT EXISTS idx_node_links_url ON node_links(url);
+CREATE INDEX IF NOT EXISTS idx_unresolved_links_target ON unresolved_links(target);
@@ -2,12 +2,14 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	_ "embed"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -24,29 +26,77 @@ type Store struct {
 	db *sql.DB
 }
 
-// New opens (or creates) a SQLite database at dbPath and initializes the schema.
+// New opens (or creates) a SQLite database at dbPath and applies pending
+// migrations.
 func New(dbPath string) (*Store, error) {
-	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
-		return nil, fmt.Errorf("create db directory: %w", err)
+	db, err := open(dbPath)
+	if err != nil {
+		return nil, err
 	}
+	if _, _, err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
 
-	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(wal)&_pragma=foreign_keys(1)")
+// NewNoMigrate opens the SQLite database at dbPath without changing its
+// schema, for deployments that migrate separately. It fails with
+// ErrSchemaOutdated unless the schema is at the latest version.
+func NewNoMigrate(dbPath string) (*Store, error) {
+	db, err := open(dbPath)
 	if err != nil {
-		return nil, fmt.Errorf("open database: %w", err)
+		return nil, err
 	}
-
-	if _, err := db.Exec(schemaSQL); err != nil {
+	if err := checkSchema(db); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("initialize schema: %w", err)
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Migrate applies pending migrations to the database at dbPath, creating it
+// if needed, and returns its schema versions before and after.
+func Migrate(dbPath string) (from, to int, err error) {
+	db, err := open(dbPath)
+	if err != nil {
+		return 0, 0, err
 	}
+	defer db.Close()
+	return migrate(db)
+}
 
-	// Migrate: add archived column if missing (for databases created before this feature)
-	db.Exec(`ALTER TABLE graphs ADD COLUMN archived INTEGER NOT NULL DEFAULT 0`)
+// SchemaVersion connects to the existing database at dbPath and returns its
+// schema version, without changing it.
+func SchemaVersion(dbPath string) (int, error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return 0, err
+	}
+	db, err := open(dbPath)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	version, _, err := schemaVersion(db)
+	if err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return version, nil
+}
 
-	return &Store{db: db}, nil
+func open(dbPath string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
+		return nil, fmt.Errorf("create db directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(wal)&_pragma=foreign_keys(1)")
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	return db, nil
 }
 
-// NewMemory creates an in-memory SQLite store for testing.
+// NewMemory creates an in-memory SQLite store for testing and embedding. Each
+// call returns a separate, empty database with the latest schema.
 func NewMemory() (*Store, error) {
 	db, err := sql.Open("sqlite", ":memory:?_pragma=foreign_keys(1)")
 	if err != nil {
@@ -55,7 +105,7 @@ func NewMemory() (*Store, error) {
 	// With :memory:, each connection gets its own database. Limit to one
 	// connection so all queries share the same schema and data.
 	db.SetMaxOpenConns(1)
-	if _, err := db.Exec(schemaSQL); err != nil {
+	if _, _, err := migrate(db); err != nil {
 		db.Close()
 		return nil, err
 	}
@@ -243,7 +293,8 @@ func scanGraphInfos(rows *sql.Rows) ([]models.GraphInfo, error) {
 
 // --- Node operations ---
 
-// UpsertNode inserts or updates a node. VaultID must be set.
+// UpsertNode inserts or updates a node and replaces its stored URLs. VaultID
+// must be set.
 func (s *Store) UpsertNode(n *models.VaultNode) error {
 	tags, err := json.Marshal(n.Tags)
 	if err != nil {
@@ -253,33 +304,87 @@ func (s *Store) UpsertNode(n *models.VaultNode) error {
 	if err != nil {
 		return fmt.Errorf("marshal metadata for node %s: %w", n.ID, err)
 	}
+	outline, err := json.Marshal(n.Outline)
+	if err != nil {
+		return fmt.Errorf("marshal outline for node %s: %w", n.ID, err)
+	}
 
-	_, err = s.db.Exec(`
-		INSERT INTO nodes (id, vault_id, file_path, title, content, frontmatter, node_type, tags, in_degree, out_degree, created_at, updated_at, parsed_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO nodes (id, vault_id, file_path, title, content, frontmatter, node_type, tags, in_degree, out_degree, word_count, reading_time, excerpt, outline, created_at, updated_at, parsed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
 		ON CONFLICT(id) DO UPDATE SET
 			vault_id=excluded.vault_id, file_path=excluded.file_path, title=excluded.title,
 			content=excluded.content, frontmatter=excluded.frontmatter, node_type=excluded.node_type,
 			tags=excluded.tags, in_degree=excluded.in_degree, out_degree=excluded.out_degree,
+			word_count=excluded.word_count, reading_time=excluded.reading_time, excerpt=excluded.excerpt, outline=excluded.outline,
 			created_at=excluded.created_at, updated_at=excluded.updated_at, parsed_at=datetime('now')
 	`, n.ID, n.VaultID, n.FilePath, n.Title, n.Content, string(meta), n.NodeType, string(tags),
-		n.InDegree, n.OutDegree,
-		n.CreatedAt.Format(time.RFC3339), n.UpdatedAt.Format(time.RFC3339))
-	return err
+		n.InDegree, n.OutDegree, n.WordCount, n.ReadingTime, n.Excerpt, string(outline),
+		n.CreatedAt.UTC().Format(time.RFC3339), n.UpdatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM node_links WHERE node_id = ?`, n.ID); err != nil {
+		return fmt.Errorf("clear links of node %s: %w", n.ID, err)
+	}
+	for _, u := range n.URLs {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO node_links (node_id, url) VALUES (?, ?)`, n.ID, u); err != nil {
+			return fmt.Errorf("insert link of node %s: %w", n.ID, err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM unresolved_links WHERE node_id = ?`, n.ID); err != nil {
+		return fmt.Errorf("clear unresolved links of node %s: %w", n.ID, err)
+	}
+	for _, target := range n.Unresolved {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO unresolved_links (node_id, target) VALUES (?, ?)`, n.ID, target); err != nil {
+			return fmt.Errorf("insert unresolved link of node %s: %w", n.ID, err)
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM potential_edges WHERE source_id = ?`, n.ID); err != nil {
+		return fmt.Errorf("clear potential edges of node %s: %w", n.ID, err)
+	}
+	for _, e := range n.Potential {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO potential_edges (source_id, target_id, edge_type, display_text, weight) VALUES (?, ?, ?, ?, ?)`,
+			n.ID, e.TargetID, e.EdgeType, e.DisplayText, e.Weight); err != nil {
+			return fmt.Errorf("insert potential edge of node %s: %w", n.ID, err)
+		}
+	}
+	return tx.Commit()
 }
 
 // GetNode retrieves a single node by ID.
 func (s *Store) GetNode(id string) (*models.VaultNode, error) {
-	row := s.db.QueryRow(`SELECT id, vault_id, file_path, title, content, frontmatter, node_type, tags, in_degree, out_degree, created_at, updated_at FROM nodes WHERE id = ?`, id)
+	row := s.db.QueryRow(`SELECT id, vault_id, file_path, title, content, frontmatter, node_type, tags, in_degree, out_degree, word_count, reading_time, excerpt, centrality, level, created_at, updated_at FROM nodes WHERE id = ?`, id)
 	return scanNode(row)
 }
 
 // GetNodeByVaultPath retrieves a node by vault ID and file path.
 func (s *Store) GetNodeByVaultPath(vaultID int, path string) (*models.VaultNode, error) {
-	row := s.db.QueryRow(`SELECT id, vault_id, file_path, title, content, frontmatter, node_type, tags, in_degree, out_degree, created_at, updated_at FROM nodes WHERE vault_id = ? AND file_path = ?`, vaultID, path)
+	row := s.db.QueryRow(`SELECT id, vault_id, file_path, title, content, frontmatter, node_type, tags, in_degree, out_degree, word_count, reading_time, excerpt, centrality, level, created_at, updated_at FROM nodes WHERE vault_id = ? AND file_path = ?`, vaultID, path)
 	return scanNode(row)
 }
 
+// GetNodeOutline returns the stored heading outline for a node.
+func (s *Store) GetNodeOutline(id string) ([]models.Heading, error) {
+	var raw sql.NullString
+	if err := s.db.QueryRow(`SELECT outline FROM nodes WHERE id = ?`, id).Scan(&raw); err != nil {
+		return nil, err
+	}
+	outline := []models.Heading{}
+	if raw.Valid && raw.String != "" && raw.String != "null" {
+		if err := json.Unmarshal([]byte(raw.String), &outline); err != nil {
+			return nil, fmt.Errorf("unmarshal outline for node %s: %w", id, err)
+		}
+	}
+	return outline, nil
+}
+
 // DeleteNode removes a node and its associated edges.
 func (s *Store) DeleteNode(id string) error {
 	_, err := s.db.Exec(`DELETE FROM nodes WHERE id = ?`, id)
@@ -288,7 +393,123 @@ func (s *Store) DeleteNode(id string) error {
 
 // GetAllNodes returns all nodes (without content for performance).
 func (s *Store) GetAllNodes() ([]models.VaultNode, error) {
-	rows, err := s.db.Query(`SELECT id, vault_id, file_path, title, '', frontmatter, node_type, tags, in_degree, out_degree, created_at, updated_at FROM nodes`)
+	rows, err := s.db.Query(`SELECT id, vault_id, file_path, title, '', frontmatter, node_type, tags, in_degree, out_degree, word_count, reading_time, excerpt, centrality, level, created_at, updated_at FROM nodes`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanNodes(rows)
+}
+
+// forEachPageSize is how many rows ForEachNode and ForEachEdge read per
+// query.
+var forEachPageSize = 1000
+
+// ForEachNode calls fn with every node (without content), in ID order,
+// without loading them all at once. It stops at the first error fn returns.
+// Nodes are read a page at a time and fn is only called between queries, so
+// a slow fn, such as one writing to a client, never holds the store's
+// connection, and fn may use the store.
+func (s *Store) ForEachNode(ctx context.Context, fn func(models.VaultNode) error) error {
+	after := ""
+	for {
+		page, err := s.nodePage(ctx, after)
+		if err != nil {
+			return err
+		}
+		for _, n := range page {
+			if err := fn(n); err != nil {
+				return err
+			}
+		}
+		if len(page) < forEachPageSize {
+			return nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+// nodePage returns up to forEachPageSize nodes (without content) with IDs
+// after after, in ID order.
+func (s *Store) nodePage(ctx context.Context, after string) ([]models.VaultNode, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, vault_id, file_path, title, '', frontmatter, node_type, tags, in_degree, out_degree, word_count, reading_time, excerpt, centrality, level, created_at, updated_at FROM nodes WHERE id > ? ORDER BY id LIMIT ?`, after, forEachPageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanNodes(rows)
+}
+
+// GetNodesByVault returns all nodes of a vault (without content).
+func (s *Store) GetNodesByVault(vaultID int) ([]models.VaultNode, error) {
+	rows, err := s.db.Query(`SELECT id, vault_id, file_path, title, '', frontmatter, node_type, tags, in_degree, out_degree, word_count, reading_time, excerpt, centrality, level, created_at, updated_at FROM nodes WHERE vault_id = ?`, vaultID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanNodes(rows)
+}
+
+// GetNodesByIDs returns the nodes (without content) with the given IDs, in no
+// particular order. Unknown IDs are skipped.
+func (s *Store) GetNodesByIDs(ids []string) ([]models.VaultNode, error) {
+	raw, err := json.Marshal(ids)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`SELECT id, vault_id, file_path, title, '', frontmatter, node_type, tags, in_degree, out_degree, word_count, reading_time, excerpt, centrality, level, created_at, updated_at FROM nodes WHERE id IN (SELECT value FROM json_each(?))`, string(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanNodes(rows)
+}
+
+// GetNodesModified returns nodes (without content) last modified at or after
+// after and before before, oldest first. A zero time leaves that end open.
+func (s *Store) GetNodesModified(after, before time.Time) ([]models.VaultNode, error) {
+	query := `SELECT id, vault_id, file_path, title, '', frontmatter, node_type, tags, in_degree, out_degree, word_count, reading_time, excerpt, centrality, level, created_at, updated_at FROM nodes WHERE 1 = 1`
+	var args []any
+	if !after.IsZero() {
+		query += ` AND updated_at >= ?`
+		args = append(args, after.UTC().Format(time.RFC3339))
+	}
+	if !before.IsZero() {
+		query += ` AND updated_at < ?`
+		args = append(args, before.UTC().Format(time.RFC3339))
+	}
+	rows, err := s.db.Query(query+` ORDER BY updated_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanNodes(rows)
+}
+
+// GetNodesByPathGlob returns nodes whose file path matches a SQLite GLOB
+// pattern (without content).
+func (s *Store) GetNodesByPathGlob(pattern string) ([]models.VaultNode, error) {
+	rows, err := s.db.Query(`SELECT id, vault_id, file_path, title, '', frontmatter, node_type, tags, in_degree, out_degree, word_count, reading_time, excerpt, centrality, level, created_at, updated_at FROM nodes WHERE file_path GLOB ? ORDER BY file_path`, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanNodes(rows)
+}
+
+// GetRecentPublicNodes returns up to limit nodes whose frontmatter sets flag
+// to true and does not set private to true, most recently updated first.
+// Content is included.
+func (s *Store) GetRecentPublicNodes(flag string, limit int) ([]models.VaultNode, error) {
+	rows, err := s.db.Query(`
+		SELECT id, vault_id, file_path, title, content, frontmatter, node_type, tags, in_degree, out_degree, word_count, reading_time, excerpt, centrality, level, created_at, updated_at
+		FROM nodes
+		WHERE json_valid(frontmatter)
+			AND json_extract(frontmatter, ?) IN (1, 'true')
+			AND COALESCE(json_extract(frontmatter, '$.private'), 0) NOT IN (1, 'true')
+		ORDER BY updated_at DESC, id
+		LIMIT ?
+	`, jsonKeyPath(flag), limit)
 	if err != nil {
 		return nil, err
 	}
@@ -296,6 +517,12 @@ func (s *Store) GetAllNodes() ([]models.VaultNode, error) {
 	return scanNodes(rows)
 }
 
+// jsonKeyPath returns a JSON path selecting a top-level key, quoted so keys
+// containing dots or other punctuation work.
+func jsonKeyPath(key string) string {
+	return `$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+}
+
 // --- Edge operations ---
 
 // UpsertEdge inserts or updates an edge.
@@ -322,6 +549,59 @@ func (s *Store) GetAllEdges() ([]models.VaultEdge, error) {
 	return scanEdges(rows)
 }
 
+// ForEachEdge calls fn with every edge, in ID order, without loading them
+// all at once. It stops at the first error fn returns. Like ForEachNode, it
+// reads a page at a time and calls fn only between queries.
+func (s *Store) ForEachEdge(ctx context.Context, fn func(models.VaultEdge) error) error {
+	after := ""
+	for {
+		page, err := s.edgePage(ctx, after)
+		if err != nil {
+			return err
+		}
+		for _, e := range page {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		if len(page) < forEachPageSize {
+			return nil
+		}
+		after = page[len(page)-1].ID
+	}
+}
+
+// edgePage returns up to forEachPageSize edges with IDs after after, in ID
+// order.
+func (s *Store) edgePage(ctx context.Context, after string) ([]models.VaultEdge, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, source_id, target_id, edge_type, display_text, weight FROM edges WHERE id > ? ORDER BY id LIMIT ?`, after, forEachPageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanEdges(rows)
+}
+
+// GetEdgesByNodes returns every edge with its source or target among the
+// given node IDs, in one query.
+func (s *Store) GetEdgesByNodes(nodeIDs []string) ([]models.VaultEdge, error) {
+	raw, err := json.Marshal(nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`
+		WITH ids(id) AS (SELECT value FROM json_each(?))
+		SELECT id, source_id, target_id, edge_type, display_text, weight FROM edges
+		WHERE source_id IN (SELECT id FROM ids) OR target_id IN (SELECT id FROM ids)
+		ORDER BY source_id, target_id, edge_type
+	`, string(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanEdges(rows)
+}
+
 // DeleteEdgesBySource removes all edges originating from a node.
 func (s *Store) DeleteEdgesBySource(sourceID string) error {
 	_, err := s.db.Exec(`DELETE FROM edges WHERE source_id = ?`, sourceID)
@@ -337,73 +617,39 @@ func (s *Store) DeleteEdgesByNode(nodeID string) error {
 // --- Graph-scoped data ---
 
 // GetGraphData returns nodes, edges, and positions scoped to a specific graph.
+// They are read in one transaction, so they agree with each other even while
+// a parse replaces the vault's data.
 func (s *Store) GetGraphData(graphID int) (*models.Graph, error) {
-	// Nodes in this graph
-	nodeRows, err := s.db.Query(`
-		SELECT n.id, n.vault_id, n.file_path, n.title, '', n.frontmatter, n.node_type, n.tags,
-			n.in_degree, n.out_degree, n.created_at, n.updated_at
-		FROM nodes n
-		JOIN graph_nodes gn ON gn.node_id = n.id
-		WHERE gn.graph_id = ?
-	`, graphID)
-	if err != nil {
-		return nil, fmt.Errorf("get graph nodes: %w", err)
-	}
-	defer nodeRows.Close()
-	nodes, err := scanNodes(nodeRows)
-	if err != nil {
-		return nil, fmt.Errorf("scan graph nodes: %w", err)
-	}
-
-	// Edges where both endpoints are in this graph
-	edgeRows, err := s.db.Query(`
-		SELECT e.id, e.source_id, e.target_id, e.edge_type, e.display_text, e.weight
-		FROM edges e
-		WHERE e.source_id IN (SELECT node_id FROM graph_nodes WHERE graph_id = ?)
-		  AND e.target_id IN (SELECT node_id FROM graph_nodes WHERE graph_id = ?)
-	`, graphID, graphID)
-	if err != nil {
-		return nil, fmt.Errorf("get graph edges: %w", err)
-	}
-	defer edgeRows.Close()
-	edges, err := scanEdges(edgeRows)
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
 	if err != nil {
-		return nil, fmt.Errorf("scan graph edges: %w", err)
+		return nil, err
 	}
-
-	// Positions for this graph
-	posRows, err := s.db.Query(`SELECT node_id, x, y, z, locked FROM node_positions WHERE graph_id = ?`, graphID)
+	defer tx.Rollback()
+	raw, err := readGraphData(tx, graphID)
 	if err != nil {
-		return nil, fmt.Errorf("get graph positions: %w", err)
-	}
-	defer posRows.Close()
-	posMap := make(map[string]models.NodePosition)
-	for posRows.Next() {
-		var p models.NodePosition
-		if err := posRows.Scan(&p.NodeID, &p.X, &p.Y, &p.Z, &p.Locked); err != nil {
-			return nil, err
-		}
-		posMap[p.NodeID] = p
-	}
-	if err := posRows.Err(); err != nil {
 		return nil, err
 	}
 
 	// Assemble API response
-	apiNodes := make([]models.Node, 0, len(nodes))
-	for _, n := range nodes {
-		pos := posMap[n.ID]
+	apiNodes := make([]models.Node, 0, len(raw.Nodes))
+	for _, n := range raw.Nodes {
+		pos := raw.Positions[n.ID]
 		apiNodes = append(apiNodes, models.Node{
-			ID:       n.ID,
-			Title:    n.Title,
-			FilePath: n.FilePath,
-			Position: models.Position{X: pos.X, Y: pos.Y, Z: pos.Z},
-			Metadata: map[string]interface{}{"type": n.NodeType},
+			ID:          n.ID,
+			Title:       n.Title,
+			FilePath:    n.FilePath,
+			Position:    models.Position{X: pos.X, Y: pos.Y, Z: pos.Z},
+			Pinned:      pos.Pinned,
+			WordCount:   n.WordCount,
+			ReadingTime: n.ReadingTime,
+			Level:       n.Level,
+			Excerpt:     n.Excerpt,
+			Metadata:    map[string]interface{}{"type": n.NodeType},
 		})
 	}
 
-	apiEdges := make([]models.Edge, 0, len(edges))
-	for _, e := range edges {
+	apiEdges := make([]models.Edge, 0, len(raw.Edges))
+	for _, e := range raw.Edges {
 		apiEdges = append(apiEdges, models.Edge{
 			ID:     e.ID,
 			Source: e.SourceID,
@@ -426,18 +672,36 @@ type GraphDataRaw struct {
 
 // GetGraphDataRaw returns full node/edge/position data plus graph config for a graph.
 // Unlike GetGraphData, this returns VaultNode (with tags, frontmatter) for filter/group evaluation.
+// Like it, it reads everything in one transaction.
 func (s *Store) GetGraphDataRaw(graphID int) (*GraphDataRaw, error) {
+	tx, err := s.db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	// Graph config
 	var config string
-	err := s.db.QueryRow(`SELECT COALESCE(config, '') FROM graphs WHERE id = ?`, graphID).Scan(&config)
+	err = tx.QueryRow(`SELECT COALESCE(config, '') FROM graphs WHERE id = ?`, graphID).Scan(&config)
 	if err != nil {
 		return nil, fmt.Errorf("get graph config: %w", err)
 	}
+	raw, err := readGraphData(tx, graphID)
+	if err != nil {
+		return nil, err
+	}
+	raw.Config = config
+	return raw, nil
+}
 
+// readGraphData reads the nodes, edges and positions of a graph within tx.
+// SQLite reads a transaction's queries from one snapshot of the database, so
+// edges never point at nodes deleted between the queries.
+func readGraphData(tx *sql.Tx, graphID int) (*GraphDataRaw, error) {
 	// Nodes in this graph (full data including content for frontmatter)
-	nodeRows, err := s.db.Query(`
+	nodeRows, err := tx.Query(`
 		SELECT n.id, n.vault_id, n.file_path, n.title, '', n.frontmatter, n.node_type, n.tags,
-			n.in_degree, n.out_degree, n.created_at, n.updated_at
+			n.in_degree, n.out_degree, n.word_count, n.reading_time, n.excerpt, n.centrality, n.level, n.created_at, n.updated_at
 		FROM nodes n
 		JOIN graph_nodes gn ON gn.node_id = n.id
 		WHERE gn.graph_id = ?
@@ -452,7 +716,7 @@ func (s *Store) GetGraphDataRaw(graphID int) (*GraphDataRaw, error) {
 	}
 
 	// Edges where both endpoints are in this graph
-	edgeRows, err := s.db.Query(`
+	edgeRows, err := tx.Query(`
 		SELECT e.id, e.source_id, e.target_id, e.edge_type, e.display_text, e.weight
 		FROM edges e
 		WHERE e.source_id IN (SELECT node_id FROM graph_nodes WHERE graph_id = ?)
@@ -468,7 +732,7 @@ func (s *Store) GetGraphDataRaw(graphID int) (*GraphDataRaw, error) {
 	}
 
 	// Positions
-	posRows, err := s.db.Query(`SELECT node_id, x, y, z, locked FROM node_positions WHERE graph_id = ?`, graphID)
+	posRows, err := tx.Query(`SELECT node_id, x, y, z, locked, pinned FROM node_positions WHERE graph_id = ?`, graphID)
 	if err != nil {
 		return nil, fmt.Errorf("get graph positions: %w", err)
 	}
@@ -476,7 +740,7 @@ func (s *Store) GetGraphDataRaw(graphID int) (*GraphDataRaw, error) {
 	posMap := make(map[string]models.NodePosition)
 	for posRows.Next() {
 		var p models.NodePosition
-		if err := posRows.Scan(&p.NodeID, &p.X, &p.Y, &p.Z, &p.Locked); err != nil {
+		if err := posRows.Scan(&p.NodeID, &p.X, &p.Y, &p.Z, &p.Locked, &p.Pinned); err != nil {
 			return nil, err
 		}
 		posMap[p.NodeID] = p
@@ -486,7 +750,6 @@ func (s *Store) GetGraphDataRaw(graphID int) (*GraphDataRaw, error) {
 	}
 
 	return &GraphDataRaw{
-		Config:    config,
 		Nodes:     nodes,
 		Edges:     edges,
 		Positions: posMap,
@@ -497,7 +760,7 @@ func (s *Store) GetGraphDataRaw(graphID int) (*GraphDataRaw, error) {
 func (s *Store) SearchInGraph(graphID int, query string) ([]models.VaultNode, error) {
 	rows, err := s.db.Query(`
 		SELECT n.id, n.vault_id, n.file_path, n.title, '', n.frontmatter, n.node_type, n.tags,
-			n.in_degree, n.out_degree, n.created_at, n.updated_at
+			n.in_degree, n.out_degree, n.word_count, n.reading_time, n.excerpt, n.centrality, n.level, n.created_at, n.updated_at
 		FROM nodes n
 		JOIN nodes_fts fts ON n.rowid = fts.rowid
 		JOIN graph_nodes gn ON gn.node_id = n.id
@@ -515,6 +778,7 @@ func (s *Store) SearchInGraph(graphID int, query string) ([]models.VaultNode, er
 // --- Position operations (graph-scoped) ---
 
 // UpsertPosition inserts or updates a single node position within a graph.
+// The node's pinned flag is left as is; see SetPinned.
 func (s *Store) UpsertPosition(graphID int, p *models.NodePosition) error {
 	_, err := s.db.Exec(`
 		INSERT INTO node_positions (graph_id, node_id, x, y, z, locked, updated_at)
@@ -525,7 +789,8 @@ func (s *Store) UpsertPosition(graphID int, p *models.NodePosition) error {
 	return err
 }
 
-// UpsertPositions batch-inserts or updates node positions within a graph.
+// UpsertPositions batch-inserts or updates node positions within a graph,
+// leaving pinned flags as they are.
 func (s *Store) UpsertPositions(graphID int, positions []models.NodePosition) error {
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -552,56 +817,230 @@ func (s *Store) UpsertPositions(graphID int, positions []models.NodePosition) er
 	return tx.Commit()
 }
 
-// GetPositionsByGraph returns all positions for a graph, keyed by node ID.
-func (s *Store) GetPositionsByGraph(graphID int) (map[string]models.NodePosition, error) {
-	rows, err := s.db.Query(`SELECT node_id, x, y, z, locked FROM node_positions WHERE graph_id = ?`, graphID)
+// SaveLayoutPositions saves positions computed by a layout job. Only
+// coordinates are written, so locked and pinned flags stay as they are.
+// Positions of pinned nodes are left alone; since that is checked as each row
+// is written, a node pinned while the layout ran is not moved either.
+func (s *Store) SaveLayoutPositions(graphID int, positions []models.NodePosition) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	positions := make(map[string]models.NodePosition)
-	for rows.Next() {
-		var p models.NodePosition
-		if err := rows.Scan(&p.NodeID, &p.X, &p.Y, &p.Z, &p.Locked); err != nil {
-			return nil, err
+	stmt, err := tx.Prepare(`
+		INSERT INTO node_positions (graph_id, node_id, x, y, z, updated_at)
+		VALUES (?, ?, ?, ?, ?, datetime('now'))
+		ON CONFLICT(graph_id, node_id) DO UPDATE SET
+			x=excluded.x, y=excluded.y, z=excluded.z, updated_at=datetime('now')
+		WHERE node_positions.pinned = 0
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, p := range positions {
+		if _, err := stmt.Exec(graphID, p.NodeID, p.X, p.Y, p.Z); err != nil {
+			return err
 		}
-		p.GraphID = graphID
-		positions[p.NodeID] = p
 	}
-	return positions, rows.Err()
+	return tx.Commit()
 }
 
-// GetPositionCount returns the number of positions stored for a graph.
-func (s *Store) GetPositionCount(graphID int) (int, error) {
-	var count int
-	err := s.db.QueryRow(`SELECT COUNT(*) FROM node_positions WHERE graph_id = ?`, graphID).Scan(&count)
-	return count, err
+// ReplacePositions replaces all positions of a graph, including pinned flags.
+func (s *Store) ReplacePositions(graphID int, positions []models.NodePosition) error {
+	return s.replacePositions(graphID, nil, positions)
 }
 
-// --- Graph membership ---
+// ReplacePositionsOf replaces the positions of the nodes nodeIDs in a graph,
+// including pinned flags: those of them missing from positions are deleted.
+// Positions of other nodes are left alone, and positions has none of them.
+func (s *Store) ReplacePositionsOf(graphID int, nodeIDs []string, positions []models.NodePosition) error {
+	if nodeIDs == nil {
+		nodeIDs = []string{}
+	}
+	return s.replacePositions(graphID, nodeIDs, positions)
+}
 
-// ReplaceGraphMemberships replaces all graph memberships for a single node.
-func (s *Store) ReplaceGraphMemberships(nodeID string, graphIDs []int) error {
+// replacePositions is ReplacePositionsOf, for every node when nodeIDs is nil.
+func (s *Store) replacePositions(graphID int, nodeIDs []string, positions []models.NodePosition) error {
+	var filter interface{} // NULL: every node's position is replaced
+	if nodeIDs != nil {
+		raw, err := json.Marshal(nodeIDs)
+		if err != nil {
+			return err
+		}
+		filter = string(raw)
+	}
 	tx, err := s.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	if _, err := tx.Exec(`DELETE FROM graph_nodes WHERE node_id = ?`, nodeID); err != nil {
+	if _, err := tx.Exec(`DELETE FROM node_positions WHERE graph_id = ?1 AND (?2 IS NULL OR node_id IN (SELECT value FROM json_each(?2)))`, graphID, filter); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`
+		INSERT INTO node_positions (graph_id, node_id, x, y, z, locked, pinned, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	`)
+	if err != nil {
 		return err
 	}
+	defer stmt.Close()
 
-	if len(graphIDs) > 0 {
-		stmt, err := tx.Prepare(`INSERT INTO graph_nodes (graph_id, node_id) VALUES (?, ?)`)
-		if err != nil {
+	for _, p := range positions {
+		if _, err := stmt.Exec(graphID, p.NodeID, p.X, p.Y, p.Z, p.Locked, p.Pinned); err != nil {
 			return err
 		}
-		defer stmt.Close()
-		for _, gid := range graphIDs {
-			if _, err := stmt.Exec(gid, nodeID); err != nil {
-				return err
+	}
+	return tx.Commit()
+}
+
+// SetPinned pins or unpins nodes in a graph. Pinned nodes keep their
+// positions when a layout is recomputed. Only nodes with a saved position can
+// be pinned; it returns how many were updated.
+func (s *Store) SetPinned(graphID int, nodeIDs []string, pinned bool) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	updated := 0
+	for _, id := range nodeIDs {
+		res, err := tx.Exec(`
+			UPDATE node_positions SET pinned = ?, updated_at = datetime('now')
+			WHERE graph_id = ? AND node_id = ?
+		`, pinned, graphID, id)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		updated += int(n)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// GetPositionsByGraph returns all positions for a graph, keyed by node ID.
+func (s *Store) GetPositionsByGraph(graphID int) (map[string]models.NodePosition, error) {
+	rows, err := s.db.Query(`SELECT node_id, x, y, z, locked, pinned FROM node_positions WHERE graph_id = ?`, graphID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	positions := make(map[string]models.NodePosition)
+	for rows.Next() {
+		var p models.NodePosition
+		if err := rows.Scan(&p.NodeID, &p.X, &p.Y, &p.Z, &p.Locked, &p.Pinned); err != nil {
+			return nil, err
+		}
+		p.GraphID = graphID
+		positions[p.NodeID] = p
+	}
+	return positions, rows.Err()
+}
+
+// GetPositionCount returns the number of positions stored for a graph.
+func (s *Store) GetPositionCount(graphID int) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM node_positions WHERE graph_id = ?`, graphID).Scan(&count)
+	return count, err
+}
+
+// AgeAbsentPositions counts a full index of the vault against the positions
+// in its graphs: positions of nodes it did not find have one more absent
+// parse, and those of nodes it found have none.
+func (s *Store) AgeAbsentPositions(ctx context.Context, vaultID int) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE node_positions
+		SET absent_parses = CASE WHEN EXISTS (SELECT 1 FROM nodes WHERE id = node_positions.node_id) THEN 0 ELSE absent_parses + 1 END
+		WHERE graph_id IN (SELECT id FROM graphs WHERE vault_id = ?)
+	`, vaultID)
+	return err
+}
+
+// PurgeAbsentPositions deletes the positions of nodes missing from at least
+// the last parses full indexes of their vault, and still missing. It returns
+// how many it deleted.
+func (s *Store) PurgeAbsentPositions(ctx context.Context, parses int) (int, error) {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM node_positions
+		WHERE absent_parses >= ? AND NOT EXISTS (SELECT 1 FROM nodes WHERE id = node_positions.node_id)
+	`, max(parses, 1))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// --- Graph membership ---
+
+// MissingGraphMembers returns the IDs among nodeIDs that are not members of
+// a graph, in their original order.
+func (s *Store) MissingGraphMembers(graphID int, nodeIDs []string) ([]string, error) {
+	ids, err := json.Marshal(nodeIDs)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`
+		SELECT node_id FROM graph_nodes
+		WHERE graph_id = ? AND node_id IN (SELECT value FROM json_each(?))
+	`, graphID, string(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make(map[string]bool, len(nodeIDs))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		members[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, id := range nodeIDs {
+		if !members[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// ReplaceGraphMemberships replaces all graph memberships for a single node.
+func (s *Store) ReplaceGraphMemberships(nodeID string, graphIDs []int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM graph_nodes WHERE node_id = ?`, nodeID); err != nil {
+		return err
+	}
+
+	if len(graphIDs) > 0 {
+		stmt, err := tx.Prepare(`INSERT INTO graph_nodes (graph_id, node_id) VALUES (?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for _, gid := range graphIDs {
+			if _, err := stmt.Exec(gid, nodeID); err != nil {
+				return err
 			}
 		}
 	}
@@ -618,43 +1057,1062 @@ func (s *Store) GetMetadata(key string) (string, error) {
 	if err == sql.ErrNoRows {
 		return "", nil
 	}
-	return value, err
-}
+	return value, err
+}
+
+// SetMetadata sets a metadata key-value pair.
+func (s *Store) SetMetadata(key, value string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO vault_metadata (key, value, updated_at) VALUES (?, ?, datetime('now'))
+		ON CONFLICT(key) DO UPDATE SET value=excluded.value, updated_at=datetime('now')
+	`, key, value)
+	return err
+}
+
+// SaveVaultHealth records the health of a vault, replacing what was recorded.
+func (s *Store) SaveVaultHealth(h models.VaultHealth) error {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	return s.SetMetadata(vaultHealthKey(h.VaultID), string(data))
+}
+
+// GetVaultHealth returns the health last recorded for a vault, or nil if
+// none has been.
+func (s *Store) GetVaultHealth(vaultID int) (*models.VaultHealth, error) {
+	data, err := s.GetMetadata(vaultHealthKey(vaultID))
+	if err != nil || data == "" {
+		return nil, err
+	}
+	var h models.VaultHealth
+	if err := json.Unmarshal([]byte(data), &h); err != nil {
+		return nil, fmt.Errorf("decode health of vault %d: %w", vaultID, err)
+	}
+	return &h, nil
+}
+
+func vaultHealthKey(vaultID int) string {
+	return fmt.Sprintf("health_vault_%d", vaultID)
+}
+
+// --- Parse history ---
+
+// historyTimeLayout is fixed-width so stored timestamps sort chronologically as text.
+const historyTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// SaveParseHistory inserts or updates a parse history record.
+func (s *Store) SaveParseHistory(h *models.ParseHistory) error {
+	stats, err := json.Marshal(h.Stats)
+	if err != nil {
+		return err
+	}
+	var completedAt *string
+	if h.CompletedAt != nil {
+		v := h.CompletedAt.UTC().Format(historyTimeLayout)
+		completedAt = &v
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO parse_history (id, vault_id, started_at, completed_at, status, stats, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			completed_at=excluded.completed_at, status=excluded.status, stats=excluded.stats, error=excluded.error
+	`, h.ID, h.VaultID, h.StartedAt.UTC().Format(historyTimeLayout), completedAt, string(h.Status), string(stats), h.Error)
+	return err
+}
+
+// GetParseHistory returns the most recent parse runs, newest first. A vaultID
+// of 0 includes all vaults; an empty status includes all statuses; a limit of
+// 0 or less returns every matching run.
+func (s *Store) GetParseHistory(vaultID int, status models.ParseStatus, limit int) ([]models.ParseHistory, error) {
+	return s.GetParseHistoryPage(vaultID, status, limit, 0)
+}
+
+// GetParseHistoryPage is GetParseHistory skipping the offset most recent
+// matching runs.
+func (s *Store) GetParseHistoryPage(vaultID int, status models.ParseStatus, limit, offset int) ([]models.ParseHistory, error) {
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+	rows, err := s.db.Query(`
+		SELECT id, vault_id, started_at, completed_at, status, stats, error
+		FROM parse_history
+		WHERE (? = 0 OR vault_id = ?) AND (? = '' OR status = ?)
+		ORDER BY started_at DESC, id
+		LIMIT ? OFFSET ?
+	`, vaultID, vaultID, string(status), string(status), limit, max(offset, 0))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.ParseHistory
+	for rows.Next() {
+		h, err := scanParseHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+// CountParseHistory counts the parse runs GetParseHistory would return
+// without a limit.
+func (s *Store) CountParseHistory(vaultID int, status models.ParseStatus) (int, error) {
+	var n int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM parse_history
+		WHERE (? = 0 OR vault_id = ?) AND (? = '' OR status = ?)
+	`, vaultID, vaultID, string(status), string(status)).Scan(&n)
+	return n, err
+}
+
+// GetParse returns the parse run with the given ID, or sql.ErrNoRows if
+// there is none.
+func (s *Store) GetParse(id string) (*models.ParseHistory, error) {
+	h, err := scanParseHistory(s.db.QueryRow(`
+		SELECT id, vault_id, started_at, completed_at, status, stats, error
+		FROM parse_history WHERE id = ?
+	`, id))
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func scanParseHistory(sc nodeScanner) (models.ParseHistory, error) {
+	var (
+		h           models.ParseHistory
+		startedAt   string
+		completedAt sql.NullString
+		stats       sql.NullString
+		errMsg      sql.NullString
+	)
+	if err := sc.Scan(&h.ID, &h.VaultID, &startedAt, &completedAt, &h.Status, &stats, &errMsg); err != nil {
+		return h, err
+	}
+	h.StartedAt, _ = time.Parse(time.RFC3339Nano, startedAt)
+	if completedAt.Valid {
+		t, _ := time.Parse(time.RFC3339Nano, completedAt.String)
+		h.CompletedAt = &t
+	}
+	if stats.Valid && stats.String != "" {
+		if err := json.Unmarshal([]byte(stats.String), &h.Stats); err != nil {
+			return h, fmt.Errorf("decode parse stats %s: %w", h.ID, err)
+		}
+	}
+	if errMsg.Valid {
+		h.Error = &errMsg.String
+	}
+	return h, nil
+}
+
+// SaveParseReport stores the report of the parse run with the given ID.
+func (s *Store) SaveParseReport(id, report string) error {
+	_, err := s.db.Exec(`UPDATE parse_history SET report = ? WHERE id = ?`, report, id)
+	return err
+}
+
+// GetParseReport returns the report of the parse run with the given ID. It
+// returns sql.ErrNoRows if there is no such run or the run has no report.
+func (s *Store) GetParseReport(id string) (string, error) {
+	var report sql.NullString
+	if err := s.db.QueryRow(`SELECT report FROM parse_history WHERE id = ?`, id).Scan(&report); err != nil {
+		return "", err
+	}
+	if !report.Valid {
+		return "", sql.ErrNoRows
+	}
+	return report.String, nil
+}
+
+// SaveParseErrors replaces the file errors recorded for the parse run with
+// the given ID.
+func (s *Store) SaveParseErrors(id string, errs []models.ParseFileError) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM parse_errors WHERE parse_id = ?`, id); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO parse_errors (parse_id, file_path, kind, line, message) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, e := range errs {
+		var line *int
+		if e.Line > 0 {
+			line = &e.Line
+		}
+		if _, err := stmt.Exec(id, e.FilePath, e.Kind, line, e.Message); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetParseErrors returns the file errors of the parse run with the given ID,
+// by path, limited to kind unless it is empty. It returns sql.ErrNoRows if
+// there is no such run.
+func (s *Store) GetParseErrors(id, kind string) ([]models.ParseFileError, error) {
+	var exists int
+	if err := s.db.QueryRow(`SELECT 1 FROM parse_history WHERE id = ?`, id).Scan(&exists); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`
+		SELECT file_path, kind, line, message FROM parse_errors
+		WHERE parse_id = ? AND (? = '' OR kind = ?)
+		ORDER BY file_path, rowid
+	`, id, kind, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	errs := []models.ParseFileError{}
+	for rows.Next() {
+		var e models.ParseFileError
+		var line sql.NullInt64
+		if err := rows.Scan(&e.FilePath, &e.Kind, &line, &e.Message); err != nil {
+			return nil, err
+		}
+		e.Line = int(line.Int64)
+		errs = append(errs, e)
+	}
+	return errs, rows.Err()
+}
+
+// SaveParseLogs replaces the log records of the parse run with the given ID.
+func (s *Store) SaveParseLogs(id string, logs []models.ParseLogRecord) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM parse_logs WHERE parse_id = ?`, id); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO parse_logs (parse_id, logged_at, level, message) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, l := range logs {
+		if _, err := stmt.Exec(id, l.Time.UTC(), l.Level, l.Message); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetParseLogs returns the log records of the parse run with the given ID in
+// the order they were logged, limited to level unless it is empty. It returns
+// sql.ErrNoRows if there is no such run.
+func (s *Store) GetParseLogs(id, level string) ([]models.ParseLogRecord, error) {
+	var exists int
+	if err := s.db.QueryRow(`SELECT 1 FROM parse_history WHERE id = ?`, id).Scan(&exists); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`
+		SELECT logged_at, level, message FROM parse_logs
+		WHERE parse_id = ? AND (? = '' OR level = ?)
+		ORDER BY rowid
+	`, id, level, level)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []models.ParseLogRecord{}
+	for rows.Next() {
+		var l models.ParseLogRecord
+		if err := rows.Scan(&l.Time, &l.Level, &l.Message); err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+// --- Access control lists ---
+
+// SetNodeACL stores the principals allowed to see a node. An empty list removes the ACL.
+func (s *Store) SetNodeACL(nodeID string, principals []string) error {
+	if len(principals) == 0 {
+		_, err := s.db.Exec(`DELETE FROM node_acls WHERE node_id = ?`, nodeID)
+		return err
+	}
+	data, err := json.Marshal(principals)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO node_acls (node_id, principals, updated_at) VALUES (?, ?, datetime('now'))
+		ON CONFLICT(node_id) DO UPDATE SET principals=excluded.principals, updated_at=datetime('now')
+	`, nodeID, string(data))
+	return err
+}
+
+// GetNodeACLs returns every stored ACL keyed by node ID.
+func (s *Store) GetNodeACLs() (map[string][]string, error) {
+	rows, err := s.db.Query(`SELECT node_id, principals FROM node_acls`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	acls := make(map[string][]string)
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		var principals []string
+		if err := json.Unmarshal([]byte(data), &principals); err != nil {
+			return nil, fmt.Errorf("decode ACL for %s: %w", id, err)
+		}
+		acls[id] = principals
+	}
+	return acls, rows.Err()
+}
+
+// --- Shares ---
+
+// CreateShare stores a subgraph snapshot under sh.Token.
+func (s *Store) CreateShare(sh *models.Share) error {
+	data, err := json.Marshal(sh.Graph)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO shares (token, title, graph_id, snapshot, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, sh.Token, sh.Title, sh.GraphID, string(data), sh.CreatedBy, sh.CreatedAt.Format(time.RFC3339))
+	return err
+}
+
+// GetShare retrieves a share by token. It returns sql.ErrNoRows if none exists.
+func (s *Store) GetShare(token string) (*models.Share, error) {
+	var (
+		sh        models.Share
+		title     sql.NullString
+		createdBy sql.NullString
+		data      string
+		createdAt string
+	)
+	err := s.db.QueryRow(`
+		SELECT token, title, graph_id, snapshot, created_by, created_at FROM shares WHERE token = ?
+	`, token).Scan(&sh.Token, &title, &sh.GraphID, &data, &createdBy, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(data), &sh.Graph); err != nil {
+		return nil, fmt.Errorf("decode share %s: %w", token, err)
+	}
+	sh.Title = title.String
+	sh.CreatedBy = createdBy.String
+	sh.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	return &sh, nil
+}
+
+// DeleteShare removes a share. Deleting a missing share is not an error.
+func (s *Store) DeleteShare(token string) error {
+	_, err := s.db.Exec(`DELETE FROM shares WHERE token = ?`, token)
+	return err
+}
+
+// --- Comments ---
+
+// AddComment stores a comment on a node and sets its ID.
+func (s *Store) AddComment(c *models.Comment) error {
+	res, err := s.db.Exec(`
+		INSERT INTO comments (node_id, author, body, created_at) VALUES (?, ?, ?, ?)
+	`, c.NodeID, c.Author, c.Body, c.CreatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	c.ID = int(id)
+	return nil
+}
+
+// GetComments returns a node's comments, oldest first.
+func (s *Store) GetComments(nodeID string) ([]models.Comment, error) {
+	rows, err := s.db.Query(`
+		SELECT id, node_id, author, body, created_at FROM comments WHERE node_id = ? ORDER BY id
+	`, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var (
+			c         models.Comment
+			author    sql.NullString
+			createdAt string
+		)
+		if err := rows.Scan(&c.ID, &c.NodeID, &author, &c.Body, &createdAt); err != nil {
+			return nil, err
+		}
+		c.Author = author.String
+		c.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+// --- Bookmarks ---
+
+// AddBookmark stars a node for a user ("" when auth is disabled). Starring a
+// node twice keeps the original time.
+func (s *Store) AddBookmark(user, nodeID string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO bookmarks (user, node_id, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(user, node_id) DO NOTHING
+	`, user, nodeID, time.Now().UTC().Format(time.RFC3339))
+	return err
+}
+
+// DeleteBookmark unstars a node. Removing a missing bookmark is not an error.
+func (s *Store) DeleteBookmark(user, nodeID string) error {
+	_, err := s.db.Exec(`DELETE FROM bookmarks WHERE user = ? AND node_id = ?`, user, nodeID)
+	return err
+}
+
+// GetBookmarks returns a user's starred nodes that still exist, most recently
+// starred first.
+func (s *Store) GetBookmarks(user string) ([]models.Bookmark, error) {
+	rows, err := s.db.Query(`
+		SELECT b.node_id, n.title, n.file_path, b.created_at
+		FROM bookmarks b
+		JOIN nodes n ON n.id = b.node_id
+		WHERE b.user = ?
+		ORDER BY b.created_at DESC, b.rowid DESC
+	`, user)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bookmarks []models.Bookmark
+	for rows.Next() {
+		var (
+			b         models.Bookmark
+			createdAt string
+		)
+		if err := rows.Scan(&b.NodeID, &b.Title, &b.FilePath, &createdAt); err != nil {
+			return nil, err
+		}
+		b.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, rows.Err()
+}
+
+// --- View analytics ---
+
+// RecordView records that a user ("" when anonymous) viewed a node's content.
+func (s *Store) RecordView(nodeID, user string, at time.Time) error {
+	_, err := s.db.Exec(`
+		INSERT INTO node_views (node_id, user, viewed_at) VALUES (?, ?, ?)
+	`, nodeID, user, at.UTC().Format(historyTimeLayout))
+	return err
+}
+
+// GetMostViewed returns the nodes viewed most often since the given time,
+// most views first. Deleted nodes are left out, as are nodes not in ids
+// unless ids is nil.
+func (s *Store) GetMostViewed(since time.Time, limit int, ids []string) ([]models.NodeViews, error) {
+	return s.queryNodeViews(`
+		SELECT v.node_id, n.title, n.file_path, COUNT(*), MAX(v.viewed_at)
+		FROM node_views v
+		JOIN nodes n ON n.id = v.node_id
+		WHERE v.viewed_at >= ?2
+			AND (?1 IS NULL OR v.node_id IN (SELECT value FROM json_each(?1)))
+		GROUP BY v.node_id
+		ORDER BY COUNT(*) DESC, MAX(v.viewed_at) DESC
+		LIMIT ?3
+	`, ids, since.UTC().Format(historyTimeLayout), limit)
+}
+
+// GetRecentlyViewed returns the most recently viewed nodes, latest first,
+// with their all-time view counts. Deleted nodes are left out, as are nodes
+// not in ids unless ids is nil.
+func (s *Store) GetRecentlyViewed(limit int, ids []string) ([]models.NodeViews, error) {
+	return s.queryNodeViews(`
+		SELECT v.node_id, n.title, n.file_path, COUNT(*), MAX(v.viewed_at)
+		FROM node_views v
+		JOIN nodes n ON n.id = v.node_id
+		WHERE ?1 IS NULL OR v.node_id IN (SELECT value FROM json_each(?1))
+		GROUP BY v.node_id
+		ORDER BY MAX(v.viewed_at) DESC
+		LIMIT ?2
+	`, ids, limit)
+}
+
+// queryNodeViews runs a view query whose first parameter, ?1, is the JSON
+// list of node IDs to include, or NULL for every node.
+func (s *Store) queryNodeViews(query string, ids []string, args ...any) ([]models.NodeViews, error) {
+	var filter interface{} // NULL: every node is included
+	if ids != nil {
+		raw, err := json.Marshal(ids)
+		if err != nil {
+			return nil, err
+		}
+		filter = string(raw)
+	}
+	rows, err := s.db.Query(query, append([]any{filter}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := []models.NodeViews{}
+	for rows.Next() {
+		var (
+			v        models.NodeViews
+			viewedAt string
+		)
+		if err := rows.Scan(&v.NodeID, &v.Title, &v.FilePath, &v.Views, &viewedAt); err != nil {
+			return nil, err
+		}
+		v.LastViewedAt, _ = time.Parse(time.RFC3339Nano, viewedAt)
+		views = append(views, v)
+	}
+	return views, rows.Err()
+}
+
+// --- Frontmatter keys ---
+
+// GetMetadataKeys lists every frontmatter key used by any note, with usage
+// counts and inferred value types, ordered by count descending then key.
+// If ids is not nil, only the notes with those IDs count.
+func (s *Store) GetMetadataKeys(ids []string) ([]models.MetadataKey, error) {
+	var filter interface{} // NULL: every note counts
+	if ids != nil {
+		raw, err := json.Marshal(ids)
+		if err != nil {
+			return nil, err
+		}
+		filter = string(raw)
+	}
+	rows, err := s.db.Query(`
+		SELECT j.key,
+			CASE
+				WHEN j.type IN ('true', 'false') THEN 'boolean'
+				WHEN j.type = 'integer' THEN 'integer'
+				WHEN j.type = 'real' THEN 'number'
+				WHEN j.type = 'array' THEN 'list'
+				WHEN j.type = 'object' THEN 'object'
+				WHEN j.type = 'null' THEN 'null'
+				WHEN j.value GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]*' THEN 'date'
+				ELSE 'string'
+			END AS kind,
+			COUNT(*)
+		FROM nodes n, json_each(n.frontmatter) j
+		WHERE n.frontmatter IS NOT NULL AND json_valid(n.frontmatter)
+			AND (?1 IS NULL OR n.id IN (SELECT value FROM json_each(?1)))
+		GROUP BY j.key, kind
+	`, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byKey := make(map[string]*models.MetadataKey)
+	for rows.Next() {
+		var key, kind string
+		var count int
+		if err := rows.Scan(&key, &kind, &count); err != nil {
+			return nil, err
+		}
+		mk, ok := byKey[key]
+		if !ok {
+			mk = &models.MetadataKey{Key: key, Types: make(map[string]int)}
+			byKey[key] = mk
+		}
+		mk.Count += count
+		mk.Types[kind] += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]models.MetadataKey, 0, len(byKey))
+	for _, mk := range byKey {
+		best := 0
+		for kind, n := range mk.Types {
+			if kind == "null" {
+				continue
+			}
+			if n > best || (n == best && kind < string(mk.Type)) {
+				best = n
+				mk.Type = models.MetadataType(kind)
+			}
+		}
+		keys = append(keys, *mk)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Count != keys[j].Count {
+			return keys[i].Count > keys[j].Count
+		}
+		return keys[i].Key < keys[j].Key
+	})
+	return keys, nil
+}
+
+// --- External links ---
+
+// GetExternalLinks returns every URL stored nodes link to, sorted, with the
+// linking nodes and the URL's last check. With deadOnly, only URLs the last
+// check found dead are returned.
+func (s *Store) GetExternalLinks(deadOnly bool) ([]models.ExternalLink, error) {
+	rows, err := s.db.Query(`
+		SELECT l.url, l.node_id, COALESCE(c.status, 0), COALESCE(c.dead, 0), COALESCE(c.error, ''), c.checked_at
+		FROM node_links l
+		LEFT JOIN link_checks c ON c.url = l.url
+		WHERE ? = 0 OR c.dead = 1
+		ORDER BY l.url, l.node_id
+	`, deadOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.ExternalLink
+	for rows.Next() {
+		var (
+			l         models.ExternalLink
+			nodeID    string
+			checkedAt sql.NullString
+		)
+		if err := rows.Scan(&l.URL, &nodeID, &l.Status, &l.Dead, &l.Error, &checkedAt); err != nil {
+			return nil, err
+		}
+		if n := len(links); n > 0 && links[n-1].URL == l.URL {
+			links[n-1].NodeIDs = append(links[n-1].NodeIDs, nodeID)
+			continue
+		}
+		if checkedAt.Valid {
+			t, _ := time.Parse(time.RFC3339, checkedAt.String)
+			l.CheckedAt = &t
+		}
+		l.NodeIDs = []string{nodeID}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// GetBrokenLinks returns every link target stored nodes link to that
+// resolves to no node, sorted, with the linking nodes.
+func (s *Store) GetBrokenLinks() ([]models.BrokenLink, error) {
+	rows, err := s.db.Query(`SELECT target, node_id FROM unresolved_links ORDER BY target, node_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.BrokenLink
+	for rows.Next() {
+		var target, nodeID string
+		if err := rows.Scan(&target, &nodeID); err != nil {
+			return nil, err
+		}
+		if n := len(links); n > 0 && links[n-1].Target == target {
+			links[n-1].NodeIDs = append(links[n-1].NodeIDs, nodeID)
+			continue
+		}
+		links = append(links, models.BrokenLink{Target: target, NodeIDs: []string{nodeID}})
+	}
+	return links, rows.Err()
+}
+
+// GetPotentialEdges returns the stored potential edges of a type whose
+// source and target are both stored, by source then target. A non-empty
+// sourceID or targetID keeps only the edges from or to that node.
+func (s *Store) GetPotentialEdges(edgeType, sourceID, targetID string) ([]models.VaultEdge, error) {
+	rows, err := s.db.Query(`
+		SELECT p.source_id, p.target_id, p.edge_type, COALESCE(p.display_text, ''), p.weight
+		FROM potential_edges p
+		JOIN nodes t ON t.id = p.target_id
+		WHERE p.edge_type = ? AND (? = '' OR p.source_id = ?) AND (? = '' OR p.target_id = ?)
+		ORDER BY p.source_id, p.target_id
+	`, edgeType, sourceID, sourceID, targetID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edges []models.VaultEdge
+	for rows.Next() {
+		var e models.VaultEdge
+		if err := rows.Scan(&e.SourceID, &e.TargetID, &e.EdgeType, &e.DisplayText, &e.Weight); err != nil {
+			return nil, err
+		}
+		edges = append(edges, e)
+	}
+	return edges, rows.Err()
+}
+
+// GetExternalURLs returns the distinct URLs stored nodes link to, least
+// recently checked first; unchecked URLs come before all others.
+func (s *Store) GetExternalURLs() ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT l.url
+		FROM node_links l
+		LEFT JOIN link_checks c ON c.url = l.url
+		GROUP BY l.url
+		ORDER BY COALESCE(MAX(c.checked_at), ''), l.url
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		urls = append(urls, u)
+	}
+	return urls, rows.Err()
+}
+
+// SaveLinkCheck records the outcome of checking a URL, replacing the last.
+func (s *Store) SaveLinkCheck(c models.LinkCheck) error {
+	_, err := s.db.Exec(`
+		INSERT INTO link_checks (url, status, dead, error, checked_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(url) DO UPDATE SET
+			status=excluded.status, dead=excluded.dead, error=excluded.error, checked_at=excluded.checked_at
+	`, c.URL, c.Status, c.Dead, c.Error, c.CheckedAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+// PruneLinkChecks deletes the checks of URLs no node links to anymore and
+// returns how many were deleted.
+func (s *Store) PruneLinkChecks() (int, error) {
+	res, err := s.db.Exec(`DELETE FROM link_checks WHERE url NOT IN (SELECT url FROM node_links)`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// --- Near duplicates ---
+
+// ForEachNodeContent calls fn with the ID and content of every node with
+// content, in ID order, without loading all contents at once. It stops at
+// the first error fn returns.
+func (s *Store) ForEachNodeContent(ctx context.Context, fn func(id, content string) error) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, content FROM nodes WHERE content != '' ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, content string
+		if err := rows.Scan(&id, &content); err != nil {
+			return err
+		}
+		if err := fn(id, content); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ReplaceNearDuplicates replaces the stored near-duplicate pairs with pairs,
+// skipping pairs of nodes deleted since they were found.
+func (s *Store) ReplaceNearDuplicates(ctx context.Context, pairs []models.NearDuplicate) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM near_duplicates`); err != nil {
+		return err
+	}
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT OR IGNORE INTO near_duplicates (node_a, node_b, similarity, detected_at)
+		SELECT ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM nodes WHERE id = ?) AND EXISTS (SELECT 1 FROM nodes WHERE id = ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, p := range pairs {
+		at := p.DetectedAt.UTC().Format(time.RFC3339)
+		if _, err := stmt.ExecContext(ctx, p.A, p.B, p.Similarity, at, p.A, p.B); err != nil {
+			return fmt.Errorf("insert pair %s, %s: %w", p.A, p.B, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetNearDuplicates returns the stored near-duplicate pairs at least
+// minSimilarity similar, most similar first.
+func (s *Store) GetNearDuplicates(minSimilarity float64) ([]models.NearDuplicate, error) {
+	rows, err := s.db.Query(`
+		SELECT node_a, node_b, similarity, detected_at FROM near_duplicates
+		WHERE similarity >= ?
+		ORDER BY similarity DESC, node_a, node_b
+	`, minSimilarity)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs []models.NearDuplicate
+	for rows.Next() {
+		var p models.NearDuplicate
+		var at string
+		if err := rows.Scan(&p.A, &p.B, &p.Similarity, &at); err != nil {
+			return nil, err
+		}
+		p.DetectedAt, _ = time.Parse(time.RFC3339, at)
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+// --- Change log ---
+
+// changeLogRetention is how many change_log entries are kept. Clients whose
+// version is older than that must reload graphs in full.
+const changeLogRetention = 100000
+
+// pruneChangeLog drops all but the latest changeLogRetention entries.
+func pruneChangeLog(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM change_log WHERE revision <= (SELECT MAX(revision) FROM change_log) - ?
+	`, changeLogRetention)
+	return err
+}
+
+// GetGraphVersion returns the revision of the latest change to graph data:
+// nodes, edges, memberships, positions, ACLs, or graph configs. It is 0
+// before the first change.
+func (s *Store) GetGraphVersion() (int64, error) {
+	var version int64
+	err := s.db.QueryRow(`SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'change_log'), 0)`).Scan(&version)
+	return version, err
+}
+
+// EdgeChange identifies an edge that was added, changed or removed.
+type EdgeChange struct {
+	ID       string
+	SourceID string
+	TargetID string
+}
+
+// GraphChanges lists what changed after a version. IDs are deduplicated.
+type GraphChanges struct {
+	Complete     bool         // False if changes after the version were pruned
+	GraphChanged bool         // The graph's config or archived flag changed
+	ACLChanged   bool         // A node's ACL was assigned or removed, in any graph
+	NodeIDs      []string     // Nodes added, changed or removed, in any graph
+	MemberIDs    []string     // Nodes added to or removed from the graph
+	PositionIDs  []string     // Nodes whose position in the graph changed
+	Edges        []EdgeChange // Edges added, changed or removed, in any graph
+}
+
+// GetGraphChanges returns the changes relevant to a graph with revisions
+// after since, up to and including until.
+func (s *Store) GetGraphChanges(graphID int, since, until int64) (*GraphChanges, error) {
+	changes := &GraphChanges{}
+	var oldest sql.NullInt64
+	if err := s.db.QueryRow(`SELECT MIN(revision) FROM change_log`).Scan(&oldest); err != nil {
+		return nil, err
+	}
+	changes.Complete = !oldest.Valid || since+1 >= oldest.Int64
+
+	rows, err := s.db.Query(`
+		SELECT DISTINCT kind, entity_id, COALESCE(source_id, ''), COALESCE(target_id, '') FROM change_log
+		WHERE revision > ? AND revision <= ? AND (graph_id IS NULL OR graph_id = ?)
+	`, since, until, graphID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind, id, source, target string
+		if err := rows.Scan(&kind, &id, &source, &target); err != nil {
+			return nil, err
+		}
+		switch kind {
+		case "node":
+			changes.NodeIDs = append(changes.NodeIDs, id)
+		case "member":
+			changes.MemberIDs = append(changes.MemberIDs, id)
+		case "position":
+			changes.PositionIDs = append(changes.PositionIDs, id)
+		case "edge":
+			changes.Edges = append(changes.Edges, EdgeChange{ID: id, SourceID: source, TargetID: target})
+		case "graph":
+			changes.GraphChanged = true
+		case "acl":
+			changes.ACLChanged = true
+		}
+	}
+	return changes, rows.Err()
+}
+
+// --- Bulk operations ---
+
+// ReplaceVaultData atomically replaces all nodes, edges, and graph memberships for a vault.
+// Positions are preserved (no FK from node_positions.node_id to nodes.id).
+func (s *Store) ReplaceVaultData(vaultID int, nodes []models.VaultNode, edges []models.VaultEdge, memberships map[int][]string) error {
+	return s.ReplaceVaultDataContext(context.Background(), vaultID, nodes, edges, memberships)
+}
+
+// ReplaceVaultDataContext is ReplaceVaultData with cancellation. If ctx is done
+// before the commit, the transaction is rolled back and the previous data kept.
+//
+// Rows are synced rather than deleted and reinserted, so rows that did not
+// change are left alone: edges keep their IDs, and change_log only records
+// real changes. Nodes whose file moved are replaced, since another node may
+// take over their path.
+func (s *Store) ReplaceVaultDataContext(ctx context.Context, vaultID int, nodes []models.VaultNode, edges []models.VaultEdge, memberships map[int][]string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Stage the new keys in temp tables to diff against
+	for _, stmt := range []string{
+		`DROP TABLE IF EXISTS temp.new_nodes`,
+		`DROP TABLE IF EXISTS temp.new_edges`,
+		`DROP TABLE IF EXISTS temp.new_members`,
+		`CREATE TEMP TABLE new_nodes (id TEXT PRIMARY KEY, file_path TEXT NOT NULL)`,
+		`CREATE TEMP TABLE new_edges (source_id TEXT, target_id TEXT, edge_type TEXT, PRIMARY KEY (source_id, target_id, edge_type))`,
+		`CREATE TEMP TABLE new_members (graph_id INTEGER, node_id TEXT, PRIMARY KEY (graph_id, node_id))`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("stage vault data: %w", err)
+		}
+	}
+	stage := func(query string, each func(exec func(args ...any) error) error) error {
+		stmt, err := tx.PrepareContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		return each(func(args ...any) error {
+			_, err := stmt.ExecContext(ctx, args...)
+			return err
+		})
+	}
+	if err := stage(`INSERT OR REPLACE INTO temp.new_nodes (id, file_path) VALUES (?, ?)`, func(exec func(...any) error) error {
+		for _, n := range nodes {
+			if err := exec(n.ID, n.FilePath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("stage nodes: %w", err)
+	}
+	if err := stage(`INSERT OR IGNORE INTO temp.new_edges (source_id, target_id, edge_type) VALUES (?, ?, ?)`, func(exec func(...any) error) error {
+		for _, e := range edges {
+			if err := exec(e.SourceID, e.TargetID, e.EdgeType); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("stage edges: %w", err)
+	}
+	if err := stage(`INSERT OR IGNORE INTO temp.new_members (graph_id, node_id) VALUES (?, ?)`, func(exec func(...any) error) error {
+		for graphID, nodeIDs := range memberships {
+			for _, nodeID := range nodeIDs {
+				if err := exec(graphID, nodeID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("stage graph_nodes: %w", err)
+	}
+
+	// Delete graph_nodes for this vault's graphs that are gone
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM graph_nodes
+		WHERE graph_id IN (SELECT id FROM graphs WHERE vault_id = ?)
+		  AND (graph_id, node_id) NOT IN (SELECT graph_id, node_id FROM temp.new_members)
+	`, vaultID); err != nil {
+		return fmt.Errorf("clear graph_nodes: %w", err)
+	}
 
-// SetMetadata sets a metadata key-value pair.
-func (s *Store) SetMetadata(key, value string) error {
-	_, err := s.db.Exec(`
-		INSERT INTO vault_metadata (key, value, updated_at) VALUES (?, ?, datetime('now'))
-		ON CONFLICT(key) DO UPDATE SET value=excluded.value, updated_at=datetime('now')
-	`, key, value)
-	return err
-}
+	// Delete nodes that are gone or moved (cascades to edges)
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM nodes WHERE vault_id = ?
+		  AND (id, file_path) NOT IN (SELECT id, file_path FROM temp.new_nodes)
+	`, vaultID); err != nil {
+		return fmt.Errorf("clear vault nodes: %w", err)
+	}
 
-// --- Bulk operations ---
+	// Delete edges touching this vault's nodes that are gone
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM edges
+		WHERE (source_id IN (SELECT id FROM nodes WHERE vault_id = ?) OR target_id IN (SELECT id FROM nodes WHERE vault_id = ?))
+		  AND (source_id, target_id, edge_type) NOT IN (SELECT source_id, target_id, edge_type FROM temp.new_edges)
+	`, vaultID, vaultID); err != nil {
+		return fmt.Errorf("clear vault edges: %w", err)
+	}
 
-// ReplaceVaultData atomically replaces all nodes, edges, and graph memberships for a vault.
-// Positions are preserved (no FK from node_positions.node_id to nodes.id).
-func (s *Store) ReplaceVaultData(vaultID int, nodes []models.VaultNode, edges []models.VaultEdge, memberships map[int][]string) error {
-	tx, err := s.db.Begin()
+	// Node URLs are replaced along with the nodes
+	if _, err := tx.ExecContext(ctx, `DELETE FROM node_links WHERE node_id IN (SELECT id FROM nodes WHERE vault_id = ?)`, vaultID); err != nil {
+		return fmt.Errorf("clear vault links: %w", err)
+	}
+	linkStmt, err := tx.PrepareContext(ctx, `INSERT OR IGNORE INTO node_links (node_id, url) VALUES (?, ?)`)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
-
-	// Delete graph_nodes for this vault's graphs (before nodes are deleted)
-	if _, err := tx.Exec(`DELETE FROM graph_nodes WHERE graph_id IN (SELECT id FROM graphs WHERE vault_id = ?)`, vaultID); err != nil {
-		return fmt.Errorf("clear graph_nodes: %w", err)
+	defer linkStmt.Close()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM unresolved_links WHERE node_id IN (SELECT id FROM nodes WHERE vault_id = ?)`, vaultID); err != nil {
+		return fmt.Errorf("clear vault unresolved links: %w", err)
 	}
-
-	// Delete nodes for this vault (cascades to edges)
-	if _, err := tx.Exec(`DELETE FROM nodes WHERE vault_id = ?`, vaultID); err != nil {
-		return fmt.Errorf("clear vault nodes: %w", err)
+	unresolvedStmt, err := tx.PrepareContext(ctx, `INSERT OR IGNORE INTO unresolved_links (node_id, target) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer unresolvedStmt.Close()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM potential_edges WHERE source_id IN (SELECT id FROM nodes WHERE vault_id = ?)`, vaultID); err != nil {
+		return fmt.Errorf("clear vault potential edges: %w", err)
+	}
+	potentialStmt, err := tx.PrepareContext(ctx, `INSERT OR REPLACE INTO potential_edges (source_id, target_id, edge_type, display_text, weight) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
 	}
+	defer potentialStmt.Close()
 
-	// Insert nodes
-	nodeStmt, err := tx.Prepare(`
-		INSERT INTO nodes (id, vault_id, file_path, title, content, frontmatter, node_type, tags, in_degree, out_degree, created_at, updated_at, parsed_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+	// Upsert nodes. IDs must be unique across vaults.
+	nodeStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO nodes (id, vault_id, file_path, title, content, frontmatter, node_type, tags, in_degree, out_degree, word_count, reading_time, excerpt, outline, level, created_at, updated_at, parsed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, datetime('now'))
+		ON CONFLICT(id) DO UPDATE SET
+			title=excluded.title, content=excluded.content, frontmatter=excluded.frontmatter,
+			node_type=excluded.node_type, tags=excluded.tags, in_degree=excluded.in_degree,
+			out_degree=excluded.out_degree, word_count=excluded.word_count, reading_time=excluded.reading_time, excerpt=excluded.excerpt,
+			outline=excluded.outline, level=excluded.level, created_at=excluded.created_at, updated_at=excluded.updated_at,
+			parsed_at=excluded.parsed_at
+		WHERE nodes.vault_id = excluded.vault_id
 	`)
 	if err != nil {
 		return err
@@ -670,17 +2128,42 @@ func (s *Store) ReplaceVaultData(vaultID int, nodes []models.VaultNode, edges []
 		if err != nil {
 			return fmt.Errorf("marshal metadata for node %s: %w", n.ID, err)
 		}
-		if _, err := nodeStmt.Exec(n.ID, vaultID, n.FilePath, n.Title, n.Content, string(meta), n.NodeType, string(tags),
-			n.InDegree, n.OutDegree,
-			n.CreatedAt.Format(time.RFC3339), n.UpdatedAt.Format(time.RFC3339)); err != nil {
+		outline, err := json.Marshal(n.Outline)
+		if err != nil {
+			return fmt.Errorf("marshal outline for node %s: %w", n.ID, err)
+		}
+		res, err := nodeStmt.ExecContext(ctx, n.ID, vaultID, n.FilePath, n.Title, n.Content, string(meta), n.NodeType, string(tags),
+			n.InDegree, n.OutDegree, n.WordCount, n.ReadingTime, n.Excerpt, string(outline), n.Level,
+			n.CreatedAt.UTC().Format(time.RFC3339), n.UpdatedAt.UTC().Format(time.RFC3339))
+		if err != nil {
 			return fmt.Errorf("insert node %s: %w", n.ID, err)
 		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			return fmt.Errorf("insert node %s: ID is used in another vault", n.ID)
+		}
+		for _, u := range n.URLs {
+			if _, err := linkStmt.ExecContext(ctx, n.ID, u); err != nil {
+				return fmt.Errorf("insert link of node %s: %w", n.ID, err)
+			}
+		}
+		for _, target := range n.Unresolved {
+			if _, err := unresolvedStmt.ExecContext(ctx, n.ID, target); err != nil {
+				return fmt.Errorf("insert unresolved link of node %s: %w", n.ID, err)
+			}
+		}
+		for _, e := range n.Potential {
+			if _, err := potentialStmt.ExecContext(ctx, n.ID, e.TargetID, e.EdgeType, e.DisplayText, e.Weight); err != nil {
+				return fmt.Errorf("insert potential edge of node %s: %w", n.ID, err)
+			}
+		}
 	}
 
-	// Insert edges
-	edgeStmt, err := tx.Prepare(`
+	// Upsert edges; existing ones keep their IDs
+	edgeStmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO edges (id, source_id, target_id, edge_type, display_text, weight, created_at)
 		VALUES (?, ?, ?, ?, ?, ?, datetime('now'))
+		ON CONFLICT(source_id, target_id, edge_type) DO UPDATE SET
+			display_text=excluded.display_text, weight=excluded.weight
 	`)
 	if err != nil {
 		return err
@@ -694,28 +2177,24 @@ func (s *Store) ReplaceVaultData(vaultID int, nodes []models.VaultNode, edges []
 		if e.SourceID == e.TargetID {
 			continue
 		}
-		if _, err := edgeStmt.Exec(e.ID, e.SourceID, e.TargetID, e.EdgeType, e.DisplayText, e.Weight); err != nil {
+		if _, err := edgeStmt.ExecContext(ctx, e.ID, e.SourceID, e.TargetID, e.EdgeType, e.DisplayText, e.Weight); err != nil {
 			return fmt.Errorf("insert edge %s->%s: %w", e.SourceID, e.TargetID, err)
 		}
 	}
 
-	// Insert graph memberships
-	if len(memberships) > 0 {
-		memberStmt, err := tx.Prepare(`INSERT INTO graph_nodes (graph_id, node_id) VALUES (?, ?)`)
-		if err != nil {
-			return err
-		}
-		defer memberStmt.Close()
+	// Insert new graph memberships
+	if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO graph_nodes (graph_id, node_id) SELECT graph_id, node_id FROM temp.new_members`); err != nil {
+		return fmt.Errorf("insert graph_nodes: %w", err)
+	}
 
-		for graphID, nodeIDs := range memberships {
-			for _, nodeID := range nodeIDs {
-				if _, err := memberStmt.Exec(graphID, nodeID); err != nil {
-					return fmt.Errorf("insert graph_node %d:%s: %w", graphID, nodeID, err)
-				}
-			}
+	for _, table := range []string{"new_nodes", "new_edges", "new_members"} {
+		if _, err := tx.ExecContext(ctx, `DROP TABLE temp.`+table); err != nil {
+			return err
 		}
 	}
-
+	if err := pruneChangeLog(ctx, tx); err != nil {
+		return fmt.Errorf("prune change log: %w", err)
+	}
 	return tx.Commit()
 }
 
@@ -727,12 +2206,13 @@ type nodeScanner interface {
 
 func scanOneNode(sc nodeScanner) (models.VaultNode, error) {
 	var n models.VaultNode
-	var frontmatter, tags, nodeType, createdAt, updatedAt sql.NullString
-	err := sc.Scan(&n.ID, &n.VaultID, &n.FilePath, &n.Title, &n.Content, &frontmatter, &nodeType, &tags, &n.InDegree, &n.OutDegree, &createdAt, &updatedAt)
+	var frontmatter, tags, nodeType, excerpt, createdAt, updatedAt sql.NullString
+	err := sc.Scan(&n.ID, &n.VaultID, &n.FilePath, &n.Title, &n.Content, &frontmatter, &nodeType, &tags, &n.InDegree, &n.OutDegree, &n.WordCount, &n.ReadingTime, &excerpt, &n.Centrality, &n.Level, &createdAt, &updatedAt)
 	if err != nil {
 		return n, err
 	}
 	n.NodeType = nodeType.String
+	n.Excerpt = excerpt.String
 	if frontmatter.Valid {
 		if err := json.Unmarshal([]byte(frontmatter.String), &n.Metadata); err != nil {
 			return n, fmt.Errorf("unmarshal frontmatter for node %s: %w", n.ID, err)
@@ -775,13 +2255,504 @@ func scanNodes(rows *sql.Rows) ([]models.VaultNode, error) {
 func scanEdges(rows *sql.Rows) ([]models.VaultEdge, error) {
 	var edges []models.VaultEdge
 	for rows.Next() {
-		var e models.VaultEdge
-		var displayText sql.NullString
-		if err := rows.Scan(&e.ID, &e.SourceID, &e.TargetID, &e.EdgeType, &displayText, &e.Weight); err != nil {
+		e, err := scanOneEdge(rows)
+		if err != nil {
 			return nil, err
 		}
-		e.DisplayText = displayText.String
 		edges = append(edges, e)
 	}
 	return edges, rows.Err()
 }
+
+func scanOneEdge(rows *sql.Rows) (models.VaultEdge, error) {
+	var e models.VaultEdge
+	var displayText sql.NullString
+	err := rows.Scan(&e.ID, &e.SourceID, &e.TargetID, &e.EdgeType, &displayText, &e.Weight)
+	e.DisplayText = displayText.String
+	return e, err
+}
+
+// GetFileCache returns a vault's cached parsed files keyed by path.
+func (s *Store) GetFileCache(vaultID int) (map[string]models.CachedFile, error) {
+	rows, err := s.db.Query(`SELECT path, hash, data, COALESCE(node_id, '') FROM file_cache WHERE vault_id = ?`, vaultID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cache := make(map[string]models.CachedFile)
+	for rows.Next() {
+		var f models.CachedFile
+		if err := rows.Scan(&f.Path, &f.Hash, &f.Data, &f.NodeID); err != nil {
+			return nil, err
+		}
+		cache[f.Path] = f
+	}
+	return cache, rows.Err()
+}
+
+// ReplaceFileCache replaces all of a vault's cached parsed files.
+func (s *Store) ReplaceFileCache(vaultID int, files []models.CachedFile) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM file_cache WHERE vault_id = ?`, vaultID); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO file_cache (vault_id, path, hash, data, node_id) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, f := range files {
+		if _, err := stmt.Exec(vaultID, f.Path, f.Hash, f.Data, f.NodeID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UpsertCachedFile stores one cached parsed file.
+func (s *Store) UpsertCachedFile(vaultID int, f models.CachedFile) error {
+	_, err := s.db.Exec(`
+		INSERT INTO file_cache (vault_id, path, hash, data, node_id) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(vault_id, path) DO UPDATE SET hash=excluded.hash, data=excluded.data, node_id=excluded.node_id
+	`, vaultID, f.Path, f.Hash, f.Data, f.NodeID)
+	return err
+}
+
+// RemapPositions moves saved positions from old node IDs to new ones
+// (old -> new) in every graph, replacing positions the new IDs already have.
+// Mappings apply simultaneously, so IDs can be swapped. It returns the graphs
+// whose positions moved and the number of positions moved.
+func (s *Store) RemapPositions(remap map[string]string) ([]int, int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	graphIDs, moved, err := remapNodeIDs(tx, "node_positions", remap)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, 0, err
+	}
+	return graphIDs, moved, nil
+}
+
+// RenameNodes moves positions and ACLs from old node IDs to new ones
+// (old -> new), replacing any the new IDs already have, and moves comments,
+// bookmarks and views alongside any the new IDs have. It returns the graphs whose
+// positions moved.
+func (s *Store) RenameNodes(renames map[string]string) ([]int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	graphIDs, _, err := remapNodeIDs(tx, "node_positions", renames)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := remapNodeIDs(tx, "node_acls", renames); err != nil {
+		return nil, err
+	}
+	if err := moveNodeRefs(tx, "comments", renames); err != nil {
+		return nil, err
+	}
+	if err := moveNodeRefs(tx, "bookmarks", renames); err != nil {
+		return nil, err
+	}
+	if err := moveNodeRefs(tx, "node_views", renames); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return graphIDs, nil
+}
+
+// moveNodeRefs rewrites node_id in table (comments, bookmarks or node_views) from each old
+// ID to its new one, keeping rows the new IDs already have. Rows that would
+// then duplicate one, such as a user's bookmark of both IDs, are merged.
+func moveNodeRefs(tx *sql.Tx, table string, renames map[string]string) error {
+	const placeholder = "\x00rename:"
+	for oldID := range renames {
+		if _, err := tx.Exec(`UPDATE `+table+` SET node_id = ? WHERE node_id = ?`, placeholder+oldID, oldID); err != nil {
+			return err
+		}
+	}
+	for oldID, newID := range renames {
+		if _, err := tx.Exec(`UPDATE OR REPLACE `+table+` SET node_id = ? WHERE node_id = ?`, newID, placeholder+oldID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remapNodeIDs rewrites node_id in table (node_positions or node_acls) from
+// each old ID to its new one. Rows are first moved to placeholder IDs so that
+// chained and swapped mappings do not collide. For node_positions it returns
+// the affected graphs; for both, the number of rows moved.
+func remapNodeIDs(tx *sql.Tx, table string, remap map[string]string) ([]int, int, error) {
+	var graphIDs []int
+	seen := make(map[int]bool)
+	if table == "node_positions" {
+		for oldID := range remap {
+			rows, err := tx.Query(`SELECT graph_id FROM node_positions WHERE node_id = ?`, oldID)
+			if err != nil {
+				return nil, 0, err
+			}
+			for rows.Next() {
+				var id int
+				if err := rows.Scan(&id); err != nil {
+					rows.Close()
+					return nil, 0, err
+				}
+				if !seen[id] {
+					seen[id] = true
+					graphIDs = append(graphIDs, id)
+				}
+			}
+			rows.Close()
+		}
+		sort.Ints(graphIDs)
+	}
+
+	const placeholder = "\x00remap:"
+	for oldID := range remap {
+		if _, err := tx.Exec(`UPDATE `+table+` SET node_id = ? WHERE node_id = ?`, placeholder+oldID, oldID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	// Positions are per graph, ACLs per node
+	scope := ""
+	if table == "node_positions" {
+		scope = ` AND graph_id IN (SELECT graph_id FROM node_positions WHERE node_id = ?)`
+	}
+	moved := 0
+	for oldID, newID := range remap {
+		args := []any{newID}
+		if scope != "" {
+			args = append(args, placeholder+oldID)
+		}
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE node_id = ?`+scope, args...); err != nil {
+			return nil, 0, err
+		}
+		res, err := tx.Exec(`UPDATE `+table+` SET node_id = ? WHERE node_id = ?`, newID, placeholder+oldID)
+		if err != nil {
+			return nil, 0, err
+		}
+		n, _ := res.RowsAffected()
+		moved += int(n)
+	}
+	return graphIDs, moved, nil
+}
+
+// --- Layout jobs ---
+
+// SaveLayoutJob inserts or updates a layout job.
+func (s *Store) SaveLayoutJob(j *models.LayoutJob) error {
+	var completedAt *string
+	if j.CompletedAt != nil {
+		v := j.CompletedAt.UTC().Format(time.RFC3339)
+		completedAt = &v
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO layout_jobs (id, graph_id, algorithm, status, positions, error, created_at, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status=excluded.status, positions=excluded.positions, error=excluded.error, completed_at=excluded.completed_at
+	`, j.ID, j.GraphID, j.Algorithm, string(j.Status), j.Positions, j.Error, j.CreatedAt.UTC().Format(time.RFC3339), completedAt)
+	return err
+}
+
+// GetLayoutJob retrieves a layout job by ID. It returns sql.ErrNoRows if none exists.
+func (s *Store) GetLayoutJob(id string) (*models.LayoutJob, error) {
+	var (
+		j           models.LayoutJob
+		errMsg      sql.NullString
+		createdAt   string
+		completedAt sql.NullString
+	)
+	err := s.db.QueryRow(`
+		SELECT id, graph_id, algorithm, status, positions, error, created_at, completed_at
+		FROM layout_jobs WHERE id = ?
+	`, id).Scan(&j.ID, &j.GraphID, &j.Algorithm, &j.Status, &j.Positions, &errMsg, &createdAt, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+	j.Error = errMsg.String
+	j.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+	if completedAt.Valid {
+		t, _ := time.Parse(time.RFC3339, completedAt.String)
+		j.CompletedAt = &t
+	}
+	return &j, nil
+}
+
+// FailUnfinishedLayoutJobs marks queued and running layout jobs as failed
+// with the given message, returning how many there were.
+func (s *Store) FailUnfinishedLayoutJobs(message string) (int, error) {
+	res, err := s.db.Exec(`
+		UPDATE layout_jobs SET status = ?, error = ?, completed_at = ?
+		WHERE status IN (?, ?)
+	`, string(models.LayoutJobFailed), message, time.Now().UTC().Format(time.RFC3339),
+		string(models.LayoutJobQueued), string(models.LayoutJobRunning))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// --- Maintenance ---
+
+// graphTables are the tables read to serve graphs, which Optimize analyzes.
+var graphTables = []string{"nodes", "edges", "graphs", "graph_nodes", "node_positions", "change_log"}
+
+// ClearFileCaches removes every vault's cached parsed files, so the next
+// index parses every file again. It returns how many files were cached.
+func (s *Store) ClearFileCaches() (int, error) {
+	res, err := s.db.Exec(`DELETE FROM file_cache`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// RecomputeMetrics sets every node's in and out degree and its centrality,
+// its PageRank scaled so the most central node scores 1, from the stored
+// edges in one transaction. It returns how many nodes changed.
+func (s *Store) RecomputeMetrics(ctx context.Context) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	changed := make(map[string]bool)
+	rows, err := tx.QueryContext(ctx, `
+		WITH degrees AS (
+			SELECT n.id,
+				(SELECT COUNT(*) FROM edges WHERE target_id = n.id) AS in_degree,
+				(SELECT COUNT(*) FROM edges WHERE source_id = n.id) AS out_degree
+			FROM nodes n
+		)
+		UPDATE nodes SET in_degree = degrees.in_degree, out_degree = degrees.out_degree
+		FROM degrees
+		WHERE nodes.id = degrees.id
+			AND (nodes.in_degree IS NOT degrees.in_degree OR nodes.out_degree IS NOT degrees.out_degree)
+		RETURNING nodes.id
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("recompute degrees: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		changed[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	idRows, err := tx.QueryContext(ctx, `SELECT id FROM nodes ORDER BY id`)
+	if err != nil {
+		return 0, fmt.Errorf("load nodes: %w", err)
+	}
+	var ids []string
+	for idRows.Next() {
+		var id string
+		if err := idRows.Scan(&id); err != nil {
+			idRows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	idRows.Close()
+	if err := idRows.Err(); err != nil {
+		return 0, err
+	}
+	edgeRows, err := tx.QueryContext(ctx, `SELECT source_id, target_id FROM edges`)
+	if err != nil {
+		return 0, fmt.Errorf("load edges: %w", err)
+	}
+	var edges [][2]string
+	for edgeRows.Next() {
+		var e [2]string
+		if err := edgeRows.Scan(&e[0], &e[1]); err != nil {
+			edgeRows.Close()
+			return 0, err
+		}
+		edges = append(edges, e)
+	}
+	edgeRows.Close()
+	if err := edgeRows.Err(); err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE nodes SET centrality = ? WHERE id = ? AND abs(coalesce(centrality, 0) - ?) > 1e-9`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for id, rank := range pageRank(ids, edges) {
+		res, err := stmt.ExecContext(ctx, rank, id, rank)
+		if err != nil {
+			return 0, fmt.Errorf("update centrality: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			changed[id] = true
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(changed), nil
+}
+
+// SetNodeTypes sets the type of each node in types, keyed by node ID, in one
+// transaction, and returns the IDs of the nodes whose type changed. Unknown
+// IDs are skipped.
+func (s *Store) SetNodeTypes(ctx context.Context, types map[string]string) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE nodes SET node_type = ? WHERE id = ? AND coalesce(node_type, '') != ?`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	var changed []string
+	for id, nodeType := range types {
+		res, err := stmt.ExecContext(ctx, nodeType, id, nodeType)
+		if err != nil {
+			return nil, fmt.Errorf("update node %s: %w", id, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			changed = append(changed, id)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// Optimize refreshes the query planner's statistics for the graph tables,
+// then rebuilds the database file to reclaim free pages. VACUUM needs
+// exclusive access, so it waits for other writers and blocks them while it
+// runs.
+func (s *Store) Optimize(ctx context.Context) error {
+	for _, table := range graphTables {
+		if _, err := s.db.ExecContext(ctx, `ANALYZE `+table); err != nil {
+			return fmt.Errorf("analyze %s: %w", table, err)
+		}
+	}
+	if _, err := s.db.ExecContext(ctx, `VACUUM`); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	return nil
+}
+
+// StorageUsage is the space the database takes, by table and index.
+type StorageUsage struct {
+	PageSize  int64       `json:"page_size"`
+	FileBytes int64       `json:"file_bytes"` // Size of the main database file
+	FreeBytes int64       `json:"free_bytes"` // Unused pages, which Optimize reclaims
+	Tables    []TableSize `json:"tables"`     // Largest, with indexes, first
+	Indexes   []IndexSize `json:"indexes"`    // Largest first
+}
+
+// TableSize is the space one table and its indexes take.
+type TableSize struct {
+	Name       string `json:"name"`
+	Rows       int64  `json:"rows"`
+	Bytes      int64  `json:"bytes"`
+	IndexBytes int64  `json:"index_bytes"`
+}
+
+// IndexSize is the space one index takes.
+type IndexSize struct {
+	Name  string `json:"name"`
+	Table string `json:"table"`
+	Bytes int64  `json:"bytes"`
+}
+
+// StorageUsage measures the database by page, counting each table's rows.
+// Counting reads every table, so it takes time on large databases.
+func (s *Store) StorageUsage(ctx context.Context) (*StorageUsage, error) {
+	u := &StorageUsage{Tables: []TableSize{}, Indexes: []IndexSize{}}
+	var pages, free int64
+	if err := s.db.QueryRowContext(ctx, `SELECT page_size, page_count, freelist_count FROM pragma_page_size, pragma_page_count, pragma_freelist_count`).Scan(&u.PageSize, &pages, &free); err != nil {
+		return nil, fmt.Errorf("page counts: %w", err)
+	}
+	u.FileBytes, u.FreeBytes = pages*u.PageSize, free*u.PageSize
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.type, m.name, m.tbl_name, SUM(d.pgsize)
+		FROM dbstat d JOIN sqlite_master m ON m.name = d.name
+		GROUP BY m.name
+		ORDER BY SUM(d.pgsize) DESC, m.name`)
+	if err != nil {
+		return nil, fmt.Errorf("page sizes: %w", err)
+	}
+	defer rows.Close()
+	tables := make(map[string]*TableSize)
+	var order []string
+	table := func(name string) *TableSize {
+		t, ok := tables[name]
+		if !ok {
+			t = &TableSize{Name: name}
+			tables[name] = t
+			order = append(order, name)
+		}
+		return t
+	}
+	for rows.Next() {
+		var typ, name, tbl string
+		var bytes int64
+		if err := rows.Scan(&typ, &name, &tbl, &bytes); err != nil {
+			return nil, err
+		}
+		if typ == "index" {
+			u.Indexes = append(u.Indexes, IndexSize{Name: name, Table: tbl, Bytes: bytes})
+			table(tbl).IndexBytes += bytes
+		} else {
+			table(name).Bytes += bytes
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, name := range order {
+		t := tables[name]
+		if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "`+strings.ReplaceAll(name, `"`, `""`)+`"`).Scan(&t.Rows); err != nil {
+			return nil, fmt.Errorf("count %s: %w", name, err)
+		}
+		u.Tables = append(u.Tables, *t)
+	}
+	sort.SliceStable(u.Tables, func(i, j int) bool {
+		return u.Tables[i].Bytes+u.Tables[i].IndexBytes > u.Tables[j].Bytes+u.Tables[j].IndexBytes
+	})
+	return u, nil
+}
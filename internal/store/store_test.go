@@ -1,7 +1,11 @@
 package store
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -244,6 +248,25 @@ func TestUpsertAndGetNode(t *testing.T) {
 	assert.Contains(t, got.Content, "Some content here.")
 }
 
+func TestUpsertNodeKeepsExcerpt(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+	n := testNode(vid, "n1", "Test Node", "test.md")
+	n.Excerpt = "Some content here."
+	require.NoError(t, s.UpsertNode(&n))
+
+	got, err := s.GetNode("n1")
+	require.NoError(t, err)
+	assert.Equal(t, "Some content here.", got.Excerpt)
+
+	// Listings leave content out but keep the excerpt
+	all, err := s.GetAllNodes()
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Empty(t, all[0].Content)
+	assert.Equal(t, "Some content here.", all[0].Excerpt)
+}
+
 func TestUpsertNodeUpdatesExisting(t *testing.T) {
 	s := newTestStore(t)
 	vid := createTestVault(t, s, "v", "/v")
@@ -361,6 +384,32 @@ func TestDeleteEdgesBySource(t *testing.T) {
 	assert.Equal(t, "b", edges[0].SourceID)
 }
 
+func TestGetEdgesByNodes(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+	for _, id := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, s.UpsertNode(&models.VaultNode{ID: id, VaultID: vid, Title: id, FilePath: id + ".md", CreatedAt: time.Now(), UpdatedAt: time.Now()}))
+	}
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{ID: "e1", SourceID: "a", TargetID: "b", EdgeType: "wikilink", Weight: 1}))
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{ID: "e2", SourceID: "c", TargetID: "a", EdgeType: "wikilink", Weight: 1}))
+	require.NoError(t, s.UpsertEdge(&models.VaultEdge{ID: "e3", SourceID: "c", TargetID: "d", EdgeType: "wikilink", Weight: 1}))
+
+	edges, err := s.GetEdgesByNodes([]string{"a", "missing"})
+	require.NoError(t, err)
+	require.Len(t, edges, 2)
+	assert.Equal(t, "e1", edges[0].ID)
+	assert.Equal(t, "e2", edges[1].ID)
+
+	edges, err = s.GetEdgesByNodes([]string{"b", "d"})
+	require.NoError(t, err)
+	assert.Len(t, edges, 2)
+
+	nodes, err := s.GetNodesByIDs([]string{"d", "missing"})
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "d", nodes[0].ID)
+}
+
 // --- Graph-scoped data tests ---
 
 func TestGetGraphDataScopesNodes(t *testing.T) {
@@ -530,6 +579,95 @@ func TestUpsertPositionsBatch(t *testing.T) {
 	assert.Len(t, graph.Nodes, 2)
 }
 
+func TestSaveLayoutPositionsKeepsPinned(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+	gid := createTestGraph(t, s, vid, "root", "")
+	require.NoError(t, s.UpsertPositions(gid, []models.NodePosition{{NodeID: "a", X: 1}, {NodeID: "b", X: 2, Locked: true}}))
+	_, err := s.SetPinned(gid, []string{"a"}, true)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SaveLayoutPositions(gid, []models.NodePosition{{NodeID: "a", X: 10}, {NodeID: "b", X: 20}, {NodeID: "c", X: 30}}))
+	positions, err := s.GetPositionsByGraph(gid)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, positions["a"].X)
+	assert.True(t, positions["a"].Pinned)
+	assert.Equal(t, 20.0, positions["b"].X)
+	assert.True(t, positions["b"].Locked, "layouts keep the locked flag")
+	assert.Equal(t, 30.0, positions["c"].X)
+}
+
+func TestSetPinned(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+	gid := createTestGraph(t, s, vid, "root", "")
+	require.NoError(t, s.UpsertPositions(gid, []models.NodePosition{{NodeID: "a", X: 1}, {NodeID: "b", X: 2}}))
+
+	n, err := s.SetPinned(gid, []string{"a", "missing"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// Moving a pinned node keeps it pinned
+	require.NoError(t, s.UpsertPosition(gid, &models.NodePosition{NodeID: "a", X: 5}))
+	positions, err := s.GetPositionsByGraph(gid)
+	require.NoError(t, err)
+	assert.True(t, positions["a"].Pinned)
+	assert.Equal(t, 5.0, positions["a"].X)
+	assert.False(t, positions["b"].Pinned)
+
+	_, err = s.SetPinned(gid, []string{"a"}, false)
+	require.NoError(t, err)
+	positions, err = s.GetPositionsByGraph(gid)
+	require.NoError(t, err)
+	assert.False(t, positions["a"].Pinned)
+}
+
+func TestPurgeAbsentPositions(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	v1 := createTestVault(t, s, "v1", "/v1")
+	v2 := createTestVault(t, s, "v2", "/v2")
+	g1 := createTestGraph(t, s, v1, "root", "")
+	g2 := createTestGraph(t, s, v2, "root", "")
+	require.NoError(t, s.UpsertNode(&models.VaultNode{ID: "a", VaultID: v1, Title: "A", FilePath: "a.md", CreatedAt: time.Now(), UpdatedAt: time.Now()}))
+	require.NoError(t, s.UpsertPositions(g1, []models.NodePosition{{NodeID: "a"}, {NodeID: "gone"}, {NodeID: "back"}}))
+	require.NoError(t, s.UpsertPositions(g2, []models.NodePosition{{NodeID: "gone2"}}))
+	ids := func(gid int) []string {
+		positions, err := s.GetPositionsByGraph(gid)
+		require.NoError(t, err)
+		var out []string
+		for id := range positions {
+			out = append(out, id)
+		}
+		return out
+	}
+
+	require.NoError(t, s.AgeAbsentPositions(ctx, v1))
+	require.NoError(t, s.UpsertNode(&models.VaultNode{ID: "back", VaultID: v1, Title: "Back", FilePath: "back.md", CreatedAt: time.Now(), UpdatedAt: time.Now()}))
+	require.NoError(t, s.AgeAbsentPositions(ctx, v1))
+
+	// gone has missed two indexes of v1; back is found again, and gone2's
+	// vault has not been indexed
+	n, err := s.PurgeAbsentPositions(ctx, 3)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	n, err = s.PurgeAbsentPositions(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.ElementsMatch(t, []string{"a", "back"}, ids(g1))
+	assert.ElementsMatch(t, []string{"gone2"}, ids(g2))
+
+	// back's count started over, and nodes that return before a purge keep
+	// their positions
+	require.NoError(t, s.DeleteNode("back"))
+	require.NoError(t, s.AgeAbsentPositions(ctx, v1))
+	require.NoError(t, s.UpsertNode(&models.VaultNode{ID: "back", VaultID: v1, Title: "Back", FilePath: "back.md", CreatedAt: time.Now(), UpdatedAt: time.Now()}))
+	n, err = s.PurgeAbsentPositions(ctx, 1)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.ElementsMatch(t, []string{"a", "back"}, ids(g1))
+}
+
 // --- Metadata tests ---
 
 func TestSetAndGetMetadata(t *testing.T) {
@@ -574,6 +712,62 @@ func TestReplaceVaultData(t *testing.T) {
 	assert.Len(t, graph.Edges, 1)
 }
 
+func TestReplaceVaultDataStoresLevels(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+	gid := createTestGraph(t, s, vid, "root", "")
+
+	nodes := []models.VaultNode{
+		{ID: "a", Title: "A", FilePath: "a.md", Level: 1, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "b", Title: "B", FilePath: "b.md", Level: 2, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	edges := []models.VaultEdge{
+		{SourceID: "a", TargetID: "b", EdgeType: "wikilink", Weight: 1},
+	}
+	require.NoError(t, s.ReplaceVaultData(vid, nodes, edges, map[int][]string{gid: {"a", "b"}}))
+
+	graph, err := s.GetGraphData(gid)
+	require.NoError(t, err)
+	levels := make(map[string]int)
+	for _, n := range graph.Nodes {
+		levels[n.ID] = n.Level
+	}
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, levels)
+	b, err := s.GetNode("b")
+	require.NoError(t, err)
+	assert.Equal(t, 2, b.Level)
+}
+
+func TestReplaceVaultDataPotentialEdges(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+
+	mention := models.VaultEdge{TargetID: "b", EdgeType: "unlinked-mention", DisplayText: "bee", Weight: 2}
+	nodes := []models.VaultNode{
+		// The target comes after its source
+		{ID: "a", Title: "A", FilePath: "a.md", Potential: []models.VaultEdge{mention}, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "b", Title: "B", FilePath: "b.md", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	require.NoError(t, s.ReplaceVaultData(vid, nodes, nil, nil))
+
+	edges, err := s.GetPotentialEdges("unlinked-mention", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, []models.VaultEdge{{SourceID: "a", TargetID: "b", EdgeType: "unlinked-mention", DisplayText: "bee", Weight: 2}}, edges)
+	edges, err = s.GetPotentialEdges("unlinked-mention", "b", "")
+	require.NoError(t, err)
+	assert.Empty(t, edges)
+
+	// Replaced with the nodes, and gone with the target
+	require.NoError(t, s.ReplaceVaultData(vid, nodes[:1], nil, nil))
+	edges, err = s.GetPotentialEdges("unlinked-mention", "", "")
+	require.NoError(t, err)
+	assert.Empty(t, edges)
+	nodes[0].Potential = nil
+	require.NoError(t, s.ReplaceVaultData(vid, nodes, nil, nil))
+	edges, err = s.GetPotentialEdges("unlinked-mention", "", "")
+	require.NoError(t, err)
+	assert.Empty(t, edges)
+}
 func TestReplaceVaultDataPreservesOtherVault(t *testing.T) {
 	s := newTestStore(t)
 	v1 := createTestVault(t, s, "v1", "/v1")
@@ -598,6 +792,61 @@ func TestReplaceVaultDataPreservesOtherVault(t *testing.T) {
 	assert.Equal(t, "v2n1", graph2.Nodes[0].ID)
 }
 
+// Graph reads see each parse's data entirely or not at all, so edges always
+// join nodes of the same read.
+func TestGetGraphDataConsistentDuringReplace(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer s.Close()
+	vid := createTestVault(t, s, "v", "/v")
+	gid := createTestGraph(t, s, vid, "root", "")
+
+	// Each parse replaces every node, alternating between two vaults' worth
+	versions := [2][]string{{"a", "b"}, {"c", "d"}}
+	replace := func(i int) error {
+		ids := versions[i%2]
+		return s.ReplaceVaultData(vid,
+			[]models.VaultNode{testNode(vid, ids[0], ids[0], ids[0]+".md"), testNode(vid, ids[1], ids[1], ids[1]+".md")},
+			[]models.VaultEdge{{SourceID: ids[0], TargetID: ids[1], EdgeType: "wikilink", Weight: 1}},
+			map[int][]string{gid: ids})
+	}
+	require.NoError(t, replace(0))
+
+	done := make(chan error)
+	go func() {
+		var err error
+		for i := 1; i <= 30 && err == nil; i++ {
+			err = replace(i)
+		}
+		done <- err
+	}()
+	for reading := true; reading; {
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+			reading = false
+		default:
+		}
+		raw, err := s.GetGraphDataRaw(gid)
+		require.NoError(t, err)
+		ids := make(map[string]bool)
+		for _, n := range raw.Nodes {
+			ids[n.ID] = true
+		}
+		require.Len(t, ids, 2)
+		for _, e := range raw.Edges {
+			require.True(t, ids[e.SourceID] && ids[e.TargetID], "edge %s->%s read with nodes %v", e.SourceID, e.TargetID, ids)
+		}
+
+		graph, err := s.GetGraphData(gid)
+		require.NoError(t, err)
+		require.Len(t, graph.Nodes, 2)
+		for _, e := range graph.Edges {
+			require.True(t, e.Source == graph.Nodes[0].ID || e.Source == graph.Nodes[1].ID, "edge from %s read with other nodes", e.Source)
+		}
+	}
+}
+
 func TestReplaceVaultDataPreservesPositions(t *testing.T) {
 	s := newTestStore(t)
 	vid := createTestVault(t, s, "v", "/v")
@@ -637,6 +886,104 @@ func TestReplaceVaultDataSkipsSelfReferentialEdges(t *testing.T) {
 	assert.Len(t, allEdges, 0)
 }
 
+func TestGetGraphChanges(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+	gid := createTestGraph(t, s, vid, "root", "")
+
+	nodes := []models.VaultNode{
+		{ID: "a", Title: "A", FilePath: "a.md", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "b", Title: "B", FilePath: "b.md", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "c", Title: "C", FilePath: "c.md", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	edges := []models.VaultEdge{
+		{SourceID: "a", TargetID: "b", EdgeType: "wikilink", Weight: 1},
+		{SourceID: "b", TargetID: "c", EdgeType: "wikilink", Weight: 1},
+	}
+	members := map[int][]string{gid: {"a", "b", "c"}}
+	require.NoError(t, s.ReplaceVaultData(vid, nodes, edges, members))
+	before, err := s.GetAllEdges()
+	require.NoError(t, err)
+
+	v1, err := s.GetGraphVersion()
+	require.NoError(t, err)
+	assert.Positive(t, v1)
+
+	// Reindexing unchanged data records nothing and keeps edge IDs
+	require.NoError(t, s.ReplaceVaultData(vid, nodes, edges, members))
+	v2, err := s.GetGraphVersion()
+	require.NoError(t, err)
+	assert.Equal(t, v1, v2)
+	after, err := s.GetAllEdges()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, before, after)
+
+	// Edit a and delete c, then reposition a
+	nodes[0].Title = "A, edited"
+	require.NoError(t, s.ReplaceVaultData(vid, nodes[:2], edges[:1], map[int][]string{gid: {"a", "b"}}))
+	require.NoError(t, s.UpsertPosition(gid, &models.NodePosition{NodeID: "a", X: 1, Y: 2}))
+	v3, err := s.GetGraphVersion()
+	require.NoError(t, err)
+
+	changes, err := s.GetGraphChanges(gid, v2, v3)
+	require.NoError(t, err)
+	assert.True(t, changes.Complete)
+	assert.False(t, changes.GraphChanged)
+	assert.ElementsMatch(t, []string{"a", "c"}, changes.NodeIDs)
+	assert.Equal(t, []string{"c"}, changes.MemberIDs)
+	assert.Equal(t, []string{"a"}, changes.PositionIDs)
+	require.Len(t, changes.Edges, 1)
+	assert.Equal(t, "c", changes.Edges[0].TargetID)
+
+	// Nothing after the latest version
+	changes, err = s.GetGraphChanges(gid, v3, v3)
+	require.NoError(t, err)
+	assert.Empty(t, changes.NodeIDs)
+
+	_, err = s.UpsertGraph(vid, "root", "", `{"filter": "tag:x"}`)
+	require.NoError(t, err)
+	v4, err := s.GetGraphVersion()
+	require.NoError(t, err)
+	changes, err = s.GetGraphChanges(gid, v3, v4)
+	require.NoError(t, err)
+	assert.True(t, changes.GraphChanged)
+}
+
+func TestReplaceVaultDataMovedFiles(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+	gid := createTestGraph(t, s, vid, "root", "")
+
+	require.NoError(t, s.ReplaceVaultData(vid, []models.VaultNode{
+		{ID: "a", Title: "A", FilePath: "x.md", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "b", Title: "B", FilePath: "y.md", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}, []models.VaultEdge{{SourceID: "a", TargetID: "b", EdgeType: "wikilink", Weight: 1}}, map[int][]string{gid: {"a", "b"}}))
+
+	// Swap the files' paths
+	require.NoError(t, s.ReplaceVaultData(vid, []models.VaultNode{
+		{ID: "a", Title: "A", FilePath: "y.md", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "b", Title: "B", FilePath: "x.md", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}, []models.VaultEdge{{SourceID: "a", TargetID: "b", EdgeType: "wikilink", Weight: 1}}, map[int][]string{gid: {"a", "b"}}))
+
+	graph, err := s.GetGraphData(gid)
+	require.NoError(t, err)
+	assert.Len(t, graph.Nodes, 2)
+	assert.Len(t, graph.Edges, 1)
+	a, err := s.GetNode("a")
+	require.NoError(t, err)
+	assert.Equal(t, "y.md", a.FilePath)
+}
+
+func TestReplaceVaultDataRejectsIDFromOtherVault(t *testing.T) {
+	s := newTestStore(t)
+	v1 := createTestVault(t, s, "v1", "/v1")
+	v2 := createTestVault(t, s, "v2", "/v2")
+	node := models.VaultNode{ID: "a", Title: "A", FilePath: "a.md", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+
+	require.NoError(t, s.ReplaceVaultData(v1, []models.VaultNode{node}, nil, nil))
+	assert.Error(t, s.ReplaceVaultData(v2, []models.VaultNode{node}, nil, nil))
+}
+
 // --- Graph membership tests ---
 
 func TestReplaceGraphMemberships(t *testing.T) {
@@ -662,6 +1009,26 @@ func TestReplaceGraphMemberships(t *testing.T) {
 	assert.Len(t, graph2.Nodes, 1)
 }
 
+func TestMissingGraphMembers(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+	g1 := createTestGraph(t, s, vid, "g1", "g1")
+	g2 := createTestGraph(t, s, vid, "g2", "g2")
+
+	require.NoError(t, s.UpsertNode(&models.VaultNode{ID: "a", VaultID: vid, Title: "A", FilePath: "a.md", CreatedAt: time.Now(), UpdatedAt: time.Now()}))
+	require.NoError(t, s.UpsertNode(&models.VaultNode{ID: "b", VaultID: vid, Title: "B", FilePath: "b.md", CreatedAt: time.Now(), UpdatedAt: time.Now()}))
+	require.NoError(t, s.ReplaceGraphMemberships("a", []int{g1}))
+	require.NoError(t, s.ReplaceGraphMemberships("b", []int{g2}))
+
+	missing, err := s.MissingGraphMembers(g1, []string{"nope", "a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"nope", "b"}, missing)
+
+	missing, err = s.MissingGraphMembers(g1, []string{"a"})
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
 func TestGraphNodeCountInList(t *testing.T) {
 	s := newTestStore(t)
 	vid := createTestVault(t, s, "v", "/v")
@@ -692,6 +1059,20 @@ func TestTagsRoundTrip(t *testing.T) {
 	assert.Equal(t, models.StringArray{"alpha", "beta", "gamma"}, got.Tags)
 }
 
+func TestReadingMetricsRoundTrip(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+	n := testNode(vid, "n1", "Test", "test.md")
+	n.WordCount = 450
+	n.ReadingTime = 3
+	require.NoError(t, s.UpsertNode(&n))
+
+	got, err := s.GetNode("n1")
+	require.NoError(t, err)
+	assert.Equal(t, 450, got.WordCount)
+	assert.Equal(t, 3, got.ReadingTime)
+}
+
 // --- Large batch ---
 
 func TestReplaceVaultDataLargeBatch(t *testing.T) {
@@ -779,6 +1160,64 @@ groups:
 	}
 }
 
+func TestGetMetadataKeys(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+
+	a := testNode(vid, "a", "A", "a.md")
+	a.Metadata = models.JSONMetadata{"status": "draft", "due": "2024-01-02", "reviews": 2}
+	b := testNode(vid, "b", "B", "b.md")
+	b.Metadata = models.JSONMetadata{"status": "done", "aliases": []interface{}{"x"}}
+	require.NoError(t, s.UpsertNode(&a))
+	require.NoError(t, s.UpsertNode(&b))
+
+	keys, err := s.GetMetadataKeys(nil)
+	require.NoError(t, err)
+	require.Len(t, keys, 4)
+
+	assert.Equal(t, "status", keys[0].Key)
+	assert.Equal(t, 2, keys[0].Count)
+	assert.Equal(t, models.MetadataString, keys[0].Type)
+
+	byKey := make(map[string]models.MetadataKey)
+	for _, k := range keys {
+		byKey[k.Key] = k
+	}
+	assert.Equal(t, models.MetadataDate, byKey["due"].Type)
+	assert.Equal(t, models.MetadataInteger, byKey["reviews"].Type)
+	assert.Equal(t, models.MetadataList, byKey["aliases"].Type)
+}
+
+func TestGetRecentPublicNodes(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i, meta := range []models.JSONMetadata{
+		{"public": true},
+		{"public": true, "private": true},
+		{"public": false},
+		nil,
+		{"public": "true"},
+	} {
+		n := testNode(vid, fmt.Sprintf("n%d", i), "N", fmt.Sprintf("n%d.md", i))
+		n.Metadata = meta
+		n.UpdatedAt = base.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, s.UpsertNode(&n))
+	}
+
+	nodes, err := s.GetRecentPublicNodes("public", 10)
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "n4", nodes[0].ID, "most recently updated first")
+	assert.Equal(t, "n0", nodes[1].ID)
+	assert.NotEmpty(t, nodes[0].Content)
+
+	nodes, err = s.GetRecentPublicNodes("public", 1)
+	require.NoError(t, err)
+	assert.Len(t, nodes, 1)
+}
+
 func TestGetGraphDataRawNotFound(t *testing.T) {
 	s := newTestStore(t)
 	_, err := s.GetGraphDataRaw(999)
@@ -821,3 +1260,437 @@ func TestFileStorePersists(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "n1", got.ID)
 }
+
+func TestNodeACLs(t *testing.T) {
+	s := newTestStore(t)
+
+	require.NoError(t, s.SetNodeACL("a", []string{"alice", "editors"}))
+	require.NoError(t, s.SetNodeACL("b", []string{"bob"}))
+	require.NoError(t, s.SetNodeACL("b", []string{"carol"}))
+
+	acls, err := s.GetNodeACLs()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"a": {"alice", "editors"},
+		"b": {"carol"},
+	}, acls)
+
+	require.NoError(t, s.SetNodeACL("a", nil))
+	acls, err = s.GetNodeACLs()
+	require.NoError(t, err)
+	assert.NotContains(t, acls, "a")
+}
+
+func TestShares(t *testing.T) {
+	s := newTestStore(t)
+
+	share := &models.Share{
+		Token:     "tok",
+		Title:     "Slice",
+		GraphID:   3,
+		Graph:     models.Graph{Nodes: []models.Node{{ID: "a", Title: "A"}}, Edges: []models.Edge{}},
+		CreatedBy: "alice",
+		CreatedAt: time.Now().Truncate(time.Second),
+	}
+	require.NoError(t, s.CreateShare(share))
+
+	got, err := s.GetShare("tok")
+	require.NoError(t, err)
+	assert.Equal(t, "Slice", got.Title)
+	assert.Equal(t, 3, got.GraphID)
+	assert.Equal(t, "alice", got.CreatedBy)
+	assert.True(t, share.CreatedAt.Equal(got.CreatedAt))
+	require.Len(t, got.Graph.Nodes, 1)
+	assert.Equal(t, "A", got.Graph.Nodes[0].Title)
+
+	require.NoError(t, s.DeleteShare("tok"))
+	_, err = s.GetShare("tok")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestParseHistory(t *testing.T) {
+	s := newTestStore(t)
+
+	started := time.Now().Add(-time.Minute)
+	h := &models.ParseHistory{ID: "run-1", VaultID: 1, StartedAt: started, Status: models.ParseStatusRunning}
+	require.NoError(t, s.SaveParseHistory(h))
+
+	done := time.Now()
+	h.Status = models.ParseStatusCompleted
+	h.CompletedAt = &done
+	h.Stats = models.JSONStats{TotalFiles: 3, PhaseDurations: map[models.ParsePhase]int64{models.ParsePhaseParse: 12}}
+	require.NoError(t, s.SaveParseHistory(h))
+
+	msg := "boom"
+	require.NoError(t, s.SaveParseHistory(&models.ParseHistory{
+		ID: "run-2", VaultID: 2, StartedAt: time.Now(), Status: models.ParseStatusFailed, Error: &msg,
+	}))
+
+	all, err := s.GetParseHistory(0, "", 10)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, "run-2", all[0].ID, "newest first")
+	require.NotNil(t, all[0].Error)
+	assert.Equal(t, "boom", *all[0].Error)
+
+	completed, err := s.GetParseHistory(1, models.ParseStatusCompleted, 10)
+	require.NoError(t, err)
+	require.Len(t, completed, 1)
+	assert.Equal(t, 3, completed[0].Stats.TotalFiles)
+	assert.Equal(t, int64(12), completed[0].Stats.PhaseDurations[models.ParsePhaseParse])
+	require.NotNil(t, completed[0].CompletedAt)
+	assert.True(t, started.Equal(completed[0].StartedAt))
+}
+
+func TestParseErrors(t *testing.T) {
+	s := newTestStore(t)
+
+	_, err := s.GetParseErrors("run-1", "")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, s.SaveParseHistory(&models.ParseHistory{ID: "run-1", VaultID: 1, StartedAt: time.Now(), Status: models.ParseStatusRunning}))
+	errs, err := s.GetParseErrors("run-1", "")
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+
+	require.NoError(t, s.SaveParseErrors("run-1", []models.ParseFileError{
+		{FilePath: "z.md", Kind: "frontmatter", Line: 3, Message: "bad yaml"},
+		{FilePath: "a.md", Kind: "encoding", Line: 1, Message: "not UTF-8"},
+		{FilePath: "a.md", Kind: "hook", Message: "rejected"},
+	}))
+	errs, err = s.GetParseErrors("run-1", "")
+	require.NoError(t, err)
+	require.Len(t, errs, 3)
+	assert.Equal(t, models.ParseFileError{FilePath: "a.md", Kind: "encoding", Line: 1, Message: "not UTF-8"}, errs[0])
+	assert.Equal(t, "hook", errs[1].Kind)
+	assert.Zero(t, errs[1].Line)
+	assert.Equal(t, "z.md", errs[2].FilePath)
+
+	errs, err = s.GetParseErrors("run-1", "frontmatter")
+	require.NoError(t, err)
+	require.Len(t, errs, 1)
+	assert.Equal(t, 3, errs[0].Line)
+
+	// Saving again replaces the errors
+	require.NoError(t, s.SaveParseErrors("run-1", nil))
+	errs, err = s.GetParseErrors("run-1", "")
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+}
+
+func TestParseLogs(t *testing.T) {
+	s := newTestStore(t)
+
+	_, err := s.GetParseLogs("run-1", "")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, s.SaveParseHistory(&models.ParseHistory{ID: "run-1", VaultID: 1, StartedAt: time.Now(), Status: models.ParseStatusRunning}))
+	logs, err := s.GetParseLogs("run-1", "")
+	require.NoError(t, err)
+	assert.Empty(t, logs)
+
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.SaveParseLogs("run-1", []models.ParseLogRecord{
+		{Time: at, Level: "info", Message: "Starting full index of /v"},
+		{Time: at, Level: "warning", Message: "Warning: failed to save file cache"},
+		{Time: at.Add(time.Second), Level: "info", Message: "Full index of /v completed"},
+	}))
+	logs, err = s.GetParseLogs("run-1", "")
+	require.NoError(t, err)
+	require.Len(t, logs, 3)
+	assert.Equal(t, "Starting full index of /v", logs[0].Message)
+	assert.True(t, at.Equal(logs[0].Time))
+	assert.Equal(t, "Full index of /v completed", logs[2].Message)
+
+	logs, err = s.GetParseLogs("run-1", "warning")
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "Warning: failed to save file cache", logs[0].Message)
+
+	// Saving again replaces the logs
+	require.NoError(t, s.SaveParseLogs("run-1", nil))
+	logs, err = s.GetParseLogs("run-1", "")
+	require.NoError(t, err)
+	assert.Empty(t, logs)
+}
+
+func TestFileCache(t *testing.T) {
+	s := newTestStore(t)
+
+	require.NoError(t, s.ReplaceFileCache(1, []models.CachedFile{
+		{Path: "a.md", Hash: "h1", Data: "{}"},
+		{Path: "b.md", Hash: "h2", Data: "{}"},
+	}))
+	require.NoError(t, s.ReplaceFileCache(2, []models.CachedFile{{Path: "a.md", Hash: "other", Data: "{}"}}))
+	require.NoError(t, s.UpsertCachedFile(1, models.CachedFile{Path: "b.md", Hash: "h3", Data: `{"title":"B"}`}))
+
+	cache, err := s.GetFileCache(1)
+	require.NoError(t, err)
+	require.Len(t, cache, 2)
+	assert.Equal(t, "h1", cache["a.md"].Hash)
+	assert.Equal(t, "h3", cache["b.md"].Hash)
+	assert.Equal(t, `{"title":"B"}`, cache["b.md"].Data)
+
+	// Replacing drops entries for files that no longer exist
+	require.NoError(t, s.ReplaceFileCache(1, []models.CachedFile{{Path: "a.md", Hash: "h1", Data: "{}"}}))
+	cache, err = s.GetFileCache(1)
+	require.NoError(t, err)
+	assert.Len(t, cache, 1)
+	other, err := s.GetFileCache(2)
+	require.NoError(t, err)
+	assert.Equal(t, "other", other["a.md"].Hash)
+}
+
+func TestRenameNodes(t *testing.T) {
+	s := newTestStore(t)
+	vaultID, err := s.UpsertVault("v", "/v")
+	require.NoError(t, err)
+	g1, err := s.UpsertGraph(vaultID, "one", "one", "")
+	require.NoError(t, err)
+	g2, err := s.UpsertGraph(vaultID, "two", "two", "")
+	require.NoError(t, err)
+
+	require.NoError(t, s.UpsertPosition(g1, &models.NodePosition{NodeID: "old", X: 1}))
+	require.NoError(t, s.UpsertPosition(g2, &models.NodePosition{NodeID: "old", X: 2}))
+	require.NoError(t, s.UpsertPosition(g2, &models.NodePosition{NodeID: "new", X: 3}))
+	require.NoError(t, s.SetNodeACL("old", []string{"alice"}))
+	require.NoError(t, s.AddComment(&models.Comment{NodeID: "new", Body: "existing", CreatedAt: time.Now()}))
+	require.NoError(t, s.AddComment(&models.Comment{NodeID: "old", Body: "moved", CreatedAt: time.Now()}))
+	require.NoError(t, s.AddBookmark("alice", "old"))
+	require.NoError(t, s.AddBookmark("alice", "new"))
+	require.NoError(t, s.AddBookmark("bob", "old"))
+
+	graphIDs, err := s.RenameNodes(map[string]string{"old": "new"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int{g1, g2}, graphIDs)
+
+	p1, err := s.GetPositionsByGraph(g1)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, p1["new"].X)
+	p2, err := s.GetPositionsByGraph(g2)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, p2["new"].X, "moved position replaces the new ID's")
+	assert.NotContains(t, p2, "old")
+
+	acls, err := s.GetNodeACLs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, acls["new"])
+	assert.NotContains(t, acls, "old")
+
+	comments, err := s.GetComments("new")
+	require.NoError(t, err)
+	require.Len(t, comments, 2, "comments merge rather than replace")
+	assert.Equal(t, "existing", comments[0].Body)
+	assert.Equal(t, "moved", comments[1].Body)
+
+	// Bookmarks are per node ID, so the nodes must exist to be listed
+	require.NoError(t, s.UpsertNode(&models.VaultNode{ID: "new", VaultID: vaultID, Title: "New", FilePath: "new.md", CreatedAt: time.Now(), UpdatedAt: time.Now()}))
+	for _, user := range []string{"alice", "bob"} {
+		bookmarks, err := s.GetBookmarks(user)
+		require.NoError(t, err)
+		require.Len(t, bookmarks, 1, user)
+		assert.Equal(t, "new", bookmarks[0].NodeID)
+		assert.Equal(t, "New", bookmarks[0].Title)
+	}
+}
+
+func TestRemapPositionsSwap(t *testing.T) {
+	s := newTestStore(t)
+	vaultID, err := s.UpsertVault("v", "/v")
+	require.NoError(t, err)
+	g, err := s.UpsertGraph(vaultID, "g", "", "")
+	require.NoError(t, err)
+	require.NoError(t, s.UpsertPosition(g, &models.NodePosition{NodeID: "a", X: 1}))
+	require.NoError(t, s.UpsertPosition(g, &models.NodePosition{NodeID: "b", X: 2}))
+
+	graphIDs, moved, err := s.RemapPositions(map[string]string{"a": "b", "b": "a", "missing": "c"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{g}, graphIDs)
+	assert.Equal(t, 2, moved)
+
+	positions, err := s.GetPositionsByGraph(g)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, positions["a"].X)
+	assert.Equal(t, 1.0, positions["b"].X)
+	assert.NotContains(t, positions, "c")
+}
+
+func TestNodeTimestampsMigrateToUTC(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, err := New(path)
+	require.NoError(t, err)
+	vid := createTestVault(t, s, "v", "/v")
+	_, err = s.db.Exec(`
+		INSERT INTO nodes (id, vault_id, file_path, title, created_at, updated_at)
+		VALUES ('n', ?, 'n.md', 'N', '2026-01-01T10:00:00+02:00', '2026-01-02T23:30:00-05:00')
+	`, vid)
+	require.NoError(t, err)
+	// As if created before the timestamps migration, and so before later ones
+	_, err = s.db.Exec(`DROP TRIGGER nodes_changelog_update; ALTER TABLE nodes DROP COLUMN excerpt; ALTER TABLE nodes DROP COLUMN centrality; ALTER TABLE nodes DROP COLUMN level; ALTER TABLE parse_history DROP COLUMN report; ALTER TABLE node_positions DROP COLUMN absent_parses; PRAGMA user_version = 6`)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = New(path)
+	require.NoError(t, err)
+	defer s.Close()
+	var createdAt, updatedAt string
+	require.NoError(t, s.db.QueryRow(`SELECT created_at, updated_at FROM nodes WHERE id = 'n'`).Scan(&createdAt, &updatedAt))
+	assert.Equal(t, "2026-01-01T08:00:00Z", createdAt)
+	assert.Equal(t, "2026-01-03T04:30:00Z", updatedAt)
+}
+
+// --- Maintenance tests ---
+
+func TestClearFileCaches(t *testing.T) {
+	s := newTestStore(t)
+	require.NoError(t, s.ReplaceFileCache(1, []models.CachedFile{{Path: "a.md", Hash: "h1", Data: "{}"}}))
+	require.NoError(t, s.ReplaceFileCache(2, []models.CachedFile{{Path: "b.md", Hash: "h2", Data: "{}"}}))
+
+	n, err := s.ClearFileCaches()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	cache, err := s.GetFileCache(1)
+	require.NoError(t, err)
+	assert.Empty(t, cache)
+}
+
+func TestRecomputeMetrics(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+	nodes := []models.VaultNode{testNode(vid, "a", "A", "a.md"), testNode(vid, "b", "B", "b.md"), testNode(vid, "c", "C", "c.md")}
+	nodes[0].OutDegree = 2
+	nodes[1].InDegree = 1
+	nodes[2].InDegree = 5 // Wrong
+	require.NoError(t, s.ReplaceVaultData(vid, nodes, []models.VaultEdge{testEdge("a", "b"), testEdge("a", "c")}, nil))
+
+	// Every centrality starts at 0, so every node changes
+	n, err := s.RecomputeMetrics(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	c, err := s.GetNode("c")
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.InDegree)
+	assert.Equal(t, 0, c.OutDegree)
+	assert.InDelta(t, 1, c.Centrality, 1e-9)
+	a, err := s.GetNode("a")
+	require.NoError(t, err)
+	assert.Greater(t, a.Centrality, 0.0)
+	assert.Less(t, a.Centrality, c.Centrality)
+
+	n, err = s.RecomputeMetrics(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestPageRank(t *testing.T) {
+	// A hub linked from every leaf outranks the leaves
+	ranks := pageRank([]string{"hub", "x", "y", "z"}, [][2]string{{"x", "hub"}, {"y", "hub"}, {"z", "hub"}, {"hub", "x"}, {"x", "gone"}})
+	assert.InDelta(t, 1, ranks["hub"], 1e-9)
+	assert.Greater(t, ranks["x"], ranks["y"])
+	assert.InDelta(t, ranks["y"], ranks["z"], 1e-9)
+	assert.NotContains(t, ranks, "gone")
+
+	assert.Empty(t, pageRank(nil, nil))
+}
+
+func TestOptimize(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer s.Close()
+	vid := createTestVault(t, s, "v", "/v")
+	require.NoError(t, s.ReplaceVaultData(vid, []models.VaultNode{testNode(vid, "a", "A", "a.md")}, nil, nil))
+
+	require.NoError(t, s.Optimize(context.Background()))
+	node, err := s.GetNode("a")
+	require.NoError(t, err)
+	assert.Equal(t, "A", node.Title)
+}
+
+func TestStorageUsage(t *testing.T) {
+	s := newTestStore(t)
+	vid := createTestVault(t, s, "v", "/v")
+	nodes := []models.VaultNode{testNode(vid, "a", "A", "a.md"), testNode(vid, "b", "B", "b.md")}
+	require.NoError(t, s.ReplaceVaultData(vid, nodes, []models.VaultEdge{testEdge("a", "b")}, nil))
+
+	u, err := s.StorageUsage(context.Background())
+	require.NoError(t, err)
+	assert.Positive(t, u.PageSize)
+	assert.GreaterOrEqual(t, u.FileBytes, u.FreeBytes)
+
+	tables := make(map[string]TableSize)
+	var total int64
+	for _, tbl := range u.Tables {
+		tables[tbl.Name] = tbl
+		total += tbl.Bytes
+	}
+	assert.Equal(t, int64(2), tables["nodes"].Rows)
+	assert.Equal(t, int64(1), tables["edges"].Rows)
+	assert.Positive(t, tables["nodes"].Bytes)
+	assert.LessOrEqual(t, total, u.FileBytes)
+
+	var edgeIndexes int64
+	for _, idx := range u.Indexes {
+		if idx.Table == "edges" {
+			edgeIndexes += idx.Bytes
+		}
+	}
+	assert.Positive(t, edgeIndexes)
+	assert.Equal(t, edgeIndexes, tables["edges"].IndexBytes)
+}
+
+func TestForEachNodeAndEdge(t *testing.T) {
+	s := newTestStore(t)
+	vid, err := s.UpsertVault("v", "/v")
+	require.NoError(t, err)
+	require.NoError(t, s.ReplaceVaultData(vid, []models.VaultNode{
+		{ID: "b", VaultID: vid, Title: "B", FilePath: "b.md", Content: "body"},
+		{ID: "a", VaultID: vid, Title: "A", FilePath: "a.md", NodeType: "hub"},
+		{ID: "c", VaultID: vid, Title: "C", FilePath: "c.md"},
+	}, []models.VaultEdge{
+		{ID: "e2", SourceID: "a", TargetID: "c", EdgeType: "wikilink", Weight: 1},
+		{ID: "e1", SourceID: "a", TargetID: "b", EdgeType: "embed", DisplayText: "Bee", Weight: 2},
+	}, nil))
+
+	var ids []string
+	require.NoError(t, s.ForEachNode(context.Background(), func(n models.VaultNode) error {
+		ids = append(ids, n.ID)
+		assert.Empty(t, n.Content, "content is not loaded")
+		return nil
+	}))
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+
+	var edges []models.VaultEdge
+	require.NoError(t, s.ForEachEdge(context.Background(), func(e models.VaultEdge) error {
+		edges = append(edges, e)
+		return nil
+	}))
+	require.Len(t, edges, 2)
+	assert.Equal(t, models.VaultEdge{ID: "e1", SourceID: "a", TargetID: "b", EdgeType: "embed", DisplayText: "Bee", Weight: 2}, edges[0])
+
+	// The first error stops the walk
+	stop := errors.New("stop")
+	ids = nil
+	err = s.ForEachNode(context.Background(), func(n models.VaultNode) error {
+		ids = append(ids, n.ID)
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, []string{"a"}, ids)
+
+	// Across pages, with the store free to use in between
+	defer func(n int) { forEachPageSize = n }(forEachPageSize)
+	forEachPageSize = 2
+	ids = nil
+	require.NoError(t, s.ForEachNode(context.Background(), func(n models.VaultNode) error {
+		ids = append(ids, n.ID)
+		_, err := s.GetNode(n.ID)
+		return err
+	}))
+	assert.Equal(t, []string{"a", "b", "c"}, ids)
+	edges = nil
+	require.NoError(t, s.ForEachEdge(context.Background(), func(e models.VaultEdge) error {
+		edges = append(edges, e)
+		return nil
+	}))
+	assert.Len(t, edges, 2)
+}
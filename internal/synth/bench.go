@@ -0,0 +1,112 @@
+package synth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/ali01/mnemosyne/internal/indexer"
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/store"
+)
+
+// PhaseResult is the cost of one phase of a full index. Allocations are the
+// whole process's, so benchmarks should run with nothing else going on.
+type PhaseResult struct {
+	Phase      models.ParsePhase `json:"phase"` // "total" for the whole index
+	DurationMS float64           `json:"duration_ms"`
+	AllocBytes uint64            `json:"alloc_bytes"`
+	Allocs     uint64            `json:"allocs"`
+}
+
+// BenchResult measures a full index of one generated vault.
+type BenchResult struct {
+	Options        Options       `json:"options"`
+	Generated      Stats         `json:"generated"`
+	Nodes          int           `json:"nodes"`
+	Edges          int           `json:"edges"`
+	Phases         []PhaseResult `json:"phases"` // In execution order
+	Total          PhaseResult   `json:"total"`
+	FilesPerSecond float64       `json:"files_per_second"`
+}
+
+// phaseMeter records time and allocations at phase boundaries.
+type phaseMeter struct {
+	phases  []PhaseResult
+	current models.ParsePhase
+	start   time.Time
+	mem     runtime.MemStats
+}
+
+func (pm *phaseMeter) mark(phase models.ParsePhase) {
+	now := time.Now()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	if pm.current != "" {
+		pm.phases = append(pm.phases, PhaseResult{
+			Phase:      pm.current,
+			DurationMS: float64(now.Sub(pm.start).Microseconds()) / 1000,
+			AllocBytes: mem.TotalAlloc - pm.mem.TotalAlloc,
+			Allocs:     mem.Mallocs - pm.mem.Mallocs,
+		})
+	}
+	pm.current, pm.start, pm.mem = phase, now, mem
+}
+
+// Benchmark generates a vault with opts in a temporary directory, indexes it
+// into a new database there, and measures each phase of the full index. The
+// directory is removed afterwards.
+func Benchmark(ctx context.Context, opts Options) (*BenchResult, error) {
+	dir, err := os.MkdirTemp("", "mnemosyne-bench-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	vaultPath := filepath.Join(dir, "vault")
+	generated, err := Generate(vaultPath, opts)
+	if err != nil {
+		return nil, fmt.Errorf("generate vault: %w", err)
+	}
+	s, err := store.New(filepath.Join(dir, "bench.db"))
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	m := indexer.NewIndexManager(s)
+	meter := &phaseMeter{}
+	m.SetOnPhase(meter.mark)
+	vaultID, _, err := m.RegisterVault(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start from a collected heap so earlier sizes do not skew this one
+	runtime.GC()
+	if err := m.FullIndexVaultContext(ctx, vaultID); err != nil {
+		return nil, fmt.Errorf("index vault: %w", err)
+	}
+
+	res := &BenchResult{Options: opts, Generated: *generated, Phases: meter.phases, Total: PhaseResult{Phase: "total"}}
+	for _, p := range meter.phases {
+		res.Total.DurationMS += p.DurationMS
+		res.Total.AllocBytes += p.AllocBytes
+		res.Total.Allocs += p.Allocs
+	}
+	if res.Total.DurationMS > 0 {
+		res.FilesPerSecond = float64(opts.Notes) / (res.Total.DurationMS / 1000)
+	}
+	history, err := s.GetParseHistory(vaultID, models.ParseStatusCompleted, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) > 0 {
+		res.Nodes = history[0].Stats.TotalNodes
+		res.Edges = history[0].Stats.TotalEdges
+	}
+	return res, nil
+}
@@ -0,0 +1,288 @@
+// Package synth generates synthetic vaults: notes with frontmatter, tags and
+// wikilinks shaped like a real knowledge base, for demos and benchmarks.
+// Generation is deterministic for a given Options.
+package synth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Options shape a generated vault.
+type Options struct {
+	Notes       int     `json:"notes"`
+	HubRatio    float64 `json:"hub_ratio"`     // Fraction of notes that are hubs: topic notes most links point to
+	Tags        int     `json:"tags"`          // Distinct tags; a few are common and most are rare
+	TagsPerNote int     `json:"tags_per_note"` // Maximum tags on a note
+	Words       int     `json:"words"`         // Approximate words of prose per note
+	Seed        uint64  `json:"seed"`          // Same seed, same vault
+}
+
+// DefaultOptions returns options for a vault of n notes.
+func DefaultOptions(n int) Options {
+	return Options{
+		Notes:       n,
+		HubRatio:    0.05,
+		Tags:        40,
+		TagsPerNote: 3,
+		Words:       150,
+		Seed:        1,
+	}
+}
+
+// Validate reports options that cannot generate a vault.
+func (o Options) Validate() error {
+	switch {
+	case o.Notes < 1:
+		return errors.New("notes must be at least 1")
+	case o.HubRatio < 0 || o.HubRatio > 1:
+		return errors.New("hub ratio must be between 0 and 1")
+	case o.Tags < 0 || o.TagsPerNote < 0:
+		return errors.New("tags must not be negative")
+	case o.Words < 0:
+		return errors.New("words must not be negative")
+	}
+	return nil
+}
+
+// Stats describes a generated vault.
+type Stats struct {
+	Notes int `json:"notes"`
+	Hubs  int `json:"hubs"`
+	Links int `json:"links"` // Distinct wikilinks, i.e. the edges the vault indexes to
+	Tags  int `json:"tags"`  // Distinct tags used
+}
+
+// Generate writes a vault to dir, which must be empty or not exist: a
+// GRAPH.yaml coloring the most common tags, hub notes in topics/, and leaf
+// notes in one folder per hub they belong to, under notes/.
+func Generate(dir string, o Options) (*Stats, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s is not empty", dir)
+	}
+
+	g := newGenerator(o)
+	stats := &Stats{Notes: o.Notes, Hubs: g.hubs}
+	usedTags := make(map[string]bool)
+
+	if err := writeFile(filepath.Join(dir, "GRAPH.yaml"), g.graphConfig()); err != nil {
+		return nil, err
+	}
+	for i := 0; i < o.Notes; i++ {
+		r := g.rand(i)
+		n := g.note(i, r)
+		stats.Links += len(n.links)
+		for _, t := range n.tags {
+			usedTags[t] = true
+		}
+		if err := writeFile(filepath.Join(dir, filepath.FromSlash(n.path)), g.render(n, r)); err != nil {
+			return nil, err
+		}
+	}
+	stats.Tags = len(usedTags)
+	return stats, nil
+}
+
+func writeFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	w.WriteString(content)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// words is the vocabulary of titles and prose.
+var words = strings.Fields(`
+	memory graph network signal model theory system pattern language history
+	market design energy climate protein circuit orbit ocean forest city
+	learning practice habit attention writing reading research method proof
+	logic number structure process change growth risk trade policy culture
+	music film image color light sound motion space time field wave
+	particle cell gene brain mind body health food water soil river
+	tool craft code data software hardware interface protocol archive index
+`)
+
+type note struct {
+	id      string
+	title   string
+	path    string
+	hub     bool
+	tags    []string
+	links   []int // Note indexes linked to, distinct
+	created time.Time
+}
+
+type generator struct {
+	o      Options
+	hubs   int
+	tags   []string
+	titles []string
+	slugs  []string // File names without .md, which links use
+	epoch  time.Time
+}
+
+func newGenerator(o Options) *generator {
+	hubs := int(math.Round(float64(o.Notes) * o.HubRatio))
+	if hubs == 0 && o.HubRatio > 0 {
+		hubs = 1
+	}
+	g := &generator{o: o, hubs: hubs, epoch: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	// Stream 0 is for the vault as a whole, stream i+1 for note i
+	r := rand.New(rand.NewPCG(o.Seed, 0))
+	for i := 0; i < o.Tags; i++ {
+		g.tags = append(g.tags, fmt.Sprintf("%s-%d", words[r.IntN(len(words))], i))
+	}
+	// Titles are needed to render links, so they are drawn up front
+	g.titles = make([]string, o.Notes)
+	g.slugs = make([]string, o.Notes)
+	for i := range g.titles {
+		g.titles[i] = title(r, i)
+		g.slugs[i] = strings.ToLower(strings.ReplaceAll(g.titles[i], " ", "-"))
+	}
+	return g
+}
+
+// rand returns the random source for one note, so notes can be generated in
+// any order and a vault's first notes do not depend on its size.
+func (g *generator) rand(i int) *rand.Rand {
+	return rand.New(rand.NewPCG(g.o.Seed, uint64(i)+1))
+}
+
+// title returns the i-th note's title: two to four words, numbered so
+// titles are unique.
+func title(r *rand.Rand, i int) string {
+	n := 2 + r.IntN(3)
+	parts := make([]string, n)
+	for j := range parts {
+		w := words[r.IntN(len(words))]
+		parts[j] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return fmt.Sprintf("%s %d", strings.Join(parts, " "), i+1)
+}
+
+// zipf picks from n items, favoring the first: item k is about 1/(k+1) as
+// likely as item 0.
+func zipf(r *rand.Rand, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	x := math.Exp(r.Float64() * math.Log(float64(n)+1))
+	return min(int(x)-1, n-1)
+}
+
+func (g *generator) note(i int, r *rand.Rand) note {
+	n := note{
+		id:      fmt.Sprintf("n%06d", i+1),
+		title:   g.titles[i],
+		hub:     i < g.hubs,
+		created: g.epoch.Add(time.Duration(i) * 6 * time.Hour),
+	}
+	slug := g.slugs[i]
+	if n.hub {
+		n.path = "topics/" + slug + ".md"
+	} else if g.hubs > 0 {
+		n.path = fmt.Sprintf("notes/%s/%s.md", strings.ToLower(strings.Fields(g.titles[i%g.hubs])[0]), slug)
+	} else {
+		n.path = "notes/" + slug + ".md"
+	}
+
+	if len(g.tags) > 0 && g.o.TagsPerNote > 0 {
+		seen := make(map[string]bool)
+		for k := r.IntN(g.o.TagsPerNote) + 1; k > 0; k-- {
+			t := g.tags[zipf(r, len(g.tags))]
+			if !seen[t] {
+				seen[t] = true
+				n.tags = append(n.tags, t)
+			}
+		}
+	}
+
+	// Leaves link to their hub and a few others, mostly popular hubs; hubs
+	// link to related hubs. A few links go to any note.
+	linked := map[int]bool{i: true}
+	add := func(j int) {
+		if !linked[j] {
+			linked[j] = true
+			n.links = append(n.links, j)
+		}
+	}
+	if !n.hub && g.hubs > 0 {
+		add(i % g.hubs)
+	}
+	count := 1 + r.IntN(3)
+	if n.hub {
+		count = 2 + r.IntN(4)
+	}
+	for k := 0; k < count; k++ {
+		if g.hubs > 0 && r.Float64() < 0.7 {
+			add(zipf(r, g.hubs))
+		} else {
+			add(r.IntN(g.o.Notes))
+		}
+	}
+	return n
+}
+
+// render returns a note's markdown: frontmatter, then prose with the links
+// spread through it.
+func (g *generator) render(n note, r *rand.Rand) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "---\nid: %s\ncreated: %s\n", n.id, n.created.Format("2006-01-02"))
+	if n.hub {
+		b.WriteString("type: hub\n")
+	}
+	if len(n.tags) > 0 {
+		fmt.Fprintf(&b, "tags: [%s]\n", strings.Join(n.tags, ", "))
+	}
+	fmt.Fprintf(&b, "---\n# %s\n\n", n.title)
+
+	next := 0 // Next link to write
+	for w := 0; w < g.o.Words; w++ {
+		if next < len(n.links) && w >= next*g.o.Words/len(n.links) {
+			fmt.Fprintf(&b, "[[%s]] ", g.slugs[n.links[next]])
+			next++
+		}
+		b.WriteString(words[r.IntN(len(words))])
+		if (w+1)%15 == 0 {
+			b.WriteString(".\n")
+		} else {
+			b.WriteString(" ")
+		}
+	}
+	for ; next < len(n.links); next++ {
+		fmt.Fprintf(&b, "\n[[%s]]", g.slugs[n.links[next]])
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// graphConfig colors the most common tags.
+func (g *generator) graphConfig() string {
+	palette := []string{"#e6194b", "#3cb44b", "#4363d8", "#f58231", "#911eb4"}
+	var b strings.Builder
+	b.WriteString("name: demo\ngroups:\n")
+	b.WriteString("  - query: \"path:topics\"\n    color: \"#ffe119\"\n")
+	for i := 0; i < len(g.tags) && i < len(palette); i++ {
+		fmt.Fprintf(&b, "  - query: \"tag:#%s\"\n    color: %q\n", g.tags[i], palette[i])
+	}
+	return b.String()
+}
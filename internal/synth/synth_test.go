@@ -0,0 +1,99 @@
+package synth_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/indexer"
+	"github.com/ali01/mnemosyne/internal/mnemosynetest"
+	"github.com/ali01/mnemosyne/internal/models"
+	"github.com/ali01/mnemosyne/internal/synth"
+)
+
+func TestGenerateIndexes(t *testing.T) {
+	dir := t.TempDir()
+	stats, err := synth.Generate(dir, synth.DefaultOptions(300))
+	require.NoError(t, err)
+	assert.Equal(t, 300, stats.Notes)
+	assert.Equal(t, 15, stats.Hubs)
+	assert.Positive(t, stats.Tags)
+
+	s := mnemosynetest.NewStore(t)
+	m := indexer.NewIndexManager(s)
+	vaultID, graphIDs, err := m.RegisterVault(dir)
+	require.NoError(t, err)
+	require.NoError(t, m.FullIndexVault(vaultID))
+
+	g, err := s.GetGraphData(graphIDs[0])
+	require.NoError(t, err)
+	assert.Len(t, g.Nodes, stats.Notes)
+	assert.Len(t, g.Edges, stats.Links)
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	read := func(dir string) map[string]string {
+		files := make(map[string]string)
+		filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			require.NoError(t, err)
+			if !d.IsDir() {
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+				rel, _ := filepath.Rel(dir, path)
+				files[rel] = string(data)
+			}
+			return nil
+		})
+		return files
+	}
+
+	a, b := t.TempDir(), t.TempDir()
+	_, err := synth.Generate(a, synth.DefaultOptions(50))
+	require.NoError(t, err)
+	_, err = synth.Generate(b, synth.DefaultOptions(50))
+	require.NoError(t, err)
+	assert.Equal(t, read(a), read(b))
+
+	opts := synth.DefaultOptions(50)
+	opts.Seed = 2
+	c := t.TempDir()
+	_, err = synth.Generate(c, opts)
+	require.NoError(t, err)
+	assert.NotEqual(t, read(a), read(c))
+}
+
+func TestGenerateValidates(t *testing.T) {
+	opts := synth.DefaultOptions(0)
+	_, err := synth.Generate(t.TempDir(), opts)
+	assert.Error(t, err)
+
+	opts = synth.DefaultOptions(10)
+	opts.HubRatio = 2
+	_, err = synth.Generate(t.TempDir(), opts)
+	assert.Error(t, err)
+
+	// Existing files are never overwritten
+	dir := mnemosynetest.NewVault(t, map[string]string{"mine.md": "# Mine\n"})
+	_, err = synth.Generate(dir, synth.DefaultOptions(10))
+	assert.ErrorContains(t, err, "not empty")
+}
+
+func TestBenchmark(t *testing.T) {
+	res, err := synth.Benchmark(context.Background(), synth.DefaultOptions(200))
+	require.NoError(t, err)
+	assert.Equal(t, 200, res.Nodes)
+	assert.Equal(t, res.Generated.Links, res.Edges)
+
+	var phases []models.ParsePhase
+	for _, p := range res.Phases {
+		phases = append(phases, p.Phase)
+	}
+	assert.Equal(t, models.ParsePhases, phases)
+	assert.Positive(t, res.Total.DurationMS)
+	assert.Positive(t, res.Total.Allocs)
+	assert.Positive(t, res.FilesPerSecond)
+}
@@ -0,0 +1,256 @@
+package vault
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CitationLinkType is the LinkType of pandoc citations and the edges they
+// produce. Citation targets are citekeys, resolved against the vault's
+// BibTeX entries rather than its files.
+const CitationLinkType = "citation"
+
+// ReferenceNodeType is the NodeType of nodes created from BibTeX entries.
+const ReferenceNodeType = "reference"
+
+// Reference is an entry of a BibTeX file in the vault.
+type Reference struct {
+	Key     string            // Citekey
+	Type    string            // Entry type, lowercased: "article", "book", ...
+	Fields  map[string]string // Lowercased field name -> value without braces or quotes
+	Raw     string            // The entry as written
+	Path    string            // Relative path of the .bib file
+	ModTime time.Time         // Modification time of the .bib file
+}
+
+// ReferenceID returns the node ID of the reference with citekey key.
+func ReferenceID(key string) string {
+	return "@" + key
+}
+
+// Title returns the entry's title, or its citekey if it has none.
+func (r *Reference) Title() string {
+	if t := r.Fields["title"]; t != "" {
+		return t
+	}
+	return r.Key
+}
+
+var (
+	// Matches a bracketed pandoc citation group, e.g. [see @doe99, p. 3; @roe]
+	citationGroupRegex = regexp.MustCompile(`\[([^\[\]]*@[^\[\]]*)\]`)
+
+	// Matches one citekey in a group: after the start, whitespace or ';', an
+	// optional '-' (suppress author), '@', and the key, optionally in braces
+	citekeyRegex = regexp.MustCompile(`(?:^|[\s;])-?@(?:\{([^{}]+)\}|([\p{L}\p{N}_][\p{L}\p{N}_:.#$%&\-+?<>~/]*))`)
+)
+
+// ExtractCitations returns a citation link for each citekey in the pandoc
+// citations of content, e.g. [@doe99] or [see @doe99, pp. 33-35; -@roe].
+// Keys lose trailing punctuation, as in pandoc.
+func ExtractCitations(content string) []WikiLink {
+	var links []WikiLink
+	for _, group := range citationGroupRegex.FindAllStringSubmatchIndex(content, -1) {
+		inner := content[group[2]:group[3]]
+		for _, m := range citekeyRegex.FindAllStringSubmatchIndex(inner, -1) {
+			var key string
+			if m[2] >= 0 {
+				key = strings.TrimSpace(inner[m[2]:m[3]])
+			} else {
+				key = strings.TrimRight(inner[m[4]:m[5]], ":.#$%&-+?<>~/")
+			}
+			if key == "" {
+				continue
+			}
+			at := group[2] + m[0] + strings.IndexByte(inner[m[0]:m[1]], '@')
+			links = append(links, WikiLink{
+				Raw:      content[group[0]:group[1]],
+				Target:   key,
+				LinkType: CitationLinkType,
+				Position: at,
+			})
+		}
+	}
+	return links
+}
+
+// ParseBibTeX returns the entries of a BibTeX file. @string, @preamble and
+// @comment blocks are skipped, as are entries too malformed to read; string
+// macros are not expanded.
+func ParseBibTeX(content string) []Reference {
+	var refs []Reference
+	for i := 0; i < len(content); {
+		at := strings.IndexByte(content[i:], '@')
+		if at < 0 {
+			break
+		}
+		start := i + at
+		ref, end, ok := parseBibEntry(content, start)
+		if !ok {
+			i = start + 1
+			continue
+		}
+		i = end
+		switch ref.Type {
+		case "string", "preamble", "comment":
+			continue
+		}
+		if ref.Key != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// parseBibEntry parses the entry starting at the '@' at start and returns it
+// with the offset just past its closing delimiter.
+func parseBibEntry(s string, start int) (Reference, int, bool) {
+	i := start + 1
+	for i < len(s) && isBibNameByte(s[i]) {
+		i++
+	}
+	ref := Reference{Type: strings.ToLower(s[start+1 : i])}
+	i = skipBibSpace(s, i)
+	if ref.Type == "" || i >= len(s) || (s[i] != '{' && s[i] != '(') {
+		return ref, 0, false
+	}
+	closer := byte('}')
+	if s[i] == '(' {
+		closer = ')'
+	}
+	end := matchingBibDelimiter(s, i, s[i], closer)
+	if end < 0 {
+		return ref, 0, false
+	}
+	ref.Raw = s[start : end+1]
+	if ref.Type == "string" || ref.Type == "preamble" || ref.Type == "comment" {
+		return ref, end + 1, true
+	}
+
+	body := s[i+1 : end]
+	comma := strings.IndexByte(body, ',')
+	if comma < 0 {
+		ref.Key = strings.TrimSpace(body)
+		return ref, end + 1, true
+	}
+	ref.Key = strings.TrimSpace(body[:comma])
+	ref.Fields = parseBibFields(body[comma+1:])
+	return ref, end + 1, true
+}
+
+// parseBibFields parses the "name = value" pairs of an entry. Values are
+// braced, quoted, bare (numbers and macros), or concatenated with '#'.
+func parseBibFields(s string) map[string]string {
+	fields := make(map[string]string)
+	i := 0
+	for i < len(s) {
+		i = skipBibSpace(s, i)
+		nameStart := i
+		for i < len(s) && isBibNameByte(s[i]) {
+			i++
+		}
+		name := strings.ToLower(s[nameStart:i])
+		i = skipBibSpace(s, i)
+		if name == "" || i >= len(s) || s[i] != '=' {
+			// Not a field; resume after the next comma
+			next := strings.IndexByte(s[i:], ',')
+			if next < 0 {
+				break
+			}
+			i += next + 1
+			continue
+		}
+		i++
+
+		var value strings.Builder
+		for {
+			i = skipBibSpace(s, i)
+			if i >= len(s) {
+				break
+			}
+			switch s[i] {
+			case '{':
+				end := matchingBibDelimiter(s, i, '{', '}')
+				if end < 0 {
+					end = len(s) - 1
+				}
+				value.WriteString(s[i+1 : end])
+				i = end + 1
+			case '"':
+				end := i + 1
+				for depth := 0; end < len(s) && (s[end] != '"' || depth > 0); end++ {
+					switch s[end] {
+					case '{':
+						depth++
+					case '}':
+						depth--
+					}
+				}
+				value.WriteString(s[i+1 : min(end, len(s))])
+				i = end + 1
+			default:
+				partStart := i
+				for i < len(s) && s[i] != ',' && s[i] != '#' && !isBibSpace(s[i]) {
+					i++
+				}
+				value.WriteString(s[partStart:i])
+			}
+			i = skipBibSpace(s, i)
+			if i < len(s) && s[i] == '#' {
+				i++
+				continue
+			}
+			break
+		}
+		fields[name] = cleanBibValue(value.String())
+
+		next := strings.IndexByte(s[min(i, len(s)):], ',')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return fields
+}
+
+// cleanBibValue removes the braces BibTeX uses to protect case and collapses
+// whitespace, e.g. "The {GNU}\n  Manual" -> "The GNU Manual".
+func cleanBibValue(v string) string {
+	v = strings.NewReplacer("{", "", "}", "").Replace(v)
+	return strings.Join(strings.Fields(v), " ")
+}
+
+// matchingBibDelimiter returns the offset of the closer matching the opener
+// at s[open], or -1 if it is never closed.
+func matchingBibDelimiter(s string, open int, opener, closer byte) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case opener:
+			depth++
+		case closer:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func skipBibSpace(s string, i int) int {
+	for i < len(s) && isBibSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isBibSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isBibNameByte(c byte) bool {
+	return c == '_' || c == '-' || c == ':' || c == '.' ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,105 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBib = `% Library export
+@string{jfp = "Journal of Functional Programming"}
+
+@Article{doe99,
+  author  = {Doe, Jane and Roe, Richard},
+  title   = {The {GNU} Manual
+             of Everything},
+  journal = jfp,
+  year    = 1999,
+}
+
+@book(roe2020, title = "Nested {Braces} Here", year = "2020" # "b")
+
+@comment{ignored, title = {Not an entry}}
+not an entry @ all
+`
+
+func TestParseBibTeX(t *testing.T) {
+	refs := ParseBibTeX(testBib)
+	require.Len(t, refs, 2)
+
+	assert.Equal(t, "doe99", refs[0].Key)
+	assert.Equal(t, "article", refs[0].Type)
+	assert.Equal(t, "The GNU Manual of Everything", refs[0].Title())
+	assert.Equal(t, "Doe, Jane and Roe, Richard", refs[0].Fields["author"])
+	assert.Equal(t, "jfp", refs[0].Fields["journal"])
+	assert.Equal(t, "1999", refs[0].Fields["year"])
+	assert.Contains(t, refs[0].Raw, "@Article{doe99,")
+
+	assert.Equal(t, "roe2020", refs[1].Key)
+	assert.Equal(t, "book", refs[1].Type)
+	assert.Equal(t, "Nested Braces Here", refs[1].Title())
+	assert.Equal(t, "2020b", refs[1].Fields["year"])
+
+	assert.Empty(t, ParseBibTeX("@misc{unclosed, title = {x}"))
+	untitled := ParseBibTeX("@misc{bare}")
+	require.Len(t, untitled, 1)
+	assert.Equal(t, "bare", untitled[0].Title())
+}
+
+func TestExtractCitations(t *testing.T) {
+	content := "As shown [see @doe99, pp. 33-35; -@roe2020]. Also [@{odd key}] and [@smith.].\n" +
+		"Not citations: [mail me@example.com], @doe99, [[wiki]] and [link](x)."
+
+	links := ExtractCitations(content)
+	var keys []string
+	for _, l := range links {
+		assert.Equal(t, CitationLinkType, l.LinkType)
+		assert.Equal(t, byte('@'), content[l.Position])
+		keys = append(keys, l.Target)
+	}
+	assert.Equal(t, []string{"doe99", "roe2020", "odd key", "smith"}, keys)
+	assert.Equal(t, "[see @doe99, pp. 33-35; -@roe2020]", links[0].Raw)
+}
+
+func TestBuildGraphCitations(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"refs/library.bib": testBib,
+		"lit/doe.md":       "---\nid: lit-doe\n---\nSummary of [@doe99, p. 3], [@missing] and [@knuth84].\n",
+		"lit/knuth84.md":   "---\nid: lit-knuth\n---\nA literature note named after its citekey.\n",
+	}
+	for path, content := range files {
+		full := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o750))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0o600))
+	}
+
+	result, err := NewParser(dir, 0, 0).ParseVault()
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Stats.References)
+	assert.Equal(t, 2, result.Stats.ResolvedLinks)
+	require.Len(t, result.UnresolvedLinks, 1)
+	assert.Equal(t, "missing", result.UnresolvedLinks[0].Link.Target)
+
+	graph, err := NewGraphBuilder(GraphBuilderConfig{}).BuildGraph(result)
+	require.NoError(t, err)
+	require.Len(t, graph.Nodes, 4)
+
+	ref := graph.Nodes[0]
+	assert.Equal(t, "@doe99", ref.ID)
+	assert.Equal(t, ReferenceNodeType, ref.NodeType)
+	assert.Equal(t, "The GNU Manual of Everything", ref.Title)
+	assert.Equal(t, "refs/library.bib#doe99", ref.FilePath)
+	assert.Equal(t, "1999", ref.Metadata["year"])
+	assert.Equal(t, "doe99", ref.Metadata["citekey"])
+	assert.Equal(t, 1, ref.InDegree)
+
+	require.Len(t, graph.Edges, 2)
+	assert.Equal(t, "lit-doe", graph.Edges[0].SourceID)
+	assert.Equal(t, "@doe99", graph.Edges[0].TargetID)
+	assert.Equal(t, CitationLinkType, graph.Edges[0].EdgeType)
+	assert.Equal(t, "lit-knuth", graph.Edges[1].TargetID)
+}
@@ -0,0 +1,112 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// cacheVersion is folded into every content hash. Bump it when parser output
+// changes so files cached by older builds are parsed again.
+const cacheVersion = "5"
+
+// cachedFile is the stored form of a parsed MarkdownFile. Content and
+// FileInfo are left out: the file is read to hash it, so both are at hand.
+type cachedFile struct {
+	Title       string             `json:"title"`
+	Frontmatter *cachedFrontmatter `json:"frontmatter,omitempty"`
+	Links       []WikiLink         `json:"links,omitempty"`
+	WordCount   int                `json:"word_count"`
+	Excerpt     string             `json:"excerpt,omitempty"`
+	URLs        []string           `json:"urls,omitempty"`
+	Outline     []models.Heading   `json:"outline,omitempty"`
+}
+
+type cachedFrontmatter struct {
+	ID         string   `json:"id"`
+	Tags       []string `json:"tags"`
+	Related    []string `json:"related"`
+	References []string `json:"references"`
+	Raw        string   `json:"raw"` // YAML, which keeps dates and integers intact through a round trip
+}
+
+// contentHash identifies a file's content as parsed with the settings in salt.
+func contentHash(salt string, content []byte) string {
+	h := sha256.New()
+	h.Write([]byte(cacheVersion + "\x00" + salt + "\x00"))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// encodeCachedFile serializes a parsed file for the file cache.
+func encodeCachedFile(file *MarkdownFile) (string, error) {
+	c := cachedFile{
+		Title:     file.Title,
+		Links:     file.Links,
+		WordCount: file.WordCount,
+		Excerpt:   file.Excerpt,
+		URLs:      file.URLs,
+		Outline:   file.Outline,
+	}
+	if fm := file.Frontmatter; fm != nil {
+		raw, err := yaml.Marshal(fm.Raw)
+		if err != nil {
+			return "", fmt.Errorf("encode frontmatter: %w", err)
+		}
+		c.Frontmatter = &cachedFrontmatter{
+			ID:         fm.ID,
+			Tags:       fm.Tags,
+			Related:    fm.Related,
+			References: fm.References,
+			Raw:        string(raw),
+		}
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeCachedFile restores a parsed file from the file cache, with text, the
+// file's content as prepared for parsing, as its Content.
+func decodeCachedFile(data, path, text string, info os.FileInfo) (*MarkdownFile, error) {
+	var c cachedFile
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return nil, err
+	}
+	file := &MarkdownFile{
+		Path:      path,
+		Title:     c.Title,
+		Content:   text,
+		Links:     c.Links,
+		WordCount: c.WordCount,
+		Excerpt:   c.Excerpt,
+		URLs:      c.URLs,
+		Outline:   c.Outline,
+		FileInfo:  info,
+	}
+	if fm := c.Frontmatter; fm != nil {
+		raw := make(map[string]any)
+		if err := yaml.Unmarshal([]byte(fm.Raw), &raw); err != nil {
+			return nil, fmt.Errorf("decode frontmatter: %w", err)
+		}
+		if raw == nil {
+			raw = make(map[string]any)
+		}
+		file.Frontmatter = &FrontmatterData{
+			ID:         fm.ID,
+			Tags:       fm.Tags,
+			Related:    fm.Related,
+			References: fm.References,
+			Raw:        raw,
+		}
+	}
+	return file, nil
+}
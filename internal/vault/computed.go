@@ -0,0 +1,65 @@
+package vault
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/ali01/mnemosyne/internal/expr"
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// ComputedField is a metadata field derived from an expression at parse time.
+type ComputedField struct {
+	Name string
+	Expr *expr.Expr
+}
+
+// CompileComputedFields compiles field expressions, keyed by field name.
+// Fields are returned sorted by name, which is also their evaluation order,
+// so a field can refer to any field that sorts before it.
+func CompileComputedFields(defs map[string]string) ([]ComputedField, error) {
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := make([]ComputedField, 0, len(names))
+	for _, name := range names {
+		e, err := expr.Parse(defs[name])
+		if err != nil {
+			return nil, fmt.Errorf("computed field %q: %w", name, err)
+		}
+		fields = append(fields, ComputedField{Name: name, Expr: e})
+	}
+	return fields, nil
+}
+
+// applyComputedFields evaluates fields against node and stores the results in
+// its metadata. Fields that fail to evaluate are logged and left unset.
+func applyComputedFields(node *models.VaultNode, fields []ComputedField, logger *slog.Logger) {
+	if len(fields) == 0 {
+		return
+	}
+	if node.Metadata == nil {
+		node.Metadata = make(models.JSONMetadata)
+	}
+
+	env := expr.Env{
+		"metadata":     map[string]interface{}(node.Metadata),
+		"title":        node.Title,
+		"path":         node.FilePath,
+		"tags":         []string(node.Tags),
+		"word_count":   float64(node.WordCount),
+		"reading_time": float64(node.ReadingTime),
+	}
+	for _, f := range fields {
+		v, err := f.Expr.Eval(env)
+		if err != nil {
+			warnf(logger, "computed field '%s' failed for '%s': %v", f.Name, node.FilePath, err)
+			continue
+		}
+		node.Metadata[f.Name] = v
+	}
+}
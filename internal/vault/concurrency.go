@@ -0,0 +1,74 @@
+package vault
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// tuneWindow is the number of files observed between worker-count adjustments.
+const tuneWindow = 64
+
+// defaultMaxConcurrency caps adaptive workers when no explicit cap is set.
+func defaultMaxConcurrency() int {
+	return 4 * runtime.GOMAXPROCS(0)
+}
+
+// workerTuner sizes the parser's worker pool from observed file timings.
+//
+// Parsing starts with one worker per available CPU. After every tuneWindow
+// files it compares time spent reading files (I/O) with time spent parsing
+// them (CPU): workers blocked on I/O leave CPUs idle, so the target becomes
+// cpus * (1 + io/cpu), clamped to the cap. The pool only grows.
+type workerTuner struct {
+	mu      sync.Mutex
+	cpus    int
+	max     int
+	current int
+	files   int
+	io      time.Duration
+	cpu     time.Duration
+}
+
+func newWorkerTuner(maxWorkers int) *workerTuner {
+	if maxWorkers <= 0 {
+		maxWorkers = defaultMaxConcurrency()
+	}
+	cpus := runtime.GOMAXPROCS(0)
+	return &workerTuner{cpus: cpus, max: maxWorkers, current: min(cpus, maxWorkers)}
+}
+
+// initial returns the number of workers to start with.
+func (t *workerTuner) initial() int {
+	return t.current
+}
+
+// record adds one file's timings and returns how many workers to add.
+func (t *workerTuner) record(io, cpu time.Duration) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.files++
+	t.io += io
+	t.cpu += cpu
+	if t.files%tuneWindow != 0 || t.cpu <= 0 {
+		return 0
+	}
+
+	ratio := float64(t.io) / float64(t.cpu)
+	target := min(int(math.Ceil(float64(t.cpus)*(1+ratio))), t.max)
+	if target <= t.current {
+		return 0
+	}
+	add := target - t.current
+	t.current = target
+	return add
+}
+
+// workers returns the current pool size.
+func (t *workerTuner) workers() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.current
+}
@@ -0,0 +1,47 @@
+package vault
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerTuner_GrowsWhenIOBound(t *testing.T) {
+	cpus := runtime.GOMAXPROCS(0)
+	tuner := newWorkerTuner(cpus * 3)
+	assert.Equal(t, cpus, tuner.initial())
+
+	// Reads take as long as parsing: target is cpus * 2
+	added := 0
+	for i := 0; i < tuneWindow; i++ {
+		added += tuner.record(time.Millisecond, time.Millisecond)
+	}
+	assert.Equal(t, cpus, added)
+	assert.Equal(t, 2*cpus, tuner.workers())
+
+	// Heavily I/O-bound: clamped to the cap
+	for i := 0; i < tuneWindow; i++ {
+		tuner.record(100*time.Millisecond, time.Millisecond)
+	}
+	assert.Equal(t, 3*cpus, tuner.workers())
+}
+
+func TestWorkerTuner_CPUBoundStaysPut(t *testing.T) {
+	tuner := newWorkerTuner(0)
+	start := tuner.workers()
+	for i := 0; i < 2*tuneWindow; i++ {
+		assert.Zero(t, tuner.record(0, time.Millisecond))
+	}
+	assert.Equal(t, start, tuner.workers())
+	assert.Equal(t, defaultMaxConcurrency(), tuner.max)
+}
+
+func TestWorkerTuner_CapBelowCPUs(t *testing.T) {
+	tuner := newWorkerTuner(1)
+	assert.Equal(t, 1, tuner.initial())
+	for i := 0; i < tuneWindow; i++ {
+		assert.Zero(t, tuner.record(time.Second, time.Millisecond))
+	}
+}
@@ -0,0 +1,29 @@
+package vault
+
+import (
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Matches a daily note base name, e.g. "2024-06-01" or "2024-06-01 Saturday"
+var dailyNoteRegex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(\s|$)`)
+
+// DailyNoteDate returns the date of a daily note from its path. Daily notes are
+// files whose name starts with a YYYY-MM-DD date.
+func DailyNoteDate(path string) (time.Time, bool) {
+	ext := filepath.Ext(path)
+	if ext != ".md" {
+		return time.Time{}, false
+	}
+	name := filepath.Base(path)
+	m := dailyNoteRegex.FindStringSubmatch(name[:len(name)-len(ext)])
+	if m == nil {
+		return time.Time{}, false
+	}
+	d, err := time.Parse("2006-01-02", m[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return d, true
+}
@@ -0,0 +1,31 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDailyNoteDate(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"journal/2024-06-01.md", "2024-06-01"},
+		{"2024-06-02 Sunday.md", "2024-06-02"},
+		{"journal/2024-06-01-meeting.md", ""},
+		{"notes/Meeting 2024-06-01.md", ""},
+		{"2024-13-01.md", ""},
+		{"2024-06-01.txt", ""},
+	}
+	for _, tt := range tests {
+		d, ok := DailyNoteDate(tt.path)
+		if tt.want == "" {
+			assert.False(t, ok, tt.path)
+			continue
+		}
+		assert.True(t, ok, tt.path)
+		assert.Equal(t, tt.want, d.Format(time.DateOnly), tt.path)
+	}
+}
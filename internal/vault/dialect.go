@@ -0,0 +1,61 @@
+package vault
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Dialect selects markdown syntax behaviors applied during parsing.
+type Dialect struct {
+	// Name is the dialect the settings were derived from.
+	Name string
+
+	// Wikilinks enables [[wikilink]] and ![[embed]] extraction.
+	Wikilinks bool
+
+	// StripComments removes Obsidian %%comments%% before links, headings, and
+	// words are extracted. Stored note content is left untouched.
+	StripComments bool
+
+	// FrontmatterDelimiter fences YAML frontmatter. Empty means "---".
+	FrontmatterDelimiter string
+}
+
+// Built-in dialects. CommonMark and GFM differ only in syntax Mnemosyne does
+// not extract, so they currently parse identically.
+var (
+	DialectObsidian   = Dialect{Name: "obsidian", Wikilinks: true, StripComments: true, FrontmatterDelimiter: "---"}
+	DialectCommonMark = Dialect{Name: "commonmark", FrontmatterDelimiter: "---"}
+	DialectGFM        = Dialect{Name: "gfm", FrontmatterDelimiter: "---"}
+)
+
+// LookupDialect returns the built-in dialect with the given name.
+// An empty name selects Obsidian.
+func LookupDialect(name string) (Dialect, error) {
+	switch strings.ToLower(name) {
+	case "", "obsidian":
+		return DialectObsidian, nil
+	case "commonmark":
+		return DialectCommonMark, nil
+	case "gfm":
+		return DialectGFM, nil
+	}
+	return Dialect{}, fmt.Errorf("unknown markdown dialect %q (want obsidian, commonmark, or gfm)", name)
+}
+
+// Matches Obsidian %%comments%%, which may span lines
+var obsidianCommentRegex = regexp.MustCompile(`(?s)%%.*?%%`)
+
+// stripComments blanks out %%comments%% while preserving newlines and byte
+// offsets, so link positions and heading line numbers stay accurate.
+func stripComments(content string) string {
+	return obsidianCommentRegex.ReplaceAllStringFunc(content, func(m string) string {
+		return strings.Map(func(r rune) rune {
+			if r == '\n' {
+				return r
+			}
+			return ' '
+		}, m)
+	})
+}
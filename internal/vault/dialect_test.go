@@ -0,0 +1,77 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dialectNote = `---
+id: note
+---
+# Title
+See [[visible]]. %%Hidden [[secret]] words%%
+
+%%
+## Hidden heading
+%%
+## Shown
+`
+
+func TestLookupDialect(t *testing.T) {
+	d, err := LookupDialect("")
+	require.NoError(t, err)
+	assert.Equal(t, DialectObsidian, d)
+
+	d, err = LookupDialect("GFM")
+	require.NoError(t, err)
+	assert.False(t, d.Wikilinks)
+
+	_, err = LookupDialect("asciidoc")
+	assert.Error(t, err)
+}
+
+func TestProcessContent_ObsidianStripsComments(t *testing.T) {
+	file, err := ProcessMarkdownReader(strings.NewReader(dialectNote), "note.md")
+	require.NoError(t, err)
+
+	require.Len(t, file.Links, 1)
+	assert.Equal(t, "visible", file.Links[0].Target)
+	assert.Equal(t, strings.Index(dialectNote, "[[visible]]"), file.Links[0].Position)
+
+	require.Len(t, file.Outline, 2)
+	assert.Equal(t, "Shown", file.Outline[1].Text)
+	assert.Equal(t, 10, file.Outline[1].Line)
+
+	assert.Contains(t, file.Content, "%%Hidden", "stored content is not modified")
+	assert.Equal(t, 4, file.WordCount) // Title, See, [[visible]]., Shown
+}
+
+func TestProcessContent_CommonMark(t *testing.T) {
+	d := DialectCommonMark
+	file, err := ProcessMarkdownReaderWithOptions(strings.NewReader(dialectNote), "note.md", ParseOptions{Dialect: &d})
+	require.NoError(t, err)
+
+	assert.Empty(t, file.Links)
+	assert.Len(t, file.Outline, 3)
+}
+
+func TestProcessContent_CustomFrontmatterDelimiter(t *testing.T) {
+	d := DialectObsidian
+	d.FrontmatterDelimiter = "+++"
+	content := "+++\nid: plus\n+++\n# Heading\n"
+
+	file, err := ProcessMarkdownReaderWithOptions(strings.NewReader(content), "plus.md", ParseOptions{Dialect: &d})
+	require.NoError(t, err)
+	require.NotNil(t, file.Frontmatter)
+	assert.Equal(t, "plus", file.Frontmatter.ID)
+	require.Len(t, file.Outline, 1)
+	assert.Equal(t, 4, file.Outline[0].Line)
+
+	// The default delimiter no longer matches
+	file, err = ProcessMarkdownReaderWithOptions(strings.NewReader("---\nid: x\n---\n"), "x.md", ParseOptions{Dialect: &d})
+	require.NoError(t, err)
+	assert.Nil(t, file.Frontmatter)
+}
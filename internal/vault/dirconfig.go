@@ -0,0 +1,206 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DirConfigName is the file in which a directory of the vault describes
+// itself: classification rules and ignore patterns for the notes under it.
+const DirConfigName = ".mnemosyne.yaml"
+
+// errDirConfigNotRegular is returned for a DirConfigName that is a symlink or
+// other special file.
+var errDirConfigNotRegular = errors.New("not a regular file; symlinks are not followed")
+
+// DirConfig is the content of a DirConfigName file, or the server-wide rules
+// the directories' are merged with.
+type DirConfig struct {
+	// Classify gives node types to the notes under the directory. The first
+	// matching rule wins; rules of nearer directories are tried first, and
+	// the server-wide rules last.
+	Classify []ClassifyRule `yaml:"classify,omitempty"`
+
+	// Ignore lists patterns of files and directories under the directory
+	// that are not indexed. A pattern with a "/" matches paths relative to
+	// the directory, one without matches names at any depth, as in
+	// .gitignore. Patterns use path.Match syntax.
+	Ignore []string `yaml:"ignore,omitempty"`
+}
+
+// ClassifyRule gives notes a node type. A rule without conditions matches
+// every note.
+type ClassifyRule struct {
+	Path string `yaml:"path,omitempty"` // Pattern like Ignore's that the note must match
+	Tag  string `yaml:"tag,omitempty"`  // Tag the note must have
+	Type string `yaml:"type"`           // Node type to give the note
+}
+
+// Validate checks the rules and patterns.
+func (c DirConfig) Validate() error {
+	for i, r := range c.Classify {
+		if r.Type == "" {
+			return fmt.Errorf("classify rule %d: type is required", i+1)
+		}
+		if _, err := path.Match(r.Path, ""); err != nil {
+			return fmt.Errorf("classify rule %d: path %q: %w", i+1, r.Path, err)
+		}
+	}
+	for _, p := range c.Ignore {
+		if _, err := path.Match(p, ""); p == "" || err != nil {
+			return fmt.Errorf("ignore pattern %q is not valid", p)
+		}
+	}
+	return nil
+}
+
+// loadDirConfig reads the DirConfigName file in dir, if there is one. A
+// symlink is refused rather than followed: it could point out of the vault,
+// and decoding errors quote what they read.
+func loadDirConfig(dir string) (*DirConfig, error) {
+	name := filepath.Join(dir, DirConfigName)
+	info, err := os.Lstat(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, errDirConfigNotRegular
+	}
+	f, err := os.Open(name) // #nosec G304 -- dir is a walked vault directory
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// The file may have been swapped for a link since it was checked
+	if opened, err := f.Stat(); err != nil || !os.SameFile(info, opened) {
+		return nil, errDirConfigNotRegular
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	var c DirConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Classifier applies the directories' DirConfigs and the server-wide one to
+// the notes of a vault. A nil Classifier classifies and ignores nothing.
+type Classifier struct {
+	global DirConfig
+	dirs   map[string]DirConfig // By vault-relative slash path; "." is the root
+}
+
+// LoadClassifier walks the vault for its DirConfigName files, as parsing it
+// does, and returns the classifier they make with opts.Rules. Files that
+// are not valid are left out.
+func LoadClassifier(ctx context.Context, vaultPath string, opts ParseOptions) (*Classifier, error) {
+	walked, err := walkVault(ctx, vaultPath, opts)
+	if err != nil {
+		return nil, err
+	}
+	return walked.classifier, nil
+}
+
+func newClassifier(global DirConfig) *Classifier {
+	return &Classifier{global: global, dirs: make(map[string]DirConfig)}
+}
+
+// Type returns the node type the rules give the note at relPath with tags,
+// or "" if none matches.
+func (c *Classifier) Type(relPath string, tags []string) string {
+	if c == nil {
+		return ""
+	}
+	var typ string
+	c.each(relPath, func(rel string, cfg DirConfig) bool {
+		for _, r := range cfg.Classify {
+			if (r.Path == "" || matchPattern(r.Path, rel)) && (r.Tag == "" || slices.Contains(tags, r.Tag)) {
+				typ = r.Type
+				return true
+			}
+		}
+		return false
+	})
+	return typ
+}
+
+// key identifies the DirConfigs that apply to the note at relPath, and the
+// server-wide one, so that its cache entry lapses when any of them changes.
+func (c *Classifier) key(relPath string) string {
+	if c == nil {
+		return ""
+	}
+	// By directory rather than by the note's own path, so renamed notes
+	// keep their key and are still detected as renames
+	relPath = filepath.ToSlash(relPath)
+	var b strings.Builder
+	c.each(relPath, func(rel string, cfg DirConfig) bool {
+		data, _ := yaml.Marshal(cfg) // Strings only; cannot fail
+		fmt.Fprintf(&b, "%s\x00%s\x00", strings.TrimSuffix(relPath, rel), data)
+		return false
+	})
+	return b.String()
+}
+
+// ignored reports whether an ignore pattern matches relPath.
+func (c *Classifier) ignored(relPath string) bool {
+	if c == nil {
+		return false
+	}
+	return c.each(relPath, func(rel string, cfg DirConfig) bool {
+		return slices.ContainsFunc(cfg.Ignore, func(p string) bool { return matchPattern(p, rel) })
+	})
+}
+
+// each calls fn with relPath relative to each of its directories that has a
+// DirConfig, nearest first, and then to the vault root with the server-wide
+// config, until fn returns true. It returns whether fn did.
+func (c *Classifier) each(relPath string, fn func(rel string, cfg DirConfig) bool) bool {
+	relPath = filepath.ToSlash(relPath)
+	for dir := path.Dir(relPath); ; dir = path.Dir(dir) {
+		if cfg, ok := c.dirs[dir]; ok {
+			rel := relPath
+			if dir != "." {
+				rel = strings.TrimPrefix(relPath, dir+"/")
+			}
+			if fn(rel, cfg) {
+				return true
+			}
+		}
+		if dir == "." {
+			break
+		}
+	}
+	return fn(relPath, c.global)
+}
+
+// matchPattern reports whether pattern matches rel, a slash path: the whole
+// of it if the pattern has a "/", otherwise its base name.
+func matchPattern(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		rel = path.Base(rel)
+	}
+	ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), rel)
+	return ok
+}
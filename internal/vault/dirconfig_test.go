@@ -0,0 +1,78 @@
+package vault
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifier(t *testing.T) {
+	c := newClassifier(DirConfig{
+		Classify: []ClassifyRule{{Tag: "meeting", Type: "meeting"}, {Path: "journal/*.md", Type: "journal"}},
+		Ignore:   []string{"*.excalidraw.md", "/archive"},
+	})
+	c.dirs["projects/apollo"] = DirConfig{
+		Classify: []ClassifyRule{{Path: "specs/*", Type: "spec"}, {Tag: "meeting", Type: "standup"}},
+		Ignore:   []string{"drafts", "notes/scratch.md"},
+	}
+
+	assert.Equal(t, "meeting", c.Type("inbox/sync.md", []string{"meeting"}))
+	assert.Equal(t, "journal", c.Type("journal/2024-01-01.md", nil))
+	assert.Equal(t, "", c.Type("journal/2024/01.md", nil), "patterns with a slash match the whole path")
+	assert.Equal(t, "spec", c.Type("projects/apollo/specs/launch.md", []string{"meeting"}), "nearer rules win")
+	assert.Equal(t, "standup", c.Type("projects/apollo/log.md", []string{"meeting"}))
+	assert.Equal(t, "", c.Type("projects/apollo/log.md", nil))
+	assert.Equal(t, "", (*Classifier)(nil).Type("a.md", nil))
+
+	for _, p := range []string{"a.excalidraw.md", "projects/b.excalidraw.md", "archive", "projects/apollo/drafts", "projects/apollo/notes/scratch.md"} {
+		assert.True(t, c.ignored(p), p)
+	}
+	for _, p := range []string{"a.md", "projects/archive", "projects/drafts", "projects/apollo/scratch.md"} {
+		assert.False(t, c.ignored(p), p)
+	}
+}
+
+func TestParser_DirConfigs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, rel)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, rel), []byte(content), 0o644))
+	}
+	write("a.md", "---\nid: a\ntags: [meeting]\n---\n")
+	write("apollo/.mnemosyne.yaml", "classify:\n  - path: 'specs/*'\n    type: spec\nignore:\n  - drafts\n")
+	write("apollo/specs/launch.md", "---\nid: launch\ntags: [meeting]\n---\n")
+	write("apollo/drafts/wip.md", "---\nid: wip\n---\n")
+	write("broken/.mnemosyne.yaml", "clasify: []\n")
+	write("broken/b.md", "---\nid: b\n---\n")
+	write("old.md", "---\nid: old\n---\n")
+
+	parser := NewParser(dir, 1, 0)
+	parser.SetOptions(ParseOptions{Rules: DirConfig{
+		Classify: []ClassifyRule{{Tag: "meeting", Type: "meeting"}},
+		Ignore:   []string{"old.md"},
+	}})
+	result, err := parser.ParseVault()
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"a", "launch", "b"}, slices.Collect(maps.Keys(result.Files)))
+	require.Len(t, result.ParseErrors, 1)
+	assert.Equal(t, filepath.Join("broken", DirConfigName), result.ParseErrors[0].FilePath)
+	assert.Equal(t, ParseErrorDirConfig, result.ParseErrors[0].Kind)
+
+	graph, err := NewGraphBuilder(GraphBuilderConfig{}).BuildGraph(result)
+	require.NoError(t, err)
+	types := make(map[string]string)
+	for _, n := range graph.Nodes {
+		types[n.ID] = n.NodeType
+	}
+	assert.Equal(t, map[string]string{"a": "meeting", "launch": "spec", "b": ""}, types)
+
+	c, err := LoadClassifier(t.Context(), dir, ParseOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "spec", c.Type("apollo/specs/x.md", nil))
+}
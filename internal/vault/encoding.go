@@ -0,0 +1,143 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// errInvalidUTF8 is the error of an EncodingError for content that is not
+// valid UTF-8.
+var errInvalidUTF8 = errors.New("file is not valid UTF-8; invalid bytes were replaced")
+
+// ErrNotText is returned for files that are not text, such as images or
+// archives named .md. They are skipped rather than stored as content.
+var ErrNotText = errors.New("not a text file")
+
+// sniffText returns an ErrNotText error if content, judging by its first
+// bytes, is not text. UTF-16 passes, as decodeText converts it.
+func sniffText(content []byte) error {
+	if bytes.HasPrefix(content, utf16LEBOM) || bytes.HasPrefix(content, utf16BEBOM) || sniffUTF16(content) != nil {
+		return nil
+	}
+	if ct := http.DetectContentType(content); !strings.HasPrefix(ct, "text/") {
+		return fmt.Errorf("%w: detected %s", ErrNotText, ct)
+	}
+	return nil
+}
+
+// EncodingError reports content that is not valid UTF-8 once decoded.
+// Invalid bytes are replaced with U+FFFD and the file is still parsed.
+type EncodingError struct {
+	Line int // Line of the first invalid byte, from 1
+	Err  error
+}
+
+func (e *EncodingError) Error() string { return e.Err.Error() }
+
+func (e *EncodingError) Unwrap() error { return e.Err }
+
+// decodeText converts file content to UTF-8 text with "\n" line endings, as
+// files exported by Windows tools often differ: a UTF-8 byte order mark is
+// dropped, UTF-16 is decoded (with a byte order mark, or detected by the
+// zero bytes of its ASCII characters), and "\r\n" and lone "\r" become "\n".
+// Content still not valid UTF-8 has its invalid bytes replaced, with an
+// EncodingError as a warning.
+func decodeText(content []byte) (string, *EncodingError) {
+	switch {
+	case bytes.HasPrefix(content, utf8BOM):
+		content = content[len(utf8BOM):]
+	case bytes.HasPrefix(content, utf16LEBOM):
+		content = decodeUTF16(content[len(utf16LEBOM):], binary.LittleEndian)
+	case bytes.HasPrefix(content, utf16BEBOM):
+		content = decodeUTF16(content[len(utf16BEBOM):], binary.BigEndian)
+	default:
+		if order := sniffUTF16(content); order != nil {
+			content = decodeUTF16(content, order)
+		}
+	}
+
+	if bytes.IndexByte(content, '\r') >= 0 {
+		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+		content = bytes.ReplaceAll(content, []byte("\r"), []byte("\n"))
+	}
+
+	if utf8.Valid(content) {
+		return string(content), nil
+	}
+	warning := &EncodingError{Line: invalidUTF8Line(content), Err: errInvalidUTF8}
+	return string(bytes.ToValidUTF8(content, []byte("�"))), warning
+}
+
+// sniffUTF16 returns the byte order of content that looks like UTF-16
+// without a byte order mark: mostly ASCII text, so that most of its
+// characters have a zero high byte, while UTF-8 text has no zero bytes at
+// all. It returns nil for anything else.
+func sniffUTF16(content []byte) binary.ByteOrder {
+	sample := content[:min(len(content), 1024)&^1]
+	if len(sample) < 4 {
+		return nil
+	}
+	var evenZeros, oddZeros int
+	for i := 0; i < len(sample); i += 2 {
+		if sample[i] == 0 {
+			evenZeros++
+		}
+		if sample[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	pairs := len(sample) / 2
+	switch {
+	case oddZeros*2 > pairs && evenZeros == 0:
+		return binary.LittleEndian
+	case evenZeros*2 > pairs && oddZeros == 0:
+		return binary.BigEndian
+	}
+	return nil
+}
+
+// decodeUTF16 converts UTF-16 in the given byte order to UTF-8. Unpaired
+// surrogates and a trailing odd byte become U+FFFD.
+func decodeUTF16(b []byte, order binary.ByteOrder) []byte {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = order.Uint16(b[2*i:])
+	}
+	out := make([]byte, 0, len(b))
+	for _, r := range utf16.Decode(units) {
+		out = utf8.AppendRune(out, r)
+	}
+	if len(b)%2 == 1 {
+		out = utf8.AppendRune(out, utf8.RuneError)
+	}
+	return out
+}
+
+// invalidUTF8Line returns the line, from 1, of the first byte in content that
+// is not valid UTF-8.
+func invalidUTF8Line(content []byte) int {
+	line := 1
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRune(content[i:])
+		if r == utf8.RuneError && size == 1 {
+			break
+		}
+		if r == '\n' {
+			line++
+		}
+		i += size
+	}
+	return line
+}
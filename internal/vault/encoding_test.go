@@ -0,0 +1,121 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeUTF16 encodes s as UTF-16 in order, with bom prepended.
+func encodeUTF16(s string, order binary.AppendByteOrder, bom []byte) []byte {
+	b := bytes.Clone(bom)
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = order.AppendUint16(b, u)
+	}
+	return b
+}
+
+func TestDecodeText(t *testing.T) {
+	const want = "---\nid: café\n---\n# Café 東京\nLine two\n"
+	crlf := strings.ReplaceAll(want, "\n", "\r\n")
+
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{"utf-8", []byte(want)},
+		{"utf-8 bom", append(bytes.Clone(utf8BOM), want...)},
+		{"crlf", []byte(crlf)},
+		{"mixed line endings", []byte("---\r\nid: café\n---\r# Café 東京\r\nLine two\n")},
+		{"utf-16le bom", encodeUTF16(crlf, binary.LittleEndian, utf16LEBOM)},
+		{"utf-16be bom", encodeUTF16(want, binary.BigEndian, utf16BEBOM)},
+		{"utf-16le without bom", encodeUTF16(crlf, binary.LittleEndian, nil)},
+		{"utf-16be without bom", encodeUTF16(want, binary.BigEndian, nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warning := decodeText(tt.content)
+			assert.Nil(t, warning)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestDecodeText_InvalidUTF8(t *testing.T) {
+	got, warning := decodeText([]byte("ok\r\nline \xe9 two\n"))
+	assert.Equal(t, "ok\nline � two\n", got)
+	require.NotNil(t, warning)
+	assert.Equal(t, 2, warning.Line)
+	assert.ErrorIs(t, warning, errInvalidUTF8)
+
+	// An odd trailing byte of UTF-16 is replaced, without a warning
+	got, warning = decodeText(append(encodeUTF16("ab", binary.LittleEndian, utf16LEBOM), 'c'))
+	assert.Nil(t, warning)
+	assert.Equal(t, "ab�", got)
+}
+
+func TestProcessMarkdownFile_WindowsExport(t *testing.T) {
+	content := encodeUTF16("---\r\nid: win\r\ntags: [a]\r\n---\r\n# Title\r\nSee [[Other]].\r\n", binary.LittleEndian, utf16LEBOM)
+	file, err := ProcessMarkdownReaderWithOptions(bytes.NewReader(content), "win.md", ParseOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "win", file.GetID())
+	assert.Equal(t, []string{"a"}, file.GetTags())
+	assert.Equal(t, "---\nid: win\ntags: [a]\n---\n# Title\nSee [[Other]].\n", file.Content)
+	require.Len(t, file.Links, 1)
+	assert.Equal(t, "Other", file.Links[0].Target)
+	assert.Empty(t, file.Warnings)
+
+	file, err = ProcessMarkdownReaderWithOptions(strings.NewReader("---\nid: latin1\n---\nCaf\xe9\n"), "latin1.md", ParseOptions{})
+	require.NoError(t, err)
+	require.Len(t, file.Warnings, 1)
+	var ee *EncodingError
+	require.True(t, errors.As(file.Warnings[0], &ee))
+	assert.Equal(t, 4, ee.Line)
+}
+
+func TestSniffText(t *testing.T) {
+	for name, content := range map[string][]byte{
+		"markdown": []byte("---\nid: a\n---\n# Title\n"),
+		"empty":    {},
+		"latin1":   []byte("Caf\xe9"),
+		"utf16":    encodeUTF16("plain text here", binary.LittleEndian, nil),
+		"utf16bom": encodeUTF16("x", binary.BigEndian, utf16BEBOM),
+	} {
+		assert.NoError(t, sniffText(content), name)
+	}
+	for name, content := range map[string][]byte{
+		"png":  []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"),
+		"pdf":  []byte("%PDF-1.7\n"),
+		"zip":  []byte("PK\x03\x04\x14\x00\x00\x00"),
+		"data": {0x01, 0x02, 0x00, 0xff, 0x10},
+	} {
+		assert.ErrorIs(t, sniffText(content), ErrNotText, name)
+	}
+}
+
+func TestParser_SkipsBinaryFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "note.md"), []byte("---\nid: note\n---\n![[photo.md]]"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "photo.md"), []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00"), 0o644))
+
+	result, err := NewParser(dir, 1, 0).ParseVault()
+	require.NoError(t, err)
+	assert.Len(t, result.Files, 1)
+	assert.Equal(t, 1, result.Stats.SkippedFiles)
+	assert.Zero(t, result.Stats.FailedFiles)
+	require.Len(t, result.ParseErrors, 1)
+	assert.Equal(t, "photo.md", result.ParseErrors[0].FilePath)
+	assert.Equal(t, ParseErrorBinary, result.ParseErrors[0].Kind)
+	assert.Contains(t, result.ParseErrors[0].Error.Error(), "image/png")
+
+	_, err = ProcessMarkdownFile(dir, "photo.md")
+	assert.ErrorIs(t, err, ErrNotText)
+}
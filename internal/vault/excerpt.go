@@ -0,0 +1,155 @@
+package vault
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// DefaultExcerptSentences is the length of node excerpts when ParseOptions
+// leave ExcerptSentences unset.
+const DefaultExcerptSentences = 2
+
+// excerptMaxRunes caps excerpts whatever their sentence count, for notes
+// written in long unpunctuated paragraphs.
+const excerptMaxRunes = 300
+
+var (
+	// Matches embeds, which have no text worth previewing: ![[note]] and ![alt](src)
+	embedRegex = regexp.MustCompile(`!\[\[[^\]]*\]\]|!\[[^\]]*\]\([^)]*\)`)
+
+	// Matches [[target#section|alias]]; the alias and section are optional
+	inlineWikiLinkRegex = regexp.MustCompile(`\[\[([^\]|#]*)(?:#[^\]|]*)?(?:\|([^\]]*))?\]\]`)
+
+	// Matches [text](url)
+	inlineLinkRegex = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+
+	// Matches HTML tags
+	htmlTagRegex = regexp.MustCompile(`<[^>]+>`)
+
+	// Matches list item markers, with an optional task checkbox
+	listMarkerRegex = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?`)
+
+	// Removes emphasis, strikethrough, highlight and code markers
+	emphasisReplacer = strings.NewReplacer("**", "", "__", "", "~~", "", "==", "", "`", "", "*", "")
+)
+
+// Excerpt returns the first n sentences of a note body as plain text for
+// previews: headings, code blocks, tables and embeds are dropped, links are
+// replaced by their text, and other markdown syntax is removed. Paragraphs
+// and list items that do not end in punctuation count as one sentence. The
+// excerpt is at most excerptMaxRunes long.
+func Excerpt(body string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	var sentences, para []string
+	// flush ends the current paragraph and reports whether n sentences are in
+	flush := func() bool {
+		if len(para) > 0 {
+			sentences = append(sentences, splitSentences(strings.Join(para, " "))...)
+			para = para[:0]
+		}
+		return len(sentences) >= n
+	}
+
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if fenceRegex.MatchString(line) {
+			inFence = !inFence
+			if flush() {
+				break
+			}
+			continue
+		}
+		if inFence {
+			continue
+		}
+		text := strings.TrimSpace(line)
+		if text == "" || headingRegex.MatchString(text) || strings.HasPrefix(text, "|") || isThematicBreak(text) {
+			if flush() {
+				break
+			}
+			continue
+		}
+		text = strings.TrimLeft(text, "> ")
+		if m := listMarkerRegex.FindString(text); m != "" {
+			// Each list item is a paragraph of its own
+			if flush() {
+				break
+			}
+			text = text[len(m):]
+		}
+		if text = stripInlineMarkdown(text); text != "" {
+			para = append(para, text)
+		}
+	}
+	flush()
+
+	if len(sentences) > n {
+		sentences = sentences[:n]
+	}
+	return truncateAtWord(strings.Join(sentences, " "), excerptMaxRunes)
+}
+
+// isThematicBreak reports whether a trimmed line is a horizontal rule.
+func isThematicBreak(line string) bool {
+	return len(line) >= 3 && strings.Trim(line, "-*_ ") == ""
+}
+
+// stripInlineMarkdown reduces a line to its text.
+func stripInlineMarkdown(line string) string {
+	line = embedRegex.ReplaceAllString(line, "")
+	line = inlineWikiLinkRegex.ReplaceAllStringFunc(line, func(link string) string {
+		m := inlineWikiLinkRegex.FindStringSubmatch(link)
+		if m[2] != "" {
+			return m[2]
+		}
+		return path.Base(strings.TrimSpace(m[1]))
+	})
+	line = inlineLinkRegex.ReplaceAllString(line, "$1")
+	line = htmlTagRegex.ReplaceAllString(line, "")
+	line = emphasisReplacer.Replace(line)
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// splitSentences splits text after each '.', '!' or '?' (and any closing
+// quotes or brackets) that ends the text or is followed by a space.
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c != '.' && c != '!' && c != '?' {
+			continue
+		}
+		end := i + 1
+		for end < len(text) && strings.IndexByte(`"')]`, text[end]) >= 0 {
+			end++
+		}
+		if end < len(text) && text[end] != ' ' {
+			continue
+		}
+		if s := strings.TrimSpace(text[start:end]); s != "" {
+			sentences = append(sentences, s)
+		}
+		start, i = end, end-1
+	}
+	if s := strings.TrimSpace(text[start:]); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+// truncateAtWord truncates text to at most n runes, breaking at a word
+// boundary and marking the cut with an ellipsis.
+func truncateAtWord(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	cut := string(runes[:n-1])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
@@ -0,0 +1,57 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		n        int
+		expected string
+	}{
+		{"first sentences", "One. Two! Three? Four.", 2, "One. Two!"},
+		{"fewer than n", "Only one.", 3, "Only one."},
+		{"zero", "One. Two.", 0, ""},
+		{"skips headings", "# Title\n\nBody text here.", 1, "Body text here."},
+		{"joins paragraph lines", "First line\ncontinues here. Next.", 1, "First line continues here."},
+		{"unpunctuated paragraphs", "Intro\n\nMore text.", 2, "Intro More text."},
+		{"list items", "- alpha\n- [x] beta\n1. gamma", 3, "alpha beta gamma"},
+		{"skips code", "```\ncode. here.\n```\nAfter code.", 1, "After code."},
+		{"wikilinks", "See [[notes/Target]] and [[other#part|the alias]].", 1, "See Target and the alias."},
+		{"links and embeds", "![[diagram.png]]A [link](http://x.y) ![img](a.png)here.", 1, "A link here."},
+		{"emphasis", "**Bold** and *italic* and `code` and ~~gone~~.", 1, "Bold and italic and code and gone."},
+		{"quotes and tables", "> Quoted words.\n\n| a | b |\n|---|---|", 2, "Quoted words."},
+		{"decimals", "Pi is 3.14 roughly. Yes.", 1, "Pi is 3.14 roughly."},
+		{"closing quote", `He said "stop." Then left.`, 1, `He said "stop."`},
+		{"rules", "---\n\nText.", 1, "Text."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Excerpt(tt.body, tt.n))
+		})
+	}
+}
+
+func TestExcerptTruncates(t *testing.T) {
+	excerpt := Excerpt(strings.Repeat("word ", 200), 1)
+	assert.LessOrEqual(t, utf8.RuneCountInString(excerpt), excerptMaxRunes)
+	assert.True(t, strings.HasSuffix(excerpt, "word…"))
+}
+
+func TestProcessMarkdownExcerpt(t *testing.T) {
+	content := "---\nid: e1\n---\n# Title\n\nFirst. Second. Third.\n"
+	file, err := ProcessMarkdownReader(strings.NewReader(content), "e1.md")
+	require.NoError(t, err)
+	assert.Equal(t, "First. Second.", file.Excerpt)
+
+	file, err = ProcessMarkdownReaderWithOptions(strings.NewReader(content), "e1.md", ParseOptions{ExcerptSentences: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "First.", file.Excerpt)
+}
@@ -1,9 +1,12 @@
 package vault
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 )
@@ -20,31 +23,92 @@ type FrontmatterData struct {
 var (
 	// Matches YAML frontmatter between --- markers
 	frontmatterRegex = regexp.MustCompile(`(?s)^---\s*\n(.*?)---\s*\n`)
+
+	// frontmatterRegexCache holds compiled patterns for custom delimiters
+	frontmatterRegexCache sync.Map // delimiter -> *regexp.Regexp
 )
 
+// frontmatterRegexFor returns the frontmatter pattern for a fence delimiter.
+// An empty delimiter means the standard "---".
+func frontmatterRegexFor(delim string) *regexp.Regexp {
+	if delim == "" || delim == "---" {
+		return frontmatterRegex
+	}
+	if re, ok := frontmatterRegexCache.Load(delim); ok {
+		return re.(*regexp.Regexp)
+	}
+	q := regexp.QuoteMeta(delim)
+	re := regexp.MustCompile(`(?s)^` + q + `\s*\n(.*?)` + q + `\s*\n`)
+	frontmatterRegexCache.Store(delim, re)
+	return re
+}
+
+// StripFrontmatter returns content without leading "---" frontmatter.
+func StripFrontmatter(content string) string {
+	if loc := frontmatterRegex.FindStringIndex(content); loc != nil {
+		return content[loc[1]:]
+	}
+	return content
+}
+
+// errMissingID is the error of frontmatter without an 'id' where one is
+// required.
+var errMissingID = errors.New("frontmatter missing required 'id' field")
+
+// FrontmatterError reports invalid frontmatter.
+type FrontmatterError struct {
+	Line int // Line of the file the error is on, from 1; 0 if unknown
+	Err  error
+}
+
+func (e *FrontmatterError) Error() string { return e.Err.Error() }
+
+func (e *FrontmatterError) Unwrap() error { return e.Err }
+
+// yamlLineRegex finds the line a YAML error is on, e.g. "yaml: line 3: ...".
+var yamlLineRegex = regexp.MustCompile(`\bline (\d+):`)
+
+// newFrontmatterError wraps a YAML error, locating it in the file given the
+// number of lines before the YAML.
+func newFrontmatterError(linesBefore int, err error) *FrontmatterError {
+	fe := &FrontmatterError{Err: err}
+	if m := yamlLineRegex.FindStringSubmatch(err.Error()); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		fe.Line = linesBefore + n
+	}
+	return fe
+}
+
 // ExtractFrontmatter parses YAML frontmatter and returns remaining content
 func ExtractFrontmatter(content string) (*FrontmatterData, string, error) {
-	matches := frontmatterRegex.FindStringSubmatch(content)
-	if len(matches) < 2 {
+	return extractFrontmatter(content, frontmatterRegex, true)
+}
+
+// extractFrontmatter is ExtractFrontmatter with a specific delimiter pattern.
+// When requireID is false, frontmatter without an 'id' field is accepted.
+func extractFrontmatter(content string, re *regexp.Regexp, requireID bool) (*FrontmatterData, string, error) {
+	loc := re.FindStringSubmatchIndex(content)
+	if len(loc) < 4 || loc[2] < 0 {
 		return nil, content, nil // No frontmatter
 	}
 
 	// Extract YAML content
-	yamlContent := matches[1]
+	yamlContent := content[loc[2]:loc[3]]
+	yamlLine := strings.Count(content[:loc[2]], "\n") // Lines before the YAML
 
 	// Remove frontmatter from content
-	contentWithoutFrontmatter := strings.TrimPrefix(content, matches[0])
+	contentWithoutFrontmatter := content[loc[1]:]
 
 	// Parse YAML into raw map first
 	var raw map[string]any
 	if err := yaml.Unmarshal([]byte(yamlContent), &raw); err != nil {
-		return nil, "", fmt.Errorf("failed to parse frontmatter YAML: %w", err)
+		return nil, "", newFrontmatterError(yamlLine, fmt.Errorf("failed to parse frontmatter YAML: %w", err))
 	}
 
 	// Also parse into structured data
 	var data FrontmatterData
 	if err := yaml.Unmarshal([]byte(yamlContent), &data); err != nil {
-		return nil, "", fmt.Errorf("failed to parse frontmatter structure: %w", err)
+		return nil, "", newFrontmatterError(yamlLine, fmt.Errorf("failed to parse frontmatter structure: %w", err))
 	}
 
 	// Store raw data for preservation (ensure it's not nil)
@@ -54,8 +118,8 @@ func ExtractFrontmatter(content string) (*FrontmatterData, string, error) {
 	data.Raw = raw
 
 	// Validate required fields
-	if data.ID == "" {
-		return nil, "", fmt.Errorf("frontmatter missing required 'id' field")
+	if data.ID == "" && requireID {
+		return nil, "", &FrontmatterError{Err: errMissingID}
 	}
 
 	// Ensure slices are non-nil
@@ -0,0 +1,92 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// recoverFrontmatter salvages frontmatter that failed to parse with err:
+// each top-level key, with the indented lines and list items after it, is
+// parsed on its own and kept if it parses. It returns the keys kept, or nil
+// data when none were, with the content after the frontmatter block, and a
+// warning naming the keys dropped.
+func recoverFrontmatter(content string, re *regexp.Regexp, err error) (*FrontmatterData, string, *FrontmatterError) {
+	loc := re.FindStringSubmatchIndex(content)
+	if len(loc) < 4 || loc[2] < 0 {
+		return nil, content, nil
+	}
+	warning := &FrontmatterError{Err: err}
+	var fe *FrontmatterError
+	if errors.As(err, &fe) {
+		warning.Line = fe.Line
+	}
+	body := content[loc[1]:]
+
+	data := FrontmatterData{Raw: map[string]any{}}
+	var kept, dropped []string
+	for _, block := range frontmatterBlocks(content[loc[2]:loc[3]]) {
+		key, _, _ := strings.Cut(block, ":")
+		key = strings.TrimSpace(key)
+		var raw map[string]any
+		var fields FrontmatterData
+		if yaml.Unmarshal([]byte(block), &raw) != nil || yaml.Unmarshal([]byte(block), &fields) != nil {
+			dropped = append(dropped, key)
+			continue
+		}
+		for k, v := range raw {
+			data.Raw[k] = v
+		}
+		// Unmarshalling again into data sets only the fields the block has
+		if err := yaml.Unmarshal([]byte(block), &data); err != nil {
+			dropped = append(dropped, key)
+			continue
+		}
+		kept = append(kept, key)
+	}
+
+	if len(kept) == 0 {
+		warning.Err = fmt.Errorf("ignored malformed frontmatter: %w", err)
+		return nil, body, warning
+	}
+	msg := "recovered frontmatter keys " + strings.Join(kept, ", ")
+	if len(dropped) > 0 {
+		msg += ", dropped " + strings.Join(dropped, ", ")
+	}
+	warning.Err = fmt.Errorf("%s: %w", msg, err)
+	if data.Tags == nil {
+		data.Tags = []string{}
+	}
+	if data.Related == nil {
+		data.Related = []string{}
+	}
+	if data.References == nil {
+		data.References = []string{}
+	}
+	return &data, body, warning
+}
+
+// frontmatterBlocks splits YAML into top-level entries: a line starting in
+// the first column, other than a list item or comment, starts an entry.
+// Lines before the first entry are dropped.
+func frontmatterBlocks(yamlContent string) []string {
+	var blocks []string
+	var cur strings.Builder
+	for _, line := range strings.SplitAfter(yamlContent, "\n") {
+		top := line != "" && !strings.ContainsRune(" \t\r\n-#", rune(line[0]))
+		if top && cur.Len() > 0 {
+			blocks = append(blocks, cur.String())
+			cur.Reset()
+		}
+		if top || cur.Len() > 0 {
+			cur.WriteString(line)
+		}
+	}
+	if cur.Len() > 0 {
+		blocks = append(blocks, cur.String())
+	}
+	return blocks
+}
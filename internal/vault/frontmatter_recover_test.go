@@ -0,0 +1,49 @@
+package vault
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessContent_LenientFrontmatter(t *testing.T) {
+	content := "---\nid: note\ntitle: Broken: colon\ntags:\n  - a\n  - b\n---\n# Body\n[[Other]]\n"
+
+	// Strict mode fails the file
+	_, err := ProcessMarkdownReader(strings.NewReader(content), "note.md")
+	var fe *FrontmatterError
+	require.ErrorAs(t, err, &fe)
+	assert.Equal(t, 3, fe.Line)
+
+	opts := ParseOptions{LenientFrontmatter: true}
+	file, err := ProcessMarkdownReaderWithOptions(strings.NewReader(content), "note.md", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "note", file.GetID())
+	assert.Equal(t, []string{"a", "b"}, file.GetTags())
+	assert.NotContains(t, file.Frontmatter.Raw, "title")
+	assert.Equal(t, "note", file.Title, "falls back to the filename")
+	require.Len(t, file.Links, 1)
+	require.Len(t, file.Warnings, 1)
+	assert.Contains(t, file.Warnings[0].Error(), "recovered frontmatter keys id, tags, dropped title")
+	require.True(t, errors.As(file.Warnings[0], &fe))
+	assert.Equal(t, 3, fe.Line)
+
+	// With no key to keep, the file has no frontmatter
+	file, err = ProcessMarkdownReaderWithOptions(strings.NewReader("---\n: : :\n---\nBody\n"), "x.md", opts)
+	require.NoError(t, err)
+	assert.Nil(t, file.Frontmatter)
+	require.Len(t, file.Warnings, 1)
+	assert.Contains(t, file.Warnings[0].Error(), "ignored malformed frontmatter")
+
+	// A missing id is still an error
+	_, err = ProcessMarkdownReaderWithOptions(strings.NewReader("---\ntags: [a]\n---\n"), "y.md", opts)
+	assert.Error(t, err)
+}
+
+func TestFrontmatterBlocks(t *testing.T) {
+	blocks := frontmatterBlocks("# comment\nid: a\ntags:\n- x\n- y\nnested:\n  k: v\n")
+	assert.Equal(t, []string{"id: a\n", "tags:\n- x\n- y\n", "nested:\n  k: v\n"}, blocks)
+}
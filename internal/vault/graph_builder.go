@@ -2,9 +2,14 @@
 package vault
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"path"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/ali01/mnemosyne/internal/models"
@@ -40,6 +45,39 @@ type GraphBuilderConfig struct {
 	// should be excluded from the final graph. When true, isolated nodes are filtered out,
 	// which can significantly reduce graph size for visualization purposes.
 	SkipOrphans bool
+
+	// DecayHalfLife, when positive, halves an edge's weight for each half-life
+	// its source note has gone unmodified, so links in stale notes draw
+	// thinner. Zero disables decay.
+	DecayHalfLife time.Duration
+
+	// NormalizeWeights scales edge weights so the heaviest edge weighs 1.
+	// It runs after decay.
+	NormalizeWeights bool
+
+	// Now is the time note ages are measured at for decay. Zero means the
+	// time the graph is built.
+	Now time.Time
+
+	// ComputedFields are evaluated for every node and stored in its metadata.
+	// See CompileComputedFields.
+	ComputedFields []ComputedField
+
+	// HubTypes are the node types of the notes at the top of the graph, at
+	// level 1; every other note's level counts the links from the nearest.
+	// Nil means DefaultHubTypes.
+	HubTypes []string
+
+	// UnlinkedMentions records on each note the notes it names in plain
+	// text without linking to them, as potential edges. See
+	// UnlinkedMentionType.
+	UnlinkedMentions bool
+
+	// Hooks receive the finished graph via OnGraphBuilt.
+	Hooks []ParserHook
+
+	// Logger receives what building logs. Nil means the standard logger.
+	Logger *slog.Logger
 }
 
 // DuplicateID represents a file ID that appears in multiple vault files.
@@ -126,6 +164,9 @@ func NewGraphBuilder(config GraphBuilderConfig) *GraphBuilder {
 	if config.DefaultWeight <= 0 {
 		config.DefaultWeight = 1.0
 	}
+	if config.HubTypes == nil {
+		config.HubTypes = DefaultHubTypes
+	}
 	return &GraphBuilder{config: config}
 }
 
@@ -148,6 +189,12 @@ func NewGraphBuilder(config GraphBuilderConfig) *GraphBuilder {
 //
 // Returns an error if parseResult is nil or if critical errors occur during processing.
 func (gb *GraphBuilder) BuildGraph(parseResult *ParseResult) (*Graph, error) {
+	return gb.BuildGraphContext(context.Background(), parseResult)
+}
+
+// BuildGraphContext is BuildGraph with cancellation: it stops and returns
+// ctx's error once ctx is done.
+func (gb *GraphBuilder) BuildGraphContext(ctx context.Context, parseResult *ParseResult) (*Graph, error) {
 	if parseResult == nil {
 		return nil, fmt.Errorf("parseResult cannot be nil")
 	}
@@ -155,20 +202,29 @@ func (gb *GraphBuilder) BuildGraph(parseResult *ParseResult) (*Graph, error) {
 	startTime := time.Now()
 	stats := &GraphStats{}
 
-	log.Printf("Building graph from %d parsed files...", len(parseResult.Files))
+	logf(gb.config.Logger, "Building graph from %d parsed files...", len(parseResult.Files))
 
 	// Pass 1: Build nodes from files
-	nodeMap, linkMap, duplicatesMap, err := gb.buildNodes(parseResult.Files, stats)
+	nodeMap, linkMap, duplicatesMap, err := gb.buildNodes(ctx, parseResult.Files, stats)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build nodes from %d files: %w", len(parseResult.Files), err)
 	}
+	classifyNodes(nodeMap, parseResult.Classifier)
+	gb.addReferenceNodes(nodeMap, parseResult.References, stats)
+	gb.addPersonNodes(nodeMap, linkMap, parseResult, stats)
 
 	// Pass 2: Build edges from links
-	edges, err := gb.buildEdges(nodeMap, linkMap, parseResult, stats)
+	edges, err := gb.buildEdges(ctx, nodeMap, linkMap, parseResult, stats)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build edges from %d nodes: %w", len(nodeMap), err)
 	}
 
+	gb.weighEdges(edges, nodeMap, startTime)
+	assignLevels(nodeMap, edges, gb.config.HubTypes)
+	if gb.config.UnlinkedMentions {
+		addUnlinkedMentions(nodeMap, edges)
+	}
+
 	// Calculate final statistics and prepare result
 	result := gb.finalizeResult(nodeMap, edges, parseResult.UnresolvedLinks, duplicatesMap, stats)
 
@@ -176,14 +232,18 @@ func (gb *GraphBuilder) BuildGraph(parseResult *ParseResult) (*Graph, error) {
 	stats.BuildDurationMS = duration.Milliseconds()
 	result.Stats = *stats
 
-	log.Printf("Graph building completed in %v", duration)
-	log.Printf("Created: %d nodes, %d edges | Skipped: %d files | Orphaned: %d nodes",
+	if err := runGraphBuilt(gb.config.Hooks, result); err != nil {
+		return nil, err
+	}
+
+	logf(gb.config.Logger, "Graph building completed in %v", duration)
+	logf(gb.config.Logger, "Created: %d nodes, %d edges | Skipped: %d files | Orphaned: %d nodes",
 		stats.NodesCreated, stats.EdgesCreated, stats.FilesSkipped, stats.OrphanedNodes)
 
 	// Log unresolved links if any
 	totalUnresolved := len(parseResult.UnresolvedLinks) + stats.UnresolvedLinks
 	if totalUnresolved > 0 {
-		log.Printf("Unresolved links: %d to non-existent files, %d to files without IDs",
+		logf(gb.config.Logger, "Unresolved links: %d to non-existent files, %d to files without IDs",
 			len(parseResult.UnresolvedLinks), stats.UnresolvedLinks)
 	}
 
@@ -195,7 +255,7 @@ func (gb *GraphBuilder) BuildGraph(parseResult *ParseResult) (*Graph, error) {
 // If made concurrent in the future, the duplicate detection logic would need
 // synchronization to prevent race conditions.
 // Returns: nodeMap (ID -> VaultNode), linkMap (ID -> outgoing links), duplicatesMap
-func (gb *GraphBuilder) buildNodes(files map[string]*MarkdownFile, stats *GraphStats) (
+func (gb *GraphBuilder) buildNodes(ctx context.Context, files map[string]*MarkdownFile, stats *GraphStats) (
 	map[string]*models.VaultNode,
 	map[string][]WikiLink,
 	map[string]*DuplicateID,
@@ -207,6 +267,10 @@ func (gb *GraphBuilder) buildNodes(files map[string]*MarkdownFile, stats *GraphS
 	seenIDs := make(map[string]string)             // ID -> path mapping for first occurrence
 
 	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, nil, err
+		}
+
 		// Get ID from file
 		id := ""
 		if file.Frontmatter != nil {
@@ -234,7 +298,7 @@ func (gb *GraphBuilder) buildNodes(files map[string]*MarkdownFile, stats *GraphS
 				duplicatesMap[id] = dup
 			}
 			dup.SkippedPaths = append(dup.SkippedPaths, file.Path)
-			log.Printf("Warning: Duplicate ID '%s' found in files '%s' and '%s'. Keeping first occurrence.",
+			warnf(gb.config.Logger, "Duplicate ID '%s' found in files '%s' and '%s'. Keeping first occurrence.",
 				id, existingPath, file.Path)
 			continue
 		}
@@ -245,7 +309,7 @@ func (gb *GraphBuilder) buildNodes(files map[string]*MarkdownFile, stats *GraphS
 		if err != nil {
 			// Log error but continue processing other files
 			stats.FilesSkipped++
-			log.Printf("Warning: Failed to create node from file '%s' (ID: %s): %v", file.Path, id, err)
+			warnf(gb.config.Logger, "Failed to create node from file '%s' (ID: %s): %v", file.Path, id, err)
 			continue
 		}
 
@@ -258,8 +322,91 @@ func (gb *GraphBuilder) buildNodes(files map[string]*MarkdownFile, stats *GraphS
 	return nodeMap, linkMap, duplicatesMap, nil
 }
 
+// addReferenceNodes adds a node for each BibTeX entry, so citations have
+// targets. A reference whose ID is already taken by a note is skipped.
+func (gb *GraphBuilder) addReferenceNodes(nodeMap map[string]*models.VaultNode, refs map[string]*Reference, stats *GraphStats) {
+	for _, ref := range refs {
+		id := ReferenceID(ref.Key)
+		if existing, exists := nodeMap[id]; exists {
+			warnf(gb.config.Logger, "Reference '%s' in '%s' has the same ID as '%s'. Skipping reference.",
+				ref.Key, ref.Path, existing.FilePath)
+			continue
+		}
+		nodeMap[id] = gb.createReferenceNode(ref)
+		stats.NodesCreated++
+	}
+}
+
+// addPersonNodes gives the notes in the people directory PersonNodeType,
+// unless they have a type, and adds a node for each person mentioned without
+// a note. Such a node's file path is where the note would be, without .md,
+// e.g. "people/jane-doe", and its timestamps span the mentioning notes'.
+func (gb *GraphBuilder) addPersonNodes(
+	nodeMap map[string]*models.VaultNode,
+	linkMap map[string][]WikiLink,
+	parseResult *ParseResult,
+	stats *GraphStats,
+) {
+	if parseResult.Mentions == nil {
+		return
+	}
+	for _, id := range parseResult.People {
+		if node, ok := nodeMap[id]; ok && node.NodeType == "" {
+			node.NodeType = PersonNodeType
+		}
+	}
+
+	people := make(map[string]*models.VaultNode)
+	for sourceID, links := range linkMap {
+		source := nodeMap[sourceID]
+		for _, link := range links {
+			if link.LinkType != MentionLinkType {
+				continue
+			}
+			id, _ := parseResult.ResolveLink(link, source.FilePath)
+			if id != PersonID(link.Target) {
+				continue
+			}
+			name := path.Base(link.Target)
+			node, ok := people[id]
+			if !ok {
+				if _, exists := nodeMap[id]; exists {
+					continue
+				}
+				node = &models.VaultNode{
+					ID:        id,
+					Title:     name,
+					NodeType:  PersonNodeType,
+					Tags:      []string{},
+					FilePath:  path.Join(parseResult.Mentions.PeopleDir, strings.TrimPrefix(id, "person:")),
+					CreatedAt: source.UpdatedAt,
+					UpdatedAt: source.UpdatedAt,
+				}
+				people[id] = node
+				nodeMap[id] = node
+				stats.NodesCreated++
+				continue
+			}
+			// Spellings differ, e.g. @Jane-Doe and @jane-doe; pick one stably
+			if name < node.Title {
+				node.Title = name
+			}
+			if source.UpdatedAt.Before(node.CreatedAt) {
+				node.CreatedAt = source.UpdatedAt
+			}
+			if source.UpdatedAt.After(node.UpdatedAt) {
+				node.UpdatedAt = source.UpdatedAt
+			}
+		}
+	}
+	for _, node := range people {
+		applyComputedFields(node, gb.config.ComputedFields, gb.config.Logger)
+	}
+}
+
 // buildEdges creates VaultEdge objects from WikiLinks (Pass 2)
 func (gb *GraphBuilder) buildEdges(
+	ctx context.Context,
 	nodeMap map[string]*models.VaultNode,
 	linkMap map[string][]WikiLink,
 	parseResult *ParseResult,
@@ -270,6 +417,9 @@ func (gb *GraphBuilder) buildEdges(
 	inDegreeMap := make(map[string]int) // Track in-degrees separately
 
 	for sourceID, links := range linkMap {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		sourceNode := nodeMap[sourceID]
 		outDegree := 0
 
@@ -277,7 +427,7 @@ func (gb *GraphBuilder) buildEdges(
 			// Resolve the target node
 			// Get source file path from node
 			sourceFilePath := sourceNode.FilePath
-			targetID, found := parseResult.Resolver.ResolveLink(link.Target, sourceFilePath)
+			targetID, found := parseResult.ResolveLink(link, sourceFilePath)
 			if !found {
 				// This link was already counted as unresolved during parsing
 				continue
@@ -295,7 +445,7 @@ func (gb *GraphBuilder) buildEdges(
 			edge, err := gb.createEdge(sourceID, targetID, link, sourceNode.UpdatedAt)
 			if err != nil {
 				// Log error but continue
-				log.Printf("Warning: Failed to create edge from '%s' to '%s' (link: %s): %v",
+				warnf(gb.config.Logger, "Failed to create edge from '%s' to '%s' (link: %s): %v",
 					sourceID, targetID, link.Target, err)
 				continue
 			}
@@ -329,6 +479,35 @@ func (gb *GraphBuilder) buildEdges(
 	return edges, nil
 }
 
+// weighEdges decays and normalizes edge weights as configured.
+func (gb *GraphBuilder) weighEdges(edges []models.VaultEdge, nodeMap map[string]*models.VaultNode, buildTime time.Time) {
+	if gb.config.DecayHalfLife > 0 {
+		now := gb.config.Now
+		if now.IsZero() {
+			now = buildTime
+		}
+		halfLife := float64(gb.config.DecayHalfLife)
+		for i := range edges {
+			age := now.Sub(nodeMap[edges[i].SourceID].UpdatedAt)
+			if age > 0 {
+				edges[i].Weight *= math.Exp2(-float64(age) / halfLife)
+			}
+		}
+	}
+
+	if gb.config.NormalizeWeights {
+		heaviest := 0.0
+		for _, e := range edges {
+			heaviest = max(heaviest, e.Weight)
+		}
+		if heaviest > 0 {
+			for i := range edges {
+				edges[i].Weight /= heaviest
+			}
+		}
+	}
+}
+
 // createNode creates a VaultNode from a MarkdownFile
 func (gb *GraphBuilder) createNode(file *MarkdownFile, id string) (*models.VaultNode, error) {
 	title := file.Title
@@ -352,23 +531,87 @@ func (gb *GraphBuilder) createNode(file *MarkdownFile, id string) (*models.Vault
 	}
 
 	node := &models.VaultNode{
-		ID:         id,
-		Title:      title,
-		NodeType:   nodeType,
-		Tags:       tags,
-		Content:    file.Content,
-		Metadata:   metadata,
-		FilePath:   file.Path,
-		InDegree:   0, // Will be calculated in edge building
-		OutDegree:  0, // Will be calculated in edge building
-		Centrality: 0, // Will be calculated by metrics calculator
-		CreatedAt:  createdAt,
-		UpdatedAt:  modifiedAt,
+		ID:          id,
+		Title:       title,
+		NodeType:    nodeType,
+		Tags:        tags,
+		Content:     file.Content,
+		Metadata:    metadata,
+		Outline:     file.Outline,
+		FilePath:    file.Path,
+		InDegree:    0, // Will be calculated in edge building
+		OutDegree:   0, // Will be calculated in edge building
+		WordCount:   file.WordCount,
+		ReadingTime: ReadingTime(file.WordCount),
+		Excerpt:     file.Excerpt,
+		URLs:        file.URLs,
+		Centrality:  0, // Will be calculated by metrics calculator
+		CreatedAt:   createdAt,
+		UpdatedAt:   modifiedAt,
 	}
 
+	applyComputedFields(node, gb.config.ComputedFields, gb.config.Logger)
+
 	return node, nil
 }
 
+// createReferenceNode creates a VaultNode from a BibTeX entry. Its file path
+// is the .bib file's with the citekey as fragment, e.g. "refs.bib#doe99",
+// and its metadata holds the entry's fields.
+func (gb *GraphBuilder) createReferenceNode(ref *Reference) *models.VaultNode {
+	metadata := models.JSONMetadata{
+		"citekey":    ref.Key,
+		"entry_type": ref.Type,
+	}
+	for k, v := range ref.Fields {
+		metadata[k] = v
+	}
+
+	node := &models.VaultNode{
+		ID:        ReferenceID(ref.Key),
+		Title:     ref.Title(),
+		NodeType:  ReferenceNodeType,
+		Tags:      []string{},
+		Content:   ref.Raw,
+		Metadata:  metadata,
+		FilePath:  ref.Path + "#" + ref.Key,
+		CreatedAt: ref.ModTime,
+		UpdatedAt: ref.ModTime,
+	}
+
+	applyComputedFields(node, gb.config.ComputedFields, gb.config.Logger)
+
+	return node
+}
+
+// classifyNodes gives the notes the node types of the classifier's rules.
+func classifyNodes(nodeMap map[string]*models.VaultNode, classifier *Classifier) {
+	for _, node := range nodeMap {
+		node.NodeType = classifier.Type(node.FilePath, node.Tags)
+	}
+}
+
+// BaseNodeType returns the type the graph builder gives a stored node before
+// hooks classify it: BibTeX entries are references, mentioned people are
+// persons when mentions are on, notes matching a classification rule have
+// its type, other notes in the people directory are persons, and the rest
+// have none.
+func BaseNodeType(n *models.VaultNode, mentions *MentionExtractor, classifier *Classifier) string {
+	if i := strings.LastIndex(n.FilePath, "#"); i >= 0 && n.ID == ReferenceID(n.FilePath[i+1:]) {
+		return ReferenceNodeType
+	}
+	if mentions != nil && strings.HasPrefix(n.ID, "person:") {
+		return PersonNodeType
+	}
+	if t := classifier.Type(n.FilePath, n.Tags); t != "" {
+		return t
+	}
+	if mentions != nil && mentions.IsPersonNote(n.FilePath) {
+		return PersonNodeType
+	}
+	return ""
+}
+
 // validateEdgeIDs validates that both source and target IDs are non-empty
 func validateEdgeIDs(sourceID, targetID string, link WikiLink) error {
 	if sourceID == "" {
@@ -422,6 +665,13 @@ func (gb *GraphBuilder) finalizeResult(
 	duplicatesMap map[string]*DuplicateID,
 	stats *GraphStats,
 ) *Graph {
+	// Record each source's unresolved targets on its node, once each
+	for _, link := range unresolvedLinks {
+		if node, ok := nodeMap[link.SourceID]; ok && !slices.Contains(node.Unresolved, link.Link.Target) {
+			node.Unresolved = append(node.Unresolved, link.Link.Target)
+		}
+	}
+
 	// Extract nodes from map
 	nodes := make([]models.VaultNode, 0, len(nodeMap))
 	for _, node := range nodeMap {
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -128,6 +129,114 @@ func TestBuildGraph_Simple(t *testing.T) {
 	assert.Equal(t, 0, result.Stats.OrphanedNodes)
 }
 
+func TestBuildGraph_ComputedFields(t *testing.T) {
+	fields, err := CompileComputedFields(map[string]string{
+		"maturity": `metadata.reviews >= 3 ? "evergreen" : "seedling"`,
+		"broken":   `metadata.reviews / 0`,
+	})
+	require.NoError(t, err)
+	gb := NewGraphBuilder(GraphBuilderConfig{ComputedFields: fields})
+
+	mature := createTestMarkdownFile("a.md", "a", "A", nil, nil)
+	mature.Frontmatter.Raw["reviews"] = 5
+	young := createTestMarkdownFile("b.md", "b", "B", nil, nil)
+
+	resolver := NewLinkResolver()
+	resolver.AddFile(mature)
+	resolver.AddFile(young)
+
+	result, err := gb.BuildGraph(&ParseResult{
+		Files:    map[string]*MarkdownFile{"a": mature, "b": young},
+		Resolver: resolver,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "evergreen", findNodeByID(result.Nodes, "a").Metadata["maturity"])
+	assert.Equal(t, "seedling", findNodeByID(result.Nodes, "b").Metadata["maturity"])
+	assert.NotContains(t, findNodeByID(result.Nodes, "a").Metadata, "broken")
+}
+
+func TestBuildGraph_EdgeWeightDecayAndNormalization(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	halfLife := 30 * 24 * time.Hour
+
+	fresh := createTestMarkdownFile("fresh.md", "fresh", "Fresh", nil, []WikiLink{{Target: "stale", LinkType: "wikilink"}})
+	fresh.FileInfo = &testFileInfo{modTime: now}
+	stale := createTestMarkdownFile("stale.md", "stale", "Stale", nil, []WikiLink{{Target: "fresh", LinkType: "wikilink"}})
+	stale.FileInfo = &testFileInfo{modTime: now.Add(-2 * halfLife)}
+
+	resolver := NewLinkResolver()
+	resolver.AddFile(fresh)
+	resolver.AddFile(stale)
+	parsed := &ParseResult{
+		Files:    map[string]*MarkdownFile{"fresh": fresh, "stale": stale},
+		Resolver: resolver,
+	}
+	weights := func(cfg GraphBuilderConfig) map[string]float64 {
+		graph, err := NewGraphBuilder(cfg).BuildGraph(parsed)
+		require.NoError(t, err)
+		w := make(map[string]float64)
+		for _, e := range graph.Edges {
+			w[e.SourceID] = e.Weight
+		}
+		return w
+	}
+
+	assert.Equal(t, map[string]float64{"fresh": 2, "stale": 2}, weights(GraphBuilderConfig{DefaultWeight: 2}))
+
+	decayed := weights(GraphBuilderConfig{DefaultWeight: 2, DecayHalfLife: halfLife, Now: now})
+	assert.InDelta(t, 2, decayed["fresh"], 1e-9)
+	assert.InDelta(t, 0.5, decayed["stale"], 1e-9, "two half-lives")
+
+	normalized := weights(GraphBuilderConfig{DefaultWeight: 2, DecayHalfLife: halfLife, Now: now, NormalizeWeights: true})
+	assert.InDelta(t, 1, normalized["fresh"], 1e-9)
+	assert.InDelta(t, 0.25, normalized["stale"], 1e-9)
+}
+
+func TestBuildGraph_Levels(t *testing.T) {
+	link := func(target string) []WikiLink { return []WikiLink{{Target: target, LinkType: "wikilink"}} }
+	files := []*MarkdownFile{
+		createTestMarkdownFile("index.md", "index", "Index", []string{"index"}, link("a")),
+		createTestMarkdownFile("a.md", "a", "A", nil, link("b")),
+		createTestMarkdownFile("b.md", "b", "B", nil, nil),
+		createTestMarkdownFile("c.md", "c", "C", nil, link("b")),
+		// Apart from the rest
+		createTestMarkdownFile("x.md", "x", "X", nil, link("y")),
+		createTestMarkdownFile("y.md", "y", "Y", nil, nil),
+		createTestMarkdownFile("lone.md", "lone", "Lone", nil, nil),
+	}
+	resolver := NewLinkResolver()
+	parsed := &ParseResult{
+		Files:      make(map[string]*MarkdownFile),
+		Resolver:   resolver,
+		Classifier: newClassifier(DirConfig{Classify: []ClassifyRule{{Tag: "index", Type: "index"}}}),
+	}
+	for _, f := range files {
+		resolver.AddFile(f)
+		parsed.Files[f.Frontmatter.ID] = f
+	}
+	levels := func(cfg GraphBuilderConfig) map[string]int {
+		graph, err := NewGraphBuilder(cfg).BuildGraph(parsed)
+		require.NoError(t, err)
+		l := make(map[string]int)
+		for _, n := range graph.Nodes {
+			l[n.ID] = n.Level
+		}
+		return l
+	}
+
+	// Links count in either direction; parts without a hub hang from their
+	// most linked note
+	assert.Equal(t, map[string]int{"index": 1, "a": 2, "b": 3, "c": 4, "x": 2, "y": 1, "lone": 1}, levels(GraphBuilderConfig{}))
+	assert.Equal(t, map[string]int{"index": 3, "a": 2, "b": 1, "c": 2, "x": 2, "y": 1, "lone": 1},
+		levels(GraphBuilderConfig{HubTypes: []string{"moc"}}))
+}
+
+func TestCompileComputedFields_Invalid(t *testing.T) {
+	_, err := CompileComputedFields(map[string]string{"bad": "1 +"})
+	assert.Error(t, err)
+}
+
 func TestBuildGraph_WithDuplicateIDs(t *testing.T) {
 	// Test that duplicate detection works properly
 	// This simulates a scenario where the parser might have multiple files with same ID
@@ -142,7 +251,7 @@ func TestBuildGraph_WithDuplicateIDs(t *testing.T) {
 
 	// Create a custom test that processes files in order
 	stats := &GraphStats{}
-	nodeMap, _, duplicatesMap, err := gb.buildNodes(files, stats)
+	nodeMap, _, duplicatesMap, err := gb.buildNodes(context.Background(), files, stats)
 	require.NoError(t, err)
 
 	// Should have detected the duplicate
@@ -232,6 +341,40 @@ func TestBuildGraph_WithUnresolvedLinks(t *testing.T) {
 	assert.Len(t, result.UnresolvedLinks, 1) // Parser unresolved links are preserved in Graph
 }
 
+func TestBuildGraph_NodeUnresolvedTargets(t *testing.T) {
+	gb := NewGraphBuilder(GraphBuilderConfig{})
+
+	file1 := createTestMarkdownFile("note.md", "note", "Note", nil, []WikiLink{
+		{Target: "existing", LinkType: "wikilink"},
+		{Target: "missing", LinkType: "wikilink"},
+	})
+	file2 := createTestMarkdownFile("existing.md", "existing", "Existing", nil, nil)
+
+	resolver := NewLinkResolver()
+	resolver.AddFile(file1)
+	resolver.AddFile(file2)
+
+	result, err := gb.BuildGraph(&ParseResult{
+		Files: map[string]*MarkdownFile{
+			"note":     file1,
+			"existing": file2,
+		},
+		Resolver: resolver,
+		UnresolvedLinks: []UnresolvedLink{
+			{
+				SourceID:   "note",
+				SourcePath: "note.md",
+				Link:       WikiLink{Target: "missing", LinkType: "wikilink"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// Each node lists the targets of its links that did not resolve
+	assert.Equal(t, models.StringArray{"missing"}, findNodeByID(result.Nodes, "note").Unresolved)
+	assert.Empty(t, findNodeByID(result.Nodes, "existing").Unresolved)
+}
+
 func TestBuildGraph_WithGraphExcludedLinks(t *testing.T) {
 	// Test links to files that exist but aren't in the graph (no ID)
 	gb := NewGraphBuilder(GraphBuilderConfig{})
@@ -721,3 +864,19 @@ func (m *testFileInfo) ModTime() time.Time { return m.modTime }
 func (m *testFileInfo) IsDir() bool        { return false }
 func (m *testFileInfo) Sys() interface{}   { return nil }
 
+
+func TestBuildGraph_Cancelled(t *testing.T) {
+	gb := NewGraphBuilder(GraphBuilderConfig{})
+	file := createTestMarkdownFile("index.md", "index", "Index", []string{}, nil)
+	resolver := NewLinkResolver()
+	resolver.AddFile(file)
+	parseResult := &ParseResult{
+		Files:    map[string]*MarkdownFile{"index": file},
+		Resolver: resolver,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := gb.BuildGraphContext(ctx, parseResult)
+	require.ErrorIs(t, err, context.Canceled)
+}
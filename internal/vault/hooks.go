@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// ParserHook lets external code take part in parsing and indexing without
+// modifying this package, e.g. to extract citations, people, or locations into
+// node metadata. Embed NopHook to implement only the methods you need.
+//
+// Hooks run in registration order. Returning an error from OnFileParsed
+// records a parse error for that file, as does returning FileErrors from
+// OnBeforeStore; other errors abort the build or store operation.
+type ParserHook interface {
+	// OnFileParsed is called after each markdown file is read and parsed,
+	// before link resolution. It may be called concurrently from several
+	// parser workers and must be safe for concurrent use.
+	OnFileParsed(file *MarkdownFile) error
+
+	// OnGraphBuilt is called once the graph builder has produced nodes and edges.
+	OnGraphBuilt(graph *Graph) error
+
+	// OnBeforeStore is called with the nodes and edges about to be written to
+	// the database. Elements may be modified in place.
+	OnBeforeStore(nodes []models.VaultNode, edges []models.VaultEdge) error
+}
+
+// NopHook is a ParserHook that does nothing.
+type NopHook struct{}
+
+// OnFileParsed implements ParserHook.
+func (NopHook) OnFileParsed(*MarkdownFile) error { return nil }
+
+// OnGraphBuilt implements ParserHook.
+func (NopHook) OnGraphBuilt(*Graph) error { return nil }
+
+// OnBeforeStore implements ParserHook.
+func (NopHook) OnBeforeStore([]models.VaultNode, []models.VaultEdge) error { return nil }
+
+// FileErrors is the error an OnBeforeStore hook returns when it failed only
+// for some files and handled the rest. It does not abort the store: the files
+// are reported as ParseErrorHook errors.
+type FileErrors []ParseError
+
+func (e FileErrors) Error() string {
+	if len(e) == 0 {
+		return "no files failed"
+	}
+	msg := fmt.Sprintf("%s: %v", e[0].FilePath, e[0].Error)
+	if len(e) > 1 {
+		msg += fmt.Sprintf(" (and %d more files)", len(e)-1)
+	}
+	return msg
+}
+
+// RunBeforeStore calls OnBeforeStore on each hook in order, stopping at the
+// first error. FileErrors do not stop it; those of every hook are returned
+// together once all have run.
+func RunBeforeStore(hooks []ParserHook, nodes []models.VaultNode, edges []models.VaultEdge) error {
+	var failed FileErrors
+	for _, h := range hooks {
+		err := h.OnBeforeStore(nodes, edges)
+		var fe FileErrors
+		if errors.As(err, &fe) {
+			failed = append(failed, fe...)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("before-store hook: %w", err)
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+func runFileParsed(hooks []ParserHook, file *MarkdownFile) error {
+	for _, h := range hooks {
+		if err := h.OnFileParsed(file); err != nil {
+			return fmt.Errorf("file-parsed hook: %w", err)
+		}
+	}
+	return nil
+}
+
+func runGraphBuilt(hooks []ParserHook, graph *Graph) error {
+	for _, h := range hooks {
+		if err := h.OnGraphBuilt(graph); err != nil {
+			return fmt.Errorf("graph-built hook: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package vault
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IDRule derives a node ID from a filename when frontmatter has no 'id',
+// e.g. Zettelkasten names like "202301151230 Title.md".
+type IDRule struct {
+	pattern  *regexp.Regexp
+	template string
+}
+
+// NewIDRule compiles a filename ID rule. pattern is matched against the file's
+// base name without the .md extension. template is a regexp expansion
+// template for the ID; if empty, the first capture group is used, or the
+// whole match when there are no groups.
+func NewIDRule(pattern, template string) (*IDRule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("id rule %q: %w", pattern, err)
+	}
+	if template == "" {
+		template = "$0"
+		if re.NumSubexp() > 0 {
+			template = "${1}"
+		}
+	}
+	return &IDRule{pattern: re, template: template}, nil
+}
+
+// Match returns the ID derived from path, if the rule matches.
+func (r *IDRule) Match(path string) (string, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), ".md")
+	match := r.pattern.FindStringSubmatchIndex(name)
+	if match == nil {
+		return "", false
+	}
+	id := strings.TrimSpace(string(r.pattern.ExpandString(nil, r.template, name, match)))
+	return id, id != ""
+}
+
+// idFromFilename applies rules in order and returns the first derived ID.
+func idFromFilename(rules []IDRule, path string) (string, bool) {
+	for i := range rules {
+		if id, ok := rules[i].Match(path); ok {
+			return id, true
+		}
+	}
+	return "", false
+}
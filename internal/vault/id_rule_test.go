@@ -0,0 +1,56 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIDRule_Match(t *testing.T) {
+	zk, err := NewIDRule(`^(\d{12})\b`, "")
+	require.NoError(t, err)
+
+	id, ok := zk.Match("notes/202301151230 Title.md")
+	assert.True(t, ok)
+	assert.Equal(t, "202301151230", id)
+
+	_, ok = zk.Match("notes/Plain Title.md")
+	assert.False(t, ok)
+
+	prefixed, err := NewIDRule(`^(\d{8})-(\w+)`, "zk-$1-$2")
+	require.NoError(t, err)
+	id, ok = prefixed.Match("20230115-idea.md")
+	assert.True(t, ok)
+	assert.Equal(t, "zk-20230115-idea", id)
+
+	_, err = NewIDRule(`(`, "")
+	assert.Error(t, err)
+}
+
+func TestProcessContent_IDRuleFallback(t *testing.T) {
+	rule, err := NewIDRule(`^(\d{12})`, "")
+	require.NoError(t, err)
+	opts := ParseOptions{IDRules: []IDRule{*rule}}
+
+	// No frontmatter at all
+	file, err := ProcessMarkdownReaderWithOptions(strings.NewReader("# Idea\n"), "202301151230 Idea.md", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "202301151230", file.GetID())
+
+	// Frontmatter without id is accepted when rules are configured
+	file, err = ProcessMarkdownReaderWithOptions(strings.NewReader("---\ntags: [a]\n---\nbody"), "202301151231 Tagged.md", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "202301151231", file.GetID())
+	assert.Equal(t, []string{"a"}, file.GetTags())
+
+	// Frontmatter id wins over the filename
+	file, err = ProcessMarkdownReaderWithOptions(strings.NewReader("---\nid: explicit\n---\n"), "202301151232 X.md", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "explicit", file.GetID())
+
+	// Without rules, frontmatter without id is still an error
+	_, err = ProcessMarkdownReader(strings.NewReader("---\ntags: [a]\n---\n"), "202301151233 Y.md")
+	assert.Error(t, err)
+}
@@ -0,0 +1,40 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// largeFileHead is how much of a file over ParseOptions.MaxFileSize is read:
+// enough for its frontmatter.
+const largeFileHead = 64 << 10
+
+// ErrFileTooLarge is the warning on files over ParseOptions.MaxFileSize,
+// which are indexed with their frontmatter only.
+var ErrFileTooLarge = errors.New("file too large")
+
+// tooLarge reports whether the file described by info is over the limit.
+func (o ParseOptions) tooLarge(info os.FileInfo) bool {
+	return o.MaxFileSize > 0 && info.Size() > o.MaxFileSize
+}
+
+// processLargeFile builds the metadata of a file over the size limit from
+// head, its first largeFileHead bytes: ID, title and links from the
+// frontmatter, and no content, so nothing from the body is parsed or
+// stored. The file gets an ErrFileTooLarge warning.
+func processLargeFile(head []byte, fileInfo os.FileInfo, relativePath string, opts ParseOptions) (*MarkdownFile, error) {
+	// The head may end mid-character, so problems decoding it are not the
+	// file's
+	text, _ := prepareContent(head, opts)
+	frontmatter := frontmatterRegexFor(opts.dialect().FrontmatterDelimiter).FindString(text)
+	file, err := processText(frontmatter, nil, fileInfo, relativePath, opts)
+	if err != nil {
+		return nil, err
+	}
+	file.Content = ""
+	file.Outline = nil
+	file.Warnings = append([]error{fmt.Errorf("%w: %d bytes, over the limit of %d; indexed without content",
+		ErrFileTooLarge, fileInfo.Size(), opts.MaxFileSize)}, file.Warnings...)
+	return file, nil
+}
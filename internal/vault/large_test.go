@@ -0,0 +1,54 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+func TestParser_LargeFilesMetadataOnly(t *testing.T) {
+	dir := t.TempDir()
+	log := "---\nid: log\ntitle: Server log\ntags: [ops]\nrelated: [\"[[small]]\"]\n---\n" +
+		strings.Repeat("ERROR [[Not A Link]] line\n", 5000)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "log.md"), []byte(log), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "small.md"), []byte("---\nid: small\n---\nSee [[log]]."), 0o644))
+
+	parser := NewParser(dir, 1, 0)
+	parser.SetOptions(ParseOptions{MaxFileSize: 1024})
+	parser.SetFileCache("s", map[string]models.CachedFile{})
+	result, err := parser.ParseVault()
+	require.NoError(t, err)
+
+	require.Len(t, result.Files, 2)
+	large := result.Files["log"]
+	assert.Equal(t, "Server log", large.Title)
+	assert.Equal(t, []string{"ops"}, large.GetTags())
+	assert.Empty(t, large.Content)
+	assert.Zero(t, large.WordCount)
+	require.Len(t, large.Links, 1, "frontmatter links are kept, body links are not parsed")
+	assert.Equal(t, "small", large.Links[0].Target)
+	assert.Equal(t, int64(len(log)), large.FileInfo.Size())
+	assert.Equal(t, "See [[log]].", StripFrontmatter(result.Files["small"].Content))
+	assert.Equal(t, 1, result.Stats.LargeFiles)
+
+	require.Len(t, result.ParseErrors, 1)
+	assert.Equal(t, "log.md", result.ParseErrors[0].FilePath)
+	assert.Equal(t, ParseErrorSize, result.ParseErrors[0].Kind)
+	assert.ErrorIs(t, result.ParseErrors[0].Error, ErrFileTooLarge)
+	require.Len(t, result.Cache, 1, "large files are not cached")
+	assert.Equal(t, "small.md", result.Cache[0].Path)
+
+	// Without a limit the whole file is parsed
+	file, err := ProcessMarkdownFileWithOptions(dir, "log.md", ParseOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, log, file.Content)
+	file, err = ProcessMarkdownFileWithOptions(dir, "log.md", ParseOptions{MaxFileSize: 1024})
+	require.NoError(t, err)
+	assert.Empty(t, file.Content)
+}
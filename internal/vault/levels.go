@@ -0,0 +1,77 @@
+package vault
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// DefaultHubTypes are the node types whose notes are at level 1 when
+// GraphBuilderConfig.HubTypes is nil.
+var DefaultHubTypes = []string{"index", "hub"}
+
+// AssignLevels sets the Level of each of nodes as BuildGraph does. Call it
+// again after hooks change node types, since they decide which notes are
+// hubs. Nil hubTypes means DefaultHubTypes.
+func AssignLevels(nodes []models.VaultNode, edges []models.VaultEdge, hubTypes []string) {
+	if hubTypes == nil {
+		hubTypes = DefaultHubTypes
+	}
+	nodeMap := make(map[string]*models.VaultNode, len(nodes))
+	for i := range nodes {
+		nodeMap[nodes[i].ID] = &nodes[i]
+	}
+	assignLevels(nodeMap, edges, hubTypes)
+}
+
+// assignLevels sets each node's Level to one more than its distance, over
+// links in either direction, from the nearest node with one of hubTypes. In
+// parts of the graph no hub reaches, the node with the most incoming links
+// (the first by ID on a tie) stands in for one, so every node gets a level.
+func assignLevels(nodeMap map[string]*models.VaultNode, edges []models.VaultEdge, hubTypes []string) {
+	neighbors := make(map[string][]string, len(nodeMap))
+	for _, e := range edges {
+		neighbors[e.SourceID] = append(neighbors[e.SourceID], e.TargetID)
+		neighbors[e.TargetID] = append(neighbors[e.TargetID], e.SourceID)
+	}
+
+	ids := make([]string, 0, len(nodeMap))
+	for id, n := range nodeMap {
+		n.Level = 0
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	// Breadth-first from every hub at once, then from a stand-in for each
+	// part left over, most linked first
+	var queue []string
+	spread := func() {
+		for ; len(queue) > 0; queue = queue[1:] {
+			n := nodeMap[queue[0]]
+			for _, id := range neighbors[n.ID] {
+				if next, ok := nodeMap[id]; ok && next.Level == 0 {
+					next.Level = n.Level + 1
+					queue = append(queue, id)
+				}
+			}
+		}
+	}
+	for _, id := range ids {
+		if n := nodeMap[id]; slices.Contains(hubTypes, n.NodeType) {
+			n.Level = 1
+			queue = append(queue, id)
+		}
+	}
+	spread()
+	slices.SortStableFunc(ids, func(a, b string) int {
+		return cmp.Compare(nodeMap[b].InDegree, nodeMap[a].InDegree)
+	})
+	for _, id := range ids {
+		if n := nodeMap[id]; n.Level == 0 {
+			n.Level = 1
+			queue = append(queue, id)
+			spread()
+		}
+	}
+}
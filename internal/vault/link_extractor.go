@@ -0,0 +1,56 @@
+package vault
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// LinkExtractor turns matches of a custom link syntax (e.g. `@person-name` or
+// `cite:key`) into typed links that are resolved like wikilinks.
+type LinkExtractor struct {
+	// EdgeType is the LinkType given to extracted links and the resulting edges.
+	EdgeType string
+
+	pattern *regexp.Regexp
+	target  string
+}
+
+// NewLinkExtractor compiles a link extractor. target is a regexp expansion
+// template producing the link target, e.g. "people/$1"; if empty, the first
+// capture group is used, or the whole match when there are no groups.
+func NewLinkExtractor(pattern, edgeType, target string) (*LinkExtractor, error) {
+	if edgeType == "" {
+		return nil, fmt.Errorf("link extractor %q: edge type is required", pattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("link extractor %q: %w", edgeType, err)
+	}
+	if target == "" {
+		target = "$0"
+		if re.NumSubexp() > 0 {
+			target = "${1}"
+		}
+	}
+	return &LinkExtractor{EdgeType: edgeType, pattern: re, target: target}, nil
+}
+
+// Extract returns a link for each match in content. Matches whose target
+// expands to an empty string are skipped.
+func (e *LinkExtractor) Extract(content string) []WikiLink {
+	matches := e.pattern.FindAllStringSubmatchIndex(content, -1)
+	links := make([]WikiLink, 0, len(matches))
+	for _, match := range matches {
+		target := string(e.pattern.ExpandString(nil, e.target, content, match))
+		if target == "" {
+			continue
+		}
+		links = append(links, WikiLink{
+			Raw:      content[match[0]:match[1]],
+			Target:   target,
+			LinkType: e.EdgeType,
+			Position: match[0],
+		})
+	}
+	return links
+}
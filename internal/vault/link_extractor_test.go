@@ -0,0 +1,56 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkExtractor_Extract(t *testing.T) {
+	people, err := NewLinkExtractor(`@([a-z][a-z-]+)`, "mention", "people/$1")
+	require.NoError(t, err)
+
+	links := people.Extract("Met @jane-doe and @bob today.")
+	require.Len(t, links, 2)
+	assert.Equal(t, "people/jane-doe", links[0].Target)
+	assert.Equal(t, "mention", links[0].LinkType)
+	assert.Equal(t, "@jane-doe", links[0].Raw)
+	assert.Equal(t, 4, links[0].Position)
+	assert.Equal(t, "people/bob", links[1].Target)
+}
+
+func TestLinkExtractor_DefaultTarget(t *testing.T) {
+	cite, err := NewLinkExtractor(`cite:(\w+)`, "citation", "")
+	require.NoError(t, err)
+	links := cite.Extract("As shown in cite:knuth84.")
+	require.Len(t, links, 1)
+	assert.Equal(t, "knuth84", links[0].Target)
+
+	whole, err := NewLinkExtractor(`TODO`, "todo", "")
+	require.NoError(t, err)
+	links = whole.Extract("TODO fix")
+	require.Len(t, links, 1)
+	assert.Equal(t, "TODO", links[0].Target)
+}
+
+func TestNewLinkExtractor_Errors(t *testing.T) {
+	_, err := NewLinkExtractor(`(`, "bad", "")
+	assert.Error(t, err)
+	_, err = NewLinkExtractor(`x`, "", "")
+	assert.Error(t, err)
+}
+
+func TestProcessMarkdownReaderWithOptions_CustomLinks(t *testing.T) {
+	cite, err := NewLinkExtractor(`cite:(\w+)`, "citation", "refs/$1")
+	require.NoError(t, err)
+
+	file, err := ProcessMarkdownReaderWithOptions(strings.NewReader("See [[other]] and cite:knuth."), "a.md",
+		ParseOptions{LinkExtractors: []LinkExtractor{*cite}})
+	require.NoError(t, err)
+	require.Len(t, file.Links, 2)
+	assert.Equal(t, "wikilink", file.Links[0].LinkType)
+	assert.Equal(t, "citation", file.Links[1].LinkType)
+	assert.Equal(t, "refs/knuth", file.Links[1].Target)
+}
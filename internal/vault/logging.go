@@ -0,0 +1,53 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+)
+
+// StdLogHandler is a slog.Handler that writes messages to the standard
+// logger, warnings prefixed "Warning:" and errors "Error:" as elsewhere in
+// the server. Attributes are left out.
+type StdLogHandler struct{}
+
+// Enabled implements slog.Handler.
+func (StdLogHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+// Handle implements slog.Handler.
+func (StdLogHandler) Handle(_ context.Context, r slog.Record) error {
+	switch {
+	case r.Level >= slog.LevelError:
+		log.Print("Error: " + r.Message)
+	case r.Level >= slog.LevelWarn:
+		log.Print("Warning: " + r.Message)
+	default:
+		log.Print(r.Message)
+	}
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h StdLogHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+// WithGroup implements slog.Handler.
+func (h StdLogHandler) WithGroup(string) slog.Handler { return h }
+
+var stdLogger = slog.New(StdLogHandler{})
+
+// logf logs an info message to l, or to the standard logger when l is nil.
+func logf(l *slog.Logger, format string, args ...interface{}) {
+	if l == nil {
+		l = stdLogger
+	}
+	l.Info(fmt.Sprintf(format, args...))
+}
+
+// warnf logs a warning to l, or to the standard logger when l is nil.
+func warnf(l *slog.Logger, format string, args ...interface{}) {
+	if l == nil {
+		l = stdLogger
+	}
+	l.Warn(fmt.Sprintf(format, args...))
+}
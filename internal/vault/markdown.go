@@ -1,12 +1,16 @@
 package vault
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode"
+
+	"github.com/ali01/mnemosyne/internal/models"
 )
 
 // MarkdownFile represents a parsed markdown file
@@ -16,69 +20,266 @@ type MarkdownFile struct {
 	Content     string           // Raw markdown content
 	Frontmatter *FrontmatterData // Parsed frontmatter
 	Links       []WikiLink       // Extracted WikiLinks
+	WordCount   int              // Words in the body (frontmatter excluded)
+	Excerpt     string           // First sentences of the body as plain text
+	URLs        []string         // Distinct http(s) URLs in the body
+	Outline     []models.Heading // Heading structure
 	FileInfo    os.FileInfo      // File metadata
+	Warnings    []error          // Problems parsed around, e.g. frontmatter recovered in lenient mode
+}
+
+// wordsPerMinute is the reading speed used for reading time estimates.
+const wordsPerMinute = 200
+
+// ParseOptions customizes how markdown files are parsed. The zero value
+// parses Obsidian-style markdown with wikilinks only.
+type ParseOptions struct {
+	// Dialect selects markdown syntax behaviors. Nil means DialectObsidian.
+	Dialect *Dialect
+
+	// LinkExtractors produce additional typed links from custom syntax.
+	LinkExtractors []LinkExtractor
+
+	// Mentions, when set, extracts mentions of people, which link to person
+	// nodes. Nil disables mentions.
+	Mentions *MentionExtractor
+
+	// LenientFrontmatter recovers from frontmatter that is not valid YAML
+	// instead of failing the file: the top-level keys that parse on their
+	// own are kept, or the file is treated as having no frontmatter if none
+	// do, and a warning is added to the file.
+	LenientFrontmatter bool
+
+	// IDRules derive IDs from filenames for notes whose frontmatter has no
+	// 'id'. When set, frontmatter without an 'id' is no longer an error.
+	IDRules []IDRule
+
+	// Slugger, when set, also resolves links by the slugs of their targets,
+	// so "[[Cafe]]" finds "Café.md".
+	Slugger *Slugger
+
+	// SlugIDs gives notes whose frontmatter has no 'id', and that no IDRule
+	// matches, the slug of their filename as ID. When set, frontmatter
+	// without an 'id' is no longer an error.
+	SlugIDs bool
+
+	// Symlinks says whether symlinks inside the vault are followed or
+	// skipped. Whichever it is, no file outside the vault root is read.
+	Symlinks SymlinkPolicy
+
+	// Rules are the server-wide classification rules and ignore patterns,
+	// merged with those of the vault's DirConfigName files.
+	Rules DirConfig
+
+	// MaxFileSize, when positive, is the largest file in bytes that is
+	// parsed in full. Larger files are indexed with their frontmatter only,
+	// without content, and get an ErrFileTooLarge warning.
+	MaxFileSize int64
+
+	// Redactions rewrite each note's content before anything else reads
+	// it, so redacted text is never stored, linked, indexed or served.
+	Redactions []Redaction
+
+	// ExcerptSentences is the length of each note's Excerpt. Zero means
+	// DefaultExcerptSentences.
+	ExcerptSentences int
+}
+
+func (o ParseOptions) dialect() Dialect {
+	if o.Dialect == nil {
+		return DialectObsidian
+	}
+	return *o.Dialect
+}
+
+func (o ParseOptions) slugger() *Slugger {
+	if o.Slugger == nil {
+		return &Slugger{}
+	}
+	return o.Slugger
+}
+
+func (o ParseOptions) excerptSentences() int {
+	if o.ExcerptSentences <= 0 {
+		return DefaultExcerptSentences
+	}
+	return o.ExcerptSentences
 }
 
 // ProcessMarkdownFile reads and processes a markdown file
 func ProcessMarkdownFile(vaultPath, relativePath string) (*MarkdownFile, error) {
-	fullPath := filepath.Join(vaultPath, relativePath)
+	return ProcessMarkdownFileWithOptions(vaultPath, relativePath, ParseOptions{})
+}
 
-	// Read file content
-	content, err := os.ReadFile(fullPath) // #nosec G304 -- fullPath is from controlled vault directory
+// ProcessMarkdownFileWithOptions reads and processes a markdown file using opts.
+func ProcessMarkdownFileWithOptions(vaultPath, relativePath string, opts ParseOptions) (*MarkdownFile, error) {
+	content, fileInfo, err := readMarkdownFile(vaultPath, relativePath, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file %s: %w", relativePath, err)
+		return nil, err
 	}
+	return processFileContent(content, fileInfo, relativePath, opts)
+}
 
-	// Get file info
-	fileInfo, err := os.Stat(fullPath)
+// readMarkdownFile reads a file's content and info. It is the I/O half of
+// ProcessMarkdownFileWithOptions. Files that symlinks place outside the
+// vault are not read (see resolveInVault), and of files over
+// opts.MaxFileSize only the head is. Files that are not text fail with
+// ErrNotText.
+func readMarkdownFile(vaultPath, relativePath string, opts ParseOptions) ([]byte, os.FileInfo, error) {
+	fullPath, err := resolveInVault(vaultPath, relativePath, opts.Symlinks)
 	if err != nil {
-		return nil, fmt.Errorf("failed to stat file %s: %w", relativePath, err)
+		return nil, nil, fmt.Errorf("failed to read file %s: %w", relativePath, err)
 	}
 
-	// Process content
-	contentStr := string(content)
+	f, err := os.Open(fullPath) // #nosec G304 -- fullPath is from controlled vault directory
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file %s: %w", relativePath, err)
+	}
+	defer f.Close()
 
-	// Extract frontmatter
-	frontmatter, _, err := ExtractFrontmatter(contentStr)
+	// Get file info
+	fileInfo, err := f.Stat()
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract frontmatter from %s: %w", relativePath, err)
+		return nil, nil, fmt.Errorf("failed to stat file %s: %w", relativePath, err)
 	}
 
-	// Extract WikiLinks from full content (body + frontmatter)
-	links := ExtractWikiLinks(contentStr)
+	// Read file content
+	var r io.Reader = f
+	if opts.tooLarge(fileInfo) {
+		r = io.LimitReader(f, largeFileHead)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file %s: %w", relativePath, err)
+	}
+	if err := sniffText(content); err != nil {
+		return nil, nil, fmt.Errorf("skipped %s: %w", relativePath, err)
+	}
+	return content, fileInfo, nil
+}
 
-	// Extract title from frontmatter or filename
-	title := extractTitle(relativePath, frontmatter)
+// processFileContent parses content read by readMarkdownFile. It is the CPU
+// half of ProcessMarkdownFileWithOptions.
+func processFileContent(content []byte, fileInfo os.FileInfo, relativePath string, opts ParseOptions) (*MarkdownFile, error) {
+	if opts.tooLarge(fileInfo) {
+		return processLargeFile(content, fileInfo, relativePath, opts)
+	}
+	text, warnings := prepareContent(content, opts)
+	return processText(text, warnings, fileInfo, relativePath, opts)
+}
 
-	return &MarkdownFile{
-		Path:        relativePath,
-		Title:       title,
-		Content:     contentStr,
-		Frontmatter: frontmatter,
-		Links:       links,
-		FileInfo:    fileInfo,
-	}, nil
+// prepareContent decodes file content to text (see decodeText) and applies
+// the redactions. Warnings are problems worked around on the way.
+func prepareContent(content []byte, opts ParseOptions) (string, []error) {
+	text, warning := decodeText(content)
+	var warnings []error
+	if warning != nil {
+		warnings = append(warnings, warning)
+	}
+	return redact(opts.Redactions, text), warnings
+}
+
+// processText parses text prepared by prepareContent, adding the warnings
+// from preparing it to the file's.
+func processText(text string, warnings []error, fileInfo os.FileInfo, relativePath string, opts ParseOptions) (*MarkdownFile, error) {
+	file, err := processContent(text, relativePath, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract frontmatter from %s: %w", relativePath, err)
+	}
+	file.FileInfo = fileInfo
+	file.Warnings = append(warnings, file.Warnings...)
+	return file, nil
 }
 
 // ProcessMarkdownReader processes markdown from a reader (for testing)
 func ProcessMarkdownReader(reader io.Reader, path string) (*MarkdownFile, error) {
+	return ProcessMarkdownReaderWithOptions(reader, path, ParseOptions{})
+}
+
+// ProcessMarkdownReaderWithOptions processes markdown from a reader using opts.
+func ProcessMarkdownReaderWithOptions(reader io.Reader, path string, opts ParseOptions) (*MarkdownFile, error) {
 	content, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read content: %w", err)
 	}
 
-	contentStr := string(content)
+	text, warnings := prepareContent(content, opts)
+	file, err := processContent(text, path, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract frontmatter: %w", err)
+	}
+	file.Warnings = append(warnings, file.Warnings...)
+	return file, nil
+}
+
+// processContent parses markdown content prepared by prepareContent.
+// FileInfo is left unset. The only error it returns comes from frontmatter
+// extraction.
+func processContent(contentStr, path string, opts ParseOptions) (*MarkdownFile, error) {
+	dialect := opts.dialect()
+	fmRegex := frontmatterRegexFor(dialect.FrontmatterDelimiter)
 
 	// Extract frontmatter
-	frontmatter, _, err := ExtractFrontmatter(contentStr)
+	frontmatter, body, err := extractFrontmatter(contentStr, fmRegex, len(opts.IDRules) == 0 && !opts.SlugIDs)
+	var warnings []error
+	if err != nil && opts.LenientFrontmatter && !errors.Is(err, errMissingID) {
+		var warning *FrontmatterError
+		frontmatter, body, warning = recoverFrontmatter(contentStr, fmRegex, err)
+		if warning != nil {
+			warnings = append(warnings, warning)
+		}
+		err = nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract frontmatter: %w", err)
+		return nil, err
+	}
+
+	// Fall back to a filename-derived ID
+	if frontmatter == nil || frontmatter.ID == "" {
+		id, ok := idFromFilename(opts.IDRules, path)
+		if !ok && opts.SlugIDs {
+			id, ok = opts.slugger().Slug(strings.TrimSuffix(filepath.Base(path), ".md")), true
+		}
+		if ok {
+			if frontmatter == nil {
+				frontmatter = &FrontmatterData{
+					Tags:       []string{},
+					Related:    []string{},
+					References: []string{},
+					Raw:        map[string]any{},
+				}
+			}
+			frontmatter.ID = id
+		}
+	}
+
+	// Text used for extraction; comments are blanked but offsets preserved
+	text := contentStr
+	if dialect.StripComments {
+		text = stripComments(text)
+		body = stripComments(body)
 	}
 
 	// Extract WikiLinks from full content (body + frontmatter)
-	links := ExtractWikiLinks(contentStr)
+	var links []WikiLink
+	if dialect.Wikilinks {
+		links = ExtractWikiLinks(text)
+	}
+
+	// Extract custom-syntax links
+	for _, ex := range opts.LinkExtractors {
+		links = append(links, ex.Extract(text)...)
+	}
+
+	// Extract pandoc citations, resolved against the vault's BibTeX entries
+	links = append(links, ExtractCitations(text)...)
 
-	// Extract title from frontmatter or path
+	// Extract mentions of people
+	if opts.Mentions != nil {
+		links = append(links, opts.Mentions.Extract(text)...)
+	}
+
+	// Extract title from frontmatter or filename
 	title := extractTitle(path, frontmatter)
 
 	return &MarkdownFile{
@@ -87,7 +288,11 @@ func ProcessMarkdownReader(reader io.Reader, path string) (*MarkdownFile, error)
 		Content:     contentStr,
 		Frontmatter: frontmatter,
 		Links:       links,
-		FileInfo:    nil, // No file info when processing from reader
+		WordCount:   CountWords(body),
+		Excerpt:     Excerpt(body, opts.excerptSentences()),
+		URLs:        ExtractURLs(body),
+		Outline:     extractOutline(text, frontmatterLineCount(text, fmRegex)),
+		Warnings:    warnings,
 	}, nil
 }
 
@@ -109,6 +314,29 @@ func extractTitle(path string, frontmatter *FrontmatterData) string {
 	return strings.TrimSuffix(base, ".md")
 }
 
+// CountWords counts the words in markdown text. Tokens made up only of
+// markdown syntax (e.g. "#", "-", "---", "|") are not counted as words.
+func CountWords(text string) int {
+	count := 0
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, func(r rune) bool {
+			return unicode.IsLetter(r) || unicode.IsDigit(r)
+		}) >= 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// ReadingTime estimates the reading time in minutes for a word count,
+// rounding up so any non-empty note takes at least one minute.
+func ReadingTime(wordCount int) int {
+	if wordCount <= 0 {
+		return 0
+	}
+	return (wordCount + wordsPerMinute - 1) / wordsPerMinute
+}
+
 // GetID returns the unique ID from frontmatter
 func (m *MarkdownFile) GetID() string {
 	if m.Frontmatter != nil {
@@ -142,6 +142,41 @@ func TestExtractTitle(t *testing.T) {
 	}
 }
 
+func TestCountWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{"empty", "", 0},
+		{"plain sentence", "The quick brown fox.", 4},
+		{"markdown syntax ignored", "# Heading\n\n- item one\n- item two\n\n---", 5},
+		{"wikilinks count as words", "See [[Other Note]] for details", 5},
+		{"numbers", "Chapter 3 of 10", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, CountWords(tt.text))
+		})
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	assert.Equal(t, 0, ReadingTime(0))
+	assert.Equal(t, 1, ReadingTime(1))
+	assert.Equal(t, 1, ReadingTime(200))
+	assert.Equal(t, 2, ReadingTime(201))
+	assert.Equal(t, 5, ReadingTime(1000))
+}
+
+func TestProcessMarkdownReader_WordCountExcludesFrontmatter(t *testing.T) {
+	content := "---\nid: wc\ntags: [a, b]\n---\n# Title\n\nOne two three."
+	file, err := ProcessMarkdownReader(strings.NewReader(content), "wc.md")
+	require.NoError(t, err)
+	assert.Equal(t, 4, file.WordCount)
+}
+
 func TestMarkdownFile_GetID(t *testing.T) {
 	// With frontmatter
 	file := &MarkdownFile{
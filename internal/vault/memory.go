@@ -0,0 +1,95 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrMemoryBudgetExceeded is returned by ParseVault when parsing the vault
+// would exceed the configured memory budget.
+var ErrMemoryBudgetExceeded = errors.New("vault too large for configured memory")
+
+const (
+	// parsedSizeFactor estimates in-memory bytes per byte of markdown once a
+	// file is parsed (raw content, body, frontmatter, links, and map overhead).
+	parsedSizeFactor = 4
+
+	// memoryCheckInterval is the number of files parsed between heap checks.
+	// Reading memory stats briefly stops the world, so it is not done per file.
+	memoryCheckInterval = 128
+)
+
+// memoryGuard aborts parsing when the Go heap grows past a budget.
+type memoryGuard struct {
+	budget   uint64
+	files    atomic.Int64
+	exceeded atomic.Bool
+
+	mu       sync.Mutex
+	heapSeen uint64
+}
+
+func newMemoryGuard(budget int64) *memoryGuard {
+	if budget <= 0 {
+		return nil
+	}
+	return &memoryGuard{budget: uint64(budget)}
+}
+
+// preflight fails fast when the vault's estimated parsed size exceeds the budget.
+func (g *memoryGuard) preflight(files int, totalBytes int64) error {
+	if g == nil {
+		return nil
+	}
+	estimate := uint64(totalBytes) * parsedSizeFactor
+	if estimate > g.budget {
+		return fmt.Errorf("%w: %d markdown files (%s) need an estimated %s, budget is %s",
+			ErrMemoryBudgetExceeded, files, formatBytes(uint64(totalBytes)), formatBytes(estimate), formatBytes(g.budget))
+	}
+	return nil
+}
+
+// fileDone records a parsed file, checking the heap every memoryCheckInterval
+// files. It reports whether parsing may continue.
+func (g *memoryGuard) fileDone() bool {
+	if g == nil {
+		return true
+	}
+	if g.exceeded.Load() {
+		return false
+	}
+	if g.files.Add(1)%memoryCheckInterval != 0 {
+		return true
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	g.mu.Lock()
+	g.heapSeen = max(g.heapSeen, ms.HeapAlloc)
+	g.mu.Unlock()
+	if ms.HeapAlloc > g.budget {
+		g.exceeded.Store(true)
+		return false
+	}
+	return true
+}
+
+// stopped reports whether the budget has been exceeded.
+func (g *memoryGuard) stopped() bool {
+	return g != nil && g.exceeded.Load()
+}
+
+// err describes an exceeded budget after parsing done of total files.
+func (g *memoryGuard) err(done, total int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return fmt.Errorf("%w: heap reached %s after %d of %d files, budget is %s",
+		ErrMemoryBudgetExceeded, formatBytes(g.heapSeen), done, total, formatBytes(g.budget))
+}
+
+func formatBytes(n uint64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
@@ -0,0 +1,55 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeNotes(t *testing.T, dir string, n int, body string) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		content := fmt.Sprintf("---\nid: n%d\n---\n%s\n", i, body)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("n%d.md", i)), []byte(content), 0o644))
+	}
+}
+
+func TestParseVault_MemoryBudgetPreflight(t *testing.T) {
+	dir := t.TempDir()
+	writeNotes(t, dir, 3, strings.Repeat("word ", 1000))
+
+	parser := NewParser(dir, 2, 0)
+	parser.SetMemoryBudget(1024)
+	_, err := parser.ParseVault()
+	require.ErrorIs(t, err, ErrMemoryBudgetExceeded)
+	assert.Contains(t, err.Error(), "3 markdown files")
+}
+
+func TestParseVault_MemoryBudgetDuringParse(t *testing.T) {
+	dir := t.TempDir()
+	writeNotes(t, dir, memoryCheckInterval+10, "x")
+
+	// Small enough files to pass the preflight estimate, but the process heap
+	// is always larger than this budget once checked.
+	parser := NewParser(dir, 2, 0)
+	parser.SetMemoryBudget(64 << 10)
+	_, err := parser.ParseVault()
+	require.ErrorIs(t, err, ErrMemoryBudgetExceeded)
+	assert.Contains(t, err.Error(), "heap reached")
+}
+
+func TestParseVault_MemoryBudgetNotExceeded(t *testing.T) {
+	dir := t.TempDir()
+	writeNotes(t, dir, 5, "hello")
+
+	parser := NewParser(dir, 2, 0)
+	parser.SetMemoryBudget(1 << 40)
+	result, err := parser.ParseVault()
+	require.NoError(t, err)
+	assert.Len(t, result.Files, 5)
+}
@@ -0,0 +1,136 @@
+package vault
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// MentionLinkType is the LinkType of mentions of people and the edges they
+// produce.
+const MentionLinkType = "mention"
+
+// PersonNodeType is the NodeType of notes in the people directory and of
+// the nodes created for people mentioned without a note.
+const PersonNodeType = "person"
+
+const (
+	// DefaultMentionPattern matches @name, where a name starts with a letter
+	// and continues with letters, digits, '-' and '_'. An '@' preceded by a
+	// word character, '.', '/' or another '@' (as in e-mail addresses) is
+	// not a mention.
+	DefaultMentionPattern = `(?:^|[^\p{L}\p{N}_.@/])@(\p{L}[\p{L}\p{N}_-]*)`
+
+	// DefaultPeopleDir is the directory of notes about people.
+	DefaultPeopleDir = "people"
+)
+
+// MentionExtractor finds mentions of people, e.g. "@jane-doe". A mention
+// resolves to the note in the people directory named after the person, or
+// else to a person node of its own, created for the mentions.
+type MentionExtractor struct {
+	// PeopleDir is the vault-relative directory whose notes are people.
+	PeopleDir string
+
+	pattern *regexp.Regexp
+}
+
+// NewMentionExtractor compiles a mention extractor. The pattern's first
+// capture group is the person's name, or the whole match when it has none;
+// an empty pattern means DefaultMentionPattern and an empty peopleDir
+// DefaultPeopleDir.
+func NewMentionExtractor(pattern, peopleDir string) (*MentionExtractor, error) {
+	if pattern == "" {
+		pattern = DefaultMentionPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("mentions: %w", err)
+	}
+	if peopleDir == "" {
+		peopleDir = DefaultPeopleDir
+	}
+	return &MentionExtractor{PeopleDir: path.Clean(strings.Trim(peopleDir, "/")), pattern: re}, nil
+}
+
+// Extract returns a mention link for each mention in content. Mentions in
+// fenced code blocks and in pandoc citations are skipped.
+func (e *MentionExtractor) Extract(content string) []WikiLink {
+	content = blankCitationsAndCode(content)
+	var links []WikiLink
+	for _, m := range e.pattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		name := strings.TrimRight(content[start:end], "-_")
+		if name == "" {
+			continue
+		}
+		// Report the mention from its '@', if the pattern has one
+		if at := strings.LastIndexByte(content[m[0]:start], '@'); at >= 0 {
+			start = m[0] + at
+		}
+		links = append(links, WikiLink{
+			Raw:      content[start:end],
+			Target:   name,
+			LinkType: MentionLinkType,
+			Position: start,
+		})
+	}
+	return links
+}
+
+// IsPersonNote reports whether the file at relPath is in the people directory.
+func (e *MentionExtractor) IsPersonNote(relPath string) bool {
+	dir := path.Dir(strings.ReplaceAll(relPath, "\\", "/"))
+	return dir == e.PeopleDir || strings.HasPrefix(dir, e.PeopleDir+"/")
+}
+
+// PersonKey normalizes a person's name, so "@Jane-Doe", "@jane_doe" and
+// people/Jane Doe.md are the same person.
+func PersonKey(name string) string {
+	return normalizeForMatching(strings.TrimSuffix(path.Base(name), ".md"))
+}
+
+// PersonID returns the ID of the node created for a person mentioned
+// without a note.
+func PersonID(name string) string {
+	return "person:" + strings.ReplaceAll(PersonKey(name), " ", "-")
+}
+
+// blankCitationsAndCode replaces pandoc citation groups and fenced code
+// blocks with spaces, keeping offsets and line breaks.
+func blankCitationsAndCode(content string) string {
+	blank := func(s string) string {
+		buf := []byte(s)
+		for i := range buf {
+			if buf[i] != '\n' {
+				buf[i] = ' '
+			}
+		}
+		return string(buf)
+	}
+	content = citationGroupRegex.ReplaceAllStringFunc(content, blank)
+
+	var b strings.Builder
+	b.Grow(len(content))
+	inFence := false
+	for i, line := range strings.Split(content, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		if fenceRegex.MatchString(line) {
+			inFence = !inFence
+			b.WriteString(blank(line))
+			continue
+		}
+		if inFence {
+			b.WriteString(blank(line))
+			continue
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMentionExtractor_Extract(t *testing.T) {
+	e, err := NewMentionExtractor("", "")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultPeopleDir, e.PeopleDir)
+
+	content := "Met @Jane-Doe and (@bob) today. Mail jane@example.com, see [@doe99].\n" +
+		"```\n@decorator\n```\n@ana_"
+	links := e.Extract(content)
+	require.Len(t, links, 3)
+	assert.Equal(t, "Jane-Doe", links[0].Target)
+	assert.Equal(t, "@Jane-Doe", links[0].Raw)
+	assert.Equal(t, MentionLinkType, links[0].LinkType)
+	assert.Equal(t, 4, links[0].Position)
+	assert.Equal(t, "bob", links[1].Target)
+	assert.Equal(t, "ana", links[2].Target)
+
+	custom, err := NewMentionExtractor(`\bwith ([A-Z][a-z]+)`, "/contacts/")
+	require.NoError(t, err)
+	assert.Equal(t, "contacts", custom.PeopleDir)
+	links = custom.Extract("Lunch with Maria.")
+	require.Len(t, links, 1)
+	assert.Equal(t, "Maria", links[0].Target)
+
+	_, err = NewMentionExtractor("(", "")
+	assert.Error(t, err)
+}
+
+func TestPersonKey(t *testing.T) {
+	assert.Equal(t, PersonKey("Jane-Doe"), PersonKey("people/jane doe.md"))
+	assert.Equal(t, PersonKey("jane_doe"), PersonKey("jane-doe"))
+	assert.Equal(t, "person:jane-doe", PersonID("Jane_Doe"))
+}
+
+func TestBuildGraphMentions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"people/Jane Doe.md":  "---\nid: jane\n---\nWorks with @bob.\n",
+		"meetings/kickoff.md": "---\nid: kickoff\n---\nWith @jane-doe, @Bob and @bob.\n",
+	}
+	for path, content := range files {
+		full := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o750))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0o600))
+	}
+
+	mentions, err := NewMentionExtractor("", "")
+	require.NoError(t, err)
+	parser := NewParser(dir, 0, 0)
+	parser.SetOptions(ParseOptions{Mentions: mentions})
+	result, err := parser.ParseVault()
+	require.NoError(t, err)
+	assert.Empty(t, result.UnresolvedLinks)
+
+	graph, err := NewGraphBuilder(GraphBuilderConfig{}).BuildGraph(result)
+	require.NoError(t, err)
+	require.Len(t, graph.Nodes, 3)
+
+	nodes := make(map[string]int)
+	for i, n := range graph.Nodes {
+		nodes[n.ID] = i
+	}
+	jane := graph.Nodes[nodes["jane"]]
+	assert.Equal(t, PersonNodeType, jane.NodeType)
+	assert.Equal(t, "", graph.Nodes[nodes["kickoff"]].NodeType)
+
+	bob := graph.Nodes[nodes["person:bob"]]
+	assert.Equal(t, PersonNodeType, bob.NodeType)
+	assert.Equal(t, "Bob", bob.Title)
+	assert.Equal(t, "people/bob", bob.FilePath)
+	assert.Equal(t, 2, bob.InDegree)
+
+	var targets []string
+	for _, e := range graph.Edges {
+		assert.Equal(t, MentionLinkType, e.EdgeType)
+		targets = append(targets, e.SourceID+"->"+e.TargetID)
+	}
+	assert.Equal(t, []string{"jane->person:bob", "kickoff->jane", "kickoff->person:bob"}, targets)
+}
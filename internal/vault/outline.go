@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+var (
+	// Matches ATX headings: "# Title" through "###### Title"
+	headingRegex = regexp.MustCompile(`^(#{1,6})[ \t]+(.+?)[ \t#]*$`)
+
+	// Matches the opening or closing line of a fenced code block
+	fenceRegex = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// ExtractOutline returns the heading structure of markdown content in document order.
+// Frontmatter and headings inside fenced code blocks are ignored. Line numbers are
+// 1-based and relative to the full content (including frontmatter).
+func ExtractOutline(content string) []models.Heading {
+	return extractOutline(content, frontmatterLineCount(content, frontmatterRegex))
+}
+
+// extractOutline is ExtractOutline starting after the first start lines.
+func extractOutline(content string, start int) []models.Heading {
+	lines := strings.Split(content, "\n")
+
+	headings := []models.Heading{}
+	slugCounts := make(map[string]int)
+	inFence := false
+	for i := start; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+
+		if fenceRegex.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		m := headingRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		text := strings.TrimSpace(m[2])
+		slug := HeadingSlug(text)
+		// Disambiguate repeated headings the same way GitHub does: slug, slug-1, slug-2...
+		if n := slugCounts[slug]; n > 0 {
+			slugCounts[slug] = n + 1
+			slug = slug + "-" + strconv.Itoa(n)
+		} else {
+			slugCounts[slug] = 1
+		}
+
+		headings = append(headings, models.Heading{
+			Level: len(m[1]),
+			Text:  text,
+			Slug:  slug,
+			Line:  i + 1,
+		})
+	}
+	return headings
+}
+
+// HeadingSlug converts heading text into a URL-friendly anchor:
+// lowercase, punctuation removed, whitespace collapsed to single dashes.
+func HeadingSlug(text string) string {
+	var b strings.Builder
+	lastDash := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			b.WriteRune(r)
+			lastDash = false
+		case unicode.IsSpace(r) || r == '-':
+			if !lastDash && b.Len() > 0 {
+				b.WriteRune('-')
+				lastDash = true
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// frontmatterLineCount returns the number of lines occupied by leading frontmatter.
+func frontmatterLineCount(content string, re *regexp.Regexp) int {
+	loc := re.FindStringIndex(content)
+	if loc == nil {
+		return 0
+	}
+	return strings.Count(content[:loc[1]], "\n")
+}
@@ -0,0 +1,52 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractOutline(t *testing.T) {
+	content := "---\nid: o1\n---\n# Title\n\nIntro\n\n## Section One\ntext\n### Sub *part*\n```\n# not a heading\n```\n## Section One\n"
+
+	outline := ExtractOutline(content)
+	assert.Len(t, outline, 4)
+
+	assert.Equal(t, 1, outline[0].Level)
+	assert.Equal(t, "Title", outline[0].Text)
+	assert.Equal(t, "title", outline[0].Slug)
+	assert.Equal(t, 4, outline[0].Line)
+
+	assert.Equal(t, 2, outline[1].Level)
+	assert.Equal(t, "section-one", outline[1].Slug)
+
+	assert.Equal(t, 3, outline[2].Level)
+	assert.Equal(t, "sub-part", outline[2].Slug)
+
+	// Duplicate headings get disambiguated slugs
+	assert.Equal(t, "section-one-1", outline[3].Slug)
+}
+
+func TestExtractOutline_NoHeadings(t *testing.T) {
+	outline := ExtractOutline("just text\n#not-a-heading tag")
+	assert.NotNil(t, outline)
+	assert.Empty(t, outline)
+}
+
+func TestHeadingSlug(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"Hello World", "hello-world"},
+		{"What's New?", "whats-new"},
+		{"  Spaces   Everywhere ", "spaces-everywhere"},
+		{"Über Café", "über-café"},
+		{"snake_case-and-dash", "snake_case-and-dash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, HeadingSlug(tt.text))
+		})
+	}
+}
@@ -1,40 +1,105 @@
 package vault
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/ali01/mnemosyne/internal/models"
 )
 
 // Parser is the main vault parser that orchestrates the parsing process
 // It coordinates reading markdown files, extracting metadata, and resolving links
 type Parser struct {
-	vaultPath   string        // Root path of the Obsidian vault
-	resolver    *LinkResolver // Handles WikiLink resolution
-	concurrency int           // Number of concurrent workers for parsing
-	batchSize   int           // Number of files to process per batch
+	vaultPath   string                       // Root path of the Obsidian vault
+	resolver    *LinkResolver                // Handles WikiLink resolution
+	concurrency int                          // Number of concurrent workers for parsing; 0 adapts to the machine
+	maxWorkers  int                          // Cap on adaptive workers; 0 uses 4x GOMAXPROCS
+	memBudget   int64                        // Heap budget in bytes while parsing; 0 is unlimited
+	batchSize   int                          // Number of files to process per batch
+	hooks       []ParserHook                 // Called for each parsed file
+	options     ParseOptions                 // Markdown parsing options
+	progress    ProgressFunc                 // Called after each file is processed
+	cache       map[string]models.CachedFile // Previously parsed files by path; nil disables caching
+	cacheSalt   string                       // Parse settings folded into content hashes
+	logger      *slog.Logger                 // Receives what parsing logs; nil is the standard logger
 }
 
+// ProgressFunc receives the number of files processed so far and the total.
+// It may be called concurrently from parser workers.
+type ProgressFunc func(done, total int)
+
 // ParseResult contains the complete parsed vault data
 // This is the main output of the parsing process
 type ParseResult struct {
 	Files           map[string]*MarkdownFile // ID -> MarkdownFile mapping
+	References      map[string]*Reference    // Citekey -> BibTeX entry
+	Mentions        *MentionExtractor        // Mention settings; nil when mentions are off
+	Classifier      *Classifier              // Classification rules of the vault's directories and the server
+	People          map[string]string        // PersonKey -> ID of the person's note
 	Resolver        *LinkResolver            // Link resolver with all mappings
 	ParseErrors     []ParseError             // Errors encountered during parsing
 	UnresolvedLinks []UnresolvedLink         // WikiLinks that couldn't be resolved
 	Stats           ParseStats               // Statistics about the parsing process
+
+	// With a file cache set, Cache holds an entry for every parsed file and
+	// Unchanged the paths reused from the cache instead of being parsed.
+	Cache     []models.CachedFile
+	Unchanged map[string]bool
+}
+
+// warningParseError records a warning from MarkdownFile.Warnings.
+func warningParseError(path string, w error) ParseError {
+	pe := ParseError{FilePath: path, Error: w}
+	var fe *FrontmatterError
+	var ee *EncodingError
+	switch {
+	case errors.As(w, &fe):
+		pe.Kind, pe.Line = ParseErrorFrontmatter, fe.Line
+	case errors.As(w, &ee):
+		pe.Kind, pe.Line = ParseErrorEncoding, ee.Line
+	case errors.Is(w, ErrFileTooLarge):
+		pe.Kind = ParseErrorSize
+	}
+	return pe
+}
+
+// readErrorKind is the ParseError kind for a file that could not be read.
+func readErrorKind(err error) string {
+	switch {
+	case errors.Is(err, ErrOutsideVault):
+		return ParseErrorSymlink
+	case errors.Is(err, ErrNotText):
+		return ParseErrorBinary
+	}
+	return ParseErrorRead
 }
 
 // ParseError represents an error during parsing a specific file
 type ParseError struct {
 	FilePath string
+	Kind     string // One of the ParseError* kinds
+	Line     int    // Line of the file the error is on, from 1; 0 if unknown
 	Error    error
 }
 
+// Kinds of ParseError.
+const (
+	ParseErrorRead        = "read"        // The file could not be read
+	ParseErrorFrontmatter = "frontmatter" // Invalid YAML frontmatter, or no 'id' where one is required; in lenient mode the file still parsed
+	ParseErrorEncoding    = "encoding"    // Not valid UTF-8 once decoded; invalid bytes were replaced and the file still parsed
+	ParseErrorHook        = "hook"        // A parser hook rejected the file
+	ParseErrorSymlink     = "symlink"     // A symlink was not followed: broken, a cycle, or out of the vault
+	ParseErrorSize        = "size"        // Over the size limit; indexed from its frontmatter, without content
+	ParseErrorBinary      = "binary"      // Not text, e.g. an image named .md; skipped
+	ParseErrorDirConfig   = "config"      // A directory's .mnemosyne.yaml is not valid; its rules are not applied
+)
+
 // UnresolvedLink represents a WikiLink that couldn't be resolved to a target file
 type UnresolvedLink struct {
 	SourceID   string   // ID of the file containing the link
@@ -47,19 +112,26 @@ type ParseStats struct {
 	TotalFiles      int       // Total markdown files found
 	ParsedFiles     int       // Successfully parsed files
 	FailedFiles     int       // Files that failed to parse
+	SkippedFiles    int       // Files skipped as not text
 	TotalLinks      int       // Total WikiLinks found
 	ResolvedLinks   int       // WikiLinks successfully resolved
 	UnresolvedLinks int       // WikiLinks that couldn't be resolved
 	StartTime       time.Time // When parsing started
 	EndTime         time.Time // When parsing completed
 	DurationMS      int64     // Total parsing duration in milliseconds
+	Workers         int       // Parser workers in use when parsing finished
+	CachedFiles     int       // Unchanged files reused from the file cache
+	References      int       // BibTeX entries found
+	LargeFiles      int       // Files over the size limit, indexed without content
 }
 
-// NewParser creates a new vault parser with the specified configuration
+// NewParser creates a new vault parser with the specified configuration.
+// A concurrency of 0 or less sizes the worker pool adaptively: it starts at
+// GOMAXPROCS and grows while workers spend their time waiting on file reads.
 func NewParser(vaultPath string, concurrency, batchSize int) *Parser {
 	// Set defaults if not specified
-	if concurrency <= 0 {
-		concurrency = 4
+	if concurrency < 0 {
+		concurrency = 0
 	}
 	if batchSize <= 0 {
 		batchSize = 100
@@ -73,37 +145,112 @@ func NewParser(vaultPath string, concurrency, batchSize int) *Parser {
 	}
 }
 
+// SetHooks sets the hooks called for each parsed file.
+func (p *Parser) SetHooks(hooks []ParserHook) {
+	p.hooks = hooks
+}
+
+// SetOptions sets the markdown parsing options.
+func (p *Parser) SetOptions(opts ParseOptions) {
+	p.options = opts
+	p.resolver.SetSlugger(opts.Slugger)
+}
+
+// SetMaxConcurrency caps the number of adaptive workers. It has no effect
+// when a fixed concurrency was given to NewParser.
+func (p *Parser) SetMaxConcurrency(n int) {
+	p.maxWorkers = n
+}
+
+// SetMemoryBudget limits the heap, in bytes, while parsing. ParseVault fails
+// with ErrMemoryBudgetExceeded, before or during parsing, rather than letting
+// the process run out of memory. Zero disables the limit.
+func (p *Parser) SetMemoryBudget(bytes int64) {
+	p.memBudget = bytes
+}
+
+// SetLogger sends what parsing logs to l instead of the standard logger.
+func (p *Parser) SetLogger(l *slog.Logger) {
+	p.logger = l
+}
+
+// SetProgressFunc sets a callback invoked after each file is processed.
+func (p *Parser) SetProgressFunc(fn ProgressFunc) {
+	p.progress = fn
+}
+
+// SetFileCache enables content-hash caching. Files whose hash matches their
+// cache entry are restored from it without being parsed or passed to hooks.
+// salt identifies the parse settings (dialect, extractors, hooks); entries
+// hashed under a different salt never match. A nil cache is treated as empty.
+func (p *Parser) SetFileCache(salt string, cache map[string]models.CachedFile) {
+	if cache == nil {
+		cache = make(map[string]models.CachedFile)
+	}
+	p.cache = cache
+	p.cacheSalt = salt
+}
+
 // ParseVault parses the entire vault and returns the result
 // This is the main entry point for parsing an Obsidian vault
 func (p *Parser) ParseVault() (*ParseResult, error) {
+	return p.ParseVaultContext(context.Background())
+}
+
+// ParseVaultContext is ParseVault with cancellation: workers stop taking
+// files once ctx is done, and ctx's error is returned.
+func (p *Parser) ParseVaultContext(ctx context.Context) (*ParseResult, error) {
 	// Initialize the result structure
 	result := &ParseResult{
-		Files:    make(map[string]*MarkdownFile),
-		Resolver: p.resolver,
+		Files:      make(map[string]*MarkdownFile),
+		References: make(map[string]*Reference),
+		Mentions:   p.options.Mentions,
+		People:     make(map[string]string),
+		Resolver:   p.resolver,
 		Stats: ParseStats{
 			StartTime: time.Now(),
 		},
 	}
+	if p.cache != nil {
+		result.Unchanged = make(map[string]bool)
+	}
 
 	// Step 1: Discover all markdown files in the vault
-	// This walks the directory tree and collects all .md file paths
-	log.Printf("Scanning vault at %s for markdown files...", p.vaultPath)
-	filePaths, err := p.collectMarkdownFiles()
+	// This walks the directory tree and collects all .md and .bib file paths
+	logf(p.logger, "Scanning vault at %s for markdown files...", p.vaultPath)
+	walked, err := walkVault(ctx, p.vaultPath, p.options)
 	if err != nil {
 		return nil, fmt.Errorf("failed to collect markdown files: %w", err)
 	}
+	filePaths, bibPaths, totalBytes := walked.files, walked.bibs, walked.totalBytes
+	result.ParseErrors = append(result.ParseErrors, walked.problems...)
+	result.Classifier = walked.classifier
 
 	result.Stats.TotalFiles = len(filePaths)
-	log.Printf("Found %d markdown files", len(filePaths))
+	logf(p.logger, "Found %d markdown files", len(filePaths))
+
+	guard := newMemoryGuard(p.memBudget)
+	if err := guard.preflight(len(filePaths), totalBytes); err != nil {
+		return nil, err
+	}
 
 	// Step 2: Parse all files concurrently
 	// This reads each file, extracts frontmatter, and collects WikiLinks
-	log.Printf("Parsing files with %d workers...", p.concurrency)
-	p.processFilesConcurrently(filePaths, result)
+	p.processFilesConcurrently(ctx, filePaths, result, guard)
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("parse cancelled: %w", err)
+	}
+	if guard.stopped() {
+		return nil, guard.err(result.Stats.ParsedFiles+result.Stats.FailedFiles+result.Stats.SkippedFiles, result.Stats.TotalFiles)
+	}
+
+	// Citations resolve against BibTeX entries, so read them before resolving
+	p.parseBibliographies(bibPaths, result)
+	p.collectPeople(result)
 
 	// Step 3: Resolve all WikiLinks to their target files
 	// This matches link text to actual file IDs using various strategies
-	log.Println("Resolving WikiLinks...")
+	logf(p.logger, "Resolving WikiLinks...")
 	p.resolveAllLinks(result)
 
 	// Step 4: Calculate final statistics
@@ -111,52 +258,52 @@ func (p *Parser) ParseVault() (*ParseResult, error) {
 	duration := result.Stats.EndTime.Sub(result.Stats.StartTime)
 	result.Stats.DurationMS = duration.Milliseconds()
 
-	log.Printf("Parsing completed in %v", duration)
-	log.Printf("Parsed: %d/%d files, Resolved: %d/%d links",
+	logf(p.logger, "Parsing completed in %v", duration)
+	logf(p.logger, "Parsed: %d/%d files, Resolved: %d/%d links",
 		result.Stats.ParsedFiles, result.Stats.TotalFiles,
 		result.Stats.ResolvedLinks, result.Stats.TotalLinks)
 
 	return result, nil
 }
 
-// collectMarkdownFiles walks the vault directory and collects all .md files
-// It returns a slice of relative paths to all markdown files
-func (p *Parser) collectMarkdownFiles() ([]string, error) {
-	var files []string
-
-	// Walk the directory tree starting from vault root
-	err := filepath.Walk(p.vaultPath, func(path string, info os.FileInfo, err error) error {
+// parseBibliographies reads the vault's BibTeX files into result.References.
+// When a citekey appears in more than one file, the entry from the file that
+// sorts first is kept.
+func (p *Parser) parseBibliographies(paths []string, result *ParseResult) {
+	for _, relPath := range paths {
+		fullPath, err := resolveInVault(p.vaultPath, relPath, p.options.Symlinks)
 		if err != nil {
-			return err
+			result.ParseErrors = append(result.ParseErrors, ParseError{FilePath: relPath, Kind: readErrorKind(err), Error: err})
+			continue
 		}
-
-		// Skip hidden directories and files (like .git, .obsidian)
-		if strings.HasPrefix(info.Name(), ".") {
-			if info.IsDir() {
-				return filepath.SkipDir // Don't descend into hidden directories
-			}
-			return nil // Skip hidden files
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			result.ParseErrors = append(result.ParseErrors, ParseError{FilePath: relPath, Kind: ParseErrorRead, Error: err})
+			continue
 		}
-
-		// Collect markdown files
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".md") {
-			// Convert to relative path for consistency
-			relPath, err := filepath.Rel(p.vaultPath, path)
-			if err != nil {
-				return err
+		content, err := os.ReadFile(fullPath) // #nosec G304 -- fullPath is from controlled vault directory
+		if err != nil {
+			result.ParseErrors = append(result.ParseErrors, ParseError{FilePath: relPath, Kind: ParseErrorRead, Error: err})
+			continue
+		}
+		for _, ref := range ParseBibTeX(string(content)) {
+			if kept, ok := result.References[ref.Key]; ok {
+				warnf(p.logger, "Duplicate citekey '%s' found in '%s' and '%s'. Keeping first occurrence.",
+					ref.Key, kept.Path, relPath)
+				continue
 			}
-			files = append(files, relPath)
+			ref.Path = relPath
+			ref.ModTime = info.ModTime()
+			result.References[ref.Key] = &ref
 		}
-
-		return nil
-	})
-
-	return files, err
+	}
+	result.Stats.References = len(result.References)
 }
 
 // processFilesConcurrently processes files using worker goroutines
 // This enables parallel processing for better performance with large vaults
-func (p *Parser) processFilesConcurrently(filePaths []string, result *ParseResult) {
+// Workers stop early once ctx is done or guard reports the memory budget exceeded.
+func (p *Parser) processFilesConcurrently(ctx context.Context, filePaths []string, result *ParseResult, guard *memoryGuard) {
 	var wg sync.WaitGroup
 	var mu sync.Mutex // Protects shared result data
 
@@ -168,57 +315,172 @@ func (p *Parser) processFilesConcurrently(filePaths []string, result *ParseResul
 	}
 	close(workCh) // Close channel to signal no more work
 
-	// Start worker goroutines
-	for i := 0; i < p.concurrency; i++ {
-		wg.Add(1)
-		go func(_ int) {
-			defer wg.Done()
-
-			// Each worker processes files from the work channel
-			for path := range workCh {
-				// Process individual markdown file
-				file, err := ProcessMarkdownFile(p.vaultPath, path)
-
-				// Update results (with mutex for thread safety)
-				mu.Lock()
-				var shouldLogProgress bool
-				var currentProgress int
-
-				if err != nil {
-					// Record parse error
-					result.ParseErrors = append(result.ParseErrors, ParseError{
-						FilePath: path,
-						Error:    err,
-					})
-					result.Stats.FailedFiles++
+	// A fixed concurrency disables tuning
+	var tuner *workerTuner
+	workers := p.concurrency
+	if workers == 0 {
+		tuner = newWorkerTuner(p.maxWorkers)
+		workers = tuner.initial()
+		logf(p.logger, "Parsing files with %d workers (adaptive, max %d)...", workers, tuner.max)
+	} else {
+		logf(p.logger, "Parsing files with %d workers...", workers)
+	}
+
+	var worker func()
+	worker = func() {
+		defer wg.Done()
+
+		// Each worker processes files from the work channel
+		for path := range workCh {
+			if guard.stopped() || ctx.Err() != nil {
+				return
+			}
+
+			// Process individual markdown file, timing I/O and parsing separately
+			start := time.Now()
+			content, info, err := readMarkdownFile(p.vaultPath, path, p.options)
+			read := time.Now()
+			errKind := readErrorKind(err)
+			large := err == nil && p.options.tooLarge(info)
+			var text string
+			var warnings []error
+			if err == nil && !large {
+				text, warnings = prepareContent(content, p.options)
+			}
+			var file *MarkdownFile
+			var entry *models.CachedFile
+			cached := false
+			if err == nil && p.cache != nil && len(warnings) == 0 && !large {
+				file, entry, cached = p.lookupCache(path, content, text, info, result.Classifier.key(path))
+			}
+			if err == nil && !cached {
+				errKind = ParseErrorFrontmatter
+				if large {
+					file, err = processLargeFile(content, info, path, p.options)
 				} else {
-					// Store successfully parsed file
-					id := file.GetID()
-					result.Files[id] = file
-					// Register file with resolver for link resolution
-					p.resolver.AddFile(file)
-					result.Stats.ParsedFiles++
-					result.Stats.TotalLinks += len(file.Links)
+					file, err = processText(text, warnings, info, path, p.options)
 				}
-
-				// Check if we should log progress (inside mutex)
-				if result.Stats.ParsedFiles%100 == 0 {
-					shouldLogProgress = true
-					currentProgress = result.Stats.ParsedFiles + result.Stats.FailedFiles
+				if err == nil {
+					errKind = ParseErrorHook
+					err = runFileParsed(p.hooks, file)
 				}
-				mu.Unlock()
+				if err == nil && len(file.Warnings) > 0 {
+					entry = nil // Parsed again each time, so the warnings are reported on every index
+				}
+				if err == nil && entry != nil {
+					if entry.Data, err = encodeCachedFile(file); err != nil {
+						warnf(p.logger, "failed to cache '%s': %v", path, err)
+						entry, err = nil, nil
+					}
+				}
+			}
+			if tuner != nil {
+				if add := tuner.record(read.Sub(start), time.Since(read)); add > 0 {
+					logf(p.logger, "Parser I/O-bound, adding %d workers (now %d)", add, tuner.workers())
+					for i := 0; i < add; i++ {
+						wg.Add(1)
+						go worker()
+					}
+				}
+			}
+
+			// Update results (with mutex for thread safety)
+			mu.Lock()
+			var shouldLogProgress bool
+			var currentProgress int
 
-				// Log progress outside mutex to avoid holding lock during I/O
-				if shouldLogProgress {
-					log.Printf("Progress: %d/%d files parsed",
-						currentProgress, result.Stats.TotalFiles)
+			if err == nil {
+				for _, w := range file.Warnings {
+					result.ParseErrors = append(result.ParseErrors, warningParseError(path, w))
+				}
+			}
+			if err != nil {
+				// Record parse error
+				pe := ParseError{FilePath: path, Kind: errKind, Error: err}
+				var fe *FrontmatterError
+				if errors.As(err, &fe) {
+					pe.Line = fe.Line
 				}
+				result.ParseErrors = append(result.ParseErrors, pe)
+				if errors.Is(err, ErrNotText) {
+					result.Stats.SkippedFiles++
+				} else {
+					result.Stats.FailedFiles++
+				}
+			} else {
+				// Store successfully parsed file
+				id := file.GetID()
+				result.Files[id] = file
+				// Register file with resolver for link resolution
+				p.resolver.AddFile(file)
+				result.Stats.ParsedFiles++
+				result.Stats.TotalLinks += len(file.Links)
+				if entry != nil {
+					entry.NodeID = id
+					result.Cache = append(result.Cache, *entry)
+				}
+				if cached {
+					result.Unchanged[path] = true
+					result.Stats.CachedFiles++
+				}
+				if large {
+					result.Stats.LargeFiles++
+				}
+			}
+
+			// Check if we should log progress (inside mutex)
+			done := result.Stats.ParsedFiles + result.Stats.FailedFiles + result.Stats.SkippedFiles
+			if result.Stats.ParsedFiles%100 == 0 {
+				shouldLogProgress = true
+				currentProgress = done
+			}
+			mu.Unlock()
+
+			if p.progress != nil {
+				p.progress(done, result.Stats.TotalFiles)
+			}
+			guard.fileDone()
+
+			// Log progress outside mutex to avoid holding lock during I/O
+			if shouldLogProgress {
+				logf(p.logger, "Progress: %d/%d files parsed",
+					currentProgress, result.Stats.TotalFiles)
 			}
-		}(i)
+		}
+	}
+
+	// Start worker goroutines
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go worker()
 	}
 
 	// Wait for all workers to complete
 	wg.Wait()
+
+	result.Stats.Workers = workers
+	if tuner != nil {
+		result.Stats.Workers = tuner.workers()
+	}
+}
+
+// lookupCache hashes content, with rules, the key of the classify rules that
+// apply to the file, and restores the file, with text as its Content, from
+// the cache if its entry matches. The returned entry carries the hash for
+// caching this parse; its Data is set only on a hit.
+func (p *Parser) lookupCache(path string, content []byte, text string, info os.FileInfo, rules string) (*MarkdownFile, *models.CachedFile, bool) {
+	entry := &models.CachedFile{Path: path, Hash: contentHash(p.cacheSalt+"\x00"+rules, content)}
+	prev, ok := p.cache[path]
+	if !ok || prev.Hash != entry.Hash {
+		return nil, entry, false
+	}
+	file, err := decodeCachedFile(prev.Data, path, text, info)
+	if err != nil {
+		warnf(p.logger, "ignoring corrupt cache entry for '%s': %v", path, err)
+		return nil, entry, false
+	}
+	entry.Data = prev.Data
+	return file, entry, true
 }
 
 // resolveAllLinks resolves all WikiLinks in the parsed files
@@ -234,7 +496,7 @@ func (p *Parser) resolveAllLinks(result *ParseResult) {
 			// 2. Relative path resolution
 			// 3. Basename matching
 			// 4. Fuzzy/normalized matching
-			_, found := p.resolver.ResolveLink(link.Target, file.Path)
+			_, found := result.ResolveLink(link, file.Path)
 			if found {
 				result.Stats.ResolvedLinks++
 			} else {
@@ -250,6 +512,48 @@ func (p *Parser) resolveAllLinks(result *ParseResult) {
 	}
 }
 
+// collectPeople maps the notes in the people directory to the people they
+// are about, named by their filenames. Of two notes about the same person,
+// the one with the smaller ID is kept.
+func (p *Parser) collectPeople(result *ParseResult) {
+	if result.Mentions == nil {
+		return
+	}
+	for id, file := range result.Files {
+		if !result.Mentions.IsPersonNote(file.Path) {
+			continue
+		}
+		key := PersonKey(file.Path)
+		if kept, ok := result.People[key]; !ok || id < kept {
+			result.People[key] = id
+		}
+	}
+}
+
+// ResolveLink resolves a link from the file at sourcePath to a node ID.
+// Citations of a citekey with a BibTeX entry resolve to its reference node;
+// other links, including citations of literature notes named after their
+// citekey, resolve to files through the Resolver. With mentions on, mentions
+// resolve to the person's note in the people directory, then to any note
+// the Resolver finds, and always resolve: to PersonID otherwise.
+func (r *ParseResult) ResolveLink(link WikiLink, sourcePath string) (string, bool) {
+	if link.LinkType == CitationLinkType {
+		if _, ok := r.References[link.Target]; ok {
+			return ReferenceID(link.Target), true
+		}
+	}
+	if link.LinkType == MentionLinkType && r.Mentions != nil {
+		if id, ok := r.People[PersonKey(link.Target)]; ok {
+			return id, true
+		}
+		if id, ok := r.Resolver.ResolveLink(link.Target, sourcePath); ok {
+			return id, true
+		}
+		return PersonID(link.Target), true
+	}
+	return r.Resolver.ResolveLink(link.Target, sourcePath)
+}
+
 // GetFile retrieves a parsed file by its ID
 func (r *ParseResult) GetFile(id string) (*MarkdownFile, bool) {
 	file, found := r.Files[id]
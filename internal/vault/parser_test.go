@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ali01/mnemosyne/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -16,7 +18,7 @@ func TestNewParser(t *testing.T) {
 	parser := NewParser("/test/vault", 0, 0) // Use defaults
 	assert.NotNil(t, parser)
 	assert.Equal(t, "/test/vault", parser.vaultPath)
-	assert.Equal(t, 4, parser.concurrency) // Default concurrency
+	assert.Equal(t, 0, parser.concurrency) // Adaptive concurrency
 	assert.Equal(t, 100, parser.batchSize) // Default batch size
 }
 
@@ -155,6 +157,60 @@ Content`,
 	assert.True(t, errorPaths["invalid-yaml.md"])
 }
 
+func TestParser_LenientFrontmatterWarns(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "broken.md"), []byte("---\nid: broken\ntitle: a: b\n---\nBody"), 0o644))
+
+	parse := func(cache map[string]models.CachedFile) *ParseResult {
+		parser := NewParser(tempDir, 1, 0)
+		parser.SetOptions(ParseOptions{LenientFrontmatter: true})
+		parser.SetFileCache("s", cache)
+		result, err := parser.ParseVault()
+		require.NoError(t, err)
+		return result
+	}
+
+	result := parse(nil)
+	assert.Equal(t, 1, result.Stats.ParsedFiles)
+	assert.Zero(t, result.Stats.FailedFiles)
+	assert.Contains(t, result.Files, "broken")
+	require.Len(t, result.ParseErrors, 1)
+	assert.Equal(t, ParseErrorFrontmatter, result.ParseErrors[0].Kind)
+	assert.Equal(t, 3, result.ParseErrors[0].Line)
+
+	// Files with warnings are not cached, so the warning is reported again
+	assert.Empty(t, result.Cache)
+	assert.Len(t, parse(map[string]models.CachedFile{}).ParseErrors, 1)
+}
+
+type rejectHook struct {
+	NopHook
+	path string
+}
+
+func (h rejectHook) OnFileParsed(file *MarkdownFile) error {
+	if file.Path == h.path {
+		return fmt.Errorf("rejected")
+	}
+	return nil
+}
+
+func TestParser_HookErrorRecordsParseError(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "ok.md"), []byte("---\nid: ok\n---\n# OK"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "bad.md"), []byte("---\nid: bad\n---\n# Bad"), 0o644))
+
+	parser := NewParser(tempDir, 2, 0)
+	parser.SetHooks([]ParserHook{rejectHook{path: "bad.md"}})
+
+	result, err := parser.ParseVault()
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Stats.ParsedFiles)
+	assert.Equal(t, 1, result.Stats.FailedFiles)
+	require.Len(t, result.ParseErrors, 1)
+	assert.Equal(t, "bad.md", result.ParseErrors[0].FilePath)
+}
+
 func TestParser_Progress(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -442,3 +498,65 @@ Modified content %d`, i, i)
 	require.NoError(t, parseErr)
 	assert.GreaterOrEqual(t, result.Stats.ParsedFiles, 5)
 }
+
+func TestParser_Cancelled(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 10; i++ {
+		content := fmt.Sprintf("---\nid: n%d\n---\nbody\n", i)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("n%d.md", i)), []byte(content), 0o644))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	parser := NewParser(dir, 2, 0)
+	_, err := parser.ParseVaultContext(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestParser_FileCache(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("a.md", "---\nid: a\ntags: [x]\npriority: 2\ndue: 2024-01-15\n---\n# A\nLinks to [[b]].\n")
+	write("b.md", "---\nid: b\n---\nPlain.\n")
+
+	parse := func(salt string, cache map[string]models.CachedFile) *ParseResult {
+		parser := NewParser(dir, 2, 0)
+		parser.SetFileCache(salt, cache)
+		result, err := parser.ParseVault()
+		require.NoError(t, err)
+		return result
+	}
+	toMap := func(entries []models.CachedFile) map[string]models.CachedFile {
+		m := make(map[string]models.CachedFile)
+		for _, e := range entries {
+			m[e.Path] = e
+		}
+		return m
+	}
+
+	first := parse("s", nil)
+	assert.Equal(t, 0, first.Stats.CachedFiles)
+	require.Len(t, first.Cache, 2)
+
+	second := parse("s", toMap(first.Cache))
+	assert.Equal(t, 2, second.Stats.CachedFiles)
+	assert.True(t, second.Unchanged["a.md"])
+	a, b := first.Files["a"], second.Files["a"]
+	assert.Equal(t, a.Title, b.Title)
+	assert.Equal(t, a.Content, b.Content)
+	assert.Equal(t, a.Links, b.Links)
+	assert.Equal(t, a.Outline, b.Outline)
+	assert.Equal(t, a.WordCount, b.WordCount)
+	assert.Equal(t, a.Frontmatter, b.Frontmatter, "frontmatter types survive the cache")
+
+	write("b.md", "---\nid: b\n---\nEdited.\n")
+	third := parse("s", toMap(second.Cache))
+	assert.Equal(t, 1, third.Stats.CachedFiles)
+	assert.False(t, third.Unchanged["b.md"])
+
+	// A different salt means different parse settings
+	fourth := parse("other", toMap(third.Cache))
+	assert.Equal(t, 0, fourth.Stats.CachedFiles)
+}
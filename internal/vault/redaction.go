@@ -0,0 +1,57 @@
+package vault
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultRedaction replaces redacted text when a rule sets no replacement.
+const DefaultRedaction = "[REDACTED]"
+
+// Redaction removes sensitive text, such as secrets or private blocks, from
+// notes before they are parsed, stored or served.
+type Redaction struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// NewRedaction compiles a redaction rule. Every match of pattern is replaced
+// with replacement, a regexp expansion template (e.g. "token=[REDACTED]" or
+// "${1}***").
+func NewRedaction(pattern, replacement string) (*Redaction, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("redaction %q: %w", pattern, err)
+	}
+	return &Redaction{pattern: re, replacement: replacement}, nil
+}
+
+// Apply returns content with every match of the rule replaced.
+func (r *Redaction) Apply(content string) string {
+	return r.pattern.ReplaceAllString(content, r.replacement)
+}
+
+// redact applies rules to content in order.
+func redact(rules []Redaction, content string) string {
+	for i := range rules {
+		content = rules[i].Apply(content)
+	}
+	return content
+}
+
+// RedactLines applies opts' redactions to lines, the lines of a note, and
+// blanks out its comments when the dialect strips them. The lines are
+// redacted as one text, so rules spanning lines apply; if that changes the
+// number of lines, they no longer match up and every line comes back empty.
+func RedactLines(lines []string, opts ParseOptions) []string {
+	text := redact(opts.Redactions, strings.Join(lines, "\n"))
+	if opts.dialect().StripComments {
+		text = stripComments(text)
+	}
+	redacted := strings.Split(text, "\n")
+	if len(redacted) != len(lines) {
+		return make([]string, len(lines))
+	}
+	return redacted
+}
@@ -0,0 +1,80 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+func TestRedaction_Apply(t *testing.T) {
+	r, err := NewRedaction(`(api[_-]key\s*[:=]\s*)\S+`, "${1}"+DefaultRedaction)
+	require.NoError(t, err)
+	assert.Equal(t, "api_key = [REDACTED] and api-key: [REDACTED]", r.Apply("api_key = abc123 and api-key: xyz"))
+
+	_, err = NewRedaction(`(`, "")
+	assert.Error(t, err)
+}
+
+func TestProcessContent_Redactions(t *testing.T) {
+	private, err := NewRedaction(`(?s)%%\s*private\b.*?%%`, DefaultRedaction)
+	require.NoError(t, err)
+	opts := ParseOptions{Redactions: []Redaction{*private}}
+
+	content := "---\nid: n\n---\nPublic start. %%private [[Secret Link]] passwords%% Public end. [[Public Link]]\n"
+	file, err := ProcessMarkdownReaderWithOptions(strings.NewReader(content), "n.md", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "n", file.GetID())
+	assert.NotContains(t, file.Content, "passwords")
+	assert.Contains(t, file.Content, "Public start. [REDACTED] Public end.")
+	assert.NotContains(t, file.Excerpt, "passwords")
+	require.Len(t, file.Links, 1, "links inside redacted text are dropped")
+	assert.Equal(t, "Public Link", file.Links[0].Target)
+
+	// Other comments are left alone
+	file, err = ProcessMarkdownReaderWithOptions(strings.NewReader("---\nid: m\n---\n%%draft%% text"), "m.md", opts)
+	require.NoError(t, err)
+	assert.Contains(t, file.Content, "%%draft%%")
+}
+
+func TestRedactLines(t *testing.T) {
+	key, err := NewRedaction(`(?s)BEGIN.*?END`, "BEGIN\nEND")
+	require.NoError(t, err)
+	opts := ParseOptions{Redactions: []Redaction{*key}}
+
+	// Rules spanning lines apply, and comments are blanked
+	lines := []string{"BEGIN", "secret", "END", "a %%b%%"}
+	assert.Equal(t, []string{"", "", "", ""}, RedactLines(lines, opts), "lines no longer match up")
+	lines = []string{"BEGIN", "END", "a %%b%%"}
+	assert.Equal(t, []string{"BEGIN", "END", "a      "}, RedactLines(lines, opts))
+	opts.Dialect = &DialectCommonMark
+	assert.Equal(t, []string{"BEGIN", "END", "a %%b%%"}, RedactLines(lines, opts))
+
+	key, err = NewRedaction(`(?s)BEGIN.*?END`, DefaultRedaction)
+	require.NoError(t, err)
+	opts.Redactions = []Redaction{*key}
+	assert.Equal(t, []string{"x", "[REDACTED] y"}, RedactLines([]string{"x", "BEGIN s END y"}, opts))
+}
+
+func TestParser_RedactsCachedFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.md"), []byte("---\nid: a\n---\nkey: sk-123\n"), 0o644))
+	rule, err := NewRedaction(`sk-\w+`, DefaultRedaction)
+	require.NoError(t, err)
+
+	var cache map[string]models.CachedFile
+	for range 2 {
+		parser := NewParser(dir, 1, 0)
+		parser.SetOptions(ParseOptions{Redactions: []Redaction{*rule}})
+		parser.SetFileCache("s", cache)
+		result, err := parser.ParseVault()
+		require.NoError(t, err)
+		assert.Equal(t, "---\nid: a\n---\nkey: [REDACTED]\n", result.Files["a"].Content)
+		cache = map[string]models.CachedFile{"a.md": result.Cache[0]}
+	}
+}
@@ -11,7 +11,9 @@ type LinkResolver struct {
 	pathToID        map[string]string   // Full path -> ID
 	basenameToIDs   map[string][]string // Basename -> []IDs (multiple files can have same name)
 	normalizedToIDs map[string][]string // Normalized name -> []IDs
+	slugToIDs       map[string][]string // Slug of the basename -> []IDs
 	idToPath        map[string]string   // ID -> Full path
+	slugger         *Slugger            // Slugs basenames; nil disables slug matching
 }
 
 // NewLinkResolver creates a new link resolver
@@ -20,10 +22,18 @@ func NewLinkResolver() *LinkResolver {
 		pathToID:        make(map[string]string),
 		basenameToIDs:   make(map[string][]string),
 		normalizedToIDs: make(map[string][]string),
+		slugToIDs:       make(map[string][]string),
 		idToPath:        make(map[string]string),
 	}
 }
 
+// SetSlugger makes links that match no file otherwise resolve to the file
+// whose basename has the same slug, e.g. "[[Cafe]]" to "Café.md". It must be
+// called before files are added.
+func (r *LinkResolver) SetSlugger(s *Slugger) {
+	r.slugger = s
+}
+
 // AddFile registers a file with the resolver
 func (r *LinkResolver) AddFile(file *MarkdownFile) {
 	id := file.GetID()
@@ -43,6 +53,11 @@ func (r *LinkResolver) AddFile(file *MarkdownFile) {
 	// Store normalized mapping (lowercase, no special chars)
 	normalized := normalizeForMatching(basename)
 	r.normalizedToIDs[normalized] = append(r.normalizedToIDs[normalized], id)
+
+	if r.slugger != nil {
+		slug := r.slugger.Slug(basename)
+		r.slugToIDs[slug] = append(r.slugToIDs[slug], id)
+	}
 }
 
 // ResolveLink resolves a WikiLink target to a file ID
@@ -70,6 +85,10 @@ func (r *LinkResolver) ResolveLink(target string, sourceFile string) (string, bo
 		return id, true
 	}
 
+	if id, found := r.trySlugMatch(basename, sourceFile); found {
+		return id, true
+	}
+
 	return "", false
 }
 
@@ -123,6 +142,19 @@ func (r *LinkResolver) tryNormalizedMatch(basename, sourceFile string) (string,
 	return r.selectBestMatch(ids, sourceFile)
 }
 
+// trySlugMatch attempts matching by slug, transliterating the basename
+func (r *LinkResolver) trySlugMatch(basename, sourceFile string) (string, bool) {
+	if r.slugger == nil {
+		return "", false
+	}
+	ids, found := r.slugToIDs[r.slugger.Slug(basename)]
+	if !found {
+		return "", false
+	}
+
+	return r.selectBestMatch(ids, sourceFile)
+}
+
 // selectBestMatch selects the best match from multiple candidates
 func (r *LinkResolver) selectBestMatch(ids []string, sourceFile string) (string, bool) {
 	if len(ids) == 0 {
@@ -520,3 +520,36 @@ func TestLinkResolver_NormalizationEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+func TestLinkResolver_SlugMatching(t *testing.T) {
+	files := []*MarkdownFile{
+		{Path: "Café Ørsted.md", Frontmatter: &FrontmatterData{ID: "cafe"}},
+		{Path: "notes/Москва.md", Frontmatter: &FrontmatterData{ID: "moscow"}},
+	}
+
+	// Without a slugger, transliterated targets do not resolve
+	plain := NewLinkResolver()
+	for _, f := range files {
+		plain.AddFile(f)
+	}
+	_, found := plain.ResolveLink("Cafe Orsted", "")
+	assert.False(t, found)
+
+	resolver := NewLinkResolver()
+	resolver.SetSlugger(&Slugger{})
+	for _, f := range files {
+		resolver.AddFile(f)
+	}
+	for target, wantID := range map[string]string{
+		"Cafe Orsted":  "cafe",
+		"cafe-orsted!": "cafe",
+		"moskva":       "moscow",
+		"Москва":       "moscow",
+	} {
+		id, found := resolver.ResolveLink(target, "")
+		assert.True(t, found, "Failed to resolve: %s", target)
+		assert.Equal(t, wantID, id)
+	}
+	_, found = resolver.ResolveLink("moskvy", "")
+	assert.False(t, found)
+}
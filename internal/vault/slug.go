@@ -0,0 +1,160 @@
+package vault
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultSlugLength caps slugs, in characters, when Slugger.MaxLength is
+// unset.
+const DefaultSlugLength = 80
+
+// slugHashLength is the length of the hex hash that keeps slugs of titles
+// with untransliterated letters distinct.
+const slugHashLength = 8
+
+// Slugger turns titles into stable slugs for URLs and node IDs. The zero
+// value transliterates titles to lowercase ASCII words joined by dashes,
+// e.g. "Café Ørsted" -> "cafe-orsted" and "Москва" -> "moskva". Letters
+// without a transliteration, such as CJK, are dropped and a hash of the
+// title is appended so that such titles still get distinct, predictable
+// slugs; a title with no letters or digits at all, e.g. only emoji, is
+// slugged to the hash alone.
+type Slugger struct {
+	// Unicode keeps the letters and digits of every script, lowercased,
+	// instead of transliterating them, e.g. "東京 Notes" -> "東京-notes".
+	Unicode bool
+
+	// Separator joins words. Empty means "-".
+	Separator string
+
+	// MaxLength caps slugs in characters, cutting between words where
+	// possible. Zero means DefaultSlugLength.
+	MaxLength int
+}
+
+// Slug returns the slug of title. The same title always gives the same slug.
+func (s *Slugger) Slug(title string) string {
+	title = strings.TrimSpace(strings.ToLower(norm.NFKC.String(title)))
+	sep := s.Separator
+	if sep == "" {
+		sep = "-"
+	}
+	maxLen := s.MaxLength
+	if maxLen <= 0 {
+		maxLen = DefaultSlugLength
+	}
+
+	var words []string
+	var word strings.Builder
+	lossy := false
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range title {
+		switch {
+		case unicode.IsMark(r):
+			if s.Unicode {
+				word.WriteRune(r) // Vowel signs of e.g. Devanagari
+			}
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case s.Unicode || r < utf8.RuneSelf:
+			word.WriteRune(r)
+		default:
+			if t, ok := transliterate(r); ok {
+				word.WriteString(t)
+			} else {
+				lossy = true
+				flush()
+			}
+		}
+	}
+	flush()
+
+	hash := ""
+	if lossy || len(words) == 0 {
+		h := fnv.New32a()
+		h.Write([]byte(title))
+		hash = fmt.Sprintf("%0*x", slugHashLength, h.Sum32())
+		if len(words) == 0 {
+			return hash
+		}
+		maxLen = max(maxLen-utf8.RuneCountInString(sep)-slugHashLength, 1)
+	}
+
+	var b strings.Builder
+	n := 0
+	for i, w := range words {
+		wn := utf8.RuneCountInString(w)
+		if i > 0 {
+			if n+utf8.RuneCountInString(sep)+wn > maxLen {
+				break
+			}
+			b.WriteString(sep)
+			n += utf8.RuneCountInString(sep)
+		} else if wn > maxLen {
+			w = string([]rune(w)[:maxLen])
+		}
+		b.WriteString(w)
+		n += wn
+	}
+	if hash != "" {
+		b.WriteString(sep)
+		b.WriteString(hash)
+	}
+	return b.String()
+}
+
+// transliterate returns the lowercase ASCII spelling of a letter or digit,
+// if it has one: Latin letters lose their diacritics, and Greek and Cyrillic
+// letters are romanized.
+func transliterate(r rune) (string, bool) {
+	if t, ok := transliterations[r]; ok {
+		return t, true
+	}
+	var b strings.Builder
+	for _, d := range norm.NFKD.String(string(r)) {
+		switch {
+		case unicode.IsMark(d):
+		case d < utf8.RuneSelf:
+			b.WriteRune(unicode.ToLower(d))
+		default:
+			t, ok := transliterations[d]
+			if !ok {
+				return "", false
+			}
+			b.WriteString(t)
+		}
+	}
+	return b.String(), true
+}
+
+// transliterations spell lowercase letters that do not decompose to ASCII.
+var transliterations = map[rune]string{
+	// Latin
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th",
+	'ł': "l", 'ı': "i", 'ħ': "h", 'ŋ': "ng", 'ĸ': "k", 'ŀ': "l", 'ſ': "s",
+
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'і': "i",
+	'ї': "yi", 'є': "ye", 'ґ': "g",
+}
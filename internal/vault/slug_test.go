@@ -0,0 +1,79 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlugger_Slug(t *testing.T) {
+	var s Slugger
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Hello, World!", "hello-world"},
+		{"  Café Ørsted  ", "cafe-orsted"},
+		{"Straße & Œuvre", "strasse-oeuvre"},
+		{"Москва — столица", "moskva-stolitsa"},
+		{"Αθήνα", "athina"},
+		{"Ｆｕｌｌｗｉｄｔｈ １２３", "fullwidth-123"},
+		{"snake_case-and--dashes", "snake-case-and-dashes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			assert.Equal(t, tt.want, s.Slug(tt.title))
+		})
+	}
+}
+
+func TestSlugger_SlugUntransliterated(t *testing.T) {
+	var s Slugger
+
+	// CJK letters are dropped and a hash of the title keeps slugs distinct
+	tokyo := s.Slug("東京 Notes")
+	assert.Regexp(t, `^notes-[0-9a-f]{8}$`, tokyo)
+	assert.Equal(t, tokyo, s.Slug("東京 Notes"), "slugs are stable")
+	assert.NotEqual(t, tokyo, s.Slug("大阪 Notes"))
+
+	// Titles without letters or digits slug to the hash alone
+	assert.Regexp(t, `^[0-9a-f]{8}$`, s.Slug("🚀✨"))
+	assert.NotEqual(t, s.Slug("🚀✨"), s.Slug("🎉"))
+
+	// Emoji alone do not need a hash
+	assert.Equal(t, "launch-day", s.Slug("🚀 Launch Day 🎉"))
+}
+
+func TestSlugger_Options(t *testing.T) {
+	uni := Slugger{Unicode: true, Separator: "_"}
+	assert.Equal(t, "東京_notes", uni.Slug("東京 Notes"))
+	assert.Equal(t, "café_नमस्ते", uni.Slug("Café नमस्ते"))
+
+	short := Slugger{MaxLength: 12}
+	assert.Equal(t, "one-two", short.Slug("one two three four"))
+	assert.Equal(t, "abcdefghijab", short.Slug(strings.Repeat("abcdefghij", 3)))
+	got := short.Slug("東京 one two")
+	assert.Regexp(t, `^one-[0-9a-f]{8}$`, got)
+	assert.LessOrEqual(t, len(got), 12)
+}
+
+func TestProcessContent_SlugIDs(t *testing.T) {
+	opts := ParseOptions{SlugIDs: true}
+
+	file, err := ProcessMarkdownReaderWithOptions(strings.NewReader("# Café\n"), "notes/Café Ørsted.md", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "cafe-orsted", file.GetID())
+
+	// ID rules and frontmatter ids come first
+	rule, err := NewIDRule(`^(\d{12})`, "")
+	require.NoError(t, err)
+	opts.IDRules = []IDRule{*rule}
+	file, err = ProcessMarkdownReaderWithOptions(strings.NewReader("body"), "202301151230 Título.md", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "202301151230", file.GetID())
+	file, err = ProcessMarkdownReaderWithOptions(strings.NewReader("---\nid: explicit\n---\n"), "Título.md", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "explicit", file.GetID())
+}
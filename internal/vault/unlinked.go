@@ -0,0 +1,200 @@
+package vault
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+// UnlinkedMentionType is the EdgeType of potential edges from a note to a
+// note it names in plain text without linking to it.
+const UnlinkedMentionType = "unlinked-mention"
+
+// minMentionLength is the fewest letters and digits a title or alias needs to
+// be looked for; shorter names are mostly noise.
+const minMentionLength = 3
+
+// inlineCodeRegex matches `code` spans within a line.
+var inlineCodeRegex = regexp.MustCompile("`[^`\n]+`")
+
+// mentionName is a title or alias as the words it is made of.
+type mentionName struct {
+	words    []string
+	targetID string
+}
+
+// mentionWord is a word of a note's text, lowercased, with its byte span.
+type mentionWord struct {
+	text       string
+	start, end int
+}
+
+// NoteAliases returns the aliases in a note's "aliases" frontmatter, a list
+// or a single string, as Obsidian reads them.
+func NoteAliases(metadata models.JSONMetadata) []string {
+	switch v := metadata["aliases"].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		aliases := make([]string, 0, len(v))
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				aliases = append(aliases, s)
+			}
+		}
+		return aliases
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// addUnlinkedMentions records on each note the notes whose titles or aliases
+// its text names without linking to them, as UnlinkedMentionType potential
+// edges weighted by how often they are named. Names are matched word by word
+// ignoring case and punctuation, so "Machine-Learning" names "machine
+// learning"; the longest name at each place wins. Names shared by several
+// nodes are ambiguous and skipped. Frontmatter, code, links and URLs are not
+// searched.
+func addUnlinkedMentions(nodeMap map[string]*models.VaultNode, edges []models.VaultEdge) {
+	byFirst := make(map[string][]mentionName)
+	owners := make(map[string]string) // Name -> target ID, "" when ambiguous
+	for id, node := range nodeMap {
+		names := append([]string{node.Title}, NoteAliases(node.Metadata)...)
+		for _, name := range names {
+			words := wordsOf(name)
+			key := strings.Join(words, " ")
+			if utf8.RuneCountInString(strings.ReplaceAll(key, " ", "")) < minMentionLength {
+				continue
+			}
+			if owner, seen := owners[key]; seen {
+				if owner != id {
+					owners[key] = ""
+				}
+				continue
+			}
+			owners[key] = id
+			byFirst[words[0]] = append(byFirst[words[0]], mentionName{words: words, targetID: id})
+		}
+	}
+	for first, names := range byFirst {
+		kept := names[:0]
+		for _, n := range names {
+			if owners[strings.Join(n.words, " ")] != "" {
+				kept = append(kept, n)
+			}
+		}
+		// Longest names first, so they win over names they contain
+		sort.SliceStable(kept, func(i, j int) bool { return len(kept[i].words) > len(kept[j].words) })
+		byFirst[first] = kept
+	}
+
+	linked := make(map[edgeKey]bool, len(edges))
+	for _, e := range edges {
+		linked[edgeKey{sourceID: e.SourceID, targetID: e.TargetID}] = true
+	}
+
+	for sourceID, source := range nodeMap {
+		if !strings.HasSuffix(source.FilePath, ".md") {
+			continue // References and people without notes have no text of their own
+		}
+		body := StripFrontmatter(source.Content)
+		words := mentionWords(blankUnsearched(body))
+		byTarget := make(map[string]*models.VaultEdge)
+		for i := 0; i < len(words); {
+			name, ok := matchName(words[i:], byFirst[words[i].text])
+			if !ok {
+				i++
+				continue
+			}
+			last := words[i+len(name.words)-1]
+			if name.targetID != sourceID && !linked[edgeKey{sourceID: sourceID, targetID: name.targetID}] {
+				if e, ok := byTarget[name.targetID]; ok {
+					e.Weight++
+				} else {
+					byTarget[name.targetID] = &models.VaultEdge{
+						SourceID:    sourceID,
+						TargetID:    name.targetID,
+						EdgeType:    UnlinkedMentionType,
+						DisplayText: body[words[i].start:last.end],
+						Weight:      1,
+						CreatedAt:   source.UpdatedAt,
+					}
+				}
+			}
+			i += len(name.words)
+		}
+		source.Potential = source.Potential[:0]
+		for _, e := range byTarget {
+			source.Potential = append(source.Potential, *e)
+		}
+		sort.Slice(source.Potential, func(i, j int) bool {
+			return source.Potential[i].TargetID < source.Potential[j].TargetID
+		})
+	}
+}
+
+// matchName returns the first of names whose words start words.
+func matchName(words []mentionWord, names []mentionName) (mentionName, bool) {
+	for _, name := range names {
+		if len(name.words) > len(words) {
+			continue
+		}
+		match := true
+		for k, w := range name.words {
+			if words[k].text != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return name, true
+		}
+	}
+	return mentionName{}, false
+}
+
+// blankUnsearched replaces the parts of a note's body that are not prose
+// with spaces, keeping offsets: code, citations, wikilinks, markdown links
+// and URLs.
+func blankUnsearched(body string) string {
+	text := blankCitationsAndCode(body)
+	for _, re := range []*regexp.Regexp{inlineCodeRegex, wikiLinkRegex, inlineLinkRegex, urlRegex} {
+		text = re.ReplaceAllStringFunc(text, func(s string) string {
+			return strings.Repeat(" ", len(s))
+		})
+	}
+	return text
+}
+
+// mentionWords splits text into lowercased runs of letters and digits.
+func mentionWords(text string) []mentionWord {
+	var words []mentionWord
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if inWord && start < 0 {
+			start = i
+		} else if !inWord && start >= 0 {
+			words = append(words, mentionWord{text: strings.ToLower(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, mentionWord{text: strings.ToLower(text[start:]), start: start, end: len(text)})
+	}
+	return words
+}
+
+// wordsOf returns the lowercased words of a name.
+func wordsOf(name string) []string {
+	var words []string
+	for _, w := range mentionWords(name) {
+		words = append(words, w.text)
+	}
+	return words
+}
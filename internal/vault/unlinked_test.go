@@ -0,0 +1,75 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ali01/mnemosyne/internal/models"
+)
+
+func TestNoteAliases(t *testing.T) {
+	assert.Equal(t, []string{"ML"}, NoteAliases(models.JSONMetadata{"aliases": "ML"}))
+	assert.Equal(t, []string{"ML", "AI"}, NoteAliases(models.JSONMetadata{"aliases": []any{"ML", 3, "AI"}}))
+	assert.Nil(t, NoteAliases(models.JSONMetadata{"title": "x"}))
+	assert.Nil(t, NoteAliases(nil))
+}
+
+func TestBuildGraphUnlinkedMentions(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Machine Learning.md": "---\nid: ml\naliases: [statistical learning]\n---\nLearning from data.\n",
+		"Learning.md":         "---\nid: learning\n---\nHow we learn.\n",
+		"Go.md":               "---\nid: go\n---\nToo short to look for.\n",
+		"a/Notes.md":          "---\nid: notes-a\n---\nOne of two.\n",
+		"b/Notes.md":          "---\nid: notes-b\n---\nTwo of two.\n",
+		"essay.md": "---\nid: essay\ntags: [machine learning]\n---\n" +
+			"Machine-learning and MACHINE LEARNING beat plain learning.\n" +
+			"Statistical learning too. Go, notes.\n" +
+			"Not `machine learning`, [[Learning|learning]] or https://x.org/machine-learning.\n" +
+			"```\nmachine learning\n```\n",
+		"linked.md": "---\nid: linked\n---\nSee [[Machine Learning]]; machine learning again.\n",
+	}
+	for path, content := range files {
+		full := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0o750))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0o600))
+	}
+	result, err := NewParser(dir, 0, 0).ParseVault()
+	require.NoError(t, err)
+
+	graph, err := NewGraphBuilder(GraphBuilderConfig{}).BuildGraph(result)
+	require.NoError(t, err)
+	for _, n := range graph.Nodes {
+		assert.Empty(t, n.Potential, "off by default")
+	}
+
+	graph, err = NewGraphBuilder(GraphBuilderConfig{UnlinkedMentions: true}).BuildGraph(result)
+	require.NoError(t, err)
+	nodes := make(map[string]models.VaultNode)
+	for _, n := range graph.Nodes {
+		nodes[n.ID] = n
+	}
+	for _, e := range graph.Edges {
+		assert.NotEqual(t, UnlinkedMentionType, e.EdgeType, "potential edges are not edges")
+	}
+
+	// The longest name wins and aliases count; Learning is linked already,
+	// Go too short and Notes ambiguous
+	essay := nodes["essay"].Potential
+	require.Len(t, essay, 1)
+	assert.Equal(t, models.VaultEdge{
+		SourceID: "essay", TargetID: "ml", EdgeType: UnlinkedMentionType,
+		DisplayText: "Machine-learning", Weight: 3, CreatedAt: nodes["essay"].UpdatedAt,
+	}, essay[0])
+
+	// Linked targets are not reported, nor are notes naming themselves
+	assert.Empty(t, nodes["linked"].Potential)
+	ml := nodes["ml"].Potential
+	require.Len(t, ml, 1)
+	assert.Equal(t, "learning", ml[0].TargetID)
+	assert.Equal(t, "Learning", ml[0].DisplayText)
+}
@@ -0,0 +1,36 @@
+package vault
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Matches http(s) URLs up to whitespace or a character that ends them in
+// markdown: brackets, parentheses, quotes, angle brackets and backticks
+var urlRegex = regexp.MustCompile("https?://[^\\s<>\"'`()\\[\\]]+")
+
+// ExtractURLs returns the distinct http(s) URLs in a note body, in
+// order of first appearance. URLs in fenced code blocks are skipped, and
+// trailing sentence punctuation is not part of a URL.
+func ExtractURLs(body string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if fenceRegex.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		for _, u := range urlRegex.FindAllString(line, -1) {
+			u = strings.TrimRight(u, ".,;:!?*_~")
+			if len(u) > len("https://") && !seen[u] {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+	}
+	return urls
+}
@@ -0,0 +1,35 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractURLs(t *testing.T) {
+	body := strings.Join([]string{
+		"See https://example.com/a. And [docs](https://docs.example.com/x?y=1#z), or <http://old.example.org>.",
+		"Again: https://example.com/a",
+		"```",
+		"curl https://in-code.example.com",
+		"```",
+		"**https://bold.example.com**, ftp://not-http.example.com and http:// alone",
+	}, "\n")
+
+	assert.Equal(t, []string{
+		"https://example.com/a",
+		"https://docs.example.com/x?y=1#z",
+		"http://old.example.org",
+		"https://bold.example.com",
+	}, ExtractURLs(body))
+	assert.Empty(t, ExtractURLs("No links here."))
+}
+
+func TestProcessMarkdownURLs(t *testing.T) {
+	content := "---\nid: x1\nsource: https://frontmatter.example.com\n---\nRead https://body.example.com today.\n"
+	file, err := ProcessMarkdownReader(strings.NewReader(content), "x1.md")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://body.example.com"}, file.URLs)
+}
@@ -0,0 +1,54 @@
+package vault
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// DiskUsage is the space a vault's files take on disk.
+type DiskUsage struct {
+	MarkdownFiles   int   `json:"markdown_files"`
+	MarkdownBytes   int64 `json:"markdown_bytes"`
+	AttachmentFiles int   `json:"attachment_files"` // Every other file: images, PDFs, BibTeX and the like
+	AttachmentBytes int64 `json:"attachment_bytes"`
+}
+
+// MeasureDisk adds up the sizes of the regular files in the vault at
+// vaultPath, skipping hidden names (like .git and .obsidian) as parsing
+// does. Files are counted whether or not ignore patterns leave them out of
+// the graph, and symlinks are not followed, so nothing is counted twice.
+func MeasureDisk(ctx context.Context, vaultPath string) (DiskUsage, error) {
+	var u DiskUsage
+	err := filepath.WalkDir(vaultPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path != vaultPath && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(path, ".md") {
+			u.MarkdownFiles++
+			u.MarkdownBytes += info.Size()
+		} else {
+			u.AttachmentFiles++
+			u.AttachmentBytes += info.Size()
+		}
+		return nil
+	})
+	return u, err
+}
@@ -0,0 +1,229 @@
+package vault
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SymlinkPolicy says what the parser does with symlinks inside the vault.
+type SymlinkPolicy string
+
+// Symlink policies. The zero value is SymlinksFollow.
+const (
+	SymlinksFollow SymlinkPolicy = "follow" // Follow links whose targets are inside the vault
+	SymlinksSkip   SymlinkPolicy = "skip"   // Ignore links, reading only regular files and directories
+)
+
+var (
+	// ErrOutsideVault is returned for a path that resolves, through
+	// symlinks, to outside the vault root. Such files are never read.
+	ErrOutsideVault = errors.New("resolves outside the vault")
+
+	// ErrSymlinkCycle is reported for a directory link to one of its own
+	// ancestors. The link is not followed.
+	ErrSymlinkCycle = errors.New("symlink cycle")
+
+	// errSymlinkSkipped is returned for a path through a symlink when
+	// symlinks are skipped.
+	errSymlinkSkipped = errors.New("path goes through a symlink and symlinks are skipped")
+)
+
+// walkResult is what walkVault finds.
+type walkResult struct {
+	files      []string     // Markdown files, relative to the vault root
+	bibs       []string     // BibTeX files, relative to the vault root
+	totalBytes int64        // Bytes of the markdown files that will be read
+	problems   []ParseError // Symlinks not followed, and DirConfigName files not valid
+	classifier *Classifier  // The DirConfigName files found, merged with ParseOptions.Rules
+}
+
+// vaultWalker walks a vault by its real paths. Real files and directories
+// are walked first and symlinks after, so each file is collected once under
+// its own path when it has one, and under the first link to it otherwise.
+type vaultWalker struct {
+	ctx    context.Context
+	root   string // Real path of the vault root
+	opts   ParseOptions
+	dirs   map[string]bool // Real paths of the directories walked
+	files  map[string]bool // Real paths of the files collected
+	links  []vaultLink     // Symlinks found and not yet followed
+	result walkResult
+}
+
+// vaultLink is a symlink found at path, a real path, named rel in the vault.
+type vaultLink struct {
+	path string
+	rel  string
+}
+
+// walkVault collects the vault's markdown and BibTeX files, skipping hidden
+// names (like .git and .obsidian) and those ignored by DirConfigName files
+// or opts.Rules. Symlinks are followed or skipped by
+// opts.Symlinks; a followed link is only read when its target is inside the
+// vault, and each real directory is walked once, so cycles end.
+func walkVault(ctx context.Context, vaultPath string, opts ParseOptions) (*walkResult, error) {
+	root, err := filepath.EvalSymlinks(vaultPath)
+	if err != nil {
+		return nil, err
+	}
+	w := &vaultWalker{ctx: ctx, root: root, opts: opts, dirs: make(map[string]bool), files: make(map[string]bool)}
+	w.result.classifier = newClassifier(opts.Rules)
+	if err := w.walkDir(root, ""); err != nil {
+		return nil, err
+	}
+	for len(w.links) > 0 {
+		link := w.links[0]
+		w.links = w.links[1:]
+		if err := w.follow(link); err != nil {
+			return nil, err
+		}
+	}
+	slices.Sort(w.result.files)
+	slices.Sort(w.result.bibs)
+	return &w.result, nil
+}
+
+// walkDir collects the files under dir, a real path named rel in the vault,
+// after loading its DirConfigName file.
+func (w *vaultWalker) walkDir(dir, rel string) error {
+	w.dirs[dir] = true
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if cfg, err := loadDirConfig(dir); err != nil {
+		w.result.problems = append(w.result.problems, ParseError{
+			FilePath: filepath.Join(rel, DirConfigName), Kind: ParseErrorDirConfig, Error: err,
+		})
+	} else if cfg != nil {
+		w.result.classifier.dirs[filepath.ToSlash(cmp.Or(rel, "."))] = *cfg
+	}
+	for _, e := range entries {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+		entryRel := filepath.Join(rel, e.Name())
+		if strings.HasPrefix(e.Name(), ".") || w.result.classifier.ignored(entryRel) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		switch {
+		case e.Type()&os.ModeSymlink != 0:
+			if w.opts.Symlinks != SymlinksSkip {
+				w.links = append(w.links, vaultLink{path: path, rel: entryRel})
+			}
+		case e.IsDir():
+			if err := w.walkDir(path, entryRel); err != nil {
+				return err
+			}
+		default:
+			info, err := e.Info()
+			if err != nil {
+				return err
+			}
+			w.addFile(path, entryRel, info)
+		}
+	}
+	return nil
+}
+
+// follow walks or collects the target of link, or records why it cannot.
+func (w *vaultWalker) follow(link vaultLink) error {
+	target, err := filepath.EvalSymlinks(link.path)
+	if err != nil {
+		w.problem(link.rel, fmt.Errorf("broken symlink: %w", err))
+		return nil
+	}
+	if !withinDir(w.root, target) {
+		w.problem(link.rel, fmt.Errorf("symlink %w", ErrOutsideVault))
+		return nil
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		w.problem(link.rel, fmt.Errorf("broken symlink: %w", err))
+		return nil
+	}
+	if !info.IsDir() {
+		w.addFile(target, link.rel, info)
+		return nil
+	}
+	if w.dirs[target] {
+		// Links to an ancestor would be walked forever; other aliases of
+		// walked directories are skipped quietly
+		if withinDir(target, filepath.Dir(link.path)) {
+			w.problem(link.rel, fmt.Errorf("%w: links to %s", ErrSymlinkCycle, w.vaultRel(target)))
+		}
+		return nil
+	}
+	return w.walkDir(target, link.rel)
+}
+
+// addFile collects the file at path, a real path named rel in the vault,
+// unless it is not a markdown or BibTeX file or was collected already.
+func (w *vaultWalker) addFile(path, rel string, info os.FileInfo) {
+	if !info.Mode().IsRegular() || w.files[path] {
+		return
+	}
+	switch {
+	case strings.HasSuffix(rel, ".md"):
+		w.result.files = append(w.result.files, rel)
+		if w.opts.tooLarge(info) {
+			w.result.totalBytes += min(info.Size(), largeFileHead)
+		} else {
+			w.result.totalBytes += info.Size()
+		}
+	case strings.HasSuffix(rel, ".bib"):
+		w.result.bibs = append(w.result.bibs, rel)
+	default:
+		return
+	}
+	w.files[path] = true
+}
+
+func (w *vaultWalker) problem(rel string, err error) {
+	w.result.problems = append(w.result.problems, ParseError{FilePath: rel, Kind: ParseErrorSymlink, Error: err})
+}
+
+// vaultRel returns path, a real path inside the vault, relative to its root.
+func (w *vaultWalker) vaultRel(path string) string {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+// resolveInVault returns the real path of the file at relativePath in the
+// vault, failing with ErrOutsideVault if symlinks take it outside the vault
+// root, or, when policy is SymlinksSkip, if any symlink is on the way.
+// Checking again at read time keeps files out of reach even if links change
+// after the vault was walked.
+func resolveInVault(vaultPath, relativePath string, policy SymlinkPolicy) (string, error) {
+	root, err := filepath.EvalSymlinks(vaultPath)
+	if err != nil {
+		return "", err
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(root, relativePath))
+	if err != nil {
+		return "", err
+	}
+	if !withinDir(root, path) {
+		return "", ErrOutsideVault
+	}
+	if policy == SymlinksSkip && path != filepath.Join(root, relativePath) {
+		return "", errSymlinkSkipped
+	}
+	return path, nil
+}
+
+// withinDir reports whether path is dir or inside it. Both must be clean.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,155 @@
+package vault
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// symlinkVault lays out a vault beside a directory of secrets, with links
+// that try to reach the secrets, loop, or point nowhere.
+func symlinkVault(t *testing.T) (vaultDir, outside string) {
+	t.Helper()
+	base := t.TempDir()
+	vaultDir = filepath.Join(base, "vault")
+	outside = filepath.Join(base, "secrets")
+	for _, dir := range []string{vaultDir, outside, filepath.Join(vaultDir, "notes", "deep"), filepath.Join(vaultDir, ".shared")} {
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+	}
+	write := func(path, id string) {
+		require.NoError(t, os.WriteFile(path, []byte("---\nid: "+id+"\n---\nbody"), 0o644))
+	}
+	write(filepath.Join(vaultDir, "notes", "a.md"), "a")
+	write(filepath.Join(vaultDir, ".shared", "s.md"), "s")
+	write(filepath.Join(outside, "secret.md"), "secret")
+	write(filepath.Join(outside, "other.md"), "other")
+
+	link := func(target, name string) {
+		require.NoError(t, os.Symlink(target, filepath.Join(vaultDir, name)))
+	}
+	link(filepath.Join(outside, "secret.md"), "secret.md")            // File outside, absolute
+	link(filepath.Join("..", "secrets"), "secrets")                   // Directory outside, relative
+	link(filepath.Join("..", "..", "..", "secrets"), "notes/deep/up") // Climbs out from below
+	link("..", "notes/deep/loop")                                     // Cycle to an ancestor
+	link("missing.md", "broken.md")                                   // Broken
+	link("notes/a.md", "alias.md")                                    // Alias of a walked file
+	link("notes", "alias-dir")                                        // Alias of a walked directory
+	link(".shared", "shared")                                         // Hidden directory inside the vault
+	return vaultDir, outside
+}
+
+func symlinkProblems(result *ParseResult) map[string]error {
+	problems := make(map[string]error)
+	for _, pe := range result.ParseErrors {
+		if pe.Kind == ParseErrorSymlink {
+			problems[pe.FilePath] = pe.Error
+		}
+	}
+	return problems
+}
+
+func TestParser_SymlinksFollow(t *testing.T) {
+	vaultDir, _ := symlinkVault(t)
+	result, err := NewParser(vaultDir, 1, 0).ParseVault()
+	require.NoError(t, err)
+
+	var paths []string
+	for id, f := range result.Files {
+		paths = append(paths, id+"="+f.Path)
+	}
+	assert.ElementsMatch(t, []string{"a=notes/a.md", "s=shared/s.md"}, paths,
+		"files outside the vault are never read and aliases are read once")
+	assert.Equal(t, 2, result.Stats.TotalFiles)
+
+	problems := symlinkProblems(result)
+	assert.Len(t, problems, 5)
+	for _, path := range []string{"secret.md", "secrets", filepath.Join("notes", "deep", "up")} {
+		assert.ErrorIs(t, problems[path], ErrOutsideVault, path)
+	}
+	assert.ErrorIs(t, problems[filepath.Join("notes", "deep", "loop")], ErrSymlinkCycle)
+	assert.Contains(t, problems["broken.md"].Error(), "broken symlink")
+}
+
+func TestParser_SymlinksSkip(t *testing.T) {
+	vaultDir, _ := symlinkVault(t)
+	parser := NewParser(vaultDir, 1, 0)
+	parser.SetOptions(ParseOptions{Symlinks: SymlinksSkip})
+	result, err := parser.ParseVault()
+	require.NoError(t, err)
+
+	require.Len(t, result.Files, 1)
+	assert.Equal(t, "notes/a.md", result.Files["a"].Path)
+	assert.Empty(t, result.ParseErrors)
+
+	_, err = ProcessMarkdownFileWithOptions(vaultDir, "alias.md", ParseOptions{Symlinks: SymlinksSkip})
+	assert.Error(t, err)
+}
+
+func TestReadMarkdownFile_StaysInVault(t *testing.T) {
+	vaultDir, outside := symlinkVault(t)
+
+	// Links swapped after the walk, and paths that climb out, are refused
+	for _, rel := range []string{"secret.md", "secrets/other.md", "notes/deep/up/other.md", "../secrets/other.md"} {
+		_, _, err := readMarkdownFile(vaultDir, rel, ParseOptions{})
+		assert.ErrorIs(t, err, ErrOutsideVault, rel)
+	}
+	require.NoError(t, os.Remove(filepath.Join(vaultDir, "alias.md")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.md"), filepath.Join(vaultDir, "alias.md")))
+	_, _, err := readMarkdownFile(vaultDir, "alias.md", ParseOptions{})
+	assert.ErrorIs(t, err, ErrOutsideVault)
+
+	content, _, err := readMarkdownFile(vaultDir, "shared/s.md", ParseOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "id: s")
+}
+
+func TestParser_DirConfigSymlink(t *testing.T) {
+	vaultDir, outside := symlinkVault(t)
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "creds.yaml"), []byte("api_token: hunter2\n"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "creds.yaml"), filepath.Join(vaultDir, DirConfigName)))
+	require.NoError(t, os.Symlink(filepath.Join("..", "..", "secrets", "creds.yaml"), filepath.Join(vaultDir, "notes", DirConfigName)))
+
+	result, err := NewParser(vaultDir, 1, 0).ParseVault()
+	require.NoError(t, err)
+
+	var problems []string
+	for _, pe := range result.ParseErrors {
+		if pe.Kind == ParseErrorDirConfig {
+			problems = append(problems, pe.FilePath)
+			assert.NotContains(t, pe.Error.Error(), "hunter2", "the link's target is never read")
+			assert.NotContains(t, pe.Error.Error(), "api_token")
+		}
+	}
+	assert.ElementsMatch(t, []string{DirConfigName, filepath.Join("notes", DirConfigName)}, problems)
+	assert.Len(t, result.Files, 2, "the vault is indexed without the linked configs")
+}
+
+func TestParser_SymlinkedVaultRoot(t *testing.T) {
+	vaultDir, _ := symlinkVault(t)
+	root := filepath.Join(t.TempDir(), "vault-link")
+	require.NoError(t, os.Symlink(vaultDir, root))
+
+	result, err := NewParser(root, 1, 0).ParseVault()
+	require.NoError(t, err)
+	assert.Len(t, result.Files, 2, "a vault reached through a link is walked as usual")
+}
+
+func TestMeasureDisk(t *testing.T) {
+	vaultDir, _ := symlinkVault(t)
+	require.NoError(t, os.WriteFile(filepath.Join(vaultDir, "notes", "photo.jpg"), []byte("jpeg"), 0o644))
+
+	// Links are not followed and hidden directories are skipped, so only
+	// notes/a.md and the photo count
+	u, err := MeasureDisk(context.Background(), vaultDir)
+	require.NoError(t, err)
+	assert.Equal(t, DiskUsage{
+		MarkdownFiles:   1,
+		MarkdownBytes:   int64(len("---\nid: a\n---\nbody")),
+		AttachmentFiles: 1,
+		AttachmentBytes: 4,
+	}, u)
+}
@@ -131,6 +131,36 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 	w.timer = time.AfterFunc(w.debounce, w.flush)
 }
 
+// Notify queues files changed behind the watcher's back, e.g. by a git pull,
+// given as vault-relative paths. They are indexed with the next batch of
+// events, so changes the watcher also saw are indexed once; files in new
+// directories, which the watcher may miss, are indexed too.
+func (w *Watcher) Notify(relPaths []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, rel := range relPaths {
+		path := filepath.Join(w.vaultPath, filepath.FromSlash(rel))
+		if !w.isWatchable(path) {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			w.pending[path] = fsnotify.Remove
+			continue
+		}
+		w.pending[path] = fsnotify.Create
+		// Watch directories the change created; already watched ones are no-ops
+		for dir := filepath.Dir(path); dir != w.vaultPath && strings.HasPrefix(dir, w.vaultPath); dir = filepath.Dir(dir) {
+			w.watcher.Add(dir)
+		}
+	}
+
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(w.debounce, w.flush)
+}
+
 func (w *Watcher) flush() {
 	w.mu.Lock()
 	pending := w.pending
@@ -206,6 +206,28 @@ id: "c"
 	assert.Contains(t, receivedIDs, graphIDs[0])
 }
 
+func TestWatcherNotify(t *testing.T) {
+	dir, s, m, vaultID, graphIDs := setupTestVault(t)
+
+	// Not started: only notified changes are indexed, as when events are missed
+	w, err := New(m, vaultID, dir)
+	require.NoError(t, err)
+	defer w.Stop()
+
+	writeFile(t, filepath.Join(dir, "pulled", "deep", "note-p.md"), `---
+id: "p"
+---
+# Pulled
+`)
+	require.NoError(t, os.Remove(filepath.Join(dir, "note-a.md")))
+	w.Notify([]string{"pulled/deep/note-p.md", "note-a.md", "image.png"})
+
+	assert.Eventually(t, func() bool {
+		g, err := s.GetGraphData(graphIDs[0])
+		return err == nil && len(g.Nodes) == 1 && g.Nodes[0].ID == "p"
+	}, 3*time.Second, 100*time.Millisecond, "expected only the pulled note after notifying")
+}
+
 func writeFile(t *testing.T, path, content string) {
 	t.Helper()
 	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))